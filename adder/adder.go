@@ -80,6 +80,9 @@ type Adder struct {
 
 	params api.AddParams
 
+	// policy, when set, is enforced on every added file.
+	policy *api.UploadPolicy
+
 	// AddedOutput updates are placed on this channel
 	// whenever a block is processed. They contain information
 	// about the block, the CID, the Name etc. and are mostly
@@ -109,6 +112,12 @@ func New(ds ClusterDAGService, p api.AddParams, out chan api.AddedOutput) *Adder
 	}
 }
 
+// SetUploadPolicy makes the adder detect the content type of the files
+// being added and fail when they do not follow the given policy.
+func (a *Adder) SetUploadPolicy(p *api.UploadPolicy) {
+	a.policy = p
+}
+
 func (a *Adder) setContext(ctx context.Context) {
 	if a.ctx == nil { // only allows first context
 		ctxc, cancel := context.WithCancel(ctx)
@@ -165,6 +174,14 @@ func (a *Adder) FromFiles(ctx context.Context, f files.Directory) (api.Cid, erro
 		)
 	}
 
+	if a.policy != nil {
+		f = &policyDirectory{
+			Directory: f,
+			policy:    a.policy,
+			metadata:  a.params.Metadata,
+		}
+	}
+
 	it := f.Entries()
 	var adderRoot api.Cid
 	for it.Next() {
@@ -3,6 +3,7 @@ package adder
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"mime/multipart"
 	"sync"
@@ -231,3 +232,78 @@ func TestAdder_LargeFolder(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestAdder_UploadPolicy(t *testing.T) {
+	ctx := context.Background()
+	policy := &api.UploadPolicy{
+		DenyTypes: []string{"application/x-executable"},
+		MaxSizes:  map[string]uint64{"image/": 100},
+		RequireMetadata: map[string][]string{
+			"text/csv": {"license"},
+		},
+	}
+
+	png := append([]byte("\x89PNG\x0D\x0A\x1A\x0A"), make([]byte, 200)...)
+
+	testcases := []struct {
+		name     string
+		file     string
+		content  []byte
+		metadata map[string]string
+		allowed  bool
+	}{
+		{"executable", "prog", []byte("\x7fELF\x02\x01\x01"), nil, false},
+		{"text", "notes.txt", []byte("hello"), nil, true},
+		{"large image", "pic.png", png, nil, false},
+		{"small image", "pic.png", png[:50], nil, true},
+		{"dataset without metadata", "data.csv", []byte("a,b\n1,2\n"), nil, false},
+		{"dataset with metadata", "data.csv", []byte("a,b\n1,2\n"), map[string]string{"license": "CC0"}, true},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := files.NewMapDirectory(map[string]files.Node{
+				tc.file: files.NewBytesFile(tc.content),
+			})
+			mf := files.NewMultiFileReader(dir, true)
+			r := multipart.NewReader(mf, mf.Boundary())
+
+			dags := newMockCDAGServ()
+			defer dags.Close()
+
+			p := api.DefaultAddParams()
+			p.Metadata = tc.metadata
+			adder := New(dags, p, nil)
+			adder.SetUploadPolicy(policy)
+			_, err := adder.FromMultipart(ctx, r)
+			if tc.allowed && err != nil {
+				t.Fatal(err)
+			}
+			if !tc.allowed && !errors.Is(err, api.ErrUploadPolicy) {
+				t.Fatal("expected an upload policy error:", err)
+			}
+		})
+	}
+}
+
+func TestDetectContentType(t *testing.T) {
+	testcases := []struct {
+		name     string
+		data     []byte
+		expected string
+	}{
+		{"a.out", []byte("\x7fELF\x02\x01"), "application/x-executable"},
+		{"setup.exe", []byte("MZ\x90\x00"), "application/vnd.microsoft.portable-executable"},
+		{"run.sh", []byte("#!/bin/sh\necho hi\n"), "text/x-shellscript"},
+		{"data.parquet", []byte("PAR1\x15\x00"), "application/vnd.apache.parquet"},
+		{"data.csv", []byte("a,b\n1,2\n"), "text/csv"},
+		{"page", []byte("<html><body></body></html>"), "text/html"},
+		{"unknown", []byte("hello"), "text/plain"},
+	}
+
+	for _, tc := range testcases {
+		if ctype := detectContentType(tc.name, tc.data); ctype != tc.expected {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.expected, ctype)
+		}
+	}
+}
@@ -4,6 +4,7 @@ package adderutils
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"mime/multipart"
 	"net/http"
 	"sync"
@@ -22,11 +23,13 @@ var logger = logging.Logger("adder")
 // AddMultipartHTTPHandler is a helper function to add content
 // uploaded using a multipart request. The outputTransform parameter
 // allows to customize the http response output format to something
-// else than api.AddedOutput objects.
+// else than api.AddedOutput objects. When policy is not nil, the added files
+// must follow it.
 func AddMultipartHTTPHandler(
 	ctx context.Context,
 	rpc *rpc.Client,
 	params api.AddParams,
+	policy *api.UploadPolicy,
 	reader *multipart.Reader,
 	w http.ResponseWriter,
 	outputTransform func(api.AddedOutput) interface{},
@@ -68,12 +71,17 @@ func AddMultipartHTTPHandler(
 
 		enc := json.NewEncoder(w)
 		add := adder.New(dags, params, output)
+		add.SetUploadPolicy(policy)
 		root, err := add.FromMultipart(ctx, reader)
 		if err != nil { // Send an error
 			logger.Error(err)
-			w.WriteHeader(http.StatusInternalServerError)
+			code := http.StatusInternalServerError
+			if errors.Is(err, api.ErrUploadPolicy) {
+				code = http.StatusForbidden
+			}
+			w.WriteHeader(code)
 			errorResp := api.Error{
-				Code:    http.StatusInternalServerError,
+				Code:    code,
 				Message: err.Error(),
 			}
 
@@ -102,6 +110,7 @@ func AddMultipartHTTPHandler(
 		streamOutput(w, output, outputTransform)
 	}()
 	add := adder.New(dags, params, output)
+	add.SetUploadPolicy(policy)
 	root, err := add.FromMultipart(ctx, reader)
 	if err != nil {
 		logger.Error(err)
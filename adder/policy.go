@@ -0,0 +1,197 @@
+package adder
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	files "github.com/ipfs/boxo/files"
+)
+
+// sniffLen is the number of bytes used to detect the content type of a file,
+// as in http.DetectContentType.
+const sniffLen = 512
+
+// Content types which are detected by their magic numbers before falling
+// back to http.DetectContentType.
+var magicTypes = []struct {
+	magic []byte
+	ctype string
+}{
+	{[]byte("\x7fELF"), "application/x-executable"},
+	{[]byte("\xfe\xed\xfa\xce"), "application/x-mach-binary"},
+	{[]byte("\xfe\xed\xfa\xcf"), "application/x-mach-binary"},
+	{[]byte("\xce\xfa\xed\xfe"), "application/x-mach-binary"},
+	{[]byte("\xcf\xfa\xed\xfe"), "application/x-mach-binary"},
+	{[]byte("MZ"), "application/vnd.microsoft.portable-executable"},
+	{[]byte("#!"), "text/x-shellscript"},
+	{[]byte("PAR1"), "application/vnd.apache.parquet"},
+}
+
+// Content types for extensions that are usually not known by the system
+// mime database. They are only used when the content cannot be identified.
+var extensionTypes = map[string]string{
+	".csv":     "text/csv",
+	".tsv":     "text/tab-separated-values",
+	".jsonl":   "application/jsonl",
+	".ndjson":  "application/jsonl",
+	".parquet": "application/vnd.apache.parquet",
+}
+
+// detectContentType returns the content type of a file from its first bytes
+// and, when they are not conclusive, from its name.
+func detectContentType(name string, data []byte) string {
+	for _, m := range magicTypes {
+		if bytes.HasPrefix(data, m.magic) {
+			return m.ctype
+		}
+	}
+
+	ctype := http.DetectContentType(data)
+	if i := strings.Index(ctype, ";"); i >= 0 {
+		ctype = ctype[:i]
+	}
+	if ctype != "application/octet-stream" && ctype != "text/plain" {
+		return ctype
+	}
+
+	ext := strings.ToLower(filepath.Ext(name))
+	if t, ok := extensionTypes[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		if i := strings.Index(t, ";"); i >= 0 {
+			t = t[:i]
+		}
+		return t
+	}
+	return ctype
+}
+
+// policyDirectory wraps a files.Directory so that all the files in it are
+// checked against an upload policy as they are read.
+type policyDirectory struct {
+	files.Directory
+	policy   *api.UploadPolicy
+	metadata map[string]string
+}
+
+func (pd *policyDirectory) Entries() files.DirIterator {
+	return &policyIterator{
+		DirIterator: pd.Directory.Entries(),
+		policy:      pd.policy,
+		metadata:    pd.metadata,
+	}
+}
+
+type policyIterator struct {
+	files.DirIterator
+	policy   *api.UploadPolicy
+	metadata map[string]string
+}
+
+func (pi *policyIterator) Node() files.Node {
+	return withPolicy(pi.Name(), pi.DirIterator.Node(), pi.policy, pi.metadata)
+}
+
+// withPolicy wraps files and directories so that they are checked
+// against the policy. Other nodes (i.e. symlinks) are returned as they are.
+func withPolicy(name string, n files.Node, policy *api.UploadPolicy, metadata map[string]string) files.Node {
+	switch n := n.(type) {
+	case files.Directory:
+		return &policyDirectory{Directory: n, policy: policy, metadata: metadata}
+	case *files.Symlink:
+		return n
+	case files.File:
+		pf := &policyFile{File: n, name: name, policy: policy, metadata: metadata}
+		if fi, ok := n.(files.FileInfo); ok {
+			// keep NoCopy working.
+			return &policyFileInfo{policyFile: pf, fi: fi}
+		}
+		return pf
+	default:
+		return n
+	}
+}
+
+// policyFile detects the content type of a file on the first read and
+// fails reading when the file is not allowed by the policy or when it
+// grows over the size limit for its type.
+type policyFile struct {
+	files.File
+	name     string
+	policy   *api.UploadPolicy
+	metadata map[string]string
+
+	reader  io.Reader
+	ctype   string
+	maxSize uint64
+	read    uint64
+}
+
+func (pf *policyFile) sniff() error {
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(pf.File, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	buf = buf[:n]
+
+	pf.ctype = detectContentType(pf.name, buf)
+	if err := pf.policy.CheckType(pf.ctype, pf.metadata); err != nil {
+		return fmt.Errorf("%s: %w", pf.name, err)
+	}
+	pf.maxSize = pf.policy.MaxSize(pf.ctype)
+	pf.reader = io.MultiReader(bytes.NewReader(buf), pf.File)
+	return nil
+}
+
+func (pf *policyFile) Read(p []byte) (int, error) {
+	if pf.reader == nil {
+		if err := pf.sniff(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := pf.reader.Read(p)
+	pf.read += uint64(n)
+	if pf.maxSize > 0 && pf.read > pf.maxSize {
+		return n, fmt.Errorf(
+			"%s: %w: %s files cannot be larger than %d bytes",
+			pf.name,
+			api.ErrUploadPolicy,
+			pf.ctype,
+			pf.maxSize,
+		)
+	}
+	return n, err
+}
+
+// Seek is only supported before reading, since the content type detection
+// consumes the beginning of the file.
+func (pf *policyFile) Seek(offset int64, whence int) (int64, error) {
+	if pf.reader != nil {
+		return 0, files.ErrNotSupported
+	}
+	return pf.File.Seek(offset, whence)
+}
+
+type policyFileInfo struct {
+	*policyFile
+	fi files.FileInfo
+}
+
+func (pfi *policyFileInfo) AbsPath() string {
+	return pfi.fi.AbsPath()
+}
+
+func (pfi *policyFileInfo) Stat() os.FileInfo {
+	return pfi.fi.Stat()
+}
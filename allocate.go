@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	peer "github.com/libp2p/go-libp2p/core/peer"
 
@@ -20,6 +23,7 @@ import (
 // The allocation process has several steps:
 //
 // * Find which peers are pinning a CID
+// * During allocation bursts, request fresh metrics from peers if enabled
 // * Obtain the last values for the configured informer metrics from the
 //   monitor component
 // * Divide the metrics between "current" (peers already pinning the CID)
@@ -74,19 +78,19 @@ func (c *Cluster) allocate(ctx context.Context, hash api.Cid, currentPin api.Pin
 		currentAllocs = currentPin.Allocations
 	}
 
-	// Get Metrics that the allocator is interested on
-	mSet := make(api.MetricsSet)
-	metrics := c.allocator.Metrics()
-	for _, metricName := range metrics {
-		mSet[metricName] = c.monitor.LatestMetrics(ctx, metricName)
+	if c.config.MetricsPrefetchFanout > 0 && c.allocBurst.observe(time.Now()) {
+		c.prefetchMetrics(ctx)
 	}
 
+	// Get Metrics that the allocator is interested on
+	mSet := c.allocatorMetrics(ctx)
+
 	// Filter and divide metrics.  The resulting sets only have peers that
 	// have all the metrics needed and are not blacklisted.
 	classified := c.filterMetrics(
 		ctx,
 		mSet,
-		len(metrics),
+		len(mSet),
 		currentAllocs,
 		priorityList,
 		blacklist,
@@ -113,6 +117,16 @@ func (c *Cluster) allocate(ctx context.Context, hash api.Cid, currentPin api.Pin
 	return newAllocs, nil
 }
 
+// allocatorMetrics returns the latest metrics for all the metrics used by
+// the allocator.
+func (c *Cluster) allocatorMetrics(ctx context.Context) api.MetricsSet {
+	mSet := make(api.MetricsSet)
+	for _, metricName := range c.allocator.Metrics() {
+		mSet[metricName] = c.monitor.LatestMetrics(ctx, metricName)
+	}
+	return mSet
+}
+
 // Given metrics from all informers, split them into 3 MetricsSet:
 // - Those corresponding to currently allocated peers
 // - Those corresponding to priority allocations
@@ -268,3 +282,162 @@ func (c *Cluster) obtainAllocations(
 	// along with the ones provided by the allocator
 	return append(metrics.currentPeers, finalAllocs[0:allocationsToUse]...), nil
 }
+
+// explainAllocation describes how allocate() would allocate the given pin
+// with the latest metrics. The pin must have its replication factors set.
+// Peers in the excluded list are left out as allocate() does with the
+// blacklist.
+func (c *Cluster) explainAllocation(ctx context.Context, pin, currentPin api.Pin, excluded []peer.ID) api.AllocationExplanation {
+	expl := api.AllocationExplanation{
+		Cid:                  pin.Cid,
+		ReplicationFactorMin: pin.ReplicationFactorMin,
+		ReplicationFactorMax: pin.ReplicationFactorMax,
+		StorageClass:         pin.StorageClass,
+		AllocateBy:           c.allocator.Metrics(),
+	}
+
+	// Consider the cluster peers and any other peer sending metrics.
+	peers, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Error(err)
+	}
+	mSet := c.allocatorMetrics(ctx)
+	peerMetrics := make(map[peer.ID][]api.Metric)
+	for _, p := range peers {
+		peerMetrics[p] = nil
+	}
+	for _, name := range expl.AllocateBy {
+		for _, m := range mSet[name] {
+			peerMetrics[m.Peer] = append(peerMetrics[m.Peer], m)
+		}
+	}
+
+	if pin.IsPinEverywhere() {
+		for p, metrics := range peerMetrics {
+			expl.Peers = append(expl.Peers, api.PeerAllocationExplanation{
+				Peer:    p,
+				Class:   api.AllocationClassCandidate,
+				Metrics: metrics,
+				Chosen:  true,
+				Reason:  "the pin is allocated to every peer",
+			})
+		}
+		sortPeerAllocationExplanations(expl.Peers)
+		return expl
+	}
+
+	var currentAllocs []peer.ID
+	if currentPin.Defined() {
+		currentAllocs = currentPin.Allocations
+	}
+	classified := c.filterMetrics(ctx, mSet, len(mSet), currentAllocs, pin.UserAllocations, excluded)
+
+	allocs, err := c.obtainAllocations(ctx, pin.Cid, pin.ReplicationFactorMin, pin.ReplicationFactorMax, classified)
+	if err != nil {
+		expl.Error = err.Error()
+	} else if allocs == nil {
+		allocs = currentAllocs
+	}
+	expl.Allocations = allocs
+
+	var ranked []peer.ID
+	if len(classified.candidatePeers)+len(classified.priorityPeers) > 0 {
+		ranked, err = c.allocator.Allocate(ctx, pin.Cid, classified.current, classified.candidate, classified.priority)
+		if err != nil {
+			logger.Error(err)
+		}
+	}
+	rank := func(p peer.ID) int {
+		for i, r := range ranked {
+			if r == p {
+				return i + 1
+			}
+		}
+		return 0
+	}
+	needed := pin.ReplicationFactorMin - len(classified.currentPeers)
+
+	for p, metrics := range peerMetrics {
+		pe := api.PeerAllocationExplanation{
+			Peer:    p,
+			Metrics: metrics,
+			Chosen:  containsPeer(allocs, p),
+		}
+
+		switch {
+		case containsPeer(excluded, p):
+			pe.Class = api.AllocationClassExcluded
+			pe.Reason = fmt.Sprintf("not in the peer group of storage class %s", pin.StorageClass)
+		case c.config.PinOnlyOnTrustedPeers && !c.consensus.IsTrustedPeer(ctx, p):
+			pe.Class = api.AllocationClassExcluded
+			pe.Reason = "not a trusted peer and pin_only_on_trusted_peers is set"
+		case containsPeer(classified.currentPeers, p):
+			pe.Class = api.AllocationClassCurrent
+			if pe.Chosen {
+				pe.Reason = "already allocated"
+			} else {
+				pe.Reason = "allocation dropped as there are more than replication_factor_max"
+			}
+		case containsPeer(classified.priorityPeers, p) || containsPeer(classified.candidatePeers, p):
+			pe.Class = api.AllocationClassCandidate
+			if containsPeer(classified.priorityPeers, p) {
+				pe.Class = api.AllocationClassPriority
+			}
+			pe.Rank = rank(p)
+			switch {
+			case pe.Chosen:
+				pe.Reason = fmt.Sprintf("ranked %d by the allocator", pe.Rank)
+			case pe.Rank == 0:
+				pe.Reason = "discarded by the allocator"
+			case expl.Error != "":
+				pe.Reason = "not enough candidates to allocate"
+			case needed <= 0:
+				pe.Reason = "current allocations reach replication_factor_min"
+			default:
+				pe.Reason = fmt.Sprintf("ranked %d by the allocator, after replication_factor_max was reached", pe.Rank)
+			}
+		default:
+			pe.Class = api.AllocationClassExcluded
+			pe.Reason = "missing valid metrics: " + strings.Join(missingMetrics(expl.AllocateBy, metrics), ", ")
+		}
+		expl.Peers = append(expl.Peers, pe)
+	}
+	sortPeerAllocationExplanations(expl.Peers)
+	return expl
+}
+
+// missingMetrics returns the names which are not in the given metrics.
+func missingMetrics(names []string, metrics []api.Metric) []string {
+	var missing []string
+	for _, name := range names {
+		found := false
+		for _, m := range metrics {
+			if m.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// sortPeerAllocationExplanations puts the chosen peers first, then the
+// ranked ones by rank, then the rest by peer ID.
+func sortPeerAllocationExplanations(peers []api.PeerAllocationExplanation) {
+	sort.Slice(peers, func(i, j int) bool {
+		a, b := peers[i], peers[j]
+		if a.Chosen != b.Chosen {
+			return a.Chosen
+		}
+		if (a.Rank == 0) != (b.Rank == 0) {
+			return a.Rank != 0
+		}
+		if a.Rank != b.Rank {
+			return a.Rank < b.Rank
+		}
+		return a.Peer < b.Peer
+	})
+}
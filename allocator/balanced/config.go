@@ -91,6 +91,12 @@ func (cfg *Config) ToJSON() ([]byte, error) {
 	return config.DefaultJSONMarshal(jcfg)
 }
 
+// JSONKeys returns the keys of the JSON representation of this
+// configuration, including those omitted when empty.
+func (cfg *Config) JSONKeys() []string {
+	return config.JSONKeys(jsonConfig{})
+}
+
 func (cfg *Config) toJSONConfig() *jsonConfig {
 	return &jsonConfig{
 		AllocateBy: cfg.AllocateBy,
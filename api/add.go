@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
 
 	cid "github.com/ipfs/go-cid"
 	peer "github.com/libp2p/go-libp2p/core/peer"
@@ -259,3 +260,86 @@ func (p AddParams) Equals(p2 AddParams) bool {
 		p.Format == p2.Format &&
 		p.NoPin == p2.NoPin
 }
+
+// UploadPolicy defines rules that the files added to the cluster must
+// follow. Content types are detected from the first bytes of every file.
+// Rules match content types exactly ("application/pdf"), by prefix when
+// they end in "/" ("video/") or always when they are "*".
+type UploadPolicy struct {
+	// DenyTypes lists the content types that cannot be added.
+	DenyTypes []string `json:"deny_types,omitempty"`
+	// MaxSizes sets the maximum size in bytes for files of the given
+	// content types. The smallest matching limit applies.
+	MaxSizes map[string]uint64 `json:"max_sizes,omitempty"`
+	// RequireMetadata lists the metadata keys that must be set when
+	// adding files of the given content types.
+	RequireMetadata map[string][]string `json:"require_metadata,omitempty"`
+}
+
+// ErrUploadPolicy is returned when added content does not follow the
+// upload policy.
+var ErrUploadPolicy = errors.New("upload policy violation")
+
+func matchContentType(rule, ctype string) bool {
+	switch {
+	case rule == "*":
+		return true
+	case strings.HasSuffix(rule, "/"):
+		return strings.HasPrefix(ctype, rule)
+	default:
+		return rule == ctype
+	}
+}
+
+// Validate returns an error if the policy has empty rules.
+func (p UploadPolicy) Validate() error {
+	for _, t := range p.DenyTypes {
+		if t == "" {
+			return errors.New("empty content type in deny_types")
+		}
+	}
+	for t := range p.MaxSizes {
+		if t == "" {
+			return errors.New("empty content type in max_sizes")
+		}
+	}
+	for t := range p.RequireMetadata {
+		if t == "" {
+			return errors.New("empty content type in require_metadata")
+		}
+	}
+	return nil
+}
+
+// CheckType returns an error when files of the given content type cannot
+// be added with the given metadata.
+func (p UploadPolicy) CheckType(ctype string, metadata map[string]string) error {
+	for _, rule := range p.DenyTypes {
+		if matchContentType(rule, ctype) {
+			return fmt.Errorf("%w: content type %s is not allowed", ErrUploadPolicy, ctype)
+		}
+	}
+	for rule, keys := range p.RequireMetadata {
+		if !matchContentType(rule, ctype) {
+			continue
+		}
+		for _, k := range keys {
+			if _, ok := metadata[k]; !ok {
+				return fmt.Errorf("%w: content type %s requires the %q metadata key", ErrUploadPolicy, ctype, k)
+			}
+		}
+	}
+	return nil
+}
+
+// MaxSize returns the maximum size allowed for files of the given content
+// type, or 0 when there is no limit.
+func (p UploadPolicy) MaxSize(ctype string) uint64 {
+	var max uint64
+	for rule, size := range p.MaxSizes {
+		if matchContentType(rule, ctype) && (max == 0 || size < max) {
+			max = size
+		}
+	}
+	return max
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/url"
 	"testing"
 )
@@ -100,3 +101,48 @@ func TestAddParams_ToQueryString(t *testing.T) {
 		t.Error("generated and parsed params should be equal")
 	}
 }
+
+func TestUploadPolicy(t *testing.T) {
+	p := UploadPolicy{
+		DenyTypes: []string{"application/x-executable"},
+		MaxSizes: map[string]uint64{
+			"video/":    100,
+			"video/mp4": 50,
+			"*":         1000,
+		},
+		RequireMetadata: map[string][]string{
+			"text/csv": {"license"},
+		},
+	}
+
+	if err := p.CheckType("application/x-executable", nil); !errors.Is(err, ErrUploadPolicy) {
+		t.Error("executables should be denied")
+	}
+	if err := p.CheckType("text/csv", nil); !errors.Is(err, ErrUploadPolicy) {
+		t.Error("csv files should require metadata")
+	}
+	if err := p.CheckType("text/csv", map[string]string{"license": "CC0"}); err != nil {
+		t.Error(err)
+	}
+	if err := p.CheckType("image/png", nil); err != nil {
+		t.Error(err)
+	}
+
+	if s := p.MaxSize("video/webm"); s != 100 {
+		t.Error("unexpected max size for video/webm:", s)
+	}
+	if s := p.MaxSize("video/mp4"); s != 50 {
+		t.Error("the smallest limit should apply:", s)
+	}
+	if s := p.MaxSize("image/png"); s != 1000 {
+		t.Error("the * limit should apply:", s)
+	}
+
+	if err := p.Validate(); err != nil {
+		t.Error(err)
+	}
+	p.DenyTypes = append(p.DenyTypes, "")
+	if p.Validate() == nil {
+		t.Error("expected an error validating empty rules")
+	}
+}
@@ -27,6 +27,7 @@ import (
 
 	jwt "github.com/golang-jwt/jwt/v4"
 	types "github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/recovery"
 	state "github.com/ipfs-cluster/ipfs-cluster/state"
 	gopath "github.com/ipfs/boxo/path"
 	logging "github.com/ipfs/go-log/v2"
@@ -80,15 +81,26 @@ type API struct {
 	router    *mux.Router
 	routes    func(*rpc.Client) []Route
 
-	server *http.Server
-	host   host.Host
+	handler http.Handler
+	host    host.Host
 
-	httpListeners  []net.Listener
+	// The HTTP listeners are served by their own server, which is
+	// replaced by ReloadHTTP() without affecting the libp2p endpoint.
+	httpMux        sync.RWMutex
+	httpServer     *http.Server
+	httpListeners  []*sharedListener
+	libp2pServer   *http.Server
 	libp2pListener net.Listener
 
 	shutdownLock sync.Mutex
 	shutdown     bool
 	wg           sync.WaitGroup
+
+	healthChecksMux sync.RWMutex
+	healthChecks    []func() error
+
+	// oidc verifies the tokens of the configured OIDC provider, if any.
+	oidc *oidcVerifier
 }
 
 // Route defines a REST endpoint supported by this API.
@@ -135,11 +147,15 @@ func NewAPIWithHost(ctx context.Context, cfg *Config, h host.Host, routes func(*
 		routes:   routes,
 		rpcReady: make(chan struct{}, 2),
 	}
+	if cfg.OIDC != nil {
+		api.oidc = newOIDCVerifier(cfg.OIDC, cfg.Logger)
+	}
 
 	// Our handler is a gorilla router wrapped with:
 	// - a custom strictSlashHandler that uses 307 redirects (#1415)
 	// - the cors handler,
-	// - the basic auth handler.
+	// - the basic auth handler,
+	// - a panic recovery handler.
 	//
 	// Requests will need to have valid credentials first, except
 	// cors-preflight requests (OPTIONS). Then requests are handled by
@@ -147,12 +163,15 @@ func NewAPIWithHost(ctx context.Context, cfg *Config, h host.Host, routes func(*
 	// redirected if the path ends with a "/". Finally they hit one of our
 	// routes and handlers.
 	router := mux.NewRouter()
-	handler := api.authHandler(
-		cors.New(*cfg.CorsOptions()).
-			Handler(
-				strictSlashHandler(router),
-			),
-		cfg.Logger,
+	handler := recovery.Handler(
+		"api/"+cfg.ConfigKey,
+		api.authHandler(
+			cors.New(*cfg.CorsOptions()).
+				Handler(
+					strictSlashHandler(router),
+				),
+			cfg.Logger,
+		),
 	)
 	if cfg.Tracing {
 		handler = &ochttp.Handler{
@@ -170,22 +189,9 @@ func NewAPIWithHost(ctx context.Context, cfg *Config, h host.Host, routes func(*
 		return nil, err
 	}
 
-	s := &http.Server{
-		ReadTimeout:       cfg.ReadTimeout,
-		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
-		WriteTimeout:      cfg.WriteTimeout,
-		IdleTimeout:       cfg.IdleTimeout,
-		Handler:           handlers.LoggingHandler(writer, handler),
-		MaxHeaderBytes:    cfg.MaxHeaderBytes,
-	}
-
-	// See: https://github.com/ipfs/go-ipfs/issues/5168
-	// See: https://github.com/ipfs-cluster/ipfs-cluster/issues/548
-	// on why this is re-enabled.
-	s.SetKeepAlivesEnabled(true)
-	s.MaxHeaderBytes = cfg.MaxHeaderBytes
-
-	api.server = s
+	api.handler = handlers.LoggingHandler(writer, handler)
+	api.httpServer = api.newServer(cfg)
+	api.libp2pServer = api.newServer(cfg)
 	api.router = router
 
 	// Set up api.httpListeners if enabled
@@ -208,28 +214,33 @@ func NewAPIWithHost(ctx context.Context, cfg *Config, h host.Host, routes func(*
 	return api, nil
 }
 
+func (api *API) newServer(cfg *Config) *http.Server {
+	s := &http.Server{
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		Handler:           api.handler,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+
+	// See: https://github.com/ipfs/go-ipfs/issues/5168
+	// See: https://github.com/ipfs-cluster/ipfs-cluster/issues/548
+	// on why this is re-enabled.
+	s.SetKeepAlivesEnabled(true)
+	return s
+}
+
 func (api *API) setupHTTP() error {
 	if len(api.config.HTTPListenAddr) == 0 {
 		return nil
 	}
 
-	for _, listenMAddr := range api.config.HTTPListenAddr {
-		n, addr, err := manet.DialArgs(listenMAddr)
-		if err != nil {
-			return err
-		}
-
-		var l net.Listener
-		if api.config.TLS != nil {
-			l, err = tls.Listen(n, addr, api.config.TLS)
-		} else {
-			l, err = net.Listen(n, addr)
-		}
-		if err != nil {
-			return err
-		}
-		api.httpListeners = append(api.httpListeners, l)
+	listeners, err := listenHTTP(api.config.HTTPListenAddr, nil)
+	if err != nil {
+		return err
 	}
+	api.httpListeners = listeners
 	return nil
 }
 
@@ -283,13 +294,14 @@ func (api *API) addRoutes() {
 	)
 }
 
-// authHandler takes care of authentication either using basicAuth or JWT bearer tokens.
+// authHandler takes care of authentication either using basicAuth or JWT
+// bearer tokens, issued by the API or by the configured OIDC provider.
 func (api *API) authHandler(h http.Handler, lggr *logging.ZapEventLogger) http.Handler {
 
 	credentials := api.config.BasicAuthCredentials
 
 	// If no credentials are set, we do nothing.
-	if credentials == nil {
+	if credentials == nil && api.oidc == nil {
 		return h
 	}
 
@@ -304,6 +316,7 @@ func (api *API) authHandler(h http.Handler, lggr *logging.ZapEventLogger) http.H
 		username, password, okBasic := r.BasicAuth()
 		tokenString, okToken := parseBearerToken(r.Header.Get("Authorization"))
 
+		var info authInfo
 		switch {
 		case okBasic:
 			ok := verifyBasicAuth(credentials, username, password)
@@ -312,8 +325,10 @@ func (api *API) authHandler(h http.Handler, lggr *logging.ZapEventLogger) http.H
 				api.SendResponse(w, http.StatusUnauthorized, errors.New("unauthorized: access denied"), nil)
 				return
 			}
+			info = authInfo{user: username, role: RoleAdmin}
 		case okToken:
-			_, err := verifyToken(credentials, tokenString)
+			var err error
+			info, err = api.verifyBearerToken(r.Context(), tokenString)
 			if err != nil {
 				lggr.Debug(err)
 
@@ -324,17 +339,106 @@ func (api *API) authHandler(h http.Handler, lggr *logging.ZapEventLogger) http.H
 		default:
 			// No authentication provided, but needed
 			w.Header().Add("WWW-Authenticate", wwwAuthenticate("Bearer", "Restricted IPFS Cluster API", "", ""))
-			w.Header().Add("WWW-Authenticate", wwwAuthenticate("Basic", "Restricted IPFS Cluster API", "", ""))
+			if credentials != nil {
+				w.Header().Add("WWW-Authenticate", wwwAuthenticate("Basic", "Restricted IPFS Cluster API", "", ""))
+			}
 			api.SendResponse(w, http.StatusUnauthorized, errors.New("unauthorized: no auth provided"), nil)
 			return
 		}
 
+		if !info.allows(r) {
+			api.SendResponse(w, http.StatusForbidden, fmt.Errorf("forbidden: the %s role cannot perform this request", info.role), nil)
+			return
+		}
+
 		// If we are here, authentication worked.
-		h.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), authInfoKey{}, info)
+		h.ServeHTTP(w, r.WithContext(ctx))
 	}
 	return http.HandlerFunc(wrap)
 }
 
+// verifyBearerToken verifies a token issued by the OIDC provider, when it
+// claims to come from it, or by the API itself otherwise.
+func (api *API) verifyBearerToken(ctx context.Context, tokenString string) (authInfo, error) {
+	if api.oidc != nil && api.oidc.issued(tokenString) {
+		return api.oidc.verify(ctx, tokenString)
+	}
+	if api.config.BasicAuthCredentials == nil {
+		return authInfo{}, errors.New("token not issued by the OIDC provider")
+	}
+	token, err := verifyToken(api.config.BasicAuthCredentials, tokenString)
+	if err != nil {
+		return authInfo{}, err
+	}
+	return authInfo{
+		user: token.Claims.(*jwt.RegisteredClaims).Issuer,
+		role: RoleAdmin,
+	}, nil
+}
+
+type authInfoKey struct{}
+
+// AuthenticatedUser returns the user that authenticated the request, either
+// with basic auth, as the issuer of the token or as identified by the
+// token of the OIDC provider. It returns an empty string when
+// authentication is disabled.
+func AuthenticatedUser(ctx context.Context) string {
+	info, _ := ctx.Value(authInfoKey{}).(authInfo)
+	return info.user
+}
+
+// UploadPolicy returns the upload policy that applies to the user that
+// authenticated the request, or nil when there is none.
+func (api *API) UploadPolicy(r *http.Request) *types.UploadPolicy {
+	if len(api.config.UploadPolicies) == 0 {
+		return nil
+	}
+	if p, ok := api.config.UploadPolicies[AuthenticatedUser(r.Context())]; ok {
+		return &p
+	}
+	if p, ok := api.config.UploadPolicies["*"]; ok {
+		return &p
+	}
+	return nil
+}
+
+// Namespace returns the pin namespace that the user that authenticated the
+// request is restricted to, or an empty string when the user can work with
+// the pins of every namespace. A namespace given in the token of the OIDC
+// provider takes precedence over the configured ones.
+func (api *API) Namespace(r *http.Request) string {
+	if info, _ := r.Context().Value(authInfoKey{}).(authInfo); info.namespace != "" {
+		return info.namespace
+	}
+	if ns, ok := api.config.Namespaces[AuthenticatedUser(r.Context())]; ok {
+		return ns
+	}
+	return api.config.Namespaces["*"]
+}
+
+// Owners returns the owners of the pins made by the request: the user that
+// authenticated it, if any. Pins with owners are reference counted.
+func (api *API) Owners(r *http.Request) []string {
+	if user := AuthenticatedUser(r.Context()); user != "" {
+		return []string{user}
+	}
+	return nil
+}
+
+// Requester returns who made the request for the audit trail of pins: the
+// user that authenticated it, if any, and the address it came from.
+func (api *API) Requester(r *http.Request) types.PinRequester {
+	addr := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return types.PinRequester{
+		User:    AuthenticatedUser(r.Context()),
+		Address: addr,
+	}
+}
+
 func parseBearerToken(authHeader string) (string, bool) {
 	const prefix = "Bearer "
 	if len(authHeader) < len(prefix) || !strings.EqualFold(authHeader[:len(prefix)], prefix) {
@@ -416,11 +520,17 @@ func strictSlashHandler(h http.Handler) http.Handler {
 }
 
 func (api *API) run(ctx context.Context) {
+	server, tlsCfg := api.httpServer, api.config.TLS
 	api.wg.Add(len(api.httpListeners))
 	for _, l := range api.httpListeners {
-		go func(l net.Listener) {
+		go func(l *sharedListener) {
 			defer api.wg.Done()
-			api.runHTTPServer(ctx, l)
+			select {
+			case <-api.rpcReady:
+			case <-api.ctx.Done():
+				return
+			}
+			api.runHTTPServer(server, l, tlsCfg)
 		}(l)
 	}
 
@@ -433,14 +543,8 @@ func (api *API) run(ctx context.Context) {
 	}
 }
 
-// runs in goroutine from run()
-func (api *API) runHTTPServer(ctx context.Context, l net.Listener) {
-	select {
-	case <-api.rpcReady:
-	case <-api.ctx.Done():
-		return
-	}
-
+// runs in goroutine from run() and ReloadHTTP()
+func (api *API) runHTTPServer(s *http.Server, l *sharedListener, tlsCfg *tls.Config) {
 	maddr, err := manet.FromNetAddr(l.Addr())
 	if err != nil {
 		api.config.Logger.Error(err)
@@ -452,8 +556,8 @@ func (api *API) runHTTPServer(ctx context.Context, l net.Listener) {
 	}
 
 	api.config.Logger.Infof(strings.ToUpper(api.config.ConfigKey)+" (HTTP"+authInfo+"): %s", maddr)
-	err = api.server.Serve(l)
-	if err != nil && !strings.Contains(err.Error(), "closed network connection") {
+	err = s.Serve(l.serve(tlsCfg))
+	if err != nil && err != http.ErrServerClosed && !errors.Is(err, net.ErrClosed) {
 		api.config.Logger.Error(err)
 	}
 }
@@ -473,7 +577,7 @@ func (api *API) runLibp2pServer(ctx context.Context) {
 
 	api.config.Logger.Infof(strings.ToUpper(api.config.ConfigKey)+" (libp2p-http): ENABLED. Listening on:\n%s\n", listenMsg)
 
-	err := api.server.Serve(api.libp2pListener)
+	err := api.libp2pServer.Serve(api.libp2pListener)
 	if err != nil && !strings.Contains(err.Error(), "context canceled") {
 		api.config.Logger.Error(err)
 	}
@@ -498,11 +602,13 @@ func (api *API) Shutdown(ctx context.Context) error {
 	close(api.rpcReady)
 
 	// Cancel any outstanding ops
-	api.server.SetKeepAlivesEnabled(false)
+	api.SetKeepAlivesEnabled(false)
 
+	api.httpMux.Lock()
 	for _, l := range api.httpListeners {
 		l.Close()
 	}
+	api.httpMux.Unlock()
 
 	if api.libp2pListener != nil {
 		api.libp2pListener.Close()
@@ -518,6 +624,80 @@ func (api *API) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// ReloadHTTP replaces the HTTP server with a new one which uses the listen
+// addresses, TLS configuration and timeouts from the given configuration.
+// Listeners for addresses that remain configured are handed over to the new
+// server without being closed, and new ones are opened before anything is
+// stopped. Requests in flight in the old server are allowed to finish until
+// the given context is cancelled. The libp2p endpoint is not affected.
+func (api *API) ReloadHTTP(ctx context.Context, cfg *Config) error {
+	ctx, span := trace.StartSpan(ctx, "api/ReloadHTTP")
+	defer span.End()
+
+	api.shutdownLock.Lock()
+	defer api.shutdownLock.Unlock()
+
+	if api.shutdown {
+		return errors.New("the API is shut down")
+	}
+	if api.rpcClient == nil {
+		return errors.New("the API is not ready yet")
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if len(cfg.HTTPListenAddr) == 0 && api.libp2pListener == nil {
+		return ErrNoEndpointsEnabled
+	}
+
+	api.httpMux.Lock()
+	oldServer, oldListeners := api.httpServer, api.httpListeners
+	listeners, err := listenHTTP(cfg.HTTPListenAddr, oldListeners)
+	if err != nil {
+		api.httpMux.Unlock()
+		return err
+	}
+	server := api.newServer(cfg)
+	api.httpServer, api.httpListeners = server, listeners
+	api.config.HTTPListenAddr = cfg.HTTPListenAddr
+	api.config.TLS = cfg.TLS
+	api.config.PathSSLCertFile = cfg.PathSSLCertFile
+	api.config.PathSSLKeyFile = cfg.PathSSLKeyFile
+	api.httpMux.Unlock()
+
+	api.wg.Add(len(listeners))
+	for _, l := range listeners {
+		go func(l *sharedListener) {
+			defer api.wg.Done()
+			api.runHTTPServer(server, l, cfg.TLS)
+		}(l)
+	}
+
+	// Close the listeners that are no longer used, then stop the old
+	// server and wait for its requests to finish.
+	for _, l := range oldListeners {
+		if !containsListener(listeners, l) {
+			l.Close()
+		}
+	}
+	err = oldServer.Shutdown(ctx)
+	if err != nil {
+		api.config.Logger.Warnf("error stopping the previous HTTP server: %s", err)
+		oldServer.Close()
+	}
+	api.config.Logger.Infof("%s: HTTP endpoint reloaded", api.config.ConfigKey)
+	return nil
+}
+
+func containsListener(listeners []*sharedListener, l *sharedListener) bool {
+	for _, l2 := range listeners {
+		if l2 == l {
+			return true
+		}
+	}
+	return false
+}
+
 // SetClient makes the component ready to perform RPC
 // requests.
 func (api *API) SetClient(c *rpc.Client) {
@@ -579,6 +759,75 @@ func (api *API) ParseCidOrFail(w http.ResponseWriter, r *http.Request) types.Pin
 	return pin
 }
 
+// NamespaceOrFail returns the pin namespace to use for a request which asks
+// for the given one: the namespace that the user is restricted to, if any.
+// It makes the request fail when the user asks for a different namespace.
+func (api *API) NamespaceOrFail(w http.ResponseWriter, r *http.Request, requested string) (string, bool) {
+	ns := api.Namespace(r)
+	if ns == "" {
+		return requested, true
+	}
+	if requested != "" && requested != ns {
+		api.SendResponse(w, http.StatusForbidden, fmt.Errorf("namespace %s is not accessible with these credentials", requested), nil)
+		return "", false
+	}
+	return ns, true
+}
+
+// UnrestrictedOrFail returns true when the user that authenticated the
+// request can work with the pins of every namespace. Otherwise it makes the
+// request fail as forbidden. It guards the requests that work with or tell
+// about every pin and cannot be limited to a namespace.
+func (api *API) UnrestrictedOrFail(w http.ResponseWriter, r *http.Request) bool {
+	if ns := api.Namespace(r); ns != "" {
+		api.SendResponse(w, http.StatusForbidden, fmt.Errorf("forbidden: credentials restricted to namespace %s cannot perform this request", ns), nil)
+		return false
+	}
+	return true
+}
+
+// AdminOrFail returns true when the request comes from an administrator
+// who can work with the pins of every namespace. Otherwise it makes the
+// request fail as forbidden. Requests are not restricted when
+// authentication is disabled and no namespaces are configured.
+func (api *API) AdminOrFail(w http.ResponseWriter, r *http.Request) bool {
+	info, ok := r.Context().Value(authInfoKey{}).(authInfo)
+	if ok && info.role != RoleAdmin {
+		api.SendResponse(w, http.StatusForbidden, fmt.Errorf("forbidden: the %s role cannot perform this request", info.role), nil)
+		return false
+	}
+	return api.UnrestrictedOrFail(w, r)
+}
+
+// OwnsPinOrFail returns true when the user that authenticated the request
+// can work with the pin for the given CID. Otherwise it makes the request
+// fail as if the pin did not exist, since the pins of other namespaces are
+// not visible.
+func (api *API) OwnsPinOrFail(w http.ResponseWriter, r *http.Request, c types.Cid) bool {
+	ns := api.Namespace(r)
+	if ns == "" {
+		return true
+	}
+
+	var pin types.Pin
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"PinGet",
+		c,
+		&pin,
+	)
+	if err == nil && pin.Namespace != ns {
+		err = state.ErrNotFound
+	}
+	if err != nil {
+		api.SendResponse(w, SetStatusAutomatically, err, nil)
+		return false
+	}
+	return true
+}
+
 // ParsePidOrFail parses a PID and returns it or makes the request fail.
 func (api *API) ParsePidOrFail(w http.ResponseWriter, r *http.Request) peer.ID {
 	vars := mux.Vars(r)
@@ -668,9 +917,19 @@ func (api *API) SendResponse(
 	// Send an error
 	if err != nil {
 		if status == SetStatusAutomatically || status < 400 {
-			if err.Error() == state.ErrNotFound.Error() {
+			switch {
+			case err.Error() == state.ErrNotFound.Error():
 				status = http.StatusNotFound
-			} else {
+			case strings.HasPrefix(err.Error(), types.ErrPinTooLarge.Error()):
+				// errors lose their type over RPC.
+				status = http.StatusRequestEntityTooLarge
+			case strings.HasPrefix(err.Error(), types.ErrPinNameConflict.Error()):
+				status = http.StatusConflict
+			case err.Error() == types.ErrHandoffNotFound.Error():
+				status = http.StatusNotFound
+			case err.Error() == state.ErrScheduleNotFound.Error():
+				status = http.StatusNotFound
+			default:
 				status = http.StatusInternalServerError
 			}
 		}
@@ -817,6 +1076,9 @@ func (api *API) SetHeaders(w http.ResponseWriter) {
 // on a random port (0). Returns error when the HTTP endpoint
 // is not enabled.
 func (api *API) HTTPAddresses() ([]string, error) {
+	api.httpMux.RLock()
+	defer api.httpMux.RUnlock()
+
 	if len(api.httpListeners) == 0 {
 		return nil, ErrHTTPEndpointNotEnabled
 	}
@@ -845,9 +1107,32 @@ func (api *API) Headers() map[string][]string {
 // SetKeepAlivesEnabled controls the HTTP server Keep Alive settings.  Useful
 // for testing.
 func (api *API) SetKeepAlivesEnabled(b bool) {
-	api.server.SetKeepAlivesEnabled(b)
+	api.httpMux.RLock()
+	api.httpServer.SetKeepAlivesEnabled(b)
+	api.httpMux.RUnlock()
+	api.libp2pServer.SetKeepAlivesEnabled(b)
+}
+
+// AddHealthCheck registers a function that is run by the HealthHandler. When
+// any of the health checks returns an error, the health endpoint responds
+// with 503 (Service Unavailable) and the error.
+func (api *API) AddHealthCheck(check func() error) {
+	api.healthChecksMux.Lock()
+	defer api.healthChecksMux.Unlock()
+	api.healthChecks = append(api.healthChecks, check)
 }
 
+// HealthHandler responds with 204 (No Content) when all health checks pass.
 func (api *API) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	api.healthChecksMux.RLock()
+	checks := api.healthChecks
+	api.healthChecksMux.RUnlock()
+
+	for _, check := range checks {
+		if err := check(); err != nil {
+			api.SendResponse(w, http.StatusServiceUnavailable, err, nil)
+			return
+		}
+	}
 	api.SendResponse(w, http.StatusNoContent, nil, nil)
 }
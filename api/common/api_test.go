@@ -2,13 +2,17 @@ package common
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/http/httputil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -48,6 +52,16 @@ func routes(c *rpc.Client) []Route {
 				w.Write([]byte(`{ "thisis": "atest" }`))
 			},
 		},
+		{
+			"Slow",
+			"GET",
+			"/slow",
+			func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(500 * time.Millisecond)
+				w.Header().Add("Content-Type", "application/json")
+				w.Write([]byte(`{ "thisis": "slow" }`))
+			},
+		},
 	}
 
 }
@@ -68,7 +82,7 @@ func testAPIwithConfig(t *testing.T, cfg *Config, name string) *API {
 	}
 
 	// No keep alive for tests
-	rest.server.SetKeepAlivesEnabled(false)
+	rest.SetKeepAlivesEnabled(false)
 	rest.SetClient(rpctest.NewMockRPCClient(t))
 
 	return rest
@@ -119,6 +133,83 @@ func TestAPIShutdown(t *testing.T) {
 
 }
 
+func TestReloadHTTP(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	get := func(addr, path string) (string, error) {
+		resp, err := http.Get("http://" + addr + path)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	addrs, _ := rest.HTTPAddresses()
+	oldAddr := addrs[0]
+
+	// A request in flight during the reload is not interrupted.
+	slowErr := make(chan error, 1)
+	go func() {
+		body, err := get(oldAddr, "/slow")
+		if err == nil && !strings.Contains(body, "slow") {
+			err = fmt.Errorf("unexpected body: %s", body)
+		}
+		slowErr <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	// Same addresses: the listener is kept.
+	cfg := newDefaultTestConfig(t)
+	cfg.HTTPListenAddr = rest.config.HTTPListenAddr
+	if err := rest.ReloadHTTP(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-slowErr; err != nil {
+		t.Error("the request in flight should have finished:", err)
+	}
+	if _, err := get(oldAddr, "/test"); err != nil {
+		t.Error("the address should still be served:", err)
+	}
+
+	// New address: the old listener is closed.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newAddr := l.Addr().String()
+	l.Close()
+
+	cfg = newDefaultTestConfig(t)
+	cfg.HTTPListenAddr = []ma.Multiaddr{ma.StringCast("/ip4/127.0.0.1/tcp/" + strings.Split(newAddr, ":")[1])}
+	if err := rest.ReloadHTTP(ctx, cfg); err != nil {
+		t.Fatal(err)
+	}
+	addrs, _ = rest.HTTPAddresses()
+	if len(addrs) != 1 || addrs[0] != newAddr {
+		t.Fatalf("unexpected addresses after reload: %s", addrs)
+	}
+	if body, err := get(newAddr, "/test"); err != nil || !strings.Contains(body, "atest") {
+		t.Error("the new address should be served:", err)
+	}
+	if _, err := get(oldAddr, "/test"); err == nil {
+		t.Error("the old address should not be served anymore")
+	}
+
+	// Errors opening listeners leave things as they were.
+	cfg = newDefaultTestConfig(t)
+	cfg.HTTPListenAddr = []ma.Multiaddr{ma.StringCast("/ip4/1.2.3.4/tcp/1234")}
+	if err := rest.ReloadHTTP(ctx, cfg); err == nil {
+		t.Error("expected an error listening on an unavailable address")
+	}
+	if _, err := get(newAddr, "/test"); err != nil {
+		t.Error("the address should still be served after a failed reload:", err)
+	}
+}
+
 func TestHTTPSTestEndpoint(t *testing.T) {
 	ctx := context.Background()
 	rest := testAPI(t)
@@ -642,3 +733,31 @@ func TestLimitMaxHeaderSize(t *testing.T) {
 		test.BothEndpoints(t, tc.getTestFunction(rest))
 	}
 }
+
+func TestHealthChecks(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	rec := httptest.NewRecorder()
+	rest.HealthHandler(rec, httptest.NewRequest("GET", "/health", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected 204 without health checks, got %d", rec.Code)
+	}
+
+	var healthErr error
+	rest.AddHealthCheck(func() error { return healthErr })
+
+	rec = httptest.NewRecorder()
+	rest.HealthHandler(rec, httptest.NewRequest("GET", "/health", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected 204 with passing health check, got %d", rec.Code)
+	}
+
+	healthErr = errors.New("unhealthy")
+	rec = httptest.NewRecorder()
+	rest.HealthHandler(rec, httptest.NewRequest("GET", "/health", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 with failing health check, got %d", rec.Code)
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/kelseyhightower/envconfig"
 	"github.com/rs/cors"
 
+	types "github.com/ipfs-cluster/ipfs-cluster/api"
 	"github.com/ipfs-cluster/ipfs-cluster/config"
 )
 
@@ -86,6 +87,25 @@ type Config struct {
 	// which are authorized to use Basic Authentication
 	BasicAuthCredentials map[string]string
 
+	// UploadPolicies sets the rules that content added through the API
+	// must follow for each user, as authenticated with basic auth or
+	// with a token issued by them. The "*" entry applies to users without
+	// a policy of their own.
+	UploadPolicies map[string]types.UploadPolicy
+
+	// Namespaces restricts users, as authenticated with basic auth or
+	// with a token issued by them, to the pins of one namespace: the
+	// pins they add are placed in it and they cannot see or remove the
+	// pins of other namespaces. The "*" entry applies to users without
+	// a namespace of their own. Users without a namespace, or with an
+	// empty one, can work with the pins of every namespace.
+	Namespaces map[string]string
+
+	// OIDC enables the authentication of users with the JWT bearer
+	// tokens of an OAuth2/OpenID Connect provider, on top of basic auth
+	// and the tokens issued by the API. Disabled when nil.
+	OIDC *OIDCConfig
+
 	// HTTPLogFile is path of the file that would save HTTP API logs. If this
 	// path is empty, HTTP logs would be sent to standard output. This path
 	// should either be absolute or relative to cluster base directory. Its
@@ -122,9 +142,12 @@ type jsonConfig struct {
 	ID                       string         `json:"id,omitempty"`
 	PrivateKey               string         `json:"private_key,omitempty" hidden:"true"`
 
-	BasicAuthCredentials map[string]string   `json:"basic_auth_credentials"  hidden:"true"`
-	HTTPLogFile          string              `json:"http_log_file"`
-	Headers              map[string][]string `json:"headers"`
+	BasicAuthCredentials map[string]string             `json:"basic_auth_credentials"  hidden:"true"`
+	UploadPolicies       map[string]types.UploadPolicy `json:"upload_policies,omitempty"`
+	Namespaces           map[string]string             `json:"namespaces,omitempty"`
+	OIDC                 *OIDCConfig                   `json:"oidc,omitempty"`
+	HTTPLogFile          string                        `json:"http_log_file"`
+	Headers              map[string][]string           `json:"headers"`
 
 	CORSAllowedOrigins   []string `json:"cors_allowed_origins"`
 	CORSAllowedMethods   []string `json:"cors_allowed_methods"`
@@ -188,6 +211,18 @@ func (cfg *Config) Validate() error {
 		return errors.New(cfg.ConfigKey + ".cors_max_age is invalid")
 	}
 
+	if cfg.OIDC != nil {
+		if err := cfg.OIDC.Validate(); err != nil {
+			return fmt.Errorf("%s.oidc: %w", cfg.ConfigKey, err)
+		}
+	}
+
+	for user, policy := range cfg.UploadPolicies {
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("%s.upload_policies[%s]: %w", cfg.ConfigKey, user, err)
+		}
+	}
+
 	return cfg.validateLibp2p()
 }
 
@@ -236,6 +271,13 @@ func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 
 	// Other options
 	cfg.BasicAuthCredentials = jcfg.BasicAuthCredentials
+	cfg.UploadPolicies = jcfg.UploadPolicies
+	cfg.Namespaces = jcfg.Namespaces
+	// Environment variables may set an empty OIDC configuration.
+	cfg.OIDC = nil
+	if jcfg.OIDC != nil && !jcfg.OIDC.empty() {
+		cfg.OIDC = jcfg.OIDC
+	}
 	cfg.HTTPLogFile = jcfg.HTTPLogFile
 	cfg.Headers = jcfg.Headers
 
@@ -364,6 +406,12 @@ func (cfg *Config) ToJSON() (raw []byte, err error) {
 	return
 }
 
+// JSONKeys returns the keys of the JSON representation of this
+// configuration, including those omitted when empty.
+func (cfg *Config) JSONKeys() []string {
+	return config.JSONKeys(jsonConfig{})
+}
+
 func (cfg *Config) toJSONConfig() (jcfg *jsonConfig, err error) {
 	// Multiaddress String() may panic
 	defer func() {
@@ -392,6 +440,9 @@ func (cfg *Config) toJSONConfig() (jcfg *jsonConfig, err error) {
 		IdleTimeout:            cfg.IdleTimeout.String(),
 		MaxHeaderBytes:         cfg.MaxHeaderBytes,
 		BasicAuthCredentials:   cfg.BasicAuthCredentials,
+		UploadPolicies:         cfg.UploadPolicies,
+		Namespaces:             cfg.Namespaces,
+		OIDC:                   cfg.OIDC,
 		HTTPLogFile:            cfg.HTTPLogFile,
 		Headers:                cfg.Headers,
 		CORSAllowedOrigins:     cfg.CORSAllowedOrigins,
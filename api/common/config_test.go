@@ -217,6 +217,71 @@ func TestLoadJSON(t *testing.T) {
 	if err == nil {
 		t.Error("expected error with MaxHeaderBytes")
 	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.UploadPolicies = map[string]types.UploadPolicy{
+		"*": {DenyTypes: []string{""}},
+	}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with upload policies")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.OIDC = &OIDCConfig{
+		Issuer: "https://sso.example.com",
+		Roles:  map[string]string{"ops": "root"},
+	}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with an unknown OIDC role")
+	}
+
+	j.OIDC = &OIDCConfig{Audience: "cluster"}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with no OIDC issuer")
+	}
+
+	j.OIDC = &OIDCConfig{
+		Issuer: "https://sso.example.com",
+		Roles:  map[string]string{"ops": RoleAdmin},
+	}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with no OIDC audience")
+	}
+
+	j.OIDC = &OIDCConfig{
+		Issuer:   "https://sso.example.com",
+		Audience: "cluster",
+	}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error with no OIDC roles")
+	}
+
+	j.OIDC = &OIDCConfig{
+		Issuer:         "https://sso.example.com",
+		Audience:       "cluster",
+		Roles:          map[string]string{"ops": RoleAdmin},
+		NamespaceClaim: "ns",
+	}
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.OIDC == nil || cfg.OIDC.Roles["ops"] != RoleAdmin || cfg.OIDC.NamespaceClaim != "ns" {
+		t.Errorf("unexpected OIDC config: %+v", cfg.OIDC)
+	}
 }
 
 func TestApplyEnvVars(t *testing.T) {
@@ -246,6 +311,10 @@ func TestApplyEnvVars(t *testing.T) {
 	if gotpasswd := cfg.BasicAuthCredentials[user1]; gotpasswd != user1pass {
 		t.Errorf("password not what was set in env var, got: %s, want: %s", gotpasswd, user1pass)
 	}
+
+	if cfg.OIDC != nil {
+		t.Errorf("OIDC should not be enabled without OIDC variables: %+v", cfg.OIDC)
+	}
 }
 
 func TestLibp2pConfig(t *testing.T) {
@@ -0,0 +1,159 @@
+package common
+
+import (
+	"crypto/tls"
+	"net"
+	"sync"
+	"time"
+
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// sharedListener accepts connections on a network listener and hands them
+// to the server currently serving it. This allows replacing the server
+// without closing the socket, so that no connections are refused while
+// the API is reloaded.
+type sharedListener struct {
+	net.Listener
+	maddr string
+
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newSharedListener(maddr ma.Multiaddr) (*sharedListener, error) {
+	n, addr, err := manet.DialArgs(maddr)
+	if err != nil {
+		return nil, err
+	}
+	l, err := net.Listen(n, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	sl := &sharedListener{
+		Listener: l,
+		maddr:    maddr.String(),
+		conns:    make(chan net.Conn),
+		done:     make(chan struct{}),
+	}
+	go sl.acceptLoop()
+	return sl, nil
+}
+
+func (sl *sharedListener) acceptLoop() {
+	defer sl.close()
+	var delay time.Duration
+	for {
+		c, err := sl.Listener.Accept()
+		if err != nil {
+			// Back off on temporary errors like http.Server does.
+			if ne, ok := err.(net.Error); ok && ne.Temporary() { // nolint:staticcheck
+				if delay == 0 {
+					delay = 5 * time.Millisecond
+				} else if delay *= 2; delay > time.Second {
+					delay = time.Second
+				}
+				select {
+				case <-time.After(delay):
+					continue
+				case <-sl.done:
+					return
+				}
+			}
+			return
+		}
+		delay = 0
+		select {
+		case sl.conns <- c:
+		case <-sl.done:
+			c.Close()
+			return
+		}
+	}
+}
+
+func (sl *sharedListener) close() {
+	sl.closeOnce.Do(func() {
+		close(sl.done)
+		sl.Listener.Close()
+	})
+}
+
+// Close closes the underlying network listener.
+func (sl *sharedListener) Close() error {
+	sl.close()
+	return nil
+}
+
+// serve returns a listener for one server. Closing it (i.e. when the server
+// shuts down) does not close the shared listener. When tlsCfg is not nil,
+// connections are wrapped with TLS.
+func (sl *sharedListener) serve(tlsCfg *tls.Config) net.Listener {
+	var l net.Listener = &serverListener{
+		shared: sl,
+		closed: make(chan struct{}),
+	}
+	if tlsCfg != nil {
+		l = tls.NewListener(l, tlsCfg)
+	}
+	return l
+}
+
+// serverListener is the view of a sharedListener used by a single server.
+type serverListener struct {
+	shared    *sharedListener
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *serverListener) Accept() (net.Conn, error) {
+	select {
+	case <-l.closed:
+		return nil, net.ErrClosed
+	case <-l.shared.done:
+		return nil, net.ErrClosed
+	case c := <-l.shared.conns:
+		return c, nil
+	}
+}
+
+func (l *serverListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *serverListener) Addr() net.Addr {
+	return l.shared.Addr()
+}
+
+// listenHTTP returns shared listeners for the given addresses, reusing the
+// existing ones with the same address. The new listeners are closed if
+// any of them cannot be opened.
+func listenHTTP(addrs []ma.Multiaddr, existing []*sharedListener) ([]*sharedListener, error) {
+	var listeners, opened []*sharedListener
+	for _, maddr := range addrs {
+		var sl *sharedListener
+		for _, e := range existing {
+			if e.maddr == maddr.String() {
+				sl = e
+				break
+			}
+		}
+		if sl == nil {
+			var err error
+			sl, err = newSharedListener(maddr)
+			if err != nil {
+				for _, o := range opened {
+					o.Close()
+				}
+				return nil, err
+			}
+			opened = append(opened, sl)
+		}
+		listeners = append(listeners, sl)
+	}
+	return listeners, nil
+}
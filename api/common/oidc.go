@@ -0,0 +1,489 @@
+package common
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+	logging "github.com/ipfs/go-log/v2"
+)
+
+// Roles that users authenticated with OIDC tokens can be given. Users
+// authenticated with basic auth, or with the tokens issued by the API,
+// are admins.
+const (
+	// RoleAdmin can use every route.
+	RoleAdmin = "admin"
+	// RoleUser can use every route but the /admin ones.
+	RoleUser = "user"
+	// RoleReader can only make GET and HEAD requests outside /admin.
+	RoleReader = "reader"
+)
+
+// rank of the roles, so that users with several roles get the most
+// privileged one.
+var roleRanks = map[string]int{
+	RoleReader: 1,
+	RoleUser:   2,
+	RoleAdmin:  3,
+}
+
+// OIDCUserPrefix is prepended to the users identified by the tokens of the
+// OIDC provider, so that they cannot be mistaken for basic auth users with
+// the same name.
+const OIDCUserPrefix = "oidc:"
+
+// Default values for OIDCConfig.
+const (
+	DefaultOIDCUserClaim  = "sub"
+	DefaultOIDCRolesClaim = "roles"
+)
+
+var (
+	// how long the keys of the issuer are used before fetching them
+	// again.
+	jwksMaxAge = time.Hour
+	// the keys are fetched again when a token is signed with an unknown
+	// key, but not more often than this.
+	jwksMinRefreshInterval = time.Minute
+	jwksFetchTimeout       = 10 * time.Second
+)
+
+// the algorithms accepted for the tokens issued by OIDC providers.
+var oidcSigningMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// OIDCConfig enables the authentication of users with JWT bearer tokens
+// issued by an OAuth2/OpenID Connect provider, like those of a Single
+// Sign-On service. Tokens are verified with the keys published by the
+// issuer and their claims identify the user and, optionally, the role and
+// the namespace given to them.
+type OIDCConfig struct {
+	// Issuer is the URL of the provider, which must match the "iss"
+	// claim of the tokens.
+	Issuer string `json:"issuer"`
+	// JWKSURL is where the provider publishes the keys that sign the
+	// tokens. It is discovered from the OpenID configuration of the
+	// issuer when empty.
+	JWKSURL string `json:"jwks_url,omitempty"`
+	// Audience must be one of the audiences of the tokens, so that
+	// tokens that the issuer gives to other applications are rejected.
+	Audience string `json:"audience"`
+	// UserClaim is the claim that identifies users ("sub" by default).
+	// Users are named after it with the "oidc:" prefix (i.e.
+	// "oidc:alice"). They are subject to the upload policies and
+	// namespaces set for that name and own the items they pin, like
+	// basic auth users.
+	UserClaim string `json:"user_claim,omitempty"`
+	// RolesClaim is the claim that holds the roles or groups of the user,
+	// as a string or a list ("roles" by default). Nested claims are
+	// given as a dot-separated path, like "realm_access.roles".
+	RolesClaim string `json:"roles_claim,omitempty"`
+	// Roles maps the values of the roles claim to the roles of the API:
+	// "admin", "user" or "reader". Users get the most privileged of their
+	// roles and tokens without any mapped role are rejected. At least one
+	// role must be mapped.
+	Roles map[string]string `json:"roles"`
+	// NamespaceClaim, when set, is the claim that holds the namespace
+	// the user is restricted to. It takes precedence over the namespaces
+	// configured for the user, and tokens without it are rejected.
+	NamespaceClaim string `json:"namespace_claim,omitempty"`
+}
+
+// Validate checks that the configuration is usable.
+func (oc *OIDCConfig) Validate() error {
+	if oc.Issuer == "" {
+		return errors.New("issuer is not set")
+	}
+	if oc.Audience == "" {
+		return errors.New("audience is not set")
+	}
+	if len(oc.Roles) == 0 {
+		return errors.New("roles is empty: no user would be authorized")
+	}
+	for value, role := range oc.Roles {
+		if _, ok := roleRanks[role]; !ok {
+			return fmt.Errorf("roles[%s]: unknown role %q", value, role)
+		}
+	}
+	return nil
+}
+
+func (oc *OIDCConfig) empty() bool {
+	return oc.Issuer == "" && oc.JWKSURL == "" && oc.Audience == "" &&
+		oc.UserClaim == "" && oc.RolesClaim == "" && len(oc.Roles) == 0 &&
+		oc.NamespaceClaim == ""
+}
+
+func (oc *OIDCConfig) userClaim() string {
+	if oc.UserClaim == "" {
+		return DefaultOIDCUserClaim
+	}
+	return oc.UserClaim
+}
+
+func (oc *OIDCConfig) rolesClaim() string {
+	if oc.RolesClaim == "" {
+		return DefaultOIDCRolesClaim
+	}
+	return oc.RolesClaim
+}
+
+// authInfo is what the authentication of a request tells about the user.
+type authInfo struct {
+	user string
+	role string
+	// namespace is only set when it comes from the credentials.
+	namespace string
+}
+
+// allows returns whether the role of the user permits the request.
+func (ai authInfo) allows(r *http.Request) bool {
+	admin := r.URL.Path == "/admin" || strings.HasPrefix(r.URL.Path, "/admin/")
+	switch ai.role {
+	case RoleAdmin:
+		return true
+	case RoleUser:
+		return !admin
+	case RoleReader:
+		return !admin && (r.Method == http.MethodGet || r.Method == http.MethodHead)
+	default:
+		return false
+	}
+}
+
+// oidcVerifier verifies the tokens issued by an OIDC provider. The keys of
+// the provider are fetched when needed and cached.
+type oidcVerifier struct {
+	cfg    *OIDCConfig
+	client *http.Client
+	logger *logging.ZapEventLogger
+
+	mu      sync.Mutex
+	jwksURL string
+	keys    map[string]interface{}
+	fetched time.Time
+	// closed when the fetch in progress, if any, finishes.
+	fetching chan struct{}
+}
+
+func newOIDCVerifier(cfg *OIDCConfig, logger *logging.ZapEventLogger) *oidcVerifier {
+	return &oidcVerifier{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: jwksFetchTimeout},
+		logger:  logger,
+		jwksURL: cfg.JWKSURL,
+	}
+}
+
+// issued returns whether the token claims to come from the issuer, without
+// verifying it.
+func (v *oidcVerifier) issued(tokenString string) bool {
+	claims := jwt.MapClaims{}
+	_, _, err := jwt.NewParser().ParseUnverified(tokenString, claims)
+	return err == nil && claims.VerifyIssuer(v.cfg.Issuer, true)
+}
+
+// verify checks the signature and the standard claims of a token and
+// returns the information about the user that it carries.
+func (v *oidcVerifier) verify(ctx context.Context, tokenString string) (authInfo, error) {
+	var info authInfo
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(
+		tokenString,
+		claims,
+		func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return v.key(ctx, kid)
+		},
+		jwt.WithValidMethods(oidcSigningMethods),
+	)
+	if err != nil {
+		return info, err
+	}
+
+	now := time.Now().Unix()
+	switch {
+	case !claims.VerifyIssuer(v.cfg.Issuer, true):
+		return info, errors.New("unexpected issuer")
+	case !claims.VerifyExpiresAt(now, true):
+		return info, errors.New("token is expired or has no expiration")
+	case !claims.VerifyAudience(v.cfg.Audience, true):
+		return info, errors.New("unexpected audience")
+	}
+
+	user, _ := claimValue(claims, v.cfg.userClaim()).(string)
+	if user == "" {
+		return info, fmt.Errorf("token has no %s claim", v.cfg.userClaim())
+	}
+	info.user = OIDCUserPrefix + user
+
+	for _, value := range claimStrings(claimValue(claims, v.cfg.rolesClaim())) {
+		if role := v.cfg.Roles[value]; roleRanks[role] > roleRanks[info.role] {
+			info.role = role
+		}
+	}
+	if info.role == "" {
+		return info, fmt.Errorf("user %s has no role", info.user)
+	}
+
+	if v.cfg.NamespaceClaim != "" {
+		info.namespace, _ = claimValue(claims, v.cfg.NamespaceClaim).(string)
+		// Otherwise the user would not be restricted to any
+		// namespace, or get the ones configured for them.
+		if info.namespace == "" {
+			return info, fmt.Errorf("token has no %s claim", v.cfg.NamespaceClaim)
+		}
+	}
+	return info, nil
+}
+
+// key returns the public key with the given ID, fetching the keys of the
+// issuer when they are old or the key is unknown. Tokens without a key ID
+// can be verified when the issuer has a single key.
+func (v *oidcVerifier) key(ctx context.Context, kid string) (interface{}, error) {
+	if err := v.refreshKeys(ctx, kid); err != nil {
+		return nil, fmt.Errorf("error fetching the keys of the issuer: %w", err)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// refreshKeys fetches the keys of the issuer when they are old or the given
+// key is unknown. The lock is not held while fetching: requests wait for
+// the fetch in progress instead of starting another one.
+func (v *oidcVerifier) refreshKeys(ctx context.Context, kid string) error {
+	for {
+		v.mu.Lock()
+		if wait := v.fetching; wait != nil {
+			v.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-wait:
+			}
+			continue
+		}
+
+		since := time.Since(v.fetched)
+		_, known := v.keys[kid]
+		if since <= jwksMaxAge && (known || since <= jwksMinRefreshInterval) {
+			v.mu.Unlock()
+			return nil
+		}
+
+		done := make(chan struct{})
+		v.fetching = done
+		// Avoid hammering the issuer when it fails.
+		v.fetched = time.Now()
+		jwksURL := v.jwksURL
+		v.mu.Unlock()
+
+		jwksURL, keys, err := v.fetchKeys(ctx, jwksURL)
+
+		v.mu.Lock()
+		if err == nil {
+			v.jwksURL = jwksURL
+			v.keys = keys
+		}
+		v.fetching = nil
+		close(done)
+		v.mu.Unlock()
+		return err
+	}
+}
+
+// fetchKeys downloads the keys of the issuer from the given URL,
+// discovering where they are published first if it is empty. It returns the
+// URL used and the keys.
+func (v *oidcVerifier) fetchKeys(ctx context.Context, jwksURL string) (string, map[string]interface{}, error) {
+	if jwksURL == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		u := strings.TrimSuffix(v.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+		if err := v.getJSON(ctx, u, &discovery); err != nil {
+			return "", nil, err
+		}
+		if discovery.JWKSURI == "" {
+			return "", nil, errors.New("the OpenID configuration has no jwks_uri")
+		}
+		jwksURL = discovery.JWKSURI
+	}
+
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := v.getJSON(ctx, jwksURL, &jwks); err != nil {
+		return "", nil, err
+	}
+
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			v.logger.Warnf("ignoring key %q of the issuer: %s", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return jwksURL, keys, nil
+}
+
+func (v *oidcVerifier) getJSON(ctx context.Context, u string, obj interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: unexpected status %s", u, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(obj)
+}
+
+// jwk is a JSON Web Key (RFC 7517) with the parameters of RSA, elliptic
+// curve and Ed25519 public keys.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("invalid EC point")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("missing key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// claimValue returns the value of a claim given by a dot-separated path.
+func claimValue(claims jwt.MapClaims, path string) interface{} {
+	var v interface{} = map[string]interface{}(claims)
+	for _, name := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[name]
+	}
+	return v
+}
+
+// claimStrings returns the strings in a claim which is either a string or
+// a list.
+func claimStrings(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		strs := make([]string, 0, len(v))
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				strs = append(strs, s)
+			}
+		}
+		return strs
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,291 @@
+package common
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+	logging "github.com/ipfs/go-log/v2"
+)
+
+// testIssuer is an OIDC provider which publishes an RSA and an EC key.
+type testIssuer struct {
+	*httptest.Server
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+	// number of times the keys were requested.
+	keyFetches int32
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iss := &testIssuer{rsaKey: rsaKey, ecKey: ecKey}
+
+	b64 := func(i *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(i.Bytes())
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   iss.URL,
+			"jwks_uri": iss.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&iss.keyFetches, 1)
+		json.NewEncoder(w).Encode(map[string][]jwk{
+			"keys": {
+				{
+					Kty: "RSA",
+					Kid: "rsa",
+					Use: "sig",
+					N:   b64(rsaKey.N),
+					E:   b64(big.NewInt(int64(rsaKey.E))),
+				},
+				{
+					Kty: "EC",
+					Kid: "ec",
+					Crv: "P-256",
+					X:   b64(ecKey.X),
+					Y:   b64(ecKey.Y),
+				},
+			},
+		})
+	})
+	iss.Server = httptest.NewServer(mux)
+	t.Cleanup(iss.Close)
+	return iss
+}
+
+// token returns a token of the issuer for alice with the given claims.
+func (iss *testIssuer) token(t *testing.T, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	c := jwt.MapClaims{
+		"iss": iss.URL,
+		"sub": "alice",
+		"aud": "cluster",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		c[k] = v
+	}
+
+	var token *jwt.Token
+	var key interface{}
+	if kid == "ec" {
+		token = jwt.NewWithClaims(jwt.SigningMethodES256, c)
+		key = iss.ecKey
+	} else {
+		token = jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+		key = iss.rsaKey
+	}
+	token.Header["kid"] = kid
+	ss, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ss
+}
+
+func TestOIDCAuth(t *testing.T) {
+	iss := newTestIssuer(t)
+
+	cfg := newDefaultTestConfig(t)
+	cfg.BasicAuthCredentials = map[string]string{validUserName: validUserPassword, "alice": "secret"}
+	cfg.Namespaces = map[string]string{"oidc:alice": "default", "alice": "other"}
+	cfg.OIDC = &OIDCConfig{
+		Issuer:         iss.URL,
+		Audience:       "cluster",
+		RolesClaim:     "realm_access.roles",
+		Roles:          map[string]string{"ops": RoleAdmin, "devs": RoleUser, "viewers": RoleReader},
+		NamespaceClaim: "cluster_namespace",
+	}
+	api := &API{
+		config: cfg,
+		oidc:   newOIDCVerifier(cfg.OIDC, cfg.Logger),
+	}
+
+	var user, namespace string
+	handler := api.authHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user = AuthenticatedUser(r.Context())
+		namespace = api.Namespace(r)
+	}), logging.Logger("testapi"))
+
+	do := func(method, path, token string) int {
+		t.Helper()
+		user, namespace = "", ""
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	roles := func(roles ...string) jwt.MapClaims {
+		return jwt.MapClaims{
+			"realm_access":      map[string]interface{}{"roles": roles},
+			"cluster_namespace": "team",
+		}
+	}
+
+	token := iss.token(t, "rsa", roles("viewers", "ops"))
+	if code := do("POST", "/admin/test", token); code != http.StatusOK {
+		t.Errorf("admins should be able to use every route: %d", code)
+	}
+	if user != "oidc:alice" || namespace != "team" {
+		t.Errorf("the namespace claim should take precedence: %s, %s", user, namespace)
+	}
+
+	token = iss.token(t, "ec", roles("devs"))
+	if code := do("POST", "/test", token); code != http.StatusOK {
+		t.Errorf("users should be able to post: %d", code)
+	}
+	if code := do("GET", "/admin/test", token); code != http.StatusForbidden {
+		t.Errorf("users should not access admin routes: %d", code)
+	}
+
+	token = iss.token(t, "rsa", roles("viewers"))
+	if code := do("GET", "/test", token); code != http.StatusOK {
+		t.Errorf("readers should be able to get: %d", code)
+	}
+	if code := do("POST", "/test", token); code != http.StatusForbidden {
+		t.Errorf("readers should not be able to post: %d", code)
+	}
+
+	token = iss.token(t, "rsa", jwt.MapClaims{
+		"realm_access":      map[string]interface{}{"roles": "devs"},
+		"cluster_namespace": "team",
+	})
+	if code := do("GET", "/test", token); code != http.StatusOK {
+		t.Fatalf("expected success with a role string: %d", code)
+	}
+
+	invalid := map[string]string{
+		"no role":        iss.token(t, "rsa", roles("others")),
+		"expired":        iss.token(t, "rsa", jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}),
+		"wrong audience": iss.token(t, "rsa", jwt.MapClaims{"aud": "other"}),
+		"no user":        iss.token(t, "rsa", jwt.MapClaims{"sub": ""}),
+		"no namespace": iss.token(t, "rsa", jwt.MapClaims{
+			"realm_access": map[string]interface{}{"roles": "ops"},
+		}),
+		"unknown key": iss.token(t, "other", roles("ops")),
+		"garbage":     "abc",
+	}
+	for name, token := range invalid {
+		if code := do("GET", "/test", token); code != http.StatusUnauthorized {
+			t.Errorf("%s: expected unauthorized, got %d", name, code)
+		}
+	}
+
+	// The tokens issued by the API and basic auth keep working.
+	if code := do("GET", "/test", validToken); code != http.StatusOK || user != validUserName {
+		t.Errorf("the tokens of the API should be accepted: %d", code)
+	}
+	req := httptest.NewRequest("POST", "/admin/test", nil)
+	req.SetBasicAuth(validUserName, validUserPassword)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("basic auth users should be admins: %d", rec.Code)
+	}
+
+	// OIDC users do not share the settings of basic auth users with the
+	// same name.
+	req = httptest.NewRequest("GET", "/test", nil)
+	req.SetBasicAuth("alice", "secret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if user != "alice" || namespace != "other" {
+		t.Errorf("unexpected basic auth user and namespace: %s, %s", user, namespace)
+	}
+
+	if code := do("GET", "/test", ""); code != http.StatusUnauthorized {
+		t.Errorf("expected unauthorized without credentials, got %d", code)
+	}
+}
+
+func TestOIDCAuthOnly(t *testing.T) {
+	iss := newTestIssuer(t)
+
+	// Without basic auth, only the tokens of the issuer are accepted.
+	cfg := newDefaultTestConfig(t)
+	cfg.OIDC = &OIDCConfig{
+		Issuer:    iss.URL,
+		JWKSURL:   iss.URL + "/keys",
+		Audience:  "cluster",
+		UserClaim: "email",
+		Roles:     map[string]string{"ops": RoleAdmin},
+	}
+	api := &API{
+		config: cfg,
+		oidc:   newOIDCVerifier(cfg.OIDC, cfg.Logger),
+	}
+
+	var user string
+	handler := api.authHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user = AuthenticatedUser(r.Context())
+	}), logging.Logger("testapi"))
+
+	for token, expected := range map[string]int{
+		iss.token(t, "rsa", jwt.MapClaims{"email": "alice@example.com", "roles": "ops"}): http.StatusOK,
+		iss.token(t, "rsa", jwt.MapClaims{"roles": "ops"}):                               http.StatusUnauthorized, // no email
+		iss.token(t, "rsa", jwt.MapClaims{"email": "alice@example.com"}):                 http.StatusUnauthorized, // no role
+		validToken: http.StatusUnauthorized,
+	} {
+		req := httptest.NewRequest("POST", "/admin/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != expected {
+			t.Errorf("expected %d, got %d", expected, rec.Code)
+		}
+	}
+	if user != "oidc:alice@example.com" {
+		t.Errorf("the user should be identified by email: %s", user)
+	}
+}
+
+func TestOIDCConcurrentKeyFetch(t *testing.T) {
+	iss := newTestIssuer(t)
+	v := newOIDCVerifier(&OIDCConfig{
+		Issuer:   iss.URL,
+		Audience: "cluster",
+		Roles:    map[string]string{"ops": RoleAdmin},
+	}, logging.Logger("testapi"))
+
+	token := iss.token(t, "rsa", jwt.MapClaims{"roles": "ops"})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := v.verify(context.Background(), token); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&iss.keyFetches); n != 1 {
+		t.Errorf("the keys should be fetched once by concurrent requests: %d", n)
+	}
+}
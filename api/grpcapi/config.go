@@ -0,0 +1,263 @@
+package grpcapi
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/kelseyhightower/envconfig"
+	ma "github.com/multiformats/go-multiaddr"
+
+	"github.com/ipfs-cluster/ipfs-cluster/config"
+)
+
+const (
+	configKey    = "grpcapi"
+	envConfigKey = "cluster_grpcapi"
+)
+
+// DefaultListenAddrs contains the default listeners for the gRPC API.
+var DefaultListenAddrs = []string{
+	"/ip4/127.0.0.1/tcp/9098",
+}
+
+// Default values for Config.
+const (
+	DefaultMaxRecvMsgSize = 4 * 1024 * 1024
+)
+
+// Config allows to customize the behavior of the gRPC API.
+// It implements the config.ComponentConfig interface.
+type Config struct {
+	config.Saver
+
+	// Listen addresses for the gRPC API.
+	ListenAddr []ma.Multiaddr
+
+	// BasicAuthCredentials is a map of username-password pairs which are
+	// authorized to use the API, sent by clients in the "authorization"
+	// metadata as with HTTP Basic Authentication. The API is open when
+	// empty. Authenticated users own the items they pin, as with the
+	// REST API.
+	BasicAuthCredentials map[string]string
+
+	// Namespaces restricts users to the pins of one namespace, as with
+	// the REST API: the pins they make are placed in it and they cannot
+	// see or remove the pins of other namespaces. The "*" entry applies
+	// to users without a namespace of their own, including everyone when
+	// there are no credentials.
+	Namespaces map[string]string
+
+	// TLS configuration for the listeners. The API is served in plain
+	// text when nil.
+	TLS *tls.Config
+
+	// PathSSLCertFile and PathSSLKeyFile are the paths to the certificate
+	// and private key used for TLS, as given in the configuration.
+	PathSSLCertFile string
+	PathSSLKeyFile  string
+
+	// MaxRecvMsgSize is the maximum size of the messages that the
+	// server accepts, in bytes.
+	MaxRecvMsgSize int
+
+	// Tracing flag used to skip tracing specific calls when not enabled.
+	Tracing bool
+}
+
+type jsonConfig struct {
+	ListenMultiaddress   config.Strings    `json:"listen_multiaddress"`
+	BasicAuthCredentials map[string]string `json:"basic_auth_credentials" hidden:"true"`
+	Namespaces           map[string]string `json:"namespaces,omitempty"`
+	SSLCertFile          string            `json:"ssl_cert_file,omitempty"`
+	SSLKeyFile           string            `json:"ssl_key_file,omitempty"`
+	MaxRecvMsgSize       int               `json:"max_recv_msg_size,omitempty"`
+}
+
+// ConfigKey provides a human-friendly identifier for this type of Config.
+func (cfg *Config) ConfigKey() string {
+	return configKey
+}
+
+// Default sets the fields of this Config to sensible default values.
+func (cfg *Config) Default() error {
+	addrs := make([]ma.Multiaddr, 0, len(DefaultListenAddrs))
+	for _, def := range DefaultListenAddrs {
+		a, err := ma.NewMultiaddr(def)
+		if err != nil {
+			return err
+		}
+		addrs = append(addrs, a)
+	}
+	cfg.ListenAddr = addrs
+	cfg.BasicAuthCredentials = nil
+	cfg.Namespaces = nil
+	cfg.TLS = nil
+	cfg.PathSSLCertFile = ""
+	cfg.PathSSLKeyFile = ""
+	cfg.MaxRecvMsgSize = DefaultMaxRecvMsgSize
+	return nil
+}
+
+// ApplyEnvVars fills in any Config fields found
+// as environment variables.
+func (cfg *Config) ApplyEnvVars() error {
+	jcfg, err := cfg.toJSONConfig()
+	if err != nil {
+		return err
+	}
+
+	err = envconfig.Process(envConfigKey, jcfg)
+	if err != nil {
+		return err
+	}
+
+	return cfg.applyJSONConfig(jcfg)
+}
+
+// Validate checks that the fields of this Config have sensible values,
+// at least in appearance.
+func (cfg *Config) Validate() error {
+	if len(cfg.ListenAddr) == 0 {
+		return errors.New("grpcapi.listen_multiaddress not set")
+	}
+
+	if cfg.MaxRecvMsgSize <= 0 {
+		return errors.New("grpcapi.max_recv_msg_size is invalid")
+	}
+
+	for user := range cfg.BasicAuthCredentials {
+		if user == "" {
+			return errors.New("grpcapi.basic_auth_credentials should not have empty usernames")
+		}
+	}
+
+	if (cfg.PathSSLCertFile != "" || cfg.PathSSLKeyFile != "") && cfg.TLS == nil {
+		return errors.New("grpcapi: missing TLS configuration")
+	}
+	return nil
+}
+
+// LoadJSON parses a JSON representation of this Config as generated by ToJSON.
+func (cfg *Config) LoadJSON(raw []byte) error {
+	jcfg := &jsonConfig{}
+	err := json.Unmarshal(raw, jcfg)
+	if err != nil {
+		logger.Error("Error unmarshaling grpcapi config")
+		return err
+	}
+
+	err = cfg.Default()
+	if err != nil {
+		return fmt.Errorf("error setting config to default values: %s", err)
+	}
+
+	return cfg.applyJSONConfig(jcfg)
+}
+
+func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
+	if addresses := jcfg.ListenMultiaddress; len(addresses) > 0 {
+		cfg.ListenAddr = make([]ma.Multiaddr, 0, len(addresses))
+		for _, a := range addresses {
+			addr, err := ma.NewMultiaddr(a)
+			if err != nil {
+				return fmt.Errorf("error parsing grpcapi listen_multiaddress: %s", err)
+			}
+			cfg.ListenAddr = append(cfg.ListenAddr, addr)
+		}
+	}
+	cfg.BasicAuthCredentials = jcfg.BasicAuthCredentials
+	cfg.Namespaces = jcfg.Namespaces
+	config.SetIfNotDefault(jcfg.MaxRecvMsgSize, &cfg.MaxRecvMsgSize)
+
+	err := cfg.tlsOptions(jcfg)
+	if err != nil {
+		return err
+	}
+
+	return cfg.Validate()
+}
+
+func (cfg *Config) tlsOptions(jcfg *jsonConfig) error {
+	cert := jcfg.SSLCertFile
+	key := jcfg.SSLKeyFile
+
+	if cert+key == "" {
+		return nil
+	}
+
+	cfg.PathSSLCertFile = cert
+	cfg.PathSSLKeyFile = key
+
+	if !filepath.IsAbs(cert) {
+		cert = filepath.Join(cfg.BaseDir, cert)
+	}
+	if !filepath.IsAbs(key) {
+		key = filepath.Join(cfg.BaseDir, key)
+	}
+
+	tlsCert, err := tls.LoadX509KeyPair(cert, key)
+	if err != nil {
+		return fmt.Errorf("error loading grpcapi TLS certificate/key: %w", err)
+	}
+	cfg.TLS = &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{tlsCert},
+	}
+	return nil
+}
+
+// ToJSON generates a human-friendly JSON representation of this Config.
+func (cfg *Config) ToJSON() (raw []byte, err error) {
+	jcfg, err := cfg.toJSONConfig()
+	if err != nil {
+		return
+	}
+
+	raw, err = config.DefaultJSONMarshal(jcfg)
+	return
+}
+
+// JSONKeys returns the keys of the JSON representation of this
+// configuration, including those omitted when empty.
+func (cfg *Config) JSONKeys() []string {
+	return config.JSONKeys(jsonConfig{})
+}
+
+func (cfg *Config) toJSONConfig() (jcfg *jsonConfig, err error) {
+	// Multiaddress String() may panic
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s", r)
+		}
+	}()
+
+	addresses := make([]string, 0, len(cfg.ListenAddr))
+	for _, a := range cfg.ListenAddr {
+		addresses = append(addresses, a.String())
+	}
+
+	jcfg = &jsonConfig{
+		ListenMultiaddress:   addresses,
+		BasicAuthCredentials: cfg.BasicAuthCredentials,
+		Namespaces:           cfg.Namespaces,
+		SSLCertFile:          cfg.PathSSLCertFile,
+		SSLKeyFile:           cfg.PathSSLKeyFile,
+	}
+	if cfg.MaxRecvMsgSize != DefaultMaxRecvMsgSize {
+		jcfg.MaxRecvMsgSize = cfg.MaxRecvMsgSize
+	}
+	return
+}
+
+// ToDisplayJSON returns JSON config as a string.
+func (cfg *Config) ToDisplayJSON() ([]byte, error) {
+	jcfg, err := cfg.toJSONConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return config.DisplayJSON(jcfg)
+}
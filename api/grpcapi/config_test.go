@@ -0,0 +1,106 @@
+package grpcapi
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+var cfgJSON = []byte(`
+{
+	"listen_multiaddress": "/ip4/127.0.0.1/tcp/9098",
+	"basic_auth_credentials": {
+		"user": "pass"
+	},
+	"max_recv_msg_size": 1048576
+}
+`)
+
+func TestLoadEmptyJSON(t *testing.T) {
+	cfg := &Config{}
+	err := cfg.LoadJSON([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	cfg := &Config{}
+	err := cfg.LoadJSON(cfgJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BasicAuthCredentials["user"] != "pass" || cfg.MaxRecvMsgSize != 1048576 {
+		t.Error("unexpected config values")
+	}
+
+	j := &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.ListenMultiaddress = []string{"abc"}
+	tst, _ := json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error decoding listen_multiaddress")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.SSLCertFile = "missing.crt"
+	j.SSLKeyFile = "missing.key"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error loading the TLS certificate")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.MaxRecvMsgSize = -1
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error in max_recv_msg_size")
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	cfg := &Config{}
+	cfg.LoadJSON(cfgJSON)
+	newjson, err := cfg.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg = &Config{}
+	err = cfg.LoadJSON(newjson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.BasicAuthCredentials["user"] != "pass" {
+		t.Error("the credentials should have been kept")
+	}
+}
+
+func TestDefault(t *testing.T) {
+	cfg := &Config{}
+	cfg.Default()
+	if cfg.Validate() != nil {
+		t.Fatal("error validating")
+	}
+
+	cfg.ListenAddr = nil
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+}
+
+func TestApplyEnvVars(t *testing.T) {
+	os.Setenv("CLUSTER_GRPCAPI_MAXRECVMSGSIZE", "1024")
+	defer os.Unsetenv("CLUSTER_GRPCAPI_MAXRECVMSGSIZE")
+	cfg := &Config{}
+	cfg.Default()
+	cfg.ApplyEnvVars()
+
+	if cfg.MaxRecvMsgSize != 1024 {
+		t.Fatal("failed to override max_recv_msg_size with env var")
+	}
+}
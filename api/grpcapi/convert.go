@@ -0,0 +1,146 @@
+package grpcapi
+
+import (
+	"fmt"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/api/grpcapi/pb"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// This file converts between the cluster API types and their gRPC
+// definitions.
+
+func pinOptionsFromPB(opts *pb.PinOptions) (api.PinOptions, error) {
+	var o api.PinOptions
+	if opts == nil {
+		return o, nil
+	}
+
+	o.ReplicationFactorMin = int(opts.GetReplicationFactorMin())
+	o.ReplicationFactorMax = int(opts.GetReplicationFactorMax())
+	o.Name = opts.GetName()
+	switch opts.GetMode() {
+	case "", "recursive":
+		o.Mode = api.PinModeRecursive
+	case "direct":
+		o.Mode = api.PinModeDirect
+	default:
+		return o, fmt.Errorf("invalid pin mode: %s", opts.GetMode())
+	}
+	for _, a := range opts.GetUserAllocations() {
+		pid, err := peer.Decode(a)
+		if err != nil {
+			return o, fmt.Errorf("error decoding user allocation %s: %w", a, err)
+		}
+		o.UserAllocations = append(o.UserAllocations, pid)
+	}
+	if opts.GetExpireAt() != nil {
+		o.ExpireAt = opts.GetExpireAt().AsTime()
+	}
+	o.Metadata = opts.GetMetadata()
+	o.Tags = opts.GetTags()
+	if pu := opts.GetPinUpdate(); pu != "" {
+		c, err := api.DecodeCid(pu)
+		if err != nil {
+			return o, fmt.Errorf("error decoding pin_update: %w", err)
+		}
+		o.PinUpdate = c
+	}
+	return o, nil
+}
+
+func pinOptionsToPB(o api.PinOptions) *pb.PinOptions {
+	opts := &pb.PinOptions{
+		ReplicationFactorMin: int32(o.ReplicationFactorMin),
+		ReplicationFactorMax: int32(o.ReplicationFactorMax),
+		Name:                 o.Name,
+		Mode:                 o.Mode.String(),
+		UserAllocations:      peersToStrings(o.UserAllocations),
+		Metadata:             o.Metadata,
+		Tags:                 o.Tags,
+	}
+	if !o.ExpireAt.IsZero() {
+		opts.ExpireAt = timestamppb.New(o.ExpireAt)
+	}
+	if o.PinUpdate.Defined() {
+		opts.PinUpdate = o.PinUpdate.String()
+	}
+	return opts
+}
+
+func pinToPB(pin api.Pin) *pb.Pin {
+	p := &pb.Pin{
+		Cid:         pin.Cid.String(),
+		Type:        pin.Type.String(),
+		Allocations: peersToStrings(pin.Allocations),
+		MaxDepth:    int32(pin.MaxDepth),
+		Options:     pinOptionsToPB(pin.PinOptions),
+	}
+	if pin.Reference != nil {
+		p.Reference = pin.Reference.String()
+	}
+	if !pin.Timestamp.IsZero() {
+		p.Timestamp = timestamppb.New(pin.Timestamp)
+	}
+	return p
+}
+
+func globalPinInfoToPB(gpi api.GlobalPinInfo) *pb.GlobalPinInfo {
+	g := &pb.GlobalPinInfo{
+		Cid:         gpi.Cid.String(),
+		Name:        gpi.Name,
+		Allocations: peersToStrings(gpi.Allocations),
+		PeerMap:     make(map[string]*pb.PinInfo, len(gpi.PeerMap)),
+	}
+	for p, pis := range gpi.PeerMap {
+		pi := &pb.PinInfo{
+			Peername:     pis.PeerName,
+			Status:       pis.Status.String(),
+			Error:        pis.Error,
+			ErrorReason:  pis.ErrorReason,
+			AttemptCount: int32(pis.AttemptCount),
+		}
+		if pis.IPFS != "" {
+			pi.IpfsPeerId = pis.IPFS.String()
+		}
+		if !pis.TS.IsZero() {
+			pi.Timestamp = timestamppb.New(pis.TS)
+		}
+		g.PeerMap[p] = pi
+	}
+	return g
+}
+
+func idToPB(id api.ID) *pb.Peer {
+	p := &pb.Peer{
+		Id:           id.ID.String(),
+		Peername:     id.Peername,
+		ClusterPeers: peersToStrings(id.ClusterPeers),
+		Version:      id.Version,
+		Commit:       id.Commit,
+		Error:        id.Error,
+	}
+	for _, a := range id.Addresses {
+		p.Addresses = append(p.Addresses, a.String())
+	}
+	if id.IPFS.ID != "" {
+		p.IpfsPeerId = id.IPFS.ID.String()
+	}
+	return p
+}
+
+// peersToStrings converts a slice of peer IDs to strings.
+func peersToStrings(peers []peer.ID) []string {
+	if len(peers) == 0 {
+		return nil
+	}
+	strs := make([]string, len(peers))
+	for i, p := range peers {
+		strs[i] = p.String()
+	}
+	return strs
+}
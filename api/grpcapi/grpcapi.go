@@ -0,0 +1,646 @@
+// Package grpcapi implements an IPFS Cluster API component which offers the
+// main cluster operations (peers, pins and their status) over gRPC, as
+// defined in pb/cluster.proto. Listings are served as server-side streams.
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/api/common"
+	"github.com/ipfs-cluster/ipfs-cluster/api/grpcapi/pb"
+	"github.com/ipfs-cluster/ipfs-cluster/state"
+
+	logging "github.com/ipfs/go-log/v2"
+	rpc "github.com/libp2p/go-libp2p-gorpc"
+	manet "github.com/multiformats/go-multiaddr/net"
+
+	"go.opencensus.io/plugin/ocgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	grpcpeer "google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var logger = logging.Logger("grpcapi")
+
+// Server offers the IPFS Cluster gRPC API. It implements the Cluster API
+// component interface.
+type Server struct {
+	pb.UnimplementedClusterServer
+
+	ctx    context.Context
+	cancel func()
+
+	config *Config
+
+	rpcClient *rpc.Client
+	rpcReady  chan struct{}
+
+	listeners []net.Listener
+	server    *grpc.Server
+
+	shutdownLock sync.Mutex
+	shutdown     bool
+	wg           sync.WaitGroup
+}
+
+type userKey struct{}
+
+// New returns a gRPC API component listening on the configured addresses.
+// It starts serving requests once the RPC client is set.
+func New(cfg *Config) (*Server, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var listeners []net.Listener
+	for _, addr := range cfg.ListenAddr {
+		n, a, err := manet.DialArgs(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		l, err := net.Listen(n, a)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		ctx:       ctx,
+		cancel:    cancel,
+		config:    cfg,
+		rpcReady:  make(chan struct{}, 1),
+		listeners: listeners,
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(cfg.MaxRecvMsgSize),
+		grpc.UnaryInterceptor(s.unaryAuth),
+		grpc.StreamInterceptor(s.streamAuth),
+	}
+	if cfg.TLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg.TLS)))
+	}
+	if cfg.Tracing {
+		opts = append(opts, grpc.StatsHandler(&ocgrpc.ServerHandler{}))
+	}
+	s.server = grpc.NewServer(opts...)
+	pb.RegisterClusterServer(s.server, s)
+
+	go s.run()
+	return s, nil
+}
+
+// SetClient makes the component ready to perform RPC
+// requests.
+func (s *Server) SetClient(c *rpc.Client) {
+	s.rpcClient = c
+	s.rpcReady <- struct{}{}
+}
+
+// Shutdown stops any listeners and stops the component from taking
+// any requests.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdownLock.Lock()
+	defer s.shutdownLock.Unlock()
+
+	if s.shutdown {
+		logger.Debug("already shutdown")
+		return nil
+	}
+
+	logger.Info("stopping gRPC API")
+
+	s.cancel()
+	close(s.rpcReady)
+	// Stop closes the listeners and cancels ongoing streams.
+	s.server.Stop()
+	for _, l := range s.listeners {
+		l.Close()
+	}
+
+	s.wg.Wait()
+	s.shutdown = true
+	return nil
+}
+
+// launches the server when we receive the rpcReady signal.
+func (s *Server) run() {
+	<-s.rpcReady
+
+	// Do not shutdown while launching threads
+	// -- prevents race conditions with s.wg.
+	s.shutdownLock.Lock()
+	defer s.shutdownLock.Unlock()
+
+	if s.ctx.Err() != nil {
+		return
+	}
+
+	s.wg.Add(len(s.listeners))
+	for _, l := range s.listeners {
+		go func(l net.Listener) {
+			defer s.wg.Done()
+
+			maddr, err := manet.FromNetAddr(l.Addr())
+			if err != nil {
+				logger.Error(err)
+			}
+			logger.Infof("gRPC API: %s", maddr)
+			err = s.server.Serve(l) // hangs here
+			if err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+				logger.Error(err)
+			}
+		}(l)
+	}
+}
+
+// authenticate checks the credentials sent in the "authorization" metadata
+// and returns a context carrying the authenticated user.
+func (s *Server) authenticate(ctx context.Context) (context.Context, error) {
+	if len(s.config.BasicAuthCredentials) == 0 {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, auth := range md.Get("authorization") {
+		// Let net/http parse the Basic credentials.
+		r := http.Request{Header: http.Header{"Authorization": []string{auth}}}
+		user, pass, ok := r.BasicAuth()
+		if !ok {
+			continue
+		}
+		expected, ok := s.config.BasicAuthCredentials[user]
+		if ok && subtle.ConstantTimeCompare([]byte(pass), []byte(expected)) == 1 {
+			return context.WithValue(ctx, userKey{}, user), nil
+		}
+	}
+	return ctx, status.Error(codes.Unauthenticated, "unauthorized")
+}
+
+func (s *Server) unaryAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// authStream replaces the context of a server stream with an authenticated
+// one.
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (as *authStream) Context() context.Context {
+	return as.ctx
+}
+
+func (s *Server) streamAuth(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authStream{ServerStream: ss, ctx: ctx})
+}
+
+// owners returns the owners of the items pinned with the given context, as
+// with the REST API.
+func owners(ctx context.Context) []string {
+	if user, ok := ctx.Value(userKey{}).(string); ok {
+		return []string{user}
+	}
+	return nil
+}
+
+// requester returns who made the request for the audit trail of pins.
+func requester(ctx context.Context) api.PinRequester {
+	var r api.PinRequester
+	r.User, _ = ctx.Value(userKey{}).(string)
+	if p, ok := grpcpeer.FromContext(ctx); ok && p.Addr != nil {
+		r.Address = p.Addr.String()
+		if host, _, err := net.SplitHostPort(r.Address); err == nil {
+			r.Address = host
+		}
+	}
+	return r
+}
+
+// namespace returns the pin namespace that the user of the request is
+// restricted to, or an empty string when the user can work with the pins
+// of every namespace.
+func (s *Server) namespace(ctx context.Context) string {
+	user, _ := ctx.Value(userKey{}).(string)
+	if ns, ok := s.config.Namespaces[user]; ok {
+		return ns
+	}
+	return s.config.Namespaces["*"]
+}
+
+// checkOwnsPin returns a NotFound error when the user of the request is
+// restricted to a namespace and the pin for the given CID is not in it, as
+// the pins of other namespaces are not visible.
+func (s *Server) checkOwnsPin(ctx context.Context, c api.Cid) error {
+	ns := s.namespace(ctx)
+	if ns == "" {
+		return nil
+	}
+
+	var pin api.Pin
+	err := s.rpcClient.CallContext(
+		ctx,
+		"",
+		"Cluster",
+		"PinGet",
+		c,
+		&pin,
+	)
+	if err == nil && pin.Namespace != ns {
+		err = state.ErrNotFound
+	}
+	return rpcError(err)
+}
+
+// checkOwnsPath is like checkOwnsPin for the CID that an IPFS path resolves
+// to.
+func (s *Server) checkOwnsPath(ctx context.Context, path string) error {
+	if s.namespace(ctx) == "" {
+		return nil
+	}
+
+	var c api.Cid
+	err := s.rpcClient.CallContext(
+		ctx,
+		"",
+		"IPFSConnector",
+		"Resolve",
+		path,
+		&c,
+	)
+	if err != nil {
+		return rpcError(err)
+	}
+	return s.checkOwnsPin(ctx, c)
+}
+
+// rpcError converts an error returned by a cluster RPC call to a gRPC
+// status error.
+func rpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	// errors lose their type over RPC.
+	msg := err.Error()
+	switch {
+	case msg == state.ErrNotFound.Error():
+		return status.Error(codes.NotFound, msg)
+	case strings.HasPrefix(msg, api.ErrPinTooLarge.Error()):
+		return status.Error(codes.ResourceExhausted, msg)
+	case strings.HasPrefix(msg, api.ErrPinNameConflict.Error()):
+		return status.Error(codes.AlreadyExists, msg)
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		return status.Error(codes.Internal, msg)
+	}
+}
+
+func invalidArgument(err error) error {
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+func decodeCid(str string) (api.Cid, error) {
+	c, err := api.DecodeCid(str)
+	if err != nil {
+		return api.CidUndef, invalidArgument(err)
+	}
+	return c, nil
+}
+
+// ID returns the information about the peer serving the request.
+func (s *Server) ID(ctx context.Context, req *pb.IDRequest) (*pb.Peer, error) {
+	var id api.ID
+	err := s.rpcClient.CallContext(
+		ctx,
+		"",
+		"Cluster",
+		"ID",
+		struct{}{},
+		&id,
+	)
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	return idToPB(id), nil
+}
+
+// Peers streams the information about every peer in the cluster.
+func (s *Server) Peers(req *pb.PeersRequest, stream pb.Cluster_PeersServer) error {
+	in := make(chan struct{})
+	close(in)
+	out := make(chan api.ID, common.StreamChannelSize)
+	return streamRPC(stream.Context(), s.rpcClient, "Peers", in, out, func(id api.ID) error {
+		return stream.Send(idToPB(id))
+	})
+}
+
+// Pin pins a CID, or an IPFS path, in the cluster.
+func (s *Server) Pin(ctx context.Context, req *pb.PinRequest) (*pb.Pin, error) {
+	opts, err := pinOptionsFromPB(req.GetOptions())
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	opts.Namespace = s.namespace(ctx)
+	opts.Owners = owners(ctx)
+	opts.Requester = requester(ctx)
+	// pin updates copy the options of the updated pin.
+	if opts.PinUpdate.Defined() {
+		if err := s.checkOwnsPin(ctx, opts.PinUpdate); err != nil {
+			return nil, err
+		}
+	}
+
+	var pin api.Pin
+	if path := req.GetPath(); path != "" {
+		err = s.rpcClient.CallContext(
+			ctx,
+			"",
+			"Cluster",
+			"PinPath",
+			api.PinPath{PinOptions: opts, Path: path},
+			&pin,
+		)
+	} else {
+		var c api.Cid
+		c, err = decodeCid(req.GetCid())
+		if err != nil {
+			return nil, err
+		}
+		err = s.rpcClient.CallContext(
+			ctx,
+			"",
+			"Cluster",
+			"Pin",
+			api.PinWithOpts(c, opts),
+			&pin,
+		)
+	}
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	return pinToPB(pin), nil
+}
+
+// Unpin unpins a CID, or an IPFS path, from the cluster. Authenticated users
+// only remove their own reference to the item.
+func (s *Server) Unpin(ctx context.Context, req *pb.UnpinRequest) (*pb.Pin, error) {
+	var err error
+	var pin api.Pin
+	if path := req.GetPath(); path != "" {
+		if err := s.checkOwnsPath(ctx, path); err != nil {
+			return nil, err
+		}
+		pinPath := api.PinPath{Path: path}
+		pinPath.Owners = owners(ctx)
+		pinPath.Requester = requester(ctx)
+		err = s.rpcClient.CallContext(
+			ctx,
+			"",
+			"Cluster",
+			"UnpinPath",
+			pinPath,
+			&pin,
+		)
+	} else {
+		var c api.Cid
+		c, err = decodeCid(req.GetCid())
+		if err != nil {
+			return nil, err
+		}
+		if err := s.checkOwnsPin(ctx, c); err != nil {
+			return nil, err
+		}
+		in := api.PinCid(c)
+		in.Owners = owners(ctx)
+		in.Requester = requester(ctx)
+		err = s.rpcClient.CallContext(
+			ctx,
+			"",
+			"Cluster",
+			"Unpin",
+			in,
+			&pin,
+		)
+	}
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	return pinToPB(pin), nil
+}
+
+// GetPin returns a pin from the cluster pinset.
+func (s *Server) GetPin(ctx context.Context, req *pb.GetPinRequest) (*pb.Pin, error) {
+	c, err := decodeCid(req.GetCid())
+	if err != nil {
+		return nil, err
+	}
+
+	var pin api.Pin
+	err = s.rpcClient.CallContext(
+		ctx,
+		"",
+		"Cluster",
+		"PinGet",
+		c,
+		&pin,
+	)
+	if ns := s.namespace(ctx); err == nil && ns != "" && pin.Namespace != ns {
+		err = state.ErrNotFound
+	}
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	return pinToPB(pin), nil
+}
+
+// ListPins streams the pins in the cluster pinset.
+func (s *Server) ListPins(req *pb.ListPinsRequest, stream pb.Cluster_ListPinsServer) error {
+	opts := api.PinListOptions{
+		NamePrefix: req.GetNamePrefix(),
+		CidPrefix:  req.GetCidPrefix(),
+		Namespace:  s.namespace(stream.Context()),
+	}
+	if t := req.GetType(); t != "" {
+		for _, f := range strings.Split(t, ",") {
+			opts.Type |= api.PinTypeFromString(f)
+		}
+		if opts.Type == api.BadType {
+			return invalidArgument(errors.New("invalid type value"))
+		}
+	}
+
+	in := make(chan api.PinListOptions, 1)
+	in <- opts
+	close(in)
+	out := make(chan api.Pin, common.StreamChannelSize)
+	return streamRPC(stream.Context(), s.rpcClient, "PinsWithOptions", in, out, func(pin api.Pin) error {
+		return stream.Send(pinToPB(pin))
+	})
+}
+
+// Status returns the status of a CID on the peers allocated to it, or only
+// on this peer when local is set.
+func (s *Server) Status(ctx context.Context, req *pb.StatusRequest) (*pb.GlobalPinInfo, error) {
+	c, err := decodeCid(req.GetCid())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkOwnsPin(ctx, c); err != nil {
+		return nil, err
+	}
+	gpi, err := s.pinInfoCall(ctx, "Status", c, req.GetLocal())
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	return globalPinInfoToPB(gpi), nil
+}
+
+// StatusAll streams the status of every tracked CID, or of those tracked by
+// this peer when local is set.
+func (s *Server) StatusAll(req *pb.StatusAllRequest, stream pb.Cluster_StatusAllServer) error {
+	filter := api.TrackerStatusFromString(req.GetFilter())
+	if filter == api.TrackerStatusUndefined && req.GetFilter() != "" {
+		return invalidArgument(errors.New("invalid filter value"))
+	}
+	prefix := req.GetCidPrefix()
+	// users restricted to a namespace only see its pins.
+	ns := s.namespace(stream.Context())
+
+	if req.GetLocal() {
+		in := make(chan api.TrackerStatus, 1)
+		in <- filter
+		close(in)
+		out := make(chan api.PinInfo, common.StreamChannelSize)
+		return streamRPC(stream.Context(), s.rpcClient, "StatusAllLocal", in, out, func(pi api.PinInfo) error {
+			if !strings.HasPrefix(pi.Cid.String(), prefix) {
+				return nil
+			}
+			if ns != "" && pi.Namespace != ns {
+				return nil
+			}
+			return stream.Send(globalPinInfoToPB(pi.ToGlobal()))
+		})
+	}
+
+	in := make(chan api.StatusAllOptions, 1)
+	in <- api.StatusAllOptions{
+		Filter:    filter,
+		CidPrefix: prefix,
+	}
+	close(in)
+	out := make(chan api.GlobalPinInfo, common.StreamChannelSize)
+	return streamRPC(stream.Context(), s.rpcClient, "StatusAllWithOptions", in, out, func(gpi api.GlobalPinInfo) error {
+		if ns != "" && gpi.Namespace != ns {
+			return nil
+		}
+		return stream.Send(globalPinInfoToPB(gpi))
+	})
+}
+
+// Recover retries the failed operations on a CID, on the peers allocated to
+// it, or only on this peer when local is set.
+func (s *Server) Recover(ctx context.Context, req *pb.RecoverRequest) (*pb.GlobalPinInfo, error) {
+	c, err := decodeCid(req.GetCid())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkOwnsPin(ctx, c); err != nil {
+		return nil, err
+	}
+	gpi, err := s.pinInfoCall(ctx, "Recover", c, req.GetLocal())
+	if err != nil {
+		return nil, rpcError(err)
+	}
+	return globalPinInfoToPB(gpi), nil
+}
+
+// pinInfoCall calls a Cluster RPC method returning the GlobalPinInfo of a
+// CID, or its "Local" version.
+func (s *Server) pinInfoCall(ctx context.Context, method string, c api.Cid, local bool) (api.GlobalPinInfo, error) {
+	if local {
+		var pi api.PinInfo
+		err := s.rpcClient.CallContext(
+			ctx,
+			"",
+			"Cluster",
+			method+"Local",
+			c,
+			&pi,
+		)
+		return pi.ToGlobal(), err
+	}
+
+	var gpi api.GlobalPinInfo
+	err := s.rpcClient.CallContext(
+		ctx,
+		"",
+		"Cluster",
+		method,
+		c,
+		&gpi,
+	)
+	return gpi, err
+}
+
+// streamRPC calls a streaming Cluster RPC method and sends every item it
+// produces with send. The call is canceled when sending fails.
+func streamRPC[I, O any](ctx context.Context, client *rpc.Client, method string, in chan I, out chan O, send func(O) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(errCh)
+
+		errCh <- client.Stream(
+			ctx,
+			"",
+			"Cluster",
+			method,
+			in,
+			out,
+		)
+	}()
+
+	var sendErr error
+	for item := range out {
+		if sendErr != nil {
+			continue // drain
+		}
+		if sendErr = send(item); sendErr != nil {
+			cancel()
+		}
+	}
+	err := <-errCh
+	if sendErr != nil {
+		return sendErr
+	}
+	return rpcError(err)
+}
@@ -0,0 +1,422 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api/grpcapi/pb"
+	"github.com/ipfs-cluster/ipfs-cluster/test"
+
+	ma "github.com/multiformats/go-multiaddr"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func testServerWithConfig(t *testing.T, cfg *Config) (*Server, pb.ClusterClient) {
+	addr, _ := ma.NewMultiaddr("/ip4/127.0.0.1/tcp/0")
+	cfg.ListenAddr = []ma.Multiaddr{addr}
+
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal("creating the gRPC API should work: ", err)
+	}
+	s.SetClient(test.NewMockRPCClient(t))
+
+	conn, err := grpc.Dial(
+		s.listeners[0].Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return s, pb.NewClusterClient(conn)
+}
+
+func testServer(t *testing.T) (*Server, pb.ClusterClient) {
+	cfg := &Config{}
+	cfg.Default()
+	return testServerWithConfig(t, cfg)
+}
+
+func checkCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+	if status.Code(err) != code {
+		t.Errorf("expected %s error, got: %v", code, err)
+	}
+}
+
+func TestID(t *testing.T) {
+	ctx := context.Background()
+	s, client := testServer(t)
+	defer s.Shutdown(ctx)
+
+	id, err := client.ID(ctx, &pb.IDRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.GetId() != test.PeerID1.String() || id.GetIpfsPeerId() != test.PeerID1.String() {
+		t.Errorf("unexpected peer: %v", id)
+	}
+}
+
+func TestPeers(t *testing.T) {
+	ctx := context.Background()
+	s, client := testServer(t)
+	defer s.Shutdown(ctx)
+
+	stream, err := client.Peers(ctx, &pb.PeersRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var peers []*pb.Peer
+	for {
+		p, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		peers = append(peers, p)
+	}
+	if len(peers) != 1 || peers[0].GetId() != test.PeerID1.String() {
+		t.Errorf("unexpected peers: %v", peers)
+	}
+}
+
+func TestPinUnpin(t *testing.T) {
+	ctx := context.Background()
+	s, client := testServer(t)
+	defer s.Shutdown(ctx)
+
+	pin, err := client.Pin(ctx, &pb.PinRequest{
+		Cid: test.Cid1.String(),
+		Options: &pb.PinOptions{
+			Name:     "abc",
+			Mode:     "direct",
+			Metadata: map[string]string{"a": "b"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin.GetCid() != test.Cid1.String() || pin.GetOptions().GetName() != "abc" || pin.GetOptions().GetMode() != "direct" {
+		t.Errorf("unexpected pin: %v", pin)
+	}
+	if pin.GetOptions().GetMetadata()["a"] != "b" {
+		t.Errorf("the metadata should have been kept: %v", pin)
+	}
+
+	pin, err = client.Pin(ctx, &pb.PinRequest{Path: test.PathIPFS1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if "/ipfs/"+pin.GetCid() != test.PathIPFS1 {
+		t.Errorf("the path should have been resolved: %v", pin)
+	}
+
+	_, err = client.Pin(ctx, &pb.PinRequest{Cid: "abc"})
+	checkCode(t, err, codes.InvalidArgument)
+
+	_, err = client.Pin(ctx, &pb.PinRequest{
+		Cid:     test.Cid1.String(),
+		Options: &pb.PinOptions{Mode: "abc"},
+	})
+	checkCode(t, err, codes.InvalidArgument)
+
+	_, err = client.Pin(ctx, &pb.PinRequest{Cid: test.LargeCid.String()})
+	checkCode(t, err, codes.ResourceExhausted)
+
+	pin, err = client.Unpin(ctx, &pb.UnpinRequest{Cid: test.Cid1.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin.GetCid() != test.Cid1.String() {
+		t.Errorf("unexpected pin: %v", pin)
+	}
+
+	_, err = client.Unpin(ctx, &pb.UnpinRequest{Cid: test.NotFoundCid.String()})
+	checkCode(t, err, codes.NotFound)
+
+	_, err = client.Unpin(ctx, &pb.UnpinRequest{Path: test.NotFoundPath})
+	checkCode(t, err, codes.NotFound)
+}
+
+func TestGetPin(t *testing.T) {
+	ctx := context.Background()
+	s, client := testServer(t)
+	defer s.Shutdown(ctx)
+
+	pin, err := client.GetPin(ctx, &pb.GetPinRequest{Cid: test.Cid1.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin.GetCid() != test.Cid1.String() || pin.GetType() != "pin" {
+		t.Errorf("unexpected pin: %v", pin)
+	}
+
+	_, err = client.GetPin(ctx, &pb.GetPinRequest{Cid: test.NotFoundCid.String()})
+	checkCode(t, err, codes.NotFound)
+}
+
+func TestListPins(t *testing.T) {
+	ctx := context.Background()
+	s, client := testServer(t)
+	defer s.Shutdown(ctx)
+
+	stream, err := client.ListPins(ctx, &pb.ListPinsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for {
+		_, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if n != 3 {
+		t.Errorf("expected 3 pins, got %d", n)
+	}
+
+	stream, err = client.ListPins(ctx, &pb.ListPinsRequest{Type: "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = stream.Recv()
+	checkCode(t, err, codes.InvalidArgument)
+}
+
+func TestStatus(t *testing.T) {
+	ctx := context.Background()
+	s, client := testServer(t)
+	defer s.Shutdown(ctx)
+
+	for _, local := range []bool{false, true} {
+		gpi, err := client.Status(ctx, &pb.StatusRequest{Cid: test.Cid1.String(), Local: local})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gpi.GetCid() != test.Cid1.String() || len(gpi.GetPeerMap()) != 1 {
+			t.Errorf("unexpected status (local: %t): %v", local, gpi)
+		}
+		for _, pi := range gpi.GetPeerMap() {
+			if pi.GetStatus() != "pinned" {
+				t.Errorf("unexpected status (local: %t): %v", local, gpi)
+			}
+		}
+	}
+
+	gpi, err := client.Recover(ctx, &pb.RecoverRequest{Cid: test.Cid1.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gpi.GetCid() != test.Cid1.String() {
+		t.Errorf("unexpected status: %v", gpi)
+	}
+}
+
+func TestStatusAll(t *testing.T) {
+	ctx := context.Background()
+	s, client := testServer(t)
+	defer s.Shutdown(ctx)
+
+	statusAll := func(req *pb.StatusAllRequest) []*pb.GlobalPinInfo {
+		t.Helper()
+		stream, err := client.StatusAll(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var gpis []*pb.GlobalPinInfo
+		for {
+			gpi, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return gpis
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			gpis = append(gpis, gpi)
+		}
+	}
+
+	if gpis := statusAll(&pb.StatusAllRequest{}); len(gpis) != 3 {
+		t.Errorf("expected 3 items: %v", gpis)
+	}
+	if gpis := statusAll(&pb.StatusAllRequest{Filter: "pinned"}); len(gpis) != 1 {
+		t.Errorf("expected 1 pinned item: %v", gpis)
+	}
+	if gpis := statusAll(&pb.StatusAllRequest{Local: true}); len(gpis) != 2 {
+		t.Errorf("expected 2 local items: %v", gpis)
+	}
+	gpis := statusAll(&pb.StatusAllRequest{Local: true, CidPrefix: test.Cid3.String()})
+	if len(gpis) != 1 || gpis[0].GetCid() != test.Cid3.String() {
+		t.Errorf("expected the local item with the prefix: %v", gpis)
+	}
+
+	stream, err := client.StatusAll(ctx, &pb.StatusAllRequest{Filter: "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = stream.Recv()
+	checkCode(t, err, codes.InvalidArgument)
+}
+
+func TestBasicAuth(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.Default()
+	cfg.BasicAuthCredentials = map[string]string{"user": "pass"}
+	s, client := testServerWithConfig(t, cfg)
+	defer s.Shutdown(ctx)
+
+	withAuth := func(user, pass string) context.Context {
+		auth := base64.StdEncoding.EncodeToString([]byte(user + ":" + pass))
+		return metadata.AppendToOutgoingContext(ctx, "authorization", "Basic "+auth)
+	}
+
+	_, err := client.ID(ctx, &pb.IDRequest{})
+	checkCode(t, err, codes.Unauthenticated)
+
+	_, err = client.ID(withAuth("user", "wrong"), &pb.IDRequest{})
+	checkCode(t, err, codes.Unauthenticated)
+
+	stream, err := client.Peers(ctx, &pb.PeersRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = stream.Recv()
+	checkCode(t, err, codes.Unauthenticated)
+
+	_, err = client.ID(withAuth("user", "pass"), &pb.IDRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pin, err := client.Pin(withAuth("user", "pass"), &pb.PinRequest{Cid: test.Cid1.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin.GetCid() != test.Cid1.String() {
+		t.Errorf("unexpected pin: %v", pin)
+	}
+}
+
+func TestNamespaces(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.Default()
+	// requests are restricted to the "test" namespace, like Cid3 in the
+	// mock.
+	cfg.Namespaces = map[string]string{"*": "test"}
+	s, client := testServerWithConfig(t, cfg)
+	defer s.Shutdown(ctx)
+
+	stream, err := client.ListPins(ctx, &pb.ListPinsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pins []*pb.Pin
+	for {
+		pin, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		pins = append(pins, pin)
+	}
+	if len(pins) != 1 || pins[0].GetCid() != test.Cid3.String() {
+		t.Errorf("expected only the pins in the namespace: %v", pins)
+	}
+
+	_, err = client.GetPin(ctx, &pb.GetPinRequest{Cid: test.Cid3.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.GetPin(ctx, &pb.GetPinRequest{Cid: test.Cid1.String()})
+	checkCode(t, err, codes.NotFound)
+
+	_, err = client.Status(ctx, &pb.StatusRequest{Cid: test.Cid1.String()})
+	checkCode(t, err, codes.NotFound)
+
+	_, err = client.Unpin(ctx, &pb.UnpinRequest{Cid: test.Cid1.String()})
+	checkCode(t, err, codes.NotFound)
+
+	_, err = client.Unpin(ctx, &pb.UnpinRequest{Cid: test.Cid3.String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statusStream, err := client.StatusAll(ctx, &pb.StatusAllRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gpis []*pb.GlobalPinInfo
+	for {
+		gpi, err := statusStream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		gpis = append(gpis, gpi)
+	}
+	if len(gpis) != 1 || gpis[0].GetCid() != test.Cid3.String() {
+		t.Errorf("expected only the items in the namespace: %v", gpis)
+	}
+}
+
+func TestTLS(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.SetBaseDir("../common/test")
+	err := cfg.LoadJSON([]byte(`{
+		"listen_multiaddress": "/ip4/127.0.0.1/tcp/0",
+		"ssl_cert_file": "server.crt",
+		"ssl_key_file": "server.key"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TLS == nil {
+		t.Fatal("TLS should be configured")
+	}
+
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Shutdown(ctx)
+	s.SetClient(test.NewMockRPCClient(t))
+
+	conn, err := grpc.Dial(
+		s.listeners[0].Addr().String(),
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	_, err = pb.NewClusterClient(conn).ID(ctx, &pb.IDRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,5 @@
+// Package pb provides the protobuf and gRPC definitions of the Cluster gRPC
+// API.
+//
+//go:generate protoc -I=. --go_out=. --go-grpc_out=. cluster.proto
+package pb
@@ -0,0 +1,183 @@
+package ipfsproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+)
+
+// maximum size of an IPFS pin response that we inspect in audit mode.
+const maxAuditResponseSize = 1 << 20
+
+// timeout for the RPC calls recording observed pins.
+var auditRecordTimeout = 10 * time.Second
+
+// auditResponseWriter passes the response from the IPFS daemon to the
+// client, keeping the status code and a copy of the body.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (aw *auditResponseWriter) WriteHeader(code int) {
+	aw.status = code
+	aw.ResponseWriter.WriteHeader(code)
+}
+
+func (aw *auditResponseWriter) Write(b []byte) (int, error) {
+	if aw.status == 0 {
+		aw.status = http.StatusOK
+	}
+	if room := maxAuditResponseSize - aw.body.Len(); room > 0 {
+		if len(b) < room {
+			room = len(b)
+		}
+		aw.body.Write(b[:room])
+	}
+	return aw.ResponseWriter.Write(b)
+}
+
+// Flush supports streamed responses (i.e. pin/add with progress).
+func (aw *auditResponseWriter) Flush() {
+	if f, ok := aw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// pins returns the CIDs in the last response object with a "Pins" field.
+// pin/add may send progress objects before it.
+func (aw *auditResponseWriter) pins() []api.Cid {
+	var pins []api.Cid
+	dec := json.NewDecoder(&aw.body)
+	for {
+		var resp ipfsPinOpResp
+		err := dec.Decode(&resp)
+		if err == io.EOF {
+			return pins
+		}
+		if err != nil {
+			logger.Warnf("audit mode: cannot decode IPFS pin response: %s", err)
+			return pins
+		}
+		if len(resp.Pins) == 0 {
+			continue
+		}
+		pins = pins[:0]
+		for _, p := range resp.Pins {
+			c, err := api.DecodeCid(p)
+			if err != nil {
+				logger.Warnf("audit mode: bad CID in IPFS pin response: %s", err)
+				continue
+			}
+			pins = append(pins, c)
+		}
+	}
+}
+
+// forwardAudited sends the request untouched to the IPFS daemon and returns
+// the pins in the response when it was successful.
+func (proxy *Server) forwardAudited(w http.ResponseWriter, r *http.Request) []api.Cid {
+	aw := &auditResponseWriter{ResponseWriter: w}
+	proxy.reverseProxy.ServeHTTP(aw, r)
+	if aw.status != http.StatusOK {
+		return nil
+	}
+	return aw.pins()
+}
+
+func (proxy *Server) observePin(c api.Cid, path string, mode api.PinMode) {
+	ctx, cancel := context.WithTimeout(proxy.ctx, auditRecordTimeout)
+	defer cancel()
+
+	err := proxy.rpcClient.CallContext(
+		ctx,
+		"",
+		"Cluster",
+		"ObservePin",
+		api.ObservedPin{
+			Cid:       c,
+			Path:      path,
+			Mode:      mode,
+			Timestamp: time.Now(),
+		},
+		&struct{}{},
+	)
+	if err != nil {
+		logger.Errorf("audit mode: error recording observed pin %s: %s", c, err)
+	}
+}
+
+func (proxy *Server) observeUnpin(c api.Cid) {
+	proxy.gatewayCache.invalidate(c)
+
+	ctx, cancel := context.WithTimeout(proxy.ctx, auditRecordTimeout)
+	defer cancel()
+
+	err := proxy.rpcClient.CallContext(
+		ctx,
+		"",
+		"Cluster",
+		"ObserveUnpin",
+		c,
+		&struct{}{},
+	)
+	if err != nil {
+		logger.Errorf("audit mode: error recording observed unpin %s: %s", c, err)
+	}
+}
+
+// auditSlashHandler is like slashHandler, but it also removes the argument
+// from the path, as audited requests are forwarded to the IPFS daemon.
+func auditSlashHandler(origHandler http.HandlerFunc) http.HandlerFunc {
+	return slashHandler(func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = path.Dir(r.URL.Path)
+		r.URL.RawPath = ""
+		origHandler(w, r)
+	})
+}
+
+// pinModeFromIPFSQuery returns the pin mode requested to the IPFS daemon,
+// which pins recursively unless told otherwise.
+func pinModeFromIPFSQuery(r *http.Request) api.PinMode {
+	if r.URL.Query().Get("recursive") == "false" {
+		return api.PinModeDirect
+	}
+	return api.PinModeRecursive
+}
+
+func (proxy *Server) auditPinHandler(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("arg")
+	mode := pinModeFromIPFSQuery(r)
+	for _, c := range proxy.forwardAudited(w, r) {
+		proxy.observePin(c, path, mode)
+	}
+}
+
+func (proxy *Server) auditUnpinHandler(w http.ResponseWriter, r *http.Request) {
+	for _, c := range proxy.forwardAudited(w, r) {
+		proxy.observeUnpin(c)
+	}
+}
+
+func (proxy *Server) auditPinUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	args := q["arg"]
+	unpin := !(q.Get("unpin") == "false")
+
+	// The response contains the from and to CIDs.
+	pins := proxy.forwardAudited(w, r)
+	if len(pins) != 2 || len(args) != 2 {
+		return
+	}
+	if unpin {
+		proxy.observeUnpin(pins[0])
+	}
+	proxy.observePin(pins[1], args[1], api.PinModeRecursive)
+}
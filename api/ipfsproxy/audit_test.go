@@ -0,0 +1,88 @@
+package ipfsproxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ipfs-cluster/ipfs-cluster/test"
+)
+
+func TestAuditResponseWriterPins(t *testing.T) {
+	rec := httptest.NewRecorder()
+	aw := &auditResponseWriter{ResponseWriter: rec}
+	aw.Write([]byte(`{"Progress":1}`))
+	aw.Write([]byte(`{"Progress":2}`))
+	aw.Write([]byte(fmt.Sprintf(`{"Pins":["%s"]}`, test.Cid1)))
+
+	if aw.status != http.StatusOK {
+		t.Error("expected a 200 status")
+	}
+	if rec.Body.Len() != aw.body.Len() {
+		t.Error("the response should be passed to the client")
+	}
+	pins := aw.pins()
+	if len(pins) != 1 || !pins[0].Equals(test.Cid1) {
+		t.Errorf("unexpected pins: %v", pins)
+	}
+}
+
+func TestIPFSProxyAuditMode(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.Default()
+	cfg.AuditMode = true
+	proxy, mock := testIPFSProxyWithConfig(t, cfg)
+	defer mock.Close()
+	defer proxy.Shutdown(ctx)
+
+	// Cid4 is not pinned in the mock cluster state.
+	res, err := http.Post(fmt.Sprintf("%s/pin/add?arg=%s", proxyURL(proxy), test.Cid4), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatal("the request should have succeeded")
+	}
+
+	var resp ipfsPinOpResp
+	err = json.NewDecoder(res.Body).Decode(&resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Pins) != 1 || resp.Pins[0] != test.Cid4.String() {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	// The undocumented form is audited too.
+	res3, err := http.Post(fmt.Sprintf("%s/pin/add/%s", proxyURL(proxy), test.Cid4), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res3.Body.Close()
+	if res3.StatusCode != http.StatusOK {
+		t.Fatal("the request should have succeeded")
+	}
+	var resp3 ipfsPinOpResp
+	err = json.NewDecoder(res3.Body).Decode(&resp3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp3.Pins) != 1 || resp3.Pins[0] != test.Cid4.String() {
+		t.Errorf("unexpected response: %+v", resp3)
+	}
+
+	// pin/ls is answered by the IPFS daemon.
+	res2, err := http.Post(fmt.Sprintf("%s/pin/ls?arg=%s", proxyURL(proxy), test.Cid4), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res2.Body.Close()
+	if res2.StatusCode != http.StatusOK {
+		t.Error("the IPFS daemon should have the pin")
+	}
+}
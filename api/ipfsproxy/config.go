@@ -28,6 +28,7 @@ var DefaultListenAddrs = []string{
 const (
 	DefaultNodeAddr           = "/ip4/127.0.0.1/tcp/5001"
 	DefaultNodeHTTPS          = false
+	DefaultAuditMode          = false
 	DefaultReadTimeout        = 0
 	DefaultReadHeaderTimeout  = 5 * time.Second
 	DefaultWriteTimeout       = 0
@@ -35,6 +36,9 @@ const (
 	DefaultExtractHeadersPath = "/api/v0/version"
 	DefaultExtractHeadersTTL  = 5 * time.Minute
 	DefaultMaxHeaderBytes     = minMaxHeaderBytes
+	DefaultGatewayCacheSize   = 0
+	DefaultGatewayCacheTTL    = 10 * time.Minute
+	DefaultGatewayCachePath   = "gateway-cache"
 )
 
 // Config allows to customize behavior of IPFSProxy.
@@ -51,6 +55,12 @@ type Config struct {
 	// Should we talk to the IPFS API over HTTPS? (experimental, untested)
 	NodeHTTPS bool
 
+	// AuditMode disables the hijacking of pin/add, pin/rm, pin/update
+	// and pin/ls requests. They are passed untouched to the IPFS daemon
+	// and successful pin operations are recorded by cluster as observed
+	// external pins, without adding them to the shared state.
+	AuditMode bool
+
 	// LogFile is path of the file that would save Proxy API logs. If this
 	// path is empty, logs would be sent to standard output. This path
 	// should either be absolute or relative to cluster base directory. Its
@@ -92,6 +102,21 @@ type Config struct {
 	// refresh them with a new request. 0 means always.
 	ExtractHeadersTTL time.Duration
 
+	// GatewayCacheSize is the maximum size, in bytes, of the disk cache
+	// for the gateway responses (/ipfs/... requests) served through the
+	// proxy. 0 disables the cache.
+	GatewayCacheSize int64
+
+	// GatewayCacheTTL is how long cached gateway responses are served
+	// before asking the IPFS daemon again. 0 means forever, until the
+	// content is unpinned or evicted.
+	GatewayCacheTTL time.Duration
+
+	// GatewayCachePath is the folder where cached gateway responses are
+	// stored. It is emptied when the proxy starts. This path should
+	// either be absolute or relative to cluster base directory.
+	GatewayCachePath string
+
 	// Tracing flag used to skip tracing specific paths when not enabled.
 	Tracing bool
 }
@@ -100,6 +125,7 @@ type jsonConfig struct {
 	ListenMultiaddress config.Strings `json:"listen_multiaddress"`
 	NodeMultiaddress   string         `json:"node_multiaddress"`
 	NodeHTTPS          bool           `json:"node_https,omitempty"`
+	AuditMode          bool           `json:"audit_mode,omitempty"`
 
 	LogFile string `json:"log_file"`
 
@@ -112,6 +138,10 @@ type jsonConfig struct {
 	ExtractHeadersExtra []string `json:"extract_headers_extra,omitempty"`
 	ExtractHeadersPath  string   `json:"extract_headers_path,omitempty"`
 	ExtractHeadersTTL   string   `json:"extract_headers_ttl,omitempty"`
+
+	GatewayCacheSize int64  `json:"gateway_cache_size,omitempty"`
+	GatewayCacheTTL  string `json:"gateway_cache_ttl,omitempty"`
+	GatewayCachePath string `json:"gateway_cache_path,omitempty"`
 }
 
 // getLogPath gets full path of the file where proxy logs should be
@@ -128,6 +158,16 @@ func (cfg *Config) getLogPath() string {
 	return filepath.Join(cfg.BaseDir, cfg.LogFile)
 }
 
+// getGatewayCachePath gets the full path of the folder where gateway
+// responses are cached.
+func (cfg *Config) getGatewayCachePath() string {
+	if filepath.IsAbs(cfg.GatewayCachePath) || cfg.BaseDir == "" {
+		return cfg.GatewayCachePath
+	}
+
+	return filepath.Join(cfg.BaseDir, cfg.GatewayCachePath)
+}
+
 // ConfigKey provides a human-friendly identifier for this type of Config.
 func (cfg *Config) ConfigKey() string {
 	return configKey
@@ -149,6 +189,7 @@ func (cfg *Config) Default() error {
 	}
 	cfg.ListenAddr = proxy
 	cfg.NodeAddr = node
+	cfg.AuditMode = DefaultAuditMode
 	cfg.LogFile = ""
 	cfg.ReadTimeout = DefaultReadTimeout
 	cfg.ReadHeaderTimeout = DefaultReadHeaderTimeout
@@ -158,6 +199,9 @@ func (cfg *Config) Default() error {
 	cfg.ExtractHeadersPath = DefaultExtractHeadersPath
 	cfg.ExtractHeadersTTL = DefaultExtractHeadersTTL
 	cfg.MaxHeaderBytes = DefaultMaxHeaderBytes
+	cfg.GatewayCacheSize = DefaultGatewayCacheSize
+	cfg.GatewayCacheTTL = DefaultGatewayCacheTTL
+	cfg.GatewayCachePath = DefaultGatewayCachePath
 
 	return nil
 }
@@ -217,6 +261,18 @@ func (cfg *Config) Validate() error {
 		err = fmt.Errorf("ipfsproxy.max_header_size must be greater or equal to %d", minMaxHeaderBytes)
 	}
 
+	if cfg.GatewayCacheSize < 0 {
+		err = errors.New("ipfsproxy.gateway_cache_size is invalid")
+	}
+
+	if cfg.GatewayCacheTTL < 0 {
+		err = errors.New("ipfsproxy.gateway_cache_ttl is invalid")
+	}
+
+	if cfg.GatewayCacheSize > 0 && cfg.GatewayCachePath == "" {
+		err = errors.New("ipfsproxy.gateway_cache_path should not be empty")
+	}
+
 	return err
 }
 
@@ -256,6 +312,7 @@ func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 		cfg.NodeAddr = nodeAddr
 	}
 	config.SetIfNotDefault(jcfg.NodeHTTPS, &cfg.NodeHTTPS)
+	config.SetIfNotDefault(jcfg.AuditMode, &cfg.AuditMode)
 
 	config.SetIfNotDefault(jcfg.LogFile, &cfg.LogFile)
 
@@ -266,6 +323,7 @@ func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 		&config.DurationOpt{Duration: jcfg.WriteTimeout, Dst: &cfg.WriteTimeout, Name: "write_timeout"},
 		&config.DurationOpt{Duration: jcfg.IdleTimeout, Dst: &cfg.IdleTimeout, Name: "idle_timeout"},
 		&config.DurationOpt{Duration: jcfg.ExtractHeadersTTL, Dst: &cfg.ExtractHeadersTTL, Name: "extract_header_ttl"},
+		&config.DurationOpt{Duration: jcfg.GatewayCacheTTL, Dst: &cfg.GatewayCacheTTL, Name: "gateway_cache_ttl"},
 	)
 	if err != nil {
 		return err
@@ -282,6 +340,11 @@ func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 	}
 	config.SetIfNotDefault(jcfg.ExtractHeadersPath, &cfg.ExtractHeadersPath)
 
+	if jcfg.GatewayCacheSize != 0 {
+		cfg.GatewayCacheSize = jcfg.GatewayCacheSize
+	}
+	config.SetIfNotDefault(jcfg.GatewayCachePath, &cfg.GatewayCachePath)
+
 	return cfg.Validate()
 }
 
@@ -296,6 +359,12 @@ func (cfg *Config) ToJSON() (raw []byte, err error) {
 	return
 }
 
+// JSONKeys returns the keys of the JSON representation of this
+// configuration, including those omitted when empty.
+func (cfg *Config) JSONKeys() []string {
+	return config.JSONKeys(jsonConfig{})
+}
+
 func (cfg *Config) toJSONConfig() (jcfg *jsonConfig, err error) {
 	// Multiaddress String() may panic
 	defer func() {
@@ -320,6 +389,7 @@ func (cfg *Config) toJSONConfig() (jcfg *jsonConfig, err error) {
 	jcfg.IdleTimeout = cfg.IdleTimeout.String()
 	jcfg.MaxHeaderBytes = cfg.MaxHeaderBytes
 	jcfg.NodeHTTPS = cfg.NodeHTTPS
+	jcfg.AuditMode = cfg.AuditMode
 	jcfg.LogFile = cfg.LogFile
 
 	jcfg.ExtractHeadersExtra = cfg.ExtractHeadersExtra
@@ -330,6 +400,14 @@ func (cfg *Config) toJSONConfig() (jcfg *jsonConfig, err error) {
 		jcfg.ExtractHeadersTTL = ttl.String()
 	}
 
+	jcfg.GatewayCacheSize = cfg.GatewayCacheSize
+	if ttl := cfg.GatewayCacheTTL; ttl != DefaultGatewayCacheTTL {
+		jcfg.GatewayCacheTTL = ttl.String()
+	}
+	if cfg.GatewayCachePath != DefaultGatewayCachePath {
+		jcfg.GatewayCachePath = cfg.GatewayCachePath
+	}
+
 	return
 }
 
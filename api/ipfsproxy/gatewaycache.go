@@ -0,0 +1,320 @@
+package ipfsproxy
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+)
+
+// headers from the IPFS gateway responses which are stored along with the
+// cached body and served on cache hits.
+var gatewayCacheHeaders = []string{
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Type",
+	"Etag",
+	"Last-Modified",
+	"X-Ipfs-Path",
+	"X-Ipfs-Roots",
+}
+
+// gatewayCacheStatusHeader tells whether a gateway response was served
+// from the cache.
+const gatewayCacheStatusHeader = "X-Cluster-Gateway-Cache"
+
+var errGatewayCacheTooLarge = errors.New("response does not fit in the gateway cache")
+
+// time to wait before subscribing again to consensus events.
+var gatewayCacheResubscribeDelay = 5 * time.Second
+
+type gatewayCacheEntry struct {
+	key    string
+	root   api.Cid
+	file   string
+	size   int64
+	header http.Header
+	stored time.Time
+}
+
+// gatewayCache is an LRU cache for gateway responses which stores the
+// bodies on disk. Entries expire after a TTL and are removed when their
+// root CID is unpinned. The cache is emptied on start.
+type gatewayCache struct {
+	dir     string
+	maxSize int64
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	size    int64
+	lru     *list.List // front is most recently used
+	entries map[string]*list.Element
+}
+
+func newGatewayCache(dir string, maxSize int64, ttl time.Duration) (*gatewayCache, error) {
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &gatewayCache{
+		dir:     dir,
+		maxSize: maxSize,
+		ttl:     ttl,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}, nil
+}
+
+// gatewayCacheKey identifies a gateway response by its path, query and
+// requested format.
+func gatewayCacheKey(r *http.Request) string {
+	return r.URL.Path + "?" + r.URL.RawQuery + "#" + r.Header.Get("Accept")
+}
+
+// gatewayRoot returns the CID in an /ipfs/<cid>/... path.
+func gatewayRoot(p string) (api.Cid, bool) {
+	segs := strings.SplitN(strings.TrimPrefix(p, "/ipfs/"), "/", 2)
+	c, err := api.DecodeCid(segs[0])
+	if err != nil {
+		return api.CidUndef, false
+	}
+	return c, true
+}
+
+// get returns a non-expired entry and marks it as recently used.
+func (gc *gatewayCache) get(key string) (*gatewayCacheEntry, bool) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	elem, ok := gc.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*gatewayCacheEntry)
+	if gc.ttl > 0 && time.Since(entry.stored) > gc.ttl {
+		gc.remove(elem)
+		return nil, false
+	}
+	gc.lru.MoveToFront(elem)
+	return entry, true
+}
+
+// add stores an entry whose body has been written to entry.file, replacing
+// any previous one with the same key, and evicts
+// the least recently used entries until the cache fits in its size.
+func (gc *gatewayCache) add(entry *gatewayCacheEntry) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	if elem, ok := gc.entries[entry.key]; ok {
+		gc.remove(elem)
+	}
+	gc.entries[entry.key] = gc.lru.PushFront(entry)
+	gc.size += entry.size
+
+	for gc.size > gc.maxSize {
+		gc.remove(gc.lru.Back())
+	}
+}
+
+// remove must be called with the lock held.
+func (gc *gatewayCache) remove(elem *list.Element) {
+	entry := gc.lru.Remove(elem).(*gatewayCacheEntry)
+	delete(gc.entries, entry.key)
+	gc.size -= entry.size
+	if err := os.Remove(entry.file); err != nil && !os.IsNotExist(err) {
+		logger.Warnf("gateway cache: error removing %s: %s", entry.file, err)
+	}
+}
+
+// invalidate removes all the entries for the given root CID.
+func (gc *gatewayCache) invalidate(c api.Cid) {
+	if gc == nil {
+		return
+	}
+
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	var n int
+	for elem := gc.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if elem.Value.(*gatewayCacheEntry).root.Equals(c) {
+			gc.remove(elem)
+			n++
+		}
+		elem = next
+	}
+	if n > 0 {
+		logger.Debugf("gateway cache: %d entries removed for %s", n, c)
+	}
+}
+
+// serve writes a cached response. It returns false on cache misses.
+func (gc *gatewayCache) serve(w http.ResponseWriter, key string) bool {
+	entry, ok := gc.get(key)
+	if !ok {
+		return false
+	}
+	f, err := os.Open(entry.file)
+	if err != nil {
+		// evicted in the meantime
+		return false
+	}
+	defer f.Close()
+
+	for k, v := range entry.header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(gatewayCacheStatusHeader, "HIT")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, f)
+	return true
+}
+
+// gatewayCacheWriter passes the response from the IPFS daemon to the client
+// and keeps a copy of the body on disk while it fits in the cache.
+type gatewayCacheWriter struct {
+	http.ResponseWriter
+	cache *gatewayCache
+	entry *gatewayCacheEntry
+
+	status int
+	tmp    *os.File
+	err    error
+}
+
+func (gc *gatewayCache) newWriter(w http.ResponseWriter, key string, root api.Cid) *gatewayCacheWriter {
+	w.Header().Set(gatewayCacheStatusHeader, "MISS")
+	return &gatewayCacheWriter{
+		ResponseWriter: w,
+		cache:          gc,
+		entry: &gatewayCacheEntry{
+			key:  key,
+			root: root,
+		},
+	}
+}
+
+func (cw *gatewayCacheWriter) WriteHeader(code int) {
+	cw.status = code
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *gatewayCacheWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if cw.status == http.StatusOK && cw.err == nil {
+		cw.store(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *gatewayCacheWriter) store(b []byte) {
+	if cw.entry.size+int64(len(b)) > cw.cache.maxSize {
+		cw.err = errGatewayCacheTooLarge
+		return
+	}
+	if cw.tmp == nil {
+		cw.tmp, cw.err = os.CreateTemp(cw.cache.dir, "entry-")
+		if cw.err != nil {
+			logger.Warnf("gateway cache: %s", cw.err)
+			return
+		}
+	}
+	n, err := cw.tmp.Write(b)
+	cw.entry.size += int64(n)
+	if err != nil {
+		logger.Warnf("gateway cache: %s", err)
+		cw.err = err
+	}
+}
+
+// Flush supports streamed responses.
+func (cw *gatewayCacheWriter) Flush() {
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish adds the response to the cache if it was successful and complete.
+func (cw *gatewayCacheWriter) finish(ctx context.Context) {
+	if cw.tmp == nil {
+		return
+	}
+	cw.entry.file = cw.tmp.Name()
+	closeErr := cw.tmp.Close()
+
+	if cw.err != nil || closeErr != nil || ctx.Err() != nil {
+		os.Remove(cw.entry.file)
+		return
+	}
+
+	header := make(http.Header)
+	for _, k := range gatewayCacheHeaders {
+		if v := cw.Header().Values(k); len(v) > 0 {
+			header[k] = v
+		}
+	}
+	cw.entry.header = header
+	cw.entry.stored = time.Now()
+	cw.cache.add(cw.entry)
+}
+
+// gatewayHandler serves gateway requests from the cache, or forwards them
+// to the IPFS daemon and caches the responses.
+func (proxy *Server) gatewayHandler(w http.ResponseWriter, r *http.Request) {
+	root, ok := gatewayRoot(r.URL.Path)
+	if !ok || r.Header.Get("Range") != "" {
+		proxy.reverseProxy.ServeHTTP(w, r)
+		return
+	}
+
+	key := gatewayCacheKey(r)
+	if proxy.gatewayCache.serve(w, key) {
+		return
+	}
+
+	cw := proxy.gatewayCache.newWriter(w, key, root)
+	proxy.reverseProxy.ServeHTTP(cw, r)
+	cw.finish(r.Context())
+}
+
+// watchUnpins removes from the gateway cache the content unpinned from
+// the cluster.
+func (proxy *Server) watchUnpins() {
+	for {
+		in := make(chan struct{})
+		close(in)
+		out := make(chan api.ConsensusEvent, 1024)
+
+		go func() {
+			for ev := range out {
+				if ev.Type == api.ConsensusEventUnpin {
+					proxy.gatewayCache.invalidate(ev.Pin.Cid)
+				}
+			}
+		}()
+
+		err := proxy.rpcClient.Stream(proxy.ctx, "", "Cluster", "ConsensusEvents", in, out)
+		if err != nil {
+			logger.Debugf("gateway cache: consensus events: %s", err)
+		}
+
+		select {
+		case <-proxy.ctx.Done():
+			return
+		case <-time.After(gatewayCacheResubscribeDelay):
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package ipfsproxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/ipfs-cluster/ipfs-cluster/test"
+)
+
+func testGatewayGet(t *testing.T, proxy *Server, path string) (string, string) {
+	t.Helper()
+	res, err := http.Get(fmt.Sprintf("http://%s%s", proxy.listeners[0].Addr(), path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", res.StatusCode, body)
+	}
+	return string(body), res.Header.Get(gatewayCacheStatusHeader)
+}
+
+func TestGatewayCache(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.Default()
+	cfg.GatewayCacheSize = 1024
+	cfg.GatewayCachePath = t.TempDir()
+	proxy, mock := testIPFSProxyWithConfig(t, cfg)
+	defer mock.Close()
+	defer proxy.Shutdown(ctx)
+
+	path := "/ipfs/" + test.Cid1.String() + "/file"
+	body, status := testGatewayGet(t, proxy, path)
+	if body != path || status != "MISS" {
+		t.Fatalf("unexpected first response: %s (%s)", body, status)
+	}
+	body, status = testGatewayGet(t, proxy, path)
+	if body != path || status != "HIT" {
+		t.Fatalf("unexpected cached response: %s (%s)", body, status)
+	}
+
+	// Unpinning through the proxy removes the content from the cache.
+	res, err := http.Post(fmt.Sprintf("%s/pin/rm?arg=%s", proxyURL(proxy), test.Cid1), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	_, status = testGatewayGet(t, proxy, path)
+	if status != "MISS" {
+		t.Errorf("cache should have been invalidated on unpin: %s", status)
+	}
+
+	// Least recently used entries are evicted to make room.
+	proxy.gatewayCache.maxSize = int64(len(path)) + 1
+	other := "/ipfs/" + test.Cid2.String() + "/file"
+	testGatewayGet(t, proxy, other)
+	_, status = testGatewayGet(t, proxy, other)
+	if status != "HIT" {
+		t.Errorf("new entry should be cached: %s", status)
+	}
+	_, status = testGatewayGet(t, proxy, path)
+	if status != "MISS" {
+		t.Errorf("old entry should have been evicted: %s", status)
+	}
+
+	// Responses larger than the cache are not stored.
+	proxy.gatewayCache.maxSize = 10
+	testGatewayGet(t, proxy, other)
+	_, status = testGatewayGet(t, proxy, other)
+	if status != "MISS" {
+		t.Errorf("large responses should not be cached: %s", status)
+	}
+}
@@ -71,6 +71,8 @@ type Server struct {
 
 	ipfsHeadersStore sync.Map
 
+	gatewayCache *gatewayCache // nil when disabled
+
 	shutdownLock sync.Mutex
 	shutdown     bool
 	wg           sync.WaitGroup
@@ -209,6 +211,23 @@ func New(cfg *Config) (*Server, error) {
 		reverseProxy: reverseProxy,
 	}
 
+	if cfg.GatewayCacheSize > 0 {
+		proxy.gatewayCache, err = newGatewayCache(
+			cfg.getGatewayCachePath(),
+			cfg.GatewayCacheSize,
+			cfg.GatewayCacheTTL,
+		)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("error creating the gateway cache: %w", err)
+		}
+		router.
+			Methods(http.MethodGet).
+			PathPrefix("/ipfs/").
+			HandlerFunc(proxy.gatewayHandler).
+			Name("Gateway")
+	}
+
 	// Ideally, we should only intercept POST requests, but
 	// people may be calling the API with GET or worse, PUT
 	// because IPFS has been allowing this traditionally.
@@ -220,34 +239,60 @@ func New(cfg *Config) (*Server, error) {
 		Subrouter()
 
 	// Add hijacked routes
-	hijackSubrouter.
-		Path("/pin/add/{arg}").
-		HandlerFunc(slashHandler(proxy.pinHandler)).
-		Name("PinAddSlash") // supports people using the API wrong.
-	hijackSubrouter.
-		Path("/pin/add").
-		HandlerFunc(proxy.pinHandler).
-		Name("PinAdd")
-	hijackSubrouter.
-		Path("/pin/rm/{arg}").
-		HandlerFunc(slashHandler(proxy.unpinHandler)).
-		Name("PinRmSlash") // supports people using the API wrong.
-	hijackSubrouter.
-		Path("/pin/rm").
-		HandlerFunc(proxy.unpinHandler).
-		Name("PinRm")
-	hijackSubrouter.
-		Path("/pin/ls/{arg}").
-		HandlerFunc(slashHandler(proxy.pinLsHandler)).
-		Name("PinLsSlash") // supports people using the API wrong.
-	hijackSubrouter.
-		Path("/pin/ls").
-		HandlerFunc(proxy.pinLsHandler).
-		Name("PinLs")
-	hijackSubrouter.
-		Path("/pin/update").
-		HandlerFunc(proxy.pinUpdateHandler).
-		Name("PinUpdate")
+	if cfg.AuditMode {
+		// Pin requests go to the IPFS daemon. We only look at the
+		// responses. Everything else (i.e. pin/ls) is not hijacked.
+		hijackSubrouter.
+			Path("/pin/add/{arg}").
+			HandlerFunc(auditSlashHandler(proxy.auditPinHandler)).
+			Name("PinAddSlash") // supports people using the API wrong.
+		hijackSubrouter.
+			Path("/pin/add").
+			HandlerFunc(proxy.auditPinHandler).
+			Name("PinAdd")
+		hijackSubrouter.
+			Path("/pin/rm/{arg}").
+			HandlerFunc(auditSlashHandler(proxy.auditUnpinHandler)).
+			Name("PinRmSlash") // supports people using the API wrong.
+		hijackSubrouter.
+			Path("/pin/rm").
+			HandlerFunc(proxy.auditUnpinHandler).
+			Name("PinRm")
+		hijackSubrouter.
+			Path("/pin/update").
+			HandlerFunc(proxy.auditPinUpdateHandler).
+			Name("PinUpdate")
+	} else {
+		hijackSubrouter.
+			Path("/pin/add/{arg}").
+			HandlerFunc(slashHandler(proxy.pinHandler)).
+			Name("PinAddSlash") // supports people using the API wrong.
+		hijackSubrouter.
+			Path("/pin/add").
+			HandlerFunc(proxy.pinHandler).
+			Name("PinAdd")
+		hijackSubrouter.
+			Path("/pin/rm/{arg}").
+			HandlerFunc(slashHandler(proxy.unpinHandler)).
+			Name("PinRmSlash") // supports people using the API wrong.
+		hijackSubrouter.
+			Path("/pin/rm").
+			HandlerFunc(proxy.unpinHandler).
+			Name("PinRm")
+		hijackSubrouter.
+			Path("/pin/ls/{arg}").
+			HandlerFunc(slashHandler(proxy.pinLsHandler)).
+			Name("PinLsSlash") // supports people using the API wrong.
+		hijackSubrouter.
+			Path("/pin/ls").
+			HandlerFunc(proxy.pinLsHandler).
+			Name("PinLs")
+		hijackSubrouter.
+			Path("/pin/update").
+			HandlerFunc(proxy.pinUpdateHandler).
+			Name("PinUpdate")
+	}
+
 	hijackSubrouter.
 		Path("/add").
 		HandlerFunc(proxy.addHandler).
@@ -317,6 +362,14 @@ func (proxy *Server) run() {
 	proxy.shutdownLock.Lock()
 	defer proxy.shutdownLock.Unlock()
 
+	if proxy.gatewayCache != nil && proxy.ctx.Err() == nil {
+		proxy.wg.Add(1)
+		go func() {
+			defer proxy.wg.Done()
+			proxy.watchUnpins()
+		}()
+	}
+
 	// This launches the proxy
 	proxy.wg.Add(len(proxy.listeners))
 	for _, l := range proxy.listeners {
@@ -387,6 +440,10 @@ func (proxy *Server) pinOpHandler(op string, w http.ResponseWriter, r *http.Requ
 	resBytes, _ := json.Marshal(res)
 	w.WriteHeader(http.StatusOK)
 	w.Write(resBytes)
+
+	if op == "UnpinPath" {
+		proxy.gatewayCache.invalidate(pin.Cid)
+	}
 }
 
 func (proxy *Server) pinHandler(w http.ResponseWriter, r *http.Request) {
@@ -650,6 +707,7 @@ func (proxy *Server) addHandler(w http.ResponseWriter, r *http.Request) {
 		proxy.ctx,
 		proxy.rpcClient,
 		params,
+		nil,
 		reader,
 		w,
 		outputTransform,
@@ -224,9 +224,21 @@ func (api *API) addPin(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if updateCid, ok := api.parseRequestIDOrFail(w, r); updateCid.Defined() && ok {
+			// the updated pin is removed below.
+			if !api.OwnsPinOrFail(w, r, updateCid) {
+				return
+			}
 			clusterPin.PinUpdate = updateCid
 		}
 
+		ns, ok := api.NamespaceOrFail(w, r, "")
+		if !ok {
+			return
+		}
+		clusterPin.Namespace = ns
+		clusterPin.Owners = api.Owners(r)
+		clusterPin.Requester = api.Requester(r)
+
 		// Pin item
 		var pinObj types.Pin
 		err = api.rpcClient.CallContext(
@@ -244,13 +256,16 @@ func (api *API) addPin(w http.ResponseWriter, r *http.Request) {
 
 		// Unpin old item
 		if clusterPin.PinUpdate.Defined() {
+			unpin := types.PinCid(clusterPin.PinUpdate)
+			unpin.Owners = clusterPin.Owners
+			unpin.Requester = clusterPin.Requester
 			var oldPin types.Pin
 			err = api.rpcClient.CallContext(
 				r.Context(),
 				"",
 				"Cluster",
 				"Unpin",
-				types.PinCid(clusterPin.PinUpdate),
+				unpin,
 				&oldPin,
 			)
 			if err != nil {
@@ -264,7 +279,10 @@ func (api *API) addPin(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (api *API) getPinSvcStatus(ctx context.Context, c types.Cid) (pinsvc.PinStatus, error) {
+// getPinSvcStatus returns the status of the pin for the given CID. When ns
+// is set, the pins of other namespaces have an undefined status, as if they
+// did not exist.
+func (api *API) getPinSvcStatus(ctx context.Context, c types.Cid, ns string) (pinsvc.PinStatus, error) {
 	var pinInfo types.GlobalPinInfo
 
 	err := api.rpcClient.CallContext(
@@ -278,6 +296,9 @@ func (api *API) getPinSvcStatus(ctx context.Context, c types.Cid) (pinsvc.PinSta
 	if err != nil {
 		return pinsvc.PinStatus{}, err
 	}
+	if ns != "" && pinInfo.Namespace != ns {
+		return pinsvc.PinStatus{}, nil
+	}
 	return globalPinInfoToSvcPinStatus(c.String(), pinInfo), nil
 
 }
@@ -288,7 +309,7 @@ func (api *API) getPin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	api.config.Logger.Debugf("getPin: %s", c)
-	status, err := api.getPinSvcStatus(r.Context(), c)
+	status, err := api.getPinSvcStatus(r.Context(), c, api.Namespace(r))
 	if status.Status == pinsvc.StatusUndefined {
 		api.SendResponse(w, http.StatusNotFound, errors.New("pin not found"), nil)
 		return
@@ -302,13 +323,19 @@ func (api *API) removePin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	api.config.Logger.Debugf("removePin: %s", c)
+	if !api.OwnsPinOrFail(w, r, c) {
+		return
+	}
+	pin := types.PinCid(c)
+	pin.Owners = api.Owners(r)
+	pin.Requester = api.Requester(r)
 	var pinObj types.Pin
 	err := api.rpcClient.CallContext(
 		r.Context(),
 		"",
 		"Cluster",
 		"Unpin",
-		types.PinCid(c),
+		pin,
 		&pinObj,
 	)
 	if err != nil && err.Error() == state.ErrNotFound.Error() {
@@ -326,6 +353,8 @@ func (api *API) listPins(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	tst := svcStatusToTrackerStatus(opts.Status)
+	// users restricted to a namespace only see its pins.
+	ns := api.Namespace(r)
 
 	var pinList pinsvc.PinList
 	pinList.Results = []pinsvc.PinStatus{}
@@ -349,7 +378,7 @@ func (api *API) listPins(w http.ResponseWriter, r *http.Request) {
 		for _, ci := range opts.Cids {
 			go func(c types.Cid) {
 				defer wg.Done()
-				st, err := api.getPinSvcStatus(r.Context(), c)
+				st, err := api.getPinSvcStatus(r.Context(), c, ns)
 				stCh <- statusResult{st: st, err: err}
 			}(ci)
 		}
@@ -394,6 +423,9 @@ func (api *API) listPins(w http.ResponseWriter, r *http.Request) {
 		}()
 
 		for gpi := range out {
+			if ns != "" && gpi.Namespace != ns {
+				continue
+			}
 			st := globalPinInfoToSvcPinStatus(gpi.Cid.String(), gpi)
 			if st.Status == pinsvc.StatusUndefined {
 				// i.e things unpinning
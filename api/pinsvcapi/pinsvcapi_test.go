@@ -252,6 +252,41 @@ func TestAPIRemovePinEndpoint(t *testing.T) {
 	test.BothEndpoints(t, tf)
 }
 
+func TestAPINamespaces(t *testing.T) {
+	ctx := context.Background()
+	cfg := NewConfig()
+	cfg.Default()
+	cfg.CORSAllowedOrigins = []string{"myorigin"}
+	cfg.CORSAllowedMethods = []string{"GET", "POST", "DELETE"}
+	// requests without credentials are restricted to the "test"
+	// namespace, like Cid3 in the mock.
+	cfg.Namespaces = map[string]string{"*": "test"}
+	svcapi := testAPIwithConfig(t, cfg, "namespaces")
+	defer svcapi.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		var resp pinsvc.PinList
+		test.MakeGet(t, svcapi, url(svcapi)+"/pins", &resp)
+		if resp.Count != 1 || !resp.Results[0].Pin.Cid.Equals(clustertest.Cid3) {
+			t.Errorf("expected only the pins in the namespace: %+v", resp)
+		}
+
+		var errResp pinsvc.APIError
+		test.MakeGet(t, svcapi, url(svcapi)+"/pins/"+clustertest.Cid1.String(), &errResp)
+		if errResp.Details.Reason == "" {
+			t.Error("a pin of another namespace should not be found")
+		}
+
+		errResp = pinsvc.APIError{}
+		test.MakeDelete(t, svcapi, url(svcapi)+"/pins/"+clustertest.Cid1.String(), &errResp)
+		if errResp.Details.Reason == "" {
+			t.Error("a pin of another namespace should not be removed")
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
 func TestHealthEndpoint(t *testing.T) {
 	ctx := context.Background()
 	svcapi := testAPI(t)
@@ -5,6 +5,7 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -66,6 +67,10 @@ type Client interface {
 	Pin(ctx context.Context, ci api.Cid, opts api.PinOptions) (api.Pin, error)
 	// Unpin untracks a Cid from cluster.
 	Unpin(ctx context.Context, ci api.Cid) (api.Pin, error)
+	// Restore takes a Cid out of the trash, so that it stays pinned.
+	Restore(ctx context.Context, ci api.Cid) (api.Pin, error)
+	// CancelPin aborts the pinning of a Cid, optionally unpinning it.
+	CancelPin(ctx context.Context, ci api.Cid, unpin bool) (api.Pin, error)
 
 	// PinPath resolves given path into a cid and performs the pin operation.
 	PinPath(ctx context.Context, path string, opts api.PinOptions) (api.Pin, error)
@@ -76,8 +81,22 @@ type Client interface {
 	// Allocations returns the consensus state listing all tracked items
 	// and the peers that should be pinning them.
 	Allocations(ctx context.Context, filter api.PinType, out chan<- api.Pin) error
+	// AllocationsWithFilter works like Allocations but only returns the
+	// items matching the given PinFilter.
+	AllocationsWithFilter(ctx context.Context, filter api.PinType, pinFilter api.PinFilter, out chan<- api.Pin) error
+	// AllocationsWithOptions returns the items in the consensus state
+	// selected by the given options. When opts.Limit is set, items are
+	// sorted by CID and the CID of the last one can be used as
+	// opts.After to get the next page.
+	AllocationsWithOptions(ctx context.Context, opts api.PinListOptions, out chan<- api.Pin) error
 	// Allocation returns the current allocations for a given Cid.
 	Allocation(ctx context.Context, ci api.Cid) (api.Pin, error)
+	// AllocationExplain returns how the given Cid would be allocated with
+	// the latest metrics and why every peer is chosen or not.
+	AllocationExplain(ctx context.Context, ci api.Cid) (api.AllocationExplanation, error)
+	// PinHistory returns the pin and unpin operations on the given Cid
+	// recorded by the cluster peers and who requested them, oldest first.
+	PinHistory(ctx context.Context, ci api.Cid) ([]api.PinAuditEntry, error)
 
 	// Status returns the current ipfs state for a given Cid. If local is true,
 	// the information affects only the current peer, otherwise the information
@@ -87,6 +106,9 @@ type Client interface {
 	StatusCids(ctx context.Context, cids []api.Cid, local bool, out chan<- api.GlobalPinInfo) error
 	// StatusAll gathers Status() for all tracked items.
 	StatusAll(ctx context.Context, filter api.TrackerStatus, local bool, out chan<- api.GlobalPinInfo) error
+	// StatusAllWithOptions gathers Status() for all tracked items,
+	// querying peers in batches and with a timeout for each of them.
+	StatusAllWithOptions(ctx context.Context, opts api.StatusAllOptions, local bool, out chan<- api.GlobalPinInfo) error
 
 	// Recover retriggers pin or unpin ipfs operations for a Cid in error
 	// state.  If local is true, the operation is limited to the current
@@ -96,6 +118,9 @@ type Client interface {
 	// local is true, the operation is limited to the current peer.
 	// Otherwise, it happens everywhere.
 	RecoverAll(ctx context.Context, local bool, out chan<- api.GlobalPinInfo) error
+	// RecoverAllWithOptions triggers Recover() operations on the
+	// tracked items selected by the options, at the given rate.
+	RecoverAllWithOptions(ctx context.Context, opts api.RecoverAllOptions, local bool, out chan<- api.GlobalPinInfo) error
 
 	// Alerts returns information health events in the cluster (expired
 	// metrics etc.).
@@ -122,6 +147,98 @@ type Client interface {
 	// returns collected CIDs. If local is true, it would garbage collect
 	// only on contacted peer, otherwise on all peers' IPFS daemons.
 	RepoGC(ctx context.Context, local bool) (api.GlobalRepoGC, error)
+
+	// PinQueues returns information about the queues of pin and unpin
+	// operations of the cluster peers. If local is true, only that of the
+	// contacted peer is returned.
+	PinQueues(ctx context.Context, local bool) (api.GlobalPinQueueInfo, error)
+
+	// DedupStats returns the last block deduplication statistics computed
+	// by the cluster peers. If local is true, only those from the
+	// contacted peer are returned.
+	DedupStats(ctx context.Context, local bool) (api.GlobalDedupStats, error)
+
+	// Capacity returns the storage capacity of the cluster peers and
+	// the cluster-wide totals. If local is true, only the contacted peer
+	// is included.
+	Capacity(ctx context.Context, local bool) (api.GlobalCapacity, error)
+
+	// SLOReport returns the compliance with the pin service level
+	// objectives over the SLO window. If local is true, only the pins
+	// of the contacted peer are counted.
+	SLOReport(ctx context.Context, local bool) (api.SLOReport, error)
+
+	// PinGC evaluates the pin garbage collection policy and returns the
+	// pins that it selects. They are unpinned when execute is true.
+	PinGC(ctx context.Context, execute bool) (api.PinGCReport, error)
+
+	// ObservedPins returns the pins made directly on the IPFS daemon of
+	// the contacted peer, as observed by its IPFS proxy in audit mode.
+	ObservedPins(ctx context.Context) ([]api.ObservedPin, error)
+
+	// PeersArchive returns the peers that have been removed from the
+	// cluster, most recently removed first.
+	PeersArchive(ctx context.Context) ([]api.ArchivedPeer, error)
+
+	// APIEndpoints returns the REST API endpoints advertised by the
+	// cluster peers.
+	APIEndpoints(ctx context.Context) ([]api.APIEndpoint, error)
+
+	// ConsensusLog returns up to limit operations committed to the
+	// consensus log before the given index (or the latest when 0),
+	// newest first.
+	ConsensusLog(ctx context.Context, before uint64, limit int) ([]api.ConsensusLogEntry, error)
+
+	// DeadLetters returns the operations that the contacted peer failed
+	// to apply to the shared state.
+	DeadLetters(ctx context.Context) ([]api.DeadLetter, error)
+	// RetryDeadLetter commits again the given failed operation.
+	RetryDeadLetter(ctx context.Context, id string) error
+	// DiscardDeadLetter forgets the given failed operation.
+	DiscardDeadLetter(ctx context.Context, id string) error
+
+	// ConsensusEvents streams an event for every operation applied to
+	// the shared state until the context is cancelled.
+	ConsensusEvents(ctx context.Context, out chan<- api.ConsensusEvent) error
+	// EventHistory streams the events in the cluster event history of
+	// the contacted peer which match the given query, oldest first.
+	EventHistory(ctx context.Context, q api.ClusterEventQuery, out chan<- api.ClusterEvent) error
+
+	// Quorum returns whether the consensus can commit operations, with
+	// the voters reachable by the contacted peer.
+	Quorum(ctx context.Context) (api.QuorumStatus, error)
+
+	// StateChecksum returns the checksum of the shared state of the
+	// cluster peers. If local is true, only the contacted peer is
+	// included.
+	StateChecksum(ctx context.Context, local bool) (api.GlobalStateChecksum, error)
+
+	// Rollback replaces the shared state with the given pinset. Pins
+	// not included are unpinned. It must be sent to the Raft leader.
+	Rollback(ctx context.Context, pins []api.Pin) (api.RollbackInfo, error)
+
+	// RunJob runs a maintenance job on the given cluster peers, or on
+	// all of them when none are given, and returns their results.
+	RunJob(ctx context.Context, job string, peers []peer.ID) (api.GlobalJobResult, error)
+
+	// Handoff starts transferring the selected pins to another cluster.
+	// The handoff runs on the contacted peer, which must be asked for
+	// its progress.
+	Handoff(ctx context.Context, req api.HandoffRequest) (api.HandoffJob, error)
+	// Handoffs returns the handoffs started on the contacted peer.
+	Handoffs(ctx context.Context) ([]api.HandoffJob, error)
+	// HandoffStatus returns the progress of a handoff.
+	HandoffStatus(ctx context.Context, id string) (api.HandoffJob, error)
+	// CancelHandoff stops a handoff.
+	CancelHandoff(ctx context.Context, id string) error
+
+	// SchedulePin registers a pin to be committed at a later time, or
+	// repeatedly following a cron expression.
+	SchedulePin(ctx context.Context, s api.PinSchedule) (api.PinSchedule, error)
+	// PinSchedules returns the pin schedules in the cluster.
+	PinSchedules(ctx context.Context) ([]api.PinSchedule, error)
+	// CancelPinSchedule removes a pin schedule.
+	CancelPinSchedule(ctx context.Context, id string) error
 	
 	// Health returns no content when everything is ok, and an error otherwise
 	Health(ctx context.Context) (error)
@@ -139,6 +256,10 @@ type Config struct {
 	Username string
 	Password string
 
+	// Token is a bearer token, like one obtained from the SSO provider
+	// configured in the API. Ignored when Username is set.
+	Token string
+
 	// The ipfs-cluster REST API endpoint in multiaddress form
 	// (takes precedence over host:port). It this address contains
 	// an /ipfs/, /p2p/ or /dnsaddr, the API will be contacted
@@ -193,6 +314,38 @@ func (c *Config) AsTemplateForResolvedAddress(ctx context.Context, addr ma.Multi
 	return c.AsTemplateFor(resolvedAddrs), nil
 }
 
+// AsTemplateForDiscovered asks the cluster peer that this configuration
+// points to for the REST API endpoints advertised by all the cluster peers,
+// and uses this configuration as a template for each of them, so that the
+// result can be given to NewLBClient. When publicOnly is set, only endpoints
+// which accept public traffic are used.
+func (c *Config) AsTemplateForDiscovered(ctx context.Context, publicOnly bool) ([]*Config, error) {
+	cfg := *c
+	client, err := NewDefaultClient(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints, err := client.APIEndpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var addrs []ma.Multiaddr
+	for _, ep := range endpoints {
+		if publicOnly && !ep.Public {
+			continue
+		}
+		for _, addr := range ep.Addresses {
+			addrs = append(addrs, addr.Value())
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, errors.New("no API endpoints are advertised by the cluster peers")
+	}
+	return c.AsTemplateFor(addrs), nil
+}
+
 // DefaultClient provides methods to interact with the ipfs-cluster API. Use
 // NewDefaultClient() to create one.
 type defaultClient struct {
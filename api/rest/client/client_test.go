@@ -109,6 +109,29 @@ func TestNewDefaultClient(t *testing.T) {
 	}
 }
 
+func TestAsTemplateForDiscovered(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	tmpl := &Config{
+		APIAddr:           apiMAddr(api),
+		Username:          "user",
+		DisableKeepAlives: true,
+	}
+	cfgs, err := tmpl.AsTemplateForDiscovered(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The mock advertises a single public endpoint.
+	if len(cfgs) != 1 || cfgs[0].APIAddr.String() != "/ip4/1.2.3.4/tcp/9094" || cfgs[0].Username != "user" {
+		t.Errorf("unexpected discovered configurations: %+v", cfgs)
+	}
+	if !tmpl.APIAddr.Equal(apiMAddr(api)) {
+		t.Error("the template should not be modified")
+	}
+}
+
 func TestDefaultAddress(t *testing.T) {
 	cfg := &Config{
 		APIAddr:           nil,
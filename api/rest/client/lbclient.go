@@ -200,6 +200,32 @@ func (lc *loadBalancingClient) Unpin(ctx context.Context, ci api.Cid) (api.Pin,
 	return pin, err
 }
 
+// Restore takes a Cid out of the trash, so that it stays pinned.
+func (lc *loadBalancingClient) Restore(ctx context.Context, ci api.Cid) (api.Pin, error) {
+	var pin api.Pin
+	call := func(c Client) error {
+		var err error
+		pin, err = c.Restore(ctx, ci)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return pin, err
+}
+
+// CancelPin aborts the pinning of a Cid, optionally unpinning it.
+func (lc *loadBalancingClient) CancelPin(ctx context.Context, ci api.Cid, unpin bool) (api.Pin, error) {
+	var pin api.Pin
+	call := func(c Client) error {
+		var err error
+		pin, err = c.CancelPin(ctx, ci, unpin)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return pin, err
+}
+
 // PinPath allows to pin an element by the given IPFS path.
 func (lc *loadBalancingClient) PinPath(ctx context.Context, path string, opts api.PinOptions) (api.Pin, error) {
 	var pin api.Pin
@@ -230,6 +256,22 @@ func (lc *loadBalancingClient) UnpinPath(ctx context.Context, p string) (api.Pin
 // Allocations returns the consensus state listing all tracked items and
 // the peers that should be pinning them.
 func (lc *loadBalancingClient) Allocations(ctx context.Context, filter api.PinType, out chan<- api.Pin) error {
+	return lc.AllocationsWithFilter(ctx, filter, api.PinFilter{}, out)
+}
+
+// AllocationsWithFilter works like Allocations but only returns the items
+// matching the given PinFilter.
+func (lc *loadBalancingClient) AllocationsWithFilter(ctx context.Context, filter api.PinType, pinFilter api.PinFilter, out chan<- api.Pin) error {
+	opts := api.PinListOptions{
+		Type:      filter,
+		PinFilter: pinFilter,
+	}
+	return lc.AllocationsWithOptions(ctx, opts, out)
+}
+
+// AllocationsWithOptions returns the items in the consensus state selected
+// by the given options.
+func (lc *loadBalancingClient) AllocationsWithOptions(ctx context.Context, opts api.PinListOptions, out chan<- api.Pin) error {
 	call := func(c Client) error {
 		done := make(chan struct{})
 		cout := make(chan api.Pin, cap(out))
@@ -241,7 +283,7 @@ func (lc *loadBalancingClient) Allocations(ctx context.Context, filter api.PinTy
 		}()
 
 		// this blocks until done
-		err := c.Allocations(ctx, filter, cout)
+		err := c.AllocationsWithOptions(ctx, opts, cout)
 		// wait for cout to be closed
 		select {
 		case <-ctx.Done():
@@ -268,6 +310,34 @@ func (lc *loadBalancingClient) Allocation(ctx context.Context, ci api.Cid) (api.
 	return pin, err
 }
 
+// AllocationExplain returns how the given Cid would be allocated with the
+// latest metrics and why every peer is chosen or not.
+func (lc *loadBalancingClient) AllocationExplain(ctx context.Context, ci api.Cid) (api.AllocationExplanation, error) {
+	var expl api.AllocationExplanation
+	call := func(c Client) error {
+		var err error
+		expl, err = c.AllocationExplain(ctx, ci)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return expl, err
+}
+
+// PinHistory returns the pin and unpin operations on the given Cid recorded
+// by the cluster peers and who requested them, oldest first.
+func (lc *loadBalancingClient) PinHistory(ctx context.Context, ci api.Cid) ([]api.PinAuditEntry, error) {
+	var entries []api.PinAuditEntry
+	call := func(c Client) error {
+		var err error
+		entries, err = c.PinHistory(ctx, ci)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return entries, err
+}
+
 // Status returns the current ipfs state for a given Cid. If local is true,
 // the information affects only the current peer, otherwise the information
 // is fetched from all cluster peers.
@@ -343,6 +413,34 @@ func (lc *loadBalancingClient) StatusAll(ctx context.Context, filter api.Tracker
 	return err
 }
 
+// StatusAllWithOptions gathers Status() for all tracked items, querying
+// peers in batches and with a timeout for each of them.
+func (lc *loadBalancingClient) StatusAllWithOptions(ctx context.Context, opts api.StatusAllOptions, local bool, out chan<- api.GlobalPinInfo) error {
+	call := func(c Client) error {
+		done := make(chan struct{})
+		cout := make(chan api.GlobalPinInfo, cap(out))
+		go func() {
+			for o := range cout {
+				out <- o
+			}
+			done <- struct{}{}
+		}()
+
+		// this blocks until done
+		err := c.StatusAllWithOptions(ctx, opts, local, cout)
+		// wait for cout to be closed
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		return err
+	}
+
+	err := lc.retry(0, call)
+	close(out)
+	return err
+}
+
 // Recover retriggers pin or unpin ipfs operations for a Cid in error state.
 // If local is true, the operation is limited to the current peer, otherwise
 // it happens on every cluster peer.
@@ -362,6 +460,12 @@ func (lc *loadBalancingClient) Recover(ctx context.Context, ci api.Cid, local bo
 // true, the operation is limited to the current peer. Otherwise, it happens
 // everywhere.
 func (lc *loadBalancingClient) RecoverAll(ctx context.Context, local bool, out chan<- api.GlobalPinInfo) error {
+	return lc.RecoverAllWithOptions(ctx, api.RecoverAllOptions{}, local, out)
+}
+
+// RecoverAllWithOptions triggers Recover() operations on the tracked items
+// selected by the options, at the given rate.
+func (lc *loadBalancingClient) RecoverAllWithOptions(ctx context.Context, opts api.RecoverAllOptions, local bool, out chan<- api.GlobalPinInfo) error {
 	call := func(c Client) error {
 		done := make(chan struct{})
 		cout := make(chan api.GlobalPinInfo, cap(out))
@@ -373,7 +477,7 @@ func (lc *loadBalancingClient) RecoverAll(ctx context.Context, local bool, out c
 		}()
 
 		// this blocks until done
-		err := c.RecoverAll(ctx, local, cout)
+		err := c.RecoverAllWithOptions(ctx, opts, local, cout)
 		// wait for cout to be closed
 		select {
 		case <-ctx.Done():
@@ -470,6 +574,381 @@ func (lc *loadBalancingClient) RepoGC(ctx context.Context, local bool) (api.Glob
 	return repoGC, err
 }
 
+// PinQueues returns information about the queues of pin and unpin
+// operations of the cluster peers. If local is true, only that of the
+// contacted peer is returned.
+func (lc *loadBalancingClient) PinQueues(ctx context.Context, local bool) (api.GlobalPinQueueInfo, error) {
+	var queues api.GlobalPinQueueInfo
+
+	call := func(c Client) error {
+		var err error
+		queues, err = c.PinQueues(ctx, local)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return queues, err
+}
+
+// ObservedPins returns the pins made directly on the IPFS daemon of the
+// contacted peer, as observed by its IPFS proxy in audit mode.
+func (lc *loadBalancingClient) ObservedPins(ctx context.Context) ([]api.ObservedPin, error) {
+	var pins []api.ObservedPin
+
+	call := func(c Client) error {
+		var err error
+		pins, err = c.ObservedPins(ctx)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return pins, err
+}
+
+// PeersArchive returns the peers that have been removed from the cluster,
+// most recently removed first.
+func (lc *loadBalancingClient) PeersArchive(ctx context.Context) ([]api.ArchivedPeer, error) {
+	var peers []api.ArchivedPeer
+
+	call := func(c Client) error {
+		var err error
+		peers, err = c.PeersArchive(ctx)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return peers, err
+}
+
+// APIEndpoints returns the REST API endpoints advertised by the cluster
+// peers.
+func (lc *loadBalancingClient) APIEndpoints(ctx context.Context) ([]api.APIEndpoint, error) {
+	var endpoints []api.APIEndpoint
+
+	call := func(c Client) error {
+		var err error
+		endpoints, err = c.APIEndpoints(ctx)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return endpoints, err
+}
+
+// DedupStats returns the last block deduplication statistics computed
+// by the cluster peers. If local is true, only those from the
+// contacted peer are returned.
+func (lc *loadBalancingClient) DedupStats(ctx context.Context, local bool) (api.GlobalDedupStats, error) {
+	var stats api.GlobalDedupStats
+
+	call := func(c Client) error {
+		var err error
+		stats, err = c.DedupStats(ctx, local)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return stats, err
+}
+
+// Capacity returns the storage capacity of the cluster peers and the
+// cluster-wide totals. If local is true, only the contacted peer is
+// included.
+func (lc *loadBalancingClient) Capacity(ctx context.Context, local bool) (api.GlobalCapacity, error) {
+	var capacity api.GlobalCapacity
+
+	call := func(c Client) error {
+		var err error
+		capacity, err = c.Capacity(ctx, local)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return capacity, err
+}
+
+// SLOReport returns the compliance with the pin service level objectives
+// over the SLO window. If local is true, only the pins of the contacted
+// peer are counted.
+func (lc *loadBalancingClient) SLOReport(ctx context.Context, local bool) (api.SLOReport, error) {
+	var report api.SLOReport
+
+	call := func(c Client) error {
+		var err error
+		report, err = c.SLOReport(ctx, local)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return report, err
+}
+
+// PinGC evaluates the pin garbage collection policy and returns the pins
+// that it selects. They are unpinned when execute is true.
+func (lc *loadBalancingClient) PinGC(ctx context.Context, execute bool) (api.PinGCReport, error) {
+	var report api.PinGCReport
+
+	call := func(c Client) error {
+		var err error
+		report, err = c.PinGC(ctx, execute)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return report, err
+}
+
+// ConsensusLog returns up to limit operations committed to the
+// consensus log before the given index (or the latest when 0),
+// newest first.
+func (lc *loadBalancingClient) ConsensusLog(ctx context.Context, before uint64, limit int) ([]api.ConsensusLogEntry, error) {
+	var entries []api.ConsensusLogEntry
+
+	call := func(c Client) error {
+		var err error
+		entries, err = c.ConsensusLog(ctx, before, limit)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return entries, err
+}
+
+// DeadLetters returns the operations that the contacted peer failed to
+// apply to the shared state.
+func (lc *loadBalancingClient) DeadLetters(ctx context.Context) ([]api.DeadLetter, error) {
+	var letters []api.DeadLetter
+
+	call := func(c Client) error {
+		var err error
+		letters, err = c.DeadLetters(ctx)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return letters, err
+}
+
+// RetryDeadLetter commits again the given failed operation.
+func (lc *loadBalancingClient) RetryDeadLetter(ctx context.Context, id string) error {
+	call := func(c Client) error {
+		return c.RetryDeadLetter(ctx, id)
+	}
+	return lc.retry(0, call)
+}
+
+// DiscardDeadLetter forgets the given failed operation.
+func (lc *loadBalancingClient) DiscardDeadLetter(ctx context.Context, id string) error {
+	call := func(c Client) error {
+		return c.DiscardDeadLetter(ctx, id)
+	}
+	return lc.retry(0, call)
+}
+
+// ConsensusEvents streams an event for every operation applied to the
+// shared state until the context is cancelled.
+func (lc *loadBalancingClient) ConsensusEvents(ctx context.Context, out chan<- api.ConsensusEvent) error {
+	call := func(c Client) error {
+		done := make(chan struct{})
+		cout := make(chan api.ConsensusEvent, cap(out))
+		go func() {
+			for o := range cout {
+				out <- o
+			}
+			done <- struct{}{}
+		}()
+
+		// this blocks until done
+		err := c.ConsensusEvents(ctx, cout)
+		// wait for cout to be closed
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		return err
+	}
+
+	err := lc.retry(0, call)
+	close(out)
+	return err
+}
+
+// EventHistory streams the events in the cluster event history of the
+// contacted peer which match the given query, oldest first.
+func (lc *loadBalancingClient) EventHistory(ctx context.Context, q api.ClusterEventQuery, out chan<- api.ClusterEvent) error {
+	call := func(c Client) error {
+		done := make(chan struct{})
+		cout := make(chan api.ClusterEvent, cap(out))
+		go func() {
+			for o := range cout {
+				out <- o
+			}
+			done <- struct{}{}
+		}()
+
+		// this blocks until done
+		err := c.EventHistory(ctx, q, cout)
+		// wait for cout to be closed
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		return err
+	}
+
+	err := lc.retry(0, call)
+	close(out)
+	return err
+}
+
+// Quorum returns whether the consensus can commit operations, with the
+// voters reachable by the contacted peer.
+func (lc *loadBalancingClient) Quorum(ctx context.Context) (api.QuorumStatus, error) {
+	var status api.QuorumStatus
+
+	call := func(c Client) error {
+		var err error
+		status, err = c.Quorum(ctx)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return status, err
+}
+
+// StateChecksum returns the checksum of the shared state of the cluster
+// peers. If local is true, only the contacted peer is included.
+func (lc *loadBalancingClient) StateChecksum(ctx context.Context, local bool) (api.GlobalStateChecksum, error) {
+	var global api.GlobalStateChecksum
+
+	call := func(c Client) error {
+		var err error
+		global, err = c.StateChecksum(ctx, local)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return global, err
+}
+
+// Rollback replaces the shared state with the given pinset. Pins
+// not included are unpinned. It must be sent to the Raft leader.
+func (lc *loadBalancingClient) Rollback(ctx context.Context, pins []api.Pin) (api.RollbackInfo, error) {
+	var info api.RollbackInfo
+
+	call := func(c Client) error {
+		var err error
+		info, err = c.Rollback(ctx, pins)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return info, err
+}
+
+// RunJob runs a maintenance job on the given cluster peers, or on all of
+// them when none are given, and returns their results.
+func (lc *loadBalancingClient) RunJob(ctx context.Context, job string, peers []peer.ID) (api.GlobalJobResult, error) {
+	var res api.GlobalJobResult
+
+	call := func(c Client) error {
+		var err error
+		res, err = c.RunJob(ctx, job, peers)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return res, err
+}
+
+// Handoff starts transferring the selected pins to another cluster. The
+// handoff runs on the contacted peer, which must be asked for its progress.
+func (lc *loadBalancingClient) Handoff(ctx context.Context, req api.HandoffRequest) (api.HandoffJob, error) {
+	var job api.HandoffJob
+
+	call := func(c Client) error {
+		var err error
+		job, err = c.Handoff(ctx, req)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return job, err
+}
+
+// Handoffs returns the handoffs started on the contacted peer.
+func (lc *loadBalancingClient) Handoffs(ctx context.Context) ([]api.HandoffJob, error) {
+	var jobs []api.HandoffJob
+
+	call := func(c Client) error {
+		var err error
+		jobs, err = c.Handoffs(ctx)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return jobs, err
+}
+
+// HandoffStatus returns the progress of a handoff.
+func (lc *loadBalancingClient) HandoffStatus(ctx context.Context, id string) (api.HandoffJob, error) {
+	var job api.HandoffJob
+
+	call := func(c Client) error {
+		var err error
+		job, err = c.HandoffStatus(ctx, id)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return job, err
+}
+
+// CancelHandoff stops a handoff.
+func (lc *loadBalancingClient) CancelHandoff(ctx context.Context, id string) error {
+	call := func(c Client) error {
+		return c.CancelHandoff(ctx, id)
+	}
+	return lc.retry(0, call)
+}
+
+// SchedulePin registers a pin to be committed at a later time, or
+// repeatedly following a cron expression.
+func (lc *loadBalancingClient) SchedulePin(ctx context.Context, s api.PinSchedule) (api.PinSchedule, error) {
+	var out api.PinSchedule
+
+	call := func(c Client) error {
+		var err error
+		out, err = c.SchedulePin(ctx, s)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return out, err
+}
+
+// PinSchedules returns the pin schedules in the cluster.
+func (lc *loadBalancingClient) PinSchedules(ctx context.Context) ([]api.PinSchedule, error) {
+	var schedules []api.PinSchedule
+
+	call := func(c Client) error {
+		var err error
+		schedules, err = c.PinSchedules(ctx)
+		return err
+	}
+
+	err := lc.retry(0, call)
+	return schedules, err
+}
+
+// CancelPinSchedule removes a pin schedule.
+func (lc *loadBalancingClient) CancelPinSchedule(ctx context.Context, id string) error {
+	call := func(c Client) error {
+		return c.CancelPinSchedule(ctx, id)
+	}
+	return lc.retry(0, call)
+}
+
 // Add imports files to the cluster from the given paths. A path can
 // either be a local filesystem location or an web url (http:// or https://).
 // In the latter case, the destination will be downloaded with a GET request.
@@ -10,6 +10,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -116,6 +117,31 @@ func (c *defaultClient) Unpin(ctx context.Context, ci api.Cid) (api.Pin, error)
 	return pin, err
 }
 
+// Restore takes a Cid out of the trash, so that it stays pinned.
+func (c *defaultClient) Restore(ctx context.Context, ci api.Cid) (api.Pin, error) {
+	ctx, span := trace.StartSpan(ctx, "client/Restore")
+	defer span.End()
+	var pin api.Pin
+	err := c.do(ctx, "POST", fmt.Sprintf("/pins/%s/restore", ci.String()), nil, nil, &pin)
+	return pin, err
+}
+
+// CancelPin aborts the pinning of a Cid, optionally unpinning it.
+func (c *defaultClient) CancelPin(ctx context.Context, ci api.Cid, unpin bool) (api.Pin, error) {
+	ctx, span := trace.StartSpan(ctx, "client/CancelPin")
+	defer span.End()
+	var pin api.Pin
+	err := c.do(
+		ctx,
+		"POST",
+		fmt.Sprintf("/pins/%s/cancel?unpin=%t", ci.String(), unpin),
+		nil,
+		nil,
+		&pin,
+	)
+	return pin, err
+}
+
 // PinPath allows to pin an element by the given IPFS path.
 func (c *defaultClient) PinPath(ctx context.Context, path string, opts api.PinOptions) (api.Pin, error) {
 	ctx, span := trace.StartSpan(ctx, "client/PinPath")
@@ -165,29 +191,27 @@ func (c *defaultClient) UnpinPath(ctx context.Context, p string) (api.Pin, error
 // Allocations returns the consensus state listing all tracked items and
 // the peers that should be pinning them.
 func (c *defaultClient) Allocations(ctx context.Context, filter api.PinType, out chan<- api.Pin) error {
-	defer close(out)
-
-	ctx, span := trace.StartSpan(ctx, "client/Allocations")
-	defer span.End()
+	return c.AllocationsWithFilter(ctx, filter, api.PinFilter{}, out)
+}
 
-	types := []api.PinType{
-		api.DataType,
-		api.MetaType,
-		api.ClusterDAGType,
-		api.ShardType,
+// AllocationsWithFilter works like Allocations but only returns the items
+// matching the given PinFilter.
+func (c *defaultClient) AllocationsWithFilter(ctx context.Context, filter api.PinType, pinFilter api.PinFilter, out chan<- api.Pin) error {
+	opts := api.PinListOptions{
+		Type:      filter,
+		PinFilter: pinFilter,
 	}
+	return c.AllocationsWithOptions(ctx, opts, out)
+}
 
-	var strFilter []string
+// AllocationsWithOptions returns the items in the consensus state selected
+// by the given options. When opts.Limit is set, items are sorted by CID and
+// the CID of the last one can be used as opts.After to get the next page.
+func (c *defaultClient) AllocationsWithOptions(ctx context.Context, opts api.PinListOptions, out chan<- api.Pin) error {
+	defer close(out)
 
-	if filter == api.AllType {
-		strFilter = []string{"all"}
-	} else {
-		for _, t := range types {
-			if t&filter > 0 { // the filter includes this type
-				strFilter = append(strFilter, t.String())
-			}
-		}
-	}
+	ctx, span := trace.StartSpan(ctx, "client/Allocations")
+	defer span.End()
 
 	handler := func(dec *json.Decoder) error {
 		var obj api.Pin
@@ -199,11 +223,10 @@ func (c *defaultClient) Allocations(ctx context.Context, filter api.PinType, out
 		return nil
 	}
 
-	f := url.QueryEscape(strings.Join(strFilter, ","))
 	return c.doStream(
 		ctx,
 		"GET",
-		fmt.Sprintf("/allocations?filter=%s", f),
+		"/allocations?"+opts.ToQuery().Encode(),
 		nil,
 		nil,
 		handler)
@@ -219,6 +242,28 @@ func (c *defaultClient) Allocation(ctx context.Context, ci api.Cid) (api.Pin, er
 	return pin, err
 }
 
+// AllocationExplain returns how the given Cid would be allocated with the
+// latest metrics and why every peer is chosen or not.
+func (c *defaultClient) AllocationExplain(ctx context.Context, ci api.Cid) (api.AllocationExplanation, error) {
+	ctx, span := trace.StartSpan(ctx, "client/AllocationExplain")
+	defer span.End()
+
+	var expl api.AllocationExplanation
+	err := c.do(ctx, "GET", fmt.Sprintf("/pins/%s/allocation-explain", ci.String()), nil, nil, &expl)
+	return expl, err
+}
+
+// PinHistory returns the pin and unpin operations on the given Cid recorded
+// by the cluster peers and who requested them, oldest first.
+func (c *defaultClient) PinHistory(ctx context.Context, ci api.Cid) ([]api.PinAuditEntry, error) {
+	ctx, span := trace.StartSpan(ctx, "client/PinHistory")
+	defer span.End()
+
+	var entries []api.PinAuditEntry
+	err := c.do(ctx, "GET", fmt.Sprintf("/pins/%s/history", ci.String()), nil, nil, &entries)
+	return entries, err
+}
+
 // Status returns the current ipfs state for a given Cid. If local is true,
 // the information affects only the current peer, otherwise the information
 // is fetched from all cluster peers.
@@ -242,7 +287,7 @@ func (c *defaultClient) Status(ctx context.Context, ci api.Cid, local bool) (api
 // true, the information affects only the current peer, otherwise the
 // information is fetched from all cluster peers.
 func (c *defaultClient) StatusCids(ctx context.Context, cids []api.Cid, local bool, out chan<- api.GlobalPinInfo) error {
-	return c.statusAllWithCids(ctx, api.TrackerStatusUndefined, cids, local, out)
+	return c.statusAllWithCids(ctx, api.StatusAllOptions{}, cids, local, out)
 }
 
 // StatusAll gathers Status() for all tracked items. If a filter is
@@ -251,17 +296,24 @@ func (c *defaultClient) StatusCids(ctx context.Context, cids []api.Cid, local bo
 // a bitwise OR operation (st1 | st2 | ...). A "0" filter value (or
 // api.TrackerStatusUndefined), means all.
 func (c *defaultClient) StatusAll(ctx context.Context, filter api.TrackerStatus, local bool, out chan<- api.GlobalPinInfo) error {
-	return c.statusAllWithCids(ctx, filter, nil, local, out)
+	return c.statusAllWithCids(ctx, api.StatusAllOptions{Filter: filter}, nil, local, out)
+}
+
+// StatusAllWithOptions works like StatusAll, but allows to set how peers are
+// queried. When opts.BatchSize is set, the same item may be received
+// several times, each with the statuses from a different set of peers.
+func (c *defaultClient) StatusAllWithOptions(ctx context.Context, opts api.StatusAllOptions, local bool, out chan<- api.GlobalPinInfo) error {
+	return c.statusAllWithCids(ctx, opts, nil, local, out)
 }
 
-func (c *defaultClient) statusAllWithCids(ctx context.Context, filter api.TrackerStatus, cids []api.Cid, local bool, out chan<- api.GlobalPinInfo) error {
+func (c *defaultClient) statusAllWithCids(ctx context.Context, opts api.StatusAllOptions, cids []api.Cid, local bool, out chan<- api.GlobalPinInfo) error {
 	defer close(out)
 	ctx, span := trace.StartSpan(ctx, "client/StatusAll")
 	defer span.End()
 
 	filterStr := ""
-	if filter != api.TrackerStatusUndefined { // undefined filter means "all"
-		filterStr = filter.String()
+	if opts.Filter != api.TrackerStatusUndefined { // undefined filter means "all"
+		filterStr = opts.Filter.String()
 		if filterStr == "" {
 			return errors.New("invalid filter value")
 		}
@@ -282,11 +334,34 @@ func (c *defaultClient) statusAllWithCids(ctx context.Context, filter api.Tracke
 		return nil
 	}
 
+	path := fmt.Sprintf("/pins?local=%t&filter=%s&cids=%s",
+		local, url.QueryEscape(filterStr), strings.Join(cidsStr, ","))
+	if opts.BatchSize > 0 {
+		path += fmt.Sprintf("&batch-size=%d", opts.BatchSize)
+	}
+	if opts.TimeoutPerPeer > 0 {
+		path += "&timeout-per-peer=" + opts.TimeoutPerPeer.String()
+	}
+	if !opts.PinFilter.IsEmpty() {
+		q := url.Values{}
+		opts.PinFilter.ToQuery(q)
+		path += "&" + q.Encode()
+	}
+	if opts.CidPrefix != "" {
+		path += "&cid-prefix=" + url.QueryEscape(opts.CidPrefix)
+	}
+	if len(opts.Peers) > 0 {
+		peers := make([]string, len(opts.Peers))
+		for i, p := range opts.Peers {
+			peers[i] = p.String()
+		}
+		path += "&peer=" + strings.Join(peers, ",")
+	}
+
 	return c.doStream(
 		ctx,
 		"GET",
-		fmt.Sprintf("/pins?local=%t&filter=%s&cids=%s",
-			local, url.QueryEscape(filterStr), strings.Join(cidsStr, ",")),
+		path,
 		nil,
 		nil,
 		handler,
@@ -309,11 +384,42 @@ func (c *defaultClient) Recover(ctx context.Context, ci api.Cid, local bool) (ap
 // true, the operation is limited to the current peer. Otherwise, it happens
 // everywhere.
 func (c *defaultClient) RecoverAll(ctx context.Context, local bool, out chan<- api.GlobalPinInfo) error {
+	return c.RecoverAllWithOptions(ctx, api.RecoverAllOptions{}, local, out)
+}
+
+// RecoverAllWithOptions triggers Recover() operations on the tracked items
+// selected by the options. Every peer re-queues opts.Rate items per second
+// at most. Recovered items are sent as they are re-queued.
+func (c *defaultClient) RecoverAllWithOptions(ctx context.Context, opts api.RecoverAllOptions, local bool, out chan<- api.GlobalPinInfo) error {
 	defer close(out)
 
 	ctx, span := trace.StartSpan(ctx, "client/RecoverAll")
 	defer span.End()
 
+	filterStr := ""
+	if opts.Filter != api.TrackerStatusUndefined {
+		filterStr = opts.Filter.String()
+		if filterStr == "" {
+			return errors.New("invalid filter value")
+		}
+	}
+
+	q := url.Values{}
+	q.Set("local", fmt.Sprintf("%t", local))
+	if filterStr != "" {
+		q.Set("filter", filterStr)
+	}
+	if opts.CidPrefix != "" {
+		q.Set("cid-prefix", opts.CidPrefix)
+	}
+	if opts.Rate > 0 {
+		q.Set("rate", strconv.FormatFloat(opts.Rate, 'f', -1, 64))
+	}
+	if opts.Force {
+		q.Set("force", "true")
+	}
+	opts.PinFilter.ToQuery(q)
+
 	handler := func(dec *json.Decoder) error {
 		var obj api.GlobalPinInfo
 		err := dec.Decode(&obj)
@@ -327,7 +433,7 @@ func (c *defaultClient) RecoverAll(ctx context.Context, local bool, out chan<- a
 	return c.doStream(
 		ctx,
 		"POST",
-		fmt.Sprintf("/pins/recover?local=%t", local),
+		"/pins/recover?"+q.Encode(),
 		nil,
 		nil,
 		handler)
@@ -409,6 +515,387 @@ func (c *defaultClient) RepoGC(ctx context.Context, local bool) (api.GlobalRepoG
 	return repoGC, err
 }
 
+// PinQueues returns information about the queues of pin and unpin
+// operations of the cluster peers. If local is true, only that of the
+// contacted peer is returned.
+func (c *defaultClient) PinQueues(ctx context.Context, local bool) (api.GlobalPinQueueInfo, error) {
+	ctx, span := trace.StartSpan(ctx, "client/PinQueues")
+	defer span.End()
+
+	var queues api.GlobalPinQueueInfo
+	err := c.do(
+		ctx,
+		"GET",
+		fmt.Sprintf("/pins/queues?local=%t", local),
+		nil,
+		nil,
+		&queues,
+	)
+
+	return queues, err
+}
+
+// ObservedPins returns the pins made directly on the IPFS daemon of the
+// contacted peer, as observed by its IPFS proxy in audit mode.
+func (c *defaultClient) ObservedPins(ctx context.Context) ([]api.ObservedPin, error) {
+	ctx, span := trace.StartSpan(ctx, "client/ObservedPins")
+	defer span.End()
+
+	var pins []api.ObservedPin
+	err := c.do(ctx, "GET", "/pins/observed", nil, nil, &pins)
+	return pins, err
+}
+
+// PeersArchive returns the peers that have been removed from the cluster,
+// most recently removed first.
+func (c *defaultClient) PeersArchive(ctx context.Context) ([]api.ArchivedPeer, error) {
+	ctx, span := trace.StartSpan(ctx, "client/PeersArchive")
+	defer span.End()
+
+	var peers []api.ArchivedPeer
+	err := c.do(ctx, "GET", "/peers/archive", nil, nil, &peers)
+	return peers, err
+}
+
+// APIEndpoints returns the REST API endpoints advertised by the cluster
+// peers.
+func (c *defaultClient) APIEndpoints(ctx context.Context) ([]api.APIEndpoint, error) {
+	ctx, span := trace.StartSpan(ctx, "client/APIEndpoints")
+	defer span.End()
+
+	var endpoints []api.APIEndpoint
+	err := c.do(ctx, "GET", "/peers/apis", nil, nil, &endpoints)
+	return endpoints, err
+}
+
+// DedupStats returns the last block deduplication statistics computed
+// by the cluster peers. If local is true, only those from the
+// contacted peer are returned.
+func (c *defaultClient) DedupStats(ctx context.Context, local bool) (api.GlobalDedupStats, error) {
+	ctx, span := trace.StartSpan(ctx, "client/DedupStats")
+	defer span.End()
+
+	var stats api.GlobalDedupStats
+	err := c.do(
+		ctx,
+		"GET",
+		fmt.Sprintf("/accounting/dedup?local=%t", local),
+		nil,
+		nil,
+		&stats,
+	)
+
+	return stats, err
+}
+
+// Capacity returns the storage capacity of the cluster peers and the
+// cluster-wide totals. If local is true, only the contacted peer is
+// included.
+func (c *defaultClient) Capacity(ctx context.Context, local bool) (api.GlobalCapacity, error) {
+	ctx, span := trace.StartSpan(ctx, "client/Capacity")
+	defer span.End()
+
+	var capacity api.GlobalCapacity
+	err := c.do(
+		ctx,
+		"GET",
+		fmt.Sprintf("/capacity?local=%t", local),
+		nil,
+		nil,
+		&capacity,
+	)
+
+	return capacity, err
+}
+
+// SLOReport returns the compliance with the pin service level objectives
+// over the SLO window. If local is true, only the pins of the contacted
+// peer are counted.
+func (c *defaultClient) SLOReport(ctx context.Context, local bool) (api.SLOReport, error) {
+	ctx, span := trace.StartSpan(ctx, "client/SLOReport")
+	defer span.End()
+
+	var report api.SLOReport
+	err := c.do(
+		ctx,
+		"GET",
+		fmt.Sprintf("/slo?local=%t", local),
+		nil,
+		nil,
+		&report,
+	)
+
+	return report, err
+}
+
+// PinGC evaluates the pin garbage collection policy and returns the pins
+// that it selects. They are unpinned when execute is true.
+func (c *defaultClient) PinGC(ctx context.Context, execute bool) (api.PinGCReport, error) {
+	ctx, span := trace.StartSpan(ctx, "client/PinGC")
+	defer span.End()
+
+	method := "GET"
+	if execute {
+		method = "POST"
+	}
+
+	var report api.PinGCReport
+	err := c.do(ctx, method, "/pins/gc", nil, nil, &report)
+	return report, err
+}
+
+// ConsensusLog returns up to limit operations committed to the
+// consensus log before the given index (or the latest when 0),
+// newest first.
+func (c *defaultClient) ConsensusLog(ctx context.Context, before uint64, limit int) ([]api.ConsensusLogEntry, error) {
+	ctx, span := trace.StartSpan(ctx, "client/ConsensusLog")
+	defer span.End()
+
+	query := url.Values{}
+	if before > 0 {
+		query.Set("before", strconv.FormatUint(before, 10))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var entries []api.ConsensusLogEntry
+	err := c.do(
+		ctx,
+		"GET",
+		"/consensus/log?"+query.Encode(),
+		nil,
+		nil,
+		&entries,
+	)
+	return entries, err
+}
+
+// DeadLetters returns the operations that the contacted peer failed to
+// apply to the shared state.
+func (c *defaultClient) DeadLetters(ctx context.Context) ([]api.DeadLetter, error) {
+	ctx, span := trace.StartSpan(ctx, "client/DeadLetters")
+	defer span.End()
+
+	var letters []api.DeadLetter
+	err := c.do(ctx, "GET", "/consensus/deadletters", nil, nil, &letters)
+	return letters, err
+}
+
+// RetryDeadLetter commits again the given failed operation.
+func (c *defaultClient) RetryDeadLetter(ctx context.Context, id string) error {
+	ctx, span := trace.StartSpan(ctx, "client/RetryDeadLetter")
+	defer span.End()
+
+	return c.do(ctx, "POST", fmt.Sprintf("/consensus/deadletters/%s/retry", url.PathEscape(id)), nil, nil, nil)
+}
+
+// DiscardDeadLetter forgets the given failed operation.
+func (c *defaultClient) DiscardDeadLetter(ctx context.Context, id string) error {
+	ctx, span := trace.StartSpan(ctx, "client/DiscardDeadLetter")
+	defer span.End()
+
+	return c.do(ctx, "DELETE", fmt.Sprintf("/consensus/deadletters/%s", url.PathEscape(id)), nil, nil, nil)
+}
+
+// ConsensusEvents streams an event for every operation applied to the
+// shared state until the context is cancelled.
+func (c *defaultClient) ConsensusEvents(ctx context.Context, out chan<- api.ConsensusEvent) error {
+	defer close(out)
+
+	ctx, span := trace.StartSpan(ctx, "client/ConsensusEvents")
+	defer span.End()
+
+	handler := func(dec *json.Decoder) error {
+		var obj api.ConsensusEvent
+		err := dec.Decode(&obj)
+		if err != nil {
+			return err
+		}
+		out <- obj
+		return nil
+	}
+
+	return c.doStream(ctx, "GET", "/consensus/events", nil, nil, handler)
+}
+
+// EventHistory streams the events in the cluster event history of the
+// contacted peer which match the given query, oldest first.
+func (c *defaultClient) EventHistory(ctx context.Context, q api.ClusterEventQuery, out chan<- api.ClusterEvent) error {
+	defer close(out)
+
+	ctx, span := trace.StartSpan(ctx, "client/EventHistory")
+	defer span.End()
+
+	handler := func(dec *json.Decoder) error {
+		var obj api.ClusterEvent
+		err := dec.Decode(&obj)
+		if err != nil {
+			return err
+		}
+		out <- obj
+		return nil
+	}
+
+	return c.doStream(ctx, "GET", "/events/history?"+q.ToQuery().Encode(), nil, nil, handler)
+}
+
+// Quorum returns whether the consensus can commit operations, with the
+// voters reachable by the contacted peer.
+func (c *defaultClient) Quorum(ctx context.Context) (api.QuorumStatus, error) {
+	ctx, span := trace.StartSpan(ctx, "client/Quorum")
+	defer span.End()
+
+	var status api.QuorumStatus
+	err := c.do(ctx, "GET", "/health/quorum", nil, nil, &status)
+	return status, err
+}
+
+// StateChecksum returns the checksum of the shared state of the cluster
+// peers. If local is true, only the contacted peer is included.
+func (c *defaultClient) StateChecksum(ctx context.Context, local bool) (api.GlobalStateChecksum, error) {
+	ctx, span := trace.StartSpan(ctx, "client/StateChecksum")
+	defer span.End()
+
+	var global api.GlobalStateChecksum
+	err := c.do(
+		ctx,
+		"GET",
+		fmt.Sprintf("/consensus/checksum?local=%t", local),
+		nil,
+		nil,
+		&global,
+	)
+	return global, err
+}
+
+// Rollback replaces the shared state with the given pinset. Pins
+// not included are unpinned. It must be sent to the Raft leader.
+func (c *defaultClient) Rollback(ctx context.Context, pins []api.Pin) (api.RollbackInfo, error) {
+	ctx, span := trace.StartSpan(ctx, "client/Rollback")
+	defer span.End()
+
+	body := new(bytes.Buffer)
+	enc := json.NewEncoder(body)
+	for _, pin := range pins {
+		if err := enc.Encode(pin); err != nil {
+			return api.RollbackInfo{}, err
+		}
+	}
+
+	var info api.RollbackInfo
+	err := c.do(
+		ctx,
+		"POST",
+		"/admin/rollback?confirm=true",
+		nil,
+		body,
+		&info,
+	)
+	return info, err
+}
+
+// RunJob runs a maintenance job on the given cluster peers, or on all of
+// them when none are given, and returns their results.
+func (c *defaultClient) RunJob(ctx context.Context, job string, peers []peer.ID) (api.GlobalJobResult, error) {
+	ctx, span := trace.StartSpan(ctx, "client/RunJob")
+	defer span.End()
+
+	strPeers := make([]string, len(peers))
+	for i, p := range peers {
+		strPeers[i] = p.String()
+	}
+
+	var res api.GlobalJobResult
+	err := c.do(
+		ctx,
+		"POST",
+		fmt.Sprintf("/admin/jobs/%s?peers=%s", url.PathEscape(job), strings.Join(strPeers, ",")),
+		nil,
+		nil,
+		&res,
+	)
+	return res, err
+}
+
+// Handoff starts transferring the selected pins to another cluster. The
+// handoff runs on the contacted peer, which must be asked for its progress.
+func (c *defaultClient) Handoff(ctx context.Context, req api.HandoffRequest) (api.HandoffJob, error) {
+	ctx, span := trace.StartSpan(ctx, "client/Handoff")
+	defer span.End()
+
+	body := new(bytes.Buffer)
+	if err := json.NewEncoder(body).Encode(req); err != nil {
+		return api.HandoffJob{}, err
+	}
+
+	var job api.HandoffJob
+	err := c.do(ctx, "POST", "/admin/handoffs?confirm=true", nil, body, &job)
+	return job, err
+}
+
+// Handoffs returns the handoffs started on the contacted peer.
+func (c *defaultClient) Handoffs(ctx context.Context) ([]api.HandoffJob, error) {
+	ctx, span := trace.StartSpan(ctx, "client/Handoffs")
+	defer span.End()
+
+	var jobs []api.HandoffJob
+	err := c.do(ctx, "GET", "/admin/handoffs", nil, nil, &jobs)
+	return jobs, err
+}
+
+// HandoffStatus returns the progress of a handoff.
+func (c *defaultClient) HandoffStatus(ctx context.Context, id string) (api.HandoffJob, error) {
+	ctx, span := trace.StartSpan(ctx, "client/HandoffStatus")
+	defer span.End()
+
+	var job api.HandoffJob
+	err := c.do(ctx, "GET", fmt.Sprintf("/admin/handoffs/%s", url.PathEscape(id)), nil, nil, &job)
+	return job, err
+}
+
+// CancelHandoff stops a handoff.
+func (c *defaultClient) CancelHandoff(ctx context.Context, id string) error {
+	ctx, span := trace.StartSpan(ctx, "client/CancelHandoff")
+	defer span.End()
+
+	return c.do(ctx, "DELETE", fmt.Sprintf("/admin/handoffs/%s", url.PathEscape(id)), nil, nil, nil)
+}
+
+// SchedulePin registers a pin to be committed at a later time, or
+// repeatedly following a cron expression.
+func (c *defaultClient) SchedulePin(ctx context.Context, s api.PinSchedule) (api.PinSchedule, error) {
+	ctx, span := trace.StartSpan(ctx, "client/SchedulePin")
+	defer span.End()
+
+	body := new(bytes.Buffer)
+	if err := json.NewEncoder(body).Encode(s); err != nil {
+		return api.PinSchedule{}, err
+	}
+
+	var out api.PinSchedule
+	err := c.do(ctx, "POST", "/schedules", nil, body, &out)
+	return out, err
+}
+
+// PinSchedules returns the pin schedules in the cluster.
+func (c *defaultClient) PinSchedules(ctx context.Context) ([]api.PinSchedule, error) {
+	ctx, span := trace.StartSpan(ctx, "client/PinSchedules")
+	defer span.End()
+
+	var schedules []api.PinSchedule
+	err := c.do(ctx, "GET", "/schedules", nil, nil, &schedules)
+	return schedules, err
+}
+
+// CancelPinSchedule removes a pin schedule.
+func (c *defaultClient) CancelPinSchedule(ctx context.Context, id string) error {
+	ctx, span := trace.StartSpan(ctx, "client/CancelPinSchedule")
+	defer span.End()
+
+	return c.do(ctx, "DELETE", fmt.Sprintf("/schedules/%s", url.PathEscape(id)), nil, nil, nil)
+}
+
 // WaitFor is a utility function that allows for a caller to wait until a CID
 // status target is reached (as given in StatusFilterParams).
 // It returns the final status for that CID and an error, if there was one.
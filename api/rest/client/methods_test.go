@@ -173,6 +173,50 @@ func TestUnpin(t *testing.T) {
 	testClients(t, api, testF)
 }
 
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		pin, err := c.Restore(ctx, test.Cid1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pin.Cid.Equals(test.Cid1) {
+			t.Error("expected the restored pin")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestCancelPin(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		pin, err := c.CancelPin(ctx, test.Cid1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pin.Cid.Equals(test.Cid1) || !pin.IsCanceled() {
+			t.Error("expected the canceled pin")
+		}
+
+		pin, err = c.CancelPin(ctx, test.Cid1, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pin.IsCanceled() {
+			t.Error("expected the unpinned pin")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
 type pathCase struct {
 	path        string
 	wantErr     bool
@@ -305,6 +349,78 @@ func TestAllocations(t *testing.T) {
 	testClients(t, api, testF)
 }
 
+func TestAllocationsWithFilter(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		pins := make(chan types.Pin)
+		var got []types.Pin
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range pins {
+				got = append(got, p)
+			}
+		}()
+
+		pf := types.PinFilter{Tags: []string{"test"}}
+		err := c.AllocationsWithFilter(ctx, types.AllType, pf, pins)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wg.Wait()
+		if len(got) != 1 || !got[0].Cid.Equals(test.Cid3) {
+			t.Errorf("unexpected pins: %+v", got)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestAllocationsWithOptions(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		page := func(opts types.PinListOptions) []types.Pin {
+			pins := make(chan types.Pin)
+			var got []types.Pin
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for p := range pins {
+					got = append(got, p)
+				}
+			}()
+
+			err := c.AllocationsWithOptions(ctx, opts, pins)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wg.Wait()
+			return got
+		}
+
+		got := page(types.PinListOptions{Limit: 2})
+		if len(got) != 2 || !got[0].Cid.Equals(test.Cid2) || !got[1].Cid.Equals(test.Cid3) {
+			t.Errorf("unexpected first page: %+v", got)
+		}
+
+		got = page(types.PinListOptions{Limit: 2, After: got[1].Cid})
+		if len(got) != 1 || !got[0].Cid.Equals(test.Cid1) {
+			t.Errorf("unexpected second page: %+v", got)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
 func TestAllocation(t *testing.T) {
 	ctx := context.Background()
 	api := testAPI(t)
@@ -323,6 +439,42 @@ func TestAllocation(t *testing.T) {
 	testClients(t, api, testF)
 }
 
+func TestAllocationExplain(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		expl, err := c.AllocationExplain(ctx, test.Cid1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !expl.Cid.Equals(test.Cid1) || len(expl.Peers) != 2 {
+			t.Errorf("unexpected explanation: %+v", expl)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestPinHistory(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		entries, err := c.PinHistory(ctx, test.Cid1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 || entries[0].Requester.User != "alice" {
+			t.Errorf("unexpected history: %+v", entries)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
 func TestStatus(t *testing.T) {
 	ctx := context.Background()
 	api := testAPI(t)
@@ -452,6 +604,24 @@ func TestStatusAll(t *testing.T) {
 		if err == nil {
 			t.Error("expected an error")
 		}
+
+		out6 := make(chan types.GlobalPinInfo)
+		go func() {
+			opts := types.StatusAllOptions{
+				Filter:         types.TrackerStatusPinned,
+				BatchSize:      1,
+				TimeoutPerPeer: time.Minute,
+			}
+			err := c.StatusAllWithOptions(ctx, opts, false, out6)
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+		pins = collectGlobalPinInfos(t, out6)
+
+		if len(pins) != 1 {
+			t.Error("there should be one pin")
+		}
 	}
 
 	testClients(t, api, testF)
@@ -492,6 +662,20 @@ func TestRecoverAll(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
+
+		out3 := make(chan types.GlobalPinInfo, 10)
+		opts := types.RecoverAllOptions{
+			Filter: types.TrackerStatusPinError,
+			Rate:   2.5,
+			Force:  true,
+		}
+		err = c.RecoverAllWithOptions(ctx, opts, false, out3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(out3) != 1 {
+			t.Errorf("expected 1 recovered item: %d", len(out3))
+		}
 	}
 
 	testClients(t, api, testF)
@@ -904,6 +1088,440 @@ func TestRepoGC(t *testing.T) {
 	testClients(t, api, testF)
 }
 
+func TestPinQueues(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		for _, local := range []bool{false, true} {
+			queues, err := c.PinQueues(ctx, local)
+			if err != nil {
+				t.Fatal(err)
+			}
+			q, ok := queues.PeerMap[test.PeerID1.String()]
+			if !ok {
+				t.Fatal("expected the queues of the mock peer")
+			}
+			if q.PinDepth != 10 || q.Pinning != 2 || q.OldestPin.IsZero() {
+				t.Errorf("unexpected queue info: %+v", q)
+			}
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestObservedPins(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		pins, err := c.ObservedPins(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pins) != 1 || !pins[0].Cid.Equals(test.Cid1) {
+			t.Errorf("unexpected observed pins: %+v", pins)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestPeersArchive(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		peers, err := c.PeersArchive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(peers) != 1 || peers[0].ID != test.PeerID3 {
+			t.Errorf("unexpected archived peers: %+v", peers)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestAPIEndpoints(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		endpoints, err := c.APIEndpoints(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(endpoints) != 1 || endpoints[0].Peer != test.PeerID1 || !endpoints[0].Public {
+			t.Errorf("unexpected api endpoints: %+v", endpoints)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestPinGC(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		report, err := c.PinGC(ctx, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Executed || len(report.Candidates) != 1 {
+			t.Errorf("unexpected proposals: %+v", report)
+		}
+
+		report, err = c.PinGC(ctx, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !report.Executed {
+			t.Error("expected the pins to be unpinned")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestCapacity(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		capacity, err := c.Capacity(ctx, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(capacity.PeerMap) != 1 {
+			t.Fatal("expected the capacity of one peer")
+		}
+		if capacity.Total.Used == 0 || capacity.Total.IngestRate == 0 {
+			t.Errorf("unexpected capacity: %+v", capacity.Total)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestSLOReport(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		report, err := c.SLOReport(ctx, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Classes) != 1 || report.Classes[0].Compliance != 0.98 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestStateChecksum(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		global, err := c.StateChecksum(ctx, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sum, ok := global.PeerMap[test.PeerID1.String()]
+		if !ok {
+			t.Fatal("expected the checksum of the contacted peer")
+		}
+		if sum.Checksum == "" || !sum.Leader {
+			t.Errorf("unexpected checksum: %+v", sum)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		letters, err := c.DeadLetters(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(letters) != 1 || !letters[0].Pin.Cid.Equals(test.Cid1) {
+			t.Fatalf("unexpected dead letters: %+v", letters)
+		}
+
+		err = c.RetryDeadLetter(ctx, letters[0].ID)
+		if err != nil {
+			t.Error(err)
+		}
+		err = c.DiscardDeadLetter(ctx, letters[0].ID)
+		if err != nil {
+			t.Error(err)
+		}
+		err = c.DiscardDeadLetter(ctx, "unknown")
+		if err == nil {
+			t.Error("expected an error discarding an unknown dead letter")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestDedupStats(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		stats, err := c.DedupStats(ctx, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(stats.PeerMap) != 1 {
+			t.Fatal("expected stats for one peer")
+		}
+		for _, st := range stats.PeerMap {
+			if st.Peer == "" {
+				t.Error("bad id")
+			}
+			if st.UniqueBlocks == 0 || st.TotalBlocks < st.UniqueBlocks {
+				t.Errorf("unexpected stats: %+v", st)
+			}
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestConsensusLog(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		entries, err := c.ConsensusLog(ctx, 3, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].Index != 2 || entries[0].Type != "pin" {
+			t.Errorf("unexpected entries: %+v", entries)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestRollback(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		pins := []types.Pin{
+			types.PinCid(test.Cid1),
+			types.PinCid(test.Cid2),
+		}
+		info, err := c.Rollback(ctx, pins)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Pins != 2 || info.AuditSnapshot == "" {
+			t.Errorf("unexpected rollback info: %+v", info)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestPinSchedules(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		s, err := c.SchedulePin(ctx, types.PinSchedule{
+			Path:      test.Cid1.String(),
+			NotBefore: time.Now().Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.ID == "" || s.Next.IsZero() {
+			t.Fatalf("unexpected schedule: %+v", s)
+		}
+
+		schedules, err := c.PinSchedules(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(schedules) != 1 {
+			t.Errorf("unexpected schedules: %+v", schedules)
+		}
+
+		if err := c.CancelPinSchedule(ctx, s.ID); err != nil {
+			t.Error(err)
+		}
+		if err := c.CancelPinSchedule(ctx, "unknown"); err == nil {
+			t.Error("expected an error canceling an unknown schedule")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestHandoff(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		req := types.HandoffRequest{
+			Target: types.NewMultiaddrWithValue(ma.StringCast("/ip4/10.0.0.1/tcp/9094")),
+			Pins:   types.PinListOptions{NamePrefix: "archive"},
+		}
+		job, err := c.Handoff(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.ID == "" || job.Status != types.HandoffRunning {
+			t.Fatalf("unexpected handoff: %+v", job)
+		}
+
+		job, err = c.HandoffStatus(ctx, job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != types.HandoffDone || job.Unpinned != 1 {
+			t.Errorf("unexpected handoff status: %+v", job)
+		}
+
+		jobs, err := c.Handoffs(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(jobs) != 1 {
+			t.Errorf("unexpected handoffs: %+v", jobs)
+		}
+
+		if err := c.CancelHandoff(ctx, job.ID); err != nil {
+			t.Error(err)
+		}
+		if err := c.CancelHandoff(ctx, "unknown"); err == nil {
+			t.Error("expected an error canceling an unknown handoff")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestConsensusEvents(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		out := make(chan types.ConsensusEvent, 10)
+		err := c.ConsensusEvents(ctx, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var evs []types.ConsensusEvent
+		for ev := range out {
+			evs = append(evs, ev)
+		}
+		if len(evs) != 2 || !evs[0].Pin.Cid.Equals(test.Cid1) || evs[1].Type != types.ConsensusEventPeerAdd {
+			t.Errorf("unexpected events: %+v", evs)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestEventHistory(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		out := make(chan types.ClusterEvent, 10)
+		q := types.ClusterEventQuery{Types: []string{types.ClusterEventPin}}
+		err := c.EventHistory(ctx, q, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var evs []types.ClusterEvent
+		for ev := range out {
+			evs = append(evs, ev)
+		}
+		if len(evs) != 1 || evs[0].ID != 2 || !evs[0].Cid.Equals(test.Cid1) {
+			t.Errorf("unexpected events: %+v", evs)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestQuorum(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		status, err := c.Quorum(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Leader != test.PeerID1 || !status.HasQuorum || len(status.Voters) != 3 {
+			t.Errorf("unexpected quorum status: %+v", status)
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
+func TestRunJob(t *testing.T) {
+	ctx := context.Background()
+	api := testAPI(t)
+	defer shutdown(api)
+
+	testF := func(t *testing.T, c Client) {
+		res, err := c.RunJob(ctx, "reconnect-ipfs", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Job != "reconnect-ipfs" || len(res.PeerMap) != 1 {
+			t.Errorf("unexpected job result: %+v", res)
+		}
+
+		_, err = c.RunJob(ctx, "unknown", []peer.ID{test.PeerID1})
+		if err == nil {
+			t.Error("expected an error for an unknown job")
+		}
+	}
+
+	testClients(t, api, testF)
+}
+
 func TestHealth(t *testing.T) {
 	ctx := context.Background()
 	api := testAPI(t)
@@ -72,6 +72,8 @@ func (c *defaultClient) doRequest(
 
 	if c.config.Username != "" {
 		r.SetBasicAuth(c.config.Username, c.config.Password)
+	} else if c.config.Token != "" {
+		r.Header.Set("Authorization", "Bearer "+c.config.Token)
 	}
 
 	for k, v := range headers {
@@ -11,13 +11,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ipfs-cluster/ipfs-cluster/adder/adderutils"
 	types "github.com/ipfs-cluster/ipfs-cluster/api"
 	"github.com/ipfs-cluster/ipfs-cluster/api/common"
+	"github.com/ipfs-cluster/ipfs-cluster/state"
 
 	logging "github.com/ipfs/go-log/v2"
 	rpc "github.com/libp2p/go-libp2p-gorpc"
@@ -27,6 +31,13 @@ import (
 	mux "github.com/gorilla/mux"
 )
 
+// number of consensus log entries returned when no limit is given.
+const defaultConsensusLogLimit = 100
+
+// interval between the comments sent on idle event streams so that
+// proxies do not close them.
+var eventsKeepAlive = 30 * time.Second
+
 var (
 	logger    = logging.Logger("restapi")
 	apiLogger = logging.Logger("restapilog")
@@ -90,6 +101,18 @@ func (api *API) routes(c *rpc.Client) []common.Route {
 			Pattern:     "/peers",
 			HandlerFunc: api.peerAddHandler,
 		},
+		{
+			Name:        "APIEndpoints",
+			Method:      "GET",
+			Pattern:     "/peers/apis",
+			HandlerFunc: api.apiEndpointsHandler,
+		},
+		{
+			Name:        "PeersArchive",
+			Method:      "GET",
+			Pattern:     "/peers/archive",
+			HandlerFunc: api.peersArchiveHandler,
+		},
 		{
 			Name:        "PeerRemove",
 			Method:      "DELETE",
@@ -126,12 +149,60 @@ func (api *API) routes(c *rpc.Client) []common.Route {
 			Pattern:     "/pins/{hash}/recover",
 			HandlerFunc: api.recoverHandler,
 		},
+		{
+			Name:        "Restore",
+			Method:      "POST",
+			Pattern:     "/pins/{hash}/restore",
+			HandlerFunc: api.restoreHandler,
+		},
+		{
+			Name:        "CancelPin",
+			Method:      "POST",
+			Pattern:     "/pins/{hash}/cancel",
+			HandlerFunc: api.cancelPinHandler,
+		},
+		{
+			Name:        "AllocationExplain",
+			Method:      "GET",
+			Pattern:     "/pins/{hash}/allocation-explain",
+			HandlerFunc: api.allocationExplainHandler,
+		},
+		{
+			Name:        "PinHistory",
+			Method:      "GET",
+			Pattern:     "/pins/{hash}/history",
+			HandlerFunc: api.pinHistoryHandler,
+		},
 		{
 			Name:        "RecoverAll",
 			Method:      "POST",
 			Pattern:     "/pins/recover",
 			HandlerFunc: api.recoverAllHandler,
 		},
+		{
+			Name:        "ObservedPins",
+			Method:      "GET",
+			Pattern:     "/pins/observed",
+			HandlerFunc: api.observedPinsHandler,
+		},
+		{
+			Name:        "PinQueues",
+			Method:      "GET",
+			Pattern:     "/pins/queues",
+			HandlerFunc: api.pinQueuesHandler,
+		},
+		{
+			Name:        "PinGCProposals",
+			Method:      "GET",
+			Pattern:     "/pins/gc",
+			HandlerFunc: api.pinGCHandler,
+		},
+		{
+			Name:        "PinGC",
+			Method:      "POST",
+			Pattern:     "/pins/gc",
+			HandlerFunc: api.pinGCHandler,
+		},
 		{
 			Name:        "Status",
 			Method:      "GET",
@@ -168,12 +239,138 @@ func (api *API) routes(c *rpc.Client) []common.Route {
 			Pattern:     "/ipfs/gc",
 			HandlerFunc: api.repoGCHandler,
 		},
+		{
+			Name:        "DedupStats",
+			Method:      "GET",
+			Pattern:     "/accounting/dedup",
+			HandlerFunc: api.dedupStatsHandler,
+		},
+		{
+			Name:        "Capacity",
+			Method:      "GET",
+			Pattern:     "/capacity",
+			HandlerFunc: api.capacityHandler,
+		},
+		{
+			Name:        "SLOReport",
+			Method:      "GET",
+			Pattern:     "/slo",
+			HandlerFunc: api.sloReportHandler,
+		},
+		{
+			Name:        "ConsensusLog",
+			Method:      "GET",
+			Pattern:     "/consensus/log",
+			HandlerFunc: api.consensusLogHandler,
+		},
+		{
+			Name:        "StateChecksum",
+			Method:      "GET",
+			Pattern:     "/consensus/checksum",
+			HandlerFunc: api.stateChecksumHandler,
+		},
+		{
+			Name:        "DeadLetters",
+			Method:      "GET",
+			Pattern:     "/consensus/deadletters",
+			HandlerFunc: api.deadLettersHandler,
+		},
+		{
+			Name:        "RetryDeadLetter",
+			Method:      "POST",
+			Pattern:     "/consensus/deadletters/{id}/retry",
+			HandlerFunc: api.retryDeadLetterHandler,
+		},
+		{
+			Name:        "DiscardDeadLetter",
+			Method:      "DELETE",
+			Pattern:     "/consensus/deadletters/{id}",
+			HandlerFunc: api.discardDeadLetterHandler,
+		},
+		{
+			Name:        "ConsensusEvents",
+			Method:      "GET",
+			Pattern:     "/consensus/events",
+			HandlerFunc: api.consensusEventsHandler,
+		},
+		{
+			Name:        "Events",
+			Method:      "GET",
+			Pattern:     "/events",
+			HandlerFunc: api.eventsHandler,
+		},
+		{
+			Name:        "EventHistory",
+			Method:      "GET",
+			Pattern:     "/events/history",
+			HandlerFunc: api.eventHistoryHandler,
+		},
+		{
+			Name:        "Rollback",
+			Method:      "POST",
+			Pattern:     "/admin/rollback",
+			HandlerFunc: api.rollbackHandler,
+		},
+		{
+			Name:        "RunJob",
+			Method:      "POST",
+			Pattern:     "/admin/jobs/{job}",
+			HandlerFunc: api.runJobHandler,
+		},
+		{
+			Name:        "Handoffs",
+			Method:      "GET",
+			Pattern:     "/admin/handoffs",
+			HandlerFunc: api.handoffsHandler,
+		},
+		{
+			Name:        "Handoff",
+			Method:      "POST",
+			Pattern:     "/admin/handoffs",
+			HandlerFunc: api.handoffHandler,
+		},
+		{
+			Name:        "HandoffStatus",
+			Method:      "GET",
+			Pattern:     "/admin/handoffs/{id}",
+			HandlerFunc: api.handoffStatusHandler,
+		},
+		{
+			Name:        "CancelHandoff",
+			Method:      "DELETE",
+			Pattern:     "/admin/handoffs/{id}",
+			HandlerFunc: api.cancelHandoffHandler,
+		},
+		{
+			Name:        "PinSchedules",
+			Method:      "GET",
+			Pattern:     "/schedules",
+			HandlerFunc: api.pinSchedulesHandler,
+		},
+		{
+			Name:        "SchedulePin",
+			Method:      "POST",
+			Pattern:     "/schedules",
+			HandlerFunc: api.schedulePinHandler,
+		},
+		{
+			Name:        "CancelPinSchedule",
+			Method:      "DELETE",
+			Pattern:     "/schedules/{id}",
+			HandlerFunc: api.cancelPinScheduleHandler,
+		},
 		{
 			Name:        "ConnectionGraph",
 			Method:      "GET",
 			Pattern:     "/health/graph",
 			HandlerFunc: api.graphHandler,
 		},
+		{
+			Name:        "Quorum",
+			Method:      "GET",
+			Pattern:     "/health/quorum",
+			HandlerFunc: api.quorumHandler,
+		},
 		{
 			Name:        "Alerts",
 			Method:      "GET",
@@ -303,6 +500,14 @@ func (api *API) addHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ns, ok := api.NamespaceOrFail(w, r, params.Namespace)
+	if !ok {
+		return
+	}
+	params.Namespace = ns
+	params.Owners = api.Owners(r)
+	params.Requester = api.Requester(r)
+
 	api.SetHeaders(w)
 
 	// any errors sent as trailer
@@ -310,6 +515,7 @@ func (api *API) addHandler(w http.ResponseWriter, r *http.Request) {
 		r.Context(),
 		api.rpcClient,
 		params,
+		api.UploadPolicy(r),
 		reader,
 		w,
 		nil,
@@ -388,6 +594,17 @@ func (api *API) pinHandler(w http.ResponseWriter, r *http.Request) {
 	if pin := api.ParseCidOrFail(w, r); pin.Defined() {
 		api.config.Logger.Debugf("rest api pinHandler: %s", pin.Cid)
 		// span.AddAttributes(trace.StringAttribute("cid", pin.Cid))
+		ns, ok := api.NamespaceOrFail(w, r, pin.Namespace)
+		if !ok {
+			return
+		}
+		pin.Namespace = ns
+		pin.Owners = api.Owners(r)
+		pin.Requester = api.Requester(r)
+		// pin updates copy the options of the updated pin.
+		if pin.PinUpdate.Defined() && !api.OwnsPinOrFail(w, r, pin.PinUpdate) {
+			return
+		}
 		var pinObj types.Pin
 		err := api.rpcClient.CallContext(
 			r.Context(),
@@ -406,6 +623,12 @@ func (api *API) unpinHandler(w http.ResponseWriter, r *http.Request) {
 	if pin := api.ParseCidOrFail(w, r); pin.Defined() {
 		api.config.Logger.Debugf("rest api unpinHandler: %s", pin.Cid)
 		// span.AddAttributes(trace.StringAttribute("cid", pin.Cid))
+		if !api.OwnsPinOrFail(w, r, pin.Cid) {
+			return
+		}
+		// only the reference of the user is removed.
+		pin.Owners = api.Owners(r)
+		pin.Requester = api.Requester(r)
 		var pinObj types.Pin
 		err := api.rpcClient.CallContext(
 			r.Context(),
@@ -420,10 +643,62 @@ func (api *API) unpinHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (api *API) restoreHandler(w http.ResponseWriter, r *http.Request) {
+	if pin := api.ParseCidOrFail(w, r); pin.Defined() {
+		api.config.Logger.Debugf("rest api restoreHandler: %s", pin.Cid)
+		if !api.OwnsPinOrFail(w, r, pin.Cid) {
+			return
+		}
+		var pinObj types.Pin
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"Restore",
+			pin.Cid,
+			&pinObj,
+		)
+		api.SendResponse(w, common.SetStatusAutomatically, err, pinObj)
+		api.config.Logger.Debug("rest api restoreHandler done")
+	}
+}
+
+func (api *API) cancelPinHandler(w http.ResponseWriter, r *http.Request) {
+	if pin := api.ParseCidOrFail(w, r); pin.Defined() {
+		api.config.Logger.Debugf("rest api cancelPinHandler: %s", pin.Cid)
+		if !api.OwnsPinOrFail(w, r, pin.Cid) {
+			return
+		}
+		req := types.CancelPinRequest{
+			Cid:       pin.Cid,
+			Unpin:     r.URL.Query().Get("unpin") == "true",
+			Requester: api.Requester(r),
+		}
+		var pinObj types.Pin
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"CancelPin",
+			req,
+			&pinObj,
+		)
+		api.SendResponse(w, common.SetStatusAutomatically, err, pinObj)
+		api.config.Logger.Debug("rest api cancelPinHandler done")
+	}
+}
+
 func (api *API) pinPathHandler(w http.ResponseWriter, r *http.Request) {
 	var pin types.Pin
 	if pinpath := api.ParsePinPathOrFail(w, r); pinpath.Defined() {
 		api.config.Logger.Debugf("rest api pinPathHandler: %s", pinpath.Path)
+		ns, ok := api.NamespaceOrFail(w, r, pinpath.Namespace)
+		if !ok {
+			return
+		}
+		pinpath.Namespace = ns
+		pinpath.Owners = api.Owners(r)
+		pinpath.Requester = api.Requester(r)
 		err := api.rpcClient.CallContext(
 			r.Context(),
 			"",
@@ -442,6 +717,11 @@ func (api *API) unpinPathHandler(w http.ResponseWriter, r *http.Request) {
 	var pin types.Pin
 	if pinpath := api.ParsePinPathOrFail(w, r); pinpath.Defined() {
 		api.config.Logger.Debugf("rest api unpinPathHandler: %s", pinpath.Path)
+		if !api.ownsPathOrFail(w, r, pinpath.Path) {
+			return
+		}
+		pinpath.Owners = api.Owners(r)
+		pinpath.Requester = api.Requester(r)
 		err := api.rpcClient.CallContext(
 			r.Context(),
 			"",
@@ -455,20 +735,44 @@ func (api *API) unpinPathHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ownsPathOrFail is like OwnsPinOrFail for the CID that the given IPFS path
+// resolves to.
+func (api *API) ownsPathOrFail(w http.ResponseWriter, r *http.Request, path string) bool {
+	if api.Namespace(r) == "" {
+		return true
+	}
+
+	var c types.Cid
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"IPFSConnector",
+		"Resolve",
+		path,
+		&c,
+	)
+	if err != nil {
+		api.SendResponse(w, common.SetStatusAutomatically, err, nil)
+		return false
+	}
+	return api.OwnsPinOrFail(w, r, c)
+}
+
 func (api *API) allocationsHandler(w http.ResponseWriter, r *http.Request) {
-	queryValues := r.URL.Query()
-	filterStr := queryValues.Get("filter")
-	var filter types.PinType
-	for _, f := range strings.Split(filterStr, ",") {
-		filter |= types.PinTypeFromString(f)
+	opts, err := types.PinListOptionsFromQuery(r.URL.Query())
+	if err != nil {
+		api.SendResponse(w, http.StatusBadRequest, err, nil)
+		return
 	}
 
-	if filter == types.BadType {
-		api.SendResponse(w, http.StatusBadRequest, errors.New("invalid filter value"), nil)
+	ns, ok := api.NamespaceOrFail(w, r, opts.Namespace)
+	if !ok {
 		return
 	}
+	opts.Namespace = ns
 
-	in := make(chan struct{})
+	in := make(chan types.PinListOptions, 1)
+	in <- opts
 	close(in)
 
 	out := make(chan types.Pin, common.StreamChannelSize)
@@ -484,7 +788,7 @@ func (api *API) allocationsHandler(w http.ResponseWriter, r *http.Request) {
 			r.Context(),
 			"",
 			"Cluster",
-			"Pins",
+			"PinsWithOptions",
 			in,
 			out,
 		)
@@ -493,22 +797,9 @@ func (api *API) allocationsHandler(w http.ResponseWriter, r *http.Request) {
 	iter := func() (interface{}, bool, error) {
 		var p types.Pin
 		var ok bool
-	iterloop:
-		for {
-
-			select {
-			case <-ctx.Done():
-				break iterloop
-			case p, ok = <-out:
-				if !ok {
-					break iterloop
-				}
-				// this means we keep iterating if no filter
-				// matched
-				if filter == types.AllType || filter&p.Type > 0 {
-					break iterloop
-				}
-			}
+		select {
+		case <-ctx.Done():
+		case p, ok = <-out:
 		}
 		return p, ok, ctx.Err()
 	}
@@ -527,10 +818,49 @@ func (api *API) allocationHandler(w http.ResponseWriter, r *http.Request) {
 			pin.Cid,
 			&pinResp,
 		)
+		if ns := api.Namespace(r); err == nil && ns != "" && pinResp.Namespace != ns {
+			err = state.ErrNotFound
+		}
 		api.SendResponse(w, common.SetStatusAutomatically, err, pinResp)
 	}
 }
 
+func (api *API) allocationExplainHandler(w http.ResponseWriter, r *http.Request) {
+	if pin := api.ParseCidOrFail(w, r); pin.Defined() {
+		if !api.OwnsPinOrFail(w, r, pin.Cid) {
+			return
+		}
+		var expl types.AllocationExplanation
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"AllocationExplain",
+			pin.Cid,
+			&expl,
+		)
+		api.SendResponse(w, common.SetStatusAutomatically, err, expl)
+	}
+}
+
+func (api *API) pinHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if pin := api.ParseCidOrFail(w, r); pin.Defined() {
+		if !api.OwnsPinOrFail(w, r, pin.Cid) {
+			return
+		}
+		var entries []types.PinAuditEntry
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"PinHistory",
+			pin.Cid,
+			&entries,
+		)
+		api.SendResponse(w, common.SetStatusAutomatically, err, entries)
+	}
+}
+
 func (api *API) statusAllHandler(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
@@ -552,20 +882,69 @@ func (api *API) statusAllHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	opts := types.StatusAllOptions{
+		Filter:    filter,
+		PinFilter: types.PinFilterFromQuery(queryValues),
+		CidPrefix: queryValues.Get("cid-prefix"),
+	}
+	if peersStr := queryValues.Get("peer"); peersStr != "" {
+		for _, p := range strings.Split(peersStr, ",") {
+			pid, err := peer.Decode(p)
+			if err != nil {
+				api.SendResponse(w, http.StatusBadRequest, fmt.Errorf("error decoding peer %s: %w", p, err), nil)
+				return
+			}
+			opts.Peers = append(opts.Peers, pid)
+		}
+	}
+	if batchStr := queryValues.Get("batch-size"); batchStr != "" {
+		batchSize, err := strconv.Atoi(batchStr)
+		if err != nil || batchSize < 0 {
+			api.SendResponse(w, http.StatusBadRequest, errors.New("invalid batch-size value"), nil)
+			return
+		}
+		opts.BatchSize = batchSize
+	}
+	if timeoutStr := queryValues.Get("timeout-per-peer"); timeoutStr != "" {
+		timeout, err := time.ParseDuration(timeoutStr)
+		if err != nil || timeout < 0 {
+			api.SendResponse(w, http.StatusBadRequest, errors.New("invalid timeout-per-peer value"), nil)
+			return
+		}
+		opts.TimeoutPerPeer = timeout
+	}
+
+	// users restricted to a namespace only see its pins.
+	ns := api.Namespace(r)
+
 	var iter common.StreamIterator
-	in := make(chan types.TrackerStatus, 1)
-	in <- filter
-	close(in)
 	errCh := make(chan error, 1)
 
 	if local == "true" {
+		in := make(chan types.TrackerStatus, 1)
+		in <- filter
+		close(in)
 		out := make(chan types.PinInfo, common.StreamChannelSize)
 		iter = func() (interface{}, bool, error) {
-			select {
-			case <-ctx.Done():
-				return nil, false, ctx.Err()
-			case p, ok := <-out:
-				return p.ToGlobal(), ok, nil
+			for {
+				select {
+				case <-ctx.Done():
+					return nil, false, ctx.Err()
+				case p, ok := <-out:
+					if ok && !opts.PinFilter.Match(p.Name, p.Tags, p.Metadata) {
+						continue
+					}
+					if ok && ns != "" && p.Namespace != ns {
+						continue
+					}
+					if ok && !strings.HasPrefix(p.Cid.String(), opts.CidPrefix) {
+						continue
+					}
+					if ok && len(opts.Peers) > 0 && !containsPeer(opts.Peers, p.Peer) {
+						continue
+					}
+					return p.ToGlobal(), ok, nil
+				}
 			}
 		}
 
@@ -585,13 +964,21 @@ func (api *API) statusAllHandler(w http.ResponseWriter, r *http.Request) {
 	} else {
 		out := make(chan types.GlobalPinInfo, common.StreamChannelSize)
 		iter = func() (interface{}, bool, error) {
-			select {
-			case <-ctx.Done():
-				return nil, false, ctx.Err()
-			case p, ok := <-out:
-				return p, ok, nil
+			for {
+				select {
+				case <-ctx.Done():
+					return nil, false, ctx.Err()
+				case p, ok := <-out:
+					if ok && ns != "" && p.Namespace != ns {
+						continue
+					}
+					return p, ok, nil
+				}
 			}
 		}
+		in := make(chan types.StatusAllOptions, 1)
+		in <- opts
+		close(in)
 		go func() {
 			defer close(errCh)
 
@@ -599,7 +986,7 @@ func (api *API) statusAllHandler(w http.ResponseWriter, r *http.Request) {
 				r.Context(),
 				"",
 				"Cluster",
-				"StatusAll",
+				"StatusAllWithOptions",
 				in,
 				out,
 			)
@@ -683,9 +1070,15 @@ func (api *API) statusCidsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	ns := api.Namespace(r)
 	iter := func() (interface{}, bool, error) {
-		gpi, ok := <-gpiCh
-		return gpi, ok, nil
+		for {
+			gpi, ok := <-gpiCh
+			if ok && ns != "" && gpi.Namespace != ns {
+				continue
+			}
+			return gpi, ok, nil
+		}
 	}
 
 	api.StreamResponse(w, iter, errCh)
@@ -696,6 +1089,9 @@ func (api *API) statusHandler(w http.ResponseWriter, r *http.Request) {
 	local := queryValues.Get("local")
 
 	if pin := api.ParseCidOrFail(w, r); pin.Defined() {
+		if !api.OwnsPinOrFail(w, r, pin.Cid) {
+			return
+		}
 		if local == "true" {
 			var pinInfo types.PinInfo
 			err := api.rpcClient.CallContext(
@@ -723,14 +1119,39 @@ func (api *API) statusHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (api *API) recoverAllHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.UnrestrictedOrFail(w, r) {
+		return
+	}
 	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
 
 	queryValues := r.URL.Query()
 	local := queryValues.Get("local")
 
+	filterStr := queryValues.Get("filter")
+	filter := types.TrackerStatusFromString(filterStr)
+	if filter == types.TrackerStatusUndefined && filterStr != "" {
+		api.SendResponse(w, http.StatusBadRequest, errors.New("invalid filter value"), nil)
+		return
+	}
+	opts := types.RecoverAllOptions{
+		Filter:    filter,
+		PinFilter: types.PinFilterFromQuery(queryValues),
+		CidPrefix: queryValues.Get("cid-prefix"),
+		Force:     queryValues.Get("force") == "true",
+	}
+	if rateStr := queryValues.Get("rate"); rateStr != "" {
+		rate, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || rate < 0 {
+			api.SendResponse(w, http.StatusBadRequest, errors.New("invalid rate value"), nil)
+			return
+		}
+		opts.Rate = rate
+	}
+
 	var iter common.StreamIterator
-	in := make(chan struct{})
+	in := make(chan types.RecoverAllOptions, 1)
+	in <- opts
 	close(in)
 	errCh := make(chan error, 1)
 
@@ -752,7 +1173,7 @@ func (api *API) recoverAllHandler(w http.ResponseWriter, r *http.Request) {
 				r.Context(),
 				"",
 				"Cluster",
-				"RecoverAllLocal",
+				"RecoverAllLocalWithOptions",
 				in,
 				out,
 			)
@@ -775,7 +1196,7 @@ func (api *API) recoverAllHandler(w http.ResponseWriter, r *http.Request) {
 				r.Context(),
 				"",
 				"Cluster",
-				"RecoverAll",
+				"RecoverAllWithOptions",
 				in,
 				out,
 			)
@@ -790,6 +1211,9 @@ func (api *API) recoverHandler(w http.ResponseWriter, r *http.Request) {
 	local := queryValues.Get("local")
 
 	if pin := api.ParseCidOrFail(w, r); pin.Defined() {
+		if !api.OwnsPinOrFail(w, r, pin.Cid) {
+			return
+		}
 		if local == "true" {
 			var pinInfo types.PinInfo
 			err := api.rpcClient.CallContext(
@@ -847,10 +1271,781 @@ func (api *API) repoGCHandler(w http.ResponseWriter, r *http.Request) {
 	api.SendResponse(w, common.SetStatusAutomatically, err, repoGC)
 }
 
-func repoGCToGlobal(r types.RepoGC) types.GlobalRepoGC {
-	return types.GlobalRepoGC{
-		PeerMap: map[string]types.RepoGC{
-			r.Peer.String(): r,
-		},
+func (api *API) peersArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	var peers []types.ArchivedPeer
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"PeersArchive",
+		struct{}{},
+		&peers,
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, peers)
+}
+
+func (api *API) apiEndpointsHandler(w http.ResponseWriter, r *http.Request) {
+	var endpoints []types.APIEndpoint
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"APIEndpoints",
+		struct{}{},
+		&endpoints,
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, endpoints)
+}
+
+func (api *API) observedPinsHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.UnrestrictedOrFail(w, r) {
+		return
+	}
+	var pins []types.ObservedPin
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"ObservedPins",
+		struct{}{},
+		&pins,
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, pins)
+}
+
+func (api *API) pinQueuesHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.UnrestrictedOrFail(w, r) {
+		return
+	}
+	queryValues := r.URL.Query()
+	local := queryValues.Get("local")
+
+	if local == "true" {
+		var localQueues types.PinQueueInfo
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"PinQueuesLocal",
+			struct{}{},
+			&localQueues,
+		)
+
+		api.SendResponse(w, common.SetStatusAutomatically, err, pinQueueInfoToGlobal(localQueues))
+		return
+	}
+
+	var queues types.GlobalPinQueueInfo
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"PinQueues",
+		struct{}{},
+		&queues,
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, queues)
+}
+
+// pinGCHandler evaluates the pin garbage collection policy. The selected
+// pins are only unpinned on POST requests. Users restricted to a namespace
+// cannot use it, as it works with the pins of every namespace.
+func (api *API) pinGCHandler(w http.ResponseWriter, r *http.Request) {
+	if api.Namespace(r) != "" {
+		api.SendResponse(w, http.StatusForbidden, errors.New("pin garbage collection is not accessible with these credentials"), nil)
+		return
+	}
+
+	var report types.PinGCReport
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"PinGC",
+		r.Method == http.MethodPost,
+		&report,
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, report)
+}
+
+func (api *API) dedupStatsHandler(w http.ResponseWriter, r *http.Request) {
+	queryValues := r.URL.Query()
+	local := queryValues.Get("local")
+
+	if local == "true" {
+		var localStats types.DedupStats
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"DedupStatsLocal",
+			struct{}{},
+			&localStats,
+		)
+
+		api.SendResponse(w, common.SetStatusAutomatically, err, types.GlobalDedupStats{
+			PeerMap: map[string]types.DedupStats{
+				localStats.Peer.String(): localStats,
+			},
+		})
+		return
+	}
+
+	var stats types.GlobalDedupStats
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"DedupStats",
+		struct{}{},
+		&stats,
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, stats)
+}
+
+// capacityHandler returns the storage capacity of the cluster peers. Only
+// the contacted peer is included when local=true.
+func (api *API) capacityHandler(w http.ResponseWriter, r *http.Request) {
+	queryValues := r.URL.Query()
+	local := queryValues.Get("local")
+
+	if local == "true" {
+		var pc types.PeerCapacity
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"CapacityLocal",
+			struct{}{},
+			&pc,
+		)
+
+		api.SendResponse(w, common.SetStatusAutomatically, err, types.GlobalCapacity{
+			Total: pc.Capacity,
+			PeerMap: map[string]types.PeerCapacity{
+				pc.Peer.String(): pc,
+			},
+		})
+		return
+	}
+
+	var capacity types.GlobalCapacity
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"Capacity",
+		struct{}{},
+		&capacity,
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, capacity)
+}
+
+// sloReportHandler returns the compliance with the pin SLOs of the whole
+// cluster, or of the contacted peer only when local=true.
+func (api *API) sloReportHandler(w http.ResponseWriter, r *http.Request) {
+	method := "SLOReport"
+	if r.URL.Query().Get("local") == "true" {
+		method = "SLOReportLocal"
+	}
+
+	var report types.SLOReport
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		method,
+		struct{}{},
+		&report,
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, report)
+}
+
+// stateChecksumHandler returns the state checksum of the cluster peers.
+// Only the contacted peer is included when local=true.
+func (api *API) stateChecksumHandler(w http.ResponseWriter, r *http.Request) {
+	queryValues := r.URL.Query()
+	local := queryValues.Get("local")
+
+	if local == "true" {
+		var sum types.StateChecksum
+		err := api.rpcClient.CallContext(
+			r.Context(),
+			"",
+			"Cluster",
+			"StateChecksumLocal",
+			struct{}{},
+			&sum,
+		)
+
+		api.SendResponse(w, common.SetStatusAutomatically, err, types.GlobalStateChecksum{
+			PeerMap: map[string]types.StateChecksum{
+				sum.Peer.String(): sum,
+			},
+		})
+		return
+	}
+
+	var global types.GlobalStateChecksum
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"StateChecksum",
+		struct{}{},
+		&global,
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, global)
+}
+
+// consensusLogHandler returns the operations recently committed to the
+// consensus log. The "before" (log index) and "limit" query parameters
+// allow paginating through them.
+func (api *API) consensusLogHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.UnrestrictedOrFail(w, r) {
+		return
+	}
+	queryValues := r.URL.Query()
+	q := types.ConsensusLogQuery{
+		Limit: defaultConsensusLogLimit,
+	}
+
+	if before := queryValues.Get("before"); before != "" {
+		b, err := strconv.ParseUint(before, 10, 64)
+		if err != nil {
+			api.SendResponse(w, http.StatusBadRequest, errors.New("error parsing 'before' query param"), nil)
+			return
+		}
+		q.Before = b
+	}
+	if limit := queryValues.Get("limit"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil || l <= 0 {
+			api.SendResponse(w, http.StatusBadRequest, errors.New("error parsing 'limit' query param"), nil)
+			return
+		}
+		q.Limit = l
+	}
+
+	var entries []types.ConsensusLogEntry
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"ConsensusLog",
+		q,
+		&entries,
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, entries)
+}
+
+// deadLettersHandler returns the operations that the peer failed to apply
+// to the shared state.
+func (api *API) deadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.UnrestrictedOrFail(w, r) {
+		return
+	}
+	var letters []types.DeadLetter
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"DeadLetters",
+		struct{}{},
+		&letters,
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, letters)
+}
+
+func (api *API) retryDeadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.UnrestrictedOrFail(w, r) {
+		return
+	}
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"RetryDeadLetter",
+		mux.Vars(r)["id"],
+		&struct{}{},
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, nil)
+}
+
+func (api *API) discardDeadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.UnrestrictedOrFail(w, r) {
+		return
+	}
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"DiscardDeadLetter",
+		mux.Vars(r)["id"],
+		&struct{}{},
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, nil)
+}
+
+// consensusEventsHandler streams the operations applied to the shared state
+// until the client disconnects. The response starts with the first event.
+func (api *API) consensusEventsHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.UnrestrictedOrFail(w, r) {
+		return
+	}
+	in := make(chan struct{})
+	close(in)
+
+	out := make(chan types.ConsensusEvent, common.StreamChannelSize)
+	errCh := make(chan error, 1)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	go func() {
+		defer close(errCh)
+
+		errCh <- api.rpcClient.Stream(
+			ctx,
+			"",
+			"Cluster",
+			"ConsensusEvents",
+			in,
+			out,
+		)
+	}()
+
+	iter := func() (interface{}, bool, error) {
+		var ev types.ConsensusEvent
+		var ok bool
+		select {
+		case <-ctx.Done():
+		case ev, ok = <-out:
+		}
+		return ev, ok, ctx.Err()
+	}
+
+	api.StreamResponse(w, iter, errCh)
+}
+
+// eventHistoryHandler streams the events in the cluster event history of
+// this peer which match the query parameters, oldest first.
+func (api *API) eventHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.UnrestrictedOrFail(w, r) {
+		return
+	}
+	q, err := types.ClusterEventQueryFromQuery(r.URL.Query())
+	if err != nil {
+		api.SendResponse(w, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	in := make(chan types.ClusterEventQuery, 1)
+	in <- q
+	close(in)
+
+	out := make(chan types.ClusterEvent, common.StreamChannelSize)
+	errCh := make(chan error, 1)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	go func() {
+		defer close(errCh)
+
+		errCh <- api.rpcClient.Stream(
+			ctx,
+			"",
+			"Cluster",
+			"EventHistory",
+			in,
+			out,
+		)
+	}()
+
+	iter := func() (interface{}, bool, error) {
+		var ev types.ClusterEvent
+		var ok bool
+		select {
+		case <-ctx.Done():
+		case ev, ok = <-out:
+		}
+		return ev, ok, ctx.Err()
+	}
+
+	api.StreamResponse(w, iter, errCh)
+}
+
+// eventsHandler streams the events in the cluster event history of this
+// peer which match the query parameters, and then the new ones as they are
+// recorded, as Server-Sent Events. The ID of every event is a cursor:
+// clients resume the stream from where they left it with the Last-Event-ID
+// header, which browsers send automatically when reconnecting, or with the
+// after parameter.
+func (api *API) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.UnrestrictedOrFail(w, r) {
+		return
+	}
+	q, err := types.ClusterEventQueryFromQuery(r.URL.Query())
+	if err != nil {
+		api.SendResponse(w, http.StatusBadRequest, err, nil)
+		return
+	}
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		q.After, err = strconv.ParseUint(lastID, 10, 64)
+		if err != nil {
+			api.SendResponse(w, http.StatusBadRequest, errors.New("invalid Last-Event-ID header"), nil)
+			return
+		}
+	}
+
+	in := make(chan types.ClusterEventQuery, 1)
+	in <- q
+	close(in)
+
+	out := make(chan types.ClusterEvent, common.StreamChannelSize)
+	errCh := make(chan error, 1)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	go func() {
+		defer close(errCh)
+
+		errCh <- api.rpcClient.Stream(
+			ctx,
+			"",
+			"Cluster",
+			"Events",
+			in,
+			out,
+		)
+	}()
+
+	api.SetHeaders(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, flush := w.(http.Flusher)
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		if flush {
+			flusher.Flush()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case ev, ok := <-out:
+			if !ok {
+				if err := <-errCh; err != nil && ctx.Err() == nil {
+					data, _ := json.Marshal(api.config.APIErrorFunc(err, http.StatusInternalServerError))
+					fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+				}
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				logger.Error(err)
+				return
+			}
+			fmt.Fprintf(w, "id: %d\ndata: %s\n\n", ev.ID, data)
+		}
+	}
+}
+
+func (api *API) quorumHandler(w http.ResponseWriter, r *http.Request) {
+	var status types.QuorumStatus
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"Quorum",
+		struct{}{},
+		&status,
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, status)
+}
+
+// rollbackHandler replaces the shared state with the pinset in the request
+// body, given as a stream of JSON pins (the format used by "state export").
+// The pin schedules in the stream are ignored. As this can unpin
+// everything, it requires the confirm=true query parameter.
+func (api *API) rollbackHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.AdminOrFail(w, r) {
+		return
+	}
+	if r.URL.Query().Get("confirm") != "true" {
+		api.SendResponse(w, http.StatusBadRequest, errors.New("rollbacks replace the full pinset and must be confirmed with confirm=true"), nil)
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+
+	var pins []types.Pin
+	for {
+		var entry struct {
+			types.Pin
+			Schedule *types.PinSchedule `json:"schedule"`
+		}
+		err := dec.Decode(&entry)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			api.SendResponse(w, http.StatusBadRequest, fmt.Errorf("error decoding pins: %w", err), nil)
+			return
+		}
+		if entry.Schedule == nil {
+			pins = append(pins, entry.Pin)
+		}
+	}
+
+	var info types.RollbackInfo
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"Rollback",
+		pins,
+		&info,
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, info)
+}
+
+// runJobHandler runs a maintenance job on the peers given as a
+// comma-separated list in the "peers" query parameter, or on all cluster
+// peers when it is not set.
+func (api *API) runJobHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.AdminOrFail(w, r) {
+		return
+	}
+	req := types.JobRequest{
+		Job: mux.Vars(r)["job"],
+	}
+
+	if peersStr := r.URL.Query().Get("peers"); peersStr != "" {
+		for _, p := range strings.Split(peersStr, ",") {
+			pid, err := peer.Decode(p)
+			if err != nil {
+				api.SendResponse(w, http.StatusBadRequest, fmt.Errorf("error decoding peer %s: %w", p, err), nil)
+				return
+			}
+			req.Peers = append(req.Peers, pid)
+		}
+	}
+
+	var res types.GlobalJobResult
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"RunJob",
+		req,
+		&res,
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, res)
+}
+
+func (api *API) handoffHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.AdminOrFail(w, r) {
+		return
+	}
+	if r.URL.Query().Get("confirm") != "true" {
+		api.SendResponse(w, http.StatusBadRequest, errors.New("handoffs unpin the pins from this cluster and must be confirmed with confirm=true"), nil)
+		return
+	}
+
+	var req types.HandoffRequest
+	dec := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+	if err := dec.Decode(&req); err != nil {
+		api.SendResponse(w, http.StatusBadRequest, fmt.Errorf("error decoding handoff request: %w", err), nil)
+		return
+	}
+	ns, ok := api.NamespaceOrFail(w, r, req.Pins.Namespace)
+	if !ok {
+		return
+	}
+	req.Pins.Namespace = ns
+
+	var job types.HandoffJob
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"Handoff",
+		req,
+		&job,
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, job)
+}
+
+func (api *API) handoffsHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.AdminOrFail(w, r) {
+		return
+	}
+	var jobs []types.HandoffJob
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"Handoffs",
+		struct{}{},
+		&jobs,
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, jobs)
+}
+
+func (api *API) handoffStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.AdminOrFail(w, r) {
+		return
+	}
+	var job types.HandoffJob
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"HandoffStatus",
+		mux.Vars(r)["id"],
+		&job,
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, job)
+}
+
+func (api *API) cancelHandoffHandler(w http.ResponseWriter, r *http.Request) {
+	if !api.AdminOrFail(w, r) {
+		return
+	}
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"CancelHandoff",
+		mux.Vars(r)["id"],
+		&struct{}{},
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, nil)
+}
+
+func (api *API) schedulePinHandler(w http.ResponseWriter, r *http.Request) {
+	var s types.PinSchedule
+	dec := json.NewDecoder(r.Body)
+	defer r.Body.Close()
+	if err := dec.Decode(&s); err != nil {
+		api.SendResponse(w, http.StatusBadRequest, fmt.Errorf("error decoding pin schedule: %w", err), nil)
+		return
+	}
+	ns, ok := api.NamespaceOrFail(w, r, s.Options.Namespace)
+	if !ok {
+		return
+	}
+	s.Options.Namespace = ns
+	s.Options.Owners = api.Owners(r)
+	s.Options.Requester = api.Requester(r)
+
+	var out types.PinSchedule
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"SchedulePin",
+		s,
+		&out,
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, out)
+}
+
+// pinSchedules returns the pin schedules that the user that authenticated
+// the request can see: those in the namespace the user is restricted to,
+// if any.
+func (api *API) pinSchedules(r *http.Request) ([]types.PinSchedule, error) {
+	var schedules []types.PinSchedule
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"PinSchedules",
+		struct{}{},
+		&schedules,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ns := api.Namespace(r)
+	if ns == "" {
+		return schedules, nil
+	}
+	visible := make([]types.PinSchedule, 0, len(schedules))
+	for _, s := range schedules {
+		if s.Options.Namespace == ns {
+			visible = append(visible, s)
+		}
+	}
+	return visible, nil
+}
+
+func (api *API) pinSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	schedules, err := api.pinSchedules(r)
+	api.SendResponse(w, common.SetStatusAutomatically, err, schedules)
+}
+
+func (api *API) cancelPinScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	// The schedules of other namespaces are not visible.
+	if api.Namespace(r) != "" {
+		schedules, err := api.pinSchedules(r)
+		if err != nil {
+			api.SendResponse(w, common.SetStatusAutomatically, err, nil)
+			return
+		}
+		found := false
+		for _, s := range schedules {
+			found = found || s.ID == id
+		}
+		if !found {
+			api.SendResponse(w, http.StatusNotFound, state.ErrScheduleNotFound, nil)
+			return
+		}
+	}
+
+	err := api.rpcClient.CallContext(
+		r.Context(),
+		"",
+		"Cluster",
+		"CancelPinSchedule",
+		id,
+		&struct{}{},
+	)
+	api.SendResponse(w, common.SetStatusAutomatically, err, nil)
+}
+
+func pinQueueInfoToGlobal(q types.PinQueueInfo) types.GlobalPinQueueInfo {
+	return types.GlobalPinQueueInfo{
+		PeerMap: map[string]types.PinQueueInfo{
+			q.Peer.String(): q,
+		},
+	}
+}
+
+func repoGCToGlobal(r types.RepoGC) types.GlobalRepoGC {
+	return types.GlobalRepoGC{
+		PeerMap: map[string]types.RepoGC{
+			r.Peer.String(): r,
+		},
+	}
+}
+
+func containsPeer(list []peer.ID, pid peer.ID) bool {
+	for _, p := range list {
+		if p == pid {
+			return true
+		}
 	}
+	return false
 }
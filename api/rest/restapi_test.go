@@ -1,7 +1,9 @@
 package rest
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -331,6 +333,12 @@ func TestAPIPinEndpoint(t *testing.T) {
 		if errResp.Code != 400 {
 			t.Error("should fail with bad Cid")
 		}
+
+		errResp = api.Error{}
+		test.MakePost(t, rest, url(rest)+"/pins/"+clustertest.LargeCid.String(), []byte{}, &errResp)
+		if errResp.Code != 413 {
+			t.Error("should fail with a pin too large")
+		}
 	}
 
 	test.BothEndpoints(t, tf)
@@ -463,6 +471,56 @@ func TestAPIUnpinEndpoint(t *testing.T) {
 	test.BothEndpoints(t, tf)
 }
 
+func TestAPIRestoreEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		var pin api.Pin
+		test.MakePost(t, rest, url(rest)+"/pins/"+clustertest.Cid1.String()+"/restore", []byte{}, &pin)
+		if !pin.Cid.Equals(clustertest.Cid1) {
+			t.Error("expected the restored pin")
+		}
+
+		errResp := api.Error{}
+		test.MakePost(t, rest, url(rest)+"/pins/"+clustertest.NotFoundCid.String()+"/restore", []byte{}, &errResp)
+		if errResp.Code != http.StatusNotFound {
+			t.Error("expected different error code: ", errResp.Code)
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPICancelPinEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		var pin api.Pin
+		test.MakePost(t, rest, url(rest)+"/pins/"+clustertest.Cid1.String()+"/cancel", []byte{}, &pin)
+		if !pin.Cid.Equals(clustertest.Cid1) || !pin.IsCanceled() {
+			t.Error("expected the canceled pin")
+		}
+
+		var unpinned api.Pin
+		test.MakePost(t, rest, url(rest)+"/pins/"+clustertest.Cid1.String()+"/cancel?unpin=true", []byte{}, &unpinned)
+		if !unpinned.Cid.Equals(clustertest.Cid1) || unpinned.IsCanceled() {
+			t.Error("expected the unpinned pin")
+		}
+
+		errResp := api.Error{}
+		test.MakePost(t, rest, url(rest)+"/pins/"+clustertest.NotFoundCid.String()+"/cancel", []byte{}, &errResp)
+		if errResp.Code != http.StatusNotFound {
+			t.Error("expected different error code: ", errResp.Code)
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
 func TestAPIUnpinEndpointWithPath(t *testing.T) {
 	ctx := context.Background()
 	rest := testAPI(t)
@@ -520,11 +578,73 @@ func TestAPIAllocationsEndpoint(t *testing.T) {
 			t.Error("unexpected pin list: ", resp)
 		}
 
+		test.MakeStreamingGet(t, rest, url(rest)+"/allocations?tags=test", &resp, false)
+		if len(resp) != 1 || !resp[0].Cid.Equals(clustertest.Cid3) {
+			t.Error("unexpected pin list when filtering by tag: ", resp)
+		}
+
+		test.MakeStreamingGet(t, rest, url(rest)+"/allocations?name=abc", &resp, false)
+		if len(resp) != 0 {
+			t.Error("unexpected pin list when filtering by name: ", resp)
+		}
+
+		test.MakeStreamingGet(t, rest, url(rest)+"/allocations?limit=2", &resp, false)
+		if len(resp) != 2 ||
+			!resp[0].Cid.Equals(clustertest.Cid2) || !resp[1].Cid.Equals(clustertest.Cid3) {
+			t.Error("unexpected first page: ", resp)
+		}
+
+		test.MakeStreamingGet(t, rest, url(rest)+"/allocations?limit=2&after="+clustertest.Cid3.String(), &resp, false)
+		if len(resp) != 1 || !resp[0].Cid.Equals(clustertest.Cid1) {
+			t.Error("unexpected second page: ", resp)
+		}
+
+		test.MakeStreamingGet(t, rest, url(rest)+"/allocations?cid-prefix=bafy", &resp, false)
+		if len(resp) != 0 {
+			t.Error("unexpected pin list when filtering by cid prefix: ", resp)
+		}
+
+		var resp9 []api.GlobalPinInfo
+		test.MakeStreamingGet(t, rest, url(rest)+"/pins?local=true&tags=test", &resp9, false)
+		if len(resp9) != 1 || !resp9[0].Cid.Equals(clustertest.Cid3) {
+			t.Errorf("unexpected statusAll+tags resp:\n %+v", resp9)
+		}
+
 		errResp := api.Error{}
 		test.MakeStreamingGet(t, rest, url(rest)+"/allocations?filter=invalid", &errResp, false)
 		if errResp.Code != http.StatusBadRequest {
 			t.Error("an invalid filter value should 400")
 		}
+
+		var resp8 []api.GlobalPinInfo
+		test.MakeStreamingGet(t, rest, url(rest)+"/pins?filter=pinned&batch-size=2&timeout-per-peer=10s", &resp8, false)
+		if len(resp8) != 1 {
+			t.Errorf("unexpected statusAll+batch-size resp:\n %+v", resp8)
+		}
+
+		var resp10 []api.GlobalPinInfo
+		test.MakeStreamingGet(t, rest, url(rest)+"/pins?filter=error,pinning&cid-prefix="+clustertest.Cid2.String(), &resp10, false)
+		if len(resp10) != 1 || !resp10[0].Cid.Equals(clustertest.Cid2) {
+			t.Errorf("unexpected statusAll+cid-prefix resp:\n %+v", resp10)
+		}
+
+		var errorResp4 api.Error
+		test.MakeStreamingGet(t, rest, url(rest)+"/pins?peer=abc", &errorResp4, false)
+		if errorResp4.Code != http.StatusBadRequest {
+			t.Error("an invalid peer value should 400")
+		}
+
+		var errorResp2 api.Error
+		test.MakeStreamingGet(t, rest, url(rest)+"/pins?timeout-per-peer=abc", &errorResp2, false)
+		if errorResp2.Code != http.StatusBadRequest {
+			t.Error("an invalid timeout-per-peer value should 400")
+		}
+
+		var errorResp3 api.Error
+		test.MakeStreamingGet(t, rest, url(rest)+"/pins?batch-size=-1", &errorResp3, false)
+		if errorResp3.Code != http.StatusBadRequest {
+			t.Error("an invalid batch-size value should 400")
+		}
 	}
 
 	test.BothEndpoints(t, tf)
@@ -552,6 +672,149 @@ func TestAPIAllocationEndpoint(t *testing.T) {
 	test.BothEndpoints(t, tf)
 }
 
+func TestAPINamespaces(t *testing.T) {
+	ctx := context.Background()
+	cfg := NewConfig()
+	cfg.Default()
+	cfg.CORSAllowedOrigins = []string{clientOrigin}
+	cfg.CORSAllowedMethods = []string{"GET", "POST", "DELETE"}
+	// requests without credentials are restricted to the "test"
+	// namespace, like Cid3 in the mock.
+	cfg.Namespaces = map[string]string{"*": "test"}
+	rest := testAPIwithConfig(t, cfg, "namespaces")
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		var pins []api.Pin
+		test.MakeStreamingGet(t, rest, url(rest)+"/allocations?filter=all", &pins, false)
+		if len(pins) != 1 || !pins[0].Cid.Equals(clustertest.Cid3) {
+			t.Errorf("expected only the pins in the namespace: %+v", pins)
+		}
+
+		errResp := api.Error{}
+		test.MakeGet(t, rest, url(rest)+"/allocations?namespace=other", &errResp)
+		if errResp.Code != 403 {
+			t.Error("listing another namespace should be forbidden")
+		}
+
+		var pin api.Pin
+		test.MakeGet(t, rest, url(rest)+"/allocations/"+clustertest.Cid3.String(), &pin)
+		if !pin.Cid.Equals(clustertest.Cid3) {
+			t.Errorf("unexpected pin: %+v", pin)
+		}
+
+		errResp = api.Error{}
+		test.MakeGet(t, rest, url(rest)+"/allocations/"+clustertest.Cid1.String(), &errResp)
+		if errResp.Code != 404 {
+			t.Error("a pin of another namespace should 404")
+		}
+
+		errResp = api.Error{}
+		test.MakeDelete(t, rest, url(rest)+"/pins/"+clustertest.Cid1.String(), &errResp)
+		if errResp.Code != 404 {
+			t.Error("unpinning a pin of another namespace should 404")
+		}
+
+		pin = api.Pin{}
+		test.MakeDelete(t, rest, url(rest)+"/pins/"+clustertest.Cid3.String(), &pin)
+		if !pin.Cid.Equals(clustertest.Cid3) {
+			t.Errorf("unexpected unpinned pin: %+v", pin)
+		}
+
+		pin = api.Pin{}
+		test.MakePost(t, rest, url(rest)+"/pins/"+clustertest.Cid4.String(), []byte{}, &pin)
+		if pin.Namespace != "test" {
+			t.Errorf("the pin should be in the namespace: %+v", pin)
+		}
+
+		errResp = api.Error{}
+		test.MakePost(t, rest, url(rest)+"/pins/"+clustertest.Cid4.String()+"?namespace=other", []byte{}, &errResp)
+		if errResp.Code != 403 {
+			t.Error("pinning in another namespace should be forbidden")
+		}
+
+		errResp = api.Error{}
+		test.MakePost(t, rest, url(rest)+"/pins/"+clustertest.Cid4.String()+"?name="+clustertest.ConflictingPinName, []byte{}, &errResp)
+		if errResp.Code != 409 {
+			t.Error("a name already used in the namespace should conflict")
+		}
+
+		errResp = api.Error{}
+		test.MakePost(t, rest, url(rest)+"/admin/handoffs?confirm=true", []byte(`{"target":"/ip4/10.0.0.1/tcp/9094"}`), &errResp)
+		if errResp.Code != 403 {
+			t.Error("handoffs should be forbidden to users restricted to a namespace")
+		}
+
+		errResp = api.Error{}
+		test.MakePost(t, rest, url(rest)+"/admin/rollback?confirm=true", []byte{}, &errResp)
+		if errResp.Code != 403 {
+			t.Error("rollbacks should be forbidden to users restricted to a namespace")
+		}
+
+		for _, path := range []string{
+			"/pins/observed",
+			"/pins/queues",
+			"/consensus/log",
+			"/consensus/deadletters",
+			"/events/history",
+		} {
+			errResp = api.Error{}
+			test.MakeGet(t, rest, url(rest)+path, &errResp)
+			if errResp.Code != 403 {
+				t.Errorf("%s should be forbidden to users restricted to a namespace", path)
+			}
+		}
+
+		errResp = api.Error{}
+		test.MakeGet(t, rest, url(rest)+"/pins/"+clustertest.Cid1.String()+"/history", &errResp)
+		if errResp.Code != 404 {
+			t.Error("the history of a pin of another namespace should 404")
+		}
+
+		var schedules []api.PinSchedule
+		test.MakeGet(t, rest, url(rest)+"/schedules", &schedules)
+		if len(schedules) != 0 {
+			t.Errorf("the schedules of other namespaces should not be listed: %+v", schedules)
+		}
+
+		errResp = api.Error{}
+		test.MakeDelete(t, rest, url(rest)+"/schedules/schedule-1", &errResp)
+		if errResp.Code != 404 {
+			t.Error("canceling a schedule of another namespace should 404")
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPIPinOwners(t *testing.T) {
+	ctx := context.Background()
+	cfg := NewConfig()
+	cfg.Default()
+	cfg.CORSAllowedOrigins = []string{clientOrigin}
+	cfg.CORSAllowedMethods = []string{"GET", "POST", "DELETE"}
+	cfg.BasicAuthCredentials = map[string]string{"alice": "secret"}
+	rest := testAPIwithConfig(t, cfg, "owners")
+	defer rest.Shutdown(ctx)
+
+	aliceURL := func(a test.API) string {
+		u, _ := a.HTTPAddresses()
+		return "http://alice:secret@" + u[0]
+	}
+
+	var pin api.Pin
+	test.MakePost(t, rest, aliceURL(rest)+"/pins/"+clustertest.Cid1.String(), []byte{}, &pin)
+	if len(pin.Owners) != 1 || pin.Owners[0] != "alice" {
+		t.Errorf("the pin should be owned by the user: %+v", pin)
+	}
+
+	pin = api.Pin{}
+	test.MakeDelete(t, rest, aliceURL(rest)+"/pins/"+clustertest.Cid1.String(), &pin)
+	if len(pin.Owners) != 1 || pin.Owners[0] != "alice" {
+		t.Errorf("only the reference of the user should be removed: %+v", pin)
+	}
+}
+
 func TestAPIMetricsEndpoint(t *testing.T) {
 	ctx := context.Background()
 	rest := testAPI(t)
@@ -776,6 +1039,53 @@ func TestAPIRecoverEndpoint(t *testing.T) {
 	test.BothEndpoints(t, tf)
 }
 
+func TestAPIAllocationExplainEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		var resp api.AllocationExplanation
+		test.MakeGet(t, rest, url(rest)+"/pins/"+clustertest.Cid1.String()+"/allocation-explain", &resp)
+		if !resp.Cid.Equals(clustertest.Cid1) {
+			t.Error("expected the same cid")
+		}
+		if len(resp.Peers) != 2 || !resp.Peers[0].Chosen || resp.Peers[1].Rank != 2 {
+			t.Errorf("unexpected explanation: %+v", resp)
+		}
+
+		var errResp api.Error
+		test.MakeGet(t, rest, url(rest)+"/pins/"+clustertest.ErrorCid.String()+"/allocation-explain", &errResp)
+		if errResp.Code != http.StatusInternalServerError {
+			t.Error("expected an error")
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPIPinHistoryEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		var resp []api.PinAuditEntry
+		test.MakeGet(t, rest, url(rest)+"/pins/"+clustertest.Cid1.String()+"/history", &resp)
+		if len(resp) != 2 {
+			t.Fatalf("expected 2 entries: %+v", resp)
+		}
+		if resp[0].Type != api.PinAuditPin || resp[0].Requester.User != "alice" || resp[0].Requester.Peer != clustertest.PeerID1 {
+			t.Errorf("unexpected entry: %+v", resp[0])
+		}
+		if resp[1].Type != api.PinAuditUnpin || !resp[1].Cid.Equals(clustertest.Cid1) {
+			t.Errorf("unexpected entry: %+v", resp[1])
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
 func TestAPIRecoverAllEndpoint(t *testing.T) {
 	ctx := context.Background()
 	rest := testAPI(t)
@@ -793,6 +1103,18 @@ func TestAPIRecoverAllEndpoint(t *testing.T) {
 		if len(resp1) == 0 {
 			t.Fatal("bad response length")
 		}
+
+		var resp2 []api.GlobalPinInfo
+		test.MakeStreamingPost(t, rest, url(rest)+"/pins/recover?filter=pin_error&rate=10&force=true", nil, "", &resp2)
+		if len(resp2) != 1 || !resp2[0].Cid.Equals(clustertest.Cid3) {
+			t.Errorf("unexpected recover+filter resp:\n %+v", resp2)
+		}
+
+		var errResp api.Error
+		test.MakeStreamingPost(t, rest, url(rest)+"/pins/recover?rate=abc", nil, "", &errResp)
+		if errResp.Code != http.StatusBadRequest {
+			t.Error("an invalid rate value should 400")
+		}
 	}
 
 	test.BothEndpoints(t, tf)
@@ -845,6 +1167,513 @@ func TestAPIIPFSGCEndpoint(t *testing.T) {
 	test.BothEndpoints(t, tf)
 }
 
+func TestAPIObservedPinsEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		var resp []api.ObservedPin
+		test.MakeGet(t, rest, url(rest)+"/pins/observed", &resp)
+		if len(resp) != 1 || !resp[0].Cid.Equals(clustertest.Cid1) || resp[0].Peer == "" {
+			t.Errorf("unexpected observed pins: %+v", resp)
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPIPinQueuesEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		var resp api.GlobalPinQueueInfo
+		test.MakeGet(t, rest, url(rest)+"/pins/queues?local=true", &resp)
+		q, ok := resp.PeerMap[clustertest.PeerID1.String()]
+		if !ok || q.PinDepth != 10 || q.ConcurrentPins != 2 {
+			t.Errorf("unexpected pin queues: %+v", resp)
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPIPeersArchiveEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		var resp []api.ArchivedPeer
+		test.MakeGet(t, rest, url(rest)+"/peers/archive", &resp)
+		if len(resp) != 1 || resp[0].ID != clustertest.PeerID3 || resp[0].Peername != clustertest.PeerName3 {
+			t.Errorf("unexpected archived peers: %+v", resp)
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPIEndpointsEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		var resp []api.APIEndpoint
+		test.MakeGet(t, rest, url(rest)+"/peers/apis", &resp)
+		if len(resp) != 1 || resp[0].Peer != clustertest.PeerID1 || !resp[0].Public || len(resp[0].Addresses) != 1 {
+			t.Errorf("unexpected api endpoints: %+v", resp)
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPIDedupStatsEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		for _, path := range []string{"/accounting/dedup?local=true", "/accounting/dedup"} {
+			var resp api.GlobalDedupStats
+			test.MakeGet(t, rest, url(rest)+path, &resp)
+			if len(resp.PeerMap) != 1 {
+				t.Fatalf("%s: expected stats for one peer", path)
+			}
+			for _, stats := range resp.PeerMap {
+				if stats.Peer == "" {
+					t.Error("expected a cluster ID")
+				}
+				if stats.UniqueBlocks != 4 || stats.SharedBlocks != 2 {
+					t.Errorf("unexpected stats: %+v", stats)
+				}
+			}
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+
+func TestAPIPinGCEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		var report api.PinGCReport
+		test.MakeGet(t, rest, url(rest)+"/pins/gc", &report)
+		if report.Executed || len(report.Candidates) != 1 {
+			t.Errorf("unexpected proposals: %+v", report)
+		}
+
+		report = api.PinGCReport{}
+		test.MakePost(t, rest, url(rest)+"/pins/gc", []byte{}, &report)
+		if !report.Executed || !report.Candidates[0].Cid.Equals(clustertest.Cid1) {
+			t.Errorf("unexpected report: %+v", report)
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPICapacityEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		for _, path := range []string{"/capacity?local=true", "/capacity"} {
+			var resp api.GlobalCapacity
+			test.MakeGet(t, rest, url(rest)+path, &resp)
+			if len(resp.PeerMap) != 1 {
+				t.Fatalf("%s: expected the capacity of one peer", path)
+			}
+			for _, pc := range resp.PeerMap {
+				if pc.Peer == "" {
+					t.Error("expected a cluster ID")
+				}
+				if pc.Free != 60*1024 || pc.DaysToFull != 60 {
+					t.Errorf("unexpected capacity: %+v", pc)
+				}
+			}
+			if resp.Total.StorageMax != 100*1024 {
+				t.Errorf("unexpected total: %+v", resp.Total)
+			}
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPISLOEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		for _, path := range []string{"/slo?local=true", "/slo"} {
+			var resp api.SLOReport
+			test.MakeGet(t, rest, url(rest)+path, &resp)
+			if resp.Window != 24*time.Hour || len(resp.Classes) != 1 {
+				t.Fatalf("%s: unexpected report: %+v", path, resp)
+			}
+			class := resp.Classes[0]
+			if class.Class != "gold" || class.Pinned != 100 || class.OnTime != 98 || class.Met {
+				t.Errorf("%s: unexpected class report: %+v", path, class)
+			}
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPIStateChecksumEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		for _, path := range []string{"/consensus/checksum?local=true", "/consensus/checksum"} {
+			var resp api.GlobalStateChecksum
+			test.MakeGet(t, rest, url(rest)+path, &resp)
+			if len(resp.PeerMap) != 1 {
+				t.Fatalf("%s: expected the checksum of one peer", path)
+			}
+			for _, sum := range resp.PeerMap {
+				if sum.Peer == "" || sum.Checksum == "" || sum.AppliedIndex != 10 {
+					t.Errorf("unexpected checksum: %+v", sum)
+				}
+			}
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPIConsensusLogEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		var entries []api.ConsensusLogEntry
+		test.MakeGet(t, rest, url(rest)+"/consensus/log", &entries)
+		if len(entries) != 3 {
+			t.Fatalf("expected 3 entries, got %d", len(entries))
+		}
+
+		entries = nil
+		test.MakeGet(t, rest, url(rest)+"/consensus/log?before=3&limit=1", &entries)
+		if len(entries) != 1 || entries[0].Index != 2 {
+			t.Errorf("unexpected page: %+v", entries)
+		}
+
+		errResp := api.Error{}
+		test.MakeGet(t, rest, url(rest)+"/consensus/log?limit=abc", &errResp)
+		if errResp.Code != http.StatusBadRequest {
+			t.Error("expected a bad request error")
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPIDeadLettersEndpoints(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		var letters []api.DeadLetter
+		test.MakeGet(t, rest, url(rest)+"/consensus/deadletters", &letters)
+		if len(letters) != 1 || letters[0].ID == "" {
+			t.Fatalf("unexpected dead letters: %+v", letters)
+		}
+
+		test.MakePost(t, rest, url(rest)+"/consensus/deadletters/"+letters[0].ID+"/retry", []byte{}, &struct{}{})
+		test.MakeDelete(t, rest, url(rest)+"/consensus/deadletters/"+letters[0].ID, &struct{}{})
+
+		errResp := api.Error{}
+		test.MakeDelete(t, rest, url(rest)+"/consensus/deadletters/unknown", &errResp)
+		if errResp.Code == 0 {
+			t.Error("expected an error discarding an unknown dead letter")
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPIRollbackEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		// pin schedules in state dumps are ignored.
+		body := []byte(`{"cid":"` + clustertest.Cid1.String() + `"}
+{"cid":"` + clustertest.Cid2.String() + `"}
+{"schedule":{"id":"schedule-1","path":"/ipns/example.org"}}
+`)
+		errResp := api.Error{}
+		test.MakePost(t, rest, url(rest)+"/admin/rollback", body, &errResp)
+		if errResp.Code != http.StatusBadRequest {
+			t.Error("rollbacks without confirmation should fail")
+		}
+
+		var info api.RollbackInfo
+		test.MakePost(t, rest, url(rest)+"/admin/rollback?confirm=true", body, &info)
+		if info.Pins != 2 || info.AuditSnapshot == "" {
+			t.Errorf("unexpected rollback info: %+v", info)
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPIPinScheduleEndpoints(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		errResp := api.Error{}
+		test.MakePost(t, rest, url(rest)+"/schedules", []byte(`{"path":"/ipns/example.org"}`), &errResp)
+		if errResp.Code == 0 {
+			t.Error("expected an error without not-before time nor cron expression")
+		}
+
+		var s api.PinSchedule
+		body := []byte(`{"path":"/ipns/example.org","cron":"0 * * * *","options":{"name":"site"}}`)
+		test.MakePost(t, rest, url(rest)+"/schedules", body, &s)
+		if s.ID == "" || s.Cron != "0 * * * *" || s.Options.Name != "site" {
+			t.Fatalf("unexpected schedule: %+v", s)
+		}
+
+		var schedules []api.PinSchedule
+		test.MakeGet(t, rest, url(rest)+"/schedules", &schedules)
+		if len(schedules) != 1 || !schedules[0].LastCid.Equals(clustertest.Cid1) {
+			t.Errorf("unexpected schedules: %+v", schedules)
+		}
+
+		test.MakeDelete(t, rest, url(rest)+"/schedules/"+s.ID, &struct{}{})
+		errResp = api.Error{}
+		test.MakeDelete(t, rest, url(rest)+"/schedules/unknown", &errResp)
+		if errResp.Code == 0 {
+			t.Error("expected an error canceling an unknown schedule")
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPIHandoffEndpoints(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		body := []byte(`{"target":"/ip4/10.0.0.1/tcp/9094","pins":{"name_prefix":"archive"}}`)
+		errResp := api.Error{}
+		test.MakePost(t, rest, url(rest)+"/admin/handoffs", body, &errResp)
+		if errResp.Code != http.StatusBadRequest {
+			t.Error("handoffs without confirmation should fail")
+		}
+
+		var job api.HandoffJob
+		test.MakePost(t, rest, url(rest)+"/admin/handoffs?confirm=true", body, &job)
+		if job.ID == "" || job.Target != "/ip4/10.0.0.1/tcp/9094" {
+			t.Fatalf("unexpected handoff: %+v", job)
+		}
+
+		var jobs []api.HandoffJob
+		test.MakeGet(t, rest, url(rest)+"/admin/handoffs", &jobs)
+		if len(jobs) != 1 {
+			t.Errorf("unexpected handoffs: %+v", jobs)
+		}
+
+		test.MakeGet(t, rest, url(rest)+"/admin/handoffs/"+job.ID, &job)
+		if job.Status != api.HandoffDone {
+			t.Errorf("unexpected handoff status: %+v", job)
+		}
+
+		test.MakeDelete(t, rest, url(rest)+"/admin/handoffs/"+job.ID, &struct{}{})
+		errResp = api.Error{}
+		test.MakeDelete(t, rest, url(rest)+"/admin/handoffs/unknown", &errResp)
+		if errResp.Code != http.StatusNotFound {
+			t.Errorf("expected not found canceling an unknown handoff: %d", errResp.Code)
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPIConsensusEventsEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		var resp []api.ConsensusEvent
+		test.MakeStreamingGet(t, rest, url(rest)+"/consensus/events", &resp, false)
+		if len(resp) != 2 || resp[0].Type != api.ConsensusEventPin || resp[1].Peer != clustertest.PeerID2 {
+			t.Errorf("unexpected events: %+v", resp)
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPIEventHistoryEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		var resp []api.ClusterEvent
+		test.MakeStreamingGet(t, rest, url(rest)+"/events/history", &resp, false)
+		if len(resp) != 3 || resp[0].Subject != clustertest.PeerID2 {
+			t.Errorf("unexpected events: %+v", resp)
+		}
+
+		resp = nil
+		test.MakeStreamingGet(t, rest, url(rest)+"/events/history?type=pin,unpin&after=2", &resp, false)
+		if len(resp) != 1 || resp[0].Type != api.ClusterEventUnpin {
+			t.Errorf("unexpected filtered events: %+v", resp)
+		}
+
+		resp = nil
+		test.MakeStreamingGet(t, rest, url(rest)+"/events/history?limit=2", &resp, false)
+		if len(resp) != 2 {
+			t.Errorf("expected 2 events: %+v", resp)
+		}
+
+		errResp := api.Error{}
+		test.MakeGet(t, rest, url(rest)+"/events/history?since=yesterday", &errResp)
+		if errResp.Code != 400 {
+			t.Error("expected an error for an invalid since parameter")
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPIEventsEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		h := test.MakeHost(t, rest)
+		defer h.Close()
+		c := test.HTTPClient(t, h, test.IsHTTPS(url(rest)))
+
+		// reads n events from the stream, which stays open.
+		events := func(path, lastID string, n int) []api.ClusterEvent {
+			ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			defer cancel()
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url(rest)+path, nil)
+			if lastID != "" {
+				req.Header.Set("Last-Event-ID", lastID)
+			}
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+				t.Fatalf("unexpected content type: %s", ct)
+			}
+
+			var evs []api.ClusterEvent
+			var id string
+			scanner := bufio.NewScanner(resp.Body)
+			for len(evs) < n && scanner.Scan() {
+				line := scanner.Text()
+				switch {
+				case strings.HasPrefix(line, "id: "):
+					id = strings.TrimPrefix(line, "id: ")
+				case strings.HasPrefix(line, "data: "):
+					var ev api.ClusterEvent
+					if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev); err != nil {
+						t.Fatal(err)
+					}
+					if id != fmt.Sprint(ev.ID) {
+						t.Errorf("the event id should be the cursor: %s, %+v", id, ev)
+					}
+					evs = append(evs, ev)
+				}
+			}
+			return evs
+		}
+
+		evs := events("/events", "", 3)
+		if len(evs) != 3 || evs[0].Subject != clustertest.PeerID2 || evs[2].Type != api.ClusterEventUnpin {
+			t.Errorf("unexpected events: %+v", evs)
+		}
+
+		evs = events("/events", "2", 1)
+		if len(evs) != 1 || evs[0].ID != 3 {
+			t.Errorf("unexpected events after the last event ID: %+v", evs)
+		}
+
+		evs = events("/events?type=pin", "", 1)
+		if len(evs) != 1 || evs[0].Type != api.ClusterEventPin {
+			t.Errorf("unexpected filtered events: %+v", evs)
+		}
+
+		errResp := api.Error{}
+		test.MakeGet(t, rest, url(rest)+"/events?after=abc", &errResp)
+		if errResp.Code != 400 {
+			t.Error("expected an error for an invalid after parameter")
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPIQuorumEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		var status api.QuorumStatus
+		test.MakeGet(t, rest, url(rest)+"/health/quorum", &status)
+		if !status.HasQuorum || status.Quorum != 2 || len(status.ReachableVoters) != 2 {
+			t.Errorf("unexpected quorum status: %+v", status)
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
+
+func TestAPIRunJobEndpoint(t *testing.T) {
+	ctx := context.Background()
+	rest := testAPI(t)
+	defer rest.Shutdown(ctx)
+
+	tf := func(t *testing.T, url test.URLFunc) {
+		var res api.GlobalJobResult
+		test.MakePost(t, rest, url(rest)+"/admin/jobs/reconnect-ipfs?peers="+clustertest.PeerID1.String(), []byte{}, &res)
+		jr, ok := res.PeerMap[clustertest.PeerID1.String()]
+		if res.Job != "reconnect-ipfs" || !ok || jr.Error != "" {
+			t.Errorf("unexpected job result: %+v", res)
+		}
+
+		errResp := api.Error{}
+		test.MakePost(t, rest, url(rest)+"/admin/jobs/reconnect-ipfs?peers=abc", []byte{}, &errResp)
+		if errResp.Code != http.StatusBadRequest {
+			t.Error("expected an error decoding the peers")
+		}
+	}
+
+	test.BothEndpoints(t, tf)
+}
 
 func TestHealthEndpoint(t *testing.T) {
 	ctx := context.Background()
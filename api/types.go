@@ -77,11 +77,14 @@ const (
 	// The item is in the state and should be pinned, but
 	// it is however not pinned and not queued/pinning.
 	TrackerStatusUnexpectedlyUnpinned
+	// The item could not be pinned after the maximum number of attempts
+	// and it will not be retried automatically.
+	TrackerStatusPinFailed
 )
 
 // Composite TrackerStatus.
 const (
-	TrackerStatusError  = TrackerStatusClusterError | TrackerStatusPinError | TrackerStatusUnpinError
+	TrackerStatusError  = TrackerStatusClusterError | TrackerStatusPinError | TrackerStatusUnpinError | TrackerStatusPinFailed
 	TrackerStatusQueued = TrackerStatusPinQueued | TrackerStatusUnpinQueued
 )
 
@@ -104,6 +107,7 @@ var trackerStatusString = map[TrackerStatus]string{
 	TrackerStatusQueued:               "queued",
 	TrackerStatusSharded:              "sharded",
 	TrackerStatusUnexpectedlyUnpinned: "unexpectedly_unpinned",
+	TrackerStatusPinFailed:            "pin_failed",
 }
 
 // values autofilled in init()
@@ -184,6 +188,58 @@ func TrackerStatusAll() []TrackerStatus {
 	return list
 }
 
+// StatusAllOptions controls how the status of all pins is gathered from
+// the cluster peers.
+type StatusAllOptions struct {
+	// Filter limits the results to items in the given statuses.
+	Filter TrackerStatus `json:"filter" codec:"f,omitempty"`
+	// BatchSize is the number of peers queried at the same time. The
+	// statuses from each batch are sent as soon as it finishes. 0 means
+	// all peers at once.
+	BatchSize int `json:"batch_size" codec:"b,omitempty"`
+	// TimeoutPerPeer is the maximum time to wait for the full response of
+	// every peer. 0 means no timeout.
+	TimeoutPerPeer time.Duration `json:"timeout_per_peer" codec:"t,omitempty"`
+	// PinFilter limits the results to the pins matching it.
+	PinFilter PinFilter `json:"pin_filter" codec:"p,omitempty"`
+	// CidPrefix limits the results to the items whose CID (as a string)
+	// starts with it.
+	CidPrefix string `json:"cid_prefix" codec:"cp,omitempty"`
+	// Peers limits the results to the statuses of the given peers, which
+	// are the only ones queried. Empty means all peers.
+	Peers []peer.ID `json:"peers" codec:"pe,omitempty"`
+}
+
+// RecoverAllOptions select the items recovered by a bulk recover operation
+// and how fast they are re-queued.
+type RecoverAllOptions struct {
+	// Filter limits the recovered items to those in the given statuses.
+	// 0 means all the statuses that can be recovered.
+	Filter TrackerStatus `json:"filter" codec:"f,omitempty"`
+	// PinFilter limits the recovered items to the pins matching it.
+	PinFilter PinFilter `json:"pin_filter" codec:"p,omitempty"`
+	// CidPrefix limits the recovered items to those whose CID (as a
+	// string) starts with it.
+	CidPrefix string `json:"cid_prefix" codec:"cp,omitempty"`
+	// Rate is the maximum number of items re-queued per second by every
+	// peer. 0 means no limit.
+	Rate float64 `json:"rate" codec:"r,omitempty"`
+	// Force retries the items right away, including pins which have
+	// used all their attempts, instead of following the retry policy.
+	Force bool `json:"force" codec:"fo,omitempty"`
+}
+
+// Match returns whether the given PinInfo is selected by these options.
+func (opts RecoverAllOptions) Match(pi PinInfo) bool {
+	if opts.Filter != TrackerStatusUndefined && !opts.Filter.Match(pi.Status) {
+		return false
+	}
+	if !strings.HasPrefix(pi.Cid.String(), opts.CidPrefix) {
+		return false
+	}
+	return opts.PinFilter.Match(pi.Name, pi.Tags, pi.Metadata)
+}
+
 // IPFSPinStatus values
 // FIXME include maxdepth
 const (
@@ -353,11 +409,39 @@ type GlobalPinInfo struct {
 	Origins     []Multiaddr       `json:"origins" codec:"g,omitempty"`
 	Created     time.Time         `json:"created" codec:"t,omitempty"`
 	Metadata    map[string]string `json:"metadata" codec:"m,omitempty"`
+	Tags        []string          `json:"tags,omitempty" codec:"tg,omitempty"`
+	Namespace   string            `json:"namespace,omitempty" codec:"ns,omitempty"`
 
 	// https://github.com/golang/go/issues/28827
 	// Peer IDs are of string Kind(). We can't use peer IDs here
 	// as Go ignores TextMarshaler.
 	PeerMap map[string]PinInfoShort `json:"peer_map" codec:"pm,omitempty"`
+
+	// Errors aggregates the distinct errors reported by the peers in
+	// PeerMap, most frequent first.
+	Errors []PinErrorSummary `json:"errors,omitempty" codec:"er,omitempty"`
+
+	// Shards contains the status of each of the shards of a sharded DAG.
+	// It is only set when requesting the status of the MetaPin.
+	Shards []GlobalPinInfo `json:"shards,omitempty" codec:"sh,omitempty"`
+}
+
+// PinErrorSummary describes an error reported by one or several peers for
+// the same pin.
+type PinErrorSummary struct {
+	Message string `json:"message" codec:"m,omitempty"`
+	// Peers contains the peers reporting the error (as in
+	// GlobalPinInfo.PeerMap keys).
+	Peers []string `json:"peers" codec:"p,omitempty"`
+	// Attempts is the sum of the attempt counts of these peers.
+	Attempts int `json:"attempts" codec:"a,omitempty"`
+	// LastAttempt is the most recent timestamp among these peers.
+	LastAttempt time.Time `json:"last_attempt" codec:"t,omitempty"`
+}
+
+// Count returns the number of peers reporting the error.
+func (pes PinErrorSummary) Count() int {
+	return len(pes.Peers)
 }
 
 // String returns the string representation of a GlobalPinInfo.
@@ -379,13 +463,73 @@ func (gpi *GlobalPinInfo) Add(pi PinInfo) {
 		gpi.Origins = pi.Origins
 		gpi.Created = pi.Created
 		gpi.Metadata = pi.Metadata
+		gpi.Tags = pi.Tags
+		gpi.Namespace = pi.Namespace
 	}
 
 	if gpi.PeerMap == nil {
 		gpi.PeerMap = make(map[string]PinInfoShort)
 	}
 
-	gpi.PeerMap[pi.Peer.String()] = pi.PinInfoShort
+	pid := pi.Peer.String()
+	if prev, ok := gpi.PeerMap[pid]; ok && prev.Error != "" {
+		gpi.removeError(pid, prev)
+	}
+	gpi.PeerMap[pid] = pi.PinInfoShort
+	if pi.Error != "" {
+		gpi.addError(pid, pi.PinInfoShort)
+	}
+}
+
+func (gpi *GlobalPinInfo) addError(pid string, pis PinInfoShort) {
+	i := 0
+	for ; i < len(gpi.Errors); i++ {
+		if gpi.Errors[i].Message == pis.Error {
+			break
+		}
+	}
+	if i == len(gpi.Errors) {
+		gpi.Errors = append(gpi.Errors, PinErrorSummary{Message: pis.Error})
+	}
+
+	es := &gpi.Errors[i]
+	es.Peers = append(es.Peers, pid)
+	es.Attempts += pis.AttemptCount
+	if pis.TS.After(es.LastAttempt) {
+		es.LastAttempt = pis.TS
+	}
+
+	// keep the most frequent errors first.
+	for ; i > 0 && gpi.Errors[i].Count() > gpi.Errors[i-1].Count(); i-- {
+		gpi.Errors[i], gpi.Errors[i-1] = gpi.Errors[i-1], gpi.Errors[i]
+	}
+}
+
+// removeError undoes addError for a peer whose status is being replaced.
+// LastAttempt is not recomputed.
+func (gpi *GlobalPinInfo) removeError(pid string, pis PinInfoShort) {
+	for i := range gpi.Errors {
+		es := &gpi.Errors[i]
+		if es.Message != pis.Error {
+			continue
+		}
+		for j, p := range es.Peers {
+			if p == pid {
+				es.Peers = append(es.Peers[:j], es.Peers[j+1:]...)
+				es.Attempts -= pis.AttemptCount
+				break
+			}
+		}
+		if len(es.Peers) == 0 {
+			gpi.Errors = append(gpi.Errors[:i], gpi.Errors[i+1:]...)
+			return
+		}
+		// keep the most frequent errors first.
+		for ; i+1 < len(gpi.Errors) && gpi.Errors[i].Count() < gpi.Errors[i+1].Count(); i++ {
+			gpi.Errors[i], gpi.Errors[i+1] = gpi.Errors[i+1], gpi.Errors[i]
+		}
+		return
+	}
 }
 
 // Defined returns if the object is not empty.
@@ -404,6 +548,39 @@ func (gpi GlobalPinInfo) Match(filter TrackerStatus) bool {
 	return false
 }
 
+// PinProgress describes the progress of a pin operation in IPFS. IPFS only
+// reports the number of blocks fetched so far.
+type PinProgress struct {
+	Blocks       int       `json:"blocks" codec:"b,omitempty"`
+	Started      time.Time `json:"started" codec:"s,omitempty"`
+	LastProgress time.Time `json:"last_progress" codec:"l,omitempty"`
+}
+
+// Defined returns true if this is not a zero-valued PinProgress.
+func (pp PinProgress) Defined() bool {
+	return !pp.Started.IsZero()
+}
+
+// ErrorReason values classify the errors of pin operations.
+const (
+	// The operation took too long.
+	ErrorReasonTimeout = "timeout"
+	// The IPFS daemon could not be reached.
+	ErrorReasonIPFSUnavailable = "ipfs_unavailable"
+	// The IPFS daemon returned an error.
+	ErrorReasonIPFSError = "ipfs_error"
+	// The operation could not be queued.
+	ErrorReasonQueueFull = "queue_full"
+	// The operation panicked.
+	ErrorReasonPanic = "panic"
+	// Blocks of the pin were found missing in IPFS and could not be
+	// recovered.
+	ErrorReasonCorrupt = "corrupt"
+	// The pin was canceled and it is not pinned until it is pinned
+	// again.
+	ErrorReasonCanceled = "canceled"
+)
+
 // PinInfoShort is a subset of PinInfo which is embedded in GlobalPinInfo
 // objects and does not carry redundant information as PinInfo would.
 type PinInfoShort struct {
@@ -415,6 +592,17 @@ type PinInfoShort struct {
 	Error         string        `json:"error" codec:"e,omitempty"`
 	AttemptCount  int           `json:"attempt_count" codec:"a,omitempty"`
 	PriorityPin   bool          `json:"priority_pin" codec:"y,omitempty"`
+	// ErrorReason classifies the last error (see the ErrorReason*
+	// values).
+	ErrorReason string `json:"error_reason,omitempty" codec:"er,omitempty"`
+	// NextRetry is the time after which an item in error is retried
+	// automatically.
+	NextRetry time.Time `json:"next_retry,omitempty" codec:"nr,omitempty"`
+	// QueuePosition is the position of a queued item in the queue of
+	// operations of the peer, starting at 1.
+	QueuePosition int `json:"queue_position,omitempty" codec:"qp,omitempty"`
+	// Progress is the progress of an item being pinned, when known.
+	Progress *PinProgress `json:"progress,omitempty" codec:"pr,omitempty"`
 }
 
 // String provides a string representation of PinInfoShort.
@@ -427,6 +615,9 @@ func (pis PinInfoShort) String() string {
 	fmt.Fprintf(&b, "error: %s\n", pis.Error)
 	fmt.Fprintf(&b, "attemptCount: %d\n", pis.AttemptCount)
 	fmt.Fprintf(&b, "priority: %t\n", pis.PriorityPin)
+	fmt.Fprintf(&b, "errorReason: %s\n", pis.ErrorReason)
+	fmt.Fprintf(&b, "nextRetry: %s\n", pis.NextRetry)
+	fmt.Fprintf(&b, "queuePosition: %d\n", pis.QueuePosition)
 	return b.String()
 }
 
@@ -440,6 +631,8 @@ type PinInfo struct {
 	Origins     []Multiaddr       `json:"origins" codec:"g,omitempty"`
 	Created     time.Time         `json:"created" codec:"t,omitempty"`
 	Metadata    map[string]string `json:"metadata" codec:"md,omitempty"`
+	Tags        []string          `json:"tags,omitempty" codec:"tg,omitempty"`
+	Namespace   string            `json:"namespace,omitempty" codec:"ns,omitempty"`
 
 	PinInfoShort
 }
@@ -559,13 +752,307 @@ type ID struct {
 	Error                 string      `json:"error" codec:"e,omitempty"`
 	IPFS                  IPFSID      `json:"ipfs,omitempty" codec:"ip,omitempty"`
 	Peername              string      `json:"peername" codec:"pn,omitempty"`
+	// CatchUp is set while the peer is catching up with the shared state.
+	CatchUp *CatchUpProgress `json:"catch_up,omitempty" codec:"cu,omitempty"`
+	// ConsensusLag is the number of known log entries that the peer has
+	// not applied to its state yet.
+	ConsensusLag uint64 `json:"consensus_lag" codec:"cl,omitempty"`
+	// RTT is the round-trip time to the peer, as measured by the peer
+	// that answered a peers listing. It is zero for that peer.
+	RTT time.Duration `json:"rtt,omitempty" codec:"rt,omitempty"`
+	// Consensus describes the consensus layer of the peer.
+	Consensus *ConsensusStats `json:"consensus,omitempty" codec:"cs,omitempty"`
+	// DatastoreSize is the disk space used by the datastore of the
+	// peer, in bytes. It is zero when the datastore cannot tell.
+	DatastoreSize uint64 `json:"datastore_size,omitempty" codec:"ds,omitempty"`
 	//PublicKey          crypto.PubKey
 }
 
+// CatchUpProgress describes how far the consensus layer of a peer is from
+// having applied all the known updates to the shared state.
+type CatchUpProgress struct {
+	Syncing      bool      `json:"syncing" codec:"s,omitempty"`
+	AppliedIndex uint64    `json:"applied_index" codec:"a,omitempty"`
+	LastIndex    uint64    `json:"last_index" codec:"l,omitempty"`
+	Started      time.Time `json:"started" codec:"t,omitempty"`
+}
+
+// Roles of a peer in the consensus layer.
+const (
+	ConsensusRoleLeader    = "leader"
+	ConsensusRoleFollower  = "follower"
+	ConsensusRoleCandidate = "candidate"
+	// ConsensusRolePeer is the role of every peer when the consensus
+	// has no leader (crdt).
+	ConsensusRolePeer = "peer"
+)
+
+// ConsensusStats describes the state of the consensus layer of a peer.
+type ConsensusStats struct {
+	Role string `json:"role" codec:"r,omitempty"`
+	// Term is the current Raft term.
+	Term uint64 `json:"term" codec:"t,omitempty"`
+	// AppliedIndex is the index of the last Raft log entry applied to
+	// the state, or the height of the Merkle-DAG with CRDT.
+	AppliedIndex uint64 `json:"applied_index" codec:"a,omitempty"`
+	// SyncLag is the number of updates this peer has received but not
+	// applied yet: Raft log entries or CRDT DAG nodes.
+	SyncLag      uint64    `json:"sync_lag" codec:"sl,omitempty"`
+	Snapshots    int       `json:"snapshots" codec:"s,omitempty"`
+	LastSnapshot time.Time `json:"last_snapshot" codec:"ls,omitempty"`
+	// StorageSize is the disk space, in bytes, used by the consensus
+	// outside of the peer datastore (Raft log and snapshots).
+	StorageSize uint64 `json:"storage_size" codec:"ss,omitempty"`
+}
+
+// RollbackInfo describes the result of rolling back the shared state to a
+// given pinset.
+type RollbackInfo struct {
+	Peer peer.ID `json:"peer" codec:"p,omitempty"`
+	// AuditSnapshot is the path, on Peer, of the export of the state
+	// as it was before the rollback.
+	AuditSnapshot string    `json:"audit_snapshot" codec:"a,omitempty"`
+	Pins          int       `json:"pins" codec:"n,omitempty"`
+	Timestamp     time.Time `json:"timestamp" codec:"t,omitempty"`
+}
+
+// ConsensusLogEntry describes an operation committed to the consensus log.
+// Operations that were batched together share the same Index.
+type ConsensusLogEntry struct {
+	Index     uint64    `json:"index" codec:"i,omitempty"`
+	Type      string    `json:"type" codec:"y,omitempty"`
+	Cid       Cid       `json:"cid" codec:"c,omitempty"`
+	Origin    peer.ID   `json:"origin,omitempty" codec:"o,omitempty"`
+	Timestamp time.Time `json:"timestamp" codec:"t,omitempty"`
+	// Pins is the size of the pinset that a rollback restored.
+	Pins int `json:"pins,omitempty" codec:"p,omitempty"`
+}
+
+// DeadLetter describes an operation that was committed to the consensus log
+// but that a peer failed to apply to its shared state.
+type DeadLetter struct {
+	ID        string    `json:"id" codec:"i,omitempty"`
+	Type      string    `json:"type" codec:"y,omitempty"`
+	Pin       Pin       `json:"pin" codec:"p,omitempty"`
+	Origin    peer.ID   `json:"origin,omitempty" codec:"o,omitempty"`
+	Error     string    `json:"error" codec:"e,omitempty"`
+	Timestamp time.Time `json:"timestamp" codec:"t,omitempty"`
+}
+
+// Types of ConsensusEvent.
+const (
+	ConsensusEventPin     = "pin"
+	ConsensusEventUnpin   = "unpin"
+	ConsensusEventPeerAdd = "peer_add"
+	ConsensusEventPeerRm  = "peer_rm"
+)
+
+// ConsensusEvent describes a change applied to the shared state by the
+// consensus component: a pin added or removed (Pin is set) or a peer added
+// to or removed from the consensus peerset (Peer is set).
+type ConsensusEvent struct {
+	Type      string    `json:"type" codec:"y"`
+	Pin       Pin       `json:"pin,omitempty" codec:"p,omitempty"`
+	Peer      peer.ID   `json:"peer,omitempty" codec:"i,omitempty"`
+	Timestamp time.Time `json:"timestamp" codec:"t,omitempty"`
+}
+
+// Types of ClusterEvent.
+const (
+	ClusterEventPin            = "pin"
+	ClusterEventUnpin          = "unpin"
+	ClusterEventPeerJoined     = "peer_joined"
+	ClusterEventPeerLeft       = "peer_left"
+	ClusterEventLeaderChanged  = "leader_changed"
+	ClusterEventConfigReloaded = "config_reloaded"
+	ClusterEventStatus         = "status"
+	ClusterEventAlert          = "alert"
+)
+
+// ClusterEvent is a significant event in the life of the cluster, as
+// recorded by Peer. Events are numbered by the recording peer in the order
+// they happened. Cid and Name are set for pin and status events and Subject
+// for events about a peer (the new leader, the peer which joined or left, or
+// the peer an alert is about). Status events report the outcome of a pin or
+// unpin operation on the recording peer: the new Status and, on errors, the
+// error in Message. Alert events carry the name of the expired metric in
+// Message.
+type ClusterEvent struct {
+	ID        uint64        `json:"id" codec:"n,omitempty"`
+	Type      string        `json:"type" codec:"y"`
+	Peer      peer.ID       `json:"peer" codec:"p,omitempty"`
+	Cid       Cid           `json:"cid,omitempty" codec:"c,omitempty"`
+	Name      string        `json:"name,omitempty" codec:"a,omitempty"`
+	Subject   peer.ID       `json:"subject,omitempty" codec:"s,omitempty"`
+	Status    TrackerStatus `json:"status,omitempty" codec:"st,omitempty"`
+	Message   string        `json:"message,omitempty" codec:"m,omitempty"`
+	Timestamp time.Time     `json:"timestamp" codec:"t,omitempty"`
+}
+
+// ClusterEventQuery selects events from the cluster event history: those
+// recorded at or after Since, with an ID greater than After and one of the
+// given Types (any when empty). At most Limit events are returned (all when
+// 0), oldest first.
+type ClusterEventQuery struct {
+	Since time.Time `json:"since" codec:"s,omitempty"`
+	After uint64    `json:"after" codec:"a,omitempty"`
+	Types []string  `json:"types" codec:"y,omitempty"`
+	Limit int       `json:"limit" codec:"l,omitempty"`
+}
+
+// Match returns whether the event is selected by the query.
+func (q ClusterEventQuery) Match(ev ClusterEvent) bool {
+	if ev.ID <= q.After {
+		return false
+	}
+	if !q.Since.IsZero() && ev.Timestamp.Before(q.Since) {
+		return false
+	}
+	if len(q.Types) == 0 {
+		return true
+	}
+	for _, t := range q.Types {
+		if t == ev.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// ToQuery returns the query as URL query parameters.
+func (q ClusterEventQuery) ToQuery() url.Values {
+	v := url.Values{}
+	if !q.Since.IsZero() {
+		v.Set("since", q.Since.Format(time.RFC3339Nano))
+	}
+	if q.After > 0 {
+		v.Set("after", strconv.FormatUint(q.After, 10))
+	}
+	if len(q.Types) > 0 {
+		v.Set("type", strings.Join(q.Types, ","))
+	}
+	if q.Limit > 0 {
+		v.Set("limit", strconv.Itoa(q.Limit))
+	}
+	return v
+}
+
+// ClusterEventQueryFromQuery is the inverse of ClusterEventQuery.ToQuery().
+func ClusterEventQueryFromQuery(v url.Values) (ClusterEventQuery, error) {
+	var q ClusterEventQuery
+	if s := v.Get("since"); s != "" {
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return q, fmt.Errorf("parameter since is invalid: %w", err)
+		}
+		q.Since = t
+	}
+	if s := v.Get("after"); s != "" {
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return q, fmt.Errorf("parameter after is invalid: %w", err)
+		}
+		q.After = n
+	}
+	if s := v.Get("type"); s != "" {
+		q.Types = strings.Split(s, ",")
+	}
+	if err := parseIntParam(v, "limit", &q.Limit); err != nil {
+		return q, err
+	}
+	if q.Limit < 0 {
+		return q, errors.New("parameter limit is invalid")
+	}
+	return q, nil
+}
+
+// QuorumStatus describes whether the consensus can commit operations: how
+// many voters are reachable out of those needed, and how long the last
+// commit took.
+type QuorumStatus struct {
+	Leader          peer.ID       `json:"leader,omitempty" codec:"l,omitempty"`
+	Voters          []peer.ID     `json:"voters" codec:"v,omitempty"`
+	ReachableVoters []peer.ID     `json:"reachable_voters" codec:"r,omitempty"`
+	Quorum          int           `json:"quorum" codec:"q,omitempty"`
+	HasQuorum       bool          `json:"has_quorum" codec:"h,omitempty"`
+	CommitLatency   time.Duration `json:"commit_latency" codec:"c,omitempty"`
+}
+
+// ConsensusLogQuery selects a page of consensus log entries: up to Limit
+// entries committed before the Before index (or the latest ones when 0).
+type ConsensusLogQuery struct {
+	Before uint64 `json:"before" codec:"b,omitempty"`
+	Limit  int    `json:"limit" codec:"l,omitempty"`
+}
+
+// ObservedPin is a pin that was made directly on the IPFS daemon of Peer
+// (through the proxy in audit mode) and that is not part of the cluster
+// shared state.
+type ObservedPin struct {
+	Cid       Cid       `json:"cid" codec:"c"`
+	Path      string    `json:"path,omitempty" codec:"pa,omitempty"`
+	Mode      PinMode   `json:"mode" codec:"o,omitempty"`
+	Peer      peer.ID   `json:"peer" codec:"p,omitempty"`
+	Timestamp time.Time `json:"timestamp" codec:"t,omitempty"`
+}
+
+// AllocationExplanation describes how a CID would be allocated with the
+// latest metrics known to a peer.
+type AllocationExplanation struct {
+	Cid                  Cid       `json:"cid" codec:"c"`
+	ReplicationFactorMin int       `json:"replication_factor_min" codec:"rn,omitempty"`
+	ReplicationFactorMax int       `json:"replication_factor_max" codec:"rx,omitempty"`
+	StorageClass         string    `json:"storage_class,omitempty" codec:"sc,omitempty"`
+	AllocateBy           []string  `json:"allocate_by" codec:"b,omitempty"`
+	Allocations          []peer.ID `json:"allocations" codec:"a,omitempty"`
+	// Error is set when the CID cannot be allocated.
+	Error string                      `json:"error,omitempty" codec:"e,omitempty"`
+	Peers []PeerAllocationExplanation `json:"peers" codec:"p,omitempty"`
+}
+
+// Peer allocation classes used in PeerAllocationExplanation.
+const (
+	AllocationClassCurrent   = "current"
+	AllocationClassPriority  = "priority"
+	AllocationClassCandidate = "candidate"
+	AllocationClassExcluded  = "excluded"
+)
+
+// PeerAllocationExplanation describes how a peer was considered when
+// allocating a CID. Rank is the position (starting at 1) of the peer in the
+// list sorted by the allocator, or 0 when the allocator did not rank it.
+type PeerAllocationExplanation struct {
+	Peer    peer.ID  `json:"peer" codec:"p"`
+	Class   string   `json:"class" codec:"c,omitempty"`
+	Metrics []Metric `json:"metrics" codec:"m,omitempty"`
+	Rank    int      `json:"rank" codec:"r,omitempty"`
+	Chosen  bool     `json:"chosen" codec:"h,omitempty"`
+	Reason  string   `json:"reason" codec:"s,omitempty"`
+}
+
+// ArchivedPeer records a peer that was removed from the cluster, so that
+// its ID can still be resolved to a name afterwards.
+type ArchivedPeer struct {
+	ID       peer.ID   `json:"id" codec:"i"`
+	Peername string    `json:"peername" codec:"n,omitempty"`
+	Removed  time.Time `json:"removed" codec:"r,omitempty"`
+}
+
+// APIEndpoint describes the REST API endpoints advertised by a cluster peer.
+// Public endpoints accept traffic from anyone, while the rest are meant for
+// trusted clients only.
+type APIEndpoint struct {
+	Peer      peer.ID     `json:"peer" codec:"p"`
+	Peername  string      `json:"peername" codec:"n,omitempty"`
+	Addresses []Multiaddr `json:"addresses" codec:"a,omitempty"`
+	Public    bool        `json:"public" codec:"u,omitempty"`
+}
+
 // IPFSID is used to store information about the underlying IPFS daemon
 type IPFSID struct {
 	ID        peer.ID     `json:"id,omitempty" codec:"i,omitempty"`
 	Addresses []Multiaddr `json:"addresses" codec:"a,omitempty"`
+	Version   string      `json:"version,omitempty" codec:"v,omitempty"`
 	Error     string      `json:"error" codec:"e,omitempty"`
 }
 
@@ -738,6 +1225,59 @@ func (pm *PinMode) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// PinPriority is a PinOption that indicates how urgently an item should be
+// pinned. The peers pin the items with a higher priority first.
+type PinPriority int
+
+// PinPriority values
+const (
+	PinPriorityLow    PinPriority = -1
+	PinPriorityNormal PinPriority = 0
+	PinPriorityHigh   PinPriority = 1
+)
+
+// PinPriorityFromString converts a string to PinPriority.
+func PinPriorityFromString(s string) (PinPriority, error) {
+	switch s {
+	case "normal", "":
+		return PinPriorityNormal, nil
+	case "low":
+		return PinPriorityLow, nil
+	case "high":
+		return PinPriorityHigh, nil
+	default:
+		return PinPriorityNormal, fmt.Errorf("unknown pin priority: %s", s)
+	}
+}
+
+// String returns a human-readable value for PinPriority.
+func (pp PinPriority) String() string {
+	switch {
+	case pp < PinPriorityNormal:
+		return "low"
+	case pp > PinPriorityNormal:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+// MarshalJSON converts the PinPriority into a readable string in JSON.
+func (pp PinPriority) MarshalJSON() ([]byte, error) {
+	return json.Marshal(pp.String())
+}
+
+// UnmarshalJSON takes a JSON value and parses it into PinPriority.
+func (pp *PinPriority) UnmarshalJSON(b []byte) error {
+	var s string
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+	*pp, err = PinPriorityFromString(s)
+	return err
+}
+
 // ToPinDepth converts the Mode to Depth.
 func (pm PinMode) ToPinDepth() PinDepth {
 	switch pm {
@@ -763,7 +1303,70 @@ type PinOptions struct {
 	Metadata             map[string]string `json:"metadata" codec:"m,omitempty"`
 	PinUpdate            Cid               `json:"pin_update,omitempty" codec:"pu,omitempty"`
 	Origins              []Multiaddr       `json:"origins" codec:"g,omitempty"`
-}
+	StorageClass         string            `json:"storage_class,omitempty" codec:"sc,omitempty"`
+	Tags                 []string          `json:"tags,omitempty" codec:"tg,omitempty"`
+	Namespace            string            `json:"namespace,omitempty" codec:"ns,omitempty"`
+	Priority             PinPriority       `json:"priority,omitempty" codec:"pr,omitempty"`
+	// Owners are the API users holding a reference to the pin. A pin
+	// with owners is only removed when the last of them unpins it.
+	Owners []string `json:"owners,omitempty" codec:"ow,omitempty"`
+	// ReplaceUpdated asks for the PinUpdate pin to be unpinned once the
+	// new pin is pinned. It is not stored with the pin.
+	ReplaceUpdated bool `json:"replace_updated,omitempty" codec:"ru,omitempty"`
+	// Requester identifies who requested the last operation on the pin.
+	// It is set by the cluster peer which received the request.
+	Requester PinRequester `json:"requester,omitempty" codec:"rq,omitempty"`
+}
+
+// PinRequester identifies who requested an operation on a pin: the API user
+// that authenticated the request (if any), the address it came from, and the
+// cluster peer which received it. Operations that a peer performs on its own
+// (i.e. when pins expire) only have Peer set.
+type PinRequester struct {
+	User    string  `json:"user,omitempty" codec:"u,omitempty"`
+	Address string  `json:"address,omitempty" codec:"a,omitempty"`
+	Peer    peer.ID `json:"peer,omitempty" codec:"p,omitempty"`
+}
+
+// String returns a short description of the requester.
+func (r PinRequester) String() string {
+	var parts []string
+	if r.User != "" {
+		parts = append(parts, "user: "+r.User)
+	}
+	if r.Address != "" {
+		parts = append(parts, "address: "+r.Address)
+	}
+	if r.Peer != "" {
+		parts = append(parts, "peer: "+r.Peer.String())
+	}
+	if len(parts) == 0 {
+		return "unknown"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ErrRejected is wrapped by the errors of the consensus components when they
+// refuse to commit an operation and retrying it is unlikely to help.
+var ErrRejected = errors.New("rejected")
+
+// ErrUncertain is wrapped by the errors of the consensus components when an
+// operation could not be confirmed but may still be committed, i.e. after a
+// timeout or a leader change. Any other error means that the operation was
+// not committed.
+var ErrUncertain = errors.New("the operation may still be committed")
+
+// ErrPinTooLarge is returned when the estimated size of a pin exceeds the
+// size limit that applies to it.
+var ErrPinTooLarge = errors.New("pin too large")
+
+// ErrHandoffNotFound is returned when a handoff is not known to the peer
+// asked about it.
+var ErrHandoffNotFound = errors.New("handoff not found")
+
+// ErrPinNameConflict is returned when pin names must be unique and the name
+// of a pin is already used in its namespace.
+var ErrPinNameConflict = errors.New("pin name conflict")
 
 // Equals returns true if two PinOption objects are equivalent. po and po2 may
 // be nil.
@@ -788,6 +1391,18 @@ func (po PinOptions) Equals(po2 PinOptions) bool {
 		return false
 	}
 
+	if po.StorageClass != po2.StorageClass {
+		return false
+	}
+
+	if po.Namespace != po2.Namespace {
+		return false
+	}
+
+	if po.Priority != po2.Priority {
+		return false
+	}
+
 	lenAllocs1 := len(po.UserAllocations)
 	lenAllocs2 := len(po2.UserAllocations)
 	if lenAllocs1 != lenAllocs2 {
@@ -814,6 +1429,14 @@ func (po PinOptions) Equals(po2 PinOptions) bool {
 		}
 	}
 
+	if strings.Join(NormalizeTags(po.Tags), ",") != strings.Join(NormalizeTags(po2.Tags), ",") {
+		return false
+	}
+
+	if strings.Join(NormalizeTags(po.Owners), ",") != strings.Join(NormalizeTags(po2.Owners), ",") {
+		return false
+	}
+
 	// deliberately ignore Update
 
 	lenOrigins1 := len(po.Origins)
@@ -862,6 +1485,9 @@ func (po PinOptions) ToQuery() (string, error) {
 	if po.PinUpdate.Defined() {
 		q.Set("pin-update", po.PinUpdate.String())
 	}
+	if po.ReplaceUpdated {
+		q.Set("replace-updated", "true")
+	}
 
 	if len(po.Origins) > 0 {
 		origins := make([]string, len(po.Origins))
@@ -871,6 +1497,22 @@ func (po PinOptions) ToQuery() (string, error) {
 		q.Set("origins", strings.Join(origins, ","))
 	}
 
+	if po.StorageClass != "" {
+		q.Set("storage-class", po.StorageClass)
+	}
+
+	if len(po.Tags) > 0 {
+		q.Set("tags", strings.Join(po.Tags, ","))
+	}
+
+	if po.Namespace != "" {
+		q.Set("namespace", po.Namespace)
+	}
+
+	if po.Priority != PinPriorityNormal {
+		q.Set("priority", po.Priority.String())
+	}
+
 	return q.Encode(), nil
 }
 
@@ -880,13 +1522,27 @@ func (po *PinOptions) FromQuery(q url.Values) error {
 
 	po.Mode = PinModeFromString(q.Get("mode"))
 
+	po.StorageClass = q.Get("storage-class")
+
+	if tags := q.Get("tags"); tags != "" {
+		po.Tags = NormalizeTags(strings.Split(tags, ","))
+	}
+
+	po.Namespace = q.Get("namespace")
+
+	priority, err := PinPriorityFromString(q.Get("priority"))
+	if err != nil {
+		return err
+	}
+	po.Priority = priority
+
 	rplStr := q.Get("replication")
 	if rplStr != "" { // override
 		q.Set("replication-min", rplStr)
 		q.Set("replication-max", rplStr)
 	}
 
-	err := parseIntParam(q, "replication-min", &po.ReplicationFactorMin)
+	err = parseIntParam(q, "replication-min", &po.ReplicationFactorMin)
 	if err != nil {
 		return err
 	}
@@ -947,6 +1603,14 @@ func (po *PinOptions) FromQuery(q url.Values) error {
 		po.PinUpdate = updateCid
 	}
 
+	if v := q.Get("replace-updated"); v != "" {
+		replace, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("error decoding replace-updated parameter: %w", err)
+		}
+		po.ReplaceUpdated = replace
+	}
+
 	originsStr := q.Get("origins")
 	if originsStr != "" {
 		origins := strings.Split(originsStr, ",")
@@ -969,6 +1633,315 @@ func (po *PinOptions) FromQuery(q url.Values) error {
 	return nil
 }
 
+// NormalizeTags returns the given tags trimmed, sorted and without empty
+// or duplicated entries.
+func NormalizeTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	seen := make(map[string]struct{}, len(tags))
+	var res []string
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if _, ok := seen[t]; ok || t == "" {
+			continue
+		}
+		seen[t] = struct{}{}
+		res = append(res, t)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// PinFilter selects pins by their name, tags and metadata. The zero value
+// matches every pin.
+type PinFilter struct {
+	// Name matches pins whose name contains it (case-insensitive).
+	Name string `json:"name,omitempty" codec:"n,omitempty"`
+	// Tags matches pins which have all of them.
+	Tags []string `json:"tags,omitempty" codec:"t,omitempty"`
+	// Metadata matches pins which have all these keys and values.
+	Metadata map[string]string `json:"metadata,omitempty" codec:"m,omitempty"`
+}
+
+// IsEmpty returns true when the filter matches every pin.
+func (pf PinFilter) IsEmpty() bool {
+	return pf.Name == "" && len(pf.Tags) == 0 && len(pf.Metadata) == 0
+}
+
+// Match returns true if a pin with the given name, tags and metadata is
+// selected by the filter.
+func (pf PinFilter) Match(name string, tags []string, metadata map[string]string) bool {
+	if pf.Name != "" && !strings.Contains(strings.ToLower(name), strings.ToLower(pf.Name)) {
+		return false
+	}
+
+	for _, t := range pf.Tags {
+		found := false
+		for _, t2 := range tags {
+			if t == t2 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for k, v := range pf.Metadata {
+		v2, ok := metadata[k]
+		if !ok || v != v2 {
+			return false
+		}
+	}
+	return true
+}
+
+// ToQuery adds the filter to the given query arguments, using the same
+// parameters as PinOptions.
+func (pf PinFilter) ToQuery(q url.Values) {
+	if pf.Name != "" {
+		q.Set("name", pf.Name)
+	}
+	if len(pf.Tags) > 0 {
+		q.Set("tags", strings.Join(pf.Tags, ","))
+	}
+	for k, v := range pf.Metadata {
+		if k == "" {
+			continue
+		}
+		q.Set(pinOptionsMetaPrefix+k, v)
+	}
+}
+
+// PinFilterFromQuery is the inverse of PinFilter.ToQuery().
+func PinFilterFromQuery(q url.Values) PinFilter {
+	var pf PinFilter
+	pf.Name = q.Get("name")
+	if tags := q.Get("tags"); tags != "" {
+		pf.Tags = NormalizeTags(strings.Split(tags, ","))
+	}
+	for k := range q {
+		metaKey := strings.TrimPrefix(k, pinOptionsMetaPrefix)
+		if metaKey == k || metaKey == "" {
+			continue
+		}
+		if pf.Metadata == nil {
+			pf.Metadata = make(map[string]string)
+		}
+		pf.Metadata[metaKey] = q.Get(k)
+	}
+	return pf
+}
+
+// PinListOptions select the pins listed from the shared state and allow
+// paging through them.
+type PinListOptions struct {
+	// Type limits the results to pins of the given types (a PinType
+	// filter). 0 selects all types.
+	Type PinType `json:"type" codec:"y,omitempty"`
+	// PinFilter selects pins by name, tags and metadata.
+	PinFilter PinFilter `json:"pin_filter" codec:"p,omitempty"`
+	// NamePrefix matches pins whose name starts with it.
+	NamePrefix string `json:"name_prefix" codec:"np,omitempty"`
+	// CidPrefix matches pins whose CID (as a string) starts with it.
+	CidPrefix string `json:"cid_prefix" codec:"cp,omitempty"`
+	// Namespace matches pins which belong to the given namespace.
+	Namespace string `json:"namespace" codec:"ns,omitempty"`
+	// ReplicationFactorMin and ReplicationFactorMax match pins with the
+	// given replication factors when not 0.
+	ReplicationFactorMin int `json:"replication_factor_min" codec:"rn,omitempty"`
+	ReplicationFactorMax int `json:"replication_factor_max" codec:"rx,omitempty"`
+	// Origin matches pins with an origin address for this IPFS peer.
+	Origin peer.ID `json:"origin,omitempty" codec:"o,omitempty"`
+	// CreatedAfter and CreatedBefore match pins whose timestamp is in
+	// the given range, when set.
+	CreatedAfter  time.Time `json:"created_after" codec:"ca,omitempty"`
+	CreatedBefore time.Time `json:"created_before" codec:"cb,omitempty"`
+	// Trashed only matches pins that have been moved to the trash.
+	Trashed bool `json:"trashed" codec:"t,omitempty"`
+	// Status matches pins by their status in the peer listing them.
+	Status TrackerStatus `json:"status" codec:"s,omitempty"`
+	// After only selects pins whose CID sorts after this one. It is the
+	// cursor to get the next page of results: the CID of the last pin
+	// of the previous one.
+	After Cid `json:"after" codec:"a,omitempty"`
+	// Limit is the maximum number of pins listed. When set, pins are
+	// sorted by CID. 0 means no limit.
+	Limit int `json:"limit" codec:"l,omitempty"`
+}
+
+// Match returns true if the pin is selected by the options. The Status
+// and Limit options are not considered.
+func (o PinListOptions) Match(p Pin) bool {
+	if o.Type != 0 && o.Type != AllType && o.Type&p.Type == 0 {
+		return false
+	}
+	if o.NamePrefix != "" && !strings.HasPrefix(p.Name, o.NamePrefix) {
+		return false
+	}
+	if (o.CidPrefix != "" || o.After.Defined()) && !o.matchCid(p.Cid.String()) {
+		return false
+	}
+	if o.Namespace != "" && p.Namespace != o.Namespace {
+		return false
+	}
+	if o.ReplicationFactorMin != 0 && p.ReplicationFactorMin != o.ReplicationFactorMin {
+		return false
+	}
+	if o.ReplicationFactorMax != 0 && p.ReplicationFactorMax != o.ReplicationFactorMax {
+		return false
+	}
+	if o.Origin != "" && !p.HasOrigin(o.Origin) {
+		return false
+	}
+	if !o.CreatedAfter.IsZero() && p.Timestamp.Before(o.CreatedAfter) {
+		return false
+	}
+	if !o.CreatedBefore.IsZero() && !p.Timestamp.Before(o.CreatedBefore) {
+		return false
+	}
+	if o.Trashed && !p.IsTrashed() {
+		return false
+	}
+	return o.PinFilter.Match(p.Name, p.Tags, p.Metadata)
+}
+
+func (o PinListOptions) matchCid(c string) bool {
+	if !strings.HasPrefix(c, o.CidPrefix) {
+		return false
+	}
+	return !o.After.Defined() || c > o.After.String()
+}
+
+// ToQuery returns the options as query arguments.
+func (o PinListOptions) ToQuery() url.Values {
+	q := url.Values{}
+	if o.Type == AllType {
+		q.Set("filter", AllType.String())
+	} else if o.Type != 0 {
+		var types []string
+		for _, t := range []PinType{DataType, MetaType, ClusterDAGType, ShardType} {
+			if o.Type&t > 0 {
+				types = append(types, t.String())
+			}
+		}
+		q.Set("filter", strings.Join(types, ","))
+	}
+	o.PinFilter.ToQuery(q)
+	if o.NamePrefix != "" {
+		q.Set("name-prefix", o.NamePrefix)
+	}
+	if o.CidPrefix != "" {
+		q.Set("cid-prefix", o.CidPrefix)
+	}
+	if o.Namespace != "" {
+		q.Set("namespace", o.Namespace)
+	}
+	if o.ReplicationFactorMin != 0 {
+		q.Set("replication-min", strconv.Itoa(o.ReplicationFactorMin))
+	}
+	if o.ReplicationFactorMax != 0 {
+		q.Set("replication-max", strconv.Itoa(o.ReplicationFactorMax))
+	}
+	if o.Origin != "" {
+		q.Set("origin", o.Origin.String())
+	}
+	if !o.CreatedAfter.IsZero() {
+		q.Set("created-after", o.CreatedAfter.Format(time.RFC3339))
+	}
+	if !o.CreatedBefore.IsZero() {
+		q.Set("created-before", o.CreatedBefore.Format(time.RFC3339))
+	}
+	if o.Trashed {
+		q.Set("trashed", "true")
+	}
+	if o.Status != TrackerStatusUndefined {
+		q.Set("status", o.Status.String())
+	}
+	if o.After.Defined() {
+		q.Set("after", o.After.String())
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	return q
+}
+
+// PinListOptionsFromQuery is the inverse of PinListOptions.ToQuery().
+func PinListOptionsFromQuery(q url.Values) (PinListOptions, error) {
+	o := PinListOptions{
+		PinFilter:  PinFilterFromQuery(q),
+		NamePrefix: q.Get("name-prefix"),
+		CidPrefix:  q.Get("cid-prefix"),
+		Namespace:  q.Get("namespace"),
+	}
+	if v := q.Get("filter"); v != "" {
+		for _, f := range strings.Split(v, ",") {
+			o.Type |= PinTypeFromString(f)
+		}
+		if o.Type == BadType {
+			return o, errors.New("invalid filter value")
+		}
+	}
+	if err := parseIntParam(q, "replication-min", &o.ReplicationFactorMin); err != nil {
+		return o, err
+	}
+	if err := parseIntParam(q, "replication-max", &o.ReplicationFactorMax); err != nil {
+		return o, err
+	}
+	if v := q.Get("origin"); v != "" {
+		pid, err := peer.Decode(v)
+		if err != nil {
+			return o, fmt.Errorf("parameter origin is invalid: %w", err)
+		}
+		o.Origin = pid
+	}
+	for _, param := range []struct {
+		name string
+		dst  *time.Time
+	}{
+		{"created-after", &o.CreatedAfter},
+		{"created-before", &o.CreatedBefore},
+	} {
+		if v := q.Get(param.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return o, fmt.Errorf("parameter %s is invalid: %w", param.name, err)
+			}
+			*param.dst = t
+		}
+	}
+	if v := q.Get("trashed"); v != "" {
+		trashed, err := strconv.ParseBool(v)
+		if err != nil {
+			return o, fmt.Errorf("parameter trashed is invalid: %w", err)
+		}
+		o.Trashed = trashed
+	}
+	if v := q.Get("status"); v != "" {
+		o.Status = TrackerStatusFromString(v)
+		if o.Status == TrackerStatusUndefined {
+			return o, errors.New("invalid status value")
+		}
+	}
+	if v := q.Get("after"); v != "" {
+		c, err := DecodeCid(v)
+		if err != nil {
+			return o, fmt.Errorf("parameter after is invalid: %w", err)
+		}
+		o.After = c
+	}
+	if err := parseIntParam(q, "limit", &o.Limit); err != nil {
+		return o, err
+	}
+	if o.Limit < 0 {
+		return o, errors.New("parameter limit is invalid")
+	}
+	return o, nil
+}
+
 // PinDepth indicates how deep a pin should be pinned, with
 // -1 meaning "to the bottom", or "recursive".
 type PinDepth int
@@ -1013,6 +1986,15 @@ type Pin struct {
 
 	// The time that the pin was submitted to the consensus layer.
 	Timestamp time.Time `json:"timestamp" codec:"i,omitempty"`
+
+	// The time that the pin was moved to the trash by an unpin. Trashed
+	// pins are kept pinned until the trash retention period is over
+	// and can be restored until then.
+	TrashedAt time.Time `json:"trashed_at,omitempty" codec:"tr,omitempty"`
+
+	// The time that the pin was canceled. Peers stop pinning canceled
+	// pins and do not retry them until they are pinned again.
+	CanceledAt time.Time `json:"canceled_at,omitempty" codec:"cx,omitempty"`
 }
 
 // String is a string representation of a Pin.
@@ -1044,6 +2026,14 @@ func (pp PinPath) Defined() bool {
 	return pp.Path != ""
 }
 
+// CancelPinRequest asks to cancel the pinning of an item and, optionally,
+// to unpin it.
+type CancelPinRequest struct {
+	Cid       Cid          `json:"cid" codec:"c"`
+	Unpin     bool         `json:"unpin,omitempty" codec:"u,omitempty"`
+	Requester PinRequester `json:"requester,omitempty" codec:"r,omitempty"`
+}
+
 // PinCid is a shortcut to create a Pin only with a Cid.  Default is for pin to
 // be recursive and the pin to be of DataType.
 func PinCid(c Cid) Pin {
@@ -1108,6 +2098,16 @@ func (pin Pin) ProtoMarshal() ([]byte, error) {
 		timestampProto = uint64(pin.Timestamp.Unix())
 	}
 
+	var trashedAtProto uint64
+	if pin.IsTrashed() {
+		trashedAtProto = uint64(pin.TrashedAt.Unix())
+	}
+
+	var canceledAtProto uint64
+	if pin.IsCanceled() {
+		canceledAtProto = uint64(pin.CanceledAt.Unix())
+	}
+
 	// Our metadata needs to always be seralized in exactly the same way,
 	// and that is why we use an array sorted by key and deprecated using
 	// a protobuf map.
@@ -1136,8 +2136,22 @@ func (pin Pin) ProtoMarshal() ([]byte, error) {
 		ExpireAt:  expireAtProto,
 		// Mode:                 pin.Mode,
 		// UserAllocations:      pin.UserAllocations,
-		Origins:        origins,
-		SortedMetadata: sortedMetadata,
+		Origins:          origins,
+		SortedMetadata:   sortedMetadata,
+		StorageClass:     pin.StorageClass,
+		Tags:             NormalizeTags(pin.Tags),
+		Namespace:        pin.Namespace,
+		Owners:           NormalizeTags(pin.Owners),
+		Priority:         int32(pin.Priority),
+		RequesterUser:    pin.Requester.User,
+		RequesterAddress: pin.Requester.Address,
+	}
+	if pin.Requester.Peer != "" {
+		bs, err := pin.Requester.Peer.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		opts.RequesterPeer = bs
 	}
 
 	pbPin := &pb.Pin{
@@ -1147,6 +2161,8 @@ func (pin Pin) ProtoMarshal() ([]byte, error) {
 		MaxDepth:    int32(pin.MaxDepth),
 		Options:     opts,
 		Timestamp:   timestampProto,
+		TrashedAt:   trashedAtProto,
+		CanceledAt:  canceledAtProto,
 	}
 	if ref := pin.Reference; ref != nil {
 		pbPin.Reference = ref.Bytes()
@@ -1196,11 +2212,33 @@ func (pin *Pin) ProtoUnmarshal(data []byte) error {
 		pin.Timestamp = time.Unix(int64(ts), 0)
 	}
 
+	if trashedAt := pbPin.GetTrashedAt(); trashedAt > 0 {
+		pin.TrashedAt = time.Unix(int64(trashedAt), 0)
+	}
+
+	if canceledAt := pbPin.GetCanceledAt(); canceledAt > 0 {
+		pin.CanceledAt = time.Unix(int64(canceledAt), 0)
+	}
+
 	opts := pbPin.GetOptions()
 	pin.ReplicationFactorMin = int(opts.GetReplicationFactorMin())
 	pin.ReplicationFactorMax = int(opts.GetReplicationFactorMax())
 	pin.Name = opts.GetName()
 	pin.ShardSize = opts.GetShardSize()
+	pin.StorageClass = opts.GetStorageClass()
+	pin.Tags = opts.GetTags()
+	pin.Namespace = opts.GetNamespace()
+	pin.Owners = opts.GetOwners()
+	pin.Priority = PinPriority(opts.GetPriority())
+	pin.Requester.User = opts.GetRequesterUser()
+	pin.Requester.Address = opts.GetRequesterAddress()
+	if pidb := opts.GetRequesterPeer(); len(pidb) > 0 {
+		pid, err := peer.IDFromBytes(pidb)
+		if err != nil {
+			return err
+		}
+		pin.Requester.Peer = pid
+	}
 
 	// pin.UserAllocations = opts.GetUserAllocations()
 	exp := opts.GetExpireAt()
@@ -1277,6 +2315,14 @@ func (pin Pin) Equals(pin2 Pin) bool {
 		return false
 	}
 
+	if pin.IsTrashed() != pin2.IsTrashed() {
+		return false
+	}
+
+	if pin.IsCanceled() != pin2.IsCanceled() {
+		return false
+	}
+
 	return pin.PinOptions.Equals(pin2.PinOptions)
 }
 
@@ -1295,6 +2341,41 @@ func (pin Pin) IsRemotePin(pid peer.ID) bool {
 	return true
 }
 
+// OriginPeers returns the IPFS peers of the origin addresses of the pin.
+// Addresses without a peer ID are skipped.
+func (pin Pin) OriginPeers() []peer.ID {
+	var pids []peer.ID
+	for _, o := range pin.Origins {
+		pinfo, err := peer.AddrInfoFromP2pAddr(o.Value())
+		if err != nil {
+			continue
+		}
+		pids = append(pids, pinfo.ID)
+	}
+	return pids
+}
+
+// HasOrigin returns whether one of the origin addresses of the pin belongs
+// to the given IPFS peer.
+func (pin Pin) HasOrigin(pid peer.ID) bool {
+	for _, o := range pin.OriginPeers() {
+		if o == pid {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTrashed returns whether the pin has been moved to the trash.
+func (pin Pin) IsTrashed() bool {
+	return !(pin.TrashedAt.IsZero() || pin.TrashedAt.Equal(unixZero))
+}
+
+// IsCanceled returns whether the pin has been canceled.
+func (pin Pin) IsCanceled() bool {
+	return !(pin.CanceledAt.IsZero() || pin.CanceledAt.Equal(unixZero))
+}
+
 // ExpiredAt returns whether the pin has expired at the given time.
 func (pin Pin) ExpiredAt(t time.Time) bool {
 	if pin.ExpireAt.IsZero() || pin.ExpireAt.Equal(unixZero) {
@@ -1428,6 +2509,71 @@ type IPFSRepoStat struct {
 	StorageMax uint64 `codec:"s, omitempty"`
 }
 
+// IPFSBlockStat wraps information about a block, as provided by
+// "block stat".
+type IPFSBlockStat struct {
+	Key  Cid `json:"Key" codec:"k,omitempty"`
+	Size int `json:"Size" codec:"s,omitempty"`
+}
+
+// IPFSDagStat wraps information about a DAG, as provided by "dag stat".
+type IPFSDagStat struct {
+	Size      uint64 `json:"size" codec:"s,omitempty"`
+	NumBlocks uint64 `json:"num_blocks" codec:"n,omitempty"`
+}
+
+// Types of PinAuditEntry.
+const (
+	PinAuditPin    = "pin"
+	PinAuditUnpin  = "unpin"
+	PinAuditCancel = "cancel"
+)
+
+// PinAuditEntry records a pin or unpin operation submitted to the consensus
+// layer by a cluster peer, and who requested it. Pins moved to the trash are
+// recorded as unpins and canceled pins as cancels. Owners are the owners
+// left after the operation.
+type PinAuditEntry struct {
+	Type      string       `json:"type" codec:"y"`
+	Cid       Cid          `json:"cid" codec:"c"`
+	Name      string       `json:"name,omitempty" codec:"n,omitempty"`
+	Owners    []string     `json:"owners,omitempty" codec:"o,omitempty"`
+	Requester PinRequester `json:"requester" codec:"r,omitempty"`
+	Timestamp time.Time    `json:"timestamp" codec:"t,omitempty"`
+}
+
+// PinSchedule is a pin which is committed at a later time, or repeatedly
+// following a cron expression. The path is resolved every time the schedule
+// runs, so recurring schedules can follow mutable (i.e. /ipns/) content.
+type PinSchedule struct {
+	ID string `json:"id" codec:"i"`
+	// Path is the CID or IPFS path to pin.
+	Path    string     `json:"path" codec:"pa"`
+	Options PinOptions `json:"options" codec:"o,omitempty"`
+	// NotBefore is the time before which the pin is not committed.
+	NotBefore time.Time `json:"not_before,omitempty" codec:"nb,omitempty"`
+	// Cron is a cron expression (minute, hour, day of month, month and
+	// day of week, in the time zone of the peers) for schedules which
+	// pin again on every occurrence. Schedules without it run once.
+	Cron    string    `json:"cron,omitempty" codec:"cr,omitempty"`
+	Peer    peer.ID   `json:"peer,omitempty" codec:"pe,omitempty"`
+	Created time.Time `json:"created" codec:"cd,omitempty"`
+	// Next is the time when the schedule runs next.
+	Next      time.Time `json:"next" codec:"nx,omitempty"`
+	LastRun   time.Time `json:"last_run,omitempty" codec:"lr,omitempty"`
+	LastCid   Cid       `json:"last_cid,omitempty" codec:"lc,omitempty"`
+	LastError string    `json:"last_error,omitempty" codec:"le,omitempty"`
+}
+
+// IPFSBlockVerification is used to request the verification of a random
+// sample of the blocks of a DAG pinned in IPFS, and to report its results.
+type IPFSBlockVerification struct {
+	Cid     Cid   `json:"cid" codec:"c"`
+	Sample  int   `json:"sample" codec:"s,omitempty"`
+	Checked int   `json:"checked" codec:"n,omitempty"`
+	Missing []Cid `json:"missing,omitempty" codec:"m,omitempty"`
+}
+
 // IPFSRepoGC represents the streaming response sent from repo gc API of IPFS.
 type IPFSRepoGC struct {
 	Key   Cid    `json:"key,omitempty" codec:"k,omitempty"`
@@ -1447,3 +2593,242 @@ type RepoGC struct {
 type GlobalRepoGC struct {
 	PeerMap map[string]RepoGC `json:"peer_map" codec:"pm,omitempty"`
 }
+
+// PinQueueInfo describes the queues of pin and unpin operations of a cluster
+// peer.
+type PinQueueInfo struct {
+	Peer     peer.ID `json:"peer" codec:"p,omitempty"`
+	Peername string  `json:"peername" codec:"pn,omitempty"`
+	// Queued operations.
+	PinDepth   int `json:"pin_depth" codec:"pd,omitempty"`
+	UnpinDepth int `json:"unpin_depth" codec:"ud,omitempty"`
+	// Timestamps of the oldest queued operations (zero when the queue is
+	// empty).
+	OldestPin   time.Time `json:"oldest_pin,omitempty" codec:"op,omitempty"`
+	OldestUnpin time.Time `json:"oldest_unpin,omitempty" codec:"ou,omitempty"`
+	// Operations being currently issued to IPFS.
+	Pinning   int `json:"pinning" codec:"pi,omitempty"`
+	Unpinning int `json:"unpinning" codec:"ui,omitempty"`
+	// Maximum number of operations issued to IPFS at the same time.
+	ConcurrentPins   int `json:"concurrent_pins" codec:"cp,omitempty"`
+	ConcurrentUnpins int `json:"concurrent_unpins" codec:"cu,omitempty"`
+	// Operations are paused because the IPFS daemon keeps failing.
+	Degraded bool   `json:"degraded,omitempty" codec:"d,omitempty"`
+	Error    string `json:"error,omitempty" codec:"e,omitempty"`
+}
+
+// GlobalPinQueueInfo contains the PinQueueInfo of every cluster peer.
+type GlobalPinQueueInfo struct {
+	PeerMap map[string]PinQueueInfo `json:"peer_map" codec:"pm,omitempty"`
+}
+
+// JobRequest asks to run a maintenance job on the given cluster peers, or on
+// all of them when none are given.
+type JobRequest struct {
+	Job   string    `json:"job" codec:"j"`
+	Peers []peer.ID `json:"peers,omitempty" codec:"p,omitempty"`
+}
+
+// JobResult is the outcome of running a maintenance job on a cluster peer.
+type JobResult struct {
+	Peer     peer.ID       `json:"peer" codec:"p,omitempty"`
+	Peername string        `json:"peername" codec:"pn,omitempty"`
+	Job      string        `json:"job" codec:"j,omitempty"`
+	Duration time.Duration `json:"duration" codec:"d,omitempty"`
+	Error    string        `json:"error,omitempty" codec:"e,omitempty"`
+}
+
+// GlobalJobResult contains the outcome of running a maintenance job on
+// several cluster peers.
+type GlobalJobResult struct {
+	Job     string               `json:"job" codec:"j,omitempty"`
+	PeerMap map[string]JobResult `json:"peer_map" codec:"pm,omitempty"`
+}
+
+// HandoffRequest asks a cluster to transfer a set of its pins to another
+// cluster: the target cluster pins them and they are only unpinned from the
+// source cluster once they are pinned in the target.
+type HandoffRequest struct {
+	// Target is the address of the REST API of the target cluster.
+	Target Multiaddr `json:"target" codec:"t"`
+	// Username and Password are the basic authentication credentials
+	// for the target API, if it needs them.
+	Username string `json:"username,omitempty" codec:"u,omitempty"`
+	Password string `json:"password,omitempty" codec:"pw,omitempty"`
+	// Pins selects the pins handed off. Only data pins are handed off.
+	Pins PinListOptions `json:"pins" codec:"p,omitempty"`
+	// Timeout is how long the target cluster may take to pin each item.
+	// 0 means no limit.
+	Timeout time.Duration `json:"timeout,omitempty" codec:"to,omitempty"`
+}
+
+// HandoffStatus is the status of a pin handoff.
+type HandoffStatus string
+
+// HandoffStatus values.
+const (
+	HandoffRunning  HandoffStatus = "running"
+	HandoffDone     HandoffStatus = "done"
+	HandoffFailed   HandoffStatus = "failed"
+	HandoffCanceled HandoffStatus = "canceled"
+)
+
+// HandoffJob tracks the progress of a pin handoff on the peer running it.
+type HandoffJob struct {
+	ID     string        `json:"id" codec:"i"`
+	Peer   peer.ID       `json:"peer" codec:"pe,omitempty"`
+	Target string        `json:"target" codec:"t,omitempty"`
+	Status HandoffStatus `json:"status" codec:"s,omitempty"`
+	// Total is the number of pins to hand off.
+	Total int `json:"total" codec:"n,omitempty"`
+	// Confirmed is the number of pins pinned in the target cluster.
+	Confirmed int `json:"confirmed" codec:"c,omitempty"`
+	// Unpinned is the number of confirmed pins that have been unpinned
+	// from this cluster.
+	Unpinned int `json:"unpinned" codec:"u,omitempty"`
+	// Errors holds the error for every pin that could not be handed off,
+	// by CID. Those pins are left in this cluster.
+	Errors   map[string]string `json:"errors,omitempty" codec:"e,omitempty"`
+	Error    string            `json:"error,omitempty" codec:"er,omitempty"`
+	Started  time.Time         `json:"started" codec:"st,omitempty"`
+	Finished time.Time         `json:"finished,omitempty" codec:"f,omitempty"`
+}
+
+// DedupStats estimates how much content is shared among the pins allocated to
+// a cluster peer. It is computed by listing the blocks of a sample of those
+// pins. Byte estimations are based on the average size of a sample of the
+// blocks.
+type DedupStats struct {
+	Peer     peer.ID `json:"peer" codec:"p,omitempty"`
+	Peername string  `json:"peername" codec:"pn,omitempty"`
+	// Number of pins allocated to the peer and number of pins sampled.
+	Pins        int `json:"pins" codec:"ps,omitempty"`
+	SampledPins int `json:"sampled_pins" codec:"sp,omitempty"`
+	// TotalBlocks is the sum of the blocks in each sampled pin.
+	TotalBlocks uint64 `json:"total_blocks" codec:"tb,omitempty"`
+	// UniqueBlocks is the number of distinct blocks in the sampled pins.
+	UniqueBlocks uint64 `json:"unique_blocks" codec:"ub,omitempty"`
+	// SharedBlocks is the number of distinct blocks which are part of
+	// more than one sampled pin.
+	SharedBlocks uint64 `json:"shared_blocks" codec:"sb,omitempty"`
+	TotalBytes   uint64 `json:"total_bytes" codec:"tby,omitempty"`
+	UniqueBytes  uint64 `json:"unique_bytes" codec:"uby,omitempty"`
+	// Truncated is set when some pins had more blocks than the sampling
+	// limit.
+	Truncated bool      `json:"truncated,omitempty" codec:"t,omitempty"`
+	Timestamp time.Time `json:"timestamp" codec:"ts,omitempty"`
+	Error     string    `json:"error,omitempty" codec:"e,omitempty"`
+}
+
+// GlobalDedupStats contains the deduplication statistics of every cluster
+// peer.
+type GlobalDedupStats struct {
+	PeerMap map[string]DedupStats `json:"peer_map" codec:"pm,omitempty"`
+}
+
+// Capacity summarizes the storage of one or several IPFS daemons. The
+// ingest rate is the growth of the used space in bytes per day, estimated
+// from the recent repository sizes. DaysToFull projects when the free
+// space runs out at that rate and is not set when usage is not growing.
+type Capacity struct {
+	StorageMax uint64  `json:"storage_max" codec:"sm,omitempty"`
+	Used       uint64  `json:"used" codec:"u,omitempty"`
+	Free       uint64  `json:"free" codec:"f,omitempty"`
+	IngestRate float64 `json:"ingest_rate" codec:"ir,omitempty"`
+	DaysToFull float64 `json:"days_to_full,omitempty" codec:"d,omitempty"`
+}
+
+// PeerCapacity is the storage capacity of a cluster peer.
+type PeerCapacity struct {
+	Capacity
+	Peer      peer.ID   `json:"peer" codec:"p,omitempty"`
+	Peername  string    `json:"peername" codec:"pn,omitempty"`
+	Timestamp time.Time `json:"timestamp" codec:"ts,omitempty"`
+	Error     string    `json:"error,omitempty" codec:"e,omitempty"`
+}
+
+// GlobalCapacity contains the capacity of every cluster peer and the
+// cluster-wide totals, which only include the peers without errors.
+type GlobalCapacity struct {
+	Total   Capacity                `json:"total" codec:"t,omitempty"`
+	PeerMap map[string]PeerCapacity `json:"peer_map" codec:"pm,omitempty"`
+}
+
+// Reasons for the pin garbage collector to unpin a pin.
+const (
+	// PinGCReasonAge is given to pins older than the maximum pin age.
+	PinGCReasonAge = "age"
+	// PinGCReasonUsage is given to the pins unpinned to bring the
+	// storage usage of the cluster back under the low water mark.
+	PinGCReasonUsage = "usage"
+)
+
+// PinGCCandidate is a pin that the pin garbage collector unpins, or
+// proposes to unpin. Size is the estimated space freed across all the peers
+// that it is allocated to.
+type PinGCCandidate struct {
+	Cid       Cid       `json:"cid" codec:"c"`
+	Name      string    `json:"name" codec:"n,omitempty"`
+	Timestamp time.Time `json:"timestamp" codec:"ts,omitempty"`
+	Size      uint64    `json:"size" codec:"s,omitempty"`
+	Reason    string    `json:"reason" codec:"r,omitempty"`
+	Error     string    `json:"error,omitempty" codec:"e,omitempty"`
+}
+
+// PinGCReport is the result of evaluating the pin garbage collection
+// policy. Used is the storage used by the cluster peers and Limit the
+// storage above which pins are collected. ToFree is the space that needs
+// freeing to get back under the low water mark. Executed is true when the
+// candidates were unpinned rather than only proposed.
+type PinGCReport struct {
+	Timestamp  time.Time        `json:"timestamp" codec:"ts,omitempty"`
+	Used       uint64           `json:"used" codec:"u,omitempty"`
+	Limit      uint64           `json:"limit" codec:"l,omitempty"`
+	ToFree     uint64           `json:"to_free" codec:"tf,omitempty"`
+	Candidates []PinGCCandidate `json:"candidates" codec:"ca,omitempty"`
+	Executed   bool             `json:"executed" codec:"x,omitempty"`
+}
+
+// SLOClassReport tells how many of the pins of a storage class that were
+// pinned during the SLO window were pinned within the target time.
+// Compliance is the fraction of them that did, and Met tells whether it
+// reaches the objective.
+type SLOClassReport struct {
+	Class      string        `json:"class" codec:"c,omitempty"`
+	Target     time.Duration `json:"target" codec:"t,omitempty"`
+	Objective  float64       `json:"objective" codec:"o,omitempty"`
+	Pinned     int           `json:"pinned" codec:"p,omitempty"`
+	OnTime     int           `json:"on_time" codec:"ot,omitempty"`
+	Compliance float64       `json:"compliance" codec:"cm,omitempty"`
+	Met        bool          `json:"met" codec:"m,omitempty"`
+}
+
+// SLOReport describes the compliance with the pin service level objectives
+// over a rolling window, by storage class. PeerErrors holds the peers that
+// could not be reached when aggregating the reports of the whole cluster.
+type SLOReport struct {
+	Timestamp  time.Time         `json:"timestamp" codec:"ts,omitempty"`
+	Window     time.Duration     `json:"window" codec:"w,omitempty"`
+	Classes    []SLOClassReport  `json:"classes" codec:"c,omitempty"`
+	PeerErrors map[string]string `json:"peer_errors,omitempty" codec:"pe,omitempty"`
+}
+
+// StateChecksum is the checksum of the shared state of a cluster peer at a
+// given applied index. Peers that have applied the same operations have the
+// same checksum, so a peer whose checksum differs from the leader's at the
+// same index has diverged.
+type StateChecksum struct {
+	Peer         peer.ID `json:"peer" codec:"p,omitempty"`
+	Peername     string  `json:"peername" codec:"pn,omitempty"`
+	Checksum     string  `json:"checksum" codec:"c,omitempty"`
+	Pins         int64   `json:"pins" codec:"n,omitempty"`
+	AppliedIndex uint64  `json:"applied_index" codec:"i,omitempty"`
+	Leader       bool    `json:"leader,omitempty" codec:"l,omitempty"`
+	Diverged     bool    `json:"diverged,omitempty" codec:"d,omitempty"`
+	Error        string  `json:"error,omitempty" codec:"e,omitempty"`
+}
+
+// GlobalStateChecksum contains the state checksum of every cluster peer.
+type GlobalStateChecksum struct {
+	PeerMap map[string]StateChecksum `json:"peer_map" codec:"pm,omitempty"`
+}
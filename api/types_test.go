@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"encoding/json"
 	"net/url"
 	"reflect"
 	"strings"
@@ -177,6 +178,10 @@ func TestPinOptionsQuery(t *testing.T) {
 				NewMultiaddrWithValue(multiaddr.StringCast("/ip4/1.2.3.4/tcp/1234/p2p/12D3KooWKewdAMAU3WjYHm8qkAJc5eW6KHbHWNigWraXXtE1UCng")),
 				NewMultiaddrWithValue(multiaddr.StringCast("/ip4/2.3.3.4/tcp/1234/p2p/12D3KooWF6BgwX966ge5AVFs9Gd2wVTBmypxZVvaBR12eYnUmXkR")),
 			},
+			StorageClass: "cold",
+			Tags:         []string{"a", "b"},
+			Namespace:    "team-a",
+			Priority:     PinPriorityHigh,
 		},
 		{
 			ReplicationFactorMax: -1,
@@ -219,6 +224,16 @@ func TestPinOptionsQuery(t *testing.T) {
 			t.Errorf("%+v\n", po2)
 		}
 	}
+
+	queryStr, err := PinOptions{ReplaceUpdated: true}.ToQuery()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, _ := url.ParseQuery(queryStr)
+	po := PinOptions{}
+	if err := po.FromQuery(q); err != nil || !po.ReplaceUpdated {
+		t.Errorf("replace-updated did not survive the query round trip: %s", queryStr)
+	}
 }
 
 func TestIDCodec(t *testing.T) {
@@ -261,6 +276,51 @@ func TestIDCodec(t *testing.T) {
 	}
 }
 
+func TestGlobalPinInfoErrors(t *testing.T) {
+	pid1, _ := peer.Decode("QmXZrtE5jQwXNqCJMfHUTQkvhQ4ZAnqMnmzFMJfLewuabc")
+	pid2, _ := peer.Decode("QmUZ13osndQ5uL4tPWHXe3iBgBgq9gfewcBMSCAuMBsDJ6")
+	pid3, _ := peer.Decode("QmPGDFvBkgWhvzEK9qaTWrWurSwqXNmhnK3hgELPdZZNPa")
+	ci, _ := DecodeCid("QmXZrtE5jQwXNqCJMfHUTQkvhQ4ZAnqMnmzFMJfLewuabc")
+	now := time.Now()
+
+	pinInfo := func(pid peer.ID, status TrackerStatus, err string, attempts int, ts time.Time) PinInfo {
+		return PinInfo{
+			Cid:  ci,
+			Peer: pid,
+			PinInfoShort: PinInfoShort{
+				Status:       status,
+				Error:        err,
+				AttemptCount: attempts,
+				TS:           ts,
+			},
+		}
+	}
+
+	var gpi GlobalPinInfo
+	gpi.Add(pinInfo(pid1, TrackerStatusPinError, "timeout", 2, now.Add(-time.Minute)))
+	gpi.Add(pinInfo(pid2, TrackerStatusPinError, "disk full", 1, now))
+	gpi.Add(pinInfo(pid3, TrackerStatusPinError, "timeout", 3, now))
+
+	if len(gpi.Errors) != 2 {
+		t.Fatalf("expected 2 distinct errors: %+v", gpi.Errors)
+	}
+	es := gpi.Errors[0]
+	if es.Message != "timeout" || es.Count() != 2 || es.Attempts != 5 || !es.LastAttempt.Equal(now) {
+		t.Errorf("unexpected error summary: %+v", es)
+	}
+
+	// pid3 recovers, pid1 fails differently.
+	gpi.Add(pinInfo(pid3, TrackerStatusPinned, "", 0, now))
+	gpi.Add(pinInfo(pid1, TrackerStatusPinError, "disk full", 3, now))
+	if len(gpi.Errors) != 1 {
+		t.Fatalf("expected 1 distinct error: %+v", gpi.Errors)
+	}
+	es = gpi.Errors[0]
+	if es.Message != "disk full" || es.Count() != 2 || es.Attempts != 4 {
+		t.Errorf("unexpected error summary: %+v", es)
+	}
+}
+
 func TestPinCodec(t *testing.T) {
 	ci, _ := DecodeCid("QmXZrtE5jQwXNqCJMfHUTQkvhQ4ZAnqMnmzFMJfLewuabc")
 	pin := PinCid(ci)
@@ -281,3 +341,243 @@ func TestPinCodec(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestPinProtoMarshal(t *testing.T) {
+	ci, _ := DecodeCid("QmXZrtE5jQwXNqCJMfHUTQkvhQ4ZAnqMnmzFMJfLewuabc")
+	pin := PinWithOpts(ci, PinOptions{
+		ReplicationFactorMin: 1,
+		ReplicationFactorMax: 2,
+		Name:                 "abc",
+		StorageClass:         "hot",
+		Tags:                 []string{"videos", "archive"},
+		Namespace:            "team-a",
+		Owners:               []string{"bob", "alice"},
+		Priority:             PinPriorityLow,
+	})
+	pin.TrashedAt = time.Unix(1700000000, 0)
+	pin.CanceledAt = time.Unix(1690000000, 0)
+	requesterPeer, _ := peer.Decode("QmUZ13osndQ5uL4tPWHXe3iBgBgq9gfewcBMSCAuMBsDJ6")
+	pin.Requester = PinRequester{
+		User:    "alice",
+		Address: "192.168.1.10",
+		Peer:    requesterPeer,
+	}
+
+	bs, err := pin.ProtoMarshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pin2 Pin
+	err = pin2.ProtoUnmarshal(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pin.Equals(pin2) || pin2.StorageClass != "hot" || len(pin2.Tags) != 2 || pin2.Namespace != "team-a" {
+		t.Errorf("unexpected pin after unmarshaling: %+v", pin2)
+	}
+	if len(pin2.Owners) != 2 || pin2.Owners[0] != "alice" {
+		t.Errorf("unexpected pin after unmarshaling: %+v", pin2)
+	}
+	if pin2.Priority != PinPriorityLow {
+		t.Errorf("unexpected priority after unmarshaling: %s", pin2.Priority)
+	}
+	if !pin2.IsTrashed() || !pin2.TrashedAt.Equal(pin.TrashedAt) {
+		t.Errorf("unexpected trash time after unmarshaling: %s", pin2.TrashedAt)
+	}
+	if !pin2.IsCanceled() || !pin2.CanceledAt.Equal(pin.CanceledAt) {
+		t.Errorf("unexpected cancel time after unmarshaling: %s", pin2.CanceledAt)
+	}
+	if pin2.Requester != pin.Requester {
+		t.Errorf("unexpected requester after unmarshaling: %s", pin2.Requester)
+	}
+}
+
+func TestPinPriorityJSON(t *testing.T) {
+	po := PinOptions{Priority: PinPriorityHigh}
+	bs, err := json.Marshal(po)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(bs), `"priority":"high"`) {
+		t.Errorf("unexpected JSON: %s", bs)
+	}
+
+	var po2 PinOptions
+	err = json.Unmarshal(bs, &po2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if po2.Priority != PinPriorityHigh {
+		t.Errorf("unexpected priority: %s", po2.Priority)
+	}
+
+	err = json.Unmarshal([]byte(`{"priority":"urgent"}`), &po2)
+	if err == nil {
+		t.Error("expected an error with an unknown priority")
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tags := NormalizeTags([]string{" b", "a", "", "b ", "c"})
+	if strings.Join(tags, ",") != "a,b,c" {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+	if NormalizeTags([]string{"", " "}) != nil {
+		t.Error("expected no tags")
+	}
+}
+
+func TestPinListOptions(t *testing.T) {
+	c, _ := DecodeCid("QmP63DkAFEnDYNjDYBpyNDfttu1fvUw99x1brscPzpqmmb")
+	before, _ := DecodeCid("QmP63DkAFEnDYNjDYBpyNDfttu1fvUw99x1brscPzpqmma")
+	after, _ := DecodeCid("QmP63DkAFEnDYNjDYBpyNDfttu1fvUw99x1brscPzpqmmq")
+	origin, _ := peer.Decode("12D3KooWKewdAMAU3WjYHm8qkAJc5eW6KHbHWNigWraXXtE1UCng")
+	otherOrigin, _ := peer.Decode("12D3KooWF6BgwX966ge5AVFs9Gd2wVTBmypxZVvaBR12eYnUmXkR")
+	hourAgo := time.Now().Add(-time.Hour)
+	inAnHour := time.Now().Add(time.Hour)
+
+	pin := PinWithOpts(c, PinOptions{
+		Name:                 "holiday-2022",
+		ReplicationFactorMin: 2,
+		ReplicationFactorMax: 3,
+		Tags:                 []string{"videos"},
+		Namespace:            "team-a",
+		Origins: []Multiaddr{
+			NewMultiaddrWithValue(multiaddr.StringCast("/ip4/1.2.3.4/tcp/1234/p2p/" + origin.String())),
+		},
+	})
+
+	testcases := []struct {
+		opts  PinListOptions
+		match bool
+	}{
+		{PinListOptions{}, true},
+		{PinListOptions{Type: DataType | MetaType}, true},
+		{PinListOptions{Type: ShardType}, false},
+		{PinListOptions{NamePrefix: "holiday"}, true},
+		{PinListOptions{NamePrefix: "2022"}, false},
+		{PinListOptions{CidPrefix: "QmP63"}, true},
+		{PinListOptions{CidPrefix: "bafy"}, false},
+		{PinListOptions{ReplicationFactorMin: 2, ReplicationFactorMax: 3}, true},
+		{PinListOptions{ReplicationFactorMax: -1}, false},
+		{PinListOptions{After: before}, true},
+		{PinListOptions{After: after}, false},
+		{PinListOptions{After: c}, false},
+		{PinListOptions{Namespace: "team-a"}, true},
+		{PinListOptions{Namespace: "team-b"}, false},
+		{PinListOptions{PinFilter: PinFilter{Tags: []string{"videos"}}, NamePrefix: "holiday"}, true},
+		{PinListOptions{Origin: origin}, true},
+		{PinListOptions{Origin: otherOrigin}, false},
+		{PinListOptions{CreatedAfter: hourAgo, CreatedBefore: inAnHour}, true},
+		{PinListOptions{CreatedAfter: inAnHour}, false},
+		{PinListOptions{CreatedBefore: hourAgo}, false},
+		{PinListOptions{Trashed: true}, false},
+	}
+
+	for i, tc := range testcases {
+		if m := tc.opts.Match(pin); m != tc.match {
+			t.Errorf("%d: expected match=%t", i, tc.match)
+		}
+
+		opts, err := PinListOptionsFromQuery(tc.opts.ToQuery())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opts.Match(pin) != tc.match {
+			t.Errorf("%d: options did not survive the query round trip: %+v", i, opts)
+		}
+	}
+
+	opts := PinListOptions{Status: TrackerStatusPinned | TrackerStatusError, Limit: 10}
+	opts2, err := PinListOptionsFromQuery(opts.ToQuery())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts2.Status != opts.Status || opts2.Limit != 10 {
+		t.Errorf("unexpected options after the query round trip: %+v", opts2)
+	}
+
+	for _, q := range []string{"filter=abc", "status=abc", "after=abc", "limit=abc", "limit=-1", "origin=abc", "created-after=abc"} {
+		v, _ := url.ParseQuery(q)
+		if _, err := PinListOptionsFromQuery(v); err == nil {
+			t.Errorf("expected an error parsing %s", q)
+		}
+	}
+}
+
+func TestClusterEventQuery(t *testing.T) {
+	now := time.Now().UTC().Round(0)
+	q := ClusterEventQuery{
+		Since: now.Add(-time.Hour),
+		After: 3,
+		Types: []string{ClusterEventPin, ClusterEventUnpin},
+		Limit: 5,
+	}
+
+	q2, err := ClusterEventQueryFromQuery(q.ToQuery())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q2.Since.Equal(q.Since) || q2.After != 3 || len(q2.Types) != 2 || q2.Limit != 5 {
+		t.Errorf("unexpected query: %+v", q2)
+	}
+
+	ev := ClusterEvent{ID: 4, Type: ClusterEventPin, Timestamp: now}
+	if !q.Match(ev) {
+		t.Error("event should match")
+	}
+	ev.ID = 3
+	if q.Match(ev) {
+		t.Error("event should not match: after")
+	}
+	ev.ID = 4
+	ev.Timestamp = now.Add(-2 * time.Hour)
+	if q.Match(ev) {
+		t.Error("event should not match: since")
+	}
+	ev.Timestamp = now
+	ev.Type = ClusterEventPeerJoined
+	if q.Match(ev) {
+		t.Error("event should not match: type")
+	}
+
+	for _, bad := range []string{"since=yesterday", "after=-1", "limit=-1"} {
+		v, _ := url.ParseQuery(bad)
+		if _, err := ClusterEventQueryFromQuery(v); err == nil {
+			t.Errorf("expected an error parsing %s", bad)
+		}
+	}
+}
+
+func TestPinFilter(t *testing.T) {
+	tags := []string{"archive", "videos"}
+	md := map[string]string{"owner": "alice", "env": "prod"}
+
+	testcases := []struct {
+		filter PinFilter
+		match  bool
+	}{
+		{PinFilter{}, true},
+		{PinFilter{Name: "HOLIDAY"}, true},
+		{PinFilter{Name: "work"}, false},
+		{PinFilter{Tags: []string{"videos"}}, true},
+		{PinFilter{Tags: []string{"videos", "music"}}, false},
+		{PinFilter{Metadata: map[string]string{"owner": "alice"}}, true},
+		{PinFilter{Metadata: map[string]string{"owner": "bob"}}, false},
+		{PinFilter{Name: "holiday", Tags: []string{"archive"}, Metadata: map[string]string{"env": "prod"}}, true},
+	}
+
+	for i, tc := range testcases {
+		if m := tc.filter.Match("holiday-2022", tags, md); m != tc.match {
+			t.Errorf("%d: expected match=%t", i, tc.match)
+		}
+
+		q := url.Values{}
+		tc.filter.ToQuery(q)
+		pf := PinFilterFromQuery(q)
+		if pf.IsEmpty() != tc.filter.IsEmpty() || pf.Match("holiday-2022", tags, md) != tc.match {
+			t.Errorf("%d: filter did not survive the query round trip: %+v", i, pf)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package ipfscluster
+
+import (
+	"context"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	trace "go.opencensus.io/trace"
+)
+
+// APIEndpoints returns the REST API endpoints advertised by the current
+// cluster peers in their ping metrics. Peers which do not advertise any
+// endpoint, or whose last ping has expired, are left out.
+func (c *Cluster) APIEndpoints(ctx context.Context) ([]api.APIEndpoint, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/APIEndpoints")
+	defer span.End()
+
+	members, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	endpoints := make([]api.APIEndpoint, 0, len(members))
+	for _, member := range members {
+		var pv pingValue
+		if member == c.id {
+			// Our own configuration is always up to date.
+			pv.Peername = c.config.Peername
+			pv.APIPublic = c.config.APIPublic
+			for _, addr := range c.config.APIAdvertiseAddresses {
+				pv.APIAddresses = append(pv.APIAddresses, api.NewMultiaddrWithValue(addr))
+			}
+		} else {
+			m := c.monitor.LatestForPeer(ctx, pingMetricName, member)
+			if !m.Defined() || m.Expired() {
+				continue
+			}
+			pv = pingValueFromMetric(m)
+		}
+
+		if len(pv.APIAddresses) == 0 {
+			continue
+		}
+		endpoints = append(endpoints, api.APIEndpoint{
+			Peer:      member,
+			Peername:  pv.Peername,
+			Addresses: pv.APIAddresses,
+			Public:    pv.APIPublic,
+		})
+	}
+	return endpoints, nil
+}
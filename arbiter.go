@@ -0,0 +1,93 @@
+package ipfscluster
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+var errArbiterMode = errors.New("this peer is configured to be an arbiter. It does not run IPFS")
+
+// arbiterTracker wraps the PinTracker of peers running in arbiter mode.
+// Arbiters never pin anything: all the pins in the shared state are
+// considered remote and they are not reported in StatusAll. The wrapped
+// tracker is only used for its Component methods.
+type arbiterTracker struct {
+	PinTracker
+	peerID   peer.ID
+	peerName string
+}
+
+func (at *arbiterTracker) remotePinInfo(pin api.Pin) api.PinInfo {
+	return api.PinInfo{
+		Cid:         pin.Cid,
+		Name:        pin.Name,
+		Peer:        at.peerID,
+		Allocations: pin.Allocations,
+		Origins:     pin.Origins,
+		Created:     pin.Timestamp,
+		Metadata:    pin.Metadata,
+		Tags:        pin.Tags,
+		Namespace:   pin.Namespace,
+		PinInfoShort: api.PinInfoShort{
+			PeerName: at.peerName,
+			Status:   api.TrackerStatusRemote,
+			TS:       time.Now(),
+		},
+	}
+}
+
+// Track does nothing.
+func (at *arbiterTracker) Track(ctx context.Context, pin api.Pin) error {
+	return nil
+}
+
+// Untrack does nothing.
+func (at *arbiterTracker) Untrack(ctx context.Context, c api.Cid) error {
+	return nil
+}
+
+// StatusAll sends no items.
+func (at *arbiterTracker) StatusAll(ctx context.Context, filter api.TrackerStatus, out chan<- api.PinInfo) error {
+	close(out)
+	return nil
+}
+
+// Status returns a remote status for any Cid.
+func (at *arbiterTracker) Status(ctx context.Context, c api.Cid) api.PinInfo {
+	return at.remotePinInfo(api.PinCid(c))
+}
+
+// RecoverAll sends no items.
+func (at *arbiterTracker) RecoverAll(ctx context.Context, out chan<- api.PinInfo) error {
+	close(out)
+	return nil
+}
+
+// RecoverAllWithOptions sends no items.
+func (at *arbiterTracker) RecoverAllWithOptions(ctx context.Context, opts api.RecoverAllOptions, out chan<- api.PinInfo) error {
+	close(out)
+	return nil
+}
+
+// Recover returns a remote status for any Cid.
+func (at *arbiterTracker) Recover(ctx context.Context, c api.Cid) (api.PinInfo, error) {
+	return at.remotePinInfo(api.PinCid(c)), nil
+}
+
+// PinQueueSize returns 0.
+func (at *arbiterTracker) PinQueueSize(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+// QueueInfo returns empty queues.
+func (at *arbiterTracker) QueueInfo(ctx context.Context) (api.PinQueueInfo, error) {
+	return api.PinQueueInfo{
+		Peer:     at.peerID,
+		Peername: at.peerName,
+	}, nil
+}
@@ -0,0 +1,175 @@
+package ipfscluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	rpc "github.com/libp2p/go-libp2p-gorpc"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	trace "go.opencensus.io/trace"
+)
+
+// auditNamespace is the datastore namespace where the pin audit trail is
+// stored.
+const auditNamespace = "/audit"
+
+// auditLog is the history of the pin and unpin operations that this peer
+// submitted to the consensus layer, along with who requested them. Entries
+// are stored under their CID and are never removed, so that the history of
+// an item can be reconstructed long after it was unpinned.
+type auditLog struct {
+	store ds.Datastore
+}
+
+func newAuditLog(store ds.Datastore) *auditLog {
+	return &auditLog{
+		store: namespace.Wrap(store, ds.NewKey(auditNamespace)),
+	}
+}
+
+func (al *auditLog) key(c api.Cid, t time.Time) ds.Key {
+	// zero-padded so that keys sort by time.
+	return ds.NewKey(c.String()).ChildString(fmt.Sprintf("%020d", t.UnixNano()))
+}
+
+func (al *auditLog) record(ctx context.Context, entry api.PinAuditEntry) error {
+	v, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return al.store.Put(ctx, al.key(entry.Cid, entry.Timestamp), v)
+}
+
+// history returns the entries recorded for the given CID, oldest first.
+func (al *auditLog) history(ctx context.Context, c api.Cid) ([]api.PinAuditEntry, error) {
+	results, err := al.store.Query(ctx, query.Query{
+		Prefix: ds.NewKey(c.String()).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+
+	var entries []api.PinAuditEntry
+	for r := range results.Next() {
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		var entry api.PinAuditEntry
+		err := json.Unmarshal(r.Value, &entry)
+		if err != nil {
+			logger.Errorf("skipping unreadable audit entry %s: %s", r.Key, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sortAuditEntries(entries)
+	return entries, nil
+}
+
+func sortAuditEntries(entries []api.PinAuditEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+}
+
+type requesterKey struct{}
+
+// withRequester returns a context carrying the requester of the operations
+// performed with it.
+func withRequester(ctx context.Context, r api.PinRequester) context.Context {
+	return context.WithValue(ctx, requesterKey{}, r)
+}
+
+// withRPCRequester is like withRequester for operations received through
+// RPC. The user and address are those given by the API component which
+// received the request, and the peer is the one that sent the RPC request.
+func withRPCRequester(ctx context.Context, r api.PinRequester) context.Context {
+	if sender, ok := ctx.Value(rpc.ContextKeyRequestSender).(peer.ID); ok {
+		r.Peer = sender
+	}
+	return withRequester(ctx, r)
+}
+
+// requester returns the requester carried by the context. Operations
+// without one are performed by this peer on its own.
+func (c *Cluster) requester(ctx context.Context) api.PinRequester {
+	r, _ := ctx.Value(requesterKey{}).(api.PinRequester)
+	if r.Peer == "" {
+		r.Peer = c.id
+	}
+	return r
+}
+
+// recordAudit adds an operation submitted to the consensus layer to the
+// audit trail.
+func (c *Cluster) recordAudit(ctx context.Context, typ string, pin api.Pin) {
+	switch {
+	case pin.IsTrashed():
+		typ = api.PinAuditUnpin
+	case typ == api.PinAuditPin && pin.IsCanceled():
+		typ = api.PinAuditCancel
+	}
+	err := c.audit.record(ctx, api.PinAuditEntry{
+		Type:      typ,
+		Cid:       pin.Cid,
+		Name:      pin.Name,
+		Owners:    pin.Owners,
+		Requester: pin.Requester,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		logger.Errorf("error recording the %s of %s in the audit trail: %s", typ, pin.Cid, err)
+	}
+}
+
+// PinHistory returns the pin and unpin operations on the given CID
+// recorded by all the cluster peers, oldest first. Peers which cannot be
+// contacted are skipped.
+func (c *Cluster) PinHistory(ctx context.Context, h api.Cid) ([]api.PinAuditEntry, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/PinHistory")
+	defer span.End()
+
+	members, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	var entries []api.PinAuditEntry
+	for _, member := range members {
+		var peerEntries []api.PinAuditEntry
+		err = c.rpcClient.CallContext(
+			ctx,
+			member,
+			"Cluster",
+			"PinHistoryLocal",
+			h,
+			&peerEntries,
+		)
+		if err != nil {
+			logger.Errorf("%s: error getting the pin history from %s: %s", c.id, member, err)
+			continue
+		}
+		entries = append(entries, peerEntries...)
+	}
+	sortAuditEntries(entries)
+	return entries, nil
+}
+
+// PinHistoryLocal returns the pin and unpin operations on the given CID
+// recorded by this peer, oldest first.
+func (c *Cluster) PinHistoryLocal(ctx context.Context, h api.Cid) ([]api.PinAuditEntry, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/PinHistoryLocal")
+	defer span.End()
+
+	return c.audit.history(ctx, h)
+}
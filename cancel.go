@@ -0,0 +1,48 @@
+package ipfscluster
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	trace "go.opencensus.io/trace"
+)
+
+// CancelPin stops pinning an item: the peers allocated to it abort any
+// ongoing pin operation, including the request to their IPFS daemon, and do
+// not pin it until it is pinned again. The pin stays in the shared state,
+// marked as canceled, and peers which had already pinned it keep it.
+//
+// When unpin is set, the item is unpinned instead, bypassing the trash, so
+// that it is also removed from the peers which had pinned it. The blocks
+// fetched so far are left to the IPFS garbage collector.
+func (c *Cluster) CancelPin(ctx context.Context, h api.Cid, unpin bool) (api.Pin, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/CancelPin")
+	defer span.End()
+
+	if c.config.FollowerMode {
+		return api.Pin{}, errFollowerMode
+	}
+
+	pin, err := c.PinGet(ctx, h)
+	if err != nil {
+		return api.Pin{}, err
+	}
+	if pin.Type != api.DataType {
+		return pin, errors.New("only data pins can be canceled")
+	}
+
+	if unpin {
+		logger.Info("canceling and unpinning:", h)
+		return c.unpin(ctx, h, false)
+	}
+	if pin.IsCanceled() {
+		return pin, nil
+	}
+
+	logger.Info("canceling pin:", h)
+	pin.CanceledAt = time.Now()
+	return pin, c.logPin(ctx, pin)
+}
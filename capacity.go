@@ -0,0 +1,184 @@
+package ipfscluster
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	rpc "github.com/libp2p/go-libp2p-gorpc"
+	trace "go.opencensus.io/trace"
+)
+
+// capacitySample is the size of the IPFS repository at a given time.
+type capacitySample struct {
+	ts   time.Time
+	used uint64
+}
+
+// capacityHistory keeps the last repository size samples taken by this
+// peer.
+type capacityHistory struct {
+	mux     sync.RWMutex
+	size    int
+	samples []capacitySample
+}
+
+func (ch *capacityHistory) add(s capacitySample) {
+	ch.mux.Lock()
+	defer ch.mux.Unlock()
+	ch.samples = append(ch.samples, s)
+	if len(ch.samples) > ch.size {
+		ch.samples = ch.samples[len(ch.samples)-ch.size:]
+	}
+}
+
+func (ch *capacityHistory) get() []capacitySample {
+	ch.mux.RLock()
+	defer ch.mux.RUnlock()
+	samples := make([]capacitySample, len(ch.samples))
+	copy(samples, ch.samples)
+	return samples
+}
+
+// watchCapacity periodically samples the size of the IPFS repository.
+func (c *Cluster) watchCapacity() {
+	if c.config.CapacityInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.config.CapacityInterval)
+	defer ticker.Stop()
+	for {
+		stat, err := c.ipfs.RepoStat(c.ctx)
+		if err != nil {
+			logger.Warnf("error sampling repository size: %s", err)
+		} else {
+			c.capacity.add(capacitySample{ts: time.Now(), used: stat.RepoSize})
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ingestRate returns the growth of the used space in bytes per day, as the
+// slope of the least squares line fitting the samples.
+func ingestRate(samples []capacitySample) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+
+	t0 := samples[0].ts
+	n := float64(len(samples))
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		x := s.ts.Sub(t0).Hours() / 24
+		y := float64(s.used)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	den := n*sumXX - sumX*sumX
+	if den == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / den
+}
+
+// setCapacityProjection sets the days until the free space runs out at the
+// ingest rate.
+func setCapacityProjection(capacity *api.Capacity) {
+	capacity.DaysToFull = 0
+	if capacity.IngestRate > 0 {
+		capacity.DaysToFull = float64(capacity.Free) / capacity.IngestRate
+	}
+}
+
+// CapacityLocal returns the storage capacity of the IPFS daemon of this
+// peer, with the ingest rate estimated from the sampled repository sizes.
+func (c *Cluster) CapacityLocal(ctx context.Context) (api.PeerCapacity, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/CapacityLocal")
+	defer span.End()
+
+	if c.config.ArbiterMode {
+		return api.PeerCapacity{}, errArbiterMode
+	}
+
+	stat, err := c.ipfs.RepoStat(ctx)
+	if err != nil {
+		return api.PeerCapacity{}, err
+	}
+
+	now := time.Now()
+	pc := api.PeerCapacity{
+		Peer:      c.id,
+		Peername:  c.config.Peername,
+		Timestamp: now,
+	}
+	pc.StorageMax = stat.StorageMax
+	pc.Used = stat.RepoSize
+	if pc.StorageMax > pc.Used {
+		pc.Free = pc.StorageMax - pc.Used
+	}
+
+	samples := append(c.capacity.get(), capacitySample{ts: now, used: stat.RepoSize})
+	pc.IngestRate = ingestRate(samples)
+	setCapacityProjection(&pc.Capacity)
+	return pc, nil
+}
+
+// Capacity returns the storage capacity of every cluster peer along with
+// the cluster-wide totals.
+func (c *Cluster) Capacity(ctx context.Context) (api.GlobalCapacity, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/Capacity")
+	defer span.End()
+
+	members, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Error(err)
+		return api.GlobalCapacity{}, err
+	}
+
+	global := api.GlobalCapacity{PeerMap: make(map[string]api.PeerCapacity)}
+
+	for _, member := range members {
+		var pc api.PeerCapacity
+		err = c.rpcClient.CallContext(
+			ctx,
+			member,
+			"Cluster",
+			"CapacityLocal",
+			struct{}{},
+			&pc,
+		)
+		if err == nil {
+			global.PeerMap[member.String()] = pc
+			global.Total.StorageMax += pc.StorageMax
+			global.Total.Used += pc.Used
+			global.Total.Free += pc.Free
+			global.Total.IngestRate += pc.IngestRate
+			continue
+		}
+
+		if rpc.IsAuthorizationError(err) {
+			logger.Debug("rpc auth error:", err)
+			continue
+		}
+
+		pv := pingValueFromMetric(c.monitor.LatestForPeer(ctx, pingMetricName, member))
+		global.PeerMap[member.String()] = api.PeerCapacity{
+			Peer:     member,
+			Peername: c.peername(pv, member),
+			Error:    err.Error(),
+		}
+	}
+
+	setCapacityProjection(&global.Total)
+	return global, nil
+}
@@ -0,0 +1,95 @@
+package ipfscluster
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	rpc "github.com/libp2p/go-libp2p-gorpc"
+	trace "go.opencensus.io/trace"
+)
+
+// stateChecksummer is implemented by consensus components that keep a
+// checksum of the shared state.
+type stateChecksummer interface {
+	StateChecksum(context.Context) (api.StateChecksum, error)
+}
+
+// StateChecksumLocal returns the checksum of the shared state of this peer.
+// It is only supported by the Raft consensus.
+func (c *Cluster) StateChecksumLocal(ctx context.Context) (api.StateChecksum, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/StateChecksumLocal")
+	defer span.End()
+
+	sc, ok := c.consensus.(stateChecksummer)
+	if !ok {
+		return api.StateChecksum{}, errors.New("the consensus component does not support state checksums")
+	}
+	sum, err := sc.StateChecksum(ctx)
+	if err != nil {
+		return api.StateChecksum{}, err
+	}
+	sum.Peername = c.config.Peername
+	return sum, nil
+}
+
+// StateChecksum returns the state checksum of every cluster peer. Peers
+// which have applied the same operations as the leader but whose checksum
+// is different are marked as diverged.
+func (c *Cluster) StateChecksum(ctx context.Context) (api.GlobalStateChecksum, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/StateChecksum")
+	defer span.End()
+
+	members, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Error(err)
+		return api.GlobalStateChecksum{}, err
+	}
+
+	global := api.GlobalStateChecksum{PeerMap: make(map[string]api.StateChecksum)}
+	var leader *api.StateChecksum
+
+	for _, member := range members {
+		var sum api.StateChecksum
+		err = c.rpcClient.CallContext(
+			ctx,
+			member,
+			"Cluster",
+			"StateChecksumLocal",
+			struct{}{},
+			&sum,
+		)
+		if err == nil {
+			global.PeerMap[member.String()] = sum
+			if sum.Leader {
+				leaderSum := sum
+				leader = &leaderSum
+			}
+			continue
+		}
+
+		if rpc.IsAuthorizationError(err) {
+			logger.Debug("rpc auth error:", err)
+			continue
+		}
+
+		pv := pingValueFromMetric(c.monitor.LatestForPeer(ctx, pingMetricName, member))
+		global.PeerMap[member.String()] = api.StateChecksum{
+			Peer:     member,
+			Peername: c.peername(pv, member),
+			Error:    err.Error(),
+		}
+	}
+
+	if leader == nil {
+		return global, nil
+	}
+	for k, sum := range global.PeerMap {
+		if sum.Error == "" && sum.AppliedIndex == leader.AppliedIndex && sum.Checksum != leader.Checksum {
+			sum.Diverged = true
+			global.PeerMap[k] = sum
+		}
+	}
+	return global, nil
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"mime/multipart"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/ipfs-cluster/ipfs-cluster/adder/sharding"
 	"github.com/ipfs-cluster/ipfs-cluster/adder/single"
 	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/kvstore"
 	"github.com/ipfs-cluster/ipfs-cluster/pstoremgr"
 	"github.com/ipfs-cluster/ipfs-cluster/rpcutil"
 	"github.com/ipfs-cluster/ipfs-cluster/state"
@@ -26,6 +28,7 @@ import (
 	peer "github.com/libp2p/go-libp2p/core/peer"
 	peerstore "github.com/libp2p/go-libp2p/core/peerstore"
 	mdns "github.com/libp2p/go-libp2p/p2p/discovery/mdns"
+	ping "github.com/libp2p/go-libp2p/p2p/protocol/ping"
 	ma "github.com/multiformats/go-multiaddr"
 
 	ocgorpc "github.com/lanzafame/go-libp2p-ocgorpc"
@@ -60,6 +63,12 @@ type Cluster struct {
 	dht       *dual.DHT
 	discovery mdns.Service
 	datastore ds.Datastore
+	intents   *intentLog
+	events    *eventLog
+	audit     *auditLog
+	schedules *pinScheduler
+	observed  *observedPins
+	archive   *peersArchive
 
 	rpcServer   *rpc.Server
 	rpcClient   *rpc.Client
@@ -91,6 +100,22 @@ type Cluster struct {
 	removed      bool
 
 	curPingVal pingValue
+
+	dedup dedupStats
+
+	capacity *capacityHistory
+
+	slo sloStats
+
+	unpins pendingUnpins
+
+	handoffs handoffJobs
+
+	index *pinIndex
+
+	allocBurst allocBurst
+
+	expiry pinExpiry
 }
 
 // NewCluster builds a new IPFS Cluster peer. It initializes a LibP2P host,
@@ -99,6 +124,10 @@ type Cluster struct {
 // The new cluster peer may still be performing initialization tasks when
 // this call returns (consensus may still be bootstrapping). Use Cluster.Ready()
 // if you need to wait until the peer is fully up.
+//
+// The datastore must be persistent: the peer keeps there the data which is
+// not part of the shared state, like the intents of the operations that it
+// accepted, which are replayed after a crash.
 func NewCluster(
 	ctx context.Context,
 	host host.Host,
@@ -127,6 +156,15 @@ func NewCluster(
 		return nil, errors.New("no informers are passed")
 	}
 
+	// Arbiters do not pin anything.
+	if cfg.ArbiterMode {
+		tracker = &arbiterTracker{
+			PinTracker: tracker,
+			peerID:     host.ID(),
+			peerName:   cfg.Peername,
+		}
+	}
+
 	ctx, cancel := context.WithCancel(ctx)
 
 	listenAddrs := ""
@@ -156,6 +194,14 @@ func NewCluster(
 		dht:         dht,
 		discovery:   mdnsSvc,
 		datastore:   datastore,
+		intents:     newIntentLog(datastore),
+		audit:       newAuditLog(datastore),
+		schedules:   newPinScheduler(),
+		events:      newEventLog(ctx, datastore, host.ID(), cfg.EventHistorySize, cfg.EventHistoryRetention),
+		observed:    newObservedPins(datastore),
+		archive:     newPeersArchive(ctx, datastore),
+		capacity:    &capacityHistory{size: cfg.CapacityHistory},
+		index:       newPinIndex(cfg.PinIndexes),
 		consensus:   consensus,
 		apis:        apis,
 		ipfs:        ipfs,
@@ -190,6 +236,16 @@ func NewCluster(
 		}
 	}
 
+	c.setupScratchStores()
+
+	// Intents left over by a previous run are read before this peer
+	// accepts any operation, so that they are not mistaken with the
+	// intents of operations in flight.
+	intents, err := c.intents.pending(ctx)
+	if err != nil {
+		logger.Errorf("error reading the intent log: %s", err)
+	}
+
 	// After setupRPC components can do their tasks with a fully operative
 	// routed libp2p host with some connections and a working DHT (hopefully).
 	err = c.setupRPC()
@@ -199,25 +255,40 @@ func NewCluster(
 	}
 	c.setupRPCClients()
 
+	// Subscribe right away so that the event history includes what
+	// happens while the peer becomes ready.
+	events := consensus.SubscribeEvents(ctx)
+	leaders := consensus.SubscribeLeader(ctx)
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.watchEvents(events, leaders)
+	}()
+
 	// Note: It is very important to first call Add() once in a non-racy
 	// place
 	c.wg.Add(1)
 	go func() {
 		defer c.wg.Done()
 		c.ready(ReadyTimeout)
-		c.run()
+		c.run(intents)
 	}()
 
 	return c, nil
 }
 
 func (c *Cluster) setupRPC() error {
-	rpcServer, err := newRPCServer(c)
+	rpcServer, err := newRPCServer(c, version.RPCProtocol, nil)
 	if err != nil {
 		return err
 	}
 	c.rpcServer = rpcServer
 
+	err = c.setupLegacyRPC()
+	if err != nil {
+		return err
+	}
+
 	var rpcClient *rpc.Client
 	if c.config.Tracing {
 		csh := &ocgorpc.ClientHandler{}
@@ -235,7 +306,11 @@ func (c *Cluster) setupRPC() error {
 }
 
 func (c *Cluster) setupRPCClients() {
-	c.ipfs.SetClient(c.rpcClient)
+	// Arbiters do not use IPFS. The connector does not start working
+	// until it has an RPC client.
+	if !c.config.ArbiterMode {
+		c.ipfs.SetClient(c.rpcClient)
+	}
 	c.tracker.SetClient(c.rpcClient)
 	for _, api := range c.apis {
 		api.SetClient(c.rpcClient)
@@ -248,6 +323,32 @@ func (c *Cluster) setupRPCClients() {
 	}
 }
 
+// scratchStoreUser is implemented by components which persist scratch data
+// in the peer datastore.
+type scratchStoreUser interface {
+	SetScratchStore(*kvstore.Store)
+}
+
+// setupScratchStores gives a scratch store to the components that want one.
+// Components are expected to use their own namespace in it.
+func (c *Cluster) setupScratchStores() {
+	store := kvstore.New(c.datastore)
+
+	components := []interface{}{c.consensus, c.ipfs, c.tracker, c.monitor, c.allocator}
+	for _, api := range c.apis {
+		components = append(components, api)
+	}
+	for _, informer := range c.informers {
+		components = append(components, informer)
+	}
+
+	for _, comp := range components {
+		if u, ok := comp.(scratchStoreUser); ok {
+			u.SetScratchStore(store)
+		}
+	}
+}
+
 // watchPinset triggers recurrent operations that loop on the pinset.
 func (c *Cluster) watchPinset() {
 	ctx, span := trace.StartSpan(c.ctx, "cluster/watchPinset")
@@ -399,6 +500,11 @@ func (c *Cluster) sendPingMetric(ctx context.Context) (api.Metric, error) {
 		!newPingVal.Valid() { // i.e. ipfs down
 		newPingVal = c.curPingVal // use last good value
 	}
+	newPingVal.APIAddresses = nil
+	for _, addr := range c.config.APIAdvertiseAddresses {
+		newPingVal.APIAddresses = append(newPingVal.APIAddresses, api.NewMultiaddrWithValue(addr))
+	}
+	newPingVal.APIPublic = c.config.APIPublic
 	c.curPingVal = newPingVal
 
 	v, err := json.Marshal(newPingVal)
@@ -442,21 +548,16 @@ func (c *Cluster) pushPingMetrics(ctx context.Context) {
 	ctx, span := trace.StartSpan(ctx, "cluster/pushPingMetrics")
 	defer span.End()
 
+	// The first ping metric is sent by ready().
 	ticker := time.NewTicker(c.config.MonitorPingInterval)
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		default:
+		case <-ticker.C:
 		}
 
 		c.sendPingMetric(ctx)
-
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-		}
 	}
 }
 
@@ -499,6 +600,11 @@ func (c *Cluster) alertsHandler() {
 				c.alerts = append(c.alerts, alrt)
 			}
 			c.alertsMux.Unlock()
+			c.RecordEvent(c.ctx, api.ClusterEvent{
+				Type:    api.ClusterEventAlert,
+				Subject: alrt.Peer,
+				Message: alrt.Name,
+			})
 
 			if alrt.Name != pingMetricName {
 				continue // only handle ping alerts
@@ -649,12 +755,19 @@ func (c *Cluster) repinFromPeer(ctx context.Context, p peer.ID, pin api.Pin) {
 	}
 }
 
-// run launches some go-routines which live throughout the cluster's life
-func (c *Cluster) run() {
+// run launches some go-routines which live throughout the cluster's life.
+// The given intents are replayed.
+func (c *Cluster) run(intents []intent) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.replayIntents(c.ctx, intents)
+	}()
+
 	c.wg.Add(1)
 	go func() {
 		defer c.wg.Done()
-		c.watchPinset()
+		c.watchPinSchedules()
 	}()
 
 	c.wg.Add(1)
@@ -663,14 +776,48 @@ func (c *Cluster) run() {
 		c.pushPingMetrics(c.ctx)
 	}()
 
-	c.wg.Add(len(c.informers))
-	for _, informer := range c.informers {
-		go func(inf Informer) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.watchPinIndex()
+	}()
+
+	// Arbiters have nothing to pin and, without informer metrics, they
+	// are never allocated anything.
+	if !c.config.ArbiterMode {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.watchPinset()
+		}()
+
+		c.wg.Add(len(c.informers))
+		for _, informer := range c.informers {
+			go func(inf Informer) {
+				defer c.wg.Done()
+				c.pushInformerMetrics(c.ctx, inf)
+			}(informer)
+		}
+
+		c.wg.Add(1)
+		go func() {
 			defer c.wg.Done()
-			c.pushInformerMetrics(c.ctx, inf)
-		}(informer)
+			c.watchDedupStats()
+		}()
+
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.watchCapacity()
+		}()
 	}
 
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.watchPinGC()
+	}()
+
 	c.wg.Add(1)
 	go func() {
 		defer c.wg.Done()
@@ -744,19 +891,28 @@ This might be due to one or several causes:
 	}
 
 	// Wait for ipfs
-	logger.Info("Waiting for IPFS to be ready...")
-	select {
-	case <-ctx.Done():
-		return
-	case <-c.ipfs.Ready(ctx):
-		ipfsid, err := c.ipfs.ID(ctx)
-		if err != nil {
-			logger.Error("IPFS signaled ready but ID() errored: ", err)
-		} else {
-			logger.Infof("IPFS is ready. Peer ID: %s", ipfsid.ID)
+	if c.config.ArbiterMode {
+		logger.Info("Running in arbiter mode: IPFS is not used")
+	} else {
+		logger.Info("Waiting for IPFS to be ready...")
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.ipfs.Ready(ctx):
+			ipfsid, err := c.ipfs.ID(ctx)
+			if err != nil {
+				logger.Error("IPFS signaled ready but ID() errored: ", err)
+			} else {
+				logger.Infof("IPFS is ready. Peer ID: %s", ipfsid.ID)
+			}
 		}
 	}
 
+	// Send our first ping before signaling readiness so that this
+	// peer can be allocated blocks as soon as it is ready, regardless
+	// of how long the tasks started by run() take to get going.
+	c.sendPingMetric(ctx)
+
 	close(c.readyCh)
 	c.shutdownLock.Lock()
 	c.readyB = true
@@ -793,14 +949,24 @@ func (c *Cluster) Shutdown(ctx context.Context) error {
 
 	logger.Info("shutting down Cluster")
 
+	if c.config.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.config.ShutdownTimeout)
+		defer cancel()
+	}
+
 	// Shutdown APIs first, avoids more requests coming through.
 	for _, api := range c.apis {
-		if err := api.Shutdown(ctx); err != nil {
+		if err := c.shutdownComponent(ctx, "api", false, api.Shutdown); err != nil {
 			logger.Errorf("error stopping API: %s", err)
 			return err
 		}
 	}
 
+	// Stop unpinning expired items and running pin schedules.
+	c.expiry.stop()
+	c.schedules.stop()
+
 	// Cancel discovery service (this shutdowns announcing). Handling
 	// entries is canceled along with the context below.
 	if c.discovery != nil {
@@ -831,8 +997,11 @@ func (c *Cluster) Shutdown(ctx context.Context) error {
 		}
 	}
 
+	// Consensus is always waited for, as it may need to write a snapshot
+	// and close its stores before the host and the datastore are
+	// closed.
 	if con := c.consensus; con != nil {
-		if err := con.Shutdown(ctx); err != nil {
+		if err := c.shutdownComponent(ctx, "consensus", true, con.Shutdown); err != nil {
 			logger.Errorf("error stopping consensus: %s", err)
 			return err
 		}
@@ -847,29 +1016,29 @@ func (c *Cluster) Shutdown(ctx context.Context) error {
 		}
 	}
 
-	if err := c.monitor.Shutdown(ctx); err != nil {
+	if err := c.shutdownComponent(ctx, "monitor", false, c.monitor.Shutdown); err != nil {
 		logger.Errorf("error stopping monitor: %s", err)
 		return err
 	}
 
-	if err := c.ipfs.Shutdown(ctx); err != nil {
+	if err := c.shutdownComponent(ctx, "ipfs_connector", false, c.ipfs.Shutdown); err != nil {
 		logger.Errorf("error stopping IPFS Connector: %s", err)
 		return err
 	}
 
-	if err := c.tracker.Shutdown(ctx); err != nil {
+	if err := c.shutdownComponent(ctx, "pin_tracker", false, c.tracker.Shutdown); err != nil {
 		logger.Errorf("error stopping PinTracker: %s", err)
 		return err
 	}
 
 	for _, inf := range c.informers {
-		if err := inf.Shutdown(ctx); err != nil {
+		if err := c.shutdownComponent(ctx, "informer", false, inf.Shutdown); err != nil {
 			logger.Errorf("error stopping informer: %s", err)
 			return err
 		}
 	}
 
-	if err := c.tracer.Shutdown(ctx); err != nil {
+	if err := c.shutdownComponent(ctx, "tracer", false, c.tracer.Shutdown); err != nil {
 		logger.Errorf("error stopping Tracer: %s", err)
 		return err
 	}
@@ -894,8 +1063,11 @@ func (c *Cluster) ID(ctx context.Context) api.ID {
 	defer span.End()
 
 	// ignore error since it is included in response object
-	ipfsID, err := c.ipfs.ID(ctx)
-	if err != nil {
+	var ipfsID api.IPFSID
+	var err error
+	if c.config.ArbiterMode {
+		ipfsID.Error = errArbiterMode.Error()
+	} else if ipfsID, err = c.ipfs.ID(ctx); err != nil {
 		ipfsID = api.IPFSID{
 			Error: err.Error(),
 		}
@@ -910,10 +1082,27 @@ func (c *Cluster) ID(ctx context.Context) api.ID {
 	}
 
 	peers := []peer.ID{}
+	var catchUp *api.CatchUpProgress
+	var lag uint64
+	var consensusStats *api.ConsensusStats
 	// This method might get called very early by a remote peer
 	// and might catch us when consensus is not set
 	if c.consensus != nil {
 		peers, _ = c.consensus.Peers(ctx)
+		p := c.consensus.CatchUpProgress(ctx)
+		if p.Syncing {
+			catchUp = &p
+		}
+		if p.LastIndex > p.AppliedIndex {
+			lag = p.LastIndex - p.AppliedIndex
+		}
+		stats := c.consensus.Stats(ctx)
+		consensusStats = &stats
+	}
+
+	dsSize, dsErr := ds.DiskUsage(ctx, c.datastore)
+	if dsErr != nil {
+		logger.Debugf("error measuring datastore disk usage: %s", dsErr)
 	}
 
 	clusterPeerInfos := c.peerManager.PeerInfos(peers)
@@ -938,6 +1127,10 @@ func (c *Cluster) ID(ctx context.Context) api.ID {
 		RPCProtocolVersion:    version.RPCProtocol,
 		IPFS:                  ipfsID,
 		Peername:              c.config.Peername,
+		CatchUp:               catchUp,
+		ConsensusLag:          lag,
+		Consensus:             consensusStats,
+		DatastoreSize:         dsSize,
 	}
 	if err != nil {
 		id.Error = err.Error()
@@ -1000,6 +1193,9 @@ func (c *Cluster) PeerRemove(ctx context.Context, pid peer.ID) error {
 	ctx, span := trace.StartSpan(ctx, "cluster/PeerRemove")
 	defer span.End()
 
+	// Keep the name before the peer metrics expire.
+	name := pingValueFromMetric(c.monitor.LatestForPeer(ctx, pingMetricName, pid)).Peername
+
 	// We need to repin before removing the peer, otherwise, it won't
 	// be able to submit the pins.
 	logger.Infof("re-allocating all CIDs directly associated to %s", pid)
@@ -1011,6 +1207,7 @@ func (c *Cluster) PeerRemove(ctx context.Context, pid peer.ID) error {
 		return err
 	}
 	logger.Info("Peer removed ", pid.Pretty())
+	c.archiveRemovedPeer(ctx, pid, name)
 	return nil
 }
 
@@ -1102,9 +1299,11 @@ func (c *Cluster) Join(ctx context.Context, addr ma.Multiaddr) error {
 
 	// ConnectSwarms in the background after a while, when we have likely
 	// received some metrics.
-	time.AfterFunc(c.config.MonitorPingInterval, func() {
-		c.ipfs.ConnectSwarms(c.ctx)
-	})
+	if !c.config.ArbiterMode {
+		time.AfterFunc(c.config.MonitorPingInterval, func() {
+			c.ipfs.ConnectSwarms(c.ctx)
+		})
+	}
 
 	// wait for leader and for state to catch up
 	// then sync
@@ -1147,7 +1346,10 @@ func (c *Cluster) distances(ctx context.Context, exclude peer.ID) (*distanceChec
 // looping through all the items. It is triggered automatically on
 // StateSyncInterval. Currently it:
 //   - Sends unpin for expired items for which this peer is "closest"
+//     and schedules it for those expiring before the next StateSync
 //     (skipped for follower peers)
+//   - Does the same for trashed items whose trash retention period is
+//     over
 func (c *Cluster) StateSync(ctx context.Context) error {
 	ctx, span := trace.StartSpan(ctx, "cluster/StateSync")
 	defer span.End()
@@ -1182,14 +1384,30 @@ func (c *Cluster) StateSync(ctx context.Context) error {
 		}
 	}()
 
-	// Unpin expired items when we are the closest peer to them.
+	// Unpin expired items when we are the closest peer to them, and
+	// schedule the unpinning of those expiring before the next StateSync.
+	nextSync := timeNow.Add(c.config.StateSyncInterval)
 	for p := range clusterPins {
-		if p.ExpiredAt(timeNow) && distance.isClosest(p.Cid) {
-			logger.Infof("Unpinning %s: pin expired at %s", p.Cid, p.ExpireAt)
-			if _, err := c.Unpin(ctx, p.Cid); err != nil {
-				logger.Error(err)
+		if p.IsTrashed() {
+			purgeAt := c.trashPurgeAt(p)
+			if !purgeAt.Before(nextSync) || !distance.isClosest(p.Cid) {
+				continue
+			}
+			if !purgeAt.After(timeNow) {
+				c.purgeTrashed(ctx, p)
+				continue
 			}
+			c.scheduleTrashPurge(p)
+			continue
+		}
+		if !p.ExpiredAt(nextSync) || !distance.isClosest(p.Cid) {
+			continue
 		}
+		if p.ExpiredAt(timeNow) {
+			c.unpinExpired(ctx, p)
+			continue
+		}
+		c.scheduleExpiry(p)
 	}
 
 	return nil
@@ -1200,13 +1418,60 @@ func (c *Cluster) StateSync(ctx context.Context) error {
 // an error happens, it is returned. This method blocks until it finishes. The
 // operation can be aborted by canceling the context.
 func (c *Cluster) StatusAll(ctx context.Context, filter api.TrackerStatus, out chan<- api.GlobalPinInfo) error {
+	return c.StatusAllWithOptions(ctx, api.StatusAllOptions{Filter: filter}, out)
+}
+
+// StatusAllWithOptions works like StatusAll, but peers are queried in
+// batches of opts.BatchSize and the GlobalPinInfos for every batch are sent
+// as soon as it finishes. Thus, the same Cid may be sent several times, each
+// with the statuses from a different set of peers. Peers that do not finish
+// sending their statuses within opts.TimeoutPerPeer are reported with a
+// ClusterError status. Options left to 0 take the values from the
+// configuration. Only the items matching opts.PinFilter and opts.CidPrefix
+// are sent, with the statuses from opts.Peers when set.
+func (c *Cluster) StatusAllWithOptions(ctx context.Context, opts api.StatusAllOptions, out chan<- api.GlobalPinInfo) error {
 	ctx, span := trace.StartSpan(ctx, "cluster/StatusAll")
 	defer span.End()
 
-	in := make(chan api.TrackerStatus, 1)
-	in <- filter
-	close(in)
-	return c.globalPinInfoStream(ctx, "PinTracker", "StatusAll", in, out)
+	if opts.BatchSize == 0 {
+		opts.BatchSize = c.config.StatusAllBatchSize
+	}
+	if opts.TimeoutPerPeer == 0 {
+		opts.TimeoutPerPeer = c.config.StatusAllPeerTimeout
+	}
+
+	newIn := func() interface{} {
+		in := make(chan api.TrackerStatus, 1)
+		in <- opts.Filter
+		close(in)
+		return in
+	}
+
+	if opts.PinFilter.IsEmpty() && opts.CidPrefix == "" {
+		return c.globalPinInfoStream(ctx, "PinTracker", "StatusAll", newIn, opts.Peers, opts.BatchSize, opts.TimeoutPerPeer, out)
+	}
+
+	defer close(out)
+	gpis := make(chan api.GlobalPinInfo, 1024)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for gpi := range gpis {
+			if !opts.PinFilter.Match(gpi.Name, gpi.Tags, gpi.Metadata) {
+				continue
+			}
+			if !strings.HasPrefix(gpi.Cid.String(), opts.CidPrefix) {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+			case out <- gpi:
+			}
+		}
+	}()
+	err := c.globalPinInfoStream(ctx, "PinTracker", "StatusAll", newIn, opts.Peers, opts.BatchSize, opts.TimeoutPerPeer, gpis)
+	<-done
+	return err
 }
 
 // StatusAllLocal returns the PinInfo for all the tracked Cids in this peer on
@@ -1225,7 +1490,47 @@ func (c *Cluster) Status(ctx context.Context, h api.Cid) (api.GlobalPinInfo, err
 	ctx, span := trace.StartSpan(ctx, "cluster/Status")
 	defer span.End()
 
-	return c.globalPinInfoCid(ctx, "PinTracker", "Status", h)
+	gpin, err := c.globalPinInfoCid(ctx, "PinTracker", "Status", h)
+	if err != nil {
+		return gpin, err
+	}
+
+	// Failing to obtain the shards does not make the status of the
+	// MetaPin itself wrong.
+	gpin.Shards, err = c.shardsStatus(ctx, h)
+	if err != nil {
+		logger.Errorf("error obtaining the status of the shards of %s: %s", h, err)
+	}
+	return gpin, nil
+}
+
+// shardsStatus returns the global status of every shard when the given cid
+// is the MetaPin of a sharded DAG, and nil otherwise.
+func (c *Cluster) shardsStatus(ctx context.Context, h api.Cid) ([]api.GlobalPinInfo, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/shardsStatus")
+	defer span.End()
+
+	pin, err := c.PinGet(ctx, h)
+	if err != nil || pin.Type != api.MetaType {
+		return nil, nil
+	}
+
+	// The list ends with the ClusterDAG and the MetaPin.
+	cids, err := c.cidsFromMetaPin(ctx, h)
+	if err != nil {
+		return nil, err
+	}
+	shards := cids[:len(cids)-2]
+
+	statuses := make([]api.GlobalPinInfo, 0, len(shards))
+	for _, shard := range shards {
+		gpin, err := c.globalPinInfoCid(ctx, "PinTracker", "Status", shard)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, gpin)
+	}
+	return statuses, nil
 }
 
 // StatusLocal returns this peer's PinInfo for a given Cid.
@@ -1269,7 +1574,23 @@ func (c *Cluster) RecoverAll(ctx context.Context, out chan<- api.GlobalPinInfo)
 	ctx, span := trace.StartSpan(ctx, "cluster/RecoverAll")
 	defer span.End()
 
-	return c.globalPinInfoStream(ctx, "Cluster", "RecoverAllLocal", nil, out)
+	return c.globalPinInfoStream(ctx, "Cluster", "RecoverAllLocal", nil, nil, 0, 0, out)
+}
+
+// RecoverAllWithOptions triggers a RecoverAllLocalWithOptions operation on
+// all peers and returns GlobalPinInfo objects for the recovered items. Every
+// peer re-queues the items selected by the options at opts.Rate at most.
+func (c *Cluster) RecoverAllWithOptions(ctx context.Context, opts api.RecoverAllOptions, out chan<- api.GlobalPinInfo) error {
+	ctx, span := trace.StartSpan(ctx, "cluster/RecoverAllWithOptions")
+	defer span.End()
+
+	newIn := func() interface{} {
+		in := make(chan api.RecoverAllOptions, 1)
+		in <- opts
+		close(in)
+		return in
+	}
+	return c.globalPinInfoStream(ctx, "Cluster", "RecoverAllLocalWithOptions", newIn, nil, 0, 0, out)
 }
 
 // RecoverAllLocal triggers a RecoverLocal operation for all Cids tracked
@@ -1290,6 +1611,15 @@ func (c *Cluster) RecoverAllLocal(ctx context.Context, out chan<- api.PinInfo) e
 	return c.tracker.RecoverAll(ctx, out)
 }
 
+// RecoverAllLocalWithOptions works like RecoverAllLocal, but only recovers
+// the items selected by the options, at opts.Rate at most.
+func (c *Cluster) RecoverAllLocalWithOptions(ctx context.Context, opts api.RecoverAllOptions, out chan<- api.PinInfo) error {
+	ctx, span := trace.StartSpan(ctx, "cluster/RecoverAllLocalWithOptions")
+	defer span.End()
+
+	return c.tracker.RecoverAllWithOptions(ctx, opts, out)
+}
+
 // Recover triggers a recover operation for a given Cid in all
 // cluster peers.
 //
@@ -1377,6 +1707,40 @@ func (c *Cluster) PinGet(ctx context.Context, h api.Cid) (api.Pin, error) {
 	return pin, nil
 }
 
+// AllocationExplain describes how the given CID would be allocated with the
+// latest metrics: which peers are considered, the metrics and ranking the
+// allocator uses for them and why they are chosen or not. The allocations
+// are not modified. CIDs which are not pinned are explained as if they were
+// pinned with the default options.
+func (c *Cluster) AllocationExplain(ctx context.Context, h api.Cid) (api.AllocationExplanation, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/AllocationExplain")
+	defer span.End()
+
+	existing, err := c.PinGet(ctx, h)
+	if err != nil && err != state.ErrNotFound {
+		return api.AllocationExplanation{}, err
+	}
+
+	pin := existing
+	if !existing.Defined() {
+		pin = api.PinCid(h)
+	}
+	pin, err = c.setupStorageClass(pin)
+	if err != nil {
+		return api.AllocationExplanation{}, err
+	}
+	pin, err = c.setupReplicationFactor(pin)
+	if err != nil {
+		return api.AllocationExplanation{}, err
+	}
+	excluded, err := c.storageClassExcludedPeers(ctx, pin.StorageClass)
+	if err != nil {
+		return api.AllocationExplanation{}, err
+	}
+
+	return c.explainAllocation(ctx, pin, existing, excluded), nil
+}
+
 // Pin makes the cluster Pin a Cid. This implies adding the Cid
 // to the IPFS Cluster peers shared-state. Depending on the cluster
 // pinning strategy, the PinTracker may then request the IPFS daemon
@@ -1480,11 +1844,28 @@ func (c *Cluster) setupPin(ctx context.Context, pin, existing api.Pin) (api.Pin,
 	defer span.End()
 	var err error
 
+	pin, err = c.setupStorageClass(pin)
+	if err != nil {
+		return pin, err
+	}
+
 	pin, err = c.setupReplicationFactor(pin)
 	if err != nil {
 		return pin, err
 	}
 
+	pin, err = c.setupNamespace(ctx, pin, existing)
+	if err != nil {
+		return pin, err
+	}
+
+	err = c.checkPinName(ctx, pin, existing)
+	if err != nil {
+		return pin, err
+	}
+
+	pin = setupOwners(pin, existing)
+
 	if !pin.ExpireAt.IsZero() && pin.ExpireAt.Before(time.Now()) {
 		return pin, errors.New("pin.ExpireAt set before current time")
 	}
@@ -1537,6 +1918,7 @@ func (c *Cluster) pin(
 		pin, err := c.PinUpdate(ctx, update, pin.Cid, pin.PinOptions)
 		return pin, true, err
 	}
+	pin.ReplaceUpdated = false
 
 	existing, err := c.PinGet(ctx, pin.Cid)
 	if err != nil && err != state.ErrNotFound {
@@ -1548,12 +1930,17 @@ func (c *Cluster) pin(
 		return pin, false, err
 	}
 
+	err = c.preflightPinSize(ctx, pin, existing)
+	if err != nil {
+		return pin, false, err
+	}
+
 	// Set the Pin timestamp to now(). This is not an user-controllable
 	// "option".
 	pin.Timestamp = time.Now()
 
 	if pin.Type == api.MetaType {
-		return pin, true, c.consensus.LogPin(ctx, pin)
+		return pin, true, c.logPin(ctx, pin)
 	}
 
 	// Usually allocations are unset when pinning normally, however, the
@@ -1563,6 +1950,21 @@ func (c *Cluster) pin(
 	// allocate() will check which peers are currently allocated
 	// and try to respect them.
 	if len(pin.Allocations) == 0 {
+		// Peers outside the peer group of the storage class are not
+		// candidates. Current allocations outside it are dropped
+		// when the class changes.
+		group, grouped, err := c.storageClassGroup(ctx, pin.StorageClass)
+		if err != nil {
+			return pin, false, err
+		}
+		if grouped {
+			excluded, err := c.peersOutside(ctx, group)
+			if err != nil {
+				return pin, false, err
+			}
+			blacklist = append(blacklist, excluded...)
+		}
+
 		// If replication factor is -1, this will return empty
 		// allocations.
 		allocs, err := c.allocate(
@@ -1577,6 +1979,12 @@ func (c *Cluster) pin(
 		if err != nil {
 			return pin, false, err
 		}
+		// Current allocations are kept as they are when there are
+		// enough of them. Those outside the peer group are not
+		// counted, so dropping them leaves enough.
+		if grouped && len(allocs) > 0 {
+			allocs = peersIntersect(allocs, group)
+		}
 		pin.Allocations = allocs
 	}
 
@@ -1587,7 +1995,11 @@ func (c *Cluster) pin(
 		logger.Infof("pinning %s on %s:", pin.Cid, pin.Allocations)
 	}
 
-	return pin, true, c.consensus.LogPin(ctx, pin)
+	err = c.logPin(ctx, pin)
+	if err == nil {
+		c.scheduleExpiry(pin)
+	}
+	return pin, true, err
 }
 
 // Unpin removes a previously pinned Cid from Cluster. It returns
@@ -1596,10 +2008,20 @@ func (c *Cluster) pin(
 //
 // Unpin does not reflect the success or failure of underlying IPFS daemon
 // unpinning operations, which happen in async fashion.
+//
+// When TrashRetention is set, items are moved to the trash instead and stay
+// pinned until the retention period is over. The returned pin is then the
+// trashed one.
 func (c *Cluster) Unpin(ctx context.Context, h api.Cid) (api.Pin, error) {
 	ctx, span := trace.StartSpan(ctx, "cluster/Unpin")
 	defer span.End()
 
+	return c.unpin(ctx, h, true)
+}
+
+// unpin removes an item from the shared state. Items are moved to the trash
+// instead when useTrash is set and the trash is enabled.
+func (c *Cluster) unpin(ctx context.Context, h api.Cid, useTrash bool) (api.Pin, error) {
 	if c.config.FollowerMode {
 		return api.Pin{}, errFollowerMode
 	}
@@ -1612,7 +2034,14 @@ func (c *Cluster) Unpin(ctx context.Context, h api.Cid) (api.Pin, error) {
 
 	switch pin.Type {
 	case api.DataType:
-		return pin, c.consensus.LogUnpin(ctx, pin)
+		if useTrash && c.config.TrashRetention > 0 && !pin.IsTrashed() {
+			return c.trash(ctx, pin)
+		}
+		err := c.logUnpin(ctx, pin)
+		if err == nil {
+			c.watchUnpin(pin, false)
+		}
+		return pin, err
 	case api.ShardType:
 		err := "cannot unpin a shard directly. Unpin content root CID instead"
 		return pin, errors.New(err)
@@ -1622,7 +2051,7 @@ func (c *Cluster) Unpin(ctx context.Context, h api.Cid) (api.Pin, error) {
 		if err != nil {
 			return pin, err
 		}
-		return pin, c.consensus.LogUnpin(ctx, pin)
+		return pin, c.logUnpin(ctx, pin)
 	case api.ClusterDAGType:
 		err := "cannot unpin a Cluster DAG directly. Unpin content root CID instead"
 		return pin, errors.New(err)
@@ -1657,11 +2086,12 @@ func (c *Cluster) unpinClusterDag(ctx context.Context, metaPin api.Pin) error {
 // Pin.  The options object can be used to set the Name for the new pin and
 // might support additional options in the future.
 //
-// The from pin is NOT unpinned upon completion. The new pin might take
-// advantage of efficient pin/update operation on IPFS-side (if the
-// IPFSConnector supports it - the default one does). This may offer
-// significant speed when pinning items which are similar to previously pinned
-// content.
+// The from pin is NOT unpinned upon completion, unless ReplaceUpdated is
+// set, in which case it is unpinned once the new pin is pinned by all the
+// peers allocated to it. The new pin might take advantage of efficient
+// pin/update operation on IPFS-side (if the IPFSConnector supports it - the
+// default one does). This may offer significant speed when pinning items
+// which are similar to previously pinned content.
 func (c *Cluster) PinUpdate(ctx context.Context, from api.Cid, to api.Cid, opts api.PinOptions) (api.Pin, error) {
 	existing, err := c.PinGet(ctx, from)
 	if err != nil { // including when the existing pin is not found
@@ -1683,7 +2113,35 @@ func (c *Cluster) PinUpdate(ctx context.Context, from api.Cid, to api.Cid, opts
 	if !opts.ExpireAt.IsZero() && opts.ExpireAt.After(time.Now()) {
 		existing.ExpireAt = opts.ExpireAt
 	}
-	return existing, c.consensus.LogPin(ctx, existing)
+	if opts.Priority != api.PinPriorityNormal {
+		existing.Priority = opts.Priority
+	}
+
+	// The new pin stays in the namespace of the pin it is based on.
+	target, err := c.PinGet(ctx, to)
+	if err != nil && err != state.ErrNotFound {
+		return api.Pin{}, err
+	}
+	if _, err := c.setupNamespace(ctx, existing, target); err != nil {
+		return api.Pin{}, err
+	}
+
+	// The new pin is referenced by the owners updating it, if any, on
+	// top of those of the target when it was already pinned.
+	if len(opts.Owners) > 0 {
+		existing.Owners = opts.Owners
+	}
+	existing = setupOwners(existing, target)
+	if err := c.checkPinName(ctx, existing, target); err != nil {
+		return api.Pin{}, err
+	}
+	if err := c.logPin(ctx, existing); err != nil {
+		return existing, err
+	}
+	if opts.ReplaceUpdated {
+		c.replaceUpdated(existing, opts.Owners)
+	}
+	return existing, nil
 }
 
 // PinPath pins an CID resolved from its IPFS Path. It returns the resolved
@@ -1753,6 +2211,22 @@ func (c *Cluster) Peers(ctx context.Context, out chan<- api.ID) {
 	c.peersWithFilter(ctx, peers, out)
 }
 
+// peerRTT pings a peer and returns the round-trip time, or the latency
+// estimated by the peerstore when the ping fails. It is zero for this peer.
+func (c *Cluster) peerRTT(ctx context.Context, p peer.ID) time.Duration {
+	if p == c.id {
+		return 0
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	res := <-ping.Ping(ctx, c.host, p)
+	if res.Error != nil {
+		logger.Debugf("error pinging %s: %s", p, res.Error)
+		return c.host.Peerstore().LatencyEWMA(p)
+	}
+	return res.RTT
+}
+
 // requests IDs from a given number of peers.
 func (c *Cluster) peersWithFilter(ctx context.Context, peers []peer.ID, out chan<- api.ID) {
 	defer close(out)
@@ -1785,6 +2259,7 @@ func (c *Cluster) peersWithFilter(ctx context.Context, peers []peer.ID, out chan
 	// because it is closed when MultiStream ends and we cannot keep
 	// adding things on it (the errors below).
 	for id := range idsOut {
+		id.RTT = c.peerRTT(ctxCall, id.ID)
 		select {
 		case <-ctx.Done():
 			logger.Errorf("Peers call aborted: %s", ctx.Err())
@@ -1843,9 +2318,11 @@ func (c *Cluster) setTrackerStatus(gpin *api.GlobalPinInfo, h api.Cid, peers []p
 			Origins:     pin.Origins,
 			Created:     pin.Timestamp,
 			Metadata:    pin.Metadata,
+			Tags:        pin.Tags,
+			Namespace:   pin.Namespace,
 			Peer:        p,
 			PinInfoShort: api.PinInfoShort{
-				PeerName:      pv.Peername,
+				PeerName:      c.peername(pv, p),
 				IPFS:          pv.IPFSID,
 				IPFSAddresses: pv.IPFSAddresses,
 				Status:        status,
@@ -1877,7 +2354,7 @@ func (c *Cluster) globalPinInfoCid(ctx context.Context, comp, method string, h a
 	}
 
 	// When NotFound return directly with an unpinned
-	// status.
+	// status, unless the unpin is waiting for confirmations.
 	if err == state.ErrNotFound {
 		var members []peer.ID
 		if c.config.FollowerMode {
@@ -1890,6 +2367,10 @@ func (c *Cluster) globalPinInfoCid(ctx context.Context, comp, method string, h a
 			}
 		}
 
+		if pu, ok := c.unpins.get(h, c.config.UnpinQuorumTimeout); ok {
+			return c.unpinQuorumStatus(ctx, comp, method, pu, members)
+		}
+
 		c.setTrackerStatus(
 			&gpin,
 			h,
@@ -1929,6 +2410,15 @@ func (c *Cluster) globalPinInfoCid(ctx context.Context, comp, method string, h a
 	// set status remote on un-allocated peers
 	c.setTrackerStatus(&gpin, h, remote, api.TrackerStatusRemote, pin, timeNow)
 
+	c.multiCallPinInfo(ctx, comp, method, h, pin, dests, &gpin)
+	return gpin, nil
+}
+
+// multiCallPinInfo calls the given PinInfo-returning method on the given
+// peers and adds their responses to gpin. Errors are added as
+// ClusterError statuses.
+func (c *Cluster) multiCallPinInfo(ctx context.Context, comp, method string, h api.Cid, pin api.Pin, dests []peer.ID, gpin *api.GlobalPinInfo) {
+	timeNow := time.Now()
 	lenDests := len(dests)
 	replies := make([]api.PinInfo, lenDests)
 
@@ -1973,8 +2463,10 @@ func (c *Cluster) globalPinInfoCid(ctx context.Context, comp, method string, h a
 			Origins:     pin.Origins,
 			Created:     pin.Timestamp,
 			Metadata:    pin.Metadata,
+			Tags:        pin.Tags,
+			Namespace:   pin.Namespace,
 			PinInfoShort: api.PinInfoShort{
-				PeerName:      pv.Peername,
+				PeerName:      c.peername(pv, dests[i]),
 				IPFS:          pv.IPFSID,
 				IPFSAddresses: pv.IPFSAddresses,
 				Status:        api.TrackerStatusClusterError,
@@ -1983,24 +2475,28 @@ func (c *Cluster) globalPinInfoCid(ctx context.Context, comp, method string, h a
 			},
 		})
 	}
-
-	return gpin, nil
 }
 
-func (c *Cluster) globalPinInfoStream(ctx context.Context, comp, method string, inChan interface{}, out chan<- api.GlobalPinInfo) error {
+// globalPinInfoStream calls the given streaming method on all peers, in
+// batches of batchSize (all at once when 0), and sends the PinInfos
+// collected from every batch as GlobalPinInfos. newIn returns the input
+// channel for every call. When peers is not empty, only the cluster members
+// among them are called. When timeoutPerPeer is set, peers have that much
+// time to finish their responses.
+func (c *Cluster) globalPinInfoStream(ctx context.Context, comp, method string, newIn func() interface{}, peers []peer.ID, batchSize int, timeoutPerPeer time.Duration, out chan<- api.GlobalPinInfo) error {
 	defer close(out)
 
 	ctx, span := trace.StartSpan(ctx, "cluster/globalPinInfoStream")
 	defer span.End()
 
-	if inChan == nil {
-		emptyChan := make(chan struct{})
-		close(emptyChan)
-		inChan = emptyChan
+	if newIn == nil {
+		newIn = func() interface{} {
+			emptyChan := make(chan struct{})
+			close(emptyChan)
+			return emptyChan
+		}
 	}
 
-	fullMap := make(map[api.Cid]api.GlobalPinInfo)
-
 	var members []peer.ID
 	var err error
 	if c.config.FollowerMode {
@@ -2012,6 +2508,9 @@ func (c *Cluster) globalPinInfoStream(ctx context.Context, comp, method string,
 			return err
 		}
 	}
+	if len(peers) > 0 {
+		members = peersIntersect(members, peers)
+	}
 
 	// We don't have a good timeout proposal for this. Depending on the
 	// size of the state and the peformance of IPFS and the network, this
@@ -2020,19 +2519,25 @@ func (c *Cluster) globalPinInfoStream(ctx context.Context, comp, method string,
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	msOut := make(chan api.PinInfo)
-	errsCh := make(chan []error, 1)
-	go func() {
-		defer close(errsCh)
-		errsCh <- c.rpcClient.MultiStream(
-			ctx,
-			members,
-			comp,
-			method,
-			inChan,
-			msOut,
-		)
-	}()
+	if batchSize <= 0 || batchSize > len(members) {
+		batchSize = len(members)
+	}
+	for start := 0; start < len(members); start += batchSize {
+		end := start + batchSize
+		if end > len(members) {
+			end = len(members)
+		}
+		err := c.globalPinInfoBatch(ctx, comp, method, newIn, members[start:end], timeoutPerPeer, out)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) globalPinInfoBatch(ctx context.Context, comp, method string, newIn func() interface{}, members []peer.ID, timeoutPerPeer time.Duration, out chan<- api.GlobalPinInfo) error {
+	fullMap := make(map[api.Cid]api.GlobalPinInfo)
+	var mapMux sync.Mutex
 
 	setPinInfo := func(p api.PinInfo) {
 		if !p.Defined() {
@@ -2047,31 +2552,55 @@ func (c *Cluster) globalPinInfoStream(ctx context.Context, comp, method string,
 		fullMap[p.Cid] = info
 	}
 
-	// make the big collection.
-	for pin := range msOut {
-		setPinInfo(pin)
+	// make the big collection. Every peer is streamed separately so that
+	// they can time out on their own.
+	errs := make([]error, len(members))
+	var wg sync.WaitGroup
+	wg.Add(len(members))
+	for i, p := range members {
+		go func(i int, p peer.ID) {
+			defer wg.Done()
+
+			pctx := ctx
+			if timeoutPerPeer > 0 {
+				var cancel context.CancelFunc
+				pctx, cancel = context.WithTimeout(ctx, timeoutPerPeer)
+				defer cancel()
+			}
+
+			pinsCh := make(chan api.PinInfo, 1024)
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- c.rpcClient.Stream(pctx, p, comp, method, newIn(), pinsCh)
+			}()
+
+			for pin := range pinsCh {
+				mapMux.Lock()
+				setPinInfo(pin)
+				mapMux.Unlock()
+			}
+			errs[i] = <-errCh
+		}(i, p)
 	}
+	wg.Wait()
 
-	// This WAITs until MultiStream is DONE.
 	erroredPeers := make(map[peer.ID]string)
-	errs, ok := <-errsCh
-	if ok {
-		for i, err := range errs {
-			if err == nil {
-				continue
-			}
-			if rpc.IsAuthorizationError(err) {
-				logger.Debug("rpc auth error", err)
-				continue
-			}
-			logger.Errorf("%s: error in broadcast response from %s: %s ", c.id, members[i], err)
-			erroredPeers[members[i]] = err.Error()
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if rpc.IsAuthorizationError(err) {
+			logger.Debug("rpc auth error", err)
+			continue
 		}
+		logger.Errorf("%s: error in broadcast response from %s: %s ", c.id, members[i], err)
+		erroredPeers[members[i]] = err.Error()
 	}
 
 	// Merge any errors
 	for p, msg := range erroredPeers {
 		pv := pingValueFromMetric(c.monitor.LatestForPeer(ctx, pingMetricName, p))
+		peername := c.peername(pv, p)
 		for c := range fullMap {
 			setPinInfo(api.PinInfo{
 				Cid:         c,
@@ -2082,7 +2611,7 @@ func (c *Cluster) globalPinInfoStream(ctx context.Context, comp, method string,
 				// Created:    // leave unitialized
 				Metadata: nil,
 				PinInfoShort: api.PinInfoShort{
-					PeerName:      pv.Peername,
+					PeerName:      peername,
 					IPFS:          pv.IPFSID,
 					IPFSAddresses: pv.IPFSAddresses,
 					Status:        api.TrackerStatusClusterError,
@@ -2217,6 +2746,143 @@ func (c *Cluster) cidsFromMetaPin(ctx context.Context, h api.Cid) ([]api.Cid, er
 // 	return
 // }
 
+// rollbacker is implemented by consensus components that can roll back the
+// shared state.
+type rollbacker interface {
+	Rollback(context.Context, []api.Pin) (api.RollbackInfo, error)
+}
+
+// Rollback replaces the shared state with the given pinset. Use with care:
+// pins not included in it are unpinned everywhere. It is only supported by
+// the Raft consensus and must run on the leader.
+func (c *Cluster) Rollback(ctx context.Context, pins []api.Pin) (api.RollbackInfo, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/Rollback")
+	defer span.End()
+
+	rb, ok := c.consensus.(rollbacker)
+	if !ok {
+		return api.RollbackInfo{}, errors.New("the consensus component does not support rollbacks")
+	}
+	for _, pin := range pins {
+		if !pin.Defined() {
+			return api.RollbackInfo{}, errors.New("rollback pinset contains undefined pins")
+		}
+	}
+	return rb.Rollback(ctx, pins)
+}
+
+// logInspector is implemented by consensus components that keep a log of
+// operations.
+type logInspector interface {
+	LogEntries(ctx context.Context, before uint64, limit int) ([]api.ConsensusLogEntry, error)
+}
+
+// ConsensusLog returns a page of the operations recently committed to the
+// consensus log, newest first. It is only supported by the Raft consensus.
+func (c *Cluster) ConsensusLog(ctx context.Context, q api.ConsensusLogQuery) ([]api.ConsensusLogEntry, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/ConsensusLog")
+	defer span.End()
+
+	li, ok := c.consensus.(logInspector)
+	if !ok {
+		return nil, errors.New("the consensus component does not provide an operation log")
+	}
+	return li.LogEntries(ctx, q.Before, q.Limit)
+}
+
+// deadLetterQueue is implemented by consensus components that keep the
+// operations which could not be applied to the shared state.
+type deadLetterQueue interface {
+	DeadLetters(ctx context.Context) ([]api.DeadLetter, error)
+	RetryDeadLetter(ctx context.Context, id string) error
+	DiscardDeadLetter(ctx context.Context, id string) error
+}
+
+func (c *Cluster) deadLetterQueue() (deadLetterQueue, error) {
+	dlq, ok := c.consensus.(deadLetterQueue)
+	if !ok {
+		return nil, errors.New("the consensus component does not keep dead letters")
+	}
+	return dlq, nil
+}
+
+// DeadLetters returns the operations that this peer failed to apply to the
+// shared state. It is only supported by the Raft consensus.
+func (c *Cluster) DeadLetters(ctx context.Context) ([]api.DeadLetter, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/DeadLetters")
+	defer span.End()
+
+	dlq, err := c.deadLetterQueue()
+	if err != nil {
+		return nil, err
+	}
+	return dlq.DeadLetters(ctx)
+}
+
+// RetryDeadLetter commits again an operation that this peer failed to apply
+// to the shared state.
+func (c *Cluster) RetryDeadLetter(ctx context.Context, id string) error {
+	ctx, span := trace.StartSpan(ctx, "cluster/RetryDeadLetter")
+	defer span.End()
+
+	dlq, err := c.deadLetterQueue()
+	if err != nil {
+		return err
+	}
+	return dlq.RetryDeadLetter(ctx, id)
+}
+
+// DiscardDeadLetter forgets an operation that this peer failed to apply to
+// the shared state.
+func (c *Cluster) DiscardDeadLetter(ctx context.Context, id string) error {
+	ctx, span := trace.StartSpan(ctx, "cluster/DiscardDeadLetter")
+	defer span.End()
+
+	dlq, err := c.deadLetterQueue()
+	if err != nil {
+		return err
+	}
+	return dlq.DiscardDeadLetter(ctx, id)
+}
+
+// ConsensusEvents sends an event to the given channel for every operation
+// applied to the shared state from now on, until the context is cancelled.
+// The channel is closed when it returns.
+func (c *Cluster) ConsensusEvents(ctx context.Context, out chan<- api.ConsensusEvent) error {
+	defer close(out)
+
+	ctx, span := trace.StartSpan(ctx, "cluster/ConsensusEvents")
+	defer span.End()
+
+	for ev := range c.consensus.SubscribeEvents(ctx) {
+		select {
+		case out <- ev:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// quorumReporter is implemented by consensus components that need a quorum
+// of voters to commit operations.
+type quorumReporter interface {
+	Quorum(context.Context) (api.QuorumStatus, error)
+}
+
+// Quorum returns whether the consensus can currently commit operations. It
+// is only supported by the Raft consensus.
+func (c *Cluster) Quorum(ctx context.Context) (api.QuorumStatus, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/Quorum")
+	defer span.End()
+
+	qr, ok := c.consensus.(quorumReporter)
+	if !ok {
+		return api.QuorumStatus{}, errors.New("the consensus component does not use a quorum")
+	}
+	return qr.Quorum(ctx)
+}
+
 // RepoGC performs garbage collection sweep on all peers' IPFS repo.
 func (c *Cluster) RepoGC(ctx context.Context) (api.GlobalRepoGC, error) {
 	ctx, span := trace.StartSpan(ctx, "cluster/RepoGC")
@@ -2257,7 +2923,7 @@ func (c *Cluster) RepoGC(ctx context.Context) (api.GlobalRepoGC, error) {
 
 		globalRepoGC.PeerMap[member.String()] = api.RepoGC{
 			Peer:     member,
-			Peername: pv.Peername,
+			Peername: c.peername(pv, member),
 			Keys:     []api.IPFSRepoGC{},
 			Error:    err.Error(),
 		}
@@ -2266,6 +2932,63 @@ func (c *Cluster) RepoGC(ctx context.Context) (api.GlobalRepoGC, error) {
 	return globalRepoGC, nil
 }
 
+// PinQueues returns information about the queues of pin and unpin
+// operations of all the cluster peers.
+func (c *Cluster) PinQueues(ctx context.Context) (api.GlobalPinQueueInfo, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/PinQueues")
+	defer span.End()
+
+	members, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Error(err)
+		return api.GlobalPinQueueInfo{}, err
+	}
+
+	global := api.GlobalPinQueueInfo{PeerMap: make(map[string]api.PinQueueInfo)}
+
+	for _, member := range members {
+		var queues api.PinQueueInfo
+		err = c.rpcClient.CallContext(
+			ctx,
+			member,
+			"Cluster",
+			"PinQueuesLocal",
+			struct{}{},
+			&queues,
+		)
+		if err == nil {
+			global.PeerMap[member.String()] = queues
+			continue
+		}
+
+		if rpc.IsAuthorizationError(err) {
+			logger.Debug("rpc auth error:", err)
+			continue
+		}
+
+		logger.Errorf("%s: error in broadcast response from %s: %s ", c.id, member, err)
+
+		pv := pingValueFromMetric(c.monitor.LatestForPeer(ctx, pingMetricName, member))
+
+		global.PeerMap[member.String()] = api.PinQueueInfo{
+			Peer:     member,
+			Peername: c.peername(pv, member),
+			Error:    err.Error(),
+		}
+	}
+
+	return global, nil
+}
+
+// PinQueuesLocal returns information about the queues of pin and unpin
+// operations of this peer.
+func (c *Cluster) PinQueuesLocal(ctx context.Context) (api.PinQueueInfo, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/PinQueuesLocal")
+	defer span.End()
+
+	return c.tracker.QueueInfo(ctx)
+}
+
 // RepoGCLocal performs garbage collection only on the local IPFS deamon.
 func (c *Cluster) RepoGCLocal(ctx context.Context) (api.RepoGC, error) {
 	ctx, span := trace.StartSpan(ctx, "cluster/RepoGCLocal")
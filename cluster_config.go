@@ -30,6 +30,7 @@ var DefaultListenAddrs = []string{
 // Configuration defaults
 const (
 	DefaultEnableRelayHop        = true
+	DefaultPrivateNetworkMode    = PrivateNetworkPnet
 	DefaultStateSyncInterval     = 5 * time.Minute
 	DefaultPinRecoverInterval    = 12 * time.Minute
 	DefaultMonitorPingInterval   = 15 * time.Second
@@ -44,7 +45,32 @@ const (
 	DefaultConnMgrGracePeriod    = 2 * time.Minute
 	DefaultDialPeerTimeout       = 3 * time.Second
 	DefaultFollowerMode          = false
+	DefaultUniquePinNames        = false
+	DefaultArbiterMode           = false
 	DefaultMDNSInterval          = 10 * time.Second
+	DefaultDedupStatsInterval    = 0
+	DefaultDedupStatsSampleSize  = 100
+	DefaultCapacityInterval      = time.Hour
+	DefaultCapacityHistory       = 168 // a week of hourly samples
+	DefaultMetricsPrefetchFanout = 0
+	DefaultStatusAllBatchSize    = 0
+	DefaultStatusAllPeerTimeout  = 0
+	DefaultShutdownTimeout       = 2 * time.Minute
+	DefaultEventHistorySize      = 10000
+	DefaultEventHistoryRetention = 7 * 24 * time.Hour
+	DefaultPinSizeLimit          = 0
+	DefaultPinPreflightTimeout   = time.Minute
+	DefaultPinGCInterval         = 0
+	DefaultPinGCHighWater        = 0.9
+	DefaultPinGCLowWater         = 0.8
+	DefaultPinGCMaxTotalSize     = 0
+	DefaultPinGCMinPinAge        = 24 * time.Hour
+	DefaultPinGCMaxPinAge        = 0
+	DefaultPinGCAutoUnpin        = false
+	DefaultPinSLOWindow          = 24 * time.Hour
+	DefaultUnpinQuorum           = 0
+	DefaultUnpinQuorumTimeout    = time.Hour
+	DefaultTrashRetention        = 0
 )
 
 // ConnMgrConfig configures the libp2p host connection manager.
@@ -54,6 +80,23 @@ type ConnMgrConfig struct {
 	GracePeriod time.Duration
 }
 
+// StorageClass defines the replication factors and the group of peers
+// (matched against their "group" tag) used by pins of the class.
+type StorageClass struct {
+	// PeerGroup restricts allocations to peers whose "group" tag (see
+	// the tags informer) has this value. Empty for no restriction.
+	PeerGroup            string `json:"peer_group"`
+	ReplicationFactorMin int    `json:"replication_factor_min"`
+	ReplicationFactorMax int    `json:"replication_factor_max"`
+}
+
+// PinSLO sets the target time for the pins of a storage class to become
+// pinned and the fraction of them that should meet it.
+type PinSLO struct {
+	Target    time.Duration
+	Objective float64
+}
+
 // Config is the configuration object containing customizable variables to
 // initialize the main ipfs-cluster component. It implements the
 // config.ComponentConfig interface.
@@ -68,6 +111,14 @@ type Config struct {
 	// 64 characters and contain only hexadecimal characters (`[0-9a-f]`).
 	Secret pnet.PSK
 
+	// PrivateNetworkMode selects how the Secret keeps other peers out:
+	// "pnet" (the libp2p private network protector) or "tls-psk" (TLS
+	// on the TCP listeners, authenticated with a key derived from the
+	// Secret, for environments where pnet is not acceptable). All the
+	// peers must use the same mode. QUIC, websockets and relays
+	// (including EnableRelayHop) are not available with "tls-psk".
+	PrivateNetworkMode string
+
 	// RPCPolicy defines access control to RPC endpoints.
 	RPCPolicy map[string]RPCEndpointType
 
@@ -127,6 +178,21 @@ type Config struct {
 	// peers.
 	MonitorPingInterval time.Duration
 
+	// APIAdvertiseAddresses are the REST API endpoints of this peer.
+	// They are advertised to other peers in the "ping" metric so that
+	// clients can discover them. Nothing is advertised when empty.
+	APIAdvertiseAddresses []ma.Multiaddr
+
+	// APIPublic tells clients whether the advertised API endpoints
+	// accept traffic from anyone or only from trusted clients.
+	APIPublic bool
+
+	// HandoffTargets are the REST API addresses of the clusters that
+	// pins can be handed off to. Handoffs unpin items from this cluster
+	// once the target reports them as pinned, so only targets trusted
+	// by the operator are accepted. Handoffs are disabled when empty.
+	HandoffTargets []ma.Multiaddr
+
 	// PeerWatchInterval is the frequency that we use to watch for changes
 	// in the consensus peerset and save new peers to the configuration
 	// file. This also affects how soon we realize that we have
@@ -138,6 +204,158 @@ type Config struct {
 	// mDNS.
 	MDNSInterval time.Duration
 
+	// DedupStatsInterval controls how often statistics about block
+	// deduplication among the pins allocated to this peer are computed.
+	// Set to 0 to disable.
+	DedupStatsInterval time.Duration
+
+	// DedupStatsSampleSize is the maximum number of pins whose blocks are
+	// listed when computing deduplication statistics.
+	DedupStatsSampleSize int
+
+	// CapacityInterval controls how often the size of the IPFS
+	// repository is sampled to estimate the ingest rate reported in the
+	// capacity summary. Set to 0 to disable.
+	CapacityInterval time.Duration
+
+	// CapacityHistory is the number of repository size samples kept to
+	// estimate the ingest rate.
+	CapacityHistory int
+
+	// MetricsPrefetchFanout is the maximum number of peers that are
+	// asked for fresh informer metrics at the same time when a burst of
+	// allocations (i.e. a batch of pins) is detected. Set to 0 to disable
+	// and rely only on the metrics broadcasted by peers.
+	MetricsPrefetchFanout int
+
+	// StatusAllBatchSize is the number of peers queried at the same time
+	// when gathering the status of all pins. The results from every batch
+	// are sent as soon as it finishes, so the same item may be returned
+	// several times, each with the statuses from a different set of
+	// peers. Set to 0 to query all peers at once.
+	StatusAllBatchSize int
+
+	// StatusAllPeerTimeout is the maximum time given to every peer to
+	// send its status for all pins. Peers that take longer are reported
+	// with an error. Set to 0 to wait indefinitely.
+	StatusAllPeerTimeout time.Duration
+
+	// ShutdownTimeout is the maximum time given to the peer to stop
+	// all its components. Components which have not finished by then
+	// are not waited for, except Consensus, which is always allowed to
+	// finish persisting its state. Set to 0 to wait indefinitely.
+	ShutdownTimeout time.Duration
+
+	// ComponentShutdownTimeouts sets the maximum time given to
+	// individual components to shut down, within the overall
+	// ShutdownTimeout. Keys are "api", "consensus", "monitor",
+	// "ipfs_connector", "pin_tracker", "informer" and "tracer".
+	ComponentShutdownTimeouts map[string]time.Duration
+
+	// EventHistorySize is the maximum number of cluster events (pins,
+	// unpins, peers joining and leaving, leader changes...) kept by this
+	// peer. The oldest events are discarded first.
+	EventHistorySize int
+
+	// EventHistoryRetention is the maximum age of the cluster events
+	// kept by this peer. Set to 0 to keep events until EventHistorySize
+	// is reached.
+	EventHistoryRetention time.Duration
+
+	// StorageClasses can be selected by name when pinning. They set the
+	// default replication factors for the pin and the peer group among
+	// which it is allocated.
+	StorageClasses map[string]StorageClass
+
+	// NamespaceQuotas sets the maximum number of pins that each pin
+	// namespace can hold. Namespaces without an entry are not limited.
+	NamespaceQuotas map[string]int
+
+	// PinIndexes lists the pin fields that are indexed in memory so that
+	// pin listings filtered by them do not read the whole pinset. They
+	// can be "name", "tags", "origins" and "created".
+	PinIndexes []string
+
+	// PinSizeLimit is the maximum size, in bytes, of the DAG of a new
+	// pin. When set, the size is estimated with "dag stat" before
+	// committing the pin, and larger pins are rejected. 0 means no
+	// limit.
+	PinSizeLimit uint64
+
+	// UserPinSizeLimits sets a PinSizeLimit for the pins made by each
+	// API user (the owners of the pin). They take precedence over
+	// PinSizeLimit. The "*" entry applies to the users without a limit
+	// of their own.
+	UserPinSizeLimits map[string]uint64
+
+	// PinPreflightTimeout is the maximum time given to the estimation
+	// of the size of a pin. Pins whose size cannot be estimated in time
+	// are rejected.
+	PinPreflightTimeout time.Duration
+
+	// UniquePinNames rejects pins whose name is already used by a
+	// different CID in the same pin namespace.
+	UniquePinNames bool
+
+	// PinGCInterval controls how often the pin garbage collection
+	// policy is evaluated. Only one of the trusted peers evaluates it.
+	// Set to 0 to disable.
+	PinGCInterval time.Duration
+
+	// PinGCHighWater is the fraction of the storage of the cluster that
+	// can be used before pins are collected. Pins are then collected,
+	// least recently pinned first, until the usage is estimated to be
+	// under PinGCLowWater.
+	PinGCHighWater float64
+	PinGCLowWater  float64
+
+	// PinGCMaxTotalSize limits, in bytes, the storage of the cluster to
+	// which the water marks apply, when it is lower than the storage
+	// configured in the IPFS daemons. 0 means no limit.
+	PinGCMaxTotalSize uint64
+
+	// PinGCMinPinAge protects pins pinned (or re-pinned) more recently
+	// than this from being collected.
+	PinGCMinPinAge time.Duration
+
+	// PinGCMaxPinAge makes pins that were last pinned longer ago than
+	// this be collected regardless of the storage usage. 0 means no
+	// limit.
+	PinGCMaxPinAge time.Duration
+
+	// PinGCAutoUnpin makes the pin garbage collector unpin the pins that
+	// it selects. Otherwise they are only proposed: logged, and listed
+	// by the /pins/gc endpoint.
+	PinGCAutoUnpin bool
+
+	// PinSLOs sets service level objectives by storage class name. The
+	// "*" entry applies to the pins of any other class, or without one.
+	// Peers measure the time from pin submission until the item is
+	// pinned locally.
+	PinSLOs map[string]PinSLO
+
+	// PinSLOWindow is the rolling window over which the compliance
+	// with PinSLOs is computed.
+	PinSLOWindow time.Duration
+
+	// UnpinQuorum is the fraction (0-1) of the peers allocated to an
+	// unpinned item which must report it as unpinned before its global
+	// status is unpinned. Until then, the status shows the peers still
+	// unpinning it. 0 means the item is unpinned as soon as the unpin
+	// is committed to the shared state.
+	UnpinQuorum float64
+
+	// UnpinQuorumTimeout is how long the confirmations of an unpin are
+	// waited for before giving up and reporting the peers that did not
+	// confirm.
+	UnpinQuorumTimeout time.Duration
+
+	// TrashRetention enables the trash when set: unpinned items are
+	// kept pinned in the trash for this long, during which they can be
+	// restored, before they are actually unpinned. Unpinning an item
+	// which is already in the trash removes it right away.
+	TrashRetention time.Duration
+
 	// PinOnlyOnTrustedPeers limits allocations to trusted peers only.
 	PinOnlyOnTrustedPeers bool
 
@@ -152,6 +370,12 @@ type Config struct {
 	// operations (Pin/Unpin).
 	FollowerMode bool
 
+	// ArbiterMode makes this peer take part in the Raft consensus
+	// (voting and keeping the log) without running IPFS. Arbiters do
+	// not track any pins and are never allocated content. They allow
+	// two storage peers to keep a quorum when one of them fails.
+	ArbiterMode bool
+
 	// Peerstore file specifies the file on which we persist the
 	// libp2p host peerstore addresses. This file is regularly saved.
 	PeerstoreFile string
@@ -169,27 +393,62 @@ type Config struct {
 // saved using JSON. Most configuration keys are converted into simple types
 // like strings, and key names aim to be self-explanatory for the user.
 type configJSON struct {
-	ID                    string             `json:"id,omitempty"`
-	Peername              string             `json:"peername"`
-	PrivateKey            string             `json:"private_key,omitempty" hidden:"true"`
-	Secret                string             `json:"secret" hidden:"true"`
-	LeaveOnShutdown       bool               `json:"leave_on_shutdown"`
-	ListenMultiaddress    config.Strings     `json:"listen_multiaddress"`
-	EnableRelayHop        bool               `json:"enable_relay_hop"`
-	ConnectionManager     *connMgrConfigJSON `json:"connection_manager"`
-	DialPeerTimeout       string             `json:"dial_peer_timeout"`
-	StateSyncInterval     string             `json:"state_sync_interval"`
-	PinRecoverInterval    string             `json:"pin_recover_interval"`
-	ReplicationFactorMin  int                `json:"replication_factor_min"`
-	ReplicationFactorMax  int                `json:"replication_factor_max"`
-	MonitorPingInterval   string             `json:"monitor_ping_interval"`
-	PeerWatchInterval     string             `json:"peer_watch_interval"`
-	MDNSInterval          string             `json:"mdns_interval"`
-	PinOnlyOnTrustedPeers bool               `json:"pin_only_on_trusted_peers"`
-	DisableRepinning      bool               `json:"disable_repinning"`
-	FollowerMode          bool               `json:"follower_mode,omitempty"`
-	PeerstoreFile         string             `json:"peerstore_file,omitempty"`
-	PeerAddresses         []string           `json:"peer_addresses"`
+	ID                    string                  `json:"id,omitempty"`
+	Peername              string                  `json:"peername"`
+	PrivateKey            string                  `json:"private_key,omitempty" hidden:"true"`
+	Secret                string                  `json:"secret" hidden:"true"`
+	PrivateNetworkMode    string                  `json:"private_network_mode"`
+	LeaveOnShutdown       bool                    `json:"leave_on_shutdown"`
+	ListenMultiaddress    config.Strings          `json:"listen_multiaddress"`
+	EnableRelayHop        bool                    `json:"enable_relay_hop"`
+	ConnectionManager     *connMgrConfigJSON      `json:"connection_manager"`
+	DialPeerTimeout       string                  `json:"dial_peer_timeout"`
+	StateSyncInterval     string                  `json:"state_sync_interval"`
+	PinRecoverInterval    string                  `json:"pin_recover_interval"`
+	ReplicationFactorMin  int                     `json:"replication_factor_min"`
+	ReplicationFactorMax  int                     `json:"replication_factor_max"`
+	MonitorPingInterval   string                  `json:"monitor_ping_interval"`
+	APIAdvertiseAddresses config.Strings          `json:"api_advertise_addresses,omitempty"`
+	APIPublic             bool                    `json:"api_public,omitempty"`
+	HandoffTargets        config.Strings          `json:"handoff_targets,omitempty"`
+	PeerWatchInterval     string                  `json:"peer_watch_interval"`
+	MDNSInterval          string                  `json:"mdns_interval"`
+	DedupStatsInterval    string                  `json:"dedup_stats_interval"`
+	DedupStatsSampleSize  int                     `json:"dedup_stats_sample_size"`
+	CapacityInterval      string                  `json:"capacity_interval"`
+	CapacityHistory       int                     `json:"capacity_history"`
+	MetricsPrefetchFanout int                     `json:"metrics_prefetch_fanout"`
+	StatusAllBatchSize    int                     `json:"status_all_batch_size"`
+	StatusAllPeerTimeout  string                  `json:"status_all_peer_timeout"`
+	ShutdownTimeout       string                  `json:"shutdown_timeout"`
+	ShutdownTimeouts      map[string]string       `json:"component_shutdown_timeouts,omitempty"`
+	EventHistorySize      int                     `json:"event_history_size"`
+	EventHistoryRetention string                  `json:"event_history_retention"`
+	StorageClasses        map[string]StorageClass `json:"storage_classes,omitempty"`
+	NamespaceQuotas       map[string]int          `json:"namespace_quotas,omitempty"`
+	PinIndexes            []string                `json:"pin_indexes,omitempty"`
+	PinSizeLimit          uint64                  `json:"pin_size_limit"`
+	UserPinSizeLimits     map[string]uint64       `json:"user_pin_size_limits,omitempty"`
+	PinPreflightTimeout   string                  `json:"pin_preflight_timeout"`
+	PinGCInterval         string                  `json:"pin_gc_interval"`
+	PinGCHighWater        float64                 `json:"pin_gc_high_water"`
+	PinGCLowWater         float64                 `json:"pin_gc_low_water"`
+	PinGCMaxTotalSize     uint64                  `json:"pin_gc_max_total_size"`
+	PinGCMinPinAge        string                  `json:"pin_gc_min_pin_age"`
+	PinGCMaxPinAge        string                  `json:"pin_gc_max_pin_age"`
+	PinGCAutoUnpin        bool                    `json:"pin_gc_auto_unpin"`
+	PinSLOs               map[string]pinSLOJSON   `json:"pin_slos,omitempty"`
+	PinSLOWindow          string                  `json:"pin_slo_window"`
+	UnpinQuorum           float64                 `json:"unpin_quorum"`
+	UnpinQuorumTimeout    string                  `json:"unpin_quorum_timeout"`
+	TrashRetention        string                  `json:"trash_retention"`
+	UniquePinNames        bool                    `json:"unique_pin_names,omitempty"`
+	PinOnlyOnTrustedPeers bool                    `json:"pin_only_on_trusted_peers"`
+	DisableRepinning      bool                    `json:"disable_repinning"`
+	FollowerMode          bool                    `json:"follower_mode,omitempty"`
+	ArbiterMode           bool                    `json:"arbiter_mode,omitempty"`
+	PeerstoreFile         string                  `json:"peerstore_file,omitempty"`
+	PeerAddresses         []string                `json:"peer_addresses"`
 }
 
 // connMgrConfigJSON configures the libp2p host connection manager.
@@ -199,6 +458,11 @@ type connMgrConfigJSON struct {
 	GracePeriod string `json:"grace_period"`
 }
 
+type pinSLOJSON struct {
+	Target    string  `json:"target"`
+	Objective float64 `json:"objective"`
+}
+
 // ConfigKey returns a human-readable string to identify
 // a cluster Config.
 func (cfg *Config) ConfigKey() string {
@@ -251,6 +515,16 @@ func (cfg *Config) Validate() error {
 		return errors.New("cluster.listen_multiaddress is empty")
 	}
 
+	switch cfg.PrivateNetworkMode {
+	case PrivateNetworkPnet:
+	case PrivateNetworkTLSPSK:
+		if len(cfg.Secret) == 0 {
+			return errors.New("cluster.private_network_mode tls-psk needs a secret")
+		}
+	default:
+		return errors.New("cluster.private_network_mode should be pnet or tls-psk")
+	}
+
 	if cfg.ConnMgr.LowWater <= 0 {
 		return errors.New("cluster.connection_manager.low_water is invalid")
 	}
@@ -287,6 +561,59 @@ func (cfg *Config) Validate() error {
 		return errors.New("cluster.peer_watch_interval is invalid")
 	}
 
+	if cfg.DedupStatsInterval < 0 {
+		return errors.New("cluster.dedup_stats_interval is invalid")
+	}
+
+	if cfg.DedupStatsSampleSize <= 0 {
+		return errors.New("cluster.dedup_stats_sample_size is invalid")
+	}
+
+	if cfg.CapacityInterval < 0 {
+		return errors.New("cluster.capacity_interval is invalid")
+	}
+
+	if cfg.CapacityHistory < 2 {
+		return errors.New("cluster.capacity_history should be at least 2")
+	}
+
+	if cfg.MetricsPrefetchFanout < 0 {
+		return errors.New("cluster.metrics_prefetch_fanout is invalid")
+	}
+
+	if cfg.StatusAllBatchSize < 0 {
+		return errors.New("cluster.status_all_batch_size is invalid")
+	}
+
+	if cfg.StatusAllPeerTimeout < 0 {
+		return errors.New("cluster.status_all_peer_timeout is invalid")
+	}
+
+	if cfg.ShutdownTimeout < 0 {
+		return errors.New("cluster.shutdown_timeout is invalid")
+	}
+
+	if cfg.EventHistorySize <= 0 {
+		return errors.New("cluster.event_history_size should be positive")
+	}
+
+	if cfg.EventHistoryRetention < 0 {
+		return errors.New("cluster.event_history_retention is invalid")
+	}
+
+	for name, t := range cfg.ComponentShutdownTimeouts {
+		if !isShutdownComponent(name) {
+			return fmt.Errorf("cluster.component_shutdown_timeouts: unknown component %q", name)
+		}
+		if t < 0 {
+			return fmt.Errorf("cluster.component_shutdown_timeouts.%s is invalid", name)
+		}
+	}
+
+	if cfg.ArbiterMode && cfg.FollowerMode {
+		return errors.New("cluster.arbiter_mode and cluster.follower_mode cannot be both enabled")
+	}
+
 	rfMax := cfg.ReplicationFactorMax
 	rfMin := cfg.ReplicationFactorMin
 
@@ -294,6 +621,81 @@ func (cfg *Config) Validate() error {
 		return err
 	}
 
+	for name, sc := range cfg.StorageClasses {
+		if name == "" {
+			return errors.New("cluster.storage_classes: empty class name")
+		}
+		scMin, scMax := sc.ReplicationFactorMin, sc.ReplicationFactorMax
+		if scMin == 0 {
+			scMin = rfMin
+		}
+		if scMax == 0 {
+			scMax = rfMax
+		}
+		if err := isReplicationFactorValid(scMin, scMax); err != nil {
+			return fmt.Errorf("cluster.storage_classes.%s: %w", name, err)
+		}
+		if sc.PeerGroup != "" && scMax < 0 {
+			return fmt.Errorf("cluster.storage_classes.%s: a peer_group cannot be used when pinning everywhere", name)
+		}
+	}
+
+	if cfg.PinPreflightTimeout <= 0 {
+		return errors.New("cluster.pin_preflight_timeout is invalid")
+	}
+
+	if cfg.PinGCInterval < 0 {
+		return errors.New("cluster.pin_gc_interval is invalid")
+	}
+
+	if cfg.PinGCLowWater <= 0 || cfg.PinGCLowWater > cfg.PinGCHighWater || cfg.PinGCHighWater > 1 {
+		return errors.New("cluster.pin_gc_low_water and pin_gc_high_water should be 0 < low <= high <= 1")
+	}
+
+	if cfg.PinGCMinPinAge < 0 || cfg.PinGCMaxPinAge < 0 {
+		return errors.New("cluster.pin_gc_min_pin_age and pin_gc_max_pin_age cannot be negative")
+	}
+
+	for class, slo := range cfg.PinSLOs {
+		if slo.Target <= 0 {
+			return fmt.Errorf("cluster.pin_slos.%s: target is invalid", class)
+		}
+		if slo.Objective < 0 || slo.Objective > 1 {
+			return fmt.Errorf("cluster.pin_slos.%s: objective should be between 0 and 1", class)
+		}
+	}
+
+	if cfg.PinSLOWindow <= 0 {
+		return errors.New("cluster.pin_slo_window is invalid")
+	}
+
+	if cfg.UnpinQuorum < 0 || cfg.UnpinQuorum > 1 {
+		return errors.New("cluster.unpin_quorum should be between 0 and 1")
+	}
+
+	if cfg.UnpinQuorumTimeout <= 0 {
+		return errors.New("cluster.unpin_quorum_timeout is invalid")
+	}
+
+	if cfg.TrashRetention < 0 {
+		return errors.New("cluster.trash_retention is invalid")
+	}
+
+	for _, field := range cfg.PinIndexes {
+		if !isPinIndexField(field) {
+			return fmt.Errorf("cluster.pin_indexes: unknown field %q", field)
+		}
+	}
+
+	for ns, quota := range cfg.NamespaceQuotas {
+		if ns == "" {
+			return errors.New("cluster.namespace_quotas: empty namespace")
+		}
+		if quota <= 0 {
+			return fmt.Errorf("cluster.namespace_quotas.%s must be positive", ns)
+		}
+	}
+
 	return isRPCPolicyValid(cfg.RPCPolicy)
 }
 
@@ -356,6 +758,7 @@ func (cfg *Config) setDefaults() {
 		hostname = ""
 	}
 	cfg.Peername = hostname
+	cfg.PrivateNetworkMode = DefaultPrivateNetworkMode
 
 	listenAddrs := []ma.Multiaddr{}
 	for _, m := range DefaultListenAddrs {
@@ -376,11 +779,45 @@ func (cfg *Config) setDefaults() {
 	cfg.ReplicationFactorMin = DefaultReplicationFactor
 	cfg.ReplicationFactorMax = DefaultReplicationFactor
 	cfg.MonitorPingInterval = DefaultMonitorPingInterval
+	cfg.APIAdvertiseAddresses = nil
+	cfg.APIPublic = false
+	cfg.HandoffTargets = nil
 	cfg.PeerWatchInterval = DefaultPeerWatchInterval
 	cfg.MDNSInterval = DefaultMDNSInterval
+	cfg.DedupStatsInterval = DefaultDedupStatsInterval
+	cfg.DedupStatsSampleSize = DefaultDedupStatsSampleSize
+	cfg.CapacityInterval = DefaultCapacityInterval
+	cfg.CapacityHistory = DefaultCapacityHistory
+	cfg.MetricsPrefetchFanout = DefaultMetricsPrefetchFanout
+	cfg.StatusAllBatchSize = DefaultStatusAllBatchSize
+	cfg.StatusAllPeerTimeout = DefaultStatusAllPeerTimeout
+	cfg.ShutdownTimeout = DefaultShutdownTimeout
+	cfg.EventHistorySize = DefaultEventHistorySize
+	cfg.EventHistoryRetention = DefaultEventHistoryRetention
+	cfg.ComponentShutdownTimeouts = nil
+	cfg.StorageClasses = nil
+	cfg.NamespaceQuotas = nil
+	cfg.PinIndexes = nil
+	cfg.PinSizeLimit = DefaultPinSizeLimit
+	cfg.UserPinSizeLimits = nil
+	cfg.PinPreflightTimeout = DefaultPinPreflightTimeout
+	cfg.PinGCInterval = DefaultPinGCInterval
+	cfg.PinGCHighWater = DefaultPinGCHighWater
+	cfg.PinGCLowWater = DefaultPinGCLowWater
+	cfg.PinGCMaxTotalSize = DefaultPinGCMaxTotalSize
+	cfg.PinGCMinPinAge = DefaultPinGCMinPinAge
+	cfg.PinGCMaxPinAge = DefaultPinGCMaxPinAge
+	cfg.PinGCAutoUnpin = DefaultPinGCAutoUnpin
+	cfg.PinSLOs = nil
+	cfg.PinSLOWindow = DefaultPinSLOWindow
+	cfg.UnpinQuorum = DefaultUnpinQuorum
+	cfg.UnpinQuorumTimeout = DefaultUnpinQuorumTimeout
+	cfg.TrashRetention = DefaultTrashRetention
+	cfg.UniquePinNames = DefaultUniquePinNames
 	cfg.PinOnlyOnTrustedPeers = DefaultPinOnlyOnTrustedPeers
 	cfg.DisableRepinning = DefaultDisableRepinning
 	cfg.FollowerMode = DefaultFollowerMode
+	cfg.ArbiterMode = DefaultArbiterMode
 	cfg.PeerstoreFile = "" // empty so it gets omitted.
 	cfg.PeerAddresses = []ma.Multiaddr{}
 	cfg.RPCPolicy = DefaultRPCPolicy
@@ -413,6 +850,7 @@ func (cfg *Config) applyConfigJSON(jcfg *configJSON) error {
 		return err
 	}
 	cfg.Secret = clusterSecret
+	config.SetIfNotDefault(jcfg.PrivateNetworkMode, &cfg.PrivateNetworkMode)
 
 	var listenAddrs []ma.Multiaddr
 	for _, addr := range jcfg.ListenMultiaddress {
@@ -451,11 +889,45 @@ func (cfg *Config) applyConfigJSON(jcfg *configJSON) error {
 		&config.DurationOpt{Duration: jcfg.MonitorPingInterval, Dst: &cfg.MonitorPingInterval, Name: "monitor_ping_interval"},
 		&config.DurationOpt{Duration: jcfg.PeerWatchInterval, Dst: &cfg.PeerWatchInterval, Name: "peer_watch_interval"},
 		&config.DurationOpt{Duration: jcfg.MDNSInterval, Dst: &cfg.MDNSInterval, Name: "mdns_interval"},
+		&config.DurationOpt{Duration: jcfg.DedupStatsInterval, Dst: &cfg.DedupStatsInterval, Name: "dedup_stats_interval"},
+		&config.DurationOpt{Duration: jcfg.CapacityInterval, Dst: &cfg.CapacityInterval, Name: "capacity_interval"},
+		&config.DurationOpt{Duration: jcfg.StatusAllPeerTimeout, Dst: &cfg.StatusAllPeerTimeout, Name: "status_all_peer_timeout"},
+		&config.DurationOpt{Duration: jcfg.ShutdownTimeout, Dst: &cfg.ShutdownTimeout, Name: "shutdown_timeout"},
+		&config.DurationOpt{Duration: jcfg.EventHistoryRetention, Dst: &cfg.EventHistoryRetention, Name: "event_history_retention"},
+		&config.DurationOpt{Duration: jcfg.PinPreflightTimeout, Dst: &cfg.PinPreflightTimeout, Name: "pin_preflight_timeout"},
+		&config.DurationOpt{Duration: jcfg.PinGCInterval, Dst: &cfg.PinGCInterval, Name: "pin_gc_interval"},
+		&config.DurationOpt{Duration: jcfg.PinGCMinPinAge, Dst: &cfg.PinGCMinPinAge, Name: "pin_gc_min_pin_age"},
+		&config.DurationOpt{Duration: jcfg.PinGCMaxPinAge, Dst: &cfg.PinGCMaxPinAge, Name: "pin_gc_max_pin_age"},
+		&config.DurationOpt{Duration: jcfg.PinSLOWindow, Dst: &cfg.PinSLOWindow, Name: "pin_slo_window"},
+		&config.DurationOpt{Duration: jcfg.UnpinQuorumTimeout, Dst: &cfg.UnpinQuorumTimeout, Name: "unpin_quorum_timeout"},
+		&config.DurationOpt{Duration: jcfg.TrashRetention, Dst: &cfg.TrashRetention, Name: "trash_retention"},
 	)
 	if err != nil {
 		return err
 	}
 
+	if len(jcfg.ShutdownTimeouts) > 0 {
+		cfg.ComponentShutdownTimeouts = make(map[string]time.Duration, len(jcfg.ShutdownTimeouts))
+		for name, v := range jcfg.ShutdownTimeouts {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("error parsing cluster.component_shutdown_timeouts.%s: %s", name, err)
+			}
+			cfg.ComponentShutdownTimeouts[name] = d
+		}
+	}
+
+	if len(jcfg.PinSLOs) > 0 {
+		cfg.PinSLOs = make(map[string]PinSLO, len(jcfg.PinSLOs))
+		for class, slo := range jcfg.PinSLOs {
+			d, err := time.ParseDuration(slo.Target)
+			if err != nil {
+				return fmt.Errorf("error parsing cluster.pin_slos.%s.target: %s", class, err)
+			}
+			cfg.PinSLOs[class] = PinSLO{Target: d, Objective: slo.Objective}
+		}
+	}
+
 	// PeerAddresses
 	peerAddrs := []ma.Multiaddr{}
 	for _, addr := range jcfg.PeerAddresses {
@@ -467,10 +939,50 @@ func (cfg *Config) applyConfigJSON(jcfg *configJSON) error {
 		peerAddrs = append(peerAddrs, peerAddr)
 	}
 	cfg.PeerAddresses = peerAddrs
+
+	var apiAddrs []ma.Multiaddr
+	for _, addr := range jcfg.APIAdvertiseAddresses {
+		apiAddr, err := ma.NewMultiaddr(addr)
+		if err != nil {
+			err = fmt.Errorf("error parsing api_advertise_addresses: %s", err)
+			return err
+		}
+		apiAddrs = append(apiAddrs, apiAddr)
+	}
+	cfg.APIAdvertiseAddresses = apiAddrs
+	cfg.APIPublic = jcfg.APIPublic
+
+	var handoffTargets []ma.Multiaddr
+	for _, addr := range jcfg.HandoffTargets {
+		target, err := ma.NewMultiaddr(addr)
+		if err != nil {
+			err = fmt.Errorf("error parsing handoff_targets: %s", err)
+			return err
+		}
+		handoffTargets = append(handoffTargets, target)
+	}
+	cfg.HandoffTargets = handoffTargets
+	config.SetIfNotDefault(jcfg.DedupStatsSampleSize, &cfg.DedupStatsSampleSize)
+	config.SetIfNotDefault(jcfg.CapacityHistory, &cfg.CapacityHistory)
+	config.SetIfNotDefault(jcfg.EventHistorySize, &cfg.EventHistorySize)
+	cfg.MetricsPrefetchFanout = jcfg.MetricsPrefetchFanout
+	cfg.StatusAllBatchSize = jcfg.StatusAllBatchSize
 	cfg.LeaveOnShutdown = jcfg.LeaveOnShutdown
+	cfg.StorageClasses = jcfg.StorageClasses
+	cfg.NamespaceQuotas = jcfg.NamespaceQuotas
+	cfg.PinIndexes = jcfg.PinIndexes
+	cfg.UnpinQuorum = jcfg.UnpinQuorum
+	cfg.PinSizeLimit = jcfg.PinSizeLimit
+	cfg.UserPinSizeLimits = jcfg.UserPinSizeLimits
+	config.SetIfNotDefault(jcfg.PinGCHighWater, &cfg.PinGCHighWater)
+	config.SetIfNotDefault(jcfg.PinGCLowWater, &cfg.PinGCLowWater)
+	cfg.PinGCMaxTotalSize = jcfg.PinGCMaxTotalSize
+	cfg.PinGCAutoUnpin = jcfg.PinGCAutoUnpin
+	cfg.UniquePinNames = jcfg.UniquePinNames
 	cfg.PinOnlyOnTrustedPeers = jcfg.PinOnlyOnTrustedPeers
 	cfg.DisableRepinning = jcfg.DisableRepinning
 	cfg.FollowerMode = jcfg.FollowerMode
+	cfg.ArbiterMode = jcfg.ArbiterMode
 
 	return cfg.Validate()
 }
@@ -486,6 +998,12 @@ func (cfg *Config) ToJSON() (raw []byte, err error) {
 	return
 }
 
+// JSONKeys returns the keys of the JSON representation of this
+// configuration, including those omitted when empty.
+func (cfg *Config) JSONKeys() []string {
+	return config.JSONKeys(configJSON{})
+}
+
 func (cfg *Config) toConfigJSON() (jcfg *configJSON, err error) {
 	// Multiaddress String() may panic
 	defer func() {
@@ -499,6 +1017,7 @@ func (cfg *Config) toConfigJSON() (jcfg *configJSON, err error) {
 	// Set all configuration fields
 	jcfg.Peername = cfg.Peername
 	jcfg.Secret = EncodeProtectorKey(cfg.Secret)
+	jcfg.PrivateNetworkMode = cfg.PrivateNetworkMode
 	jcfg.ReplicationFactorMin = cfg.ReplicationFactorMin
 	jcfg.ReplicationFactorMax = cfg.ReplicationFactorMax
 	jcfg.LeaveOnShutdown = cfg.LeaveOnShutdown
@@ -517,8 +1036,55 @@ func (cfg *Config) toConfigJSON() (jcfg *configJSON, err error) {
 	jcfg.StateSyncInterval = cfg.StateSyncInterval.String()
 	jcfg.PinRecoverInterval = cfg.PinRecoverInterval.String()
 	jcfg.MonitorPingInterval = cfg.MonitorPingInterval.String()
+	for _, addr := range cfg.APIAdvertiseAddresses {
+		jcfg.APIAdvertiseAddresses = append(jcfg.APIAdvertiseAddresses, addr.String())
+	}
+	jcfg.APIPublic = cfg.APIPublic
+	for _, addr := range cfg.HandoffTargets {
+		jcfg.HandoffTargets = append(jcfg.HandoffTargets, addr.String())
+	}
 	jcfg.PeerWatchInterval = cfg.PeerWatchInterval.String()
 	jcfg.MDNSInterval = cfg.MDNSInterval.String()
+	jcfg.DedupStatsInterval = cfg.DedupStatsInterval.String()
+	jcfg.DedupStatsSampleSize = cfg.DedupStatsSampleSize
+	jcfg.CapacityInterval = cfg.CapacityInterval.String()
+	jcfg.CapacityHistory = cfg.CapacityHistory
+	jcfg.MetricsPrefetchFanout = cfg.MetricsPrefetchFanout
+	jcfg.StatusAllBatchSize = cfg.StatusAllBatchSize
+	jcfg.StatusAllPeerTimeout = cfg.StatusAllPeerTimeout.String()
+	jcfg.ShutdownTimeout = cfg.ShutdownTimeout.String()
+	if len(cfg.ComponentShutdownTimeouts) > 0 {
+		jcfg.ShutdownTimeouts = make(map[string]string, len(cfg.ComponentShutdownTimeouts))
+		for name, d := range cfg.ComponentShutdownTimeouts {
+			jcfg.ShutdownTimeouts[name] = d.String()
+		}
+	}
+	jcfg.EventHistorySize = cfg.EventHistorySize
+	jcfg.EventHistoryRetention = cfg.EventHistoryRetention.String()
+	jcfg.StorageClasses = cfg.StorageClasses
+	jcfg.NamespaceQuotas = cfg.NamespaceQuotas
+	jcfg.PinIndexes = cfg.PinIndexes
+	jcfg.PinSizeLimit = cfg.PinSizeLimit
+	jcfg.UserPinSizeLimits = cfg.UserPinSizeLimits
+	jcfg.PinPreflightTimeout = cfg.PinPreflightTimeout.String()
+	jcfg.PinGCInterval = cfg.PinGCInterval.String()
+	jcfg.PinGCHighWater = cfg.PinGCHighWater
+	jcfg.PinGCLowWater = cfg.PinGCLowWater
+	jcfg.PinGCMaxTotalSize = cfg.PinGCMaxTotalSize
+	jcfg.PinGCMinPinAge = cfg.PinGCMinPinAge.String()
+	jcfg.PinGCMaxPinAge = cfg.PinGCMaxPinAge.String()
+	jcfg.PinGCAutoUnpin = cfg.PinGCAutoUnpin
+	if len(cfg.PinSLOs) > 0 {
+		jcfg.PinSLOs = make(map[string]pinSLOJSON, len(cfg.PinSLOs))
+		for class, slo := range cfg.PinSLOs {
+			jcfg.PinSLOs[class] = pinSLOJSON{Target: slo.Target.String(), Objective: slo.Objective}
+		}
+	}
+	jcfg.PinSLOWindow = cfg.PinSLOWindow.String()
+	jcfg.UnpinQuorum = cfg.UnpinQuorum
+	jcfg.UnpinQuorumTimeout = cfg.UnpinQuorumTimeout.String()
+	jcfg.TrashRetention = cfg.TrashRetention.String()
+	jcfg.UniquePinNames = cfg.UniquePinNames
 	jcfg.PinOnlyOnTrustedPeers = cfg.PinOnlyOnTrustedPeers
 	jcfg.DisableRepinning = cfg.DisableRepinning
 	jcfg.PeerstoreFile = cfg.PeerstoreFile
@@ -527,6 +1093,7 @@ func (cfg *Config) toConfigJSON() (jcfg *configJSON, err error) {
 		jcfg.PeerAddresses = append(jcfg.PeerAddresses, addr.String())
 	}
 	jcfg.FollowerMode = cfg.FollowerMode
+	jcfg.ArbiterMode = cfg.ArbiterMode
 
 	return
 }
@@ -123,6 +123,189 @@ func TestLoadJSON(t *testing.T) {
 		return cfg, nil
 	}
 
+	t.Run("expected storage classes", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.StorageClasses = map[string]StorageClass{
+				"cold": {PeerGroup: "archive", ReplicationFactorMin: 2, ReplicationFactorMax: 3},
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sc := cfg.StorageClasses["cold"]; sc.PeerGroup != "archive" || sc.ReplicationFactorMax != 3 {
+			t.Errorf("unexpected storage class: %+v", sc)
+		}
+	})
+
+	t.Run("namespace quotas", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.NamespaceQuotas = map[string]int{"team-a": 100}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.NamespaceQuotas["team-a"] != 100 {
+			t.Errorf("unexpected namespace quotas: %v", cfg.NamespaceQuotas)
+		}
+	})
+
+	t.Run("unique pin names", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.UniquePinNames = true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cfg.UniquePinNames {
+			t.Error("expected unique pin names")
+		}
+	})
+
+	t.Run("shutdown timeouts", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.ShutdownTimeout = "1m"
+			j.ShutdownTimeouts = map[string]string{"consensus": "30s"}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.ShutdownTimeout != time.Minute {
+			t.Error("expected shutdown_timeout of 1m")
+		}
+		if cfg.ComponentShutdownTimeouts["consensus"] != 30*time.Second {
+			t.Error("expected consensus shutdown timeout of 30s")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) {
+			j.ShutdownTimeouts = map[string]string{"consensus": "abc"}
+		})
+		if err == nil {
+			t.Error("expected error parsing the component shutdown timeout")
+		}
+	})
+
+	t.Run("private network mode", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) { j.PrivateNetworkMode = PrivateNetworkTLSPSK })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.PrivateNetworkMode != PrivateNetworkTLSPSK {
+			t.Error("expected tls-psk private network mode")
+		}
+	})
+
+	t.Run("event history", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.EventHistorySize = 100
+			j.EventHistoryRetention = "0s"
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.EventHistorySize != 100 || cfg.EventHistoryRetention != 0 {
+			t.Errorf("unexpected event history config: %d, %s", cfg.EventHistorySize, cfg.EventHistoryRetention)
+		}
+	})
+
+	t.Run("pin size limits", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.PinSizeLimit = 1024
+			j.UserPinSizeLimits = map[string]uint64{"alice": 2048}
+			j.PinPreflightTimeout = "10s"
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.PinSizeLimit != 1024 || cfg.UserPinSizeLimits["alice"] != 2048 {
+			t.Error("unexpected pin size limits")
+		}
+		if cfg.PinPreflightTimeout != 10*time.Second {
+			t.Error("unexpected pin preflight timeout")
+		}
+	})
+
+	t.Run("pin gc", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.PinGCInterval = "1h"
+			j.PinGCHighWater = 0.7
+			j.PinGCLowWater = 0.6
+			j.PinGCMaxPinAge = "720h"
+			j.PinGCAutoUnpin = true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.PinGCInterval != time.Hour || cfg.PinGCHighWater != 0.7 || cfg.PinGCLowWater != 0.6 {
+			t.Error("unexpected pin gc water marks")
+		}
+		if cfg.PinGCMinPinAge != DefaultPinGCMinPinAge || cfg.PinGCMaxPinAge != 720*time.Hour || !cfg.PinGCAutoUnpin {
+			t.Error("unexpected pin gc config")
+		}
+	})
+
+	t.Run("pin slos", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.PinSLOs = map[string]pinSLOJSON{
+				"gold": {Target: "5m", Objective: 0.99},
+				"*":    {Target: "1h"},
+			}
+			j.PinSLOWindow = "168h"
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slo := cfg.PinSLOs["gold"]; slo.Target != 5*time.Minute || slo.Objective != 0.99 {
+			t.Error("unexpected gold slo:", slo)
+		}
+		if cfg.PinSLOs["*"].Target != time.Hour || cfg.PinSLOWindow != 168*time.Hour {
+			t.Error("unexpected pin slo config")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) {
+			j.PinSLOs = map[string]pinSLOJSON{"gold": {Target: "abc"}}
+		})
+		if err == nil {
+			t.Error("expected error parsing pin_slos target")
+		}
+	})
+
+	t.Run("unpin quorum", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.UnpinQuorum = 0.5
+			j.UnpinQuorumTimeout = "10m"
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.UnpinQuorum != 0.5 || cfg.UnpinQuorumTimeout != 10*time.Minute {
+			t.Error("unexpected unpin quorum config")
+		}
+	})
+
+	t.Run("trash retention", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.TrashRetention = "48h"
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.TrashRetention != 48*time.Hour {
+			t.Error("unexpected trash retention")
+		}
+	})
+
+	t.Run("pin indexes", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.PinIndexes = []string{"name", "tags", "origins", "created"}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cfg.PinIndexes) != 4 {
+			t.Error("unexpected pin indexes:", cfg.PinIndexes)
+		}
+	})
+
 	t.Run("empty default peername", func(t *testing.T) {
 		cfg, err := loadJSON2(t, func(j *configJSON) { j.Peername = "" })
 		if err != nil {
@@ -199,6 +382,45 @@ func TestLoadJSON(t *testing.T) {
 		}
 	})
 
+	t.Run("expected api advertisement", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.APIAdvertiseAddresses = []string{"/dns4/cluster.example.org/tcp/443/https"}
+			j.APIPublic = true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cfg.APIAdvertiseAddresses) != 1 || !cfg.APIPublic {
+			t.Error("expected one public api advertise address")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) {
+			j.APIAdvertiseAddresses = []string{"abc"}
+		})
+		if err == nil {
+			t.Error("expected error parsing api_advertise_addresses")
+		}
+	})
+
+	t.Run("expected handoff targets", func(t *testing.T) {
+		cfg, err := loadJSON2(t, func(j *configJSON) {
+			j.HandoffTargets = []string{"/dns4/archive.example.org/tcp/9094"}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cfg.HandoffTargets) != 1 {
+			t.Error("expected one handoff target")
+		}
+
+		_, err = loadJSON2(t, func(j *configJSON) {
+			j.HandoffTargets = []string{"abc"}
+		})
+		if err == nil {
+			t.Error("expected error parsing handoff_targets")
+		}
+	})
+
 	t.Run("conn manager default", func(t *testing.T) {
 		cfg, err := loadJSON2(
 			t,
@@ -278,9 +500,117 @@ func TestValidate(t *testing.T) {
 		t.Fatal("expected error validating")
 	}
 
+	cfg.Default()
+	cfg.ArbiterMode = true
+	cfg.FollowerMode = true
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
 	cfg.Default()
 	cfg.PinRecoverInterval = 0
 	if cfg.Validate() == nil {
 		t.Fatal("expected error validating")
 	}
+
+	cfg.Default()
+	cfg.CapacityHistory = 1
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.ShutdownTimeout = -1
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.ComponentShutdownTimeouts = map[string]time.Duration{"unknown": time.Second}
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.PrivateNetworkMode = "none"
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.PrivateNetworkMode = PrivateNetworkTLSPSK
+	cfg.Secret = nil
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.EventHistorySize = 0
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.EventHistoryRetention = -1
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.PinPreflightTimeout = 0
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.PinGCLowWater = 0.95
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.PinSLOs = map[string]PinSLO{"gold": {Target: time.Minute, Objective: 1.5}}
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.PinIndexes = []string{"tags", "metadata"}
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.UnpinQuorum = 1.5
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.TrashRetention = -time.Hour
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.StorageClasses = map[string]StorageClass{
+		"cold": {ReplicationFactorMin: 3, ReplicationFactorMax: 2},
+	}
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.StorageClasses = map[string]StorageClass{
+		"cold": {PeerGroup: "archive"}, // pins everywhere by default
+	}
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.NamespaceQuotas = map[string]int{"team-a": 0}
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
 }
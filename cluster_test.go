@@ -3,6 +3,7 @@ package ipfscluster
 import (
 	"context"
 	"errors"
+	"fmt"
 	"mime/multipart"
 	"os"
 	"path/filepath"
@@ -13,7 +14,9 @@ import (
 	"github.com/ipfs-cluster/ipfs-cluster/adder/sharding"
 	"github.com/ipfs-cluster/ipfs-cluster/allocator/balanced"
 	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/api/rest/client"
 	"github.com/ipfs-cluster/ipfs-cluster/config"
+	"github.com/ipfs-cluster/ipfs-cluster/datastore/inmem"
 	"github.com/ipfs-cluster/ipfs-cluster/informer/numpin"
 	"github.com/ipfs-cluster/ipfs-cluster/monitor/pubsubmon"
 	"github.com/ipfs-cluster/ipfs-cluster/pintracker/stateless"
@@ -24,6 +27,9 @@ import (
 	gopath "github.com/ipfs/boxo/path"
 	rpc "github.com/libp2p/go-libp2p-gorpc"
 	peer "github.com/libp2p/go-libp2p/core/peer"
+	peerstore "github.com/libp2p/go-libp2p/core/peerstore"
+	protocol "github.com/libp2p/go-libp2p/core/protocol"
+	ma "github.com/multiformats/go-multiaddr"
 )
 
 type mockComponent struct {
@@ -90,6 +96,10 @@ func (ipfs *mockConnector) PinLsCid(ctx context.Context, pin api.Pin) (api.IPFSP
 	return api.IPFSPinStatusRecursive, nil
 }
 
+func (ipfs *mockConnector) PinProgress(ctx context.Context, c api.Cid) (api.PinProgress, error) {
+	return api.PinProgress{}, nil
+}
+
 func (ipfs *mockConnector) PinLs(ctx context.Context, in []string, out chan<- api.IPFSPinInfo) error {
 	defer close(out)
 
@@ -137,6 +147,7 @@ func (ipfs *mockConnector) Resolve(ctx context.Context, path string) (api.Cid, e
 	return test.CidResolved, nil
 }
 func (ipfs *mockConnector) ConnectSwarms(ctx context.Context) error       { return nil }
+func (ipfs *mockConnector) Reprovide(ctx context.Context) error           { return nil }
 func (ipfs *mockConnector) ConfigKey(keypath string) (interface{}, error) { return nil, nil }
 
 func (ipfs *mockConnector) BlockStream(ctx context.Context, in <-chan api.NodeWithMeta) error {
@@ -154,14 +165,50 @@ func (ipfs *mockConnector) BlockGet(ctx context.Context, c api.Cid) ([]byte, err
 	return d.([]byte), nil
 }
 
+func (ipfs *mockConnector) BlockStat(ctx context.Context, c api.Cid) (api.IPFSBlockStat, error) {
+	d, ok := ipfs.blocks.Load(c.String())
+	if !ok {
+		return api.IPFSBlockStat{Key: c, Size: 1024}, nil
+	}
+	return api.IPFSBlockStat{Key: c, Size: len(d.([]byte))}, nil
+}
+
+func (ipfs *mockConnector) DagStat(ctx context.Context, pin api.Pin) (api.IPFSDagStat, error) {
+	// Like Refs, all pins have a root and two blocks.
+	return api.IPFSDagStat{Size: 3 * 1024, NumBlocks: 3}, nil
+}
+
+func (ipfs *mockConnector) Refs(ctx context.Context, c api.Cid, maxRefs int) ([]api.Cid, error) {
+	// All pins share the same two blocks (besides their root).
+	refs := []api.Cid{test.Cid4, test.Cid5}
+	if maxRefs > 0 && len(refs) > maxRefs {
+		refs = refs[:maxRefs]
+	}
+	return refs, nil
+}
+
+func (ipfs *mockConnector) VerifyBlocks(ctx context.Context, in api.IPFSBlockVerification) (api.IPFSBlockVerification, error) {
+	return api.IPFSBlockVerification{Cid: in.Cid, Sample: in.Sample, Checked: 1}, nil
+}
+
 type mockTracer struct {
 	mockComponent
 }
 
 func testingCluster(t *testing.T) (*Cluster, *mockAPI, *mockConnector, PinTracker) {
+	return testingClusterWithConfig(t, nil)
+}
+
+// testingClusterWithConfig works like testingCluster but lets modify the
+// cluster configuration before creating it.
+func testingClusterWithConfig(t *testing.T, modifyCfg func(*Config)) (*Cluster, *mockAPI, *mockConnector, PinTracker) {
 	ident, clusterCfg, _, _, _, badgerCfg, badger3Cfg, levelDBCfg, pebbleCfg, raftCfg, crdtCfg, statelesstrackerCfg, psmonCfg, _, _, _ := testingConfigs()
 	ctx := context.Background()
 
+	if modifyCfg != nil {
+		modifyCfg(clusterCfg)
+	}
+
 	host, pubsub, dht := createHost(t, ident.PrivateKey, clusterCfg.Secret, clusterCfg.ListenAddr)
 
 	folder := filepath.Join(testsFolder, host.ID().Pretty())
@@ -253,6 +300,59 @@ func TestClusterShutdown(t *testing.T) {
 	cleanState()
 }
 
+// hangingTracer does not finish shutting down until released.
+type hangingTracer struct {
+	Tracer
+	release chan struct{}
+}
+
+func (ht *hangingTracer) Shutdown(ctx context.Context) error {
+	<-ht.release
+	return nil
+}
+
+func TestClusterShutdownTimeouts(t *testing.T) {
+	ctx := context.Background()
+	defer cleanState()
+
+	t.Run("component timeout", func(t *testing.T) {
+		cl, _, _, _ := testingClusterWithConfig(t, func(cfg *Config) {
+			cfg.ComponentShutdownTimeouts = map[string]time.Duration{
+				"tracer": 100 * time.Millisecond,
+			}
+		})
+		ht := &hangingTracer{Tracer: cl.tracer, release: make(chan struct{})}
+		defer close(ht.release)
+		cl.tracer = ht
+
+		start := time.Now()
+		shutdownTestingCluster(ctx, t, cl)
+		if d := time.Since(start); d > 5*time.Second {
+			t.Errorf("shutdown should not have waited for the tracer (took %s)", d)
+		}
+		select {
+		case <-cl.Done():
+		default:
+			t.Error("cluster should be done")
+		}
+	})
+
+	t.Run("overall timeout", func(t *testing.T) {
+		cl, _, _, _ := testingClusterWithConfig(t, func(cfg *Config) {
+			cfg.ShutdownTimeout = 200 * time.Millisecond
+		})
+		ht := &hangingTracer{Tracer: cl.tracer, release: make(chan struct{})}
+		defer close(ht.release)
+		cl.tracer = ht
+
+		start := time.Now()
+		shutdownTestingCluster(ctx, t, cl)
+		if d := time.Since(start); d > 5*time.Second {
+			t.Errorf("shutdown should not have waited for the tracer (took %s)", d)
+		}
+	})
+}
+
 func TestClusterStateSync(t *testing.T) {
 	ctx := context.Background()
 	cl, _, _, _ := testingCluster(t)
@@ -283,6 +383,44 @@ func TestClusterStateSync(t *testing.T) {
 	}
 }
 
+func TestClusterArbiterMode(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingClusterWithConfig(t, func(cfg *Config) {
+		cfg.ArbiterMode = true
+	})
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	c := test.Cid1
+	_, err := cl.Pin(ctx, c, api.PinOptions{})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	pinDelay()
+
+	pinfo := cl.StatusLocal(ctx, c)
+	if pinfo.Status != api.TrackerStatusRemote {
+		t.Error("arbiters should see all pins as remote:", pinfo.Status)
+	}
+
+	out := make(chan api.PinInfo, 10)
+	err = cl.StatusAllLocal(ctx, api.TrackerStatusUndefined, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collectPinInfos(t, out)) != 0 {
+		t.Error("arbiters should not report any status")
+	}
+
+	if len(cl.InformerMetricsLocal(ctx)) != 0 {
+		t.Error("arbiters should not produce informer metrics")
+	}
+
+	if id := cl.ID(ctx); id.IPFS.Error == "" {
+		t.Error("arbiters should not report an IPFS daemon")
+	}
+}
+
 func TestClusterID(t *testing.T) {
 	ctx := context.Background()
 	cl, _, _, _ := testingCluster(t)
@@ -298,6 +436,9 @@ func TestClusterID(t *testing.T) {
 	if id.Version != version.Version.String() {
 		t.Error("version should match current version")
 	}
+	if id.Consensus == nil || id.Consensus.Role == "" {
+		t.Error("expected the consensus role")
+	}
 	//if id.PublicKey == nil {
 	//	t.Error("publicKey should not be empty")
 	//}
@@ -336,6 +477,173 @@ func TestClusterPin(t *testing.T) {
 	}
 }
 
+func TestClusterPinNamespaces(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	cl.config.NamespaceQuotas = map[string]int{"team-a": 1}
+
+	_, err := cl.Pin(ctx, test.Cid1, api.PinOptions{Namespace: "team-a"})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+
+	_, err = cl.Pin(ctx, test.Cid2, api.PinOptions{Namespace: "team-a"})
+	if err == nil {
+		t.Error("expected an error as the namespace is full")
+	}
+
+	_, err = cl.Pin(ctx, test.Cid2, api.PinOptions{Namespace: "team-b"})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+
+	// re-pinning does not count against the quota and keeps the
+	// namespace.
+	_, err = cl.Pin(ctx, test.Cid1, api.PinOptions{Name: "renamed"})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	pin, err := cl.PinGet(ctx, test.Cid1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin.Namespace != "team-a" || pin.Name != "renamed" {
+		t.Errorf("unexpected pin: %+v", pin)
+	}
+
+	_, err = cl.Pin(ctx, test.Cid1, api.PinOptions{Namespace: "team-b"})
+	if err == nil {
+		t.Error("expected an error moving a pin to another namespace")
+	}
+}
+
+func TestClusterPinSizeLimits(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	cl.config.PinSizeLimit = 2 * 1024
+	cl.config.UserPinSizeLimits = map[string]uint64{"alice": 4 * 1024}
+
+	_, err := cl.Pin(ctx, test.Cid1, api.PinOptions{})
+	if !errors.Is(err, api.ErrPinTooLarge) {
+		t.Error("expected the pin to be too large:", err)
+	}
+
+	_, err = cl.Pin(ctx, test.Cid1, api.PinOptions{Owners: []string{"bob"}})
+	if !errors.Is(err, api.ErrPinTooLarge) {
+		t.Error("expected the pin to be too large:", err)
+	}
+
+	_, err = cl.Pin(ctx, test.Cid1, api.PinOptions{Owners: []string{"alice"}})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+
+	// existing pins are not checked again
+	_, err = cl.Pin(ctx, test.Cid1, api.PinOptions{Owners: []string{"bob"}})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+
+	cl.config.PinSizeLimit = 0
+	_, err = cl.Pin(ctx, test.Cid2, api.PinOptions{})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+}
+
+func TestClusterUniquePinNames(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	cl.config.UniquePinNames = true
+
+	_, err := cl.Pin(ctx, test.Cid1, api.PinOptions{Name: "photos", Namespace: "team-a"})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+
+	_, err = cl.Pin(ctx, test.Cid2, api.PinOptions{Name: "photos", Namespace: "team-a"})
+	if !errors.Is(err, api.ErrPinNameConflict) {
+		t.Error("expected a name conflict:", err)
+	}
+
+	// names are unique per namespace
+	_, err = cl.Pin(ctx, test.Cid2, api.PinOptions{Name: "photos", Namespace: "team-b"})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+
+	// re-pinning keeps the name
+	_, err = cl.Pin(ctx, test.Cid1, api.PinOptions{Name: "photos", Namespace: "team-a"})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+
+	// an update takes the name of the pin it updates
+	_, err = cl.PinUpdate(ctx, test.Cid1, test.Cid3, api.PinOptions{})
+	if err != nil {
+		t.Fatal("pin update should have worked:", err)
+	}
+}
+
+func TestClusterPinOwners(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	// let the replay of the intent log at startup finish, as it
+	// could re-submit one of the pins below.
+	pinDelay()
+
+	_, err := cl.Pin(ctx, test.Cid1, api.PinOptions{Owners: []string{"alice"}})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	_, err = cl.Pin(ctx, test.Cid1, api.PinOptions{Owners: []string{"bob"}})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+
+	pin, err := cl.unref(ctx, test.Cid1, []string{"alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pin.Owners) != 1 || pin.Owners[0] != "bob" {
+		t.Errorf("unexpected owners: %s", pin.Owners)
+	}
+
+	// carol holds no reference
+	_, err = cl.unref(ctx, test.Cid1, []string{"carol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pin, err = cl.PinGet(ctx, test.Cid1)
+	if err != nil {
+		t.Fatal("the pin should still be referenced by bob:", err)
+	}
+	if len(pin.Owners) != 1 || pin.Owners[0] != "bob" {
+		t.Errorf("unexpected owners: %s", pin.Owners)
+	}
+
+	_, err = cl.unref(ctx, test.Cid1, []string{"bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cl.PinGet(ctx, test.Cid1)
+	if err != state.ErrNotFound {
+		t.Error("the pin should be gone with its last reference:", err)
+	}
+}
+
 func TestPinExpired(t *testing.T) {
 	ctx := context.Background()
 	cl, _, _, _ := testingCluster(t)
@@ -372,6 +680,142 @@ func TestClusterPinPath(t *testing.T) {
 	}
 }
 
+func TestClusterSchedulePin(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	_, err := cl.SchedulePin(ctx, api.PinSchedule{Path: test.PathIPFS2})
+	if err == nil {
+		t.Error("expected an error without not-before time nor cron expression")
+	}
+	_, err = cl.SchedulePin(ctx, api.PinSchedule{Path: test.PathIPFS2, Cron: "* * *"})
+	if err == nil {
+		t.Error("expected an error with an invalid cron expression")
+	}
+	_, err = cl.SchedulePin(ctx, api.PinSchedule{Path: test.InvalidPath1, NotBefore: time.Now()})
+	if err == nil {
+		t.Error("expected an error with an invalid path")
+	}
+
+	once, err := cl.SchedulePin(ctx, api.PinSchedule{
+		Path:      test.PathIPFS2,
+		Options:   api.PinOptions{Name: "later"},
+		NotBefore: time.Now().Add(200 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if once.ID == "" || once.Peer != cl.id || once.Next.Before(once.NotBefore) {
+		t.Errorf("unexpected schedule: %+v", once)
+	}
+	_, err = cl.PinGet(ctx, test.CidResolved)
+	if err == nil {
+		t.Fatal("the item should not be pinned yet")
+	}
+
+	time.Sleep(time.Second)
+	pin, err := cl.PinGet(ctx, test.CidResolved)
+	if err != nil {
+		t.Fatal("the item should have been pinned:", err)
+	}
+	if pin.Name != "later" {
+		t.Errorf("unexpected pin name: %s", pin.Name)
+	}
+	schedules, err := cl.PinSchedules(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schedules) != 0 {
+		t.Errorf("schedules which run once should be removed: %+v", schedules)
+	}
+
+	recurring, err := cl.SchedulePin(ctx, api.PinSchedule{
+		Path: test.PathIPFS2,
+		Cron: "0 0 1 1 *",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recurring.Next.Month() != time.January || recurring.Next.Day() != 1 {
+		t.Errorf("unexpected next run: %s", recurring.Next)
+	}
+	// not due yet.
+	cl.runPinSchedule(ctx, recurring.ID)
+	schedules, err = cl.PinSchedules(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schedules) != 1 || !schedules[0].LastRun.IsZero() {
+		t.Fatalf("the schedule should not have run: %+v", schedules)
+	}
+
+	recurring.Next = time.Now()
+	err = cl.consensus.LogSchedule(ctx, recurring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl.runPinSchedule(ctx, recurring.ID)
+	schedules, err = cl.PinSchedules(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schedules) != 1 {
+		t.Fatalf("expected one schedule: %+v", schedules)
+	}
+	if schedules[0].LastRun.IsZero() || !schedules[0].LastCid.Equals(test.CidResolved) || schedules[0].LastError != "" {
+		t.Errorf("unexpected last run: %+v", schedules[0])
+	}
+	if !schedules[0].Next.After(time.Now()) {
+		t.Errorf("the next run should be in the future: %s", schedules[0].Next)
+	}
+
+	err = cl.CancelPinSchedule(ctx, recurring.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cl.CancelPinSchedule(ctx, recurring.ID)
+	if err != state.ErrScheduleNotFound {
+		t.Errorf("expected ErrScheduleNotFound: %s", err)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2024, time.March, 15, 10, 30, 20, 0, time.UTC) // a Friday
+	cases := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.March, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.March, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 */6 * * *", time.Date(2024, time.March, 15, 12, 0, 0, 0, time.UTC)},
+		{"5,10 9 * * *", time.Date(2024, time.March, 16, 9, 5, 0, 0, time.UTC)},
+		{"0 0 * * 1-5", time.Date(2024, time.March, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2024, time.March, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// either the day of month or of week matches.
+		{"0 0 20 * 0", time.Date(2024, time.March, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tc := range cases {
+		cron, err := parseCron(tc.expr)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.expr, err)
+		}
+		if next := cron.next(from); !next.Equal(tc.next) {
+			t.Errorf("%s: expected %s, got %s", tc.expr, tc.next, next)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
+
 func TestAddFile(t *testing.T) {
 	ctx := context.Background()
 	cl, _, _, _ := testingCluster(t)
@@ -429,6 +873,23 @@ func TestAddFile(t *testing.T) {
 
 		// We know that this produces 14 shards.
 		sharding.VerifyShards(t, c, cl, cl.ipfs, 14)
+
+		metaPin, _ := cl.PinGet(ctx, c)
+		cDagBlock, _ := cl.ipfs.BlockGet(ctx, *metaPin.Reference)
+		cDagNode, _ := sharding.CborDataToNode(cDagBlock, "cbor")
+
+		gpin, err := cl.Status(ctx, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(gpin.Shards) == 0 || len(gpin.Shards) != len(cDagNode.Links()) {
+			t.Fatalf("expected the status of every shard: %d", len(gpin.Shards))
+		}
+		for _, shard := range gpin.Shards {
+			if st := shard.PeerMap[cl.id.String()].Status; st != api.TrackerStatusPinned {
+				t.Errorf("shard %s should be pinned: %s", shard.Cid, st)
+			}
+		}
 	})
 }
 
@@ -824,25 +1285,166 @@ func TestClusterPins(t *testing.T) {
 	}
 }
 
-func TestClusterPinGet(t *testing.T) {
+func TestClusterPinsWithOptions(t *testing.T) {
 	ctx := context.Background()
 	cl, _, _, _ := testingCluster(t)
 	defer cleanState()
 	defer shutdownTestingCluster(ctx, t, cl)
 
-	c := test.Cid1
-	_, err := cl.Pin(ctx, c, api.PinOptions{})
-	if err != nil {
-		t.Fatal("pin should have worked:", err)
+	for _, c := range []api.Cid{test.Cid1, test.Cid2, test.Cid3} {
+		_, err := cl.Pin(ctx, c, api.PinOptions{Name: "pin-" + c.String()})
+		if err != nil {
+			t.Fatal("pin should have worked:", err)
+		}
 	}
 
-	pin, err := cl.PinGet(ctx, c)
-	if err != nil {
-		t.Fatal(err)
-	}
-	if !pin.Cid.Equals(c) || pin.ReplicationFactorMin != -1 || pin.ReplicationFactorMax != -1 {
-		t.Error("the Pin does not look as expected")
-	}
+	pinDelay()
+
+	list := func(opts api.PinListOptions) []api.Pin {
+		out := make(chan api.Pin)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- cl.PinsWithOptions(ctx, opts, out)
+		}()
+		var pins []api.Pin
+		for p := range out {
+			pins = append(pins, p)
+		}
+		if err := <-errCh; err != nil {
+			t.Fatal(err)
+		}
+		return pins
+	}
+
+	// Cid2 < Cid3 < Cid1
+	pins := list(api.PinListOptions{Limit: 2})
+	if len(pins) != 2 || !pins[0].Cid.Equals(test.Cid2) || !pins[1].Cid.Equals(test.Cid3) {
+		t.Fatalf("unexpected first page: %v", pins)
+	}
+	pins = list(api.PinListOptions{Limit: 2, After: pins[1].Cid})
+	if len(pins) != 1 || !pins[0].Cid.Equals(test.Cid1) {
+		t.Errorf("unexpected second page: %v", pins)
+	}
+
+	pins = list(api.PinListOptions{NamePrefix: "pin-" + test.Cid1.String()})
+	if len(pins) != 1 || !pins[0].Cid.Equals(test.Cid1) {
+		t.Errorf("unexpected pins filtered by name prefix: %v", pins)
+	}
+
+	pins = list(api.PinListOptions{Status: api.TrackerStatusPinned})
+	if len(pins) != 3 {
+		t.Errorf("expected all pins to be pinned: %v", pins)
+	}
+	pins = list(api.PinListOptions{Status: api.TrackerStatusPinError})
+	if len(pins) != 0 {
+		t.Errorf("expected no pins in error: %v", pins)
+	}
+}
+
+func TestClusterPinIndexes(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingClusterWithConfig(t, func(cfg *Config) {
+		cfg.PinIndexes = []string{"name", "tags", "created"}
+	})
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	_, err := cl.Pin(ctx, test.Cid1, api.PinOptions{Name: "a", Tags: []string{"clientX"}})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	pinDelay()
+
+	// wait for the index to be built on start
+	for i := 0; ; i++ {
+		if _, ok := cl.index.candidates(api.PinListOptions{NamePrefix: "a"}); ok {
+			break
+		}
+		if i > 50 {
+			t.Fatal("the pin index was not built")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// updated from the consensus events
+	_, err = cl.Pin(ctx, test.Cid2, api.PinOptions{Name: "b", Tags: []string{"clientX", "clientY"}})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	_, err = cl.Pin(ctx, test.Cid3, api.PinOptions{Name: "c"})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	pinDelay()
+
+	list := func(opts api.PinListOptions) []api.Pin {
+		out := make(chan api.Pin)
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- cl.PinsWithOptions(ctx, opts, out)
+		}()
+		var pins []api.Pin
+		for p := range out {
+			pins = append(pins, p)
+		}
+		if err := <-errCh; err != nil {
+			t.Fatal(err)
+		}
+		return pins
+	}
+
+	tagX := api.PinListOptions{PinFilter: api.PinFilter{Tags: []string{"clientX"}}}
+	cids, ok := cl.index.candidates(tagX)
+	if !ok || len(cids) != 2 {
+		t.Fatalf("expected 2 candidates for clientX: %v", cids)
+	}
+	if pins := list(tagX); len(pins) != 2 {
+		t.Errorf("expected 2 pins tagged clientX: %v", pins)
+	}
+
+	opts := api.PinListOptions{
+		PinFilter:    api.PinFilter{Name: "B", Tags: []string{"clientY"}},
+		CreatedAfter: time.Now().Add(-time.Hour),
+	}
+	pins := list(opts)
+	if len(pins) != 1 || !pins[0].Cid.Equals(test.Cid2) {
+		t.Errorf("unexpected pins filtered by name, tag and creation: %v", pins)
+	}
+
+	if _, ok := cl.index.candidates(api.PinListOptions{Namespace: "a"}); ok {
+		t.Error("namespaces are not indexed")
+	}
+
+	_, err = cl.Unpin(ctx, test.Cid2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
+	cids, _ = cl.index.candidates(tagX)
+	if len(cids) != 1 || !cids[0].Equals(test.Cid1) {
+		t.Errorf("unpinned item should have left the index: %v", cids)
+	}
+}
+
+func TestClusterPinGet(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	c := test.Cid1
+	_, err := cl.Pin(ctx, c, api.PinOptions{})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+
+	pin, err := cl.PinGet(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pin.Cid.Equals(c) || pin.ReplicationFactorMin != -1 || pin.ReplicationFactorMax != -1 {
+		t.Error("the Pin does not look as expected")
+	}
 
 	_, err = cl.PinGet(ctx, test.Cid2)
 	if err == nil {
@@ -885,154 +1487,1533 @@ func TestClusterUnpin(t *testing.T) {
 	}
 }
 
-func TestClusterUnpinPath(t *testing.T) {
+func TestClusterCancelPin(t *testing.T) {
 	ctx := context.Background()
 	cl, _, _, _ := testingCluster(t)
 	defer cleanState()
 	defer shutdownTestingCluster(ctx, t, cl)
 
-	// Unpin should error without pin being committed to state
-	_, err := cl.UnpinPath(ctx, test.PathIPFS2)
-	if err == nil {
-		t.Error("unpin with path should have failed")
+	c := test.Cid1
+	_, err := cl.CancelPin(ctx, c, false)
+	if err != state.ErrNotFound {
+		t.Error("expected a not found error:", err)
 	}
 
-	// Unpin after pin should succeed
-	pin, err := cl.PinPath(ctx, test.PathIPFS2, api.PinOptions{})
+	_, err = cl.Pin(ctx, c, api.PinOptions{})
 	if err != nil {
-		t.Fatal("pin with path should have worked:", err)
+		t.Fatal("pin should have worked:", err)
 	}
-	if !pin.Cid.Equals(test.CidResolved) {
-		t.Error("expected a different cid, found", pin.Cid.String())
+	_, err = cl.CancelPin(ctx, c, false)
+	if err != nil {
+		t.Fatal("cancel should have worked:", err)
 	}
-
-	pin, err = cl.UnpinPath(ctx, test.PathIPFS2)
+	pin, err := cl.PinGet(ctx, c)
 	if err != nil {
-		t.Error("unpin with path should have worked:", err)
+		t.Fatal(err)
 	}
-	if !pin.Cid.Equals(test.CidResolved) {
-		t.Error("expected a different cid, found", pin.Cid.String())
+	if !pin.IsCanceled() {
+		t.Error("the pin should be canceled")
 	}
-}
 
-func TestClusterPeers(t *testing.T) {
-	ctx := context.Background()
-	cl, _, _, _ := testingCluster(t)
-	defer cleanState()
-	defer shutdownTestingCluster(ctx, t, cl)
+	// pinning again clears the cancellation.
+	_, err = cl.Pin(ctx, c, api.PinOptions{})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	pin, err = cl.PinGet(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin.IsCanceled() {
+		t.Error("the pin should not be canceled anymore")
+	}
 
-	out := make(chan api.ID, 10)
-	cl.Peers(ctx, out)
-	if len(out) != 1 {
-		t.Fatal("expected 1 peer")
+	_, err = cl.CancelPin(ctx, c, true)
+	if err != nil {
+		t.Fatal("cancel should have worked:", err)
+	}
+	_, err = cl.PinGet(ctx, c)
+	if err != state.ErrNotFound {
+		t.Error("the pin should have been unpinned:", err)
 	}
 
-	ident := &config.Identity{}
-	err := ident.LoadJSON(testingIdentity)
+	entries, err := cl.PinHistory(ctx, c)
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	p := <-out
-	if p.ID != ident.ID {
-		t.Error("bad member")
+	if len(entries) != 4 || entries[1].Type != api.PinAuditCancel || entries[3].Type != api.PinAuditUnpin {
+		t.Errorf("unexpected history: %+v", entries)
 	}
 }
 
-func TestVersion(t *testing.T) {
-	ctx := context.Background()
-	cl, _, _, _ := testingCluster(t)
-	defer cleanState()
-	defer shutdownTestingCluster(ctx, t, cl)
-	if cl.Version() != version.Version.String() {
-		t.Error("bad Version()")
-	}
+// Cancelling a pin and pinning it again update the existing pin. Raft
+// should not consider them as already applied.
+func TestClusterCancelPinRaft(t *testing.T) {
+	useConsensus(t, "raft")
+	TestClusterCancelPin(t)
 }
 
-func TestClusterRecoverAllLocal(t *testing.T) {
+func TestClusterPinHistory(t *testing.T) {
 	ctx := context.Background()
 	cl, _, _, _ := testingCluster(t)
 	defer cleanState()
 	defer shutdownTestingCluster(ctx, t, cl)
 
-	_, err := cl.Pin(ctx, test.ErrorCid, api.PinOptions{})
+	c := test.Cid1
+	requester := api.PinRequester{
+		User:    "alice",
+		Address: "192.168.1.10",
+		Peer:    test.PeerID2,
+	}
+	_, err := cl.Pin(withRequester(ctx, requester), c, api.PinOptions{Name: "abc"})
 	if err != nil {
 		t.Fatal("pin should have worked:", err)
 	}
+	pin, err := cl.PinGet(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin.Requester != requester {
+		t.Errorf("the requester was not stored with the pin: %s", pin.Requester)
+	}
 
-	pinDelay()
-
-	out := make(chan api.PinInfo, 10)
-	go func() {
-		err := cl.RecoverAllLocal(ctx, out)
-		if err != nil {
-			t.Error("did not expect an error")
-		}
-	}()
+	_, err = cl.Unpin(ctx, c)
+	if err != nil {
+		t.Fatal("unpin should have worked:", err)
+	}
 
-	recov := collectPinInfos(t, out)
+	entries, err := cl.PinHistory(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries: %+v", entries)
+	}
+	if entries[0].Type != api.PinAuditPin || entries[0].Name != "abc" || entries[0].Requester != requester {
+		t.Errorf("unexpected pin entry: %+v", entries[0])
+	}
+	if entries[1].Type != api.PinAuditUnpin || entries[1].Requester.Peer != cl.id || entries[1].Requester.User != "" {
+		t.Errorf("unexpected unpin entry: %+v", entries[1])
+	}
 
-	if len(recov) != 1 {
-		t.Fatalf("there should be one pin recovered, got = %d", len(recov))
+	entries, err = cl.PinHistory(ctx, test.Cid2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no entries: %+v", entries)
 	}
-	// Recovery will fail, but the pin appearing in the response is good enough to know it was requeued.
 }
 
-func TestClusterRepoGC(t *testing.T) {
+func TestClusterUnpinQuorum(t *testing.T) {
 	ctx := context.Background()
-	cl, _, _, _ := testingCluster(t)
+	cl, _, _, _ := testingClusterWithConfig(t, func(cfg *Config) {
+		cfg.UnpinQuorum = 1
+	})
 	defer cleanState()
 	defer shutdownTestingCluster(ctx, t, cl)
 
-	gRepoGC, err := cl.RepoGC(ctx)
+	c := test.Cid1
+	_, err := cl.Pin(ctx, c, api.PinOptions{})
 	if err != nil {
-		t.Fatal("gc should have worked:", err)
+		t.Fatal("pin should have worked:", err)
+	}
+	pinDelay()
+	_, err = cl.Unpin(ctx, c)
+	if err != nil {
+		t.Fatal("unpin should have worked:", err)
+	}
+	pu, ok := cl.unpins.get(c, time.Minute)
+	if !ok {
+		t.Fatal("the unpin should be waiting for confirmation")
 	}
 
-	if gRepoGC.PeerMap == nil {
-		t.Fatal("expected a non-nil peer map")
+	// still unpinning in this peer
+	unpinning := api.GlobalPinInfo{PeerMap: map[string]api.PinInfoShort{
+		cl.id.String(): {Status: api.TrackerStatusUnpinning},
+	}}
+	if cl.confirmUnpin(c, []peer.ID{cl.id}, unpinning) {
+		t.Error("the unpin should not be confirmed")
+	}
+	if _, ok := cl.unpins.get(c, time.Minute); !ok {
+		t.Error("the unpin should still be pending")
 	}
 
-	if len(gRepoGC.PeerMap) != 1 {
-		t.Error("expected repo gc information for one peer")
+	pinDelay()
+	gpin, err := cl.unpinQuorumStatus(ctx, "PinTracker", "Status", pu, []peer.ID{cl.id})
+	if err != nil {
+		t.Fatal(err)
 	}
-	for _, repoGC := range gRepoGC.PeerMap {
-		testRepoGC(t, repoGC)
+	if gpin.PeerMap[cl.id.String()].Status != api.TrackerStatusUnpinned {
+		t.Errorf("expected unpinned: %+v", gpin)
+	}
+	if _, ok := cl.unpins.get(c, time.Minute); ok {
+		t.Error("the unpin should have been confirmed")
+	}
+
+	// not waited for when the quorum is not set
+	cl.config.UnpinQuorum = 0
+	_, err = cl.Pin(ctx, c, api.PinOptions{})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	_, err = cl.Unpin(ctx, c)
+	if err != nil {
+		t.Fatal("unpin should have worked:", err)
+	}
+	if _, ok := cl.unpins.get(c, time.Minute); ok {
+		t.Error("the unpin should not be waited for")
 	}
+}
 
+// useConsensus makes the testing clusters created during the test use the
+// given consensus component.
+func useConsensus(t *testing.T, name string) {
+	prev := consensus
+	consensus = name
+	t.Cleanup(func() { consensus = prev })
 }
 
-func TestClusterRepoGCLocal(t *testing.T) {
+func TestClusterTrash(t *testing.T) {
 	ctx := context.Background()
-	cl, _, _, _ := testingCluster(t)
+	cl, _, _, _ := testingClusterWithConfig(t, func(cfg *Config) {
+		cfg.TrashRetention = time.Hour
+	})
 	defer cleanState()
 	defer shutdownTestingCluster(ctx, t, cl)
 
-	repoGC, err := cl.RepoGCLocal(ctx)
+	c := test.Cid1
+	_, err := cl.Pin(ctx, c, api.PinOptions{})
 	if err != nil {
-		t.Fatal("gc should have worked:", err)
+		t.Fatal("pin should have worked:", err)
 	}
+	pinDelay()
 
-	testRepoGC(t, repoGC)
-}
+	_, err = cl.Restore(ctx, c)
+	if err != errNotTrashed {
+		t.Error("expected an error restoring a pin not in the trash:", err)
+	}
 
-func testRepoGC(t *testing.T, repoGC api.RepoGC) {
-	if repoGC.Peer == "" {
-		t.Error("expected a cluster ID")
+	pin, err := cl.Unpin(ctx, c)
+	if err != nil {
+		t.Fatal("unpin should have worked:", err)
 	}
-	if repoGC.Error != "" {
-		t.Error("did not expect any error")
+	if !pin.IsTrashed() {
+		t.Error("the pin should have been trashed")
+	}
+	pinDelay()
+	pin, err = cl.PinGet(ctx, c)
+	if err != nil {
+		t.Fatal("the trashed pin should still be in the state:", err)
+	}
+	if !pin.IsTrashed() {
+		t.Error("the pin in the state should be trashed")
+	}
+	if st := cl.StatusLocal(ctx, c); st.Status != api.TrackerStatusPinned {
+		t.Error("the trashed pin should still be pinned:", st.Status)
 	}
 
-	if repoGC.Keys == nil {
-		t.Fatal("expected a non-nil array of IPFSRepoGC")
+	pin, err = cl.Restore(ctx, c)
+	if err != nil {
+		t.Fatal("restore should have worked:", err)
+	}
+	pinDelay()
+	pin, err = cl.PinGet(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin.IsTrashed() {
+		t.Error("the pin should have been restored")
 	}
 
-	if len(repoGC.Keys) == 0 {
-		t.Fatal("expected at least one key, but found none")
+	// unpinning a trashed pin removes it
+	_, err = cl.Unpin(ctx, c)
+	if err != nil {
+		t.Fatal("unpin should have worked:", err)
+	}
+	pinDelay()
+	_, err = cl.Unpin(ctx, c)
+	if err != nil {
+		t.Fatal("unpin should have worked:", err)
+	}
+	pinDelay()
+	if _, err := cl.PinGet(ctx, c); err != state.ErrNotFound {
+		t.Error("the pin should have been removed:", err)
 	}
 
-	if !repoGC.Keys[0].Key.Equals(test.Cid1) {
-		t.Errorf("expected a different cid, expected: %s, found: %s", test.Cid1, repoGC.Keys[0].Key)
+	// trashed pins are removed once the retention period is over
+	_, err = cl.Pin(ctx, c, api.PinOptions{})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	pinDelay()
+	_, err = cl.Unpin(ctx, c)
+	if err != nil {
+		t.Fatal("unpin should have worked:", err)
+	}
+	pinDelay()
+	cl.config.TrashRetention = time.Millisecond
+	err = cl.StateSync(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinDelay()
+	if _, err := cl.PinGet(ctx, c); err != state.ErrNotFound {
+		t.Error("the trashed pin should have been removed:", err)
+	}
+}
+
+// Trashing and restoring update existing pins. Raft should not consider
+// them as already applied.
+func TestClusterTrashRaft(t *testing.T) {
+	useConsensus(t, "raft")
+	TestClusterTrash(t)
+}
+func TestClusterPinUpdateReplace(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	checkFreq := pinUpdateCheckFreq
+	pinUpdateCheckFreq = 100 * time.Millisecond
+	defer func() { pinUpdateCheckFreq = checkFreq }()
+
+	_, err := cl.Pin(ctx, test.Cid1, api.PinOptions{})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	pinDelay()
+
+	_, err = cl.PinUpdate(ctx, test.Cid1, test.Cid2, api.PinOptions{ReplaceUpdated: true})
+	if err != nil {
+		t.Fatal("pin update should have worked:", err)
+	}
+	pin, err := cl.PinGet(ctx, test.Cid2)
+	if err != nil {
+		t.Fatal("the new pin should be in the state:", err)
+	}
+	if pin.ReplaceUpdated {
+		t.Error("ReplaceUpdated should not be stored with the pin")
+	}
+
+	time.Sleep(time.Second)
+	_, err = cl.PinGet(ctx, test.Cid1)
+	if err != state.ErrNotFound {
+		t.Error("the updated pin should have been unpinned:", err)
+	}
+	if st := cl.StatusLocal(ctx, test.Cid2); st.Status != api.TrackerStatusPinned {
+		t.Error("the new pin should be pinned:", st.Status)
+	}
+}
+
+func TestClusterReplayIntents(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	_, err := cl.Pin(ctx, test.Cid1, api.PinOptions{})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	pending, err := cl.intents.pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Fatal("committed operations should not leave intents behind")
+	}
+
+	// Simulate operations accepted right before a crash.
+	pin := api.PinCid(test.Cid2)
+	pin.ReplicationFactorMin = -1
+	pin.ReplicationFactorMax = -1
+	pin.Timestamp = time.Now()
+	err = cl.intents.record(ctx, intentPin, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cl.intents.record(ctx, intentUnpin, api.PinCid(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A pin committed before the crash and unpinned since by another
+	// peer.
+	err = cl.intents.record(ctx, intentPin, api.PinCid(test.Cid3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cl.audit.record(ctx, api.PinAuditEntry{
+		Type:      api.PinAuditUnpin,
+		Cid:       test.Cid3,
+		Requester: api.PinRequester{Peer: test.PeerID2},
+		Timestamp: time.Now().Add(time.Second),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err = cl.intents.pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl.replayIntents(ctx, pending)
+
+	_, err = cl.PinGet(ctx, test.Cid2)
+	if err != nil {
+		t.Error("pin intent should have been replayed:", err)
+	}
+	_, err = cl.PinGet(ctx, test.Cid1)
+	if err != state.ErrNotFound {
+		t.Error("unpin intent should have been replayed:", err)
+	}
+	_, err = cl.PinGet(ctx, test.Cid3)
+	if err != state.ErrNotFound {
+		t.Error("pin intent superseded by an unpin should not be replayed:", err)
+	}
+	pending, err = cl.intents.pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Error("replayed intents should be removed")
+	}
+}
+
+func TestIntentLogSettle(t *testing.T) {
+	ctx := context.Background()
+	il := newIntentLog(inmem.New())
+
+	pending := func() []intent {
+		t.Helper()
+		intents, err := il.pending(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return intents
+	}
+
+	// A pin and an unpin of the same CID do not overwrite each other
+	// and are replayed in order.
+	pin := api.PinCid(test.Cid1)
+	if err := il.record(ctx, intentPin, pin); err != nil {
+		t.Fatal(err)
+	}
+	if err := il.record(ctx, intentUnpin, pin); err != nil {
+		t.Fatal(err)
+	}
+	intents := pending()
+	if len(intents) != 2 || intents[0].Op != intentPin || intents[1].Op != intentUnpin {
+		t.Fatalf("expected a pin and an unpin intent: %+v", intents)
+	}
+
+	// Failures which may still let the operation through keep the
+	// intent.
+	il.settle(ctx, intentUnpin, pin.Cid, fmt.Errorf("consensus commit timed out (%w)", api.ErrUncertain))
+	if len(pending()) != 2 {
+		t.Error("the intent should be kept while the operation may be committed")
+	}
+
+	il.settle(ctx, intentUnpin, pin.Cid, errors.New("error writing to the datastore"))
+	intents = pending()
+	if len(intents) != 1 || intents[0].Op != intentPin {
+		t.Errorf("only the failed intent should be removed: %+v", intents)
+	}
+
+	if err := il.record(ctx, intentUnpin, pin); err != nil {
+		t.Fatal(err)
+	}
+	il.settle(ctx, intentUnpin, pin.Cid, nil)
+	if len(pending()) != 0 {
+		t.Error("a committed operation should supersede older intents")
+	}
+}
+
+func TestClusterUnpinPath(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	// Unpin should error without pin being committed to state
+	_, err := cl.UnpinPath(ctx, test.PathIPFS2)
+	if err == nil {
+		t.Error("unpin with path should have failed")
+	}
+
+	// Unpin after pin should succeed
+	pin, err := cl.PinPath(ctx, test.PathIPFS2, api.PinOptions{})
+	if err != nil {
+		t.Fatal("pin with path should have worked:", err)
+	}
+	if !pin.Cid.Equals(test.CidResolved) {
+		t.Error("expected a different cid, found", pin.Cid.String())
+	}
+
+	pin, err = cl.UnpinPath(ctx, test.PathIPFS2)
+	if err != nil {
+		t.Error("unpin with path should have worked:", err)
+	}
+	if !pin.Cid.Equals(test.CidResolved) {
+		t.Error("expected a different cid, found", pin.Cid.String())
+	}
+}
+
+func TestClusterPeers(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	out := make(chan api.ID, 10)
+	cl.Peers(ctx, out)
+	if len(out) != 1 {
+		t.Fatal("expected 1 peer")
+	}
+
+	ident := &config.Identity{}
+	err := ident.LoadJSON(testingIdentity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := <-out
+	if p.ID != ident.ID {
+		t.Error("bad member")
+	}
+}
+
+func TestVersion(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+	if cl.Version() != version.Version.String() {
+		t.Error("bad Version()")
+	}
+}
+
+func TestClusterRecoverAllLocal(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	_, err := cl.Pin(ctx, test.ErrorCid, api.PinOptions{})
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+
+	pinDelay()
+
+	out := make(chan api.PinInfo, 10)
+	go func() {
+		err := cl.RecoverAllLocal(ctx, out)
+		if err != nil {
+			t.Error("did not expect an error")
+		}
+	}()
+
+	recov := collectPinInfos(t, out)
+
+	if len(recov) != 1 {
+		t.Fatalf("there should be one pin recovered, got = %d", len(recov))
+	}
+	// Recovery will fail, but the pin appearing in the response is good enough to know it was requeued.
+}
+
+func TestClusterPinQueues(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	queues, err := cl.PinQueues(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, ok := queues.PeerMap[cl.id.String()]
+	if !ok {
+		t.Fatal("expected the queues of the local peer")
+	}
+	if q.Error != "" || q.Peer != cl.id || q.ConcurrentPins == 0 || q.ConcurrentUnpins == 0 {
+		t.Errorf("unexpected queue info: %+v", q)
+	}
+}
+
+func TestClusterRepoGC(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	gRepoGC, err := cl.RepoGC(ctx)
+	if err != nil {
+		t.Fatal("gc should have worked:", err)
+	}
+
+	if gRepoGC.PeerMap == nil {
+		t.Fatal("expected a non-nil peer map")
+	}
+
+	if len(gRepoGC.PeerMap) != 1 {
+		t.Error("expected repo gc information for one peer")
+	}
+	for _, repoGC := range gRepoGC.PeerMap {
+		testRepoGC(t, repoGC)
+	}
+
+}
+
+func TestClusterRepoGCLocal(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	repoGC, err := cl.RepoGCLocal(ctx)
+	if err != nil {
+		t.Fatal("gc should have worked:", err)
+	}
+
+	testRepoGC(t, repoGC)
+}
+
+func testRepoGC(t *testing.T, repoGC api.RepoGC) {
+	if repoGC.Peer == "" {
+		t.Error("expected a cluster ID")
+	}
+	if repoGC.Error != "" {
+		t.Error("did not expect any error")
+	}
+
+	if repoGC.Keys == nil {
+		t.Fatal("expected a non-nil array of IPFSRepoGC")
+	}
+
+	if len(repoGC.Keys) == 0 {
+		t.Fatal("expected at least one key, but found none")
+	}
+
+	if !repoGC.Keys[0].Key.Equals(test.Cid1) {
+		t.Errorf("expected a different cid, expected: %s, found: %s", test.Cid1, repoGC.Keys[0].Key)
+	}
+}
+
+func TestClusterDedupStats(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	_, err := cl.DedupStatsLocal(ctx)
+	if err != errNoDedupStats {
+		t.Error("expected an error before statistics are computed")
+	}
+
+	for _, c := range []api.Cid{test.Cid1, test.Cid2} {
+		_, err := cl.Pin(ctx, c, api.PinOptions{})
+		if err != nil {
+			t.Fatal("pin should have worked:", err)
+		}
+	}
+
+	stats, err := cl.computeDedupStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// See mockConnector.Refs: both pins reference the same two blocks.
+	if stats.Pins != 2 || stats.SampledPins != 2 {
+		t.Errorf("unexpected pin counts: %+v", stats)
+	}
+	if stats.TotalBlocks != 6 || stats.UniqueBlocks != 4 || stats.SharedBlocks != 2 {
+		t.Errorf("unexpected block counts: %+v", stats)
+	}
+	if stats.TotalBytes != 6*1024 || stats.UniqueBytes != 4*1024 {
+		t.Errorf("unexpected byte estimations: %+v", stats)
+	}
+
+	cl.dedup.set(stats)
+	global, err := cl.DedupStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if global.PeerMap[cl.id.String()].UniqueBlocks != 4 {
+		t.Error("expected the local statistics in the global ones")
+	}
+}
+
+func TestIngestRate(t *testing.T) {
+	now := time.Now()
+	day := 24 * time.Hour
+	samples := []capacitySample{
+		{ts: now, used: 1000},
+		{ts: now.Add(day), used: 1500},
+		{ts: now.Add(2 * day), used: 2000},
+	}
+	if r := ingestRate(samples); r < 499.9 || r > 500.1 {
+		t.Error("expected 500 bytes per day:", r)
+	}
+	if r := ingestRate(samples[:1]); r != 0 {
+		t.Error("expected no rate with a single sample:", r)
+	}
+
+	var capacity api.Capacity
+	capacity.Free = 5000
+	capacity.IngestRate = 500
+	setCapacityProjection(&capacity)
+	if capacity.DaysToFull != 10 {
+		t.Error("expected 10 days to full:", capacity.DaysToFull)
+	}
+	capacity.IngestRate = -10
+	setCapacityProjection(&capacity)
+	if capacity.DaysToFull != 0 {
+		t.Error("expected no projection when usage shrinks")
+	}
+}
+
+func TestClusterCapacity(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	// See mockConnector.RepoStat: 100 bytes used out of 1000.
+	cl.capacity.add(capacitySample{ts: time.Now().Add(-48 * time.Hour), used: 0})
+
+	pc, err := cl.CapacityLocal(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pc.StorageMax != 1000 || pc.Used != 100 || pc.Free != 900 {
+		t.Errorf("unexpected capacity: %+v", pc)
+	}
+	if pc.IngestRate < 49 || pc.IngestRate > 51 {
+		t.Error("expected an ingest rate of 50 bytes per day:", pc.IngestRate)
+	}
+	if pc.DaysToFull < 17 || pc.DaysToFull > 19 {
+		t.Error("expected around 18 days to full:", pc.DaysToFull)
+	}
+
+	global, err := cl.Capacity(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if global.Total.Used != 100 || global.PeerMap[cl.id.String()].Free != 900 {
+		t.Errorf("unexpected global capacity: %+v", global)
+	}
+}
+
+func TestClusterPinGC(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	cl.config.PinGCMinPinAge = 0
+	for _, c := range []api.Cid{test.Cid1, test.Cid2} {
+		_, err := cl.Pin(ctx, c, api.PinOptions{})
+		if err != nil {
+			t.Fatal("pin should have worked:", err)
+		}
+	}
+
+	// See mockConnector.RepoStat: 100 bytes used out of 1000.
+	report, err := cl.PinGC(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Used != 100 || report.Limit != 1000 || report.ToFree != 0 || len(report.Candidates) != 0 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	// Over the high water mark: the least recently pinned goes first
+	// and is enough to get under the low water mark.
+	cl.config.PinGCMaxTotalSize = 100
+	cl.config.PinGCHighWater = 0.5
+	cl.config.PinGCLowWater = 0.5
+	report, err = cl.PinGC(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Limit != 100 || report.ToFree != 50 || len(report.Candidates) != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	cand := report.Candidates[0]
+	if !cand.Cid.Equals(test.Cid1) || cand.Reason != api.PinGCReasonUsage || cand.Size != 3*1024 {
+		t.Errorf("unexpected candidate: %+v", cand)
+	}
+
+	report, err = cl.PinGC(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Executed || len(report.Candidates) != 1 || report.Candidates[0].Error != "" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	pinDelay()
+	_, err = cl.PinGet(ctx, test.Cid1)
+	if err != state.ErrNotFound {
+		t.Error("expected the pin to be collected:", err)
+	}
+
+	// Old pins are collected regardless of the usage.
+	cl.config.PinGCMaxTotalSize = 0
+	cl.config.PinGCMaxPinAge = time.Nanosecond
+	report, err = cl.PinGC(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Candidates) != 1 || report.Candidates[0].Reason != api.PinGCReasonAge {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestClusterSLOReport(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	cl.config.PinSLOs = map[string]PinSLO{
+		"gold": {Target: time.Minute, Objective: 0.5},
+		"*":    {Target: time.Second, Objective: 0.9},
+	}
+	now := time.Now()
+	gold := func(ago time.Duration) api.Pin {
+		pin := api.PinCid(test.Cid1)
+		pin.StorageClass = "gold"
+		pin.Timestamp = now.Add(-ago)
+		return pin
+	}
+	cl.recordPinTime(ctx, gold(10*time.Second))
+	cl.recordPinTime(ctx, gold(2*time.Minute))
+	cl.recordPinTime(ctx, gold(48*time.Hour)) // outside the window
+	other := api.PinCid(test.Cid2)
+	other.StorageClass = "silver"
+	other.Timestamp = now.Add(-10 * time.Second)
+	cl.recordPinTime(ctx, other)
+
+	check := func(report api.SLOReport) {
+		t.Helper()
+		if len(report.Classes) != 2 || len(report.PeerErrors) != 0 {
+			t.Fatalf("unexpected report: %+v", report)
+		}
+		def, gold := report.Classes[0], report.Classes[1]
+		if def.Class != "*" || def.Pinned != 1 || def.OnTime != 0 || def.Met {
+			t.Errorf("unexpected default class report: %+v", def)
+		}
+		if gold.Class != "gold" || gold.Pinned != 2 || gold.OnTime != 1 || gold.Compliance != 0.5 || !gold.Met {
+			t.Errorf("unexpected gold class report: %+v", gold)
+		}
+	}
+
+	report, err := cl.SLOReportLocal(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(report)
+
+	report, err = cl.SLOReport(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(report)
+}
+
+func TestAllocBurst(t *testing.T) {
+	var ab allocBurst
+	now := time.Now()
+	for i := 1; i < allocBurstSize; i++ {
+		if ab.observe(now) {
+			t.Fatal("should not prefetch before a burst")
+		}
+	}
+	if !ab.observe(now) {
+		t.Fatal("should prefetch when a burst is detected")
+	}
+	if ab.observe(now) {
+		t.Error("should prefetch only once per window")
+	}
+
+	// the burst goes on in the next window
+	later := now.Add(allocBurstWindow + time.Millisecond)
+	for i := 1; i < allocBurstSize; i++ {
+		ab.observe(later)
+	}
+	if !ab.observe(later) {
+		t.Error("should prefetch again when the burst continues")
+	}
+}
+
+func TestClusterPrefetchMetrics(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	metrics := cl.InformerMetricsLocal(ctx)
+	if len(metrics) != 1 || metrics[0].Name != "numpin" || metrics[0].Peer != cl.id {
+		t.Fatalf("unexpected local metrics: %+v", metrics)
+	}
+
+	before := time.Now().UnixNano()
+	cl.config.MetricsPrefetchFanout = 1
+	cl.prefetchMetrics(ctx)
+	m := cl.monitor.LatestForPeer(ctx, "numpin", cl.id)
+	if m.Discard() || m.ReceivedAt < before {
+		t.Errorf("expected a freshly prefetched metric: %+v", m)
+	}
+}
+
+func TestClusterPinStorageClass(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	cl.config.StorageClasses = map[string]StorageClass{
+		"hot": {
+			ReplicationFactorMin: 1,
+			ReplicationFactorMax: 1,
+		},
+		"cold": {
+			PeerGroup:            "archive",
+			ReplicationFactorMin: 1,
+			ReplicationFactorMax: 1,
+		},
+	}
+
+	// metrics are not pushed by the single testing peer.
+	numpinMetric := api.Metric{
+		Name:  "numpin",
+		Peer:  cl.id,
+		Value: "0",
+		Valid: true,
+	}
+	numpinMetric.SetTTL(time.Minute)
+	cl.monitor.LogMetric(ctx, numpinMetric)
+
+	_, err := cl.Pin(ctx, test.Cid1, api.PinOptions{StorageClass: "warm"})
+	if err == nil {
+		t.Fatal("expected an error with an unknown storage class")
+	}
+
+	pin, err := cl.Pin(ctx, test.Cid1, api.PinOptions{StorageClass: "hot"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin.ReplicationFactorMin != 1 || pin.ReplicationFactorMax != 1 || len(pin.Allocations) != 1 {
+		t.Errorf("storage class replication factors not applied: %+v", pin)
+	}
+
+	// no peer belongs to the archive group
+	_, err = cl.Pin(ctx, test.Cid1, api.PinOptions{StorageClass: "cold"})
+	if err == nil {
+		t.Fatal("expected an error when no peers in the group")
+	}
+
+	m := api.Metric{
+		Name:  peerGroupMetric,
+		Peer:  cl.id,
+		Value: "archive",
+		Valid: true,
+	}
+	m.SetTTL(time.Minute)
+	cl.monitor.LogMetric(ctx, m)
+
+	pin, err = cl.Pin(ctx, test.Cid1, api.PinOptions{StorageClass: "cold"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin.StorageClass != "cold" || len(pin.Allocations) != 1 || pin.Allocations[0] != cl.id {
+		t.Errorf("unexpected pin: %+v", pin)
+	}
+
+	// Enough current allocations are in the group: those outside it
+	// are dropped when they are kept.
+	pin.Allocations = []peer.ID{cl.id, test.PeerID2}
+	pin.ReplicationFactorMax = 2
+	err = cl.consensus.LogPin(ctx, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pin, err = cl.Pin(ctx, test.Cid1, api.PinOptions{StorageClass: "cold", Name: "renamed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pin.Allocations) != 1 || pin.Allocations[0] != cl.id {
+		t.Errorf("allocations outside the group should be dropped: %+v", pin.Allocations)
+	}
+}
+
+func TestClusterObservedPins(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	err := cl.ObservePin(ctx, api.ObservedPin{
+		Cid:  test.Cid1,
+		Path: "/ipfs/" + test.Cid1.String(),
+		Mode: api.PinModeRecursive,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pins, err := cl.ObservedPins(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pins) != 1 || !pins[0].Cid.Equals(test.Cid1) || pins[0].Peer != cl.id || pins[0].Timestamp.IsZero() {
+		t.Fatalf("unexpected observed pins: %+v", pins)
+	}
+
+	// observed pins are not part of the shared state
+	_, err = cl.PinGet(ctx, test.Cid1)
+	if err != state.ErrNotFound {
+		t.Error("observed pins should not be committed")
+	}
+
+	err = cl.ObserveUnpin(ctx, test.Cid1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pins, err = cl.ObservedPins(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pins) != 0 {
+		t.Error("expected no observed pins after unpinning")
+	}
+}
+
+func TestClusterPeersArchive(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	for i, p := range []peer.ID{test.PeerID2, test.PeerID3} {
+		err := cl.ArchivePeer(ctx, api.ArchivedPeer{
+			ID:       p,
+			Peername: "removed",
+			Removed:  time.Now().Add(time.Duration(i) * time.Second),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archive := cl.PeersArchive(ctx)
+	if len(archive) != 2 || archive[0].ID != test.PeerID3 || archive[1].ID != test.PeerID2 {
+		t.Fatalf("expected archived peers, most recent first: %+v", archive)
+	}
+
+	if name := cl.peername(pingValue{}, test.PeerID3); name != "removed" {
+		t.Errorf("expected the archived name of the removed peer: %s", name)
+	}
+	if name := cl.peername(pingValue{Peername: "current"}, test.PeerID3); name != "current" {
+		t.Errorf("expected the name in the ping metric: %s", name)
+	}
+
+	// the archive is persisted
+	if _, ok := newPeersArchive(ctx, cl.datastore).get(test.PeerID2); !ok {
+		t.Error("archived peer should have been persisted")
+	}
+}
+
+func TestClusterAPIEndpoints(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	endpoints, err := cl.APIEndpoints(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(endpoints) != 0 {
+		t.Fatalf("expected no advertised endpoints: %+v", endpoints)
+	}
+
+	addr, _ := ma.NewMultiaddr("/dns4/cluster.example.org/tcp/443/https")
+	cl.config.APIAdvertiseAddresses = []ma.Multiaddr{addr}
+	cl.config.APIPublic = true
+
+	m, err := cl.sendPingMetric(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pv := pingValueFromMetric(m)
+	if len(pv.APIAddresses) != 1 || !pv.APIAddresses[0].Equal(addr) || !pv.APIPublic {
+		t.Errorf("expected the api endpoints in the ping metric: %+v", pv)
+	}
+
+	endpoints, err = cl.APIEndpoints(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(endpoints) != 1 || endpoints[0].Peer != cl.id || !endpoints[0].Public ||
+		len(endpoints[0].Addresses) != 1 || !endpoints[0].Addresses[0].Equal(addr) {
+		t.Errorf("unexpected advertised endpoints: %+v", endpoints)
+	}
+}
+
+func TestClusterRunJob(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	for _, job := range Jobs() {
+		res, err := cl.RunJob(ctx, api.JobRequest{Job: job})
+		if err != nil {
+			t.Fatal(err)
+		}
+		jr, ok := res.PeerMap[cl.id.String()]
+		if !ok || jr.Job != job || jr.Error != "" {
+			t.Errorf("unexpected result for job %s: %+v", job, res)
+		}
+	}
+
+	_, err := cl.RunJob(ctx, api.JobRequest{Job: "unknown"})
+	if err == nil {
+		t.Error("expected an error for an unknown job")
+	}
+}
+
+func TestClusterConsensusEvents(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	evCtx, cancel := context.WithCancel(ctx)
+	out := make(chan api.ConsensusEvent, 10)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cl.ConsensusEvents(evCtx, out)
+	}()
+	// let the subscription happen
+	time.Sleep(100 * time.Millisecond)
+
+	_, err := cl.Pin(ctx, test.Cid1, api.PinOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case ev := <-out:
+		if ev.Type != api.ConsensusEventPin || !ev.Pin.Cid.Equals(test.Cid1) {
+			t.Errorf("unexpected event: %+v", ev)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("no event received")
+	}
+
+	cancel()
+	if err := <-errCh; err != nil && err != context.Canceled {
+		t.Error(err)
+	}
+	for range out {
+	}
+}
+
+func TestClusterEventHistory(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	_, err := cl.Pin(ctx, test.Cid1, api.PinOptions{Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cl.Unpin(ctx, test.Cid1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history := func(q api.ClusterEventQuery) []api.ClusterEvent {
+		out := make(chan api.ClusterEvent, 10)
+		if err := cl.EventHistory(ctx, q, out); err != nil {
+			t.Fatal(err)
+		}
+		var evs []api.ClusterEvent
+		for ev := range out {
+			evs = append(evs, ev)
+		}
+		return evs
+	}
+
+	q := api.ClusterEventQuery{Types: []string{api.ClusterEventPin, api.ClusterEventUnpin}}
+	var evs []api.ClusterEvent
+	for i := 0; i < 50; i++ {
+		time.Sleep(100 * time.Millisecond)
+		evs = history(q)
+		if len(evs) > 1 && evs[len(evs)-1].Type == api.ClusterEventUnpin {
+			break
+		}
+	}
+	if len(evs) < 2 {
+		t.Fatalf("expected pin and unpin events: %+v", evs)
+	}
+	first, last := evs[0], evs[len(evs)-1]
+	if first.Type != api.ClusterEventPin || first.Name != "a" || !first.Cid.Equals(test.Cid1) {
+		t.Errorf("unexpected pin event: %+v", first)
+	}
+	if last.Type != api.ClusterEventUnpin || last.ID <= first.ID || last.Peer != cl.id {
+		t.Errorf("unexpected unpin event: %+v", last)
+	}
+
+	q.After = last.ID - 1
+	if evs := history(q); len(evs) != 1 || evs[0].ID != last.ID {
+		t.Errorf("unexpected events after %d: %+v", q.After, evs)
+	}
+
+	q = api.ClusterEventQuery{Limit: 1}
+	if evs := history(q); len(evs) != 1 {
+		t.Errorf("expected 1 event: %+v", evs)
+	}
+}
+
+func TestClusterEvents(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	cl.RecordEvent(ctx, api.ClusterEvent{Type: api.ClusterEventConfigReloaded})
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	out := make(chan api.ClusterEvent, 10)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cl.Events(ctx, api.ClusterEventQuery{}, out)
+	}()
+
+	next := func() api.ClusterEvent {
+		ev, ok := <-out
+		if !ok {
+			t.Fatal("the stream should stay open")
+		}
+		return ev
+	}
+
+	first := next()
+	if first.Type != api.ClusterEventConfigReloaded {
+		t.Errorf("expected the recorded event first: %+v", first)
+	}
+
+	_, err := cl.Pin(ctx, test.Cid1, api.PinOptions{Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pinned, status bool
+	for !pinned || !status {
+		ev := next()
+		if ev.ID <= first.ID {
+			t.Fatalf("events should be sent in order: %+v", ev)
+		}
+		switch ev.Type {
+		case api.ClusterEventPin:
+			pinned = ev.Cid.Equals(test.Cid1)
+		case api.ClusterEventStatus:
+			status = ev.Cid.Equals(test.Cid1) && ev.Status == api.TrackerStatusPinned
+		}
+	}
+
+	cancel()
+	for range out {
+	}
+	if err := <-errCh; err != context.Canceled {
+		t.Errorf("expected a canceled context: %v", err)
+	}
+}
+
+func TestEventLogTrim(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.New()
+	el := newEventLog(ctx, store, test.PeerID1, 3, time.Hour)
+
+	old := time.Now().Add(-2 * time.Hour)
+	for i := 0; i < 5; i++ {
+		ev := api.ClusterEvent{Type: api.ClusterEventPin}
+		if i == 0 {
+			ev.Timestamp = old
+		}
+		if err := el.record(ctx, ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history := func(el *eventLog) []api.ClusterEvent {
+		out := make(chan api.ClusterEvent, 10)
+		if err := el.history(ctx, api.ClusterEventQuery{}, out); err != nil {
+			t.Fatal(err)
+		}
+		close(out)
+		var evs []api.ClusterEvent
+		for ev := range out {
+			evs = append(evs, ev)
+		}
+		return evs
+	}
+
+	// event 1 is too old and event 2 does not fit.
+	evs := history(el)
+	if len(evs) != 3 || evs[0].ID != 3 {
+		t.Errorf("unexpected events: %+v", evs)
+	}
+
+	// reloading keeps numbering events after the last one.
+	el = newEventLog(ctx, store, test.PeerID1, 3, time.Hour)
+	if err := el.record(ctx, api.ClusterEvent{Type: api.ClusterEventUnpin}); err != nil {
+		t.Fatal(err)
+	}
+	evs = history(el)
+	if len(evs) != 3 || evs[0].ID != 4 || evs[2].ID != 6 || evs[2].Peer != test.PeerID1 {
+		t.Errorf("unexpected events after reloading: %+v", evs)
+	}
+}
+
+func TestClusterStateChecksum(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	if consensus != "raft" {
+		_, err := cl.StateChecksumLocal(ctx)
+		if err == nil {
+			t.Error("expected an error with the crdt consensus")
+		}
+		return
+	}
+
+	_, err := cl.Pin(ctx, test.Cid1, api.PinOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	global, err := cl.StateChecksum(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, ok := global.PeerMap[cl.id.String()]
+	if !ok || sum.Error != "" {
+		t.Fatalf("unexpected global checksum: %+v", global)
+	}
+	if sum.Pins != 1 || !sum.Leader || sum.Diverged || sum.Peername != cl.config.Peername {
+		t.Errorf("unexpected checksum: %+v", sum)
+	}
+}
+
+// legacyClusterRPCAPI answers Cluster.Version with a string, as an example
+// of a method whose response type changed.
+type legacyClusterRPCAPI struct {
+	*ClusterRPCAPI
+}
+
+func (rpcapi *legacyClusterRPCAPI) Version(ctx context.Context, in struct{}, out *string) error {
+	*out = rpcapi.c.Version()
+	return nil
+}
+
+func TestClusterLegacyRPC(t *testing.T) {
+	ctx := context.Background()
+	legacy := protocol.ID("/ipfscluster/0.9/rpc")
+
+	oldProtocols := version.LegacyRPCProtocols
+	version.LegacyRPCProtocols = []protocol.ID{legacy}
+	rpcShims[legacy] = func(c *Cluster) map[string]interface{} {
+		return map[string]interface{}{
+			"Cluster": &legacyClusterRPCAPI{&ClusterRPCAPI{c}},
+		}
+	}
+	defer func() {
+		version.LegacyRPCProtocols = oldProtocols
+		delete(rpcShims, legacy)
+	}()
+
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	hosts, _, dhts := createHosts(t, cl.config.Secret, 1)
+	h := hosts[0]
+	defer h.Close()
+	defer dhts[0].Close()
+	h.Peerstore().AddAddrs(cl.id, cl.host.Addrs(), peerstore.PermanentAddrTTL)
+
+	// The shim answers with the old type.
+	client := rpc.NewClient(h, legacy)
+	var v string
+	err := client.CallContext(ctx, cl.id, "Cluster", "Version", struct{}{}, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != version.Version.String() {
+		t.Error("unexpected version:", v)
+	}
+
+	// Methods not in the shim are answered as usual.
+	var id api.ID
+	err = client.CallContext(ctx, cl.id, "Cluster", "ID", struct{}{}, &id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.ID != cl.id {
+		t.Error("unexpected ID:", id.ID)
+	}
+
+	// The current protocol keeps using the default services.
+	var cv api.Version
+	err = rpc.NewClient(h, version.RPCProtocol).CallContext(ctx, cl.id, "Cluster", "Version", struct{}{}, &cv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cv.Version != version.Version.String() {
+		t.Error("unexpected version:", cv.Version)
+	}
+}
+
+// handoffTarget fakes the REST API of the target cluster of a handoff. Pins
+// are pinned right away, except those in failing.
+type handoffTarget struct {
+	client.Client
+
+	id      api.ID
+	mux     sync.Mutex
+	pins    map[api.Cid]api.PinOptions
+	failing map[api.Cid]bool
+}
+
+func (ht *handoffTarget) ID(ctx context.Context) (api.ID, error) {
+	return ht.id, nil
+}
+
+func (ht *handoffTarget) Pin(ctx context.Context, ci api.Cid, opts api.PinOptions) (api.Pin, error) {
+	ht.mux.Lock()
+	defer ht.mux.Unlock()
+	if ht.failing[ci] {
+		return api.Pin{}, errors.New("pin failed")
+	}
+	ht.pins[ci] = opts
+	return api.PinWithOpts(ci, opts), nil
+}
+
+func (ht *handoffTarget) Status(ctx context.Context, ci api.Cid, local bool) (api.GlobalPinInfo, error) {
+	ht.mux.Lock()
+	defer ht.mux.Unlock()
+	status := api.TrackerStatusUnpinned
+	if _, ok := ht.pins[ci]; ok {
+		status = api.TrackerStatusPinned
+	}
+	return api.GlobalPinInfo{
+		Cid: ci,
+		PeerMap: map[string]api.PinInfoShort{
+			ht.id.ID.String(): {Status: status},
+		},
+	}, nil
+}
+
+func TestClusterHandoff(t *testing.T) {
+	ctx := context.Background()
+	cl, _, _, _ := testingCluster(t)
+	defer cleanState()
+	defer shutdownTestingCluster(ctx, t, cl)
+
+	target := &handoffTarget{
+		id:      api.ID{ID: test.PeerID2},
+		pins:    make(map[api.Cid]api.PinOptions),
+		failing: map[api.Cid]bool{test.Cid2: true},
+	}
+	oldClient, oldFreq := newHandoffClient, handoffCheckFreq
+	defer func() {
+		newHandoffClient, handoffCheckFreq = oldClient, oldFreq
+	}()
+	newHandoffClient = func(req api.HandoffRequest) (client.Client, error) {
+		return target, nil
+	}
+	handoffCheckFreq = 100 * time.Millisecond
+
+	for _, c := range []api.Cid{test.Cid1, test.Cid2, test.Cid3} {
+		name := "archive-" + c.String()
+		if c.Equals(test.Cid3) {
+			name = "keep"
+		}
+		_, err := cl.Pin(ctx, c, api.PinOptions{Name: name})
+		if err != nil {
+			t.Fatal("pin should have worked:", err)
+		}
+	}
+	pinDelay()
+
+	req := api.HandoffRequest{
+		Target: api.NewMultiaddrWithValue(ma.StringCast("/ip4/127.0.0.1/tcp/9094")),
+		Pins:   api.PinListOptions{NamePrefix: "archive-"},
+	}
+	_, err := cl.Handoff(ctx, req)
+	if err == nil {
+		t.Fatal("handoffs to targets which are not configured should fail")
+	}
+	cl.config.HandoffTargets = []ma.Multiaddr{req.Target.Value()}
+
+	job, err := cl.Handoff(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Total != 2 {
+		t.Errorf("expected 2 pins to hand off: %+v", job)
+	}
+
+	for i := 0; job.Status == api.HandoffRunning; i++ {
+		if i > 50 {
+			t.Fatal("the handoff did not finish")
+		}
+		time.Sleep(100 * time.Millisecond)
+		job, err = cl.HandoffStatus(ctx, job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if job.Status != api.HandoffFailed || job.Confirmed != 1 || job.Unpinned != 1 || len(job.Errors) != 1 {
+		t.Errorf("unexpected handoff result: %+v", job)
+	}
+	if _, ok := job.Errors[test.Cid2.String()]; !ok {
+		t.Errorf("expected an error for %s: %+v", test.Cid2, job.Errors)
+	}
+	if opts, ok := target.pins[test.Cid1]; !ok || opts.Name != "archive-"+test.Cid1.String() {
+		t.Error("the target should have pinned the handed off pin with its options")
+	}
+
+	pinDelay()
+	if _, err := cl.PinGet(ctx, test.Cid1); err != state.ErrNotFound {
+		t.Error("the handed off pin should have been unpinned:", err)
+	}
+	if _, err := cl.PinGet(ctx, test.Cid2); err != nil {
+		t.Error("the failed pin should have been kept:", err)
+	}
+	if _, err := cl.PinGet(ctx, test.Cid3); err != nil {
+		t.Error("the unselected pin should have been kept:", err)
+	}
+
+	jobs, err := cl.Handoffs(ctx)
+	if err != nil || len(jobs) != 1 {
+		t.Errorf("unexpected handoffs: %+v (%v)", jobs, err)
+	}
+	if _, err := cl.HandoffStatus(ctx, "unknown"); err != api.ErrHandoffNotFound {
+		t.Error("expected an error for an unknown handoff:", err)
+	}
+
+	// the target cannot be this cluster
+	target.id = api.ID{ID: cl.id}
+	if _, err := cl.Handoff(ctx, req); err == nil {
+		t.Error("expected an error handing off to this cluster")
 	}
 }
@@ -25,8 +25,6 @@ import (
 	noise "github.com/libp2p/go-libp2p/p2p/security/noise"
 	libp2ptls "github.com/libp2p/go-libp2p/p2p/security/tls"
 	libp2pquic "github.com/libp2p/go-libp2p/p2p/transport/quic"
-	tcp "github.com/libp2p/go-libp2p/p2p/transport/tcp"
-	websocket "github.com/libp2p/go-libp2p/p2p/transport/websocket"
 )
 
 const dhtNamespace = "dht"
@@ -48,7 +46,8 @@ func init() {
 // the provided cluster configuration. Using that host, it creates pubsub and
 // a DHT instances (persisting to the given datastore), for shared use by all
 // cluster components. The returned host uses the DHT for routing. Relay and
-// NATService are additionally setup for this host.
+// NATService are additionally setup for this host, except relay in the
+// tls-psk private network mode.
 func NewClusterHost(
 	ctx context.Context,
 	ident *config.Identity,
@@ -93,17 +92,25 @@ func NewClusterHost(
 			return idht, err
 		}),
 		libp2p.EnableNATService(),
-		libp2p.EnableRelay(),
-		libp2p.EnableAutoRelayWithPeerSource(newPeerSource(hostGetter, dhtGetter)),
-		libp2p.EnableHolePunching(),
 	}
 
-	if cfg.EnableRelayHop {
-		opts = append(opts, libp2p.EnableRelayService())
+	// Relayed connections do not go through the TLS-PSK transport and
+	// would let in peers without the secret, so relays (and hole
+	// punching, which needs them) are only used with pnet.
+	if cfg.PrivateNetworkMode != PrivateNetworkTLSPSK {
+		opts = append(opts,
+			libp2p.EnableRelay(),
+			libp2p.EnableAutoRelayWithPeerSource(newPeerSource(hostGetter, dhtGetter)),
+			libp2p.EnableHolePunching(),
+		)
+		if cfg.EnableRelayHop {
+			opts = append(opts, libp2p.EnableRelayService())
+		}
 	}
 
 	h, err = newHost(
 		ctx,
+		cfg.PrivateNetworkMode,
 		cfg.Secret,
 		ident.PrivateKey,
 		opts...,
@@ -123,11 +130,17 @@ func NewClusterHost(
 
 // newHost creates a base cluster host without dht, pubsub, relay or nat etc.
 // mostly used for testing.
-func newHost(ctx context.Context, psk corepnet.PSK, priv crypto.PrivKey, opts ...libp2p.Option) (host.Host, error) {
+func newHost(ctx context.Context, pnetMode string, psk corepnet.PSK, priv crypto.PrivKey, opts ...libp2p.Option) (host.Host, error) {
+	pnetOpts, err := privateNetworkOpts(pnetMode, psk)
+	if err != nil {
+		return nil, err
+	}
+
 	finalOpts := []libp2p.Option{
 		libp2p.Identity(priv),
 	}
-	finalOpts = append(finalOpts, baseOpts(psk)...)
+	finalOpts = append(finalOpts, baseOpts()...)
+	finalOpts = append(finalOpts, pnetOpts...)
 	finalOpts = append(finalOpts, opts...)
 
 	h, err := libp2p.New(
@@ -140,17 +153,11 @@ func newHost(ctx context.Context, psk corepnet.PSK, priv crypto.PrivKey, opts ..
 	return h, nil
 }
 
-func baseOpts(psk corepnet.PSK) []libp2p.Option {
+func baseOpts() []libp2p.Option {
 	return []libp2p.Option{
-		libp2p.PrivateNetwork(psk),
 		libp2p.EnableNATService(),
 		libp2p.Security(noise.ID, noise.New),
 		libp2p.Security(libp2ptls.ID, libp2ptls.New),
-		// TODO: quic does not support private networks
-		// libp2p.DefaultTransports,
-		libp2p.NoTransports,
-		libp2p.Transport(tcp.NewTCPTransport),
-		libp2p.Transport(websocket.New),
 	}
 }
 
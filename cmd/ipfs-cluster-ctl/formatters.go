@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"reflect"
 	"sort"
 	"strings"
@@ -86,6 +87,30 @@ func textFormatObject(resp interface{}) {
 		textFormatPrintMetric(r)
 	case api.Alert:
 		textFormatPrintAlert(r)
+	case api.ArchivedPeer:
+		textFormatPrintArchivedPeer(r)
+	case api.APIEndpoint:
+		textFormatPrintAPIEndpoint(r)
+	case api.QuorumStatus:
+		textFormatPrintQuorumStatus(r)
+	case api.GlobalCapacity:
+		textFormatPrintGlobalCapacity(r)
+	case api.PinGCReport:
+		textFormatPrintPinGCReport(r)
+	case api.SLOReport:
+		textFormatPrintSLOReport(r)
+	case api.GlobalStateChecksum:
+		textFormatPrintGlobalStateChecksum(r)
+	case api.DeadLetter:
+		textFormatPrintDeadLetter(r)
+	case api.AllocationExplanation:
+		textFormatPrintAllocationExplanation(r)
+	case api.HandoffJob:
+		textFormatPrintHandoffJob(r)
+	case api.PinAuditEntry:
+		textFormatPrintPinAuditEntry(r)
+	case api.PinSchedule:
+		textFormatPrintPinSchedule(r)
 	case chan api.ID:
 		for item := range r {
 			textFormatObject(item)
@@ -112,6 +137,10 @@ func textFormatObject(resp interface{}) {
 		}
 	case api.GlobalRepoGC:
 		textFormatPrintGlobalRepoGC(r)
+	case api.GlobalPinQueueInfo:
+		textFormatPrintGlobalPinQueueInfo(r)
+	case api.GlobalJobResult:
+		textFormatPrintGlobalJobResult(r)
 	case []string:
 		for _, item := range r {
 			textFormatObject(item)
@@ -120,6 +149,30 @@ func textFormatObject(resp interface{}) {
 		for _, item := range r {
 			textFormatObject(item)
 		}
+	case []api.ArchivedPeer:
+		for _, item := range r {
+			textFormatObject(item)
+		}
+	case []api.APIEndpoint:
+		for _, item := range r {
+			textFormatObject(item)
+		}
+	case []api.DeadLetter:
+		for _, item := range r {
+			textFormatObject(item)
+		}
+	case []api.HandoffJob:
+		for _, item := range r {
+			textFormatObject(item)
+		}
+	case []api.PinAuditEntry:
+		for _, item := range r {
+			textFormatObject(item)
+		}
+	case []api.PinSchedule:
+		for _, item := range r {
+			textFormatObject(item)
+		}
 	default:
 		checkErr("", errors.New("unsupported type returned"+reflect.TypeOf(r).String()))
 	}
@@ -138,6 +191,47 @@ func textFormatPrintID(obj api.ID) {
 		len(obj.ClusterPeers)-1,
 	)
 
+	rtt := "-"
+	if obj.RTT > 0 {
+		rtt = obj.RTT.String()
+	}
+	fmt.Printf(
+		"  > Version: %s | IPFS: %s | RTT: %s | Consensus lag: %d\n",
+		obj.Version,
+		obj.IPFS.Version,
+		rtt,
+		obj.ConsensusLag,
+	)
+
+	if cu := obj.CatchUp; cu != nil {
+		fmt.Printf(
+			"  > Catching up with the shared state: %d/%d entries applied (since %s)\n",
+			cu.AppliedIndex,
+			cu.LastIndex,
+			cu.Started.Format(time.RFC3339),
+		)
+	}
+
+	if cs := obj.Consensus; cs != nil {
+		lastSnap := "never"
+		if !cs.LastSnapshot.IsZero() {
+			lastSnap = humanize.Time(cs.LastSnapshot)
+		}
+		fmt.Printf(
+			"  > Consensus: %s | Term: %d | Applied index: %d | Snapshots: %d (last: %s)\n",
+			cs.Role,
+			cs.Term,
+			cs.AppliedIndex,
+			cs.Snapshots,
+			lastSnap,
+		)
+		fmt.Printf(
+			"  > Storage: consensus %s | datastore %s\n",
+			humanize.Bytes(cs.StorageSize),
+			humanize.Bytes(obj.DatastoreSize),
+		)
+	}
+
 	addrs := make(sort.StringSlice, 0, len(obj.Addresses))
 	for _, a := range obj.Addresses {
 		addrs = append(addrs, a.String())
@@ -176,6 +270,9 @@ func textFormatPrintGPInfo(obj api.GlobalPinInfo) {
 	if obj.Name != "" {
 		fmt.Fprintf(&b, " | %s", obj.Name)
 	}
+	if len(obj.Tags) > 0 {
+		fmt.Fprintf(&b, " | tags: %s", strings.Join(obj.Tags, ","))
+	}
 
 	b.WriteString(":\n")
 
@@ -189,15 +286,64 @@ func textFormatPrintGPInfo(obj api.GlobalPinInfo) {
 		if v.Error != "" {
 			fmt.Fprintf(&b, ": %s", v.Error)
 		}
+		if v.ErrorReason != "" {
+			fmt.Fprintf(&b, " (%s)", v.ErrorReason)
+		}
 		txt, _ := v.TS.MarshalText()
 		fmt.Fprintf(&b, " | %s", txt)
 		fmt.Fprintf(&b, " | Attempts: %d", v.AttemptCount)
 		fmt.Fprintf(&b, " | Priority: %t", v.PriorityPin)
+		if v.QueuePosition > 0 {
+			fmt.Fprintf(&b, " | Queue position: %d", v.QueuePosition)
+		}
+		if v.Progress != nil {
+			txt, _ := v.Progress.LastProgress.MarshalText()
+			fmt.Fprintf(&b, " | Progress: %d blocks (last: %s)", v.Progress.Blocks, txt)
+		}
+		if !v.NextRetry.IsZero() {
+			txt, _ := v.NextRetry.MarshalText()
+			fmt.Fprintf(&b, " | Next retry: %s", txt)
+		}
 		fmt.Fprintf(&b, "\n")
 	}
+
+	// Only worth summarizing when errors are spread across peers.
+	if len(obj.Errors) > 1 || (len(obj.Errors) == 1 && obj.Errors[0].Count() > 1) {
+		b.WriteString("    Errors:\n")
+		for _, es := range obj.Errors {
+			txt, _ := es.LastAttempt.MarshalText()
+			fmt.Fprintf(&b, "      - %s | Peers: %d | Attempts: %d | Last: %s\n",
+				es.Message, es.Count(), es.Attempts, txt)
+		}
+	}
+
+	if len(obj.Shards) > 0 {
+		fmt.Fprintf(&b, "    Shards: %d\n", len(obj.Shards))
+		for _, shard := range obj.Shards {
+			fmt.Fprintf(&b, "      - %s | %s\n", shard.Cid, textFormatShardStatuses(shard))
+		}
+	}
 	fmt.Print(b.String())
 }
 
+// textFormatShardStatuses counts the allocated peers in each status for a
+// shard (i.e. "PINNED: 2, PINNING: 1").
+func textFormatShardStatuses(obj api.GlobalPinInfo) string {
+	counts := make(map[string]int)
+	for _, v := range obj.PeerMap {
+		if v.Status == api.TrackerStatusRemote {
+			continue
+		}
+		counts[strings.ToUpper(v.Status.String())]++
+	}
+	statuses := make([]string, 0, len(counts))
+	for st, n := range counts {
+		statuses = append(statuses, fmt.Sprintf("%s: %d", st, n))
+	}
+	sort.Strings(statuses)
+	return strings.Join(statuses, ", ")
+}
+
 func textFormatPrintVersion(obj api.Version) {
 	fmt.Println(obj.Version)
 }
@@ -237,11 +383,32 @@ func textFormatPrintPin(obj api.Pin) {
 	} else {
 		fmt.Printf(" yes")
 	}
+	if len(obj.Tags) > 0 {
+		fmt.Printf(" | Tags: %s", strings.Join(obj.Tags, ","))
+	}
+	if len(obj.Origins) > 0 {
+		fmt.Printf(" | Origins: %d", len(obj.Origins))
+	}
+	if obj.Namespace != "" {
+		fmt.Printf(" | Namespace: %s", obj.Namespace)
+	}
+	if len(obj.Owners) > 0 {
+		fmt.Printf(" | Owners: %s", strings.Join(obj.Owners, ","))
+	}
+	if obj.Priority != api.PinPriorityNormal {
+		fmt.Printf(" | Priority: %s", obj.Priority)
+	}
 	expireAt := "∞"
 	if !obj.ExpireAt.IsZero() {
 		expireAt = obj.ExpireAt.Format("2006-01-02 15:04:05")
 	}
 	fmt.Printf(" | Exp: %s", expireAt)
+	if obj.IsTrashed() {
+		fmt.Printf(" | Trashed: %s", obj.TrashedAt.Format("2006-01-02 15:04:05"))
+	}
+	if obj.IsCanceled() {
+		fmt.Printf(" | Canceled: %s", obj.CanceledAt.Format("2006-01-02 15:04:05"))
+	}
 
 	added := "unknown"
 	if !obj.Timestamp.IsZero() {
@@ -280,6 +447,266 @@ func textFormatPrintAlert(obj api.Alert) {
 	)
 }
 
+func textFormatPrintArchivedPeer(obj api.ArchivedPeer) {
+	fmt.Printf("%s | %s | Removed: %s\n",
+		obj.ID,
+		obj.Peername,
+		obj.Removed.Format(time.RFC3339),
+	)
+}
+
+func textFormatPrintAPIEndpoint(obj api.APIEndpoint) {
+	access := "private"
+	if obj.Public {
+		access = "public"
+	}
+	fmt.Printf("%s | %s | %s\n", obj.Peer, obj.Peername, access)
+	for _, addr := range obj.Addresses {
+		fmt.Printf("  > %s\n", addr)
+	}
+}
+
+func textFormatPrintDeadLetter(obj api.DeadLetter) {
+	fmt.Printf("%s | Origin: %s | Failed: %s | ERROR: %s\n",
+		obj.ID,
+		obj.Origin,
+		obj.Timestamp.Format(time.RFC3339),
+		obj.Error,
+	)
+}
+
+func textFormatPrintPinAuditEntry(obj api.PinAuditEntry) {
+	name := obj.Name
+	if name == "" {
+		name = "-"
+	}
+	fmt.Printf("%s | %-5s | %s | %s\n",
+		obj.Timestamp.Format(time.RFC3339),
+		obj.Type,
+		obj.Cid,
+		name,
+	)
+	fmt.Printf("  > Requested by: %s\n", obj.Requester)
+	if len(obj.Owners) > 0 {
+		fmt.Printf("  > Owners: %s\n", strings.Join(obj.Owners, ", "))
+	}
+}
+
+func textFormatPrintPinSchedule(obj api.PinSchedule) {
+	when := "once"
+	if obj.Cron != "" {
+		when = fmt.Sprintf("cron: %s", obj.Cron)
+	}
+	name := obj.Options.Name
+	if name == "" {
+		name = "-"
+	}
+	fmt.Printf("%s | %s | %s | %s | Next: %s\n",
+		obj.ID,
+		obj.Path,
+		name,
+		when,
+		obj.Next.Format(time.RFC3339),
+	)
+	if !obj.LastRun.IsZero() {
+		fmt.Printf("  > Last run: %s", obj.LastRun.Format(time.RFC3339))
+		if obj.LastCid.Defined() {
+			fmt.Printf(" | Pinned: %s", obj.LastCid)
+		}
+		if obj.LastError != "" {
+			fmt.Printf(" | ERROR: %s", obj.LastError)
+		}
+		fmt.Println()
+	}
+}
+
+func textFormatPrintHandoffJob(obj api.HandoffJob) {
+	fmt.Printf("%s | %s | Target: %s | Confirmed: %d/%d | Unpinned: %d/%d | Started: %s\n",
+		obj.ID,
+		obj.Status,
+		obj.Target,
+		obj.Confirmed,
+		obj.Total,
+		obj.Unpinned,
+		obj.Total,
+		obj.Started.Format(time.RFC3339),
+	)
+	if obj.Error != "" {
+		fmt.Printf("  ERROR: %s\n", obj.Error)
+	}
+	cids := make(sort.StringSlice, 0, len(obj.Errors))
+	for c := range obj.Errors {
+		cids = append(cids, c)
+	}
+	cids.Sort()
+	for _, c := range cids {
+		fmt.Printf("  > %s | ERROR: %s\n", c, obj.Errors[c])
+	}
+}
+
+func textFormatPrintAllocationExplanation(obj api.AllocationExplanation) {
+	fmt.Printf("%s | Replication: %d/%d", obj.Cid, obj.ReplicationFactorMin, obj.ReplicationFactorMax)
+	if obj.StorageClass != "" {
+		fmt.Printf(" | Class: %s", obj.StorageClass)
+	}
+	fmt.Printf(" | Allocate by: %s\n", strings.Join(obj.AllocateBy, ","))
+	if obj.Error != "" {
+		fmt.Printf("  ERROR: %s\n", obj.Error)
+	}
+	for _, p := range obj.Peers {
+		chosen := " "
+		if p.Chosen {
+			chosen = "*"
+		}
+		rank := "-"
+		if p.Rank > 0 {
+			rank = fmt.Sprintf("%d", p.Rank)
+		}
+		fmt.Printf("  %s %s | %s | rank: %s | %s\n", chosen, p.Peer, p.Class, rank, p.Reason)
+		for _, m := range p.Metrics {
+			fmt.Printf("      > %-15s: %s\n", m.Name, m.Value)
+		}
+	}
+}
+
+func textFormatPrintQuorumStatus(obj api.QuorumStatus) {
+	quorum := "OK"
+	if !obj.HasQuorum {
+		quorum = "LOST"
+	}
+	leader := "none"
+	if obj.Leader != "" {
+		leader = obj.Leader.String()
+	}
+	fmt.Printf("Quorum: %s | Reachable voters: %d/%d (%d needed) | Leader: %s | Last commit: %s\n",
+		quorum,
+		len(obj.ReachableVoters),
+		len(obj.Voters),
+		obj.Quorum,
+		leader,
+		obj.CommitLatency.Round(time.Millisecond),
+	)
+}
+
+func textFormatPrintCapacity(name string, obj api.Capacity) {
+	projection := "not growing"
+	if obj.DaysToFull > 0 {
+		projection = fmt.Sprintf("full in %.1f days", obj.DaysToFull)
+	}
+	fmt.Printf("%-15s | Used: %s of %s | Free: %s | Ingest: %s/day | %s\n",
+		name,
+		humanize.Bytes(obj.Used),
+		humanize.Bytes(obj.StorageMax),
+		humanize.Bytes(obj.Free),
+		humanize.Bytes(uint64(math.Max(obj.IngestRate, 0))),
+		projection,
+	)
+}
+
+func textFormatPrintGlobalCapacity(obj api.GlobalCapacity) {
+	peers := make(sort.StringSlice, 0, len(obj.PeerMap))
+	for peer := range obj.PeerMap {
+		peers = append(peers, peer)
+	}
+	peers.Sort()
+
+	for _, peer := range peers {
+		item := obj.PeerMap[peer]
+		// If peer name is set, use it instead of peer ID.
+		if len(item.Peername) > 0 {
+			peer = item.Peername
+		}
+		if item.Error != "" {
+			fmt.Printf("%-15s | ERROR: %s\n", peer, item.Error)
+			continue
+		}
+		textFormatPrintCapacity(peer, item.Capacity)
+	}
+	textFormatPrintCapacity("Total", obj.Total)
+}
+
+func textFormatPrintSLOReport(obj api.SLOReport) {
+	fmt.Printf("Window: %s\n", obj.Window)
+	for _, class := range obj.Classes {
+		met := "MET"
+		if !class.Met {
+			met = "NOT MET"
+		}
+		fmt.Printf("%-15s | Target: %s | On time: %d/%d (%.2f%%) | Objective: %.2f%% | %s\n",
+			class.Class,
+			class.Target,
+			class.OnTime,
+			class.Pinned,
+			class.Compliance*100,
+			class.Objective*100,
+			met,
+		)
+	}
+	for peer, err := range obj.PeerErrors {
+		fmt.Printf("%-15s | ERROR: %s\n", peer, err)
+	}
+}
+
+func textFormatPrintGlobalStateChecksum(obj api.GlobalStateChecksum) {
+	peers := make(sort.StringSlice, 0, len(obj.PeerMap))
+	for peer := range obj.PeerMap {
+		peers = append(peers, peer)
+	}
+	peers.Sort()
+
+	for _, peer := range peers {
+		item := obj.PeerMap[peer]
+		// If peer name is set, use it instead of peer ID.
+		if len(item.Peername) > 0 {
+			peer = item.Peername
+		}
+		if item.Error != "" {
+			fmt.Printf("%-15s | ERROR: %s\n", peer, item.Error)
+			continue
+		}
+		var flags string
+		if item.Leader {
+			flags += " | LEADER"
+		}
+		if item.Diverged {
+			flags += " | DIVERGED"
+		}
+		fmt.Printf("%-15s | %s | Pins: %d | Index: %d%s\n",
+			peer,
+			item.Checksum,
+			item.Pins,
+			item.AppliedIndex,
+			flags,
+		)
+	}
+}
+
+func textFormatPrintPinGCReport(obj api.PinGCReport) {
+	fmt.Printf("Used: %s of %s | To free: %s\n",
+		humanize.Bytes(obj.Used),
+		humanize.Bytes(obj.Limit),
+		humanize.Bytes(obj.ToFree),
+	)
+	action := "Proposed"
+	if obj.Executed {
+		action = "Unpinned"
+	}
+	for _, cand := range obj.Candidates {
+		if cand.Error != "" {
+			fmt.Printf("%s | ERROR: %s\n", cand.Cid, cand.Error)
+			continue
+		}
+		fmt.Printf("%s | %s | %s | Reason: %s | Pinned: %s | Size: %s\n",
+			cand.Cid,
+			action,
+			cand.Name,
+			cand.Reason,
+			cand.Timestamp.Format(time.RFC3339),
+			humanize.Bytes(cand.Size),
+		)
+	}
+}
+
 func textFormatPrintGlobalRepoGC(obj api.GlobalRepoGC) {
 	peers := make(sort.StringSlice, 0, len(obj.PeerMap))
 	for peer := range obj.PeerMap {
@@ -312,6 +739,63 @@ func textFormatPrintGlobalRepoGC(obj api.GlobalRepoGC) {
 	}
 }
 
+func textFormatPrintGlobalPinQueueInfo(obj api.GlobalPinQueueInfo) {
+	peers := make(sort.StringSlice, 0, len(obj.PeerMap))
+	for peer := range obj.PeerMap {
+		peers = append(peers, peer)
+	}
+	peers.Sort()
+
+	age := func(oldest time.Time) string {
+		if oldest.IsZero() {
+			return "-"
+		}
+		return time.Since(oldest).Round(time.Second).String()
+	}
+
+	for _, peer := range peers {
+		item := obj.PeerMap[peer]
+		// If peer name is set, use it instead of peer ID.
+		if len(item.Peername) > 0 {
+			peer = item.Peername
+		}
+		if item.Error != "" {
+			fmt.Printf("%-15s | ERROR: %s\n", peer, item.Error)
+			continue
+		}
+		fmt.Printf("%-15s\n", peer)
+		fmt.Printf("  > Pins:   queued: %d | oldest: %s | in progress: %d/%d\n",
+			item.PinDepth, age(item.OldestPin), item.Pinning, item.ConcurrentPins)
+		fmt.Printf("  > Unpins: queued: %d | oldest: %s | in progress: %d/%d\n",
+			item.UnpinDepth, age(item.OldestUnpin), item.Unpinning, item.ConcurrentUnpins)
+		if item.Degraded {
+			fmt.Printf("  > Paused: IPFS is unavailable\n")
+		}
+	}
+}
+
+func textFormatPrintGlobalJobResult(obj api.GlobalJobResult) {
+	peers := make(sort.StringSlice, 0, len(obj.PeerMap))
+	for peer := range obj.PeerMap {
+		peers = append(peers, peer)
+	}
+	peers.Sort()
+
+	fmt.Printf("%s:\n", obj.Job)
+	for _, peer := range peers {
+		item := obj.PeerMap[peer]
+		// If peer name is set, use it instead of peer ID.
+		if len(item.Peername) > 0 {
+			peer = item.Peername
+		}
+		if item.Error != "" {
+			fmt.Printf("  > %-15s | ERROR: %s\n", peer, item.Error)
+			continue
+		}
+		fmt.Printf("  > %-15s | OK | %s\n", peer, item.Duration.Round(time.Millisecond))
+	}
+}
+
 func textFormatPrintError(obj api.Error) {
 	fmt.Printf("An error occurred:\n")
 	fmt.Printf("  Code: %d\n", obj.Code)
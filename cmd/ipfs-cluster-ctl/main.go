@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -137,9 +138,15 @@ func main() {
 requires authorization. implies --https, which you can disable with --force-http`,
 			EnvVar: "CLUSTER_CREDENTIALS",
 		},
+		cli.StringFlag{
+			Name: "token",
+			Usage: `bearer token for servers that authenticate users with an SSO provider.
+implies --https, which you can disable with --force-http`,
+			EnvVar: "CLUSTER_TOKEN",
+		},
 		cli.BoolFlag{
 			Name:  "force-http, f",
-			Usage: "force HTTP. only valid when using BasicAuth",
+			Usage: "force HTTP. only valid when using BasicAuth or a token",
 		},
 	}
 
@@ -170,6 +177,11 @@ requires authorization. implies --https, which you can disable with --force-http
 			logger.Warn("SSL automatically enabled with basic auth credentials. Set \"force-http\" to disable")
 			cfg.SSL = true
 		}
+		cfg.Token = c.String("token")
+		if cfg.Token != "" && !cfg.SSL && !c.Bool("force-http") {
+			logger.Warn("SSL automatically enabled with a bearer token. Set \"force-http\" to disable")
+			cfg.SSL = true
+		}
 
 		enc := c.String("encoding")
 		if enc != "text" && enc != "json" {
@@ -246,8 +258,20 @@ This command displays information about the peer that the tool is contacting
 					Usage: "list the nodes participating in the IPFS Cluster",
 					Description: `
 This command provides a list of the ID information of all the peers in the Cluster.
+
+Along with the cluster and IPFS versions, it shows the round-trip time from the
+contacted peer to every other peer and their consensus lag (log entries not yet
+applied to their state).
+
+Peers can be sorted with --sort, using one of: id, name, rtt, version,
+ipfs-version, lag. By default they are listed as they answer.
 `,
-					Flags:     []cli.Flag{},
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "sort",
+							Usage: "sort peers by id, name, rtt, version, ipfs-version or lag",
+						},
+					},
 					ArgsUsage: " ",
 					Action: func(c *cli.Context) error {
 						out := make(chan api.ID, 1024)
@@ -256,12 +280,61 @@ This command provides a list of the ID information of all the peers in the Clust
 							defer close(errCh)
 							errCh <- globalClient.Peers(ctx, out)
 						}()
+
+						if by := c.String("sort"); by != "" {
+							var ids []api.ID
+							for id := range out {
+								ids = append(ids, id)
+							}
+							err := <-errCh
+							formatResponse(c, nil, err)
+							checkErr("sorting peers", sortIDs(ids, by))
+							sorted := make(chan api.ID, len(ids))
+							for _, id := range ids {
+								sorted <- id
+							}
+							close(sorted)
+							formatResponse(c, sorted, nil)
+							return nil
+						}
+
 						formatResponse(c, out, nil)
 						err := <-errCh
 						formatResponse(c, nil, err)
 						return nil
 					},
 				},
+				{
+					Name:  "archive",
+					Usage: "list the peers removed from the Cluster",
+					Description: `
+This command lists the peers that have been removed from the Cluster, with
+the name they had and the time of their removal, most recent first.
+`,
+					Flags:     []cli.Flag{},
+					ArgsUsage: " ",
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.PeersArchive(ctx)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "apis",
+					Usage: "list the API endpoints advertised by the Cluster peers",
+					Description: `
+This command lists the REST API endpoints that the Cluster peers advertise
+(cluster.api_advertise_addresses) and whether they accept public traffic
+(cluster.api_public). Peers which advertise nothing are not listed.
+`,
+					Flags:     []cli.Flag{},
+					ArgsUsage: " ",
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.APIEndpoints(ctx)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
 				{
 					Name:  "rm",
 					Usage: "remove a peer from the Cluster",
@@ -362,10 +435,30 @@ content.
 					Name:  "expire-in",
 					Usage: "Duration after which the pin should be unpinned automatically",
 				},
+				cli.StringFlag{
+					Name:  "storage-class",
+					Usage: "Storage class (as defined in the cluster configuration) for this pin",
+				},
+				cli.StringFlag{
+					Name:  "namespace",
+					Usage: "Namespace for this pin. Users restricted to a namespace can only use theirs",
+				},
+				cli.StringFlag{
+					Name:  "priority",
+					Usage: "Pinning priority: low, normal or high. Peers pin high priority items first",
+				},
 				cli.StringSliceFlag{
 					Name:  "metadata",
 					Usage: "Pin metadata: key=value. Can be added multiple times",
 				},
+				cli.StringFlag{
+					Name:  "tags",
+					Usage: "Comma-separated list of tags for the pin",
+				},
+				cli.StringFlag{
+					Name:  "origins",
+					Usage: "Comma-separated list of multiaddresses of peers providing the content",
+				},
 				cli.StringFlag{
 					Name:  "allocations, allocs",
 					Usage: "Optional comma-separated list of peer IDs",
@@ -477,7 +570,14 @@ content.
 					p.ExpireAt = time.Now().Add(d)
 				}
 
+				p.StorageClass = c.String("storage-class")
+				p.Namespace = c.String("namespace")
+				priority, err := api.PinPriorityFromString(c.String("priority"))
+				checkErr("parsing priority", err)
+				p.Priority = priority
 				p.Metadata = parseMetadata(c.StringSlice("metadata"))
+				p.Tags = parseTags(c.String("tags"))
+				p.Origins = parseOrigins(c.String("origins"))
 				p.Name = name
 				if c.String("allocations") != "" {
 					p.UserAllocations = api.StringsToPeers(strings.Split(c.String("allocations"), ","))
@@ -594,6 +694,12 @@ An optional allocations argument can be provided, allocations should be a
 comma-separated list of peer IDs on which we want to pin. Peers in allocations
 are prioritized over automatically-determined ones, but replication factors
 would still be respected.
+
+Optional origins can be provided as a comma-separated list of multiaddresses
+(i.e. /ip4/1.2.3.4/tcp/4001/p2p/<peerID>) of IPFS peers which have the
+content. They are stored with the pin and the allocated peers' IPFS daemons
+are told to connect to them before pinning, so that the content can be
+fetched without relying on DHT discovery.
 `,
 					ArgsUsage: "<CID|Path>",
 					Flags: []cli.Flag{
@@ -630,10 +736,30 @@ would still be respected.
 							Name:  "expire-in",
 							Usage: "Duration after which pin should be unpinned automatically",
 						},
+						cli.StringFlag{
+							Name:  "storage-class",
+							Usage: "Storage class (as defined in the cluster configuration) for this pin. Pin an existing CID with a different class to move it",
+						},
+						cli.StringFlag{
+							Name:  "namespace",
+							Usage: "Namespace for this pin. Users restricted to a namespace can only use theirs",
+						},
+						cli.StringFlag{
+							Name:  "priority",
+							Usage: "Pinning priority: low, normal or high. Peers pin high priority items first",
+						},
 						cli.StringSliceFlag{
 							Name:  "metadata",
 							Usage: "Pin metadata: key=value. Can be added multiple times",
 						},
+						cli.StringFlag{
+							Name:  "tags",
+							Usage: "Comma-separated list of tags for the pin",
+						},
+						cli.StringFlag{
+							Name:  "origins",
+							Usage: "Comma-separated list of multiaddresses of peers providing the content",
+						},
 						cli.BoolFlag{
 							Name:  "no-status, ns",
 							Usage: "Prevents fetching pin status after pinning (faster, quieter)",
@@ -675,6 +801,8 @@ would still be respected.
 							checkErr("parsing expire-in", err)
 							expireAt = time.Now().Add(d)
 						}
+						priority, err := api.PinPriorityFromString(c.String("priority"))
+						checkErr("parsing priority", err)
 
 						opts := api.PinOptions{
 							ReplicationFactorMin: rplMin,
@@ -684,6 +812,11 @@ would still be respected.
 							UserAllocations:      userAllocs,
 							ExpireAt:             expireAt,
 							Metadata:             parseMetadata(c.StringSlice("metadata")),
+							Tags:                 parseTags(c.String("tags")),
+							Origins:              parseOrigins(c.String("origins")),
+							StorageClass:         c.String("storage-class"),
+							Namespace:            c.String("namespace"),
+							Priority:             priority,
 						}
 
 						pin, cerr := globalClient.PinPath(ctx, arg, opts)
@@ -710,6 +843,17 @@ trigger unpinning operations in all the IPFS nodes holding the content.
 When the request has succeeded, the command returns the status of the CID
 in the cluster. The CID should disappear from the list offered by "pin ls",
 although unpinning operations in the cluster may take longer or fail.
+
+When the cluster sets "unpin_quorum", the status of the CID only becomes
+"unpinned" once enough of the peers it was allocated to have unpinned it,
+and "--wait" waits for that. Until then, the status shows the peers which
+are still unpinning it.
+
+When the cluster sets "trash_retention", the CID is moved to the trash
+instead: it stays pinned until the retention period is over and can be
+restored with "pin restore" until then. Trashed pins are listed with
+"pin ls --trashed". Removing a CID which is already in the trash unpins it
+right away.
 `,
 					ArgsUsage: "<CID|Path>",
 					Flags: []cli.Flag{
@@ -743,6 +887,101 @@ although unpinning operations in the cluster may take longer or fail.
 						return nil
 					},
 				},
+				{
+					Name:  "restore",
+					Usage: "Restore an item from the trash",
+					Description: `
+This command takes a CID out of the trash, so that it is not unpinned when
+the trash retention period of the cluster is over. It fails if the CID is
+not in the trash.
+`,
+					ArgsUsage: "<CID>",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "no-status, ns",
+							Usage: "Prevents fetching pin status after restoring (faster, quieter)",
+						},
+						cli.BoolFlag{
+							Name:  "wait, w",
+							Usage: waitFlagDesc,
+						},
+						cli.DurationFlag{
+							Name:  "wait-timeout, wt",
+							Value: 0,
+							Usage: waitTimeoutFlagDesc,
+						},
+					},
+					Action: func(c *cli.Context) error {
+						ci, err := api.DecodeCid(c.Args().First())
+						checkErr("parsing cid", err)
+						pin, cerr := globalClient.Restore(ctx, ci)
+						if cerr != nil {
+							formatResponse(c, nil, cerr)
+							return nil
+						}
+						handlePinResponseFormatFlags(
+							ctx,
+							c,
+							pin,
+							api.TrackerStatusPinned,
+						)
+						return nil
+					},
+				},
+				{
+					Name:  "cancel",
+					Usage: "Cancel the pinning of an item",
+					Description: `
+This command aborts the pinning of a CID on the peers allocated to it,
+including the request to their IPFS daemons. The pin stays in the cluster
+pinset, marked as canceled and reported as pin_failed, until it is pinned or
+unpinned again. Peers which had already pinned the item keep it.
+
+With --unpin, the item is unpinned instead, so that it is also removed from
+the peers which had already pinned it. The trash is not used in this case.
+`,
+					ArgsUsage: "<CID>",
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "unpin",
+							Usage: "Unpin the item",
+						},
+						cli.BoolFlag{
+							Name:  "no-status, ns",
+							Usage: "Prevents fetching pin status after canceling (faster, quieter)",
+						},
+						cli.BoolFlag{
+							Name:  "wait, w",
+							Usage: waitFlagDesc,
+						},
+						cli.DurationFlag{
+							Name:  "wait-timeout, wt",
+							Value: 0,
+							Usage: waitTimeoutFlagDesc,
+						},
+					},
+					Action: func(c *cli.Context) error {
+						ci, err := api.DecodeCid(c.Args().First())
+						checkErr("parsing cid", err)
+						unpin := c.Bool("unpin")
+						pin, cerr := globalClient.CancelPin(ctx, ci, unpin)
+						if cerr != nil {
+							formatResponse(c, nil, cerr)
+							return nil
+						}
+						target := api.TrackerStatusPinError
+						if unpin {
+							target = api.TrackerStatusUnpinned
+						}
+						handlePinResponseFormatFlags(
+							ctx,
+							c,
+							pin,
+							target,
+						)
+						return nil
+					},
+				},
 				{
 					Name:  "update",
 					Usage: "Pin a new item based on an existing one",
@@ -757,7 +996,9 @@ command. This is especially efficient when the content of two pins (their DAGs)
 are similar.
 
 Unlike the "pin update" command in the ipfs daemon, this will not unpin the
-existing item from the cluster. Please run "pin rm" for that.
+existing item from the cluster, unless --replace is given. In that case, the
+existing item is unpinned once the new one is pinned by all the peers
+allocated to it. Otherwise, please run "pin rm" for that.
 `,
 					ArgsUsage: "<existing-CID> <new-CID|Path>",
 					Flags: []cli.Flag{
@@ -770,6 +1011,14 @@ existing item from the cluster. Please run "pin rm" for that.
 							Name:  "expire-in",
 							Usage: "Duration after which the pin should be unpinned automatically after updating",
 						},
+						cli.StringFlag{
+							Name:  "priority",
+							Usage: "Pinning priority: low, normal or high. Defaults to that of the existing item",
+						},
+						cli.BoolFlag{
+							Name:  "replace",
+							Usage: "Unpin the existing item once the new one is pinned",
+						},
 						cli.BoolFlag{
 							Name:  "no-status, ns",
 							Usage: "Prevents fetching pin status after updating (faster, quieter)",
@@ -797,11 +1046,15 @@ existing item from the cluster. Please run "pin rm" for that.
 							checkErr("parsing expire-in", err)
 							expireAt = time.Now().Add(d)
 						}
+						priority, err := api.PinPriorityFromString(c.String("priority"))
+						checkErr("parsing priority", err)
 
 						opts := api.PinOptions{
-							PinUpdate: fromCid,
-							Name:      c.String("name"),
-							ExpireAt:  expireAt,
+							PinUpdate:      fromCid,
+							Name:           c.String("name"),
+							ExpireAt:       expireAt,
+							Priority:       priority,
+							ReplaceUpdated: c.Bool("replace"),
 						}
 
 						pin, cerr := globalClient.PinPath(ctx, to, opts)
@@ -834,15 +1087,76 @@ The filter only takes effect when listing all pins. The possible values are:
   - meta-pin (sharded pins)
   - clusterdag-pin (sharding-dag root pins)
   - shard-pin (individual shard pins)
+
+The --name, --tag and --metadata flags list only the pins whose name contains
+the given string and which have all the given tags and metadata. Pins can be
+further selected by the beginning of their name or CID, their replication
+factors, the IPFS peer in their origins, their creation time (RFC3339) and
+their status in the peer serving the request (see "status" for possible
+values). Filtering by fields listed in the "pin_indexes" cluster option
+does not require reading the whole pinset. --trashed lists only the pins in
+the trash (see "pin rm").
+
+Large pinsets can be listed in pages with --limit. Pins are then sorted by
+CID, and the next page is obtained by passing the last CID of a page with
+--after.
 `,
 					ArgsUsage: "[CID]",
-					Flags: []cli.Flag{
+					Flags: append([]cli.Flag{
 						cli.StringFlag{
 							Name:  "filter",
 							Usage: "Comma separated list of pin types. See help above.",
 							Value: "all",
 						},
-					},
+						cli.StringFlag{
+							Name:  "name-prefix",
+							Usage: "list only pins whose name starts with this",
+						},
+						cli.StringFlag{
+							Name:  "cid-prefix",
+							Usage: "list only pins whose CID starts with this",
+						},
+						cli.StringFlag{
+							Name:  "namespace",
+							Usage: "list only pins in this namespace",
+						},
+						cli.IntFlag{
+							Name:  "rmin",
+							Usage: "list only pins with this replication factor min",
+						},
+						cli.IntFlag{
+							Name:  "rmax",
+							Usage: "list only pins with this replication factor max",
+						},
+						cli.StringFlag{
+							Name:  "origin",
+							Usage: "list only pins with an origin for this IPFS peer ID",
+						},
+						cli.StringFlag{
+							Name:  "created-after",
+							Usage: "list only pins created at or after this time (RFC3339)",
+						},
+						cli.StringFlag{
+							Name:  "created-before",
+							Usage: "list only pins created before this time (RFC3339)",
+						},
+						cli.BoolFlag{
+							Name:  "trashed",
+							Usage: "list only pins in the trash",
+						},
+						cli.StringFlag{
+							Name:  "status",
+							Usage: "list only pins in these statuses (comma-separated)",
+						},
+						cli.IntFlag{
+							Name:  "limit",
+							Usage: "list at most this many pins, sorted by CID",
+						},
+						cli.StringFlag{
+							Name:  "after",
+							Usage: "list only pins whose CID sorts after this one",
+						},
+					}, pinFilterFlags()...),
 					Action: func(c *cli.Context) error {
 						cidStr := c.Args().First()
 						if cidStr != "" {
@@ -857,11 +1171,49 @@ The filter only takes effect when listing all pins. The possible values are:
 								filter |= api.PinTypeFromString(f)
 							}
 
+							opts := api.PinListOptions{
+								Type:                 filter,
+								PinFilter:            parsePinFilter(c),
+								NamePrefix:           c.String("name-prefix"),
+								CidPrefix:            c.String("cid-prefix"),
+								Namespace:            c.String("namespace"),
+								ReplicationFactorMin: c.Int("rmin"),
+								ReplicationFactorMax: c.Int("rmax"),
+								Trashed:              c.Bool("trashed"),
+								Limit:                c.Int("limit"),
+							}
+							if st := c.String("status"); st != "" {
+								opts.Status = api.TrackerStatusFromString(st)
+								if opts.Status == api.TrackerStatusUndefined {
+									checkErr("parsing status", errors.New("invalid status value"))
+								}
+							}
+							if after := c.String("after"); after != "" {
+								ci, err := api.DecodeCid(after)
+								checkErr("parsing cid", err)
+								opts.After = ci
+							}
+							if origin := c.String("origin"); origin != "" {
+								pid, err := peer.Decode(origin)
+								checkErr("parsing origin peer ID", err)
+								opts.Origin = pid
+							}
+							if t := c.String("created-after"); t != "" {
+								createdAfter, err := time.Parse(time.RFC3339, t)
+								checkErr("parsing created-after", err)
+								opts.CreatedAfter = createdAfter
+							}
+							if t := c.String("created-before"); t != "" {
+								createdBefore, err := time.Parse(time.RFC3339, t)
+								checkErr("parsing created-before", err)
+								opts.CreatedBefore = createdBefore
+							}
+
 							allocs := make(chan api.Pin, 1024)
 							errCh := make(chan error, 1)
 							go func() {
 								defer close(errCh)
-								errCh <- globalClient.Allocations(ctx, filter, allocs)
+								errCh <- globalClient.AllocationsWithOptions(ctx, opts, allocs)
 							}()
 							formatResponse(c, allocs, nil)
 							err := <-errCh
@@ -870,6 +1222,94 @@ The filter only takes effect when listing all pins. The possible values are:
 						return nil
 					},
 				},
+				{
+					Name:  "explain",
+					Usage: "Explain how a CID is allocated",
+					Description: `
+This command shows how the given CID would be allocated with the latest
+metrics known to the contacted peer, without modifying its allocations. For
+every peer it shows the metrics used by the allocator, whether it is
+a current allocation, a candidate or excluded, its rank in the allocator
+order and the reason why it is chosen or not. Chosen peers are marked with
+"*". CIDs which are not pinned are explained as if they were pinned with the
+default options.
+`,
+					ArgsUsage: "<CID>",
+					Action: func(c *cli.Context) error {
+						ci, err := api.DecodeCid(c.Args().First())
+						checkErr("parsing cid", err)
+						resp, cerr := globalClient.AllocationExplain(ctx, ci)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "history",
+					Usage: "Show who pinned and unpinned a CID",
+					Description: `
+This command shows the pin and unpin operations on the given CID recorded by
+the cluster peers, oldest first. Every operation shows when it was submitted,
+the name and owners of the pin and who requested it: the API user and the
+source address of the request when it came through an API, and the cluster
+peer which handled it. Operations recorded by peers which cannot be contacted
+are not shown.
+`,
+					ArgsUsage: "<CID>",
+					Action: func(c *cli.Context) error {
+						ci, err := api.DecodeCid(c.Args().First())
+						checkErr("parsing cid", err)
+						resp, cerr := globalClient.PinHistory(ctx, ci)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "queues",
+					Usage: "Show the queues of pin and unpin operations",
+					Description: `
+This command shows, for every cluster peer, how many pin and unpin operations
+are waiting to be sent to the IPFS daemon, how long the oldest of them has been
+waiting and how many are in progress out of the maximum allowed
+(pin_tracker.stateless.concurrent_pins and concurrent_unpins options).
+
+The position of every queued item is shown by the "status" command.
+
+When --local flag is passed, only the queues of the contacted peer are shown.
+`,
+					Flags: []cli.Flag{
+						localFlag(),
+					},
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.PinQueues(ctx, c.Bool("local"))
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "gc",
+					Usage: "Show or unpin the pins selected by the pin garbage collector",
+					Description: `
+This command evaluates the pin garbage collection policy (cluster.pin_gc_*
+options) and shows the pins that it selects: those last pinned longer ago
+than cluster.pin_gc_max_pin_age and, when the storage used by the cluster
+is over the high water mark, the least recently pinned ones until the usage
+is estimated to fall under the low water mark. Pins more recent than
+cluster.pin_gc_min_pin_age are never selected.
+
+The selected pins are only unpinned with --execute.
+`,
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "execute",
+							Usage: "unpin the selected pins",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.PinGC(ctx, c.Bool("execute"))
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
 			},
 		},
 		{
@@ -888,15 +1328,45 @@ When the --filter flag is passed, it will only fetch the peer information
 where status of the pin matches at least one of the filter values (a comma
 separated list). The following are valid status values:
 
-` + trackerStatusAllString(),
+` + trackerStatusAllString() + `
+
+When the --batch-size flag is passed, peers are queried in batches of that
+size and the statuses from every batch are shown as soon as it finishes, so
+the same CID may appear several times. The --timeout-per-peer flag sets how
+long every peer has to send its statuses before it is reported with an error.
+
+The --name, --tag and --metadata flags show only the items whose name contains
+the given string and which have all the given tags and metadata.
+
+The --cid-prefix flag shows only the items whose CID starts with the given
+string. The --peer flag (a comma-separated list of peer IDs) shows only the
+statuses of the given peers, which are the only ones queried. All filters are
+evaluated by the cluster peers and the results are streamed as they arrive.
+`,
 			ArgsUsage: "[CID1] [CID2]...",
-			Flags: []cli.Flag{
+			Flags: append([]cli.Flag{
 				localFlag(),
 				cli.StringFlag{
 					Name:  "filter",
 					Usage: "comma-separated list of filters",
 				},
-			},
+				cli.IntFlag{
+					Name:  "batch-size",
+					Usage: "number of peers queried at the same time",
+				},
+				cli.DurationFlag{
+					Name:  "timeout-per-peer",
+					Usage: "maximum time to wait for each peer",
+				},
+				cli.StringFlag{
+					Name:  "cid-prefix",
+					Usage: "show only items whose CID starts with this prefix",
+				},
+				cli.StringFlag{
+					Name:  "peer",
+					Usage: "comma-separated list of peer IDs to show the statuses of",
+				},
+			}, pinFilterFlags()...),
 			Action: func(c *cli.Context) error {
 				cidsStr := c.Args()
 				cids := make([]api.Cid, len(cidsStr))
@@ -923,7 +1393,21 @@ separated list). The following are valid status values:
 						if filter == api.TrackerStatusUndefined && filterFlag != "" {
 							checkErr("parsing filter flag", errors.New("invalid filter name"))
 						}
-						chErr <- globalClient.StatusAll(ctx, filter, c.Bool("local"), out)
+						opts := api.StatusAllOptions{
+							Filter:         filter,
+							BatchSize:      c.Int("batch-size"),
+							TimeoutPerPeer: c.Duration("timeout-per-peer"),
+							PinFilter:      parsePinFilter(c),
+							CidPrefix:      c.String("cid-prefix"),
+						}
+						if peers := c.String("peer"); peers != "" {
+							for _, p := range strings.Split(peers, ",") {
+								pid, err := peer.Decode(p)
+								checkErr("parsing peer ID", err)
+								opts.Peers = append(opts.Peers, pid)
+							}
+						}
+						chErr <- globalClient.StatusAllWithOptions(ctx, opts, c.Bool("local"), out)
 					}
 				}()
 
@@ -944,13 +1428,42 @@ The command will wait for any operations to succeed and will return the status
 of the item upon completion. Note that, when running on the full sets of tracked
 CIDs (without argument), it may take a considerably long time.
 
+Recovering a single CID retries it immediately, and gives pins in pin_failed
+state (which have used all their attempts) a new set of attempts. Recovering
+all CIDs only retries the pins whose next retry time has passed and leaves
+failed pins alone.
+
 When the --local flag is passed, it will only trigger recover
 operations on the contacted peer (as opposed to on every peer).
+
+When recovering all CIDs, the --filter flag (a comma-separated list of
+statuses), the --cid-prefix flag and the --name, --tag and --metadata flags
+select which items are recovered. The --rate flag limits how many items every
+peer re-queues per second, which avoids overloading IPFS daemons that have
+just come back. The --force flag retries the selected items right away,
+including failed pins, as if they were recovered one by one. Recovered items
+are shown as they are re-queued.
 `,
 			ArgsUsage: "[CID]",
-			Flags: []cli.Flag{
+			Flags: append([]cli.Flag{
 				localFlag(),
-			},
+				cli.StringFlag{
+					Name:  "filter",
+					Usage: "recover only items in these statuses (comma-separated)",
+				},
+				cli.StringFlag{
+					Name:  "cid-prefix",
+					Usage: "recover only items whose CID starts with this prefix",
+				},
+				cli.Float64Flag{
+					Name:  "rate",
+					Usage: "maximum number of items re-queued per second by every peer",
+				},
+				cli.BoolFlag{
+					Name:  "force",
+					Usage: "retry items right away, ignoring the retry policy",
+				},
+			}, pinFilterFlags()...),
 			Action: func(c *cli.Context) error {
 				cidStr := c.Args().First()
 				if cidStr != "" {
@@ -963,7 +1476,19 @@ operations on the contacted peer (as opposed to on every peer).
 					errCh := make(chan error, 1)
 					go func() {
 						defer close(errCh)
-						errCh <- globalClient.RecoverAll(ctx, c.Bool("local"), out)
+						filterFlag := c.String("filter")
+						filter := api.TrackerStatusFromString(filterFlag)
+						if filter == api.TrackerStatusUndefined && filterFlag != "" {
+							checkErr("parsing filter flag", errors.New("invalid filter name"))
+						}
+						opts := api.RecoverAllOptions{
+							Filter:    filter,
+							PinFilter: parsePinFilter(c),
+							CidPrefix: c.String("cid-prefix"),
+							Rate:      c.Float64("rate"),
+							Force:     c.Bool("force"),
+						}
+						errCh <- globalClient.RecoverAllWithOptions(ctx, opts, c.Bool("local"), out)
 					}()
 					formatResponse(c, out, nil)
 					err := <-errCh
@@ -1080,6 +1605,136 @@ trigger automatic repinnings if configured.
 						return nil
 					},
 				},
+				{
+					Name:  "quorum",
+					Usage: "Show whether the consensus can commit operations",
+					Description: `
+This command shows how many consensus voters the contacted peer can reach,
+how many are needed to commit operations, the current leader and how long
+the last commit took. Pins and unpins fail right away while the quorum is
+lost. It is only supported by the Raft consensus.
+`,
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.Quorum(ctx)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "capacity",
+					Usage: "Show the storage capacity and usage of the cluster",
+					Description: `
+This command shows, for every peer and for the whole cluster, the storage
+configured in IPFS, the used and free space, the ingest rate (the growth of
+the used space per day, estimated from the repository sizes sampled every
+cluster.capacity_interval) and how many days are left until the free space
+runs out at that rate.
+`,
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "local",
+							Usage: "only show the capacity of the contacted peer",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.Capacity(ctx, c.Bool("local"))
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "checksum",
+					Usage: "Compare the shared state of the cluster peers",
+					Description: `
+This command shows, for every peer, a checksum of the shared state (the
+pinset), the number of pins and the index of the last applied operation.
+Peers that have applied the same operations as the leader but have a
+different checksum have diverged and are marked as such. It is only
+supported by the Raft consensus.
+`,
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "local",
+							Usage: "only show the checksum of the contacted peer",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.StateChecksum(ctx, c.Bool("local"))
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "slo",
+					Usage: "Show the compliance with the pin service level objectives",
+					Description: `
+This command shows, for every class in cluster.pin_slos, how many of the
+pins that were pinned during cluster.pin_slo_window were pinned within the
+target time, and whether that fraction reaches the objective. Every
+allocation of a pin is counted, as the time is measured by the peers that
+pin it.
+`,
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "local",
+							Usage: "only count the pins of the contacted peer",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.SLOReport(ctx, c.Bool("local"))
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "deadletters",
+					Usage: "Inspect and retry operations that failed to apply",
+					Description: `
+These commands manage the "dead letters" of the contacted peer: pin and unpin
+operations which were committed to the consensus log but that the peer failed
+to apply to its shared state. They are kept until they are retried
+successfully or discarded. They are only supported by the Raft consensus.
+`,
+					Subcommands: []cli.Command{
+						{
+							Name:  "ls",
+							Usage: "List the operations that failed to apply",
+							Action: func(c *cli.Context) error {
+								resp, cerr := globalClient.DeadLetters(ctx)
+								formatResponse(c, resp, cerr)
+								return nil
+							},
+						},
+						{
+							Name:      "retry",
+							Usage:     "Commit a failed operation again",
+							ArgsUsage: "<id>",
+							Action: func(c *cli.Context) error {
+								id := c.Args().First()
+								if id == "" {
+									checkErr("", errors.New("a dead letter id must be given"))
+								}
+								cerr := globalClient.RetryDeadLetter(ctx, id)
+								formatResponse(c, nil, cerr)
+								return nil
+							},
+						},
+						{
+							Name:      "discard",
+							Usage:     "Forget a failed operation",
+							ArgsUsage: "<id>",
+							Action: func(c *cli.Context) error {
+								id := c.Args().First()
+								if id == "" {
+									checkErr("", errors.New("a dead letter id must be given"))
+								}
+								cerr := globalClient.DiscardDeadLetter(ctx, id)
+								formatResponse(c, nil, cerr)
+								return nil
+							},
+						},
+					},
+				},
 			},
 		},
 		{
@@ -1108,6 +1763,311 @@ deamon, otherwise on all IPFS daemons.
 				},
 			},
 		},
+		{
+			Name:  "job",
+			Usage: "run a maintenance job on cluster peers",
+			Description: `
+This command runs a predefined maintenance job on all the peers of the
+Cluster, or on the comma-separated list of peer IDs given with --peers, and
+shows the result reported by each of them. The available jobs are:
+
+  - reconnect-ipfs: connect the IPFS daemons to those of the other peers.
+  - flush-metrics: publish fresh informer metrics right away.
+  - reprovide: announce the IPFS daemon content to the routing system again.
+`,
+			ArgsUsage: "<job>",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "peers",
+					Usage: "comma-separated list of peer IDs to run the job on",
+				},
+			},
+			Action: func(c *cli.Context) error {
+				job := c.Args().First()
+				if job == "" {
+					checkErr("", errors.New("a job name must be given"))
+				}
+				var peers []peer.ID
+				if c.String("peers") != "" {
+					peers = api.StringsToPeers(strings.Split(c.String("peers"), ","))
+				}
+				resp, cerr := globalClient.RunJob(ctx, job, peers)
+				formatResponse(c, resp, cerr)
+				return nil
+			},
+		},
+		{
+			Name:  "handoff",
+			Usage: "Transfer pins to another cluster",
+			Description: `
+Handoffs transfer a set of pins from this cluster to another one, i.e. when
+splitting a cluster or migrating to another datacenter. Every pin is pinned in
+the target cluster with the same options and is only unpinned from this
+cluster once all the target peers allocated to it report it as pinned. Pins
+that cannot be handed off are left in this cluster.
+
+Handoffs run in the background on the contacted peer, which keeps track of
+their progress. Further "handoff" commands must be sent to the same peer.
+Only administrators can use them, and only towards the targets listed in the
+cluster.handoff_targets configuration of that peer.
+`,
+			Subcommands: []cli.Command{
+				{
+					Name:  "start",
+					Usage: "Start handing off pins to another cluster",
+					Description: `
+This command starts handing off the pins selected with the given flags (all
+data pins by default) to the cluster whose REST API listens on the given
+multiaddress, and prints the handoff ID. With --wait, it waits for the
+handoff to finish and prints its progress periodically.
+`,
+					ArgsUsage: "<target-api-multiaddress>",
+					Flags: append([]cli.Flag{
+						cli.StringFlag{
+							Name:  "target-basic-auth",
+							Usage: "<username>[:<password>] to access the target cluster API",
+						},
+						cli.StringFlag{
+							Name:  "name-prefix",
+							Usage: "hand off only pins whose name starts with this",
+						},
+						cli.StringFlag{
+							Name:  "cid-prefix",
+							Usage: "hand off only pins whose CID starts with this",
+						},
+						cli.StringFlag{
+							Name:  "namespace",
+							Usage: "hand off only pins in this namespace",
+						},
+						cli.DurationFlag{
+							Name:  "timeout",
+							Usage: "how long the target cluster may take to pin each item (0 for no limit)",
+						},
+						cli.BoolFlag{
+							Name:  "wait, w",
+							Usage: "wait for the handoff to finish",
+						},
+					}, pinFilterFlags()...),
+					Action: func(c *cli.Context) error {
+						maddr, err := ma.NewMultiaddr(c.Args().First())
+						checkErr("parsing target multiaddress", err)
+
+						req := api.HandoffRequest{
+							Target: api.NewMultiaddrWithValue(maddr),
+							Pins: api.PinListOptions{
+								PinFilter:  parsePinFilter(c),
+								NamePrefix: c.String("name-prefix"),
+								CidPrefix:  c.String("cid-prefix"),
+								Namespace:  c.String("namespace"),
+							},
+							Timeout: c.Duration("timeout"),
+						}
+						if auth := c.String("target-basic-auth"); auth != "" {
+							req.Username, req.Password = parseCredentials(auth)
+						}
+
+						job, cerr := globalClient.Handoff(ctx, req)
+						if cerr != nil || !c.Bool("wait") {
+							formatResponse(c, job, cerr)
+							return nil
+						}
+						for job.Status == api.HandoffRunning {
+							formatResponse(c, job, nil)
+							time.Sleep(5 * time.Second)
+							job, cerr = globalClient.HandoffStatus(ctx, job.ID)
+							if cerr != nil {
+								formatResponse(c, nil, cerr)
+								return nil
+							}
+						}
+						formatResponse(c, job, nil)
+						return nil
+					},
+				},
+				{
+					Name:  "ls",
+					Usage: "List the handoffs started on the contacted peer",
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.Handoffs(ctx)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:      "status",
+					Usage:     "Show the progress of a handoff",
+					ArgsUsage: "<id>",
+					Action: func(c *cli.Context) error {
+						id := c.Args().First()
+						if id == "" {
+							checkErr("", errors.New("a handoff id must be given"))
+						}
+						resp, cerr := globalClient.HandoffStatus(ctx, id)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "cancel",
+					Usage: "Stop a handoff",
+					Description: `
+This command stops a running handoff. Pins already confirmed by the target
+cluster are still unpinned from this one.
+`,
+					ArgsUsage: "<id>",
+					Action: func(c *cli.Context) error {
+						id := c.Args().First()
+						if id == "" {
+							checkErr("", errors.New("a handoff id must be given"))
+						}
+						cerr := globalClient.CancelHandoff(ctx, id)
+						formatResponse(c, nil, cerr)
+						return nil
+					},
+				},
+			},
+		},
+		{
+			Name:  "schedule",
+			Usage: "Pin items at a later time or periodically",
+			Description: `
+Pin schedules commit pins at a later time, or repeatedly following a cron
+expression. The CID or path of a schedule is resolved every time it runs, so
+recurring schedules can be used to keep up with mutable content published
+with IPNS.
+
+Schedules are part of the shared state, so every cluster peer knows about
+them. They are run by the leader or, when the consensus has no leader (CRDT),
+by one of the trusted peers.
+`,
+			Subcommands: []cli.Command{
+				{
+					Name:  "add",
+					Usage: "Schedule the pin of an item",
+					Description: `
+This command schedules the pin of the given CID or path with the given
+options. --not-before sets the time (RFC3339) before which it is not pinned.
+--cron sets a cron expression with five fields (minute, hour, day of month,
+month and day of week, in the time zone of the peers) to pin it again on
+every occurrence, i.e. "0 */6 * * *" to pin it every 6 hours. Schedules
+without a cron expression run once.
+
+Items pinned by a schedule are regular pins and are not unpinned when it is
+canceled, nor when a recurring schedule pins a newer version of a path.
+`,
+					ArgsUsage: "<CID|Path>",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "not-before",
+							Usage: "time (RFC3339) before which the item is not pinned",
+						},
+						cli.StringFlag{
+							Name:  "cron",
+							Usage: "cron expression to pin the item repeatedly",
+						},
+						cli.IntFlag{
+							Name:  "replication, r",
+							Value: 0,
+							Usage: "Sets a custom replication factor (overrides -rmax and -rmin)",
+						},
+						cli.IntFlag{
+							Name:  "replication-min, rmin",
+							Value: 0,
+							Usage: "Sets the minimum replication factor for this pin",
+						},
+						cli.IntFlag{
+							Name:  "replication-max, rmax",
+							Value: 0,
+							Usage: "Sets the maximum replication factor for this pin",
+						},
+						cli.StringFlag{
+							Name:  "name, n",
+							Value: "",
+							Usage: "Sets a name for this pin",
+						},
+						cli.StringFlag{
+							Name:  "storage-class",
+							Usage: "Storage class (as defined in the cluster configuration) for this pin",
+						},
+						cli.StringFlag{
+							Name:  "namespace",
+							Usage: "Namespace for this pin",
+						},
+						cli.StringFlag{
+							Name:  "priority",
+							Usage: "Pinning priority: low, normal or high",
+						},
+						cli.StringFlag{
+							Name:  "tags",
+							Usage: "Comma-separated list of tags for the pin",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						rplMin := c.Int("replication-min")
+						rplMax := c.Int("replication-max")
+						if rpl := c.Int("replication"); rpl != 0 {
+							rplMin = rpl
+							rplMax = rpl
+						}
+						priority, err := api.PinPriorityFromString(c.String("priority"))
+						checkErr("parsing priority", err)
+
+						s := api.PinSchedule{
+							Path: c.Args().First(),
+							Cron: c.String("cron"),
+							Options: api.PinOptions{
+								ReplicationFactorMin: rplMin,
+								ReplicationFactorMax: rplMax,
+								Name:                 c.String("name"),
+								Tags:                 parseTags(c.String("tags")),
+								StorageClass:         c.String("storage-class"),
+								Namespace:            c.String("namespace"),
+								Priority:             priority,
+							},
+						}
+						if nb := c.String("not-before"); nb != "" {
+							s.NotBefore, err = time.Parse(time.RFC3339, nb)
+							checkErr("parsing not-before", err)
+						}
+
+						resp, cerr := globalClient.SchedulePin(ctx, s)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "ls",
+					Usage: "List pin schedules",
+					Description: `
+This command lists the pin schedules in the cluster, sorted by the time when
+they run next, along with the results of their last run.
+`,
+					Action: func(c *cli.Context) error {
+						resp, cerr := globalClient.PinSchedules(ctx)
+						formatResponse(c, resp, cerr)
+						return nil
+					},
+				},
+				{
+					Name:  "rm",
+					Usage: "Cancel a pin schedule",
+					Description: `
+This command removes a pin schedule from the cluster. Items it already
+pinned stay pinned.
+`,
+					ArgsUsage: "<id>",
+					Action: func(c *cli.Context) error {
+						id := c.Args().First()
+						if id == "" {
+							checkErr("", errors.New("a schedule id must be given"))
+						}
+						cerr := globalClient.CancelPinSchedule(ctx, id)
+						formatResponse(c, nil, cerr)
+						return nil
+					},
+				},
+			},
+		},
 		{
 			Name:      "commands",
 			Usage:     "List all commands",
@@ -1253,6 +2213,54 @@ func waitFor(
 	return client.WaitFor(ctx, globalClient, fp)
 }
 
+func parseTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return api.NormalizeTags(strings.Split(tags, ","))
+}
+
+func parseOrigins(origins string) []api.Multiaddr {
+	if origins == "" {
+		return nil
+	}
+	var addrs []api.Multiaddr
+	for _, o := range strings.Split(origins, ",") {
+		addr, err := api.NewMultiaddr(strings.TrimSpace(o))
+		checkErr("parsing origins", err)
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+func pinFilterFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "name",
+			Usage: "only items whose name contains this string",
+		},
+		cli.StringSliceFlag{
+			Name:  "tag",
+			Usage: "only items with this tag. Can be added multiple times",
+		},
+		cli.StringSliceFlag{
+			Name:  "metadata",
+			Usage: "only items with this metadata: key=value. Can be added multiple times",
+		},
+	}
+}
+
+func parsePinFilter(c *cli.Context) api.PinFilter {
+	pf := api.PinFilter{
+		Name: c.String("name"),
+		Tags: api.NormalizeTags(c.StringSlice("tag")),
+	}
+	if md := c.StringSlice("metadata"); len(md) > 0 {
+		pf.Metadata = parseMetadata(md)
+	}
+	return pf
+}
+
 func parseMetadata(metadata []string) map[string]string {
 	metadataMap := make(map[string]string)
 	for _, str := range metadata {
@@ -1266,6 +2274,29 @@ func parseMetadata(metadata []string) map[string]string {
 	return metadataMap
 }
 
+// sortIDs sorts peers by the given column for "peers ls".
+func sortIDs(ids []api.ID, by string) error {
+	var less func(a, b api.ID) bool
+	switch by {
+	case "id":
+		less = func(a, b api.ID) bool { return a.ID < b.ID }
+	case "name":
+		less = func(a, b api.ID) bool { return a.Peername < b.Peername }
+	case "rtt":
+		less = func(a, b api.ID) bool { return a.RTT < b.RTT }
+	case "version":
+		less = func(a, b api.ID) bool { return a.Version < b.Version }
+	case "ipfs-version":
+		less = func(a, b api.ID) bool { return a.IPFS.Version < b.IPFS.Version }
+	case "lag":
+		less = func(a, b api.ID) bool { return a.ConsensusLag < b.ConsensusLag }
+	default:
+		return fmt.Errorf("unknown sort column: %s", by)
+	}
+	sort.SliceStable(ids, func(i, j int) bool { return less(ids[i], ids[j]) })
+	return nil
+}
+
 // func setupTracing(config tracingConfig) {
 // 	if !config.Enable {
 // 		return
@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -20,9 +22,13 @@ import (
 	"github.com/ipfs-cluster/ipfs-cluster/datastore/leveldb"
 	"github.com/ipfs-cluster/ipfs-cluster/informer/disk"
 	"github.com/ipfs-cluster/ipfs-cluster/ipfsconn/ipfshttp"
+	"github.com/ipfs-cluster/ipfs-cluster/manifest"
 	"github.com/ipfs-cluster/ipfs-cluster/monitor/pubsubmon"
 	"github.com/ipfs-cluster/ipfs-cluster/observations"
 	"github.com/ipfs-cluster/ipfs-cluster/pintracker/stateless"
+	host "github.com/libp2p/go-libp2p/core/host"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	peerstore "github.com/libp2p/go-libp2p/core/peerstore"
 	"github.com/multiformats/go-multiaddr"
 	"github.com/pkg/errors"
 	cli "github.com/urfave/cli/v2"
@@ -289,6 +295,21 @@ func runCmd(c *cli.Context) error {
 	cfgHelper.Manager().Shutdown()
 	cfgs := cfgHelper.Configs()
 
+	// Manifests are always verified with the trusted peers of the
+	// configuration, even after they have been replaced by the ones in
+	// the manifest.
+	manifestURL := c.String("manifest")
+	manifestSigners := cfgs.Crdt.TrustedPeers
+	manifestPath := filepath.Join(absPath, manifestFile)
+	var currentManifest manifest.Manifest
+	if manifestURL != "" {
+		manifestURL = gatewayURL(c.String("gateway"), manifestURL)
+		currentManifest, err = applyManifest(cfgHelper, manifestURL, manifestPath)
+		if err != nil {
+			return cli.Exit(errors.Wrap(err, "applying the peerset manifest"), 1)
+		}
+	}
+
 	stmgr, err := cmdutils.NewStateManager(cfgHelper.GetConsensus(), cfgHelper.GetDatastore(), cfgHelper.Identity(), cfgs)
 	if err != nil {
 		return cli.Exit(errors.Wrap(err, "creating state manager"), 1)
@@ -301,6 +322,14 @@ func runCmd(c *cli.Context) error {
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+
+	if interval := c.Duration("watch-config"); interval > 0 {
+		source := cfgHelper.Manager().Source
+		cfgHelper.Manager().WatchSource(ctx, interval, func([]byte) {
+			fmt.Printf("WARNING: the configuration at %s has changed. Restart the follower peer to apply it.\n", source)
+		})
+	}
+
 	host, pubsub, dht, err := ipfscluster.NewClusterHost(ctx, cfgHelper.Identity(), cfgs.Cluster, store)
 	if err != nil {
 		return cli.Exit(errors.Wrap(err, "error creating libp2p components"), 1)
@@ -410,9 +439,215 @@ func runCmd(c *cli.Context) error {
 		return cli.Exit(errors.Wrap(err, "error creating cluster peer"), 1)
 	}
 
+	if interval := c.Duration("manifest-refresh"); manifestURL != "" && interval > 0 {
+		r := &manifestRefresher{
+			url:      manifestURL,
+			signers:  manifestSigners,
+			path:     manifestPath,
+			current:  currentManifest,
+			trusted:  cfgs.Crdt.TrustedPeers,
+			host:     host,
+			crdtcons: crdtcons,
+		}
+		go r.run(ctx, interval)
+	}
+
 	return cmdutils.HandleSignals(ctx, cancel, cluster, host, dht, store)
 }
 
+// gatewayURL converts IPNS names and paths to gateway URLs. URLs are
+// returned unchanged.
+func gatewayURL(gw, u string) string {
+	if strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+		return u
+	}
+	return fmt.Sprintf("http://%s/ipns/%s", gw, strings.TrimPrefix(u, "/ipns/"))
+}
+
+// manifestFile is where followers keep the last peerset manifest that they
+// accepted, in the folder of the cluster.
+const manifestFile = "manifest.json"
+
+// fetchManifest fetches a signed peerset manifest and verifies that it was
+// signed by one of the given peers and that it is not older than the last
+// manifest accepted, which is kept at path. The manifest is saved there
+// before returning it.
+func fetchManifest(ctx context.Context, manifestURL string, signers []peer.ID, path string) (manifest.Manifest, error) {
+	signed, err := manifest.Fetch(ctx, http.DefaultClient, manifestURL)
+	if err != nil {
+		return manifest.Manifest{}, err
+	}
+	m, err := signed.Open(signers)
+	if err != nil {
+		return manifest.Manifest{}, err
+	}
+	current, err := manifest.Load(path)
+	if err != nil {
+		return manifest.Manifest{}, errors.Wrap(err, "reading the last accepted manifest")
+	}
+	if err := m.CheckNewer(current); err != nil {
+		return manifest.Manifest{}, err
+	}
+	if err := manifest.Save(path, m); err != nil {
+		return manifest.Manifest{}, errors.Wrap(err, "saving the manifest")
+	}
+	return m, nil
+}
+
+// applyManifest fetches a signed peerset manifest, verifies it with the
+// trusted peers of the current configuration and uses the trusted peers and
+// peer addresses from it. Configurations that trust all peers cannot verify
+// manifests and are rejected.
+func applyManifest(cfgHelper *cmdutils.ConfigHelper, manifestURL, path string) (manifest.Manifest, error) {
+	cfgs := cfgHelper.Configs()
+	if cfgs.Crdt.TrustAll {
+		return manifest.Manifest{}, errors.New("the configuration trusts all peers, so the manifest signer cannot be verified: set the crdt trusted_peers")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	fmt.Printf("Fetching peerset manifest from %s...\n", manifestURL)
+	m, err := fetchManifest(ctx, manifestURL, cfgs.Crdt.TrustedPeers, path)
+	if err != nil {
+		return manifest.Manifest{}, err
+	}
+	fmt.Printf("Manifest issued on %s.\n", m.Timestamp.Format(time.RFC3339))
+
+	if m.ConfigHash != "" {
+		if err := checkConfigHash(ctx, cfgHelper.Manager().Source, m.ConfigHash); err != nil {
+			fmt.Printf("WARNING: %s\n", err)
+		}
+	}
+
+	if len(m.TrustedPeers) > 0 {
+		cfgs.Crdt.TrustedPeers = m.TrustedPeers
+	}
+	for _, a := range m.PeerAddresses {
+		addr, err := multiaddr.NewMultiaddr(a)
+		if err != nil {
+			return manifest.Manifest{}, errors.Wrapf(err, "parsing manifest peer address %s", a)
+		}
+		cfgs.Cluster.PeerAddresses = append(cfgs.Cluster.PeerAddresses, addr)
+	}
+	return m, nil
+}
+
+// manifestRefresher fetches the peerset manifest periodically and applies
+// the changes to the running follower: the peers added to the trusted
+// peers are trusted, those removed are distrusted, and the peer connects to
+// the new peer addresses.
+type manifestRefresher struct {
+	url     string
+	signers []peer.ID
+	path    string
+	current manifest.Manifest
+	// the peers trusted by the follower.
+	trusted  []peer.ID
+	host     host.Host
+	crdtcons *crdt.Consensus
+}
+
+func (r *manifestRefresher) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		fetchCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		m, err := fetchManifest(fetchCtx, r.url, r.signers, r.path)
+		cancel()
+		if err != nil {
+			fmt.Printf("WARNING: error refreshing the peerset manifest: %s\n", err)
+			continue
+		}
+		if m.Timestamp.Equal(r.current.Timestamp) {
+			continue
+		}
+		fmt.Printf("Applying the peerset manifest issued on %s.\n", m.Timestamp.Format(time.RFC3339))
+		r.apply(ctx, m)
+	}
+}
+
+func (r *manifestRefresher) apply(ctx context.Context, m manifest.Manifest) {
+	if len(m.TrustedPeers) > 0 {
+		for _, p := range r.trusted {
+			if !containsPeer(m.TrustedPeers, p) {
+				r.crdtcons.Distrust(ctx, p)
+			}
+		}
+		for _, p := range m.TrustedPeers {
+			r.crdtcons.Trust(ctx, p)
+		}
+		r.trusted = m.TrustedPeers
+	}
+
+	for _, a := range m.PeerAddresses {
+		if containsString(r.current.PeerAddresses, a) {
+			continue
+		}
+		ai, err := peer.AddrInfoFromString(a)
+		if err != nil {
+			fmt.Printf("WARNING: ignoring manifest peer address %s: %s\n", a, err)
+			continue
+		}
+		r.host.Peerstore().AddAddrs(ai.ID, ai.Addrs, peerstore.PermanentAddrTTL)
+		connCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		if err := r.host.Connect(connCtx, *ai); err != nil {
+			fmt.Printf("WARNING: cannot connect to %s: %s\n", a, err)
+		}
+		cancel()
+	}
+	r.current = m
+}
+
+func containsPeer(peers []peer.ID, p peer.ID) bool {
+	for _, pid := range peers {
+		if pid == p {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(strs []string, s string) bool {
+	for _, str := range strs {
+		if str == s {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConfigHash verifies that the configuration source matches the hash
+// announced in the manifest.
+func checkConfigHash(ctx context.Context, source, hash string) error {
+	if source == "" {
+		return errors.New("the configuration has no remote source to compare with the manifest configuration hash")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := config.SourceHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if manifest.HashConfig(data) != hash {
+		return fmt.Errorf("the configuration at %s does not match the manifest configuration hash", source)
+	}
+	return nil
+}
+
 // List
 func listCmd(c *cli.Context) error {
 	clusterName := c.String("clusterName")
@@ -8,6 +8,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/ipfs-cluster/ipfs-cluster/api/rest/client"
 	"github.com/ipfs-cluster/ipfs-cluster/cmdutils"
@@ -248,6 +249,22 @@ The peer will stay running in the foreground until manually stopped.
 						Name:  "init",
 						Usage: "initialize cluster peer with the given URL before running",
 					},
+					&cli.StringFlag{
+						Name:    "manifest",
+						Usage:   "bootstrap using the signed peerset manifest at the given URL or IPNS name",
+						EnvVars: []string{"CLUSTER_FOLLOW_MANIFEST"},
+					},
+					&cli.DurationFlag{
+						Name:    "manifest-refresh",
+						Value:   time.Hour,
+						Usage:   "fetch the peerset manifest again at this interval and apply the changes (0 to disable)",
+						EnvVars: []string{"CLUSTER_FOLLOW_MANIFEST_REFRESH"},
+					},
+					&cli.DurationFlag{
+						Name:    "watch-config",
+						Usage:   "check the configuration source for changes at this (randomized) interval and warn when it changes",
+						EnvVars: []string{"CLUSTER_FOLLOW_WATCH_CONFIG"},
+					},
 					&cli.StringFlag{
 						Name:    "gateway",
 						Value:   DefaultGateway,
@@ -7,6 +7,8 @@ import (
 
 	ipfscluster "github.com/ipfs-cluster/ipfs-cluster"
 	"github.com/ipfs-cluster/ipfs-cluster/allocator/balanced"
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/api/grpcapi"
 	"github.com/ipfs-cluster/ipfs-cluster/api/ipfsproxy"
 	"github.com/ipfs-cluster/ipfs-cluster/api/pinsvcapi"
 	"github.com/ipfs-cluster/ipfs-cluster/api/rest"
@@ -14,6 +16,8 @@ import (
 	"github.com/ipfs-cluster/ipfs-cluster/config"
 	"github.com/ipfs-cluster/ipfs-cluster/consensus/crdt"
 	"github.com/ipfs-cluster/ipfs-cluster/consensus/raft"
+	"github.com/ipfs-cluster/ipfs-cluster/consensus/solo"
+	"github.com/ipfs-cluster/ipfs-cluster/datastore/inmem"
 	"github.com/ipfs-cluster/ipfs-cluster/informer/disk"
 	"github.com/ipfs-cluster/ipfs-cluster/informer/pinqueue"
 	"github.com/ipfs-cluster/ipfs-cluster/informer/tags"
@@ -21,6 +25,7 @@ import (
 	"github.com/ipfs-cluster/ipfs-cluster/monitor/pubsubmon"
 	"github.com/ipfs-cluster/ipfs-cluster/observations"
 	"github.com/ipfs-cluster/ipfs-cluster/pintracker/stateless"
+	"go.opencensus.io/stats"
 	"go.opencensus.io/tag"
 
 	ds "github.com/ipfs/go-datastore"
@@ -64,6 +69,9 @@ func daemon(c *cli.Context) error {
 	checkErr("loading configurations", err)
 	defer cfgHelper.Manager().Shutdown()
 
+	err = cfgHelper.Manager().ApplyOverrides(configOverrides(c))
+	checkErr("applying configuration overrides", err)
+
 	cfgs := cfgHelper.Configs()
 
 	if c.Bool("stats") {
@@ -85,6 +93,10 @@ func daemon(c *cli.Context) error {
 			crdtCfg := cfgs.Crdt
 			crdtCfg.TrustedPeers = append(crdtCfg.TrustedPeers, ipfscluster.PeersFromMultiaddrs(bootstraps)...)
 		}
+	case cfgs.Solo.ConfigKey():
+		if len(bootstraps) > 0 {
+			checkErr("bootstrapping", solo.ErrSinglePeer)
+		}
 	}
 
 	if c.Bool("leave") {
@@ -96,8 +108,10 @@ func daemon(c *cli.Context) error {
 	host, pubsub, dht, err := ipfscluster.NewClusterHost(ctx, cfgHelper.Identity(), cfgs.Cluster, store)
 	checkErr("creating libp2p host", err)
 
-	cluster, err := createCluster(ctx, c, cfgHelper, host, pubsub, dht, store, raftStaging)
+	reloader := &apiReloader{overrides: configOverrides(c)}
+	cluster, err := createCluster(ctx, c, cfgHelper, host, pubsub, dht, store, raftStaging, reloader)
 	checkErr("starting cluster", err)
+	reloader.cluster = cluster
 
 	// noop if no bootstraps
 	// if bootstrapping fails, consensus will never be ready
@@ -116,7 +130,54 @@ func daemon(c *cli.Context) error {
 		}
 	}()
 
-	return cmdutils.HandleSignals(ctx, cancel, cluster, host, dht, store)
+	return cmdutils.HandleSignalsWithReload(ctx, cancel, cluster, host, dht, store, reloader.reload)
+}
+
+// time given to requests in flight to finish when reloading the APIs.
+var apiReloadTimeout = 30 * time.Second
+
+// apiReloader re-reads the configuration on SIGHUP and reloads the HTTP
+// endpoints of the API components with it (listen addresses, TLS
+// certificates and timeouts). Other configuration changes need a restart.
+type apiReloader struct {
+	overrides map[string]string
+	cluster   *ipfscluster.Cluster
+	restapi   *rest.API
+	pinsvcapi *pinsvcapi.API
+}
+
+func (r *apiReloader) reload(ctx context.Context) error {
+	logger.Info("reloading API endpoints configuration")
+	cfgHelper, err := cmdutils.NewLoadedConfigHelper(configPath, identityPath)
+	if err != nil {
+		return err
+	}
+	defer cfgHelper.Manager().Shutdown()
+
+	err = cfgHelper.Manager().ApplyOverrides(r.overrides)
+	if err != nil {
+		return err
+	}
+	cfgs := cfgHelper.Configs()
+
+	ctx, cancel := context.WithTimeout(ctx, apiReloadTimeout)
+	defer cancel()
+
+	if r.restapi != nil {
+		if err := r.restapi.ReloadHTTP(ctx, &cfgs.Restapi.Config); err != nil {
+			return errors.Wrap(err, "reloading the REST API")
+		}
+	}
+	if r.pinsvcapi != nil {
+		if err := r.pinsvcapi.ReloadHTTP(ctx, &cfgs.Pinsvcapi.Config); err != nil {
+			return errors.Wrap(err, "reloading the Pinning Service API")
+		}
+	}
+	r.cluster.RecordEvent(ctx, api.ClusterEvent{
+		Type:    api.ClusterEventConfigReloaded,
+		Message: "API endpoints reloaded",
+	})
+	return nil
 }
 
 // createCluster creates all the necessary things to produce the cluster
@@ -131,6 +192,7 @@ func createCluster(
 	dht *dual.DHT,
 	store ds.Datastore,
 	raftStaging bool,
+	reloader *apiReloader,
 ) (*ipfscluster.Cluster, error) {
 
 	cfgs := cfgHelper.Configs()
@@ -142,9 +204,15 @@ func createCluster(
 	ctx, err = tag.New(ctx, tag.Upsert(observations.HostKey, host.ID().Pretty()))
 	checkErr("tag context with host id", err)
 
+	cfgs.Metrics.ClusterID = host.ID().Pretty()
+	cfgs.Metrics.ClusterPeername = cfgs.Cluster.Peername
 	err = observations.SetupMetrics(cfgs.Metrics)
 	checkErr("setting up Metrics", err)
 
+	cfgMgr.SetSaveErrorHook(func(err error, failures int) {
+		stats.Record(ctx, observations.ConfigSaveErrors.M(1))
+	})
+
 	tracer, err := observations.SetupTracing(cfgs.Tracing)
 	checkErr("setting up Tracing", err)
 
@@ -161,15 +229,19 @@ func createCluster(
 			api, err = rest.NewAPI(ctx, cfgs.Restapi)
 		}
 		checkErr("creating REST API component", err)
+		api.AddHealthCheck(cfgMgr.SaveError)
 		apis = append(apis, api)
+		reloader.restapi = api
 
 	}
 
 	if cfgMgr.IsLoadedFromJSON(config.API, cfgs.Pinsvcapi.ConfigKey()) {
 		pinsvcapi, err := pinsvcapi.NewAPI(ctx, cfgs.Pinsvcapi)
 		checkErr("creating Pinning Service API component", err)
+		pinsvcapi.AddHealthCheck(cfgMgr.SaveError)
 
 		apis = append(apis, pinsvcapi)
+		reloader.pinsvcapi = pinsvcapi
 	}
 
 	if cfgMgr.IsLoadedFromJSON(config.API, cfgs.Ipfsproxy.ConfigKey()) {
@@ -179,6 +251,13 @@ func createCluster(
 		apis = append(apis, proxy)
 	}
 
+	if cfgMgr.IsLoadedFromJSON(config.API, cfgs.Grpcapi.ConfigKey()) {
+		grpcAPI, err := grpcapi.New(cfgs.Grpcapi)
+		checkErr("creating gRPC API component", err)
+
+		apis = append(apis, grpcAPI)
+	}
+
 	connector, err := ipfshttp.NewConnector(cfgs.Ipfshttp)
 	checkErr("creating IPFS Connector component", err)
 
@@ -224,7 +303,17 @@ func createCluster(
 	}
 
 	var peersF func(context.Context) ([]peer.ID, error)
-	if cfgHelper.GetConsensus() == cfgs.Raft.ConfigKey() {
+	switch cfgHelper.GetConsensus() {
+	case cfgs.Raft.ConfigKey():
+		peersF = cons.Peers
+		// Report the lack of a leader in the health endpoints.
+		for _, a := range apis {
+			if hc, ok := a.(healthChecker); ok {
+				hc.AddHealthCheck(leaderHealthCheck(cons))
+			}
+		}
+	case cfgs.Solo.ConfigKey():
+		// Only monitor this peer.
 		peersF = cons.Peers
 	}
 
@@ -266,7 +355,15 @@ func bootstrap(ctx context.Context, cluster *ipfscluster.Cluster, bootstraps []m
 	}
 }
 
+// setupDatastore opens the persistent datastore of the peer. With Raft,
+// this is not the datastore holding the state, which is kept in memory.
 func setupDatastore(cfgHelper *cmdutils.ConfigHelper) ds.Datastore {
+	if cfgHelper.GetConsensus() == cfgHelper.Configs().Raft.ConfigKey() {
+		store, err := cmdutils.NewRaftScratchStore(cfgHelper.Configs())
+		checkErr("creating datastore", err)
+		return store
+	}
+
 	dsName := cfgHelper.GetDatastore()
 	stmgr, err := cmdutils.NewStateManager(cfgHelper.GetConsensus(), dsName, cfgHelper.Identity(), cfgHelper.Configs())
 	checkErr("creating state manager", err)
@@ -278,6 +375,24 @@ func setupDatastore(cfgHelper *cmdutils.ConfigHelper) ds.Datastore {
 	return store
 }
 
+// healthChecker is implemented by APIs that provide a health endpoint.
+type healthChecker interface {
+	AddHealthCheck(check func() error)
+}
+
+// leaderHealthCheck returns a health check which fails while the consensus
+// has no leader.
+func leaderHealthCheck(cons ipfscluster.Consensus) func() error {
+	return func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if _, err := cons.Leader(ctx); err != nil {
+			return errors.Wrap(err, "no consensus leader")
+		}
+		return nil
+	}
+}
+
 func setupConsensus(
 	cfgHelper *cmdutils.ConfigHelper,
 	h host.Host,
@@ -290,10 +405,11 @@ func setupConsensus(
 	cfgs := cfgHelper.Configs()
 	switch cfgHelper.GetConsensus() {
 	case cfgs.Raft.ConfigKey():
+		// Raft rebuilds the state from its log on every start.
 		rft, err := raft.NewConsensus(
 			h,
 			cfgHelper.Configs().Raft,
-			store,
+			inmem.New(),
 			raftStaging,
 		)
 		if err != nil {
@@ -302,6 +418,12 @@ func setupConsensus(
 		ipfscluster.ReadyTimeout = cfgs.Raft.WaitForLeaderTimeout + 5*time.Second
 		return rft, nil
 	case cfgs.Crdt.ConfigKey():
+		if cfgs.Cluster.ArbiterMode {
+			return nil, errors.New("arbiter mode is only supported with Raft consensus")
+		}
+		if cfgs.Raft.NonVoter {
+			return nil, errors.New("non_voter is only supported with Raft consensus. Use follower_mode or ipfs-cluster-follow with CRDT")
+		}
 		convrdt, err := crdt.New(
 			h,
 			dht,
@@ -316,6 +438,22 @@ func setupConsensus(
 		// additional time for this consensus layer to be ready.
 		ipfscluster.ReadyTimeout = 356 * 24 * time.Hour
 		return convrdt, nil
+	case cfgs.Solo.ConfigKey():
+		if cfgs.Cluster.ArbiterMode {
+			return nil, errors.New("arbiter mode is only supported with Raft consensus")
+		}
+		if cfgs.Raft.NonVoter {
+			return nil, errors.New("non_voter is only supported with Raft consensus")
+		}
+		sl, err := solo.New(
+			h,
+			cfgHelper.Configs().Solo,
+			store,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "creating Solo component")
+		}
+		return sl, nil
 	default:
 		return nil, errors.New("unknown consensus component")
 	}
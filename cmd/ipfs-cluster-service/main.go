@@ -16,7 +16,10 @@ import (
 	ipfscluster "github.com/ipfs-cluster/ipfs-cluster"
 	"github.com/ipfs-cluster/ipfs-cluster/api"
 	"github.com/ipfs-cluster/ipfs-cluster/cmdutils"
+	"github.com/ipfs-cluster/ipfs-cluster/config"
 	"github.com/ipfs-cluster/ipfs-cluster/consensus/crdt"
+	"github.com/ipfs-cluster/ipfs-cluster/dnsresolver"
+	"github.com/ipfs-cluster/ipfs-cluster/ipfsconn/ipfshttp"
 	"github.com/ipfs-cluster/ipfs-cluster/pstoremgr"
 	"github.com/ipfs-cluster/ipfs-cluster/version"
 	peer "github.com/libp2p/go-libp2p/core/peer"
@@ -206,6 +209,17 @@ func main() {
 			EnvVar: "IPFS_CLUSTER_LOG_LEVEL",
 			Usage:  "set overall and component-wise log levels",
 		},
+		cli.StringFlag{
+			Name:   "profile",
+			EnvVar: config.EnvProfile,
+			Usage:  "apply the given `PROFILE` from the \"profiles\" configuration section",
+		},
+		cli.StringFlag{
+			Name:   "dns-resolvers",
+			EnvVar: "CLUSTER_DNS_RESOLVERS",
+			Usage: "comma-separated list of `[domain=]resolver` used to resolve DNS multiaddresses and " +
+				"remote configuration sources. Resolvers are DNS-over-HTTPS URLs or DNS server addresses",
+		},
 	}
 
 	app.Before = func(c *cli.Context) error {
@@ -223,6 +237,19 @@ func main() {
 		}
 		locker = &lock{path: absPath}
 
+		// The configuration manager picks the profile from the
+		// environment.
+		if profile := c.String("profile"); profile != "" {
+			os.Setenv(config.EnvProfile, profile)
+		}
+
+		if resolvers := c.String("dns-resolvers"); resolvers != "" {
+			err = setupDNSResolvers(resolvers)
+			if err != nil {
+				return err
+			}
+		}
+
 		return nil
 	}
 
@@ -245,7 +272,9 @@ provided with --custom-secret (in which case it will be prompted), or
 by setting the CLUSTER_SECRET environment variable.
 
 The --consensus flag allows to select an alternative consensus components for
-in the newly-generated configuration.
+in the newly-generated configuration. The "solo" consensus is meant for
+clusters made of a single peer: it writes pins directly to the datastore
+without any replication or leader election.
 
 Note that the --force flag allows to overwrite an existing
 configuration with default values. To generate a new identity, please
@@ -268,7 +297,7 @@ the peer IDs in the given multiaddresses.
 			Flags: []cli.Flag{
 				cli.StringFlag{
 					Name:  "consensus",
-					Usage: "select consensus: 'crdt' or 'raft'",
+					Usage: "select consensus: 'crdt', 'raft' or 'solo'",
 					Value: defaultConsensus,
 				},
 				cli.StringFlag{
@@ -296,9 +325,9 @@ the peer IDs in the given multiaddresses.
 			Action: func(c *cli.Context) error {
 				consensus := c.String("consensus")
 				switch consensus {
-				case "raft", "crdt":
+				case "raft", "crdt", "solo":
 				default:
-					checkErr("choosing consensus", errors.New("flag value must be set to 'raft' or 'crdt'"))
+					checkErr("choosing consensus", errors.New("flag value must be set to 'raft', 'crdt' or 'solo'"))
 				}
 
 				datastore := c.String("datastore")
@@ -360,6 +389,8 @@ the peer IDs in the given multiaddresses.
 					checkErr("randomizing ports", err)
 					cfgs.Pinsvcapi.HTTPListenAddr, err = cmdutils.RandomizePorts(cfgs.Pinsvcapi.HTTPListenAddr)
 					checkErr("randomizing ports", err)
+					cfgs.Grpcapi.ListenAddr, err = cmdutils.RandomizePorts(cfgs.Grpcapi.ListenAddr)
+					checkErr("randomizing ports", err)
 				}
 				err = cfgHelper.Manager().ApplyEnvVars()
 				checkErr("applying environment variables to configuration", err)
@@ -425,7 +456,23 @@ the peer IDs in the given multiaddresses.
 		{
 			Name:  "daemon",
 			Usage: "Runs the IPFS Cluster peer (default)",
-			Flags: []cli.Flag{
+			Description: `
+This command launches the cluster peer using the configuration and identity
+files found in the configuration folder.
+
+Any configuration value can be overridden for this run by passing a
+--<component>.<key> flag, i.e. --restapi.http_listen_multiaddress or
+--cluster.peername. These flags take precedence over the values in the
+configuration file and in environment variables. List values can be given as
+comma-separated strings.
+
+Sending SIGHUP to the daemon reloads the HTTP endpoints of the REST and
+Pinning Service APIs with the configuration read again from disk: new listen
+addresses are opened, renewed TLS certificates are used for new connections
+and requests in flight are allowed to finish. Other configuration changes
+require a restart.
+`,
+			Flags: append([]cli.Flag{
 				cli.BoolFlag{
 					Name:  "upgrade, u",
 					Usage: "run state migrations before starting (deprecated/unused)",
@@ -451,9 +498,90 @@ the peer IDs in the given multiaddresses.
 					Name:  "no-trust",
 					Usage: "do not trust bootstrap peers (only for \"crdt\" consensus)",
 				},
-			},
+			}, configOverrideFlags()...),
 			Action: daemon,
 		},
+		{
+			Name:  "config",
+			Usage: "Reads and writes the configuration of individual components",
+			Description: `
+These commands read and write the configuration of a single component in the
+configuration file, so that it can be managed without editing the whole file.
+Components are given as "cluster" or "<section>.<component>" (i.e.
+"api.restapi" or "pin_tracker.stateless"). Use "config keys" to list them.
+
+Environment variables are not applied: the values are those in the file.
+`,
+			Subcommands: []cli.Command{
+				{
+					Name:  "keys",
+					Usage: "list the configurable components",
+					Action: func(c *cli.Context) error {
+						mgr := loadConfigFile()
+						defer mgr.Shutdown()
+						for _, k := range mgr.ComponentKeys() {
+							fmt.Println(k)
+						}
+						return nil
+					},
+				},
+				{
+					Name:      "get",
+					Usage:     "print the configuration of a component",
+					ArgsUsage: "<component>",
+					Action: func(c *cli.Context) error {
+						mgr := loadConfigFile()
+						defer mgr.Shutdown()
+						bs, err := mgr.ComponentJSON(c.Args().First())
+						checkErr("reading configuration", err)
+						fmt.Printf("%s\n", bs)
+						return nil
+					},
+				},
+				{
+					Name:      "set",
+					Usage:     "replace the configuration of a component",
+					ArgsUsage: "<component>",
+					Description: `
+This command replaces the configuration of a component with the JSON object
+in the given file (or stdin). Keys which are not set take their default
+values. The configuration is validated before it is written, and the file is
+left untouched when it is not valid. The peer must not be running.
+`,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "file, f",
+							Usage: "read the configuration from this file instead of stdin",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						locker.lock()
+						defer locker.tryUnlock()
+
+						mgr := loadConfigFile()
+						defer mgr.Shutdown()
+						if mgr.Source != "" {
+							checkErr("setting configuration", fmt.Errorf("the configuration is loaded from %s", mgr.Source))
+						}
+
+						var bs []byte
+						var err error
+						if path := c.String("file"); path != "" {
+							bs, err = os.ReadFile(path)
+						} else {
+							bs, err = io.ReadAll(os.Stdin)
+						}
+						checkErr("reading configuration", err)
+
+						key := c.Args().First()
+						checkErr("setting configuration", mgr.SetComponentJSON(key, bs))
+						checkErr("saving configuration", mgr.SaveJSON(""))
+						logger.Infof("%s configuration updated", key)
+						return nil
+					},
+				},
+			},
+		},
 		{
 			Name:  "state",
 			Usage: "Manages the peer's persistent state (pinset)",
@@ -586,11 +714,16 @@ DAG operations will be run.
 				},
 				{
 					Name:  "export",
-					Usage: "save the state to a JSON file",
+					Usage: "save the state to a file",
 					Description: `
-This command dumps the current cluster pinset (state) as a JSON file. The
+This command dumps the current cluster pinset (state) to a file. The
 resulting file can be used to migrate, restore or backup a Cluster peer.
 By default, the state will be printed to stdout.
+
+The "ndjson" format (default) writes one JSON object per pin and line, which
+is easy to inspect and diff. The "car" format writes a CAR file with one
+block per pin, holding the pin as it is stored in the state. Pin schedules
+are written after the pins, as one JSON object (or block) each.
 `,
 					Flags: []cli.Flag{
 						cli.StringFlag{
@@ -598,8 +731,16 @@ By default, the state will be printed to stdout.
 							Value: "",
 							Usage: "writes to an output file",
 						},
+						cli.StringFlag{
+							Name:  "format",
+							Value: cmdutils.DefaultStateFormat,
+							Usage: "output format: ndjson or car",
+						},
 					},
 					Action: func(c *cli.Context) error {
+						format := c.String("format")
+						checkErr("checking the format", cmdutils.CheckStateFormat(format))
+
 						locker.lock()
 						defer locker.tryUnlock()
 
@@ -622,7 +763,7 @@ By default, the state will be printed to stdout.
 							buf.Flush()
 							w.Close()
 						}()
-						checkErr("exporting state", mgr.ExportState(buf))
+						checkErr("exporting state", mgr.ExportState(buf, format))
 						logger.Info("state successfully exported")
 						return nil
 					},
@@ -632,17 +773,25 @@ By default, the state will be printed to stdout.
 					Usage: "load the state from a file produced by 'export'",
 					Description: `
 This command reads in an exported pinset (state) file and replaces the
-existing one. This can be used, for example, to restore a Cluster peer from a
-backup.
+existing one, including the pin schedules. This can be used, for example, to restore a Cluster peer from a
+backup or to seed a new cluster.
 
 If an argument is provided, it will be treated it as the path of the file
-to import. If no argument is provided, stdin will be used.
+to import. If no argument is provided, stdin will be used. The --format must
+match the one used when exporting. All the pins are read and validated
+before the existing state is replaced, so the whole pinset is loaded in
+memory: plan for around 600 bytes per pin (over 1GB for two million pins).
 `,
 					Flags: []cli.Flag{
 						cli.BoolFlag{
 							Name:  "force, f",
 							Usage: "skips confirmation prompt",
 						},
+						cli.StringFlag{
+							Name:  "format",
+							Value: cmdutils.DefaultStateFormat,
+							Usage: "input format: ndjson or car",
+						},
 						cli.IntFlag{
 							Name:  "replication-min, rmin",
 							Value: 0,
@@ -659,6 +808,9 @@ to import. If no argument is provided, stdin will be used.
 						},
 					},
 					Action: func(c *cli.Context) error {
+						format := c.String("format")
+						checkErr("checking the format", cmdutils.CheckStateFormat(format))
+
 						locker.lock()
 						defer locker.tryUnlock()
 
@@ -692,11 +844,185 @@ to import. If no argument is provided, stdin will be used.
 
 						buf := bufio.NewReader(r)
 
-						checkErr("importing state", mgr.ImportState(buf, opts))
+						checkErr("importing state", mgr.ImportState(buf, format, opts))
 						logger.Info("state successfully imported.  Make sure all peers have consistent states")
 						return nil
 					},
 				},
+				{
+					Name:  "migrate",
+					Usage: "copy the state to a different consensus component",
+					Description: `
+This command reads the pinset (state) of this peer, as stored by the consensus
+component it is configured with (--from), and writes it to the storage used by
+a different one (--to), replacing any existing state there. Pin schedules are
+copied too. Afterwards, it verifies that both states hold the same number of
+pins and pin schedules.
+
+This allows switching a cluster from "raft" to "crdt" without re-pinning
+everything: stop all peers, run this command on each of them, update their
+configuration to use the new consensus component and start them again. When
+migrating to "crdt", the datastore given with --datastore (or the configured
+one) will be used, and "trusted_peers" should be set in the new
+configuration as needed.
+
+The original state is left untouched.
+`,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "from",
+							Value: "raft",
+							Usage: "consensus component to read the state from",
+						},
+						cli.StringFlag{
+							Name:  "to",
+							Value: "crdt",
+							Usage: "consensus component to write the state to",
+						},
+						cli.StringFlag{
+							Name:  "datastore",
+							Usage: datastoreFlagUsage,
+						},
+						cli.BoolFlag{
+							Name:  "force, f",
+							Usage: "skips confirmation prompt",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						locker.lock()
+						defer locker.tryUnlock()
+
+						from := c.String("from")
+						to := c.String("to")
+						for _, cons := range []string{from, to} {
+							switch cons {
+							case "raft", "crdt":
+							default:
+								checkErr("choosing consensus", errors.New("--from and --to must be set to 'raft' or 'crdt'"))
+							}
+						}
+						if from == to {
+							checkErr("choosing consensus", errors.New("--from and --to must be different"))
+						}
+
+						cfgHelper, err := cmdutils.NewLoadedConfigHelper(configPath, identityPath)
+						checkErr("loading configurations", err)
+						cfgHelper.Manager().Shutdown()
+
+						if cfgHelper.GetConsensus() != from {
+							checkErr("", fmt.Errorf("this peer is not configured to use %s consensus", from))
+						}
+
+						datastore := c.String("datastore")
+						if datastore == "" {
+							datastore = cfgHelper.GetDatastore()
+						}
+						if datastore == "" {
+							datastore = defaultDatastore
+						}
+
+						confirm := fmt.Sprintf(
+							"Any existing %s state of this peer will be replaced. Continue? [y/n]:",
+							to,
+						)
+						if !c.Bool("force") && !yesNoPrompt(confirm) {
+							return nil
+						}
+
+						src, err := cmdutils.NewStateManagerWithHelper(cfgHelper)
+						checkErr("creating state manager", err)
+						dst, err := cmdutils.NewStateManager(to, datastore, cfgHelper.Identity(), cfgHelper.Configs())
+						checkErr("creating state manager", err)
+
+						n, err := cmdutils.MigrateState(src, dst)
+						checkErr("migrating state", err)
+						logger.Infof("%d pins migrated from %s to %s", n, from, to)
+						if to == "crdt" {
+							logger.Infof("update the configuration of this peer to use crdt consensus and the %s datastore before starting it", datastore)
+						} else {
+							logger.Infof("update the configuration of this peer to use %s consensus before starting it", to)
+						}
+						return nil
+					},
+				},
+				{
+					Name:  "verify",
+					Usage: "cross-check the state with the IPFS pinset",
+					Description: `
+This command compares the pinset (state) of this peer with the items pinned
+in its IPFS daemon, which must be running. It reports:
+
+  - missing pins: allocated to this peer but not pinned in IPFS.
+  - stale pins: pinned in IPFS but allocated to other peers.
+  - orphans: pinned in IPFS but not part of the state.
+
+The pin tracker works with the pins allocated to this peer in the state, so
+missing and stale pins are those it has failed to pin or unpin. With
+--repair, missing pins are pinned and stale pins are unpinned in IPFS.
+Orphans are only reported, as they may have been pinned outside of the
+cluster.
+
+The command exits with an error when the state and the IPFS pinset diverge
+and --repair is not used.
+`,
+					Flags: []cli.Flag{
+						cli.BoolFlag{
+							Name:  "repair",
+							Usage: "pin missing and unpin stale items in IPFS",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						locker.lock()
+						defer locker.tryUnlock()
+
+						cfgHelper, err := cmdutils.NewLoadedConfigHelper(configPath, identityPath)
+						checkErr("loading configurations", err)
+						cfgHelper.Manager().Shutdown()
+						mgr, err := cmdutils.NewStateManagerWithHelper(cfgHelper)
+						checkErr("creating state manager", err)
+
+						// The cluster peer is not running: do not try to
+						// connect swarms or send metrics.
+						ipfsCfg := cfgHelper.Configs().Ipfshttp
+						ipfsCfg.ConnectSwarmsDelay = 0
+						ipfsCfg.InformerTriggerInterval = 0
+						ipfs, err := ipfshttp.NewConnector(ipfsCfg)
+						checkErr("creating IPFS connector", err)
+						defer ipfs.Shutdown(context.Background())
+
+						ctx := context.Background()
+						report, err := cmdutils.VerifyState(ctx, mgr, ipfs, cfgHelper.Identity().ID)
+						checkErr("verifying state", err)
+
+						fmt.Printf("pins in the state: %d (%d allocated to this peer)\n", report.Pins, report.Allocated)
+						for _, pin := range report.Missing {
+							fmt.Printf("missing: %s %s\n", pin.Cid, pin.Name)
+						}
+						for _, pin := range report.Stale {
+							fmt.Printf("stale: %s %s\n", pin.Cid, pin.Name)
+						}
+						for _, ci := range report.Orphans {
+							fmt.Printf("orphan: %s\n", ci)
+						}
+						fmt.Printf("missing: %d, stale: %d, orphans: %d\n", len(report.Missing), len(report.Stale), len(report.Orphans))
+
+						if !report.Diverges() {
+							return nil
+						}
+						if !c.Bool("repair") {
+							checkErr("verifying state", errors.New("the state and the IPFS pinset diverge"))
+						}
+						errs := cmdutils.RepairState(ctx, ipfs, report)
+						for _, err := range errs {
+							logger.Error(err)
+						}
+						if len(errs) > 0 {
+							checkErr("repairing state", fmt.Errorf("%d items could not be repaired", len(errs)))
+						}
+						logger.Infof("%d missing and %d stale pins repaired", len(report.Missing), len(report.Stale))
+						return nil
+					},
+				},
 				{
 					Name:  "cleanup",
 					Usage: "remove persistent data",
@@ -731,6 +1057,110 @@ to all effects. Peers may need to bootstrap and sync from scratch after this.
 				},
 			},
 		},
+		{
+			Name:  "datastore",
+			Usage: "Manages the datastore of this peer",
+			Subcommands: []cli.Command{
+				{
+					Name:  "convert",
+					Usage: "copy the datastore contents to a different datastore backend",
+					Description: `
+This command copies everything stored in the datastore of this peer (the
+pinset and any other data kept by the "crdt" or "solo" consensus components)
+to a datastore of a different type (--to), i.e. from "badger" to "pebble".
+The new datastore uses the configuration from the "datastore" section when
+present, or the defaults otherwise, and must be empty. Afterwards, it
+verifies that both datastores hold the same number of keys.
+
+The peer must be stopped. Once the conversion finishes, replace the
+configuration section of the current datastore with one for the new
+datastore and start the peer again. The original datastore is left
+untouched and can be removed afterwards.
+
+Peers using "raft" consensus do not use the datastore.
+`,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "to",
+							Usage: datastoreFlagUsage,
+						},
+						cli.BoolFlag{
+							Name:  "force, f",
+							Usage: "skips confirmation prompt",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						locker.lock()
+						defer locker.tryUnlock()
+
+						to := c.String("to")
+						switch to {
+						case "leveldb", "badger", "badger3", "pebble":
+						default:
+							checkErr("choosing datastore", errors.New("--to must be set to 'leveldb', 'badger', 'badger3' or 'pebble'"))
+						}
+
+						cfgHelper, err := cmdutils.NewLoadedConfigHelper(configPath, identityPath)
+						checkErr("loading configurations", err)
+						cfgHelper.Manager().Shutdown()
+
+						if cfgHelper.GetConsensus() == "raft" {
+							checkErr("", errors.New("peers using raft consensus do not use the datastore"))
+						}
+						from := cfgHelper.GetDatastore()
+						if from == "" {
+							checkErr("", errors.New("could not determine the datastore in use from the configuration"))
+						}
+
+						confirm := fmt.Sprintf(
+							"The contents of the %s datastore will be copied to a new %s datastore. Continue? [y/n]:",
+							from,
+							to,
+						)
+						if !c.Bool("force") && !yesNoPrompt(confirm) {
+							return nil
+						}
+
+						n, err := cmdutils.ConvertDatastore(cfgHelper.Configs(), from, to)
+						checkErr("converting datastore", err)
+						logger.Infof("%d keys copied from the %s datastore to the %s datastore", n, from, to)
+						logger.Infof("replace the %q datastore section with a %q one in the configuration of this peer before starting it", from, to)
+						return nil
+					},
+				},
+			},
+		},
+		{
+			Name:  "manifest",
+			Usage: "Creates a signed peerset manifest for follower peers",
+			Description: `
+This command creates a manifest with the trusted peers (from the "crdt"
+configuration section) and the cluster peer addresses known to this peer
+(from "peer_addresses" and the peerstore file), and signs it with this peer's
+identity. Follower peers which trust this peer can use it to bootstrap
+("ipfs-cluster-follow <cluster> run --manifest <ipns-name>").
+
+The hash of the configuration template used by followers can be included
+with --config-template. By default, the manifest is printed to stdout. With
+--publish, it is added to the local IPFS daemon and published on IPNS with
+the given --key (the node's own key by default).
+`,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "config-template",
+					Usage: "path to the follower configuration `FILE` to include its hash",
+				},
+				cli.BoolFlag{
+					Name:  "publish",
+					Usage: "publish the manifest on IPNS using the IPFS daemon",
+				},
+				cli.StringFlag{
+					Name:  "key",
+					Usage: "IPNS key `NAME` used to publish the manifest",
+				},
+			},
+			Action: manifestCmd,
+		},
 		{
 			Name:  "version",
 			Usage: "Prints the ipfs-cluster version",
@@ -753,6 +1183,21 @@ func run(c *cli.Context) error {
 	return nil
 }
 
+// setupDNSResolvers sets the default resolver for multiaddresses and the
+// HTTP client used to fetch remote configuration sources.
+func setupDNSResolvers(resolvers string) error {
+	defs, err := dnsresolver.ParseResolvers(resolvers)
+	if err != nil {
+		return err
+	}
+	rslv, err := dnsresolver.Setup(defs)
+	if err != nil {
+		return err
+	}
+	config.SourceHTTPClient = dnsresolver.HTTPClient(rslv)
+	return nil
+}
+
 func setupLogLevel(debug bool, l string) error {
 	// if debug is set to true, log everything in debug level
 	if debug {
@@ -866,6 +1311,49 @@ func yesNoPrompt(prompt string) bool {
 	return false
 }
 
+// configOverrideFlags returns a hidden flag for every configuration key of
+// the known cluster components (i.e. --restapi.http_listen_multiaddress).
+func configOverrideFlags() []cli.Flag {
+	cfgHelper := cmdutils.NewConfigHelper("", "", "", "")
+	defer cfgHelper.Manager().Shutdown()
+
+	err := cfgHelper.Manager().Default()
+	checkErr("generating default configuration", err)
+	keys, err := cfgHelper.Manager().OverrideKeys()
+	checkErr("obtaining configuration keys", err)
+
+	flags := make([]cli.Flag, 0, len(keys))
+	for _, k := range keys {
+		flags = append(flags, cli.StringFlag{
+			Name:   k,
+			Usage:  fmt.Sprintf("override the %q configuration value", k),
+			Hidden: true,
+		})
+	}
+	return flags
+}
+
+// configOverrides collects the values of any configuration override flags
+// set by the user.
+func configOverrides(c *cli.Context) map[string]string {
+	overrides := make(map[string]string)
+	for _, name := range c.FlagNames() {
+		if strings.Contains(name, ".") && c.IsSet(name) {
+			overrides[name] = c.String(name)
+		}
+	}
+	return overrides
+}
+
+// loadConfigFile loads the configuration file without applying environment
+// variables, so that it can be modified and saved.
+func loadConfigFile() *config.Manager {
+	cfgHelper := cmdutils.NewConfigHelper(configPath, identityPath, "", "")
+	mgr := cfgHelper.Manager()
+	checkErr("loading configuration", mgr.LoadJSONFromFile(configPath))
+	return mgr
+}
+
 func getStateManager() cmdutils.StateManager {
 	cfgHelper, err := cmdutils.NewLoadedConfigHelper(
 		configPath,
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/cmdutils"
+	"github.com/ipfs-cluster/ipfs-cluster/manifest"
+	"github.com/ipfs-cluster/ipfs-cluster/pstoremgr"
+
+	manet "github.com/multiformats/go-multiaddr/net"
+	cli "github.com/urfave/cli"
+)
+
+// manifestCmd creates a signed peerset manifest from the configuration of
+// this peer and optionally publishes it on IPNS.
+func manifestCmd(c *cli.Context) error {
+	cfgHelper, err := cmdutils.NewLoadedConfigHelper(configPath, identityPath)
+	checkErr("loading configurations", err)
+	defer cfgHelper.Manager().Shutdown()
+	cfgs := cfgHelper.Configs()
+
+	if cfgHelper.GetConsensus() != cfgs.Crdt.ConfigKey() {
+		checkErr("creating manifest", errors.New("manifests are only supported with the crdt consensus"))
+	}
+
+	m := manifest.Manifest{
+		TrustedPeers: cfgs.Crdt.TrustedPeers,
+	}
+	if cfgs.Crdt.TrustAll {
+		logger.Warn("this peer trusts all peers: the manifest will not list trusted peers")
+	}
+
+	for _, addr := range cfgs.Cluster.PeerAddresses {
+		m.PeerAddresses = append(m.PeerAddresses, addr.String())
+	}
+	pm := pstoremgr.New(context.Background(), nil, cfgs.Cluster.GetPeerstorePath())
+	for _, addr := range pm.LoadPeerstore() {
+		m.PeerAddresses = append(m.PeerAddresses, addr.String())
+	}
+
+	if tmpl := c.String("config-template"); tmpl != "" {
+		data, err := os.ReadFile(tmpl)
+		checkErr("reading configuration template", err)
+		m.ConfigHash = manifest.HashConfig(data)
+	}
+
+	signed, err := manifest.Sign(m, cfgHelper.Identity().PrivateKey)
+	checkErr("signing manifest", err)
+
+	if !c.Bool("publish") {
+		out, err := json.MarshalIndent(signed, "", "  ")
+		checkErr("serializing manifest", err)
+		fmt.Println(string(out))
+		return nil
+	}
+
+	_, apiAddr, err := manet.DialArgs(cfgs.Ipfshttp.NodeAddr)
+	checkErr("parsing IPFS node address", err)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	name, err := manifest.Publish(ctx, http.DefaultClient, "http://"+apiAddr, signed, c.String("key"))
+	checkErr("publishing manifest", err)
+	fmt.Printf("Manifest published at /ipns/%s\n", name)
+	return nil
+}
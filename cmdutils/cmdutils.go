@@ -109,6 +109,20 @@ func HandleSignals(
 	host host.Host,
 	dht *dual.DHT,
 	store datastore.Datastore,
+) error {
+	return HandleSignalsWithReload(ctx, cancel, cluster, host, dht, store, nil)
+}
+
+// HandleSignalsWithReload works like HandleSignals, but calls reload on
+// SIGHUP instead of shutting down, when it is not nil.
+func HandleSignalsWithReload(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	cluster *ipfscluster.Cluster,
+	host host.Host,
+	dht *dual.DHT,
+	store datastore.Datastore,
+	reload func(context.Context) error,
 ) error {
 	signalChan := make(chan os.Signal, 20)
 	signal.Notify(
@@ -121,7 +135,15 @@ func HandleSignals(
 	var ctrlcCount int
 	for {
 		select {
-		case <-signalChan:
+		case sig := <-signalChan:
+			if sig == syscall.SIGHUP && reload != nil {
+				go func() {
+					if err := reload(ctx); err != nil {
+						ErrorOut("error reloading the configuration: %s\n", err)
+					}
+				}()
+				continue
+			}
 			ctrlcCount++
 			handleCtrlC(ctx, cluster, ctrlcCount)
 		case <-cluster.Done():
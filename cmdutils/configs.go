@@ -9,12 +9,14 @@ import (
 
 	ipfscluster "github.com/ipfs-cluster/ipfs-cluster"
 	"github.com/ipfs-cluster/ipfs-cluster/allocator/balanced"
+	"github.com/ipfs-cluster/ipfs-cluster/api/grpcapi"
 	"github.com/ipfs-cluster/ipfs-cluster/api/ipfsproxy"
 	"github.com/ipfs-cluster/ipfs-cluster/api/pinsvcapi"
 	"github.com/ipfs-cluster/ipfs-cluster/api/rest"
 	"github.com/ipfs-cluster/ipfs-cluster/config"
 	"github.com/ipfs-cluster/ipfs-cluster/consensus/crdt"
 	"github.com/ipfs-cluster/ipfs-cluster/consensus/raft"
+	"github.com/ipfs-cluster/ipfs-cluster/consensus/solo"
 	"github.com/ipfs-cluster/ipfs-cluster/datastore/badger"
 	"github.com/ipfs-cluster/ipfs-cluster/datastore/badger3"
 	"github.com/ipfs-cluster/ipfs-cluster/datastore/leveldb"
@@ -35,9 +37,11 @@ type Configs struct {
 	Restapi          *rest.Config
 	Pinsvcapi        *pinsvcapi.Config
 	Ipfsproxy        *ipfsproxy.Config
+	Grpcapi          *grpcapi.Config
 	Ipfshttp         *ipfshttp.Config
 	Raft             *raft.Config
 	Crdt             *crdt.Config
+	Solo             *solo.Config
 	Statelesstracker *stateless.Config
 	Pubsubmon        *pubsubmon.Config
 	BalancedAlloc    *balanced.Config
@@ -169,22 +173,33 @@ func (ch *ConfigHelper) Configs() *Configs {
 // then it returns that.
 //
 // Otherwise it checks whether one of the consensus configurations
-// has been loaded. If both or none have been loaded, it returns
-// an empty string.
+// has been loaded. If none or more than one have been loaded, it
+// returns an empty string.
 func (ch *ConfigHelper) GetConsensus() string {
 	if ch.consensus != "" {
 		return ch.consensus
 	}
 	crdtLoaded := ch.manager.IsLoadedFromJSON(config.Consensus, ch.configs.Crdt.ConfigKey())
 	raftLoaded := ch.manager.IsLoadedFromJSON(config.Consensus, ch.configs.Raft.ConfigKey())
-	if crdtLoaded == raftLoaded { //both loaded or none
+	soloLoaded := ch.manager.IsLoadedFromJSON(config.Consensus, ch.configs.Solo.ConfigKey())
+
+	nLoaded := 0
+	for _, v := range []bool{crdtLoaded, raftLoaded, soloLoaded} {
+		if v {
+			nLoaded++
+		}
+	}
+	if nLoaded != 1 {
 		return ""
 	}
-
-	if crdtLoaded {
+	switch {
+	case crdtLoaded:
 		return ch.configs.Crdt.ConfigKey()
+	case soloLoaded:
+		return ch.configs.Solo.ConfigKey()
+	default:
+		return ch.configs.Raft.ConfigKey()
 	}
-	return ch.configs.Raft.ConfigKey()
 }
 
 // GetDatastore attempts to return the configured datastore.  If the
@@ -234,9 +249,11 @@ func (ch *ConfigHelper) init() {
 		Restapi:          rest.NewConfig(),
 		Pinsvcapi:        pinsvcapi.NewConfig(),
 		Ipfsproxy:        &ipfsproxy.Config{},
+		Grpcapi:          &grpcapi.Config{},
 		Ipfshttp:         &ipfshttp.Config{},
 		Raft:             &raft.Config{},
 		Crdt:             &crdt.Config{},
+		Solo:             &solo.Config{},
 		Statelesstracker: &stateless.Config{},
 		Pubsubmon:        &pubsubmon.Config{},
 		BalancedAlloc:    &balanced.Config{},
@@ -255,6 +272,7 @@ func (ch *ConfigHelper) init() {
 	man.RegisterComponent(config.API, cfgs.Restapi)
 	man.RegisterComponent(config.API, cfgs.Pinsvcapi)
 	man.RegisterComponent(config.API, cfgs.Ipfsproxy)
+	man.RegisterComponent(config.API, cfgs.Grpcapi)
 	man.RegisterComponent(config.IPFSConn, cfgs.Ipfshttp)
 	man.RegisterComponent(config.PinTracker, cfgs.Statelesstracker)
 	man.RegisterComponent(config.Monitor, cfgs.Pubsubmon)
@@ -274,9 +292,13 @@ func (ch *ConfigHelper) init() {
 	case cfgs.Crdt.ConfigKey():
 		man.RegisterComponent(config.Consensus, cfgs.Crdt)
 		registerDatastores = true
+	case cfgs.Solo.ConfigKey():
+		man.RegisterComponent(config.Consensus, cfgs.Solo)
+		registerDatastores = true
 	default:
 		man.RegisterComponent(config.Consensus, cfgs.Raft)
 		man.RegisterComponent(config.Consensus, cfgs.Crdt)
+		man.RegisterComponent(config.Consensus, cfgs.Solo)
 		registerDatastores = true
 	}
 
@@ -348,8 +370,10 @@ func (ch *ConfigHelper) SetupTracing(forceEnabled bool) {
 	ch.configs.Cluster.Tracing = enabled
 	ch.configs.Raft.Tracing = enabled
 	ch.configs.Crdt.Tracing = enabled
+	ch.configs.Solo.Tracing = enabled
 	ch.configs.Restapi.Tracing = enabled
 	ch.configs.Pinsvcapi.Tracing = enabled
 	ch.configs.Ipfshttp.Tracing = enabled
 	ch.configs.Ipfsproxy.Tracing = enabled
+	ch.configs.Grpcapi.Tracing = enabled
 }
@@ -2,7 +2,6 @@ package cmdutils
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -12,6 +11,7 @@ import (
 	"github.com/ipfs-cluster/ipfs-cluster/config"
 	"github.com/ipfs-cluster/ipfs-cluster/consensus/crdt"
 	"github.com/ipfs-cluster/ipfs-cluster/consensus/raft"
+	"github.com/ipfs-cluster/ipfs-cluster/consensus/solo"
 	"github.com/ipfs-cluster/ipfs-cluster/datastore/badger"
 	"github.com/ipfs-cluster/ipfs-cluster/datastore/badger3"
 	"github.com/ipfs-cluster/ipfs-cluster/datastore/inmem"
@@ -21,20 +21,27 @@ import (
 	"github.com/ipfs-cluster/ipfs-cluster/state"
 
 	ds "github.com/ipfs/go-datastore"
+	query "github.com/ipfs/go-datastore/query"
 )
 
 // StateManager is the interface that allows to import, export and clean
 // different cluster states depending on the consensus component used.
 type StateManager interface {
-	ImportState(io.Reader, api.PinOptions) error
-	ExportState(io.Writer) error
+	// ImportState replaces the state with the pins and pin schedules in
+	// a dump in the given format (see StateFormatNDJSON and
+	// StateFormatCAR). The dump is read and validated before the
+	// existing state is removed, so the whole pinset is loaded in
+	// memory.
+	ImportState(io.Reader, string, api.PinOptions) error
+	// ExportState writes the state as a dump in the given format.
+	ExportState(io.Writer, string) error
 	GetStore() (ds.Datastore, error)
 	GetOfflineState(ds.Datastore) (state.State, error)
 	Clean() error
 }
 
 // NewStateManager returns an state manager implementation for the given
-// consensus ("raft" or "crdt"). It will need initialized configs.
+// consensus ("raft", "crdt" or "solo"). It will need initialized configs.
 func NewStateManager(consensus string, datastore string, ident *config.Identity, cfgs *Configs) (StateManager, error) {
 	switch consensus {
 	case cfgs.Raft.ConfigKey():
@@ -44,6 +51,11 @@ func NewStateManager(consensus string, datastore string, ident *config.Identity,
 			cfgs:      cfgs,
 			datastore: datastore,
 		}, nil
+	case cfgs.Solo.ConfigKey():
+		return &soloStateManager{
+			cfgs:      cfgs,
+			datastore: datastore,
+		}, nil
 	case "":
 		return nil, errors.New("could not determine the consensus component")
 	default:
@@ -75,8 +87,13 @@ func (raftsm *raftStateManager) GetOfflineState(store ds.Datastore) (state.State
 	return raft.OfflineState(raftsm.cfgs.Raft, store)
 }
 
-func (raftsm *raftStateManager) ImportState(r io.Reader, opts api.PinOptions) error {
-	err := raftsm.Clean()
+func (raftsm *raftStateManager) ImportState(r io.Reader, format string, opts api.PinOptions) error {
+	pins, schedules, err := readState(r, format, opts)
+	if err != nil {
+		return err
+	}
+
+	err = raftsm.Clean()
 	if err != nil {
 		return err
 	}
@@ -90,7 +107,7 @@ func (raftsm *raftStateManager) ImportState(r io.Reader, opts api.PinOptions) er
 	if err != nil {
 		return err
 	}
-	err = importState(r, st, opts)
+	err = importState(pins, schedules, st)
 	if err != nil {
 		return err
 	}
@@ -102,7 +119,7 @@ func (raftsm *raftStateManager) ImportState(r io.Reader, opts api.PinOptions) er
 	return raft.SnapshotSave(raftsm.cfgs.Raft, st, raftPeers)
 }
 
-func (raftsm *raftStateManager) ExportState(w io.Writer) error {
+func (raftsm *raftStateManager) ExportState(w io.Writer, format string) error {
 	store, err := raftsm.GetStore()
 	if err != nil {
 		return err
@@ -112,7 +129,7 @@ func (raftsm *raftStateManager) ExportState(w io.Writer) error {
 	if err != nil {
 		return err
 	}
-	return exportState(w, st)
+	return exportState(w, st, format)
 }
 
 func (raftsm *raftStateManager) Clean() error {
@@ -125,27 +142,20 @@ type crdtStateManager struct {
 }
 
 func (crdtsm *crdtStateManager) GetStore() (ds.Datastore, error) {
-	switch crdtsm.datastore {
-	case crdtsm.cfgs.Badger.ConfigKey():
-		return badger.New(crdtsm.cfgs.Badger)
-	case crdtsm.cfgs.Badger3.ConfigKey():
-		return badger3.New(crdtsm.cfgs.Badger3)
-	case crdtsm.cfgs.LevelDB.ConfigKey():
-		return leveldb.New(crdtsm.cfgs.LevelDB)
-	case crdtsm.cfgs.Pebble.ConfigKey():
-		return pebble.New(crdtsm.cfgs.Pebble)
-	default:
-		return nil, errors.New("unknown datastore")
-	}
-
+	return newDatastore(crdtsm.cfgs, crdtsm.datastore)
 }
 
 func (crdtsm *crdtStateManager) GetOfflineState(store ds.Datastore) (state.State, error) {
 	return crdt.OfflineState(crdtsm.cfgs.Crdt, store)
 }
 
-func (crdtsm *crdtStateManager) ImportState(r io.Reader, opts api.PinOptions) error {
-	err := crdtsm.Clean()
+func (crdtsm *crdtStateManager) ImportState(r io.Reader, format string, opts api.PinOptions) error {
+	pins, schedules, err := readState(r, format, opts)
+	if err != nil {
+		return err
+	}
+
+	err = crdtsm.Clean()
 	if err != nil {
 		return err
 	}
@@ -161,7 +171,7 @@ func (crdtsm *crdtStateManager) ImportState(r io.Reader, opts api.PinOptions) er
 	}
 	batchingSt := st.(state.BatchingState)
 
-	err = importState(r, batchingSt, opts)
+	err = importState(pins, schedules, batchingSt)
 	if err != nil {
 		return err
 	}
@@ -169,7 +179,7 @@ func (crdtsm *crdtStateManager) ImportState(r io.Reader, opts api.PinOptions) er
 	return batchingSt.Commit(context.Background())
 }
 
-func (crdtsm *crdtStateManager) ExportState(w io.Writer) error {
+func (crdtsm *crdtStateManager) ExportState(w io.Writer, format string) error {
 	store, err := crdtsm.GetStore()
 	if err != nil {
 		return err
@@ -179,7 +189,7 @@ func (crdtsm *crdtStateManager) ExportState(w io.Writer) error {
 	if err != nil {
 		return err
 	}
-	return exportState(w, st)
+	return exportState(w, st, format)
 }
 
 func (crdtsm *crdtStateManager) Clean() error {
@@ -191,59 +201,349 @@ func (crdtsm *crdtStateManager) Clean() error {
 	return crdt.Clean(context.Background(), crdtsm.cfgs.Crdt, store)
 }
 
-func importState(r io.Reader, st state.State, opts api.PinOptions) error {
+type soloStateManager struct {
+	cfgs      *Configs
+	datastore string
+}
+
+func (solosm *soloStateManager) GetStore() (ds.Datastore, error) {
+	return newDatastore(solosm.cfgs, solosm.datastore)
+}
+
+func (solosm *soloStateManager) GetOfflineState(store ds.Datastore) (state.State, error) {
+	return solo.OfflineState(solosm.cfgs.Solo, store)
+}
+
+func (solosm *soloStateManager) ImportState(r io.Reader, format string, opts api.PinOptions) error {
+	pins, schedules, err := readState(r, format, opts)
+	if err != nil {
+		return err
+	}
+
+	err = solosm.Clean()
+	if err != nil {
+		return err
+	}
+
+	store, err := solosm.GetStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	st, err := solosm.GetOfflineState(store)
+	if err != nil {
+		return err
+	}
+	return importState(pins, schedules, st)
+}
+
+func (solosm *soloStateManager) ExportState(w io.Writer, format string) error {
+	store, err := solosm.GetStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	st, err := solosm.GetOfflineState(store)
+	if err != nil {
+		return err
+	}
+	return exportState(w, st, format)
+}
+
+func (solosm *soloStateManager) Clean() error {
+	store, err := solosm.GetStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	return solo.Clean(context.Background(), solosm.cfgs.Solo, store)
+}
+
+// RaftScratchSubFolder is the folder, relative to the configuration folder,
+// of the datastore where Raft peers keep their own data.
+const RaftScratchSubFolder = "raft-scratch"
+
+// NewRaftScratchStore opens the persistent datastore where a Raft peer keeps
+// the data which is not part of the shared state, such as the intent log,
+// the dead letters, the audit trail or the event history. Raft rebuilds the
+// state in memory from its log (see GetStore()), so this is a separate
+// leveldb datastore. The crdt and solo consensus keep such data in the
+// datastore holding the state.
+func NewRaftScratchStore(cfgs *Configs) (ds.Datastore, error) {
+	cfg := &leveldb.Config{}
+	err := cfg.Default()
+	if err != nil {
+		return nil, err
+	}
+	cfg.SetBaseDir(cfgs.Cluster.BaseDir)
+	cfg.Folder = RaftScratchSubFolder
+	return leveldb.New(cfg)
+}
+
+// newDatastore opens the datastore with the given name.
+func newDatastore(cfgs *Configs, datastore string) (ds.Datastore, error) {
+	switch datastore {
+	case cfgs.Badger.ConfigKey():
+		return badger.New(cfgs.Badger)
+	case cfgs.Badger3.ConfigKey():
+		return badger3.New(cfgs.Badger3)
+	case cfgs.LevelDB.ConfigKey():
+		return leveldb.New(cfgs.LevelDB)
+	case cfgs.Pebble.ConfigKey():
+		return pebble.New(cfgs.Pebble)
+	default:
+		return nil, errors.New("unknown datastore")
+	}
+}
+
+func importState(pins []api.Pin, schedules []api.PinSchedule, st state.State) error {
 	ctx := context.Background()
-	dec := json.NewDecoder(r)
-	for {
-		var pin api.Pin
-		err := dec.Decode(&pin)
-		if err == io.EOF {
-			return nil
-		}
+	for _, pin := range pins {
+		err := st.Add(ctx, pin)
 		if err != nil {
 			return err
 		}
-
-		if opts.ReplicationFactorMax > 0 {
-			pin.ReplicationFactorMax = opts.ReplicationFactorMax
+	}
+	for _, s := range schedules {
+		err := st.AddSchedule(ctx, s)
+		if err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		if opts.ReplicationFactorMin > 0 {
-			pin.ReplicationFactorMin = opts.ReplicationFactorMin
-		}
+// exportState writes all the pins and pin schedules in the state in the
+// given format.
+func exportState(w io.Writer, st state.State, format string) error {
+	pw, err := newPinWriter(w, format)
+	if err != nil {
+		return err
+	}
 
-		if len(opts.UserAllocations) > 0 {
-			// We are injecting directly to the state.
-			// UserAllocation option is not stored in the state.
-			// We need to set Allocations directly.
-			pin.Allocations = opts.UserAllocations
+	out := make(chan api.Pin, 10000)
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(errCh)
+		errCh <- st.List(context.Background(), out)
+	}()
+	for pin := range out {
+		if err == nil {
+			err = pw.write(pin)
 		}
+	}
+	if err != nil {
+		return err
+	}
+	err = <-errCh
+	if err != nil {
+		return err
+	}
 
-		err = st.Add(ctx, pin)
+	schedules, err := st.ListSchedules(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, s := range schedules {
+		err = pw.writeSchedule(s)
 		if err != nil {
 			return err
 		}
 	}
+	return nil
 }
 
-// ExportState saves a json representation of a state
-func exportState(w io.Writer, st state.State) error {
+// MigrateState copies the state managed by "from" into the one managed by
+// "to", replacing it, and verifies that both hold the same number of pins
+// afterwards. It returns the number of migrated pins. Pin schedules are
+// migrated too. Both states are accessed offline, so the peer must not be
+// running.
+func MigrateState(from, to StateManager) (int, error) {
+	fromStore, err := from.GetStore()
+	if err != nil {
+		return 0, err
+	}
+	defer fromStore.Close()
+	fromSt, err := from.GetOfflineState(fromStore)
+	if err != nil {
+		return 0, err
+	}
+	expected, err := countPins(fromSt)
+	if err != nil {
+		return 0, err
+	}
+	expectedSchedules, err := fromSt.ListSchedules(context.Background())
+	if err != nil {
+		return 0, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(exportState(pw, fromSt, StateFormatCAR))
+	}()
+	err = to.ImportState(pr, StateFormatCAR, api.PinOptions{})
+	pr.CloseWithError(err)
+	if err != nil {
+		return 0, err
+	}
+
+	toStore, err := to.GetStore()
+	if err != nil {
+		return 0, err
+	}
+	defer toStore.Close()
+	toSt, err := to.GetOfflineState(toStore)
+	if err != nil {
+		return 0, err
+	}
+	migrated, err := countPins(toSt)
+	if err != nil {
+		return 0, err
+	}
+	if migrated != expected {
+		return migrated, fmt.Errorf("migrated state has %d pins, but %d were expected", migrated, expected)
+	}
+	schedules, err := toSt.ListSchedules(context.Background())
+	if err != nil {
+		return migrated, err
+	}
+	if len(schedules) != len(expectedSchedules) {
+		return migrated, fmt.Errorf("migrated state has %d pin schedules, but %d were expected", len(schedules), len(expectedSchedules))
+	}
+	return migrated, nil
+}
+
+func countPins(st state.ReadOnly) (int, error) {
 	out := make(chan api.Pin, 10000)
 	errCh := make(chan error, 1)
 	go func() {
 		defer close(errCh)
 		errCh <- st.List(context.Background(), out)
 	}()
-	var err error
-	enc := json.NewEncoder(w)
-	for pin := range out {
-		if err == nil {
-			err = enc.Encode(pin)
-		}
+	n := 0
+	for range out {
+		n++
+	}
+	return n, <-errCh
+}
+
+// number of keys written in every batch when converting datastores.
+const convertBatchSize = 1000
+
+// ConvertDatastore copies all the keys from the datastore with the given
+// name to a datastore of a different type (i.e. from "badger" to "pebble"),
+// and verifies that both hold the same number of keys afterwards. It
+// returns the number of copied keys. The destination datastore, opened with
+// its configuration in cfgs, must be empty. Both datastores are accessed
+// offline, so the peer must not be running.
+func ConvertDatastore(cfgs *Configs, from, to string) (int, error) {
+	if from == to {
+		return 0, errors.New("source and destination datastores must be different")
 	}
+	ctx := context.Background()
+
+	src, err := newDatastore(cfgs, from)
+	if err != nil {
+		return 0, fmt.Errorf("opening %s datastore: %w", from, err)
+	}
+	defer src.Close()
+	dst, err := newDatastore(cfgs, to)
+	if err != nil {
+		return 0, fmt.Errorf("opening %s datastore: %w", to, err)
+	}
+	defer dst.Close()
+
+	existing, err := countKeys(ctx, dst)
+	if err != nil {
+		return 0, err
+	}
+	if existing > 0 {
+		return 0, fmt.Errorf("the %s datastore is not empty (%d keys)", to, existing)
+	}
+
+	n, err := copyDatastore(ctx, src, dst)
 	if err != nil {
+		return n, err
+	}
+
+	copied, err := countKeys(ctx, dst)
+	if err != nil {
+		return n, err
+	}
+	if copied != n {
+		return n, fmt.Errorf("%d keys were copied but the %s datastore has %d", n, to, copied)
+	}
+	return n, nil
+}
+
+// copyDatastore writes all the keys in src to dst, in batches when dst
+// supports them.
+func copyDatastore(ctx context.Context, src, dst ds.Datastore) (int, error) {
+	results, err := src.Query(ctx, query.Query{})
+	if err != nil {
+		return 0, err
+	}
+	defer results.Close()
+
+	var batch ds.Batch
+	batching, isBatching := dst.(ds.Batching)
+	newBatch := func() error {
+		if !isBatching {
+			return nil
+		}
+		batch, err = batching.Batch(ctx)
 		return err
 	}
-	err = <-errCh
-	return err
+	if err := newBatch(); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for r := range results.Next() {
+		if r.Error != nil {
+			return n, r.Error
+		}
+		k := ds.NewKey(r.Key)
+		if batch != nil {
+			err = batch.Put(ctx, k, r.Value)
+		} else {
+			err = dst.Put(ctx, k, r.Value)
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+
+		if batch != nil && n%convertBatchSize == 0 {
+			if err := batch.Commit(ctx); err != nil {
+				return n, err
+			}
+			if err := newBatch(); err != nil {
+				return n, err
+			}
+		}
+	}
+	if batch != nil {
+		if err := batch.Commit(ctx); err != nil {
+			return n, err
+		}
+	}
+	return n, dst.Sync(ctx, ds.NewKey("/"))
+}
+
+func countKeys(ctx context.Context, store ds.Datastore) (int, error) {
+	results, err := store.Query(ctx, query.Query{KeysOnly: true})
+	if err != nil {
+		return 0, err
+	}
+	defer results.Close()
+
+	n := 0
+	for r := range results.Next() {
+		if r.Error != nil {
+			return n, r.Error
+		}
+		n++
+	}
+	return n, nil
 }
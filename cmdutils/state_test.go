@@ -0,0 +1,289 @@
+package cmdutils
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/config"
+	"github.com/ipfs-cluster/ipfs-cluster/test"
+
+	ds "github.com/ipfs/go-datastore"
+	query "github.com/ipfs/go-datastore/query"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+// testConfigHelper returns a config helper with the default configuration
+// for all the consensus and datastore components, using a temporary folder.
+func testConfigHelper(t *testing.T) *ConfigHelper {
+	t.Helper()
+	dir := t.TempDir()
+	ch := NewConfigHelper(
+		filepath.Join(dir, "service.json"),
+		filepath.Join(dir, "identity.json"),
+		"",
+		"",
+	)
+	t.Cleanup(func() { ch.Manager().Shutdown() })
+
+	err := ch.Manager().Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ident, err := config.NewIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch.identity = ident
+	// Saving sets the base folder of every component.
+	err = ch.SaveConfigToDisk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ch
+}
+
+func testStateManager(t *testing.T, ch *ConfigHelper, consensus, datastore string) StateManager {
+	t.Helper()
+	mgr, err := NewStateManager(consensus, datastore, ch.Identity(), ch.Configs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mgr
+}
+
+func testStatePins() []api.Pin {
+	pin1 := api.PinWithOpts(test.Cid1, api.PinOptions{
+		ReplicationFactorMin: -1,
+		ReplicationFactorMax: -1,
+		Name:                 "pin1",
+	})
+	pin2 := api.PinWithOpts(test.Cid2, api.PinOptions{
+		ReplicationFactorMin: 1,
+		ReplicationFactorMax: 2,
+		Name:                 "pin2",
+		Metadata:             map[string]string{"key": "value"},
+	})
+	pin2.Allocations = []peer.ID{test.PeerID1, test.PeerID2}
+	pin3 := api.PinWithOpts(test.Cid3, api.PinOptions{
+		ReplicationFactorMin: -1,
+		ReplicationFactorMax: -1,
+	})
+	pin3.Mode = api.PinModeDirect
+	pin3.MaxDepth = 0
+	return []api.Pin{pin1, pin2, pin3}
+}
+
+// importPins replaces the state of the manager with the given pins and
+// pin schedules.
+func importPins(t *testing.T, mgr StateManager, pins []api.Pin, schedules ...api.PinSchedule) {
+	t.Helper()
+	var buf bytes.Buffer
+	pw, err := newPinWriter(&buf, StateFormatNDJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pin := range pins {
+		if err := pw.write(pin); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, s := range schedules {
+		if err := pw.writeSchedule(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = mgr.ImportState(&buf, StateFormatNDJSON, api.PinOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// exportPins returns the pins in the state of the manager.
+func exportPins(t *testing.T, mgr StateManager) []api.Pin {
+	t.Helper()
+	pins, _ := exportDump(t, mgr, StateFormatNDJSON)
+	return pins
+}
+
+// exportDump returns the pins and pin schedules in the state of the
+// manager, exported in the given format.
+func exportDump(t *testing.T, mgr StateManager, format string) ([]api.Pin, []api.PinSchedule) {
+	t.Helper()
+	var buf bytes.Buffer
+	err := mgr.ExportState(&buf, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pins, schedules, err := readState(&buf, format, api.PinOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pins, schedules
+}
+
+func checkPins(t *testing.T, got, expected []api.Pin) {
+	t.Helper()
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d pins, got %d", len(expected), len(got))
+	}
+	for _, exp := range expected {
+		found := false
+		for _, pin := range got {
+			if pin.Cid.Equals(exp.Cid) {
+				found = true
+				if !pin.Equals(exp) {
+					t.Errorf("pin %s changed: %+v", exp.Cid, pin)
+				}
+				if pin.Name != exp.Name || pin.Metadata["key"] != exp.Metadata["key"] {
+					t.Errorf("pin %s lost its options: %+v", exp.Cid, pin)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("pin %s is missing", exp.Cid)
+		}
+	}
+}
+
+func checkSchedules(t *testing.T, got []api.PinSchedule, expected api.PinSchedule) {
+	t.Helper()
+	if len(got) != 1 || got[0].ID != expected.ID || got[0].Path != expected.Path ||
+		got[0].Cron != expected.Cron || got[0].Options.Name != expected.Options.Name {
+		t.Errorf("expected the pin schedule %+v, got %+v", expected, got)
+	}
+}
+
+func TestMigrateState(t *testing.T) {
+	ch := testConfigHelper(t)
+	raftMgr := testStateManager(t, ch, "raft", "")
+	crdtMgr := testStateManager(t, ch, "crdt", "pebble")
+
+	pins := testStatePins()
+	schedule := api.PinSchedule{
+		ID:      "0b6a7d2e-3f4c-4c1e-9a55-8d3f3c2e1b7a",
+		Path:    "/ipns/example.org",
+		Cron:    "0 3 * * *",
+		Options: api.PinOptions{Name: "nightly"},
+	}
+	importPins(t, raftMgr, pins, schedule)
+	// The destination state is replaced.
+	importPins(t, crdtMgr, []api.Pin{api.PinCid(test.Cid4)})
+
+	n, err := MigrateState(raftMgr, crdtMgr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(pins) {
+		t.Errorf("expected %d migrated pins, got %d", len(pins), n)
+	}
+	for _, format := range []string{StateFormatNDJSON, StateFormatCAR} {
+		migratedPins, schedules := exportDump(t, crdtMgr, format)
+		checkPins(t, migratedPins, pins)
+		checkSchedules(t, schedules, schedule)
+	}
+	// The source state is left untouched.
+	checkPins(t, exportPins(t, raftMgr), pins)
+}
+
+// readDatastore returns all the keys and values in the given datastore.
+func readDatastore(t *testing.T, cfgs *Configs, datastore string) map[string]string {
+	t.Helper()
+	ctx := context.Background()
+	store, err := newDatastore(cfgs, datastore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	results, err := store.Query(ctx, query.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer results.Close()
+	entries := make(map[string]string)
+	for r := range results.Next() {
+		if r.Error != nil {
+			t.Fatal(r.Error)
+		}
+		entries[r.Key] = string(r.Value)
+	}
+	return entries
+}
+
+func checkDatastore(t *testing.T, got, expected map[string]string) {
+	t.Helper()
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d keys, got %d", len(expected), len(got))
+	}
+	for k, v := range expected {
+		if got[k] != v {
+			t.Errorf("the value of %s was not copied", k)
+		}
+	}
+}
+
+func TestConvertDatastore(t *testing.T) {
+	ch := testConfigHelper(t)
+	cfgs := ch.Configs()
+	pins := testStatePins()
+	importPins(t, testStateManager(t, ch, "crdt", "pebble"), pins)
+	original := readDatastore(t, cfgs, "pebble")
+
+	n, err := ConvertDatastore(cfgs, "pebble", "leveldb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(original) {
+		t.Errorf("expected %d converted keys, got %d", len(original), n)
+	}
+	checkDatastore(t, readDatastore(t, cfgs, "leveldb"), original)
+	checkPins(t, exportPins(t, testStateManager(t, ch, "crdt", "leveldb")), pins)
+
+	// Converting into a datastore with keys fails.
+	_, err = ConvertDatastore(cfgs, "pebble", "leveldb")
+	if err == nil {
+		t.Error("expected an error converting into a non-empty datastore")
+	}
+	_, err = ConvertDatastore(cfgs, "leveldb", "leveldb")
+	if err == nil {
+		t.Error("expected an error converting a datastore into itself")
+	}
+
+	// Converting back into an empty pebble datastore gives the original
+	// keys.
+	cfgs.Pebble.Folder = "pebble2"
+	_, err = ConvertDatastore(cfgs, "leveldb", "pebble")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDatastore(t, readDatastore(t, cfgs, "pebble"), original)
+	checkPins(t, exportPins(t, testStateManager(t, ch, "crdt", "pebble")), pins)
+}
+
+func TestRaftScratchStore(t *testing.T) {
+	ctx := context.Background()
+	ch := testConfigHelper(t)
+	key := ds.NewKey("/intents/pin/a")
+
+	store, err := NewRaftScratchStore(ch.Configs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Put(ctx, key, []byte("b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	store, err = NewRaftScratchStore(ch.Configs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	v, err := store.Get(ctx, key)
+	if err != nil || string(v) != "b" {
+		t.Errorf("the scratch store should survive restarts: %s, %s", v, err)
+	}
+}
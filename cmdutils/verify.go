@@ -0,0 +1,132 @@
+package cmdutils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	ipfscluster "github.com/ipfs-cluster/ipfs-cluster"
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/state"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+// StateReport is the result of cross-checking the state of a peer with the
+// pinset of its IPFS daemon.
+type StateReport struct {
+	// Pins is the number of pins in the state.
+	Pins int
+	// Allocated is the number of pins in the state that this peer
+	// should have pinned in IPFS. This is what the pin tracker works
+	// with.
+	Allocated int
+	// Missing are the pins allocated to this peer which are not pinned
+	// in IPFS.
+	Missing []api.Pin
+	// Stale are the pins in the state which are pinned in IPFS but not
+	// allocated to this peer anymore.
+	Stale []api.Pin
+	// Orphans are the items pinned in IPFS which are not in the state.
+	// They may have been pinned outside of the cluster.
+	Orphans []api.Cid
+}
+
+// Diverges returns true when the state and the IPFS pinset do not match.
+func (r StateReport) Diverges() bool {
+	return len(r.Missing) > 0 || len(r.Stale) > 0 || len(r.Orphans) > 0
+}
+
+// VerifyState compares the state of the given peer, read offline, with the
+// recursive and direct pins in its IPFS daemon.
+func VerifyState(ctx context.Context, mgr StateManager, ipfs ipfscluster.IPFSConnector, pid peer.ID) (StateReport, error) {
+	var report StateReport
+
+	ipfsPins := make(map[api.Cid]api.IPFSPinStatus)
+	pinLs := make(chan api.IPFSPinInfo, 1024)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- ipfs.PinLs(ctx, []string{"recursive", "direct"}, pinLs)
+	}()
+	for pi := range pinLs {
+		ipfsPins[pi.Cid] = pi.Type
+	}
+	if err := <-errCh; err != nil {
+		return report, err
+	}
+
+	store, err := mgr.GetStore()
+	if err != nil {
+		return report, err
+	}
+	defer store.Close()
+	st, err := mgr.GetOfflineState(store)
+	if err != nil {
+		return report, err
+	}
+
+	err = compareState(ctx, st, ipfsPins, pid, &report)
+	if err != nil {
+		return report, err
+	}
+	sort.Slice(report.Orphans, func(i, j int) bool {
+		return report.Orphans[i].String() < report.Orphans[j].String()
+	})
+	return report, nil
+}
+
+func compareState(ctx context.Context, st state.ReadOnly, ipfsPins map[api.Cid]api.IPFSPinStatus, pid peer.ID, report *StateReport) error {
+	pins := make(chan api.Pin, 1024)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- st.List(ctx, pins)
+	}()
+
+	for pin := range pins {
+		report.Pins++
+		ipfsStatus, inIPFS := ipfsPins[pin.Cid]
+		delete(ipfsPins, pin.Cid)
+
+		// Meta pins are not pinned in IPFS.
+		if pin.Type == api.MetaType {
+			continue
+		}
+
+		if pin.IsRemotePin(pid) {
+			if inIPFS {
+				report.Stale = append(report.Stale, pin)
+			}
+			continue
+		}
+		report.Allocated++
+		if !ipfsStatus.IsPinned(pin.MaxDepth) {
+			report.Missing = append(report.Missing, pin)
+		}
+	}
+	if err := <-errCh; err != nil {
+		return err
+	}
+
+	for c := range ipfsPins {
+		report.Orphans = append(report.Orphans, c)
+	}
+	return nil
+}
+
+// RepairState pins the missing items of a report in IPFS and unpins the
+// stale ones, as the pin tracker would. Orphans are left alone. It returns
+// the errors for the items that could not be repaired.
+func RepairState(ctx context.Context, ipfs ipfscluster.IPFSConnector, report StateReport) []error {
+	var errs []error
+	for _, pin := range report.Missing {
+		if err := ipfs.Pin(ctx, pin); err != nil {
+			errs = append(errs, fmt.Errorf("error pinning %s: %w", pin.Cid, err))
+		}
+	}
+	for _, pin := range report.Stale {
+		if err := ipfs.Unpin(ctx, pin.Cid); err != nil {
+			errs = append(errs, fmt.Errorf("error unpinning %s: %w", pin.Cid, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,223 @@
+package cmdutils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/datastore/inmem"
+	"github.com/ipfs-cluster/ipfs-cluster/ipfsconn/ipfshttp"
+	"github.com/ipfs-cluster/ipfs-cluster/state"
+	"github.com/ipfs-cluster/ipfs-cluster/state/dsstate"
+	"github.com/ipfs-cluster/ipfs-cluster/test"
+
+	ds "github.com/ipfs/go-datastore"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// memStateManager is a StateManager for a state kept in memory.
+type memStateManager struct {
+	StateManager
+	store ds.Datastore
+}
+
+func newMemStateManager() *memStateManager {
+	return &memStateManager{store: inmem.New()}
+}
+
+func (sm *memStateManager) GetStore() (ds.Datastore, error) {
+	// VerifyState closes the store when done.
+	return nopCloser{sm.store}, nil
+}
+
+func (sm *memStateManager) GetOfflineState(store ds.Datastore) (state.State, error) {
+	return dsstate.New(context.Background(), store, "", dsstate.DefaultHandle())
+}
+
+type nopCloser struct {
+	ds.Datastore
+}
+
+func (nopCloser) Close() error { return nil }
+
+func testPin(c api.Cid, allocations ...peer.ID) api.Pin {
+	pin := api.PinWithOpts(c, api.PinOptions{
+		ReplicationFactorMin: 1,
+		ReplicationFactorMax: 1,
+	})
+	pin.Allocations = allocations
+	return pin
+}
+
+func addPins(t *testing.T, st state.State, pins ...api.Pin) {
+	t.Helper()
+	for _, pin := range pins {
+		if err := st.Add(context.Background(), pin); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// cids returns the sorted CIDs of the given pins.
+func cids(pins []api.Pin) []api.Cid {
+	var out []api.Cid
+	for _, pin := range pins {
+		out = append(out, pin.Cid)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].String() < out[j].String()
+	})
+	return out
+}
+
+func newTestState(t *testing.T, store ds.Datastore) state.State {
+	t.Helper()
+	st, err := dsstate.New(context.Background(), store, "", dsstate.DefaultHandle())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestCompareState(t *testing.T) {
+	ctx := context.Background()
+	pid := test.PeerID1
+
+	st := newTestState(t, inmem.New())
+
+	meta := testPin(test.Cid4)
+	meta.Type = api.MetaType
+	everywhere := testPin(test.Cid5)
+	everywhere.ReplicationFactorMin = -1
+	everywhere.ReplicationFactorMax = -1
+	direct := testPin(test.SlowCid1, pid)
+	direct.MaxDepth = 0
+	addPins(t, st,
+		testPin(test.Cid1, pid),                 // ok
+		testPin(test.Cid2, pid),                 // missing
+		testPin(test.Cid3, test.PeerID2),        // stale
+		testPin(test.CidResolved, test.PeerID2), // remote, not pinned
+		meta,                                    // not pinned in IPFS
+		everywhere,                              // ok
+		direct,                                  // pinned recursively
+	)
+
+	ipfsPins := map[api.Cid]api.IPFSPinStatus{
+		test.Cid1:     api.IPFSPinStatusRecursive,
+		test.Cid3:     api.IPFSPinStatusRecursive,
+		test.Cid4:     api.IPFSPinStatusRecursive,
+		test.Cid5:     api.IPFSPinStatusRecursive,
+		test.SlowCid1: api.IPFSPinStatusRecursive,
+		test.ErrorCid: api.IPFSPinStatusDirect, // orphan
+	}
+
+	var report StateReport
+	err := compareState(ctx, st, ipfsPins, pid, &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Pins != 7 {
+		t.Errorf("expected 7 pins, got %d", report.Pins)
+	}
+	if report.Allocated != 4 {
+		t.Errorf("expected 4 allocated pins, got %d", report.Allocated)
+	}
+	expect := func(name string, got []api.Cid, want ...api.Cid) {
+		t.Helper()
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: expected %s, got %s", name, want, got)
+		}
+	}
+	expect("missing", cids(report.Missing), cids([]api.Pin{testPin(test.Cid2), direct})...)
+	expect("stale", cids(report.Stale), test.Cid3)
+	expect("orphans", report.Orphans, test.ErrorCid)
+	if !report.Diverges() {
+		t.Error("the report should diverge")
+	}
+
+	// Matching pinsets do not diverge.
+	st = newTestState(t, inmem.New())
+	addPins(t, st, testPin(test.Cid1, pid), meta)
+	report = StateReport{}
+	err = compareState(ctx, st, map[api.Cid]api.IPFSPinStatus{test.Cid1: api.IPFSPinStatusRecursive}, pid, &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Diverges() || report.Pins != 2 || report.Allocated != 1 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func testIPFSConnector(t *testing.T) (*ipfshttp.Connector, *test.IpfsMock) {
+	mock := test.NewIpfsMock(t)
+	cfg := &ipfshttp.Config{}
+	cfg.Default()
+	cfg.NodeAddr = ma.StringCast(fmt.Sprintf("/ip4/%s/tcp/%d", mock.Addr, mock.Port))
+	cfg.ConnectSwarmsDelay = 0
+
+	ipfs, err := ipfshttp.NewConnector(cfg)
+	if err != nil {
+		mock.Close()
+		t.Fatal(err)
+	}
+	ipfs.SetClient(test.NewMockRPCClient(t))
+	return ipfs, mock
+}
+
+func TestVerifyAndRepairState(t *testing.T) {
+	ctx := context.Background()
+	pid := test.PeerID1
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+
+	mgr := newMemStateManager()
+	st := newTestState(t, mgr.store)
+	addPins(t, st,
+		testPin(test.Cid1, pid),          // ok
+		testPin(test.Cid2, pid),          // missing
+		testPin(test.Cid3, test.PeerID2), // stale
+	)
+	for _, c := range []api.Cid{test.Cid1, test.Cid3, test.Cid4} {
+		if err := ipfs.Pin(ctx, api.PinCid(c)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := VerifyState(ctx, mgr, ipfs, pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Missing) != 1 || len(report.Stale) != 1 || len(report.Orphans) != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	errs := RepairState(ctx, ipfs, report)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	report, err = VerifyState(ctx, mgr, ipfs, pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Missing) != 0 || len(report.Stale) != 0 {
+		t.Errorf("the state should have been repaired: %+v", report)
+	}
+	// Orphans are left alone.
+	if len(report.Orphans) != 1 || !report.Orphans[0].Equals(test.Cid4) {
+		t.Errorf("the orphan should still be pinned: %+v", report.Orphans)
+	}
+
+	// Errors are reported for every item that cannot be repaired.
+	errs = RepairState(ctx, ipfs, StateReport{
+		Missing: []api.Pin{testPin(test.ErrorCid, pid)},
+	})
+	if len(errs) != 1 {
+		t.Errorf("expected an error pinning %s: %v", test.ErrorCid, errs)
+	}
+}
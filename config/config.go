@@ -8,10 +8,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,6 +26,11 @@ var (
 	errFetchingSource = errors.New("could not fetch configuration from source")
 	// Error when remote source points to another remote-source
 	errSourceRedirect = errors.New("a sourced configuration cannot point to another source")
+	// ErrSaveFailed is wrapped by the errors returned by SaveError.
+	ErrSaveFailed = errors.New("configuration could not be saved")
+	// ErrUnknownKeys is returned by LoadJSON in strict mode when a
+	// configuration section contains keys not used by its component.
+	ErrUnknownKeys = errors.New("unknown configuration keys")
 )
 
 // IsErrFetchingSource reports whether this error happened when trying to
@@ -34,10 +40,23 @@ func IsErrFetchingSource(err error) bool {
 	return errors.Is(err, errFetchingSource)
 }
 
+// EnvProfile is the name of the environment variable that can be used to
+// select a configuration profile when Manager.Profile is not set.
+const EnvProfile = "CLUSTER_PROFILE"
+
 // ConfigSaveInterval specifies how often to save the configuration file if
 // it needs saving.
 var ConfigSaveInterval = time.Second
 
+// ConfigSaveMaxRetryDelay specifies the maximum time to wait between
+// attempts to save the configuration after a failure. The delay starts at
+// ConfigSaveInterval and doubles with every failed attempt.
+var ConfigSaveMaxRetryDelay = 5 * time.Minute
+
+// SourceHTTPClient is the HTTP client used to fetch remote configuration
+// sources.
+var SourceHTTPClient = http.DefaultClient
+
 // The ComponentConfig interface allows components to define configurations
 // which can be managed as part of the ipfs-cluster configuration file by the
 // Manager.
@@ -63,6 +82,14 @@ type ComponentConfig interface {
 	ToDisplayJSON() ([]byte, error)
 }
 
+// JSONKeyer is implemented by ComponentConfigs which can list all the keys
+// that their JSON representation may contain, including those omitted when
+// empty. The Manager uses it to recognize unknown and overridable keys, and
+// relies on the output of ToJSON for components that do not implement it.
+type JSONKeyer interface {
+	JSONKeys() []string
+}
+
 // These are the component configuration types
 // supported by the Manager.
 const (
@@ -124,8 +151,27 @@ type Manager struct {
 	// stores original source if any
 	Source string
 
+	// Profile selects one of the profiles defined in the "profiles"
+	// section of the configuration. The values in the profile override
+	// those in the main sections. When empty, the value of the
+	// CLUSTER_PROFILE environment variable is used.
+	Profile string
+	// base values of the keys overridden by the loaded profile, per
+	// component, so that they are not persisted when saving.
+	profileBase map[string]map[string]*json.RawMessage
+
+	// Strict makes LoadJSON fail when a section contains keys which are
+	// not used by the component it configures. Otherwise, a warning is
+	// logged. It can also be enabled with "strict": true at the top of the
+	// configuration.
+	Strict bool
+
 	sourceRedirs int // used avoid recursive source load
 
+	// last fetched version of the remote source
+	sourceMux sync.Mutex
+	source    sourceState
+
 	// map of components which has empty configuration
 	// in JSON file
 	undefinedComps map[SectionType]map[string]bool
@@ -134,6 +180,13 @@ type Manager struct {
 	// so it can be saved to the same place.
 	path    string
 	saveMux sync.Mutex
+
+	// tracks failures when saving the configuration
+	saveStatusMux sync.Mutex
+	saveErr       error
+	saveErrSince  time.Time
+	saveFailures  int
+	saveErrHook   func(err error, failures int)
 }
 
 // NewManager returns a correctly initialized Manager
@@ -159,7 +212,8 @@ func (cfg *Manager) Shutdown() {
 // this watches a save channel which is used to signal that
 // we need to store changes in the configuration.
 // because saving can be called too much, we will only
-// save at intervals of 1 save/second at most.
+// save at intervals of 1 save/second at most. When saving
+// fails, we retry with an exponential backoff until it works.
 func (cfg *Manager) watchSave(save <-chan struct{}) {
 	defer cfg.wg.Done()
 
@@ -168,18 +222,28 @@ func (cfg *Manager) watchSave(save <-chan struct{}) {
 	defer ticker.Stop()
 
 	thingsToSave := false
+	retryDelay := ConfigSaveInterval
+	var retryAt time.Time
 
 	for {
 		select {
 		case <-save:
 			thingsToSave = true
 		case <-ticker.C:
-			if thingsToSave {
+			if thingsToSave && !time.Now().Before(retryAt) {
 				err := cfg.SaveJSON("")
 				if err != nil {
-					logger.Error(err)
+					logger.Errorf("%s. Retrying in %s", err, retryDelay)
+					retryAt = time.Now().Add(retryDelay)
+					retryDelay *= 2
+					if retryDelay > ConfigSaveMaxRetryDelay {
+						retryDelay = ConfigSaveMaxRetryDelay
+					}
+				} else {
+					thingsToSave = false
+					retryDelay = ConfigSaveInterval
+					retryAt = time.Time{}
 				}
-				thingsToSave = false
 			}
 
 			// Exit if we have to
@@ -197,6 +261,7 @@ func (cfg *Manager) watchSave(save <-chan struct{}) {
 // like strings, and key names aim to be self-explanatory for the user.
 type jsonConfig struct {
 	Source       string           `json:"source,omitempty"`
+	Strict       bool             `json:"strict,omitempty"`
 	Cluster      *json.RawMessage `json:"cluster,omitempty"`
 	Consensus    jsonSection      `json:"consensus,omitempty"`
 	API          jsonSection      `json:"api,omitempty"`
@@ -208,6 +273,11 @@ type jsonConfig struct {
 	Informer     jsonSection      `json:"informer,omitempty"`
 	Observations jsonSection      `json:"observations,omitempty"`
 	Datastore    jsonSection      `json:"datastore,omitempty"`
+
+	// Profiles have the same layout as the configuration itself and
+	// hold values that override those in the main sections when the
+	// profile is selected.
+	Profiles map[string]*jsonConfig `json:"profiles,omitempty"`
 }
 
 func (jcfg *jsonConfig) getSection(i SectionType) *jsonSection {
@@ -282,6 +352,172 @@ func (cfg *Manager) ApplyEnvVars() error {
 	return nil
 }
 
+// OverrideKeys returns the list of configuration keys that can be set with
+// ApplyOverrides, in the form "<component>.<key>" (i.e.
+// "restapi.http_listen_multiaddress"). The keys are taken from the JSON
+// configuration structs of the registered components (see JSONKeyer), so
+// keys omitted when empty are included.
+func (cfg *Manager) OverrideKeys() ([]string, error) {
+	var keys []string
+	for _, compcfg := range cfg.components() {
+		known, err := componentKeys(compcfg)
+		if err != nil {
+			return nil, err
+		}
+		for k := range known {
+			keys = append(keys, compcfg.ConfigKey()+"."+k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// ApplyOverrides sets configuration values for the registered components,
+// taking precedence over values loaded from JSON and from environment
+// variables. It should therefore be called after ApplyEnvVars.
+//
+// The overrides map is keyed by "<component>.<key>" (see OverrideKeys). Values
+// are interpreted according to the type of the current value: strings are
+// used verbatim, lists can be given as comma-separated values and anything
+// else must be valid JSON.
+func (cfg *Manager) ApplyOverrides(overrides map[string]string) error {
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	byComponent := make(map[string]map[string]string)
+	for k, v := range overrides {
+		name, key, ok := strings.Cut(k, ".")
+		if !ok || name == "" || key == "" {
+			return fmt.Errorf("override key not in \"component.key\" format: %s", k)
+		}
+		if byComponent[name] == nil {
+			byComponent[name] = make(map[string]string)
+		}
+		byComponent[name][key] = v
+	}
+
+	components := make(map[string]ComponentConfig)
+	for _, compcfg := range cfg.components() {
+		components[compcfg.ConfigKey()] = compcfg
+	}
+
+	for name, values := range byComponent {
+		compcfg, ok := components[name]
+		if !ok {
+			return fmt.Errorf("cannot override %s configuration: component not registered", name)
+		}
+
+		obj, err := componentJSONObject(compcfg)
+		if err != nil {
+			return err
+		}
+		known, err := componentKeys(compcfg)
+		if err != nil {
+			return err
+		}
+
+		for key, value := range values {
+			if _, ok := known[key]; !ok {
+				return fmt.Errorf("cannot override %s.%s: unknown configuration key", name, key)
+			}
+			// Keys omitted because they are empty are
+			// overridden as if they were null.
+			current, ok := obj[key]
+			if !ok {
+				current = json.RawMessage("null")
+			}
+			raw, err := overrideValue(current, value)
+			if err != nil {
+				return fmt.Errorf("cannot override %s.%s: %w", name, key, err)
+			}
+			obj[key] = raw
+			logger.Infof("configuration value %s.%s overridden", name, key)
+		}
+
+		bs, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		err = compcfg.LoadJSON(bs)
+		if err != nil {
+			return fmt.Errorf("error applying overrides to %s: %w", name, err)
+		}
+	}
+	return cfg.Validate()
+}
+
+// components returns all registered component configurations, including
+// the cluster one.
+func (cfg *Manager) components() []ComponentConfig {
+	var comps []ComponentConfig
+	if cfg.clusterConfig != nil {
+		comps = append(comps, cfg.clusterConfig)
+	}
+	for _, t := range SectionTypes() {
+		for _, compcfg := range cfg.sections[t] {
+			comps = append(comps, compcfg)
+		}
+	}
+	return comps
+}
+
+// componentKeys returns the set of top-level keys that the JSON
+// representation of a component configuration may have.
+func componentKeys(compcfg ComponentConfig) (map[string]struct{}, error) {
+	keys := make(map[string]struct{})
+	if keyer, ok := compcfg.(JSONKeyer); ok {
+		for _, k := range keyer.JSONKeys() {
+			keys[k] = struct{}{}
+		}
+		return keys, nil
+	}
+
+	obj, err := componentJSONObject(compcfg)
+	if err != nil {
+		return nil, err
+	}
+	for k := range obj {
+		keys[k] = struct{}{}
+	}
+	return keys, nil
+}
+
+// componentJSONObject returns the top-level keys of the JSON representation
+// of a component configuration.
+func componentJSONObject(compcfg ComponentConfig) (map[string]json.RawMessage, error) {
+	bs, err := compcfg.ToJSON()
+	if err != nil {
+		return nil, err
+	}
+	obj := make(map[string]json.RawMessage)
+	err = json.Unmarshal(bs, &obj)
+	if err != nil {
+		return nil, fmt.Errorf("%s configuration is not a JSON object: %w", compcfg.ConfigKey(), err)
+	}
+	return obj, nil
+}
+
+// overrideValue converts a user-provided value to JSON, using the current
+// JSON value of the key as hint for the type.
+func overrideValue(current json.RawMessage, value string) (json.RawMessage, error) {
+	current = json.RawMessage(strings.TrimSpace(string(current)))
+	valid := json.Valid([]byte(value))
+
+	switch {
+	case len(current) > 0 && current[0] == '"':
+		return json.Marshal(value)
+	case len(current) > 0 && current[0] == '[' && !valid:
+		return json.Marshal(strings.Split(value, ","))
+	case string(current) == "null" && !valid:
+		return json.Marshal(value)
+	case !valid:
+		return nil, fmt.Errorf("value is not valid JSON: %s", value)
+	default:
+		return json.RawMessage(value), nil
+	}
+}
+
 // RegisterComponent lets the Manager load and save component configurations
 func (cfg *Manager) RegisterComponent(t SectionType, ccfg ComponentConfig) {
 	cfg.wg.Add(1)
@@ -358,23 +594,15 @@ func (cfg *Manager) LoadJSONFromFile(path string) error {
 }
 
 // LoadJSONFromHTTPSource reads a Configuration file from a URL and parses it.
+// Failed requests are retried (see SourceRetries).
 func (cfg *Manager) LoadJSONFromHTTPSource(url string) error {
 	logger.Infof("loading configuration from %s", url)
 	cfg.Source = url
-	resp, err := http.Get(url)
-	if err != nil {
-		return fmt.Errorf("%w: %s", errFetchingSource, url)
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	body, err := cfg.fetchSourceWithRetries(url)
 	if err != nil {
 		return err
 	}
 
-	if resp.StatusCode >= 300 {
-		return fmt.Errorf("unsuccessful request (%d): %s", resp.StatusCode, body)
-	}
-
 	// Avoid recursively loading remote sources
 	if cfg.sourceRedirs > 0 {
 		return errSourceRedirect
@@ -420,10 +648,26 @@ func (cfg *Manager) LoadJSON(bs []byte) error {
 		return cfg.LoadJSONFromHTTPSource(jcfg.Source)
 	}
 
+	profile, err := cfg.selectProfile(jcfg)
+	if err != nil {
+		return err
+	}
+
 	// Load Cluster section. Needs to have been registered
 	if cfg.clusterConfig != nil && jcfg.Cluster != nil {
 		cfg.clusterConfig.SetBaseDir(dir)
-		err = cfg.clusterConfig.LoadJSON([]byte(*jcfg.Cluster))
+		raw := jcfg.Cluster
+		if profile != nil {
+			raw, err = cfg.applyProfile(configKeyCluster, raw, profile.Cluster)
+			if err != nil {
+				return err
+			}
+		}
+		err = cfg.clusterConfig.LoadJSON([]byte(*raw))
+		if err != nil {
+			return err
+		}
+		err = cfg.checkUnknownKeys(configKeyCluster, cfg.clusterConfig, *raw, jcfg.Strict)
 		if err != nil {
 			return err
 		}
@@ -433,10 +677,21 @@ func (cfg *Manager) LoadJSON(bs []byte) error {
 		component.SetBaseDir(dir)
 		raw, ok := jsonSection[name]
 		if ok && raw != nil {
+			if profile != nil {
+				var err error
+				raw, err = cfg.applyProfile(name, raw, (*profile.getSection(t))[name])
+				if err != nil {
+					return err
+				}
+			}
 			err := component.LoadJSON([]byte(*raw))
 			if err != nil {
 				return err
 			}
+			err = cfg.checkUnknownKeys(name, component, *raw, jcfg.Strict)
+			if err != nil {
+				return err
+			}
 			logger.Debugf("%s component configuration loaded", name)
 		} else {
 			cfg.undefinedComps[t][name] = true
@@ -486,11 +741,66 @@ func (cfg *Manager) SaveJSON(path string) error {
 	}
 
 	bs, err := cfg.ToJSON()
-	if err != nil {
-		return err
+	if err == nil {
+		err = os.WriteFile(cfg.path, bs, 0600)
 	}
+	cfg.trackSave(err)
+	return err
+}
 
-	return os.WriteFile(cfg.path, bs, 0600)
+// trackSave records the result of a save operation and calls the
+// save-error hook on failures.
+func (cfg *Manager) trackSave(err error) {
+	cfg.saveStatusMux.Lock()
+	if err == nil {
+		cfg.saveErr = nil
+		cfg.saveErrSince = time.Time{}
+		cfg.saveFailures = 0
+		cfg.saveStatusMux.Unlock()
+		return
+	}
+
+	if cfg.saveFailures == 0 {
+		cfg.saveErrSince = time.Now()
+	}
+	cfg.saveErr = err
+	cfg.saveFailures++
+	failures := cfg.saveFailures
+	hook := cfg.saveErrHook
+	cfg.saveStatusMux.Unlock()
+
+	if hook != nil {
+		hook(err, failures)
+	}
+}
+
+// SaveError returns an error when the last attempt to save the configuration
+// failed, and nil otherwise. The error wraps ErrSaveFailed. This allows
+// surfacing a peer that cannot persist its configuration (i.e. because of a
+// read-only or full disk) through health checks.
+func (cfg *Manager) SaveError() error {
+	cfg.saveStatusMux.Lock()
+	defer cfg.saveStatusMux.Unlock()
+
+	if cfg.saveErr == nil {
+		return nil
+	}
+	return fmt.Errorf(
+		"%w (%d consecutive failures since %s): %s",
+		ErrSaveFailed,
+		cfg.saveFailures,
+		cfg.saveErrSince.Format(time.RFC3339),
+		cfg.saveErr,
+	)
+}
+
+// SetSaveErrorHook sets a function that is called every time saving the
+// configuration fails, along with the number of consecutive failures. It can
+// be used to raise alerts or record metrics.
+func (cfg *Manager) SetSaveErrorHook(hook func(err error, failures int)) {
+	cfg.saveStatusMux.Lock()
+	defer cfg.saveStatusMux.Unlock()
+	cfg.saveErrHook = hook
 }
 
 // ToJSON provides a JSON representation of the configuration by
@@ -520,6 +830,10 @@ func (cfg *Manager) ToJSON() ([]byte, error) {
 		}
 		jcfg.Cluster = new(json.RawMessage)
 		*jcfg.Cluster = raw
+		jcfg.Cluster, err = cfg.unapplyProfile(configKeyCluster, jcfg.Cluster)
+		if err != nil {
+			return nil, err
+		}
 		logger.Debug("writing changes for cluster section")
 	}
 
@@ -539,6 +853,10 @@ func (cfg *Manager) ToJSON() ([]byte, error) {
 			jsonSection := *dest
 			jsonSection[k] = new(json.RawMessage)
 			*jsonSection[k] = j
+			jsonSection[k], err = cfg.unapplyProfile(k, jsonSection[k])
+			if err != nil {
+				return err
+			}
 		}
 		return nil
 	}
@@ -551,6 +869,124 @@ func (cfg *Manager) ToJSON() ([]byte, error) {
 	return DefaultJSONMarshal(jcfg)
 }
 
+// checkUnknownKeys logs a warning, or returns an error in strict mode, when
+// the given configuration object has top-level keys which the component does
+// not use. Keys are known when they are part of the JSON configuration
+// struct of the component (see JSONKeyer).
+func (cfg *Manager) checkUnknownKeys(name string, component ComponentConfig, raw []byte, strict bool) error {
+	given := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(raw, &given); err != nil {
+		return nil // not an object. Components take care of it.
+	}
+	known, err := componentKeys(component)
+	if err != nil {
+		return err
+	}
+
+	var unknown []string
+	for k := range given {
+		if _, ok := known[k]; !ok {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+
+	if cfg.Strict || strict {
+		return fmt.Errorf("%w in %s section: %s", ErrUnknownKeys, name, strings.Join(unknown, ", "))
+	}
+	logger.Warnf("%s section: unknown configuration keys will be ignored: %s", name, strings.Join(unknown, ", "))
+	return nil
+}
+
+// key used to track profile overrides for the cluster section.
+const configKeyCluster = "cluster"
+
+// selectProfile returns the configuration profile that should be applied,
+// if any.
+func (cfg *Manager) selectProfile(jcfg *jsonConfig) (*jsonConfig, error) {
+	cfg.profileBase = nil
+
+	name := cfg.Profile
+	if name == "" {
+		name = os.Getenv(EnvProfile)
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	profile, ok := jcfg.Profiles[name]
+	if !ok || profile == nil {
+		return nil, fmt.Errorf("configuration profile %q is not defined", name)
+	}
+	logger.Infof("using configuration profile %q", name)
+	cfg.profileBase = make(map[string]map[string]*json.RawMessage)
+	return profile, nil
+}
+
+// applyProfile sets the top-level keys of a component's configuration
+// present in the profile on top of the base configuration. The original
+// values of the overridden keys are remembered so that profile values do not
+// leak into the main sections when saving.
+func (cfg *Manager) applyProfile(name string, base, profile *json.RawMessage) (*json.RawMessage, error) {
+	if profile == nil {
+		return base, nil
+	}
+
+	baseObj := make(map[string]*json.RawMessage)
+	if err := json.Unmarshal(*base, &baseObj); err != nil {
+		return nil, fmt.Errorf("%s configuration is not a JSON object: %w", name, err)
+	}
+	profileObj := make(map[string]*json.RawMessage)
+	if err := json.Unmarshal(*profile, &profileObj); err != nil {
+		return nil, fmt.Errorf("%s profile configuration is not a JSON object: %w", name, err)
+	}
+
+	overridden := make(map[string]*json.RawMessage)
+	for k, v := range profileObj {
+		overridden[k] = baseObj[k] // nil when not in the base config
+		baseObj[k] = v
+	}
+	cfg.profileBase[name] = overridden
+
+	bs, err := json.Marshal(baseObj)
+	if err != nil {
+		return nil, err
+	}
+	raw := json.RawMessage(bs)
+	return &raw, nil
+}
+
+// unapplyProfile is the inverse of applyProfile. It restores the values of
+// the keys that were overridden by the profile to their original values.
+func (cfg *Manager) unapplyProfile(name string, raw *json.RawMessage) (*json.RawMessage, error) {
+	overridden, ok := cfg.profileBase[name]
+	if !ok || raw == nil {
+		return raw, nil
+	}
+
+	obj := make(map[string]*json.RawMessage)
+	if err := json.Unmarshal(*raw, &obj); err != nil {
+		return nil, fmt.Errorf("%s configuration is not a JSON object: %w", name, err)
+	}
+	for k, v := range overridden {
+		if v == nil {
+			delete(obj, k)
+			continue
+		}
+		obj[k] = v
+	}
+
+	bs, err := DefaultJSONMarshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	restored := json.RawMessage(bs)
+	return &restored, nil
+}
+
 // ToDisplayJSON returns a printable cluster configuration.
 func (cfg *Manager) ToDisplayJSON() ([]byte, error) {
 	jcfg := &jsonConfig{}
@@ -610,6 +1046,103 @@ func (cfg *Manager) IsLoadedFromJSON(t SectionType, name string) bool {
 	return !cfg.undefinedComps[t][name]
 }
 
+// sectionNames are the names of the sections in the configuration file.
+var sectionNames = map[SectionType]string{
+	Cluster:      "cluster",
+	Consensus:    "consensus",
+	API:          "api",
+	IPFSConn:     "ipfs_connector",
+	State:        "state",
+	PinTracker:   "pin_tracker",
+	Monitor:      "monitor",
+	Allocator:    "allocator",
+	Informer:     "informer",
+	Observations: "observations",
+	Datastore:    "datastore",
+}
+
+// ComponentKeys returns the keys of the registered components, as accepted
+// by Component: "cluster" and "<section>.<component>" for the rest (i.e.
+// "api.restapi").
+func (cfg *Manager) ComponentKeys() []string {
+	var keys []string
+	if cfg.clusterConfig != nil {
+		keys = append(keys, sectionNames[Cluster])
+	}
+	for t, section := range cfg.sections {
+		for name := range section {
+			keys = append(keys, sectionNames[t]+"."+name)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Component returns the registered component configuration for the given
+// key (see ComponentKeys), along with its section type.
+func (cfg *Manager) Component(key string) (SectionType, ComponentConfig, error) {
+	if key == sectionNames[Cluster] && cfg.clusterConfig != nil {
+		return Cluster, cfg.clusterConfig, nil
+	}
+	sname, name, _ := strings.Cut(key, ".")
+	for t, section := range cfg.sections {
+		if sectionNames[t] != sname {
+			continue
+		}
+		if compcfg, ok := section[name]; ok {
+			return t, compcfg, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("unknown configuration component %q", key)
+}
+
+// ComponentJSON returns the JSON configuration of the component with the
+// given key (see ComponentKeys).
+func (cfg *Manager) ComponentJSON(key string) ([]byte, error) {
+	_, compcfg, err := cfg.Component(key)
+	if err != nil {
+		return nil, err
+	}
+	return compcfg.ToJSON()
+}
+
+// SetComponentJSON replaces the configuration of the component with the
+// given key (see ComponentKeys) with the given JSON. Unknown keys are
+// handled as in LoadJSON. When the new configuration does not validate,
+// the previous one is restored and an error is returned. The Manager
+// should be saved afterwards to persist the change.
+func (cfg *Manager) SetComponentJSON(key string, bs []byte) error {
+	t, compcfg, err := cfg.Component(key)
+	if err != nil {
+		return err
+	}
+	previous, err := compcfg.ToJSON()
+	if err != nil {
+		return err
+	}
+
+	restore := func(err error) error {
+		if rerr := compcfg.LoadJSON(previous); rerr != nil {
+			logger.Errorf("error restoring the %s configuration: %s", key, rerr)
+		}
+		return fmt.Errorf("invalid %s configuration: %w", key, err)
+	}
+
+	if err := compcfg.LoadJSON(bs); err != nil {
+		return restore(err)
+	}
+	if err := cfg.checkUnknownKeys(compcfg.ConfigKey(), compcfg, bs, false); err != nil {
+		return restore(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return restore(err)
+	}
+	if t != Cluster {
+		delete(cfg.undefinedComps[t], compcfg.ConfigKey())
+	}
+	return nil
+}
+
 // GetClusterConfig extracts cluster config from the configuration file
 // and returns bytes of it
 func GetClusterConfig(configPath string) ([]byte, error) {
@@ -2,10 +2,17 @@ package config
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 var mockJSON = []byte(`{
@@ -167,6 +174,122 @@ func TestLoadFromHTTPSource(t *testing.T) {
 	}
 }
 
+func TestLoadFromHTTPSourceRetries(t *testing.T) {
+	SourceRetryDelay = 10 * time.Millisecond
+	defer func() { SourceRetryDelay = time.Second }()
+
+	requests := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= SourceRetries {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(mockJSON)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNotFound)
+	})
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	cfgMgr := setupConfigManager()
+	err := cfgMgr.LoadJSONFromHTTPSource(s.URL + "/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != SourceRetries+1 {
+		t.Errorf("expected %d requests: %d", SourceRetries+1, requests)
+	}
+
+	requests = 0
+	err = cfgMgr.LoadJSONFromHTTPSource(s.URL + "/missing")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if requests != 1 {
+		t.Error("client errors should not be retried")
+	}
+}
+
+func TestWatchSource(t *testing.T) {
+	var mu sync.Mutex
+	content := mockJSON
+	conditional := 0
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		etag := fmt.Sprintf(`"%d"`, len(content))
+		if r.Header.Get("If-None-Match") == etag {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write(content)
+	})
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	cfgMgr := setupConfigManager()
+	err := cfgMgr.LoadJSONFromHTTPSource(s.URL + "/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan []byte, 10)
+	cfgMgr.WatchSource(ctx, 20*time.Millisecond, func(body []byte) {
+		changes <- body
+	})
+
+	time.Sleep(200 * time.Millisecond)
+	select {
+	case <-changes:
+		t.Fatal("the source has not changed")
+	default:
+	}
+	mu.Lock()
+	if conditional == 0 {
+		t.Error("expected conditional requests")
+	}
+	content = []byte(`{"cluster": {}}`)
+	mu.Unlock()
+
+	select {
+	case body := <-changes:
+		if !bytes.Equal(body, []byte(`{"cluster": {}}`)) {
+			t.Errorf("unexpected content: %s", body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the change was not detected")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	if d := backoff(time.Second, time.Minute, 1); d != time.Second {
+		t.Errorf("unexpected delay: %s", d)
+	}
+	if d := backoff(time.Second, time.Minute, 3); d != 4*time.Second {
+		t.Errorf("unexpected delay: %s", d)
+	}
+	if d := backoff(time.Second, time.Minute, 100); d != time.Minute {
+		t.Errorf("unexpected delay: %s", d)
+	}
+
+	for i := 0; i < 100; i++ {
+		d := jitter(time.Minute)
+		if d < 45*time.Second || d > 75*time.Second {
+			t.Fatalf("delay out of range: %s", d)
+		}
+	}
+}
+
 func TestSaveWithSource(t *testing.T) {
 	cfgMgr := setupConfigManager()
 	cfgMgr.Default()
@@ -210,3 +333,291 @@ func TestDefaultJSONMarshalWithoutHiddenFields(t *testing.T) {
 		t.Error(string(res))
 	}
 }
+
+type overridableCfg struct {
+	Saver
+	A string   `json:"a"`
+	B int      `json:"b"`
+	C []string `json:"c"`
+	D string   `json:"d,omitempty"`
+}
+
+func (o *overridableCfg) ConfigKey() string {
+	return "over"
+}
+
+func (o *overridableCfg) LoadJSON(raw []byte) error {
+	return json.Unmarshal(raw, o)
+}
+
+func (o *overridableCfg) ToJSON() ([]byte, error) {
+	return json.Marshal(o)
+}
+
+func (o *overridableCfg) JSONKeys() []string {
+	return JSONKeys(o)
+}
+
+func (o *overridableCfg) Default() error {
+	o.A = "default"
+	o.B = 1
+	o.C = []string{"x"}
+	return nil
+}
+
+func (o *overridableCfg) ApplyEnvVars() error {
+	return nil
+}
+
+func (o *overridableCfg) Validate() error {
+	return nil
+}
+
+func (o *overridableCfg) ToDisplayJSON() ([]byte, error) {
+	return o.ToJSON()
+}
+
+func TestApplyOverrides(t *testing.T) {
+	cfgMgr := setupConfigManager()
+	over := &overridableCfg{}
+	cfgMgr.RegisterComponent(API, over)
+	cfgMgr.Default()
+
+	keys, err := cfgMgr.OverrideKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := 0
+	for _, k := range keys {
+		if k == "over.a" || k == "over.b" || k == "over.c" || k == "over.d" {
+			found++
+		}
+	}
+	if found != 4 {
+		t.Fatalf("expected override keys for all fields: %v", keys)
+	}
+
+	err = cfgMgr.ApplyOverrides(map[string]string{
+		"over.a": "123",
+		"over.b": "5",
+		"over.c": "y,z",
+		"over.d": "w",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if over.A != "123" || over.B != 5 || len(over.C) != 2 || over.C[1] != "z" || over.D != "w" {
+		t.Errorf("overrides not applied: %+v", over)
+	}
+
+	err = cfgMgr.ApplyOverrides(map[string]string{"over.e": "1"})
+	if err == nil {
+		t.Error("expected error overriding unknown key")
+	}
+
+	err = cfgMgr.ApplyOverrides(map[string]string{"over.b": "abc"})
+	if err == nil {
+		t.Error("expected error overriding with invalid value")
+	}
+
+	err = cfgMgr.ApplyOverrides(map[string]string{"nope.a": "1"})
+	if err == nil {
+		t.Error("expected error overriding unregistered component")
+	}
+}
+
+func TestComponentJSON(t *testing.T) {
+	cfgMgr := setupConfigManager()
+	over := &overridableCfg{}
+	cfgMgr.RegisterComponent(API, over)
+	cfgMgr.Default()
+
+	found := false
+	for _, k := range cfgMgr.ComponentKeys() {
+		if k == "api.over" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected api.over in the component keys: %v", cfgMgr.ComponentKeys())
+	}
+
+	bs, err := cfgMgr.ComponentJSON("api.over")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(bs), `"a":"default"`) {
+		t.Errorf("unexpected component json: %s", bs)
+	}
+
+	err = cfgMgr.SetComponentJSON("api.over", []byte(`{"a":"new","b":2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if over.A != "new" || over.B != 2 {
+		t.Errorf("component configuration not set: %+v", over)
+	}
+
+	err = cfgMgr.SetComponentJSON("api.over", []byte(`{"b":"abc"}`))
+	if err == nil {
+		t.Error("expected an error setting an invalid configuration")
+	}
+	if over.A != "new" || over.B != 2 {
+		t.Errorf("previous configuration not restored: %+v", over)
+	}
+
+	for _, key := range []string{"over", "api.nope", "nope.over"} {
+		if _, err := cfgMgr.ComponentJSON(key); err == nil {
+			t.Errorf("expected an error getting %s", key)
+		}
+	}
+}
+
+func TestSaveError(t *testing.T) {
+	cfgMgr := setupConfigManager()
+	cfgMgr.Default()
+
+	var hookFailures int
+	cfgMgr.SetSaveErrorHook(func(err error, failures int) {
+		hookFailures = failures
+	})
+
+	dir := t.TempDir()
+	badPath := filepath.Join(dir, "missing", "service.json")
+	for i := 0; i < 2; i++ {
+		if err := cfgMgr.SaveJSON(badPath); err == nil {
+			t.Fatal("expected an error saving to a missing folder")
+		}
+	}
+
+	err := cfgMgr.SaveError()
+	if !errors.Is(err, ErrSaveFailed) {
+		t.Fatal("expected a save error")
+	}
+	if hookFailures != 2 {
+		t.Errorf("expected hook to be called with 2 failures, got %d", hookFailures)
+	}
+
+	err = cfgMgr.SaveJSON(filepath.Join(dir, "service.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfgMgr.SaveError(); err != nil {
+		t.Error("save error should be cleared after a successful save")
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	profileJSON := []byte(`{
+  "cluster": {
+    "a": "b"
+  },
+  "api": {
+    "over": {
+      "a": "base",
+      "b": 1
+    }
+  },
+  "profiles": {
+    "low": {
+      "api": {
+        "over": {
+          "b": 2,
+          "c": ["p"]
+        }
+      }
+    }
+  }
+}`)
+
+	cfgMgr := NewManager()
+	cfgMgr.RegisterComponent(Cluster, &mockCfg{})
+	over := &overridableCfg{}
+	cfgMgr.RegisterComponent(API, over)
+	cfgMgr.Profile = "low"
+
+	err := cfgMgr.LoadJSON(profileJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if over.A != "base" || over.B != 2 || len(over.C) != 1 || over.C[0] != "p" {
+		t.Fatalf("profile not applied: %+v", over)
+	}
+
+	newjson, err := cfgMgr.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved struct {
+		API struct {
+			Over map[string]json.RawMessage `json:"over"`
+		} `json:"api"`
+		Profiles map[string]json.RawMessage `json:"profiles"`
+	}
+	err = json.Unmarshal(newjson, &saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(saved.API.Over["b"]) != "1" {
+		t.Errorf("profile value persisted in main section: %s", saved.API.Over["b"])
+	}
+	if _, ok := saved.API.Over["c"]; ok {
+		t.Error("profile-only key persisted in main section")
+	}
+	if _, ok := saved.Profiles["low"]; !ok {
+		t.Error("profiles should be preserved when saving")
+	}
+
+	cfgMgr = NewManager()
+	cfgMgr.RegisterComponent(Cluster, &mockCfg{})
+	cfgMgr.RegisterComponent(API, &overridableCfg{})
+	cfgMgr.Profile = "missing"
+	err = cfgMgr.LoadJSON(profileJSON)
+	if err == nil {
+		t.Error("expected an error selecting an undefined profile")
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	typoJSON := []byte(`{
+  "cluster": {
+    "a": "b"
+  },
+  "api": {
+    "over": {
+      "a": "x",
+      "bb": 3,
+      "d": "omitted by default"
+    }
+  }
+}`)
+
+	newMgr := func() *Manager {
+		cfgMgr := NewManager()
+		cfgMgr.RegisterComponent(Cluster, &mockCfg{})
+		cfgMgr.RegisterComponent(API, &overridableCfg{})
+		return cfgMgr
+	}
+
+	err := newMgr().LoadJSON(typoJSON)
+	if err != nil {
+		t.Fatal("unknown keys should only cause a warning:", err)
+	}
+
+	cfgMgr := newMgr()
+	cfgMgr.Strict = true
+	err = cfgMgr.LoadJSON(typoJSON)
+	if !errors.Is(err, ErrUnknownKeys) {
+		t.Fatal("expected an unknown keys error in strict mode:", err)
+	}
+	if !strings.HasSuffix(err.Error(), ": bb") {
+		t.Errorf("unexpected error message: %s", err)
+	}
+
+	strictJSON := append([]byte(`{"strict": true,`), typoJSON[1:]...)
+	err = newMgr().LoadJSON(strictJSON)
+	if !errors.Is(err, ErrUnknownKeys) {
+		t.Error("strict mode should be enabled from the configuration:", err)
+	}
+}
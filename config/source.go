@@ -0,0 +1,184 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// SourceRetries is the number of times that fetching a remote configuration
+// source is retried when the request fails or the server returns a 429 or
+// 5xx response.
+var SourceRetries = 3
+
+// SourceRetryDelay is the initial delay between attempts to fetch a remote
+// configuration source. It doubles with every failed attempt up to
+// SourceMaxRetryDelay.
+var SourceRetryDelay = time.Second
+
+// SourceMaxRetryDelay is the maximum delay between attempts to fetch a remote
+// configuration source.
+var SourceMaxRetryDelay = 5 * time.Minute
+
+// SourceJitter is the fraction (between 0 and 1) of every delay before
+// fetching a remote configuration source which is randomized, so that many
+// peers using the same source do not make their requests at the same time.
+var SourceJitter = 0.5
+
+// sourceState keeps what was last fetched from the remote source so that
+// following requests can be made conditional.
+type sourceState struct {
+	etag         string
+	lastModified string
+	body         []byte
+}
+
+type sourceResponseError struct {
+	code int
+	body []byte
+}
+
+func (err sourceResponseError) Error() string {
+	return fmt.Sprintf("unsuccessful request (%d): %s", err.code, err.body)
+}
+
+// retryable returns true for errors which are worth retrying later.
+func retryable(err error) bool {
+	rerr, ok := err.(sourceResponseError)
+	if !ok {
+		return true // network errors
+	}
+	return rerr.code == http.StatusTooManyRequests || rerr.code >= 500
+}
+
+// jitter randomizes SourceJitter of the given duration around it.
+func jitter(d time.Duration) time.Duration {
+	j := float64(d) * SourceJitter
+	return time.Duration(float64(d) - j/2 + rand.Float64()*j)
+}
+
+// backoff returns the delay before the next attempt after the given number
+// of consecutive failures, starting at base and doubling up to max.
+func backoff(base, max time.Duration, failures int) time.Duration {
+	d := base
+	for i := 1; i < failures && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// fetchSource requests the remote source. When the Manager has already
+// fetched it, the request is conditional and changed is false when the
+// server answers that it has not been modified or returns the same content.
+func (cfg *Manager) fetchSource(ctx context.Context, url string) (body []byte, changed bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	cfg.sourceMux.Lock()
+	last := cfg.source
+	cfg.sourceMux.Unlock()
+	if last.etag != "" {
+		req.Header.Set("If-None-Match", last.etag)
+	}
+	if last.lastModified != "" {
+		req.Header.Set("If-Modified-Since", last.lastModified)
+	}
+
+	resp, err := SourceHTTPClient.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %s", errFetchingSource, url)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && last.body != nil {
+		return last.body, false, nil
+	}
+
+	body, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, false, sourceResponseError{code: resp.StatusCode, body: body}
+	}
+
+	cfg.sourceMux.Lock()
+	cfg.source = sourceState{
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		body:         body,
+	}
+	cfg.sourceMux.Unlock()
+	return body, !bytes.Equal(body, last.body), nil
+}
+
+// fetchSourceWithRetries calls fetchSource, retrying failed attempts
+// SourceRetries times with an exponential, jittered backoff.
+func (cfg *Manager) fetchSourceWithRetries(url string) ([]byte, error) {
+	for failures := 0; ; failures++ {
+		body, _, err := cfg.fetchSource(cfg.ctx, url)
+		if err == nil || !retryable(err) || failures >= SourceRetries {
+			return body, err
+		}
+
+		delay := jitter(backoff(SourceRetryDelay, SourceMaxRetryDelay, failures+1))
+		logger.Warnf("error fetching configuration source: %s. Retrying in %s", err, delay)
+		select {
+		case <-cfg.ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// WatchSource checks the remote source of the configuration (if any) for
+// changes until the given context is cancelled. Checks happen every
+// interval, randomized by SourceJitter, and use conditional requests so that
+// unchanged sources are cheap to serve. After failures, the interval doubles
+// up to the largest of interval and SourceMaxRetryDelay. onChange is called
+// with the new content when it differs from the loaded one. The new content
+// is not applied: components are not able to reload their configurations.
+func (cfg *Manager) WatchSource(ctx context.Context, interval time.Duration, onChange func(body []byte)) {
+	url := cfg.Source
+	if url == "" || interval <= 0 {
+		return
+	}
+
+	maxDelay := SourceMaxRetryDelay
+	if interval > maxDelay {
+		maxDelay = interval
+	}
+
+	go func() {
+		failures := 0
+		for {
+			delay := jitter(backoff(interval, maxDelay, failures+1))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+
+			body, changed, err := cfg.fetchSource(ctx, url)
+			if err != nil {
+				failures++
+				logger.Warnf("error checking configuration source for changes: %s", err)
+				continue
+			}
+			failures = 0
+			if changed {
+				logger.Infof("configuration source %s has changed", url)
+				onChange(body)
+			}
+		}
+	}()
+}
@@ -183,6 +183,48 @@ func DisplayJSON(cfg interface{}) ([]byte, error) {
 	return DefaultJSONMarshal(data)
 }
 
+// JSONKeys takes a JSON-friendly configuration struct (or a pointer to it)
+// and returns the keys of the JSON object it marshals to, including those of
+// fields marked with "omitempty". Fields of embedded structs without a json
+// tag are promoted, as encoding/json does.
+func JSONKeys(cfg interface{}) []string {
+	t := reflect.TypeOf(cfg)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		panic("the given argument should be a struct")
+	}
+
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				keys = append(keys, JSONKeys(reflect.New(ft).Interface())...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		keys = append(keys, name)
+	}
+	return keys
+}
+
 // Strings is a helper type that (un)marshals a single string to/from a single
 // JSON string and a slice of strings to/from a JSON array of strings.
 type Strings []string
@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/consensus/events"
+	"github.com/ipfs-cluster/ipfs-cluster/observations"
 	"github.com/ipfs-cluster/ipfs-cluster/pstoremgr"
 	"github.com/ipfs-cluster/ipfs-cluster/state"
 	"github.com/ipfs-cluster/ipfs-cluster/state/dsstate"
@@ -30,11 +32,15 @@ import (
 	multihash "github.com/multiformats/go-multihash"
 
 	ipfslite "github.com/hsanjuan/ipfs-lite"
+	"go.opencensus.io/stats"
 	trace "go.opencensus.io/trace"
 )
 
 var logger = logging.Logger("crdt")
 
+// How often the sync lag metric is recorded
+var syncLagInterval = 10 * time.Second
+
 var (
 	// BlocksNs is the namespace to use as blockstore with ipfs-lite.
 	BlocksNs   = "b"
@@ -92,6 +98,8 @@ type Consensus struct {
 	batchItemCh   chan batchItem
 	batchingDone  chan struct{}
 
+	events *events.Bus
+
 	shutdownLock sync.RWMutex
 	shutdown     bool
 }
@@ -154,6 +162,7 @@ func New(
 		sendToBatchCh:  make(chan batchItem),
 		batchItemCh:    make(chan batchItem, cfg.Batching.MaxQueueSize),
 		batchingDone:   make(chan struct{}),
+		events:         events.NewBus(),
 	}
 
 	go css.setup()
@@ -223,6 +232,10 @@ func (css *Consensus) setup() {
 		ctx, span := trace.StartSpan(css.ctx, "crdt/PutHook")
 		defer span.End()
 
+		if isScheduleKey(k) {
+			return
+		}
+
 		pin := api.Pin{}
 		err := pin.ProtoUnmarshal(v)
 		if err != nil {
@@ -230,6 +243,11 @@ func (css *Consensus) setup() {
 			return
 		}
 
+		css.events.Publish(api.ConsensusEvent{
+			Type: api.ConsensusEventPin,
+			Pin:  pin,
+		})
+
 		// TODO: tracing for this context
 		err = css.rpcClient.CallContext(
 			ctx,
@@ -248,6 +266,10 @@ func (css *Consensus) setup() {
 		ctx, span := trace.StartSpan(css.ctx, "crdt/DeleteHook")
 		defer span.End()
 
+		if isScheduleKey(k) {
+			return
+		}
+
 		kb, err := dshelp.BinaryFromDsKey(k)
 		if err != nil {
 			logger.Error(err, k)
@@ -261,6 +283,11 @@ func (css *Consensus) setup() {
 
 		pin := api.PinCid(c)
 
+		css.events.Publish(api.ConsensusEvent{
+			Type: api.ConsensusEventUnpin,
+			Pin:  pin,
+		})
+
 		err = css.rpcClient.CallContext(
 			ctx,
 			"",
@@ -330,11 +357,31 @@ func (css *Consensus) setup() {
 		go css.batchWorker()
 	}
 
+	go css.watchSyncLag()
+
 	// notifies State() it is safe to return
 	close(css.stateReady)
 	css.readyCh <- struct{}{}
 }
 
+// watchSyncLag records the number of DAG nodes waiting to be processed.
+// Peers only fetch the DAG nodes they have not seen, starting from the
+// announced heads, so this drains quickly after short outages.
+func (css *Consensus) watchSyncLag() {
+	ticker := time.NewTicker(syncLagInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-css.ctx.Done():
+			return
+		case <-ticker.C:
+			lag := css.crdt.InternalStats().QueuedJobs
+			stats.Record(css.ctx, observations.ConsensusSyncLag.M(int64(lag)))
+		}
+	}
+}
+
 // Shutdown closes this component, canceling the pubsub subscription and
 // closing the datastore.
 func (css *Consensus) Shutdown(ctx context.Context) error {
@@ -363,6 +410,8 @@ func (css *Consensus) Shutdown(ctx context.Context) error {
 		crdt.Close()
 	}
 
+	css.events.Close()
+
 	if css.config.hostShutdown {
 		css.host.Close()
 	}
@@ -468,6 +517,23 @@ func (css *Consensus) LogUnpin(ctx context.Context, pin api.Pin) error {
 	return css.state.Rm(ctx, pin.Cid)
 }
 
+// LogSchedule adds a pin schedule to the shared state, replacing any with
+// the same ID.
+func (css *Consensus) LogSchedule(ctx context.Context, s api.PinSchedule) error {
+	ctx, span := trace.StartSpan(ctx, "consensus/LogSchedule")
+	defer span.End()
+
+	return css.state.AddSchedule(ctx, s)
+}
+
+// LogUnschedule removes a pin schedule from the shared state.
+func (css *Consensus) LogUnschedule(ctx context.Context, id string) error {
+	ctx, span := trace.StartSpan(ctx, "consensus/LogUnschedule")
+	defer span.End()
+
+	return css.state.RmSchedule(ctx, id)
+}
+
 func (css *Consensus) sendToBatchWorker() {
 	for {
 		select {
@@ -629,6 +695,29 @@ func (css *Consensus) Peers(ctx context.Context) ([]peer.ID, error) {
 // component to be usable.
 func (css *Consensus) WaitForSync(ctx context.Context) error { return nil }
 
+// CatchUpProgress returns an empty progress, as CRDT peers never wait for
+// the state to sync.
+func (css *Consensus) CatchUpProgress(ctx context.Context) api.CatchUpProgress {
+	return api.CatchUpProgress{}
+}
+
+// Stats returns the "peer" role, as CRDT peers have no leader, and the
+// height of the Merkle-DAG as applied index. The CRDT store lives in the
+// peer datastore, so no storage size is reported.
+func (css *Consensus) Stats(ctx context.Context) api.ConsensusStats {
+	stats := api.ConsensusStats{
+		Role: api.ConsensusRolePeer,
+	}
+	select {
+	case <-css.stateReady:
+		st := css.crdt.InternalStats()
+		stats.AppliedIndex = st.MaxHeight
+		stats.SyncLag = uint64(st.QueuedJobs)
+	default: // not set up yet
+	}
+	return stats
+}
+
 // AddPeer is a no-op as we do not need to do peerset management with
 // Merkle-CRDTs. Therefore adding a peer to the peerset means doing nothing.
 func (css *Consensus) AddPeer(ctx context.Context, pid peer.ID) error {
@@ -695,6 +784,39 @@ func (css *Consensus) Leader(ctx context.Context) (peer.ID, error) {
 	return "", ErrNoLeader
 }
 
+// IsNonVoter returns false. CRDT peers do not vote and the non_voter
+// option of Raft is rejected by peers using CRDT. Read-only peers are those
+// not included in the trusted_peers of the rest of the cluster (i.e.
+// ipfs-cluster-follow peers), usually running in follower_mode.
+func (css *Consensus) IsNonVoter(ctx context.Context) bool {
+	return false
+}
+
+// WaitForLeader returns ErrNoLeader.
+func (css *Consensus) WaitForLeader(ctx context.Context) (peer.ID, error) {
+	return "", ErrNoLeader
+}
+
+// SubscribeLeader returns a channel that never receives anything, as there
+// is no leader in CRDT clusters. It is closed when the context is cancelled.
+func (css *Consensus) SubscribeLeader(ctx context.Context) <-chan peer.ID {
+	ch := make(chan peer.ID)
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch
+}
+
+// SubscribeEvents returns a channel which receives an event for every pin
+// added to or removed from the state, including those received from other
+// peers. No peer events are sent, as CRDT peers do not join a peerset. The
+// channel is closed when the context is cancelled or the component shuts
+// down.
+func (css *Consensus) SubscribeEvents(ctx context.Context) <-chan api.ConsensusEvent {
+	return css.events.Subscribe(ctx)
+}
+
 // OfflineState returns an offline, batching state using the given
 // datastore. This allows to inspect and modify the shared state in offline
 // mode.
@@ -705,6 +827,9 @@ func OfflineState(cfg *Config, store ds.Datastore) (state.BatchingState, error)
 	}
 	opts := crdt.DefaultOptions()
 	opts.Logger = logger
+	// The store may be closed right after using the state. Do not run a
+	// repair in the background against it. The peer does that on start.
+	opts.RepairInterval = 0
 
 	var blocksDatastore ds.Batching = namespace.Wrap(
 		batching,
@@ -738,3 +863,9 @@ func OfflineState(cfg *Config, store ds.Datastore) (state.BatchingState, error)
 	}
 	return dsstate.NewBatching(context.Background(), crdt, "", dsstate.DefaultHandle())
 }
+
+// isScheduleKey returns whether a key of the shared state belongs to a pin
+// schedule rather than to a pin.
+func isScheduleKey(k ds.Key) bool {
+	return ds.NewKey(dsstate.SchedulesNamespace).IsAncestorOf(k)
+}
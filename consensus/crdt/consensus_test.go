@@ -8,6 +8,7 @@ import (
 
 	"github.com/ipfs-cluster/ipfs-cluster/api"
 	"github.com/ipfs-cluster/ipfs-cluster/datastore/inmem"
+	"github.com/ipfs-cluster/ipfs-cluster/state"
 	"github.com/ipfs-cluster/ipfs-cluster/test"
 
 	ipns "github.com/ipfs/boxo/ipns"
@@ -140,6 +141,56 @@ func TestConsensusPin(t *testing.T) {
 	}
 }
 
+func TestConsensusStats(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	defer clean(t, cc)
+	defer cc.Shutdown(ctx)
+
+	err := cc.LogPin(ctx, testPin(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(250 * time.Millisecond)
+
+	st := cc.Stats(ctx)
+	if st.Role != api.ConsensusRolePeer {
+		t.Error("unexpected role:", st.Role)
+	}
+	if st.AppliedIndex == 0 {
+		t.Error("the DAG height should have increased")
+	}
+}
+
+func TestConsensusEvents(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	defer clean(t, cc)
+	defer cc.Shutdown(ctx)
+
+	subCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	sub := cc.SubscribeEvents(subCtx)
+
+	err := cc.LogPin(ctx, testPin(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := <-sub
+	if ev.Type != api.ConsensusEventPin || !ev.Pin.Cid.Equals(test.Cid1) {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+
+	err = cc.LogUnpin(ctx, api.PinCid(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev = <-sub
+	if ev.Type != api.ConsensusEventUnpin || !ev.Pin.Cid.Equals(test.Cid1) {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+}
+
 func TestConsensusUnpin(t *testing.T) {
 	ctx := context.Background()
 	cc := testingConsensus(t, 1)
@@ -266,6 +317,69 @@ func TestConsensusAddRmPeer(t *testing.T) {
 	}
 }
 
+func TestConsensusSchedule(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	cc2 := testingConsensus(t, 2)
+	defer clean(t, cc)
+	defer clean(t, cc2)
+	defer cc.Shutdown(ctx)
+	defer cc2.Shutdown(ctx)
+
+	cc.host.Peerstore().AddAddrs(cc2.host.ID(), cc2.host.Addrs(), peerstore.PermanentAddrTTL)
+	_, err := cc.host.Network().DialPeer(ctx, cc2.host.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	err = cc2.Trust(ctx, cc.host.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := api.PinSchedule{
+		ID:   "schedule",
+		Path: test.PathIPFS2,
+		Next: time.Now().Truncate(time.Second),
+	}
+	err = cc.LogSchedule(ctx, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	st, err := cc2.State(ctx)
+	if err != nil {
+		t.Fatal("error getting state:", err)
+	}
+	get, err := st.GetSchedule(ctx, s.ID)
+	if err != nil {
+		t.Fatal("the schedule should have been replicated:", err)
+	}
+	if get.Path != s.Path || !get.Next.Equal(s.Next) {
+		t.Errorf("unexpected schedule: %+v", get)
+	}
+
+	out := make(chan api.Pin, 10)
+	err = st.List(ctx, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p := range out {
+		t.Errorf("schedules should not be listed as pins: %s", p.Cid)
+	}
+
+	err = cc.LogUnschedule(ctx, s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	_, err = st.GetSchedule(ctx, s.ID)
+	if err != state.ErrScheduleNotFound {
+		t.Errorf("expected ErrScheduleNotFound: %s", err)
+	}
+}
+
 func TestConsensusDistrustPeer(t *testing.T) {
 	ctx := context.Background()
 	cc := testingConsensus(t, 1)
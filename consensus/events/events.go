@@ -0,0 +1,99 @@
+// Package events lets consensus components notify subscribers of the changes
+// they apply to the shared state, such as pins added or removed.
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	logging "github.com/ipfs/go-log/v2"
+)
+
+var logger = logging.Logger("consensusevents")
+
+// SubscriberBufferSize is the number of events that are kept for a
+// subscriber that does not read them fast enough. Further events are
+// dropped for that subscriber until it catches up.
+var SubscriberBufferSize = 1024
+
+// Bus delivers every published event to all current subscribers. Publishing
+// never blocks.
+type Bus struct {
+	mux    sync.Mutex
+	subs   map[chan api.ConsensusEvent]struct{}
+	closed bool
+	done   chan struct{}
+}
+
+// NewBus returns a Bus without subscribers.
+func NewBus() *Bus {
+	return &Bus{
+		subs: make(map[chan api.ConsensusEvent]struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// Publish sends the event to all subscribers. The event timestamp is set
+// when missing.
+func (b *Bus) Publish(ev api.ConsensusEvent) {
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now()
+	}
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+			logger.Warnf("consensus event subscriber is too slow. Dropping %s event", ev.Type)
+		}
+	}
+}
+
+// Subscribe returns a channel which receives all the events published from
+// now on. It is closed when the context is cancelled or the bus is closed.
+func (b *Bus) Subscribe(ctx context.Context) <-chan api.ConsensusEvent {
+	ch := make(chan api.ConsensusEvent, SubscriberBufferSize)
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.closed {
+		close(ch)
+		return ch
+	}
+	b.subs[ch] = struct{}{}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-b.done:
+		}
+		b.mux.Lock()
+		defer b.mux.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}()
+	return ch
+}
+
+// Close closes the channels of all subscribers. Events published afterwards
+// are discarded.
+func (b *Bus) Close() {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	close(b.done)
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
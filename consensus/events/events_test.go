@@ -0,0 +1,60 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/test"
+)
+
+func TestBus(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := NewBus()
+	sub1 := bus.Subscribe(ctx)
+	sub2 := bus.Subscribe(ctx)
+
+	bus.Publish(api.ConsensusEvent{
+		Type: api.ConsensusEventPin,
+		Pin:  api.PinCid(test.Cid1),
+	})
+
+	for _, sub := range []<-chan api.ConsensusEvent{sub1, sub2} {
+		ev := <-sub
+		if ev.Type != api.ConsensusEventPin || !ev.Pin.Cid.Equals(test.Cid1) {
+			t.Errorf("unexpected event: %+v", ev)
+		}
+		if ev.Timestamp.IsZero() {
+			t.Error("the event timestamp should be set")
+		}
+	}
+
+	bus.Close()
+	if _, ok := <-sub1; ok {
+		t.Error("subscriptions should be closed with the bus")
+	}
+	if _, ok := <-bus.Subscribe(ctx); ok {
+		t.Error("subscribing to a closed bus should return a closed channel")
+	}
+	// does not panic
+	bus.Publish(api.ConsensusEvent{Type: api.ConsensusEventUnpin})
+}
+
+func TestBusSlowSubscriber(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	bus := NewBus()
+	sub := bus.Subscribe(ctx)
+	for i := 0; i < SubscriberBufferSize+10; i++ {
+		bus.Publish(api.ConsensusEvent{Type: api.ConsensusEventPin})
+	}
+	if len(sub) != SubscriberBufferSize {
+		t.Errorf("expected %d buffered events, got %d", SubscriberBufferSize, len(sub))
+	}
+
+	cancel()
+	for range sub {
+	}
+}
@@ -0,0 +1,200 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/observations"
+
+	"go.opencensus.io/stats"
+)
+
+var errBatchingShutdown = errors.New("consensus is shutting down: operation not committed")
+
+// wraps operations so that they can be batched.
+type batchItem struct {
+	ctx       context.Context
+	op        *LogOp
+	committed chan error // receives the result of the batch commit
+	queued    time.Time
+}
+
+// batchCommit sends the operation to the batchWorker and waits until the
+// batch containing it has been committed. Only the leader batches.
+func (cc *Consensus) batchCommit(ctx context.Context, op *LogOp) error {
+	bi := batchItem{
+		ctx:       ctx,
+		op:        op,
+		committed: make(chan error, 1),
+		queued:    time.Now(),
+	}
+
+	if err := cc.enqueueBatchItem(ctx, bi); err != nil {
+		return err
+	}
+	stats.Record(cc.ctx, observations.ConsensusQueueDepth.M(int64(len(cc.batchItemCh))))
+
+	select {
+	case <-ctx.Done():
+		// The operation may still be committed.
+		return ctx.Err()
+	case <-cc.ctx.Done():
+		return errBatchingShutdown
+	case err := <-bi.committed:
+		return err
+	}
+}
+
+// enqueueBatchItem places the item in the batching queue, applying the
+// queue policy when it is full.
+func (cc *Consensus) enqueueBatchItem(ctx context.Context, bi batchItem) error {
+	if cc.config.Batching.QueuePolicy == QueuePolicyBlock {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-cc.ctx.Done():
+			return errBatchingShutdown
+		case cc.batchItemCh <- bi:
+			return nil
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-cc.ctx.Done():
+			return errBatchingShutdown
+		case cc.batchItemCh <- bi:
+			return nil
+		default: // queue is full
+		}
+
+		stats.Record(cc.ctx, observations.ConsensusQueueRejected.M(1))
+		err := fmt.Errorf("%w (%d items)", ErrQueueFull, cap(cc.batchItemCh))
+		if cc.config.Batching.QueuePolicy == QueuePolicyError {
+			logger.Warnf("rejecting %s operation on %s: %s", bi.op.Type, bi.op.Cid.Cid, err)
+			return err
+		}
+
+		// shed the oldest item to make room. The batchWorker may
+		// have taken it already, in which case we just retry.
+		select {
+		case old := <-cc.batchItemCh:
+			logger.Warnf("shedding %s operation on %s: %s", old.op.Type, old.op.Cid.Cid, err)
+			old.committed <- err
+		default:
+		}
+	}
+}
+
+// Launched in NewConsensus as a goroutine when batching is enabled.
+func (cc *Consensus) batchWorker() {
+	maxSize := cc.config.Batching.MaxBatchSize
+	maxAge := cc.config.Batching.MaxBatchAge
+	var batch []batchItem
+
+	// Create the timer but stop it. It will reset when
+	// items start arriving.
+	batchTimer := time.NewTimer(maxAge)
+	if !batchTimer.Stop() {
+		<-batchTimer.C
+	}
+
+	for {
+		select {
+		case <-cc.ctx.Done():
+			for _, bi := range batch {
+				bi.committed <- errBatchingShutdown
+			}
+			return
+		case bi := <-cc.batchItemCh:
+			stats.Record(cc.ctx,
+				observations.ConsensusQueueDepth.M(int64(len(cc.batchItemCh))),
+				observations.ConsensusQueueWait.M(float64(time.Since(bi.queued))/float64(time.Millisecond)),
+			)
+
+			// First item in batch. Start the timer
+			if len(batch) == 0 {
+				batchTimer.Reset(maxAge)
+			}
+
+			batch = append(batch, bi)
+			if len(batch) < maxSize {
+				continue
+			}
+
+			// Stop timer and commit. Leave ready to reset on next
+			// item.
+			if !batchTimer.Stop() {
+				<-batchTimer.C
+			}
+			cc.commitBatch(batch, "size")
+			batch = nil
+
+		case <-batchTimer.C:
+			// timer is expired at this point, it will have to be
+			// reset.
+			cc.commitBatch(batch, "max age")
+			batch = nil
+		}
+	}
+}
+
+// commitBatch commits all the operations in the batch as a single Raft log
+// entry and reports the result of each of them to its item. Items whose
+// context was cancelled while waiting are not committed.
+func (cc *Consensus) commitBatch(batch []batchItem, reason string) {
+	op := &LogOp{
+		Type:    LogOpBatch,
+		Batch:   make([]LogOp, 0, len(batch)),
+		Version: logOpVersion,
+	}
+	pending := make([]batchItem, 0, len(batch))
+	for _, bi := range batch {
+		if err := bi.ctx.Err(); err != nil {
+			bi.committed <- err
+			continue
+		}
+		op.Batch = append(op.Batch, LogOp{
+			Cid:    bi.op.Cid,
+			Type:   bi.op.Type,
+			Origin: bi.op.Origin,
+		})
+		pending = append(pending, bi)
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	start := time.Now()
+	cc.shutdownLock.RLock() // do not shut down while committing
+	_, err := cc.consensus.CommitOp(op)
+	cc.shutdownLock.RUnlock()
+	if err != nil {
+		logger.Errorf("error committing batch (%s): %s", reason, err)
+		for _, bi := range pending {
+			bi.committed <- err
+		}
+		return
+	}
+
+	logger.Infof("batch commit (%s): %d items", reason, len(pending))
+	for _, bi := range pending {
+		bi.committed <- cc.batchItemError(bi.op, start)
+	}
+}
+
+// batchItemError returns the error with which an operation of a batch
+// committed after the given time failed to apply. The leader applies the
+// entry before CommitOp returns, recording failed operations as dead
+// letters and removing the dead letters of those that succeed.
+func (cc *Consensus) batchItemError(op *LogOp, since time.Time) error {
+	letter, ok := cc.deadLetters.get(deadLetterID(op.Type, op.Cid.Cid))
+	if !ok || letter.Timestamp.Before(since) {
+		return nil
+	}
+	return fmt.Errorf("%s operation on %s was not applied: %s", op.Type, op.Cid.Cid, letter.Error)
+}
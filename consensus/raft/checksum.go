@@ -0,0 +1,248 @@
+package raft
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/observations"
+	"github.com/ipfs-cluster/ipfs-cluster/state"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/trace"
+)
+
+// checksumModulus bounds the sum of pin hashes to 256 bits.
+var checksumModulus = new(big.Int).Lsh(big.NewInt(1), 256)
+
+// checksumState wraps the state to keep a checksum of the pinset which is
+// updated as operations are applied. The checksum is the sum of the hashes
+// of every pin (modulo 2^256), so it does not depend on the order in which
+// pins were added and peers with the same pinset have the same checksum.
+//
+// The checksum is computed by listing the whole state on start and after
+// restoring snapshots.
+type checksumState struct {
+	state.State
+
+	mux   sync.Mutex
+	valid bool
+	sum   *big.Int
+	pins  int64
+}
+
+func newChecksumState(st state.State) *checksumState {
+	return &checksumState{
+		State: st,
+		sum:   new(big.Int),
+	}
+}
+
+// pinHash returns the hash of the deterministic serialization of a pin.
+func pinHash(pin api.Pin) (*big.Int, error) {
+	bs, err := pin.ProtoMarshal()
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	h.Write(pin.Cid.Bytes())
+	h.Write(bs)
+	return new(big.Int).SetBytes(h.Sum(nil)), nil
+}
+
+// update adds the hash of the new pin and subtracts the one of the old
+// pin. Either can be unset. It must be called with the lock held.
+func (cs *checksumState) update(old, new *api.Pin) error {
+	if !cs.valid {
+		return nil
+	}
+	if old != nil {
+		h, err := pinHash(*old)
+		if err != nil {
+			return err
+		}
+		cs.sum.Sub(cs.sum, h)
+		cs.pins--
+	}
+	if new != nil {
+		h, err := pinHash(*new)
+		if err != nil {
+			return err
+		}
+		cs.sum.Add(cs.sum, h)
+		cs.pins++
+	}
+	cs.sum.Mod(cs.sum, checksumModulus)
+	cs.record()
+	return nil
+}
+
+// record exports the first bytes of the checksum as a metric, which is
+// enough to spot differences between peers.
+func (cs *checksumState) record() {
+	var buf [32]byte
+	cs.sum.FillBytes(buf[:])
+	stats.Record(context.Background(), observations.RaftStateChecksum.M(int64(binary.BigEndian.Uint64(buf[:8])>>16)))
+}
+
+// old returns the pin currently in the state, if any.
+func (cs *checksumState) old(ctx context.Context, c api.Cid) (*api.Pin, error) {
+	if !cs.valid {
+		return nil, nil
+	}
+	pin, err := cs.State.Get(ctx, c)
+	if err == state.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pin, nil
+}
+
+// Add adds a pin to the state and updates the checksum.
+func (cs *checksumState) Add(ctx context.Context, pin api.Pin) error {
+	cs.mux.Lock()
+	defer cs.mux.Unlock()
+
+	old, err := cs.old(ctx, pin.Cid)
+	if err != nil {
+		cs.valid = false
+		return cs.State.Add(ctx, pin)
+	}
+	if err := cs.State.Add(ctx, pin); err != nil {
+		return err
+	}
+	if err := cs.update(old, &pin); err != nil {
+		logger.Errorf("error updating state checksum: %s", err)
+		cs.valid = false
+	}
+	return nil
+}
+
+// Rm removes a pin from the state and updates the checksum.
+func (cs *checksumState) Rm(ctx context.Context, c api.Cid) error {
+	cs.mux.Lock()
+	defer cs.mux.Unlock()
+
+	old, err := cs.old(ctx, c)
+	if err != nil {
+		cs.valid = false
+		return cs.State.Rm(ctx, c)
+	}
+	if err := cs.State.Rm(ctx, c); err != nil {
+		return err
+	}
+	if err := cs.update(old, nil); err != nil {
+		logger.Errorf("error updating state checksum: %s", err)
+		cs.valid = false
+	}
+	return nil
+}
+
+// Unmarshal restores the state and recomputes the checksum.
+func (cs *checksumState) Unmarshal(r io.Reader) error {
+	cs.mux.Lock()
+	defer cs.mux.Unlock()
+
+	cs.valid = false
+	if err := cs.State.Unmarshal(r); err != nil {
+		return err
+	}
+	return cs.compute(context.Background())
+}
+
+// compute calculates the checksum from the full pinset. It must be called
+// with the lock held.
+func (cs *checksumState) compute(ctx context.Context) error {
+	ctx, span := trace.StartSpan(ctx, "consensus/raft/checksum/compute")
+	defer span.End()
+
+	out := make(chan api.Pin, 1024)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cs.State.List(ctx, out)
+	}()
+
+	sum := new(big.Int)
+	var pins int64
+	var hashErr error
+	for pin := range out {
+		if hashErr != nil {
+			continue
+		}
+		h, err := pinHash(pin)
+		if err != nil {
+			hashErr = err
+			continue
+		}
+		sum.Add(sum, h)
+		pins++
+	}
+	if err := <-errCh; err != nil {
+		return err
+	}
+	if hashErr != nil {
+		return hashErr
+	}
+
+	cs.sum = sum.Mod(sum, checksumModulus)
+	cs.pins = pins
+	cs.valid = true
+	cs.record()
+	return nil
+}
+
+// checksum returns the hex-encoded checksum and the number of pins,
+// computing it first when needed.
+func (cs *checksumState) checksum(ctx context.Context) (string, int64, error) {
+	cs.mux.Lock()
+	defer cs.mux.Unlock()
+
+	if !cs.valid {
+		if err := cs.compute(ctx); err != nil {
+			return "", 0, err
+		}
+	}
+	var buf [32]byte
+	cs.sum.FillBytes(buf[:])
+	return hex.EncodeToString(buf[:]), cs.pins, nil
+}
+
+// StateChecksum returns the checksum of the pinset along with the index of
+// the last operation applied to it. Peers that have applied the same
+// operations have the same checksum.
+func (cc *Consensus) StateChecksum(ctx context.Context) (api.StateChecksum, error) {
+	ctx, span := trace.StartSpan(ctx, "consensus/StateChecksum")
+	defer span.End()
+
+	// The applied index may change while the checksum is computed. Try
+	// to report a consistent pair.
+	var sum string
+	var pins int64
+	var index uint64
+	for i := 0; i < 3; i++ {
+		index = cc.raft.r().AppliedIndex()
+		var err error
+		sum, pins, err = cc.checksum.checksum(ctx)
+		if err != nil {
+			return api.StateChecksum{}, err
+		}
+		if cc.raft.r().AppliedIndex() == index {
+			break
+		}
+	}
+
+	return api.StateChecksum{
+		Peer:         cc.host.ID(),
+		Checksum:     sum,
+		Pins:         pins,
+		AppliedIndex: index,
+		Leader:       cc.raft.CurrentLeader() == cc.host.ID(),
+	}, nil
+}
@@ -0,0 +1,143 @@
+package raft
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ipfs-cluster/ipfs-cluster/datastore/inmem"
+	"github.com/ipfs-cluster/ipfs-cluster/state/dsstate"
+	"github.com/ipfs-cluster/ipfs-cluster/test"
+)
+
+func testingChecksumState(t *testing.T) *checksumState {
+	t.Helper()
+	st, err := dsstate.New(context.Background(), inmem.New(), "", dsstate.DefaultHandle())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newChecksumState(st)
+}
+
+func TestChecksumState(t *testing.T) {
+	ctx := context.Background()
+	st1 := testingChecksumState(t)
+	st2 := testingChecksumState(t)
+
+	empty, _, err := st1.checksum(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pin1 := testPin(test.Cid1)
+	pin2 := testPin(test.Cid2)
+	pin2.Metadata = map[string]string{"b": "2", "a": "1"}
+
+	// Same pins in different order.
+	if err := st1.Add(ctx, pin1); err != nil {
+		t.Fatal(err)
+	}
+	if err := st1.Add(ctx, pin2); err != nil {
+		t.Fatal(err)
+	}
+	if err := st2.Add(ctx, pin2); err != nil {
+		t.Fatal(err)
+	}
+	if err := st2.Add(ctx, pin1); err != nil {
+		t.Fatal(err)
+	}
+
+	sum1, pins, err := st1.checksum(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum2, _, _ := st2.checksum(ctx)
+	if sum1 != sum2 || pins != 2 {
+		t.Fatalf("checksums should match: %s %s (%d pins)", sum1, sum2, pins)
+	}
+	if sum1 == empty {
+		t.Fatal("checksum should have changed")
+	}
+
+	// Updating a pin changes the checksum without counting it twice.
+	pin1.Name = "updated"
+	if err := st1.Add(ctx, pin1); err != nil {
+		t.Fatal(err)
+	}
+	sum1, pins, _ = st1.checksum(ctx)
+	if sum1 == sum2 || pins != 2 {
+		t.Fatalf("checksum should have diverged: %s (%d pins)", sum1, pins)
+	}
+
+	// The incremental checksum matches the one computed from scratch.
+	st1.mux.Lock()
+	st1.valid = false
+	st1.mux.Unlock()
+	full, _, _ := st1.checksum(ctx)
+	if full != sum1 {
+		t.Fatalf("incremental and full checksums differ: %s %s", sum1, full)
+	}
+
+	// Removing the pins returns to the empty checksum.
+	if err := st1.Rm(ctx, pin1.Cid); err != nil {
+		t.Fatal(err)
+	}
+	if err := st1.Rm(ctx, pin2.Cid); err != nil {
+		t.Fatal(err)
+	}
+	sum1, pins, _ = st1.checksum(ctx)
+	if sum1 != empty || pins != 0 {
+		t.Fatalf("expected the empty checksum: %s (%d pins)", sum1, pins)
+	}
+
+	// Restoring a snapshot recomputes the checksum.
+	var buf bytes.Buffer
+	if err := st2.Marshal(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := st1.Unmarshal(&buf); err != nil {
+		t.Fatal(err)
+	}
+	sum1, pins, _ = st1.checksum(ctx)
+	if sum1 != sum2 || pins != 2 {
+		t.Fatalf("restored checksum should match: %s %s (%d pins)", sum1, sum2, pins)
+	}
+}
+
+func TestConsensusStateChecksum(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	defer cleanRaft(1)
+	defer cc.Shutdown(ctx)
+
+	before, err := cc.StateChecksum(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cc.LogPin(ctx, testPin(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := cc.StateChecksum(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Peer != cc.host.ID() || !after.Leader {
+		t.Errorf("unexpected peer information: %+v", after)
+	}
+	if after.Checksum == before.Checksum || after.Pins != 1 {
+		t.Errorf("checksum should have changed: %+v", after)
+	}
+	if after.AppliedIndex <= before.AppliedIndex {
+		t.Error("the applied index should have increased")
+	}
+
+	st := testingChecksumState(t)
+	_ = st.Add(ctx, testPin(test.Cid1))
+	expected, _, _ := st.checksum(ctx)
+	if after.Checksum != expected {
+		t.Errorf("checksum should match the one of the same pinset: %s %s", after.Checksum, expected)
+	}
+}
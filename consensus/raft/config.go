@@ -29,7 +29,51 @@ var (
 	DefaultNetworkTimeout       = 10 * time.Second
 	DefaultCommitRetryDelay     = 200 * time.Millisecond
 	DefaultBackupsRotate        = 6
+	DefaultMaxSnapshots         = 5
 	DefaultDatastoreNamespace   = "/r" // from "/raft"
+	DefaultBatchingMaxQueueSize = 50000
+	DefaultBatchingQueuePolicy  = QueuePolicyBlock
+	DefaultCatchUpPollInterval  = 400 * time.Millisecond
+	DefaultCatchUpTimeout       = time.Duration(0) // no timeout
+	DefaultDiskUsageInterval    = time.Minute
+	DefaultMaxDiskUsage         = uint64(0)        // no limit
+	DefaultStallTimeout         = time.Duration(0) // disabled
+	DefaultRestartOnStall       = false
+	// Peers that missed fewer entries than this catch up by replaying
+	// the leader's log instead of receiving a full snapshot.
+	DefaultTrailingLogs = uint64(50000)
+)
+
+// BatchingConfig configures parameters for folding multiple pin and unpin
+// operations in a single Raft commit. Batching only happens in the Raft
+// leader. Other peers forward every operation to it.
+//
+// MaxBatchSize will trigger a commit whenever the number of operations in
+// the batch reaches the limit.
+//
+// MaxBatchAge will trigger a commit when the oldest operation in the batch
+// reaches it. Setting both values to 0 means batching is disabled.
+//
+// MaxQueueSize specifies how many operations can be waiting to be batched.
+//
+// QueuePolicy specifies what happens to new operations when the queue is
+// full: "block" makes them wait for room, "error" fails them right away
+// and "shed" fails the oldest operation in the queue to make room for them.
+//
+// Peers running versions without batching support cannot apply batched
+// operations, so all peers should be upgraded before enabling it.
+type BatchingConfig struct {
+	MaxBatchSize int
+	MaxBatchAge  time.Duration
+	MaxQueueSize int
+	QueuePolicy  string
+}
+
+// Batching queue policies.
+const (
+	QueuePolicyBlock = "block"
+	QueuePolicyError = "error"
+	QueuePolicyShed  = "shed"
 )
 
 // Config allows to configure the Raft Consensus component for ipfs-cluster.
@@ -62,9 +106,47 @@ type Config struct {
 	// BackupsRotate specifies the maximum number of Raft's DataFolder
 	// copies that we keep as backups (renaming) after cleanup.
 	BackupsRotate int
+	// MaxSnapshots specifies how many Raft snapshots are retained in the
+	// DataFolder.
+	MaxSnapshots int
 	// Namespace to use when writing keys to the datastore
 	DatastoreNamespace string
 
+	// Batching configures folding several operations in a single
+	// commit.
+	Batching BatchingConfig
+
+	// CatchUpPollInterval specifies how often we check whether the
+	// state has applied all the entries in the log while catching up
+	// on start.
+	CatchUpPollInterval time.Duration
+	// CatchUpTimeout specifies how long to wait for the state to catch
+	// up before giving up. 0 means no limit.
+	CatchUpTimeout time.Duration
+
+	// DiskUsageCheckInterval specifies how often the size of the data
+	// folder is measured.
+	DiskUsageCheckInterval time.Duration
+	// MaxDiskUsage specifies the size (bytes) of the data folder above
+	// which a snapshot is triggered to compact the log. 0 means no
+	// limit.
+	MaxDiskUsage uint64
+
+	// StallTimeout specifies how long Raft may go without applying
+	// committed entries to the state, while there is a leader, before
+	// it is considered stalled. Diagnostics are then written to the data
+	// folder. 0 disables the check.
+	StallTimeout time.Duration
+	// RestartOnStall makes the peer restart Raft when it is stalled.
+	RestartOnStall bool
+
+	// NonVoter makes this peer join the cluster as a Raft non-voter. It
+	// replicates the state but never takes part in elections or counts
+	// towards the quorum. Non-voters cannot bootstrap a new cluster.
+	// This is only supported with Raft: peers using CRDT consensus
+	// refuse to start with it (see follower_mode instead).
+	NonVoter bool
+
 	// A Hashicorp Raft's configuration object.
 	RaftConfig *hraft.Config
 
@@ -104,8 +186,40 @@ type jsonConfig struct {
 	// copies that we keep as backups (renaming) after cleanup.
 	BackupsRotate int `json:"backups_rotate"`
 
+	// MaxSnapshots specifies how many Raft snapshots are retained in
+	// the data folder.
+	MaxSnapshots int `json:"max_snapshots"`
+
 	DatastoreNamespace string `json:"datastore_namespace,omitempty"`
 
+	// Batching configures folding several operations in a single
+	// commit.
+	Batching batchingConfigJSON `json:"batching"`
+
+	// How often to check progress while catching up with the log on
+	// start.
+	CatchUpPollInterval string `json:"catch_up_poll_interval"`
+
+	// How long to wait to catch up with the log on start. 0 for no limit.
+	CatchUpTimeout string `json:"catch_up_timeout"`
+
+	// How often to measure the size of the data folder.
+	DiskUsageCheckInterval string `json:"disk_usage_check_interval"`
+
+	// Size in bytes of the data folder that triggers a snapshot. 0 for
+	// no limit.
+	MaxDiskUsage uint64 `json:"max_disk_usage"`
+
+	// How long Raft may go without applying committed entries before
+	// it is considered stalled. 0 disables the check.
+	StallTimeout string `json:"stall_timeout"`
+
+	// Restart Raft when it is stalled.
+	RestartOnStall bool `json:"restart_on_stall"`
+
+	// Join the cluster as a non-voter that can never become leader.
+	NonVoter bool `json:"non_voter"`
+
 	// HeartbeatTimeout specifies the time in follower state without
 	// a leader before we attempt an election.
 	HeartbeatTimeout string `json:"heartbeat_timeout,omitempty"`
@@ -145,6 +259,13 @@ type jsonConfig struct {
 	// LocalID string `json:local_id`
 }
 
+type batchingConfigJSON struct {
+	MaxBatchSize int    `json:"max_batch_size"`
+	MaxBatchAge  string `json:"max_batch_age"`
+	MaxQueueSize int    `json:"max_queue_size,omitempty"`
+	QueuePolicy  string `json:"queue_policy,omitempty"`
+}
+
 // ConfigKey returns a human-friendly indentifier for this Config.
 func (cfg *Config) ConfigKey() string {
 	return configKey
@@ -176,6 +297,44 @@ func (cfg *Config) Validate() error {
 		return errors.New("backups_rotate should be larger than 0")
 	}
 
+	if cfg.MaxSnapshots <= 0 {
+		return errors.New("max_snapshots should be larger than 0")
+	}
+
+	if cfg.Batching.MaxBatchSize < 0 {
+		return errors.New("batching.max_batch_size is invalid")
+	}
+
+	if cfg.Batching.MaxBatchAge < 0 {
+		return errors.New("batching.max_batch_age is invalid")
+	}
+
+	if cfg.Batching.MaxQueueSize <= 0 {
+		return errors.New("batching.max_queue_size is invalid")
+	}
+
+	switch cfg.Batching.QueuePolicy {
+	case QueuePolicyBlock, QueuePolicyError, QueuePolicyShed:
+	default:
+		return errors.New("batching.queue_policy should be block, error or shed")
+	}
+
+	if cfg.CatchUpPollInterval <= 0 {
+		return errors.New("catch_up_poll_interval is invalid")
+	}
+
+	if cfg.CatchUpTimeout < 0 {
+		return errors.New("catch_up_timeout is invalid")
+	}
+
+	if cfg.DiskUsageCheckInterval <= 0 {
+		return errors.New("disk_usage_check_interval is invalid")
+	}
+
+	if cfg.StallTimeout < 0 {
+		return errors.New("stall_timeout is invalid")
+	}
+
 	return hraft.ValidateConfig(cfg.RaftConfig)
 }
 
@@ -213,6 +372,11 @@ func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 	commitTimeout := parseDuration(jcfg.CommitTimeout)
 	snapshotInterval := parseDuration(jcfg.SnapshotInterval)
 	leaderLeaseTimeout := parseDuration(jcfg.LeaderLeaseTimeout)
+	maxBatchAge := parseDuration(jcfg.Batching.MaxBatchAge)
+	catchUpPollInterval := parseDuration(jcfg.CatchUpPollInterval)
+	catchUpTimeout := parseDuration(jcfg.CatchUpTimeout)
+	diskUsageCheckInterval := parseDuration(jcfg.DiskUsageCheckInterval)
+	stallTimeout := parseDuration(jcfg.StallTimeout)
 
 	// Set all values in config. For some, take defaults if they are 0.
 	// Set values from jcfg if they are not 0 values
@@ -224,6 +388,18 @@ func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 	cfg.CommitRetries = jcfg.CommitRetries
 	config.SetIfNotDefault(commitRetryDelay, &cfg.CommitRetryDelay)
 	config.SetIfNotDefault(jcfg.BackupsRotate, &cfg.BackupsRotate)
+	config.SetIfNotDefault(jcfg.MaxSnapshots, &cfg.MaxSnapshots)
+	cfg.Batching.MaxBatchSize = jcfg.Batching.MaxBatchSize
+	cfg.Batching.MaxBatchAge = maxBatchAge
+	config.SetIfNotDefault(jcfg.Batching.MaxQueueSize, &cfg.Batching.MaxQueueSize)
+	config.SetIfNotDefault(jcfg.Batching.QueuePolicy, &cfg.Batching.QueuePolicy)
+	config.SetIfNotDefault(catchUpPollInterval, &cfg.CatchUpPollInterval)
+	cfg.CatchUpTimeout = catchUpTimeout
+	config.SetIfNotDefault(diskUsageCheckInterval, &cfg.DiskUsageCheckInterval)
+	cfg.MaxDiskUsage = jcfg.MaxDiskUsage
+	cfg.StallTimeout = stallTimeout
+	cfg.RestartOnStall = jcfg.RestartOnStall
+	cfg.NonVoter = jcfg.NonVoter
 
 	// Raft values
 	config.SetIfNotDefault(heartbeatTimeout, &cfg.RaftConfig.HeartbeatTimeout)
@@ -246,6 +422,12 @@ func (cfg *Config) ToJSON() ([]byte, error) {
 	return config.DefaultJSONMarshal(jcfg)
 }
 
+// JSONKeys returns the keys of the JSON representation of this
+// configuration, including those omitted when empty.
+func (cfg *Config) JSONKeys() []string {
+	return config.JSONKeys(jsonConfig{})
+}
+
 func (cfg *Config) toJSONConfig() *jsonConfig {
 	jcfg := &jsonConfig{
 		DataFolder:           cfg.DataFolder,
@@ -255,6 +437,7 @@ func (cfg *Config) toJSONConfig() *jsonConfig {
 		CommitRetries:        cfg.CommitRetries,
 		CommitRetryDelay:     cfg.CommitRetryDelay.String(),
 		BackupsRotate:        cfg.BackupsRotate,
+		MaxSnapshots:         cfg.MaxSnapshots,
 		HeartbeatTimeout:     cfg.RaftConfig.HeartbeatTimeout.String(),
 		ElectionTimeout:      cfg.RaftConfig.ElectionTimeout.String(),
 		CommitTimeout:        cfg.RaftConfig.CommitTimeout.String(),
@@ -263,6 +446,23 @@ func (cfg *Config) toJSONConfig() *jsonConfig {
 		SnapshotInterval:     cfg.RaftConfig.SnapshotInterval.String(),
 		SnapshotThreshold:    cfg.RaftConfig.SnapshotThreshold,
 		LeaderLeaseTimeout:   cfg.RaftConfig.LeaderLeaseTimeout.String(),
+		Batching: batchingConfigJSON{
+			MaxBatchSize: cfg.Batching.MaxBatchSize,
+			MaxBatchAge:  cfg.Batching.MaxBatchAge.String(),
+		},
+		CatchUpPollInterval:    cfg.CatchUpPollInterval.String(),
+		CatchUpTimeout:         cfg.CatchUpTimeout.String(),
+		DiskUsageCheckInterval: cfg.DiskUsageCheckInterval.String(),
+		MaxDiskUsage:           cfg.MaxDiskUsage,
+		StallTimeout:           cfg.StallTimeout.String(),
+		RestartOnStall:         cfg.RestartOnStall,
+		NonVoter:               cfg.NonVoter,
+	}
+	if cfg.Batching.MaxQueueSize != DefaultBatchingMaxQueueSize {
+		jcfg.Batching.MaxQueueSize = cfg.Batching.MaxQueueSize
+	}
+	if cfg.Batching.QueuePolicy != DefaultBatchingQueuePolicy {
+		jcfg.Batching.QueuePolicy = cfg.Batching.QueuePolicy
 	}
 	if cfg.DatastoreNamespace != DefaultDatastoreNamespace {
 		jcfg.DatastoreNamespace = cfg.DatastoreNamespace
@@ -280,8 +480,23 @@ func (cfg *Config) Default() error {
 	cfg.CommitRetries = DefaultCommitRetries
 	cfg.CommitRetryDelay = DefaultCommitRetryDelay
 	cfg.BackupsRotate = DefaultBackupsRotate
+	cfg.MaxSnapshots = DefaultMaxSnapshots
 	cfg.DatastoreNamespace = DefaultDatastoreNamespace
+	cfg.Batching = BatchingConfig{
+		MaxBatchSize: 0,
+		MaxBatchAge:  0,
+		MaxQueueSize: DefaultBatchingMaxQueueSize,
+		QueuePolicy:  DefaultBatchingQueuePolicy,
+	}
+	cfg.CatchUpPollInterval = DefaultCatchUpPollInterval
+	cfg.CatchUpTimeout = DefaultCatchUpTimeout
+	cfg.DiskUsageCheckInterval = DefaultDiskUsageInterval
+	cfg.MaxDiskUsage = DefaultMaxDiskUsage
+	cfg.StallTimeout = DefaultStallTimeout
+	cfg.RestartOnStall = DefaultRestartOnStall
+	cfg.NonVoter = false
 	cfg.RaftConfig = hraft.DefaultConfig()
+	cfg.RaftConfig.TrailingLogs = DefaultTrailingLogs
 
 	// These options are imposed over any Default Raft Config.
 	cfg.RaftConfig.ShutdownOnRemove = false
@@ -314,6 +529,11 @@ func (cfg *Config) GetDataFolder() string {
 	return cfg.DataFolder
 }
 
+func (cfg *Config) batchingEnabled() bool {
+	return cfg.Batching.MaxBatchSize > 0 &&
+		cfg.Batching.MaxBatchAge > 0
+}
+
 // ToDisplayJSON returns JSON config as a string.
 func (cfg *Config) ToDisplayJSON() ([]byte, error) {
 	return config.DisplayJSON(cfg.toJSONConfig())
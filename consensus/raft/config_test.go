@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"testing"
+	"time"
 
 	hraft "github.com/hashicorp/raft"
 )
@@ -16,6 +17,7 @@ var cfgJSON = []byte(`
     "commit_retries": 1,
     "commit_retry_delay": "200ms",
     "backups_rotate": 5,
+    "max_snapshots": 3,
     "heartbeat_timeout": "1s",
     "election_timeout": "1s",
     "commit_timeout": "50ms",
@@ -23,7 +25,18 @@ var cfgJSON = []byte(`
     "trailing_logs": 10240,
     "snapshot_interval": "2m0s",
     "snapshot_threshold": 8192,
-    "leader_lease_timeout": "500ms"
+    "leader_lease_timeout": "500ms",
+    "batching": {
+        "max_batch_size": 100,
+        "max_batch_age": "100ms"
+    },
+    "catch_up_poll_interval": "1s",
+    "catch_up_timeout": "10m",
+    "disk_usage_check_interval": "30s",
+    "max_disk_usage": 1073741824,
+    "stall_timeout": "5m",
+    "restart_on_stall": true,
+    "non_voter": true
 }
 `)
 
@@ -54,6 +67,53 @@ func TestLoadJSON(t *testing.T) {
 	if cfg.RaftConfig.LeaderLeaseTimeout != def.LeaderLeaseTimeout {
 		t.Error("expected default leader lease")
 	}
+
+	if !cfg.batchingEnabled() || cfg.Batching.MaxBatchSize != 100 {
+		t.Error("batching should be enabled")
+	}
+	if cfg.Batching.MaxQueueSize != DefaultBatchingMaxQueueSize {
+		t.Error("expected default max_queue_size")
+	}
+	if cfg.Batching.QueuePolicy != QueuePolicyBlock {
+		t.Error("expected default queue_policy")
+	}
+
+	if cfg.MaxSnapshots != 3 {
+		t.Error("max_snapshots not parsed")
+	}
+
+	if cfg.CatchUpPollInterval != time.Second || cfg.CatchUpTimeout != 10*time.Minute {
+		t.Error("catch up options not parsed")
+	}
+
+	if !cfg.NonVoter {
+		t.Error("non_voter not parsed")
+	}
+
+	if cfg.DiskUsageCheckInterval != 30*time.Second || cfg.MaxDiskUsage != 1<<30 {
+		t.Error("disk usage options not parsed")
+	}
+
+	if cfg.StallTimeout != 5*time.Minute || !cfg.RestartOnStall {
+		t.Error("stall options not parsed")
+	}
+
+	json.Unmarshal(cfgJSON, j)
+	j.Batching.MaxQueueSize = -1
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error in batching.max_queue_size")
+	}
+
+	j = &jsonConfig{}
+	json.Unmarshal(cfgJSON, j)
+	j.Batching.QueuePolicy = "drop"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected error in batching.queue_policy")
+	}
 }
 
 func TestToJSON(t *testing.T) {
@@ -76,6 +136,9 @@ func TestDefault(t *testing.T) {
 	if cfg.Validate() != nil {
 		t.Fatal("error validating")
 	}
+	if cfg.RaftConfig.TrailingLogs != DefaultTrailingLogs {
+		t.Error("the default trailing logs should be kept to avoid snapshot transfers")
+	}
 
 	cfg.RaftConfig.HeartbeatTimeout = 0
 	if cfg.Validate() == nil {
@@ -100,6 +163,18 @@ func TestDefault(t *testing.T) {
 		t.Fatal("expected error validating")
 	}
 
+	cfg.Default()
+	cfg.DiskUsageCheckInterval = 0
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
+	cfg.Default()
+	cfg.StallTimeout = -1
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+
 	cfg.Default()
 	cfg.BackupsRotate = 0
 
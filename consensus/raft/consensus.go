@@ -4,16 +4,22 @@ package raft
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/kvstore"
 	"github.com/ipfs-cluster/ipfs-cluster/state"
 	"github.com/ipfs-cluster/ipfs-cluster/state/dsstate"
 
+	hraft "github.com/hashicorp/raft"
 	ds "github.com/ipfs/go-datastore"
 	logging "github.com/ipfs/go-log/v2"
 	consensus "github.com/libp2p/go-libp2p-consensus"
@@ -28,6 +34,42 @@ import (
 
 var logger = logging.Logger("raft")
 
+// how many operations of a rollback are committed in each log entry.
+var rollbackBatchSize = 1000
+
+// Errors returned by LogPin and LogUnpin. They are usually wrapped, so
+// errors.Is should be used to check for them. They are preserved when
+// operations are redirected to the leader.
+var (
+	// ErrCommitTimeout is returned when an operation could not be
+	// committed before the context deadline. The operation may still
+	// be committed afterwards.
+	ErrCommitTimeout = fmt.Errorf("consensus commit timed out (%w)", api.ErrUncertain)
+	// ErrNotLeader is returned when there is no leader that can commit
+	// the operation, i.e. during elections. Retrying later may succeed.
+	// The leader may have received the operation before losing its
+	// leadership, so it may still be committed.
+	ErrNotLeader = fmt.Errorf("no consensus leader available (%w)", api.ErrUncertain)
+	// ErrCommitRejected is returned when the operation was not committed
+	// for any other reason. Retrying is unlikely to help.
+	ErrCommitRejected = fmt.Errorf("consensus commit %w", api.ErrRejected)
+	// ErrNoQuorum is returned right away when not enough voters are
+	// reachable to commit the operation. The cluster is degraded until
+	// enough voters come back.
+	ErrNoQuorum = errors.New("consensus quorum unavailable")
+	// ErrQueueFull is returned when the batching queue is full and
+	// its policy is to error or to shed operations. The peer is
+	// overloaded and the operation should be retried later.
+	ErrQueueFull = errors.New("consensus batching queue is full")
+	// ErrNonVoter is returned when LogPin or LogUnpin are called on a
+	// peer that joined as a non-voter. These peers only follow the
+	// state and must not be used to modify it.
+	ErrNonVoter = fmt.Errorf("non-voters cannot modify the shared state: %w", api.ErrRejected)
+)
+
+// returned by libp2p-raft when committing on a follower.
+const errActorNotLeader = "this actor is not the leader"
+
 // Consensus handles the work of keeping a shared-state between
 // the peers of an IPFS Cluster, as well as modifying that state and
 // applying any updates in a thread-safe manner.
@@ -42,11 +84,19 @@ type Consensus struct {
 	actor     consensus.Actor
 	baseOp    *LogOp
 	raft      *raftWrapper
+	checksum  *checksumState
+
+	deadLetters *deadLetters
 
 	rpcClient *rpc.Client
 	rpcReady  chan struct{}
 	readyCh   chan struct{}
 
+	batchItemCh chan batchItem
+	inflight    *inflightOps
+
+	commitLatency int64 // nanoseconds, accessed atomically
+
 	shutdownLock sync.RWMutex
 	shutdown     bool
 }
@@ -85,14 +135,15 @@ func NewConsensus(
 		cancel()
 		return nil, err
 	}
-	consensus := libp2praft.NewOpLog(state, baseOp)
+	checksum := newChecksumState(state)
+	consensus := libp2praft.NewOpLog(checksum, baseOp)
 	raft, err := newRaftWrapper(host, cfg, consensus.FSM(), staging)
 	if err != nil {
 		logger.Error("error creating raft: ", err)
 		cancel()
 		return nil, err
 	}
-	actor := libp2praft.NewActor(raft.raft)
+	actor := libp2praft.NewActor(raft.r())
 	consensus.SetActor(actor)
 
 	cc := &Consensus{
@@ -104,12 +155,26 @@ func NewConsensus(
 		actor:     actor,
 		baseOp:    baseOp,
 		raft:      raft,
+		checksum:  checksum,
 		rpcReady:  make(chan struct{}, 1),
 		readyCh:   make(chan struct{}, 1),
+		inflight:  newInflightOps(),
+
+		deadLetters: newDeadLetters(),
 	}
 
 	baseOp.consensus = cc
 
+	if cfg.batchingEnabled() {
+		cc.batchItemCh = make(chan batchItem, cfg.Batching.MaxQueueSize)
+		go cc.batchWorker()
+	}
+
+	go cc.watchDiskUsage()
+	go cc.watchSyncLag()
+	if cfg.StallTimeout > 0 {
+		go cc.watchStalls()
+	}
 	go cc.finishBootstrap()
 	return cc, nil
 }
@@ -143,9 +208,16 @@ func (cc *Consensus) WaitForSync(ctx context.Context) error {
 		return errors.New("error waiting for leader: " + err.Error())
 	}
 
-	err = cc.raft.WaitForVoter(ctx)
-	if err != nil {
-		return errors.New("error waiting to become a Voter: " + err.Error())
+	if cc.config.NonVoter {
+		err = cc.raft.WaitForNonVoter(ctx)
+		if err != nil {
+			return errors.New("error waiting to be added as a non-Voter: " + err.Error())
+		}
+	} else {
+		err = cc.raft.WaitForVoter(ctx)
+		if err != nil {
+			return errors.New("error waiting to become a Voter: " + err.Error())
+		}
 	}
 
 	err = cc.raft.WaitForUpdates(ctx)
@@ -182,6 +254,41 @@ func (cc *Consensus) finishBootstrap() {
 	cc.readyCh <- struct{}{}
 }
 
+// CatchUpProgress returns how far the Raft state is from having applied
+// all the entries in the log while waiting for it to sync.
+func (cc *Consensus) CatchUpProgress(ctx context.Context) api.CatchUpProgress {
+	_, span := trace.StartSpan(ctx, "consensus/CatchUpProgress")
+	defer span.End()
+
+	return cc.raft.CatchUpProgress()
+}
+
+// Launched in NewConsensus as a goroutine. It records how many log entries
+// this peer has yet to apply, so that peers falling behind after an outage
+// can be spotted.
+func (cc *Consensus) watchSyncLag() {
+	ticker := time.NewTicker(syncLagInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cc.ctx.Done():
+			return
+		case <-ticker.C:
+			cc.raft.recordSyncLag(cc.ctx)
+		}
+	}
+}
+
+// Stats returns the Raft role, term and applied index of this peer, along
+// with its snapshots and the size of the Raft data folder.
+func (cc *Consensus) Stats(ctx context.Context) api.ConsensusStats {
+	_, span := trace.StartSpan(ctx, "consensus/Stats")
+	defer span.End()
+
+	return cc.raft.Stats()
+}
+
 // Shutdown stops the component so it will not process any
 // more updates. The underlying consensus is permanently
 // shutdown, along with the libp2p transport.
@@ -221,6 +328,15 @@ func (cc *Consensus) SetClient(c *rpc.Client) {
 	cc.rpcReady <- struct{}{}
 }
 
+// SetScratchStore sets the store used to persist the dead letters so that
+// they survive restarts.
+func (cc *Consensus) SetScratchStore(store *kvstore.Store) {
+	err := cc.deadLetters.setStore(cc.ctx, store.Namespace("/raft/deadletters"))
+	if err != nil {
+		logger.Error(err)
+	}
+}
+
 // Ready returns a channel which is signaled when the Consensus
 // algorithm has finished bootstrapping and is ready to use
 func (cc *Consensus) Ready(ctx context.Context) <-chan struct{} {
@@ -235,6 +351,31 @@ func (cc *Consensus) IsTrustedPeer(ctx context.Context, p peer.ID) bool {
 	return true
 }
 
+// IsNonVoter returns true when this peer is configured to join the cluster
+// as a Raft non-voter.
+func (cc *Consensus) IsNonVoter(ctx context.Context) bool {
+	return cc.config.NonVoter
+}
+
+// isPeerNonVoter asks the given peer whether it should be added as a
+// non-voter. Peers that cannot answer are considered voters.
+func (cc *Consensus) isPeerNonVoter(ctx context.Context, pid peer.ID) bool {
+	var nonVoter bool
+	err := cc.rpcClient.CallContext(
+		ctx,
+		pid,
+		"Consensus",
+		"IsNonVoter",
+		struct{}{},
+		&nonVoter,
+	)
+	if err != nil {
+		logger.Debugf("cannot check whether %s is a non-voter: %s", pid, err)
+		return false
+	}
+	return nonVoter
+}
+
 // Trust is a no-op.
 func (cc *Consensus) Trust(ctx context.Context, pid peer.ID) error { return nil }
 
@@ -243,11 +384,31 @@ func (cc *Consensus) Distrust(ctx context.Context, pid peer.ID) error { return n
 
 func (cc *Consensus) op(ctx context.Context, pin api.Pin, t LogOpType) *LogOp {
 	return &LogOp{
-		Cid:  pin,
-		Type: t,
+		Cid:     pin,
+		Type:    t,
+		Origin:  cc.origin(ctx),
+		Version: logOpVersion,
 	}
 }
 
+func (cc *Consensus) scheduleOp(ctx context.Context, s api.PinSchedule, t LogOpType) *LogOp {
+	return &LogOp{
+		Schedule: s,
+		Type:     t,
+		Origin:   cc.origin(ctx),
+		Version:  logOpVersion,
+	}
+}
+
+// origin returns the peer that submitted an operation: the sender when it
+// was redirected to us, or ourselves.
+func (cc *Consensus) origin(ctx context.Context) peer.ID {
+	if sender, err := rpc.GetRequestSender(ctx); err == nil {
+		return sender
+	}
+	return cc.host.ID()
+}
+
 // returns true if the operation was redirected to the leader
 // note that if the leader just dissappeared, the rpc call will
 // fail because we haven't heard that it's gone.
@@ -262,24 +423,18 @@ func (cc *Consensus) redirectToLeader(ctx context.Context, method string, arg in
 		logger.Debugf("redirect try %d", i)
 		leader, err := cc.Leader(ctx)
 
-		// No leader, wait for one
+		// No leader, wait for one before forwarding.
 		if err != nil {
 			logger.Warn("there seems to be no leader. Waiting for one")
 			rctx, cancel := context.WithTimeout(
 				ctx,
 				cc.config.WaitForLeaderTimeout,
 			)
-			defer cancel()
-			pidstr, err := cc.raft.WaitForLeader(rctx)
+			leader, err = cc.WaitForLeader(rctx)
+			cancel()
 
 			// means we timed out waiting for a leader
 			// we don't retry in this case
-			if err != nil {
-				err = fmt.Errorf("timed out waiting for leader: %w", err)
-				logger.Error(err)
-				return false, err
-			}
-			leader, err = peer.Decode(pidstr)
 			if err != nil {
 				logger.Error(err)
 				return false, err
@@ -301,8 +456,16 @@ func (cc *Consensus) redirectToLeader(ctx context.Context, method string, arg in
 			&struct{}{},
 		)
 		if finalErr != nil {
+			finalErr = classifyCommitError(finalErr)
+			if !errors.Is(finalErr, ErrNotLeader) {
+				break
+			}
 			logger.Errorf("retrying to redirect request to leader: %s", finalErr)
-			time.Sleep(2 * cc.config.RaftConfig.HeartbeatTimeout)
+			// Wait for a leadership change rather than
+			// retrying against the same peer right away.
+			if err := cc.waitLeaderChange(ctx, leader); err != nil {
+				return true, classifyCommitError(err)
+			}
 			continue
 		}
 		break
@@ -312,11 +475,85 @@ func (cc *Consensus) redirectToLeader(ctx context.Context, method string, arg in
 	return true, finalErr
 }
 
+// waitLeaderChange waits until there is a leader other than the given one,
+// or up to two heartbeat timeouts, as the leader may just have
+// not applied a change yet. It only errors when the context is done.
+func (cc *Consensus) waitLeaderChange(ctx context.Context, leader peer.ID) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sub := cc.raft.SubscribeLeader(ctx)
+	if l := cc.raft.CurrentLeader(); l != "" && l != leader {
+		return nil
+	}
+	timer := time.NewTimer(2 * cc.config.RaftConfig.HeartbeatTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		case l, ok := <-sub:
+			if !ok {
+				return ctx.Err()
+			}
+			if l != "" && l != leader {
+				return nil
+			}
+		}
+	}
+}
+
+// classifyCommitError wraps commit errors with ErrCommitTimeout, ErrNotLeader,
+// ErrNoQuorum or ErrCommitRejected. Errors of operations which may still be
+// committed wrap api.ErrUncertain.
+func classifyCommitError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrCommitTimeout),
+		errors.Is(err, ErrNotLeader),
+		errors.Is(err, api.ErrUncertain),
+		errors.Is(err, ErrCommitRejected),
+		errors.Is(err, ErrNoQuorum),
+		errors.Is(err, ErrQueueFull):
+		return err
+	case errors.Is(err, context.Canceled):
+		// The operation may have been submitted already.
+		return fmt.Errorf("%w (%w)", err, api.ErrUncertain)
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, hraft.ErrEnqueueTimeout):
+		return fmt.Errorf("%w: %s", ErrCommitTimeout, err)
+	case errors.Is(err, hraft.ErrNotLeader),
+		errors.Is(err, hraft.ErrLeadershipLost),
+		errors.Is(err, hraft.ErrLeadershipTransferInProgress),
+		err.Error() == errActorNotLeader:
+		return fmt.Errorf("%w: %s", ErrNotLeader, err)
+	}
+
+	// Errors from the leader lose their type when sent over RPC.
+	for _, cerr := range []error{ErrCommitTimeout, ErrNotLeader, ErrCommitRejected, ErrNoQuorum, ErrQueueFull, api.ErrUncertain} {
+		if strings.Contains(err.Error(), cerr.Error()) {
+			return fmt.Errorf("%w (from leader): %s", cerr, err)
+		}
+	}
+
+	// We could not reach the leader.
+	if rpc.IsClientError(err) {
+		return fmt.Errorf("%w: %s", ErrNotLeader, err)
+	}
+	return fmt.Errorf("%w: %s", ErrCommitRejected, err)
+}
+
 // commit submits a cc.consensus commit. It retries upon failures.
 func (cc *Consensus) commit(ctx context.Context, op *LogOp, rpcOp string, redirectArg interface{}) error {
 	ctx, span := trace.StartSpan(ctx, "consensus/commit")
 	defer span.End()
 
+	if cc.config.NonVoter {
+		return ErrNonVoter
+	}
+
 	if cc.config.Tracing {
 		// required to cross the serialized boundary
 		op.SpanCtx = span.SpanContext()
@@ -326,6 +563,7 @@ func (cc *Consensus) commit(ctx context.Context, op *LogOp, rpcOp string, redire
 		}
 	}
 
+	start := time.Now()
 	var finalErr error
 	for i := 0; i <= cc.config.CommitRetries; i++ {
 		logger.Debugf("attempt #%d: committing %+v", i, op)
@@ -336,24 +574,39 @@ func (cc *Consensus) commit(ctx context.Context, op *LogOp, rpcOp string, redire
 				i, finalErr)
 		}
 
+		// Do not wait for timeouts when the commit cannot happen.
+		if err := cc.checkQuorum(ctx); err != nil {
+			return err
+		}
+
 		// try to send it to the leader
 		// redirectToLeader has it's own retry loop. If this fails
 		// we're done here.
 		ok, err := cc.redirectToLeader(ctx, rpcOp, redirectArg)
 		if err != nil || ok {
+			if err == nil {
+				cc.recordCommitLatency(time.Since(start))
+			}
 			return err
 		}
 
 		// Being here means we are the LEADER. We can commit.
 
 		// now commit the changes to our state
-		cc.shutdownLock.RLock() // do not shut down while committing
-		_, finalErr = cc.consensus.CommitOp(op)
-		cc.shutdownLock.RUnlock()
+		committed, err := cc.leaderCommit(ctx, op)
+		finalErr = err
 		if finalErr != nil {
+			// Only retry when there may be a new leader.
+			if !errors.Is(finalErr, ErrNotLeader) {
+				return finalErr
+			}
 			goto RETRY
 		}
+		if !committed {
+			break
+		}
 
+		cc.recordCommitLatency(time.Since(start))
 		switch op.Type {
 		case LogOpPin:
 			logger.Infof("pin committed to global state: %s", op.Cid.Cid)
@@ -363,13 +616,38 @@ func (cc *Consensus) commit(ctx context.Context, op *LogOp, rpcOp string, redire
 		break
 
 	RETRY:
-		time.Sleep(cc.config.CommitRetryDelay)
+		select {
+		case <-ctx.Done():
+			return classifyCommitError(ctx.Err())
+		case <-time.After(cc.config.CommitRetryDelay):
+		}
 	}
 	return finalErr
 }
 
+// commitOp commits the operation, returning early when the context is
+// done. The operation may still be committed in that case.
+func (cc *Consensus) commitOp(ctx context.Context, op *LogOp) error {
+	done := make(chan error, 1)
+	go func() {
+		cc.shutdownLock.RLock() // do not shut down while committing
+		_, err := cc.consensus.CommitOp(op)
+		cc.shutdownLock.RUnlock()
+		done <- err
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-done:
+		return err
+	}
+}
+
 // LogPin submits a Cid to the shared state of the cluster. It will forward
-// the operation to the leader if this is not it.
+// the operation to the leader if this is not it. Nothing is committed when
+// the same pin is already in the state or being committed. Non-voters
+// return ErrNonVoter.
 func (cc *Consensus) LogPin(ctx context.Context, pin api.Pin) error {
 	ctx, span := trace.StartSpan(ctx, "consensus/LogPin")
 	defer span.End()
@@ -382,7 +660,8 @@ func (cc *Consensus) LogPin(ctx context.Context, pin api.Pin) error {
 	return nil
 }
 
-// LogUnpin removes a Cid from the shared state of the cluster.
+// LogUnpin removes a Cid from the shared state of the cluster. Nothing is
+// committed when the Cid is not in the state.
 func (cc *Consensus) LogUnpin(ctx context.Context, pin api.Pin) error {
 	ctx, span := trace.StartSpan(ctx, "consensus/LogUnpin")
 	defer span.End()
@@ -395,6 +674,27 @@ func (cc *Consensus) LogUnpin(ctx context.Context, pin api.Pin) error {
 	return nil
 }
 
+// LogSchedule submits a pin schedule to the shared state of the cluster,
+// replacing any with the same ID. It will forward the operation to the
+// leader if this is not it.
+func (cc *Consensus) LogSchedule(ctx context.Context, s api.PinSchedule) error {
+	ctx, span := trace.StartSpan(ctx, "consensus/LogSchedule")
+	defer span.End()
+
+	op := cc.scheduleOp(ctx, s, LogOpSchedule)
+	return cc.commit(ctx, op, "LogSchedule", s)
+}
+
+// LogUnschedule removes a pin schedule from the shared state of the
+// cluster.
+func (cc *Consensus) LogUnschedule(ctx context.Context, id string) error {
+	ctx, span := trace.StartSpan(ctx, "consensus/LogUnschedule")
+	defer span.End()
+
+	op := cc.scheduleOp(ctx, api.PinSchedule{ID: id}, LogOpUnschedule)
+	return cc.commit(ctx, op, "LogUnschedule", id)
+}
+
 // AddPeer adds a new peer to participate in this consensus. It will
 // forward the operation to the leader if this is not it.
 func (cc *Consensus) AddPeer(ctx context.Context, pid peer.ID) error {
@@ -412,15 +712,20 @@ func (cc *Consensus) AddPeer(ctx context.Context, pid peer.ID) error {
 			return err
 		}
 		// Being here means we are the leader and can commit
+		nonVoter := cc.isPeerNonVoter(ctx, pid)
 		cc.shutdownLock.RLock() // do not shutdown while committing
-		finalErr = cc.raft.AddPeer(ctx, pid.String())
+		finalErr = cc.raft.AddPeer(ctx, pid.String(), nonVoter)
 
 		cc.shutdownLock.RUnlock()
 		if finalErr != nil {
 			time.Sleep(cc.config.CommitRetryDelay)
 			continue
 		}
-		logger.Infof("peer added to Raft: %s", pid.Pretty())
+		if nonVoter {
+			logger.Infof("peer added to Raft as non-voter: %s", pid.Pretty())
+		} else {
+			logger.Infof("peer added to Raft: %s", pid.Pretty())
+		}
 		break
 	}
 	return finalErr
@@ -486,9 +791,40 @@ func (cc *Consensus) Leader(ctx context.Context) (peer.ID, error) {
 	_, span := trace.StartSpan(ctx, "consensus/Leader")
 	defer span.End()
 
-	// Note the hard-dependency on raft here...
-	raftactor := cc.actor.(*libp2praft.Actor)
-	return raftactor.Leader()
+	// The leader is tracked by the raft wrapper, so this does not
+	// block on raft.
+	if l := cc.raft.CurrentLeader(); l != "" {
+		return l, nil
+	}
+	return "", ErrNotLeader
+}
+
+// WaitForLeader returns the current Raft leader, waiting for one to be
+// elected if there is none.
+func (cc *Consensus) WaitForLeader(ctx context.Context) (peer.ID, error) {
+	ctx, span := trace.StartSpan(ctx, "consensus/WaitForLeader")
+	defer span.End()
+
+	pidstr, err := cc.raft.WaitForLeader(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%w: timed out waiting for leader: %s", ErrNotLeader, err)
+	}
+	return peer.Decode(pidstr)
+}
+
+// SubscribeLeader returns a channel which receives the new leader every time
+// leadership changes, or an empty peer ID when there is no leader. The
+// channel is closed when the context is cancelled.
+func (cc *Consensus) SubscribeLeader(ctx context.Context) <-chan peer.ID {
+	return cc.raft.SubscribeLeader(ctx)
+}
+
+// SubscribeEvents returns a channel which receives an event for every pin
+// and unpin applied to the state and for every peer added to or removed
+// from Raft. The channel is closed when the context is cancelled or the
+// component shuts down.
+func (cc *Consensus) SubscribeEvents(ctx context.Context) <-chan api.ConsensusEvent {
+	return cc.raft.events.Subscribe(ctx)
 }
 
 // Clean removes the Raft persisted state.
@@ -505,14 +841,144 @@ func (cc *Consensus) Clean(ctx context.Context) error {
 	return CleanupRaft(cc.config)
 }
 
-// Rollback replaces the current agreed-upon
-// state with the state provided. Only the consensus leader
-// can perform this operation.
-func (cc *Consensus) Rollback(state state.State) error {
-	// This is unused. It *might* be used for upgrades.
-	// There is rather untested magic in libp2p-raft's FSM()
-	// to make this possible.
-	return cc.consensus.Rollback(state)
+// Rollback replaces the current agreed-upon pinset with the one provided.
+// The current state is first exported to an audit snapshot in the Raft
+// data folder. The pins that are not part of the new pinset are then
+// unpinned and the new pinset is pinned, committing rollbackBatchSize
+// operations per log entry so that large pinsets do not make huge
+// entries. Every peer applies them as regular operations and reconciles
+// its pin tracker. The rollback is not atomic: operations made meanwhile
+// are interleaved with it, and it stops at the first batch which fails to
+// commit. Only the consensus leader can perform this operation.
+func (cc *Consensus) Rollback(ctx context.Context, pins []api.Pin) (api.RollbackInfo, error) {
+	ctx, span := trace.StartSpan(ctx, "consensus/Rollback")
+	defer span.End()
+
+	info := api.RollbackInfo{
+		Peer:      cc.host.ID(),
+		Pins:      len(pins),
+		Timestamp: time.Now().UTC(),
+	}
+
+	leader, err := cc.Leader(ctx)
+	if err != nil {
+		return info, err
+	}
+	if leader != cc.host.ID() {
+		return info, fmt.Errorf("%w: rollbacks must be performed by the leader (%s)", ErrNotLeader, leader)
+	}
+
+	info.AuditSnapshot, err = cc.writeRollbackAudit(ctx, info.Timestamp)
+	if err != nil {
+		return info, fmt.Errorf("error writing rollback audit snapshot: %w", err)
+	}
+	logger.Warnf("rolling back the state to %d pins. Audit snapshot: %s", len(pins), info.AuditSnapshot)
+
+	ops, err := cc.rollbackOps(ctx, pins)
+	if err != nil {
+		return info, err
+	}
+	for i := 0; i < len(ops); i += rollbackBatchSize {
+		end := i + rollbackBatchSize
+		if end > len(ops) {
+			end = len(ops)
+		}
+		op := &LogOp{
+			Type:    LogOpBatch,
+			Batch:   ops[i:end],
+			Origin:  cc.origin(ctx),
+			Version: logOpVersion,
+		}
+		err = classifyCommitError(cc.commitOp(ctx, op))
+		if err != nil {
+			logger.Errorf("error committing rollback after %d of %d operations: %s", i, len(ops), err)
+			return info, fmt.Errorf("rollback interrupted after %d of %d operations: %w", i, len(ops), err)
+		}
+	}
+	logger.Warn("rollback committed to global state")
+	return info, nil
+}
+
+// rollbackOps returns the operations that replace the current pinset with
+// the given one: unpins for the pins that are not part of it, followed by
+// pins for all of its items.
+func (cc *Consensus) rollbackOps(ctx context.Context, pins []api.Pin) ([]LogOp, error) {
+	st, err := cc.State(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := make(map[api.Cid]struct{}, len(pins))
+	for _, pin := range pins {
+		keep[pin.Cid] = struct{}{}
+	}
+
+	out := make(chan api.Pin, 1024)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- st.List(ctx, out)
+	}()
+	var ops []LogOp
+	for pin := range out {
+		if _, ok := keep[pin.Cid]; !ok {
+			ops = append(ops, LogOp{
+				Cid:  pin,
+				Type: LogOpUnpin,
+			})
+		}
+	}
+	if err := <-errCh; err != nil {
+		return nil, err
+	}
+
+	for _, pin := range pins {
+		ops = append(ops, LogOp{
+			Cid:  pin,
+			Type: LogOpPin,
+		})
+	}
+	return ops, nil
+}
+
+// writeRollbackAudit exports the current state, in the same format as
+// "ipfs-cluster-service state export", to a file in the Raft data folder.
+func (cc *Consensus) writeRollbackAudit(ctx context.Context, t time.Time) (string, error) {
+	st, err := cc.State(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(
+		cc.config.GetDataFolder(),
+		fmt.Sprintf("rollback-audit-%s.json", t.Format("20060102T150405Z")),
+	)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	out := make(chan api.Pin, 1024)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- st.List(ctx, out)
+	}()
+	enc := json.NewEncoder(f)
+	for pin := range out {
+		if err == nil {
+			err = enc.Encode(pin)
+		}
+	}
+	if listErr := <-errCh; err == nil {
+		err = listErr
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		return "", err
+	}
+	return path, nil
 }
 
 // Peers return the current list of peers in the consensus.
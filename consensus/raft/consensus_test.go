@@ -2,16 +2,21 @@ package raft
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/ipfs-cluster/ipfs-cluster/api"
 	"github.com/ipfs-cluster/ipfs-cluster/datastore/inmem"
+	"github.com/ipfs-cluster/ipfs-cluster/state"
 	"github.com/ipfs-cluster/ipfs-cluster/state/dsstate"
 	"github.com/ipfs-cluster/ipfs-cluster/test"
 
+	hraft "github.com/hashicorp/raft"
 	libp2p "github.com/libp2p/go-libp2p"
 	host "github.com/libp2p/go-libp2p/core/host"
 	peerstore "github.com/libp2p/go-libp2p/core/peerstore"
@@ -39,12 +44,16 @@ func makeTestingHost(t *testing.T) host.Host {
 }
 
 func testingConsensus(t *testing.T, idn int) *Consensus {
+	cfg := &Config{}
+	cfg.Default()
+	return testingConsensusWithCfg(t, idn, cfg)
+}
+
+func testingConsensusWithCfg(t *testing.T, idn int, cfg *Config) *Consensus {
 	ctx := context.Background()
 	cleanRaft(idn)
 	h := makeTestingHost(t)
 
-	cfg := &Config{}
-	cfg.Default()
 	cfg.DataFolder = fmt.Sprintf("raftFolderFromTests-%d", idn)
 	cfg.hostShutdown = true
 
@@ -126,6 +135,368 @@ func TestConsensusUnpin(t *testing.T) {
 	}
 }
 
+func TestConsensusSchedule(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	defer cleanRaft(1)
+	defer cc.Shutdown(ctx)
+
+	s := api.PinSchedule{
+		ID:      "schedule",
+		Path:    test.PathIPFS2,
+		Cron:    "0 * * * *",
+		Next:    time.Now().Truncate(time.Second),
+		LastCid: test.Cid1,
+	}
+	err := cc.LogSchedule(ctx, s)
+	if err != nil {
+		t.Fatal("the operation did not make it to the log:", err)
+	}
+
+	st, err := cc.State(ctx)
+	if err != nil {
+		t.Fatal("error getting state:", err)
+	}
+	get, err := st.GetSchedule(ctx, s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if get.Path != s.Path || !get.Next.Equal(s.Next) || !get.LastCid.Equals(test.Cid1) {
+		t.Errorf("unexpected schedule: %+v", get)
+	}
+
+	err = cc.LogUnschedule(ctx, s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = st.GetSchedule(ctx, s.ID)
+	if err != state.ErrScheduleNotFound {
+		t.Errorf("expected ErrScheduleNotFound: %s", err)
+	}
+}
+
+func TestConsensusPinIdempotent(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	defer cleanRaft(1)
+	defer cc.Shutdown(ctx)
+
+	pin := testPin(test.Cid1)
+	err := cc.LogPin(ctx, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := cc.raft.r().LastIndex()
+
+	// retrying client
+	err = cc.LogPin(ctx, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i := cc.raft.r().LastIndex(); i != index {
+		t.Errorf("repeated pin should not be committed (index %d -> %d)", index, i)
+	}
+
+	pin.Name = "changed"
+	err = cc.LogPin(ctx, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i := cc.raft.r().LastIndex(); i == index {
+		t.Error("modified pin should have been committed")
+	}
+
+	err = cc.LogUnpin(ctx, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index = cc.raft.r().LastIndex()
+	err = cc.LogUnpin(ctx, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i := cc.raft.r().LastIndex(); i != index {
+		t.Errorf("repeated unpin should not be committed (index %d -> %d)", index, i)
+	}
+}
+
+func TestInflightOps(t *testing.T) {
+	ctx := context.Background()
+	ops := newInflightOps()
+	op := &LogOp{Cid: testPin(test.Cid1), Type: LogOpPin}
+
+	f, inflight := ops.start(op)
+	if inflight {
+		t.Fatal("first operation should not be in flight")
+	}
+
+	dup, inflight := ops.start(&LogOp{Cid: testPin(test.Cid1), Type: LogOpPin})
+	if !inflight || dup != f {
+		t.Fatal("identical operation should wait for the first one")
+	}
+
+	other := testPin(test.Cid1)
+	other.Name = "other"
+	_, inflight = ops.start(&LogOp{Cid: other, Type: LogOpPin})
+	if inflight {
+		t.Error("a different pin should not be deduplicated")
+	}
+
+	_, inflight = ops.start(&LogOp{Cid: testPin(test.Cid1), Type: LogOpUnpin})
+	if inflight {
+		t.Error("an unpin should not be deduplicated with a pin")
+	}
+
+	ops.finish(f, ErrCommitRejected)
+	if err := dup.wait(ctx); err != ErrCommitRejected {
+		t.Errorf("waiters should get the result of the commit: %v", err)
+	}
+}
+
+func TestConsensusRollback(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	defer cleanRaft(1)
+	defer cc.Shutdown(ctx)
+
+	for _, c := range []api.Cid{test.Cid1, test.Cid2} {
+		err := cc.LogPin(ctx, testPin(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The rollback needs 3 operations, committed in 2 log entries.
+	oldBatchSize := rollbackBatchSize
+	defer func() { rollbackBatchSize = oldBatchSize }()
+	rollbackBatchSize = 2
+
+	info, err := cc.Rollback(ctx, []api.Pin{testPin(test.Cid2), testPin(test.Cid3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Pins != 2 || info.Peer != cc.host.ID() {
+		t.Errorf("unexpected rollback info: %+v", info)
+	}
+
+	audit, err := os.ReadFile(info.AuditSnapshot)
+	if err != nil {
+		t.Fatal("cannot read audit snapshot:", err)
+	}
+	if !strings.Contains(string(audit), test.Cid1.String()) ||
+		!strings.Contains(string(audit), test.Cid2.String()) {
+		t.Error("the audit snapshot should contain the previous state")
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	st, err := cc.State(ctx)
+	if err != nil {
+		t.Fatal("error getting state:", err)
+	}
+	if ok, _ := st.Has(ctx, test.Cid1); ok {
+		t.Error("Cid1 should have been removed")
+	}
+	for _, c := range []api.Cid{test.Cid2, test.Cid3} {
+		if ok, _ := st.Has(ctx, c); !ok {
+			t.Errorf("%s should be in the state", c)
+		}
+	}
+}
+
+func TestConsensusLogEntries(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	defer cleanRaft(1)
+	defer cc.Shutdown(ctx)
+
+	for _, c := range []api.Cid{test.Cid1, test.Cid2, test.Cid3} {
+		err := cc.LogPin(ctx, testPin(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := cc.LogUnpin(ctx, testPin(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := cc.LogEntries(ctx, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Type != "unpin" || !entries[0].Cid.Equals(test.Cid1) {
+		t.Errorf("unexpected latest entry: %+v", entries[0])
+	}
+	if entries[0].Origin != cc.host.ID() || entries[0].Timestamp.IsZero() {
+		t.Errorf("expected origin and timestamp: %+v", entries[0])
+	}
+	if entries[1].Type != "pin" || !entries[1].Cid.Equals(test.Cid3) {
+		t.Errorf("unexpected entry: %+v", entries[1])
+	}
+
+	// next page
+	entries, err = cc.LogEntries(ctx, entries[1].Index, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || !entries[0].Cid.Equals(test.Cid2) || !entries[1].Cid.Equals(test.Cid1) {
+		t.Errorf("unexpected second page: %+v", entries)
+	}
+}
+
+func TestConsensusDiskUsage(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.Default()
+	cfg.DiskUsageCheckInterval = 100 * time.Millisecond
+	cfg.MaxDiskUsage = 1
+	cc := testingConsensusWithCfg(t, 1, cfg)
+	defer cleanRaft(1)
+	defer cc.Shutdown(ctx)
+
+	err := cc.LogPin(ctx, testPin(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	usage, err := diskUsage(cfg.GetDataFolder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage == 0 {
+		t.Error("expected some disk usage")
+	}
+
+	time.Sleep(time.Second)
+	snaps, err := cc.raft.snapshotStore.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) == 0 {
+		t.Fatal("expected a snapshot to be taken when over max_disk_usage")
+	}
+	first := cc.raft.lastSnapshotIndex()
+
+	// Usage stays over the limit. New entries are snapshotted again.
+	err = cc.LogPin(ctx, testPin(test.Cid2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Second)
+	if last := cc.raft.lastSnapshotIndex(); last <= first {
+		t.Errorf("expected a new snapshot including the new entries (%d <= %d)", last, first)
+	}
+}
+
+func TestStallWatch(t *testing.T) {
+	var w stallWatch
+	now := time.Now()
+	timeout := time.Minute
+
+	if w.update(10, 12, true, now, timeout) {
+		t.Error("a stall should not be reported right away")
+	}
+	if w.update(10, 12, true, now.Add(30*time.Second), timeout) {
+		t.Error("a stall should not be reported before the timeout")
+	}
+	if !w.update(10, 13, true, now.Add(2*time.Minute), timeout) {
+		t.Error("expected a stall")
+	}
+	if w.update(10, 13, true, now.Add(3*time.Minute), timeout) {
+		t.Error("a stall should be reported once")
+	}
+
+	// progress resets the watch
+	if w.update(11, 13, true, now.Add(4*time.Minute), timeout) {
+		t.Error("no stall expected after progress")
+	}
+	if w.update(11, 13, true, now.Add(4*time.Minute+30*time.Second), timeout) {
+		t.Error("a stall should not be reported before the timeout")
+	}
+
+	// nothing to apply or no leader is not a stall
+	w = stallWatch{}
+	w.update(13, 13, true, now, timeout)
+	if w.update(13, 13, true, now.Add(2*time.Minute), timeout) {
+		t.Error("no stall expected when everything is applied")
+	}
+	w = stallWatch{}
+	w.update(10, 13, false, now, timeout)
+	if w.update(10, 13, false, now.Add(2*time.Minute), timeout) {
+		t.Error("no stall expected without a leader")
+	}
+}
+
+func TestConsensusRestartRaft(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	defer cleanRaft(1)
+	defer cc.Shutdown(ctx)
+
+	err := cc.LogPin(ctx, testPin(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := cc.writeStallDiagnostics(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	diag, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(diag), "applied_index") || !strings.Contains(string(diag), "goroutine") {
+		t.Error("the diagnostics should contain the Raft stats and the goroutines")
+	}
+
+	// Raft is used by others while it restarts.
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			cc.Leader(ctx)
+			cc.Stats(ctx)
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+	err = cc.restartRaft()
+	close(stop)
+	wg.Wait()
+	if err != nil {
+		t.Fatal("raft should have restarted:", err)
+	}
+	_, err = cc.WaitForLeader(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cc.LogPin(ctx, testPin(test.Cid2))
+	if err != nil {
+		t.Fatal("the operation did not make it to the log after restarting:", err)
+	}
+	time.Sleep(250 * time.Millisecond)
+	st, err := cc.State(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []api.Cid{test.Cid1, test.Cid2} {
+		if ok, _ := st.Has(ctx, c); !ok {
+			t.Errorf("%s should be in the state after restarting", c)
+		}
+	}
+}
+
 func TestConsensusUpdate(t *testing.T) {
 	ctx := context.Background()
 	cc := testingConsensus(t, 1)
@@ -173,6 +544,200 @@ func TestConsensusUpdate(t *testing.T) {
 	}
 }
 
+func TestBatchingQueuePolicy(t *testing.T) {
+	ctx := context.Background()
+	newItem := func(c api.Cid) batchItem {
+		return batchItem{
+			ctx:       ctx,
+			op:        &LogOp{Cid: testPin(c), Type: LogOpPin},
+			committed: make(chan error, 1),
+			queued:    time.Now(),
+		}
+	}
+	// No batchWorker is running, so the queue fills up.
+	newConsensus := func(policy string) *Consensus {
+		cfg := &Config{}
+		cfg.Default()
+		cfg.Batching.QueuePolicy = policy
+		return &Consensus{
+			ctx:         ctx,
+			config:      cfg,
+			batchItemCh: make(chan batchItem, 1),
+		}
+	}
+
+	t.Run("error", func(t *testing.T) {
+		cc := newConsensus(QueuePolicyError)
+		if err := cc.enqueueBatchItem(ctx, newItem(test.Cid1)); err != nil {
+			t.Fatal(err)
+		}
+		err := cc.enqueueBatchItem(ctx, newItem(test.Cid2))
+		if !errors.Is(err, ErrQueueFull) {
+			t.Fatal("expected ErrQueueFull:", err)
+		}
+	})
+
+	t.Run("shed", func(t *testing.T) {
+		cc := newConsensus(QueuePolicyShed)
+		first := newItem(test.Cid1)
+		if err := cc.enqueueBatchItem(ctx, first); err != nil {
+			t.Fatal(err)
+		}
+		if err := cc.enqueueBatchItem(ctx, newItem(test.Cid2)); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-first.committed; !errors.Is(err, ErrQueueFull) {
+			t.Error("the oldest item should have been shed:", err)
+		}
+		if bi := <-cc.batchItemCh; !bi.op.Cid.Cid.Equals(test.Cid2) {
+			t.Error("the newest item should be queued")
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		cc := newConsensus(QueuePolicyBlock)
+		if err := cc.enqueueBatchItem(ctx, newItem(test.Cid1)); err != nil {
+			t.Fatal(err)
+		}
+		tctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		err := cc.enqueueBatchItem(tctx, newItem(test.Cid2))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatal("expected to block until the context expired:", err)
+		}
+	})
+}
+
+func TestConsensusBatching(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{}
+	cfg.Default()
+	cfg.Batching.MaxBatchSize = 3
+	cfg.Batching.MaxBatchAge = 500 * time.Millisecond
+
+	cc := testingConsensusWithCfg(t, 1, cfg)
+	defer cleanRaft(1)
+	defer cc.Shutdown(ctx)
+
+	// A full batch is committed right away.
+	cids := []api.Cid{test.Cid1, test.Cid2, test.Cid3}
+	errs := make(chan error, len(cids))
+	start := time.Now()
+	for _, c := range cids {
+		go func(c api.Cid) {
+			errs <- cc.LogPin(ctx, testPin(c))
+		}(c)
+	}
+	for range cids {
+		if err := <-errs; err != nil {
+			t.Error("the operation did not make it to the log:", err)
+		}
+	}
+	if time.Since(start) >= cfg.Batching.MaxBatchAge {
+		t.Error("a full batch should be committed without waiting")
+	}
+
+	// A single operation is committed after max age.
+	start = time.Now()
+	err := cc.LogUnpin(ctx, testPin(test.Cid1))
+	if err != nil {
+		t.Error("the operation did not make it to the log:", err)
+	}
+	if time.Since(start) < cfg.Batching.MaxBatchAge {
+		t.Error("the operation should have waited for the batch")
+	}
+
+	// Cancelled operations are not committed.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = cc.LogPin(cctx, testPin(test.Cid4))
+	if err == nil {
+		t.Error("expected an error with a cancelled context")
+	}
+
+	time.Sleep(cfg.Batching.MaxBatchAge)
+	st, err := cc.State(ctx)
+	if err != nil {
+		t.Fatal("error getting state:", err)
+	}
+
+	out := make(chan api.Pin, 10)
+	err = st.List(ctx, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pins []api.Pin
+	for p := range out {
+		pins = append(pins, p)
+	}
+
+	if len(pins) != 2 {
+		t.Fatal("there should be 2 pins in the state")
+	}
+	for _, p := range pins {
+		if p.Cid.Equals(test.Cid1) || p.Cid.Equals(test.Cid4) {
+			t.Error("unexpected pin in the state:", p.Cid)
+		}
+	}
+}
+
+func TestConsensusCatchUpProgress(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	defer cleanRaft(1)
+	defer cc.Shutdown(ctx)
+
+	// Ready means WaitForSync has finished.
+	p := cc.CatchUpProgress(ctx)
+	if p.Syncing {
+		t.Error("should not be syncing once ready")
+	}
+	if p.AppliedIndex != p.LastIndex || p.Started.IsZero() {
+		t.Errorf("unexpected progress: %+v", p)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	err := cc.raft.WaitForUpdates(cctx)
+	if err != nil {
+		t.Error("an up to date state should not wait:", err)
+	}
+}
+
+func TestConsensusStats(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	defer cleanRaft(1)
+	defer cc.Shutdown(ctx)
+
+	err := cc.LogPin(ctx, testPin(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cc.raft.r().Snapshot().Error()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := cc.Stats(ctx)
+	if st.Role != api.ConsensusRoleLeader {
+		t.Error("single peer should be the leader:", st.Role)
+	}
+	if st.Term == 0 || st.AppliedIndex == 0 {
+		t.Errorf("unexpected term or applied index: %+v", st)
+	}
+	if st.Snapshots != 1 || st.LastSnapshot.IsZero() {
+		t.Errorf("expected one snapshot: %+v", st)
+	}
+	if st.StorageSize == 0 {
+		t.Error("the Raft data folder should not be empty")
+	}
+	if st.SyncLag != 0 {
+		t.Error("a single peer should have applied every entry:", st.SyncLag)
+	}
+}
+
 func TestConsensusAddPeer(t *testing.T) {
 	ctx := context.Background()
 	cc := testingConsensus(t, 1)
@@ -207,6 +772,119 @@ func TestConsensusAddPeer(t *testing.T) {
 	}
 }
 
+func TestConsensusShutdownTransfersLeadership(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	cc2 := testingConsensus(t, 2)
+	defer cleanRaft(1)
+	defer cleanRaft(2)
+	defer cc2.Shutdown(ctx)
+
+	cc.host.Peerstore().AddAddrs(cc2.host.ID(), cc2.host.Addrs(), peerstore.PermanentAddrTTL)
+	err := cc.AddPeer(ctx, cc2.host.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	err = cc2.raft.WaitForPeer(ctx, cc.host.ID().Pretty(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leader, err := cc2.WaitForLeader(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leader != cc.host.ID() {
+		t.Fatal("the first peer should be the leader")
+	}
+
+	// Record whether the second peer becomes leader. It may step
+	// down afterwards as it loses quorum.
+	becameLeader := make(chan struct{})
+	go func() {
+		for l := range cc2.SubscribeLeader(ctx) {
+			if l == cc2.host.ID() {
+				close(becameLeader)
+				return
+			}
+		}
+	}()
+
+	err = cc.Shutdown(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-becameLeader:
+	case <-ctx.Done():
+		t.Fatal("leadership was not transferred on shutdown")
+	}
+}
+
+func TestConsensusAddNonVoter(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	defer cleanRaft(1)
+	defer cc.Shutdown(ctx)
+
+	cleanRaft(2)
+	defer cleanRaft(2)
+	h := makeTestingHost(t)
+	cfg := &Config{}
+	cfg.Default()
+	cfg.DataFolder = "raftFolderFromTests-2"
+	cfg.hostShutdown = true
+	cfg.NonVoter = true
+	cc2, err := NewConsensus(h, cfg, inmem.New(), true)
+	if err != nil {
+		t.Fatal("cannot create Consensus:", err)
+	}
+	defer cc2.Shutdown(ctx)
+	cc2.SetClient(test.NewMockRPCClientWithHost(t, h))
+
+	cc.host.Peerstore().AddAddrs(h.ID(), h.Addrs(), peerstore.PermanentAddrTTL)
+	err = cc.raft.AddPeer(ctx, h.ID().String(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+	select {
+	case <-cc2.Ready(timeout):
+	case <-timeout.Done():
+		t.Fatal("non-voter did not become ready")
+	}
+
+	future := cc.raft.r().GetConfiguration()
+	if err := future.Error(); err != nil {
+		t.Fatal(err)
+	}
+	for _, srv := range future.Configuration().Servers {
+		if srv.ID == hraft.ServerID(h.ID().String()) && srv.Suffrage != hraft.Nonvoter {
+			t.Error("peer should have been added as non-voter")
+		}
+	}
+	if isVoter(hraft.ServerID(h.ID().String()), future.Configuration()) {
+		t.Error("non-voter should not be a voter")
+	}
+
+	if l, _ := cc2.Leader(ctx); l != cc.host.ID() {
+		t.Errorf("expected %s to be the leader: %s", cc.host.ID(), l)
+	}
+
+	pin := testPin(test.Cid1)
+	if err := cc2.LogPin(ctx, pin); !errors.Is(err, ErrNonVoter) {
+		t.Errorf("non-voters should not pin: %v", err)
+	}
+	if err := cc2.LogUnpin(ctx, pin); !errors.Is(err, api.ErrRejected) {
+		t.Errorf("non-voters should not unpin: %v", err)
+	}
+}
+
 func TestConsensusRmPeer(t *testing.T) {
 	ctx := context.Background()
 	cc := testingConsensus(t, 1)
@@ -257,6 +935,135 @@ func TestConsensusRmPeer(t *testing.T) {
 	}
 }
 
+func TestConsensusCommitDeadline(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	defer cleanRaft(1)
+	defer cc.Shutdown(ctx)
+
+	dctx, cancel := context.WithDeadline(ctx, time.Now().Add(-time.Second))
+	defer cancel()
+	err := cc.LogPin(dctx, testPin(test.Cid1))
+	if !errors.Is(err, ErrCommitTimeout) {
+		t.Error("expected a commit timeout error:", err)
+	}
+}
+
+func TestConsensusQuorum(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	cc2 := testingConsensus(t, 2)
+	defer cleanRaft(1)
+	defer cleanRaft(2)
+	defer cc.Shutdown(ctx)
+
+	cc.host.Peerstore().AddAddrs(cc2.host.ID(), cc2.host.Addrs(), peerstore.PermanentAddrTTL)
+	err := cc.AddPeer(ctx, cc2.host.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := cc.Quorum(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.HasQuorum || status.Quorum != 2 || len(status.ReachableVoters) != 2 {
+		t.Fatalf("unexpected quorum status: %+v", status)
+	}
+
+	err = cc.LogPin(ctx, testPin(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, _ = cc.Quorum(ctx)
+	if status.CommitLatency <= 0 {
+		t.Error("the commit latency should have been recorded")
+	}
+
+	cc2.Shutdown(ctx)
+	for i := 0; status.HasQuorum; i++ {
+		if i > 50 {
+			t.Fatal("quorum should have been lost")
+		}
+		time.Sleep(100 * time.Millisecond)
+		status, _ = cc.Quorum(ctx)
+	}
+
+	start := time.Now()
+	err = cc.LogPin(ctx, testPin(test.Cid2))
+	if !errors.Is(err, ErrNoQuorum) {
+		t.Fatal("expected a quorum error:", err)
+	}
+	if time.Since(start) >= cc.config.WaitForLeaderTimeout {
+		t.Error("commits without quorum should fail fast")
+	}
+}
+
+func TestConsensusEvents(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	cc2 := testingConsensus(t, 2)
+	defer cleanRaft(1)
+	defer cleanRaft(2)
+	defer cc.Shutdown(ctx)
+	defer cc2.Shutdown(ctx)
+
+	subCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	sub := cc.SubscribeEvents(subCtx)
+
+	err := cc.LogPin(ctx, testPin(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := <-sub
+	if ev.Type != api.ConsensusEventPin || !ev.Pin.Cid.Equals(test.Cid1) {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+
+	cc.host.Peerstore().AddAddrs(cc2.host.ID(), cc2.host.Addrs(), peerstore.PermanentAddrTTL)
+	err = cc.AddPeer(ctx, cc2.host.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev = <-sub
+	if ev.Type != api.ConsensusEventPeerAdd || ev.Peer != cc2.host.ID() {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+
+	cc.Shutdown(ctx)
+	if _, ok := <-sub; ok {
+		t.Error("the subscription should be closed on shutdown")
+	}
+}
+
+func TestClassifyCommitError(t *testing.T) {
+	testcases := []struct {
+		err      error
+		expected error
+	}{
+		{context.DeadlineExceeded, ErrCommitTimeout},
+		{hraft.ErrEnqueueTimeout, ErrCommitTimeout},
+		{hraft.ErrLeadershipLost, ErrNotLeader},
+		{errors.New(errActorNotLeader), ErrNotLeader},
+		{fmt.Errorf("rpc: %s: bad", ErrNotLeader), ErrNotLeader},
+		{fmt.Errorf("rpc: %s: 1 of 2 voters reachable", ErrNoQuorum), ErrNoQuorum},
+		{errors.New("a rollback may be necessary"), ErrCommitRejected},
+		{context.Canceled, context.Canceled},
+	}
+
+	for _, tc := range testcases {
+		err := classifyCommitError(tc.err)
+		if !errors.Is(err, tc.expected) {
+			t.Errorf("%s: expected %s, got %s", tc.err, tc.expected, err)
+		}
+	}
+
+	if classifyCommitError(nil) != nil {
+		t.Error("nil errors should stay nil")
+	}
+}
+
 func TestConsensusLeader(t *testing.T) {
 	ctx := context.Background()
 	cc := testingConsensus(t, 1)
@@ -273,6 +1080,76 @@ func TestConsensusLeader(t *testing.T) {
 	}
 }
 
+func TestConsensusWaitForLeader(t *testing.T) {
+	ctx := context.Background()
+	cleanRaft(1)
+	defer cleanRaft(1)
+	h := makeTestingHost(t)
+	cfg := &Config{}
+	cfg.Default()
+	cfg.DataFolder = "raftFolderFromTests-1"
+	cfg.hostShutdown = true
+
+	cc, err := NewConsensus(h, cfg, inmem.New(), false)
+	if err != nil {
+		t.Fatal("cannot create Consensus:", err)
+	}
+	defer cc.Shutdown(ctx)
+	cc.SetClient(test.NewMockRPCClientWithHost(t, h))
+
+	subCtx, subCancel := context.WithCancel(ctx)
+	sub := cc.SubscribeLeader(subCtx)
+
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+	l, err := cc.WaitForLeader(waitCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l != h.ID() {
+		t.Errorf("expected %s but the leader appears as %s", h.ID(), l)
+	}
+
+	subCancel()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-sub:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("subscription should have been closed")
+		}
+	}
+}
+
+func TestConsensusWaitLeaderChange(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	defer cleanRaft(1)
+	defer cc.Shutdown(ctx)
+
+	// No change: returns after the heartbeat timeouts.
+	err := cc.waitLeaderChange(ctx, cc.host.ID())
+	if err != nil {
+		t.Error(err)
+	}
+
+	// A different leader is already known.
+	err = cc.waitLeaderChange(ctx, test.PeerID1)
+	if err != nil {
+		t.Error(err)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = cc.waitLeaderChange(cctx, cc.host.ID())
+	if err == nil {
+		t.Error("expected an error with a cancelled context")
+	}
+}
+
 func TestRaftLatestSnapshot(t *testing.T) {
 	ctx := context.Background()
 	cc := testingConsensus(t, 1)
@@ -0,0 +1,196 @@
+package raft
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/kvstore"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	"go.opencensus.io/trace"
+)
+
+// ErrDeadLetterNotFound is returned when retrying or discarding a dead
+// letter that does not exist.
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// deadLetters keeps the operations that could not be applied to the state,
+// so that they can be inspected and retried. Raft keeps the state in memory,
+// so dead letters are persisted in the scratch store of the peer once it is
+// set (see setStore()).
+type deadLetters struct {
+	mu      sync.Mutex
+	store   *kvstore.Store
+	letters map[string]api.DeadLetter
+	// letters removed before the store was set, which must not be
+	// loaded from it.
+	removed map[string]struct{}
+}
+
+func newDeadLetters() *deadLetters {
+	return &deadLetters{
+		letters: make(map[string]api.DeadLetter),
+		removed: make(map[string]struct{}),
+	}
+}
+
+// setStore loads the dead letters persisted in the given store and
+// persists them to it from now on. The letters of the operations failed or
+// applied since the peer started, while replaying the log, take precedence.
+func (dl *deadLetters) setStore(ctx context.Context, store *kvstore.Store) error {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+
+	dl.store = store
+	var stale []string
+	err := store.Iterate(ctx, func(key string, value []byte) error {
+		if _, ok := dl.removed[key]; ok {
+			stale = append(stale, key)
+			return nil
+		}
+		if _, ok := dl.letters[key]; ok {
+			return nil
+		}
+		var letter api.DeadLetter
+		if err := json.Unmarshal(value, &letter); err != nil {
+			return err
+		}
+		dl.letters[letter.ID] = letter
+		return nil
+	})
+	dl.removed = nil
+	if err != nil {
+		return fmt.Errorf("error loading dead letters: %w", err)
+	}
+	for _, id := range stale {
+		if err := store.Delete(ctx, id); err != nil {
+			return fmt.Errorf("error deleting dead letter %s: %w", id, err)
+		}
+	}
+	for id, letter := range dl.letters {
+		if err := store.Put(ctx, id, letter); err != nil {
+			return fmt.Errorf("error persisting dead letter %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// deadLetterID identifies failed operations by type and cid, so that an
+// operation which fails repeatedly (i.e. when replaying the log) is only
+// kept once.
+func deadLetterID(t LogOpType, c api.Cid) string {
+	return t.String() + "-" + c.String()
+}
+
+// add records a failed operation.
+func (dl *deadLetters) add(ctx context.Context, t LogOpType, pin api.Pin, origin peer.ID, opErr error) {
+	letter := api.DeadLetter{
+		ID:        deadLetterID(t, pin.Cid),
+		Type:      t.String(),
+		Pin:       pin,
+		Origin:    origin,
+		Error:     opErr.Error(),
+		Timestamp: time.Now(),
+	}
+
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	dl.letters[letter.ID] = letter
+	if dl.store == nil {
+		delete(dl.removed, letter.ID)
+		return
+	}
+	if err := dl.store.Put(ctx, letter.ID, letter); err != nil {
+		logger.Errorf("error persisting dead letter %s: %s", letter.ID, err)
+	}
+}
+
+// remove forgets a dead letter. It returns false if it did not exist.
+func (dl *deadLetters) remove(ctx context.Context, id string) bool {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	if dl.store == nil {
+		dl.removed[id] = struct{}{}
+	}
+	if _, ok := dl.letters[id]; !ok {
+		return false
+	}
+	delete(dl.letters, id)
+	if dl.store == nil {
+		return true
+	}
+	if err := dl.store.Delete(ctx, id); err != nil {
+		logger.Errorf("error deleting dead letter %s: %s", id, err)
+	}
+	return true
+}
+
+func (dl *deadLetters) get(id string) (api.DeadLetter, bool) {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	letter, ok := dl.letters[id]
+	return letter, ok
+}
+
+// list returns the dead letters, oldest first.
+func (dl *deadLetters) list() []api.DeadLetter {
+	dl.mu.Lock()
+	letters := make([]api.DeadLetter, 0, len(dl.letters))
+	for _, letter := range dl.letters {
+		letters = append(letters, letter)
+	}
+	dl.mu.Unlock()
+
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].Timestamp.Before(letters[j].Timestamp)
+	})
+	return letters
+}
+
+// DeadLetters returns the operations which this peer failed to apply to
+// the shared state, oldest first.
+func (cc *Consensus) DeadLetters(ctx context.Context) ([]api.DeadLetter, error) {
+	return cc.deadLetters.list(), nil
+}
+
+// RetryDeadLetter commits the given failed operation again. The dead
+// letter is removed once it has been applied.
+func (cc *Consensus) RetryDeadLetter(ctx context.Context, id string) error {
+	ctx, span := trace.StartSpan(ctx, "consensus/RetryDeadLetter")
+	defer span.End()
+
+	letter, ok := cc.deadLetters.get(id)
+	if !ok {
+		return ErrDeadLetterNotFound
+	}
+
+	var err error
+	switch letter.Type {
+	case LogOpType(LogOpPin).String():
+		err = cc.LogPin(ctx, letter.Pin)
+	case LogOpType(LogOpUnpin).String():
+		err = cc.LogUnpin(ctx, letter.Pin)
+	default:
+		err = fmt.Errorf("cannot retry operation of type %s", letter.Type)
+	}
+	if err != nil {
+		return err
+	}
+	cc.deadLetters.remove(ctx, id)
+	return nil
+}
+
+// DiscardDeadLetter forgets the given failed operation.
+func (cc *Consensus) DiscardDeadLetter(ctx context.Context, id string) error {
+	if !cc.deadLetters.remove(ctx, id) {
+		return ErrDeadLetterNotFound
+	}
+	logger.Infof("dead letter %s discarded", id)
+	return nil
+}
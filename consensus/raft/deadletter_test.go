@@ -0,0 +1,120 @@
+package raft
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/datastore/inmem"
+	"github.com/ipfs-cluster/ipfs-cluster/kvstore"
+	"github.com/ipfs-cluster/ipfs-cluster/state"
+	"github.com/ipfs-cluster/ipfs-cluster/state/dsstate"
+	"github.com/ipfs-cluster/ipfs-cluster/test"
+)
+
+type failingState struct {
+	state.State
+}
+
+func (st *failingState) Add(ctx context.Context, pin api.Pin) error {
+	return errors.New("cannot add")
+}
+
+func TestDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	defer cleanRaft(1)
+	defer cc.Shutdown(ctx)
+
+	st, err := dsstate.New(ctx, inmem.New(), "", dsstate.DefaultHandle())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	op := &LogOp{
+		Cid:       testPin(test.Cid1),
+		Type:      LogOpPin,
+		Origin:    test.PeerID2,
+		consensus: cc,
+	}
+	// ApplyTo resets the operation, as it does between log entries.
+	again := *op
+	_, err = op.ApplyTo(&failingState{st})
+	if err == nil {
+		t.Fatal("expected an error applying the operation")
+	}
+
+	letters, err := cc.DeadLetters(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(letters) != 1 {
+		t.Fatalf("expected one dead letter: %+v", letters)
+	}
+	letter := letters[0]
+	if letter.Type != "pin" || !letter.Pin.Cid.Equals(test.Cid1) || letter.Origin != test.PeerID2 || letter.Error == "" {
+		t.Errorf("unexpected dead letter: %+v", letter)
+	}
+
+	err = cc.RetryDeadLetter(ctx, letter.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	letters, _ = cc.DeadLetters(ctx)
+	if len(letters) != 0 {
+		t.Errorf("dead letter should have been removed after retrying: %+v", letters)
+	}
+	cst, _ := cc.State(ctx)
+	if ok, _ := cst.Has(ctx, test.Cid1); !ok {
+		t.Error("the retried pin should be in the state")
+	}
+
+	_, _ = again.ApplyTo(&failingState{st})
+	err = cc.DiscardDeadLetter(ctx, letter.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cc.DiscardDeadLetter(ctx, letter.ID)
+	if !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("expected ErrDeadLetterNotFound: %s", err)
+	}
+}
+
+func TestDeadLettersPersisted(t *testing.T) {
+	ctx := context.Background()
+	store := kvstore.New(inmem.New())
+
+	dl := newDeadLetters()
+	dl.add(ctx, LogOpUnpin, testPin(test.Cid1), test.PeerID1, errors.New("cannot remove"))
+	dl.add(ctx, LogOpPin, testPin(test.Cid2), test.PeerID1, errors.New("cannot add"))
+	err := dl.setStore(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// After a restart, the log is replayed before the store is set:
+	// letters of operations applied in the meantime are dropped and
+	// new ones are kept.
+	dl = newDeadLetters()
+	dl.remove(ctx, deadLetterID(LogOpPin, test.Cid2))
+	dl.add(ctx, LogOpPin, testPin(test.Cid3), test.PeerID1, errors.New("cannot add"))
+	err = dl.setStore(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{deadLetterID(LogOpUnpin, test.Cid1), deadLetterID(LogOpPin, test.Cid3)}
+	letters := dl.list()
+	if len(letters) != 2 || letters[0].ID != expected[0] || letters[1].ID != expected[1] {
+		t.Fatalf("unexpected dead letters: %+v", letters)
+	}
+
+	dl = newDeadLetters()
+	err = dl.setStore(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dl.list()) != 2 {
+		t.Errorf("dead letters should have been persisted: %+v", dl.list())
+	}
+}
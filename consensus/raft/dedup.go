@@ -0,0 +1,135 @@
+package raft
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// inflightOp is an operation being committed by this peer (as leader).
+// Identical operations submitted meanwhile wait for its result rather than
+// being committed again.
+type inflightOp struct {
+	op   *LogOp
+	done chan struct{}
+	err  error
+}
+
+// inflightOps keeps track of the operations being committed, by type and
+// CID.
+type inflightOps struct {
+	mux sync.Mutex
+	ops map[string]*inflightOp
+}
+
+func newInflightOps() *inflightOps {
+	return &inflightOps{
+		ops: make(map[string]*inflightOp),
+	}
+}
+
+func inflightKey(op *LogOp) string {
+	return fmt.Sprintf("%d/%s", op.Type, op.Cid.Cid)
+}
+
+// start registers an operation. When an identical operation is already in
+// flight, it is returned along with true and nothing is registered.
+// Otherwise, finish must be called with the result of the commit.
+func (io *inflightOps) start(op *LogOp) (*inflightOp, bool) {
+	io.mux.Lock()
+	defer io.mux.Unlock()
+
+	key := inflightKey(op)
+	if f, ok := io.ops[key]; ok && f.op.Cid.Equals(op.Cid) {
+		return f, true
+	}
+
+	f := &inflightOp{
+		op:   op,
+		done: make(chan struct{}),
+	}
+	// A different operation for the same CID replaces the previous
+	// one, which is no longer deduplicated. Last write wins in the
+	// log anyways.
+	io.ops[key] = f
+	return f, false
+}
+
+// finish records the result of the commit and releases any waiters.
+func (io *inflightOps) finish(f *inflightOp, err error) {
+	io.mux.Lock()
+	key := inflightKey(f.op)
+	if io.ops[key] == f {
+		delete(io.ops, key)
+	}
+	io.mux.Unlock()
+
+	f.err = err
+	close(f.done)
+}
+
+// wait returns the result of the in-flight operation.
+func (f *inflightOp) wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-f.done:
+		return f.err
+	}
+}
+
+// alreadyApplied returns true when committing the operation would not
+// change the shared state: the same pin is already in it, or the unpinned
+// CID is not.
+func (cc *Consensus) alreadyApplied(ctx context.Context, op *LogOp) bool {
+	st, err := cc.State(ctx)
+	if err != nil {
+		return false
+	}
+
+	switch op.Type {
+	case LogOpPin:
+		existing, err := st.Get(ctx, op.Cid.Cid)
+		return err == nil && existing.Equals(op.Cid)
+	case LogOpUnpin:
+		ok, err := st.Has(ctx, op.Cid.Cid)
+		return err == nil && !ok
+	}
+	return false
+}
+
+// leaderCommit commits the operation on the leader, unless it is already
+// applied to the state or an identical one is being committed. It returns
+// false when nothing was committed by this call.
+func (cc *Consensus) leaderCommit(ctx context.Context, op *LogOp) (bool, error) {
+	// Pin schedules are not deduplicated nor batched.
+	if op.Type == LogOpSchedule || op.Type == LogOpUnschedule {
+		return true, classifyCommitError(cc.commitOp(ctx, op))
+	}
+
+	if cc.alreadyApplied(ctx, op) {
+		switch op.Type {
+		case LogOpPin:
+			logger.Infof("already pinned: %s. Nothing committed", op.Cid.Cid)
+		case LogOpUnpin:
+			logger.Infof("already unpinned: %s. Nothing committed", op.Cid.Cid)
+		}
+		return false, nil
+	}
+
+	f, inflight := cc.inflight.start(op)
+	if inflight {
+		logger.Debugf("waiting for identical in-flight operation on %s", op.Cid.Cid)
+		return false, f.wait(ctx)
+	}
+
+	var err error
+	if cc.config.batchingEnabled() {
+		err = cc.batchCommit(ctx, op)
+	} else {
+		err = cc.commitOp(ctx, op)
+	}
+	err = classifyCommitError(err)
+	cc.inflight.finish(f, err)
+	return true, err
+}
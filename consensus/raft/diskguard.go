@@ -0,0 +1,94 @@
+package raft
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/observations"
+
+	"go.opencensus.io/stats"
+)
+
+// diskUsage returns the size in bytes of all the files in the Raft data
+// folder (BoltDB log store and snapshots).
+func diskUsage(folder string) (uint64, error) {
+	var total uint64
+	err := filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += uint64(info.Size())
+		return nil
+	})
+	return total, err
+}
+
+// Launched in NewConsensus as a goroutine. It measures the disk usage of
+// the Raft data folder and takes a snapshot, which compacts the log, when
+// it goes over MaxDiskUsage and there are log entries newer than the last
+// snapshot.
+func (cc *Consensus) watchDiskUsage() {
+	ticker := time.NewTicker(cc.config.DiskUsageCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cc.ctx.Done():
+			return
+		case <-ticker.C:
+			cc.checkDiskUsage(cc.ctx)
+		}
+	}
+}
+
+func (cc *Consensus) checkDiskUsage(ctx context.Context) {
+	folder := cc.config.GetDataFolder()
+	usage, err := diskUsage(folder)
+	if err != nil {
+		logger.Errorf("error measuring Raft disk usage: %s", err)
+		return
+	}
+	stats.Record(ctx, observations.RaftDiskUsage.M(int64(usage)))
+
+	max := cc.config.MaxDiskUsage
+	if max == 0 || usage <= max {
+		return
+	}
+
+	// BoltDB does not give space back to the filesystem after the log
+	// is truncated, so usage may stay over the limit after a snapshot.
+	// Snapshotting again only helps once there are new log entries.
+	if cc.raft.lastSnapshotIndex() >= cc.raft.r().LastIndex() {
+		logger.Debugf("Raft data folder uses %d bytes (max_disk_usage: %d), but there is nothing new to snapshot", usage, max)
+		return
+	}
+
+	logger.Warnf("Raft data folder uses %d bytes (max_disk_usage: %d). Taking a snapshot", usage, max)
+	cc.shutdownLock.RLock() // do not shut down while snapshotting
+	err = cc.raft.Snapshot()
+	cc.shutdownLock.RUnlock()
+	if err != nil {
+		logger.Errorf("error taking snapshot to compact the Raft log: %s", err)
+		return
+	}
+
+	usage, err = diskUsage(folder)
+	if err != nil {
+		logger.Errorf("error measuring Raft disk usage: %s", err)
+		return
+	}
+	stats.Record(ctx, observations.RaftDiskUsage.M(int64(usage)))
+	if usage > max {
+		// The space freed by the truncated log will be reused.
+		logger.Warnf("Raft data folder still uses %d bytes after snapshot (max_disk_usage: %d)", usage, max)
+	}
+}
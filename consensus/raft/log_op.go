@@ -3,6 +3,7 @@ package raft
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"go.opencensus.io/tag"
 	"go.opencensus.io/trace"
@@ -11,17 +12,53 @@ import (
 	"github.com/ipfs-cluster/ipfs-cluster/state"
 
 	consensus "github.com/libp2p/go-libp2p-consensus"
+	peer "github.com/libp2p/go-libp2p/core/peer"
 )
 
 // Type of consensus operation
 const (
 	LogOpPin = iota + 1
 	LogOpUnpin
+	LogOpBatch
+	// Rollbacks are now committed as batches. Entries of this type
+	// written by older peers are still applied.
+	LogOpRollback
+	// Pin schedule operations. Peers which do not know about them
+	// ignore them.
+	LogOpSchedule
+	LogOpUnschedule
 )
 
+// logOpVersion is the version of the LogOp format written by this peer.
+// It must be increased on changes that older peers cannot apply correctly,
+// which will refuse entries with a newer version rather than corrupting
+// their state. Entries written before versioning have version 0 and are
+// identical to version 1.
+const logOpVersion = 1
+
 // LogOpType expresses the type of a consensus Operation
 type LogOpType int
 
+// String returns a human-readable name for the operation type.
+func (t LogOpType) String() string {
+	switch t {
+	case LogOpPin:
+		return "pin"
+	case LogOpUnpin:
+		return "unpin"
+	case LogOpBatch:
+		return "batch"
+	case LogOpRollback:
+		return "rollback"
+	case LogOpSchedule:
+		return "schedule"
+	case LogOpUnschedule:
+		return "unschedule"
+	default:
+		return "unknown"
+	}
+}
+
 // LogOp represents an operation for the OpLogConsensus system.
 // It implements the consensus.Op interface and it is used by the
 // Consensus component.
@@ -30,13 +67,16 @@ type LogOp struct {
 	TagCtx    []byte            `codec:"t,omitempty"`
 	Cid       api.Pin           `codec:"c,omitempty"`
 	Type      LogOpType         `codec:"p,omitempty"`
+	Batch     []LogOp           `codec:"b,omitempty"`  // for LogOpBatch and LogOpRollback
+	Schedule  api.PinSchedule   `codec:"sc,omitempty"` // for LogOpSchedule and LogOpUnschedule
+	Origin    peer.ID           `codec:"o,omitempty"`  // peer that submitted the operation
+	Version   int               `codec:"v,omitempty"`
 	consensus *Consensus        `codec:"-"`
 	tracing   bool              `codec:"-"`
 }
 
 // ApplyTo applies the operation to the State
 func (op *LogOp) ApplyTo(cstate consensus.State) (consensus.State, error) {
-	var err error
 	ctx := context.Background()
 	if op.tracing {
 		tagmap, err := tag.Decode(op.TagCtx)
@@ -55,15 +95,105 @@ func (op *LogOp) ApplyTo(cstate consensus.State) (consensus.State, error) {
 		panic("received unexpected state type")
 	}
 
-	pin := op.Cid
+	// The same LogOp object is used to decode every entry in the
+	// log. Fields not present in the next entry are not reset
+	// on decoding, so we do it here.
+	defer func() {
+		op.Cid = api.Pin{}
+		op.Batch = nil
+		op.Schedule = api.PinSchedule{}
+		op.Origin = ""
+		op.Version = 0
+	}()
 
+	if op.Version > logOpVersion {
+		logger.Errorf("log entry version %d is not supported (max %d). This peer needs to be upgraded", op.Version, logOpVersion)
+		return nil, fmt.Errorf("unsupported log entry version: %d", op.Version)
+	}
+
+	var err error
 	switch op.Type {
+	case LogOpBatch:
+		// Items are applied individually. Those that fail are kept
+		// as dead letters, from which the committer obtains their
+		// error, and do not make the state inconsistent.
+		for _, bOp := range op.Batch {
+			_ = op.apply(ctx, state, bOp.Type, bOp.Cid)
+		}
+	case LogOpRollback:
+		err = op.rollback(ctx, state)
+	case LogOpSchedule:
+		err = state.AddSchedule(ctx, op.Schedule)
+	case LogOpUnschedule:
+		err = state.RmSchedule(ctx, op.Schedule.ID)
+	default:
+		err = op.apply(ctx, state, op.Type, op.Cid)
+	}
+	if err != nil {
+		// We failed to apply the operation to the state
+		// and therefore we need to request a rollback to the
+		// cluster to the previous state. This operation can only be
+		// performed by the cluster leader.
+		logger.Error("the state could not be updated. A rollback may be necessary")
+		return nil, errors.New("a rollback may be necessary. Reason: " + err.Error())
+	}
+	return state, nil
+}
+
+// rollback replaces the pinset in the state with the one in the operation
+// Batch. Pins that are not part of it are unpinned. The pin tracker is
+// notified of every change so that it reconciles with the new state.
+func (op *LogOp) rollback(ctx context.Context, state state.State) error {
+	keep := make(map[api.Cid]struct{}, len(op.Batch))
+	for _, bOp := range op.Batch {
+		keep[bOp.Cid.Cid] = struct{}{}
+	}
+
+	out := make(chan api.Pin, 1024)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- state.List(ctx, out)
+	}()
+	var remove []api.Pin
+	for pin := range out {
+		if _, ok := keep[pin.Cid]; !ok {
+			remove = append(remove, pin)
+		}
+	}
+	if err := <-errCh; err != nil {
+		return err
+	}
+
+	var err error
+	for _, pin := range remove {
+		if rmErr := op.apply(ctx, state, LogOpUnpin, pin); rmErr != nil {
+			err = rmErr
+		}
+	}
+	for _, bOp := range op.Batch {
+		if addErr := op.apply(ctx, state, LogOpPin, bOp.Cid); addErr != nil {
+			err = addErr
+		}
+	}
+	logger.Warnf("state rolled back: %d pins, %d removed", len(op.Batch), len(remove))
+	return err
+}
+
+// apply performs a single pin or unpin operation on the state. Operations
+// that fail are kept as dead letters until they are retried or discarded.
+func (op *LogOp) apply(ctx context.Context, state state.State, t LogOpType, pin api.Pin) error {
+	switch t {
 	case LogOpPin:
-		err = state.Add(ctx, pin)
+		err := state.Add(ctx, pin)
 		if err != nil {
 			logger.Error(err)
-			goto ROLLBACK
+			op.consensus.deadLetters.add(ctx, t, pin, op.Origin, err)
+			return err
 		}
+		op.consensus.raft.events.Publish(api.ConsensusEvent{
+			Type: api.ConsensusEventPin,
+			Pin:  pin,
+		})
 		// Async, we let the PinTracker take care of any problems
 		op.consensus.rpcClient.GoContext(
 			ctx,
@@ -75,11 +205,16 @@ func (op *LogOp) ApplyTo(cstate consensus.State) (consensus.State, error) {
 			nil,
 		)
 	case LogOpUnpin:
-		err = state.Rm(ctx, pin.Cid)
+		err := state.Rm(ctx, pin.Cid)
 		if err != nil {
 			logger.Error(err)
-			goto ROLLBACK
+			op.consensus.deadLetters.add(ctx, t, pin, op.Origin, err)
+			return err
 		}
+		op.consensus.raft.events.Publish(api.ConsensusEvent{
+			Type: api.ConsensusEventUnpin,
+			Pin:  pin,
+		})
 		// Async, we let the PinTracker take care of any problems
 		op.consensus.rpcClient.GoContext(
 			ctx,
@@ -92,14 +227,8 @@ func (op *LogOp) ApplyTo(cstate consensus.State) (consensus.State, error) {
 		)
 	default:
 		logger.Error("unknown LogOp type. Ignoring")
+		return nil
 	}
-	return state, nil
-
-ROLLBACK:
-	// We failed to apply the operation to the state
-	// and therefore we need to request a rollback to the
-	// cluster to the previous state. This operation can only be performed
-	// by the cluster leader.
-	logger.Error("Rollbacks are not implemented")
-	return nil, errors.New("a rollback may be necessary. Reason: " + err.Error())
+	op.consensus.deadLetters.remove(ctx, deadLetterID(t, pin.Cid))
+	return nil
 }
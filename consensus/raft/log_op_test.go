@@ -3,6 +3,7 @@ package raft
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/ipfs-cluster/ipfs-cluster/api"
 	"github.com/ipfs-cluster/ipfs-cluster/datastore/inmem"
@@ -71,6 +72,139 @@ func TestApplyToUnpin(t *testing.T) {
 	}
 }
 
+func TestApplyToBatch(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	op := &LogOp{
+		Type: LogOpBatch,
+		Batch: []LogOp{
+			{Cid: testPin(test.Cid1), Type: LogOpPin},
+			{Cid: testPin(test.Cid2), Type: LogOpPin},
+			{Cid: testPin(test.Cid1), Type: LogOpUnpin},
+		},
+		consensus: cc,
+	}
+	defer cleanRaft(1)
+	defer cc.Shutdown(ctx)
+
+	st, err := dsstate.New(ctx, inmem.New(), "", dsstate.DefaultHandle())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = op.ApplyTo(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if op.Batch != nil {
+		t.Error("the batch should be reset after applying it")
+	}
+
+	out := make(chan api.Pin, 100)
+	err = st.List(ctx, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pins []api.Pin
+	for p := range out {
+		pins = append(pins, p)
+	}
+
+	if len(pins) != 1 || !pins[0].Cid.Equals(test.Cid2) {
+		t.Error("the state was not modified correctly")
+	}
+}
+
+func TestApplyToBatchFailedItem(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	op := &LogOp{
+		Type: LogOpBatch,
+		Batch: []LogOp{
+			{Cid: testPin(test.Cid1), Type: LogOpPin},
+			{Cid: testPin(test.Cid2), Type: LogOpUnpin},
+		},
+		consensus: cc,
+	}
+	defer cleanRaft(1)
+	defer cc.Shutdown(ctx)
+
+	st, err := dsstate.New(ctx, inmem.New(), "", dsstate.DefaultHandle())
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Add(ctx, testPin(test.Cid2))
+
+	start := time.Now()
+	// Adding fails, the unpin in the same batch is applied.
+	_, err = op.ApplyTo(&failingState{st})
+	if err != nil {
+		t.Fatal("a failed item should not fail the batch:", err)
+	}
+	if ok, _ := st.Has(ctx, test.Cid2); ok {
+		t.Error("the unpin should have been applied")
+	}
+
+	pin := &LogOp{Cid: testPin(test.Cid1), Type: LogOpPin}
+	if err := cc.batchItemError(pin, start); err == nil {
+		t.Error("expected an error for the failed item")
+	}
+	unpin := &LogOp{Cid: testPin(test.Cid2), Type: LogOpUnpin}
+	if err := cc.batchItemError(unpin, start); err != nil {
+		t.Error("unexpected error for the applied item:", err)
+	}
+	if err := cc.batchItemError(pin, time.Now()); err != nil {
+		t.Error("dead letters older than the commit should be ignored:", err)
+	}
+}
+
+func TestApplyToRollback(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, 1)
+	op := &LogOp{
+		Type: LogOpRollback,
+		Batch: []LogOp{
+			{Cid: testPin(test.Cid2), Type: LogOpPin},
+			{Cid: testPin(test.Cid3), Type: LogOpPin},
+		},
+		consensus: cc,
+	}
+	defer cleanRaft(1)
+	defer cc.Shutdown(ctx)
+
+	st, err := dsstate.New(ctx, inmem.New(), "", dsstate.DefaultHandle())
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Add(ctx, testPin(test.Cid1))
+	st.Add(ctx, testPin(test.Cid2))
+
+	_, err = op.ApplyTo(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op.Batch != nil {
+		t.Error("the batch should be reset after applying it")
+	}
+
+	out := make(chan api.Pin, 100)
+	err = st.List(ctx, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pins := make(map[api.Cid]struct{})
+	for p := range out {
+		pins[p.Cid] = struct{}{}
+	}
+	_, ok2 := pins[test.Cid2]
+	_, ok3 := pins[test.Cid3]
+	if len(pins) != 2 || !ok2 || !ok3 {
+		t.Error("the state was not rolled back correctly")
+	}
+}
+
 func TestApplyToBadState(t *testing.T) {
 	defer func() {
 		if r := recover(); r == nil {
@@ -86,3 +220,32 @@ func TestApplyToBadState(t *testing.T) {
 	var st interface{}
 	op.ApplyTo(st)
 }
+
+func TestApplyToUnsupportedVersion(t *testing.T) {
+	ctx := context.Background()
+	op := &LogOp{
+		Cid:     api.PinCid(test.Cid1),
+		Type:    LogOpPin,
+		Version: logOpVersion + 1,
+	}
+
+	st, err := dsstate.New(ctx, inmem.New(), "", dsstate.DefaultHandle())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = op.ApplyTo(st)
+	if err == nil {
+		t.Fatal("expected an error applying a newer log entry")
+	}
+	if op.Version != 0 {
+		t.Error("the version should be reset after applying")
+	}
+
+	ok, err := st.Has(ctx, test.Cid1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("the state should not have been modified")
+	}
+}
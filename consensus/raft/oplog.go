@@ -0,0 +1,105 @@
+package raft
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	hraft "github.com/hashicorp/raft"
+	codec "github.com/ugorji/go/codec"
+	"go.opencensus.io/trace"
+)
+
+// maximum number of entries returned by LogEntries.
+const maxLogEntriesLimit = 1000
+
+// LogEntries returns up to limit operations committed to the Raft log
+// before the given index, newest first. When before is 0, the latest
+// operations are returned. Batches are never split, so more than limit
+// entries may be returned. Entries that were compacted into a snapshot
+// are no longer available.
+func (cc *Consensus) LogEntries(ctx context.Context, before uint64, limit int) ([]api.ConsensusLogEntry, error) {
+	_, span := trace.StartSpan(ctx, "consensus/LogEntries")
+	defer span.End()
+
+	if limit <= 0 || limit > maxLogEntriesLimit {
+		limit = maxLogEntriesLimit
+	}
+
+	applied := cc.raft.r().AppliedIndex()
+	if before == 0 || before > applied {
+		before = applied + 1
+	}
+	// The store is replaced when Raft is restarted. Reading from the
+	// old one fails after it has been closed.
+	logs := cc.raft.logs()
+	first, err := logs.FirstIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]api.ConsensusLogEntry, 0, limit)
+	for i := before - 1; i > 0 && i >= first && len(entries) < limit; i-- {
+		var l hraft.Log
+		err := logs.GetLog(i, &l)
+		if errors.Is(err, hraft.ErrLogNotFound) {
+			// compacted while we were reading.
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if l.Type != hraft.LogCommand {
+			continue
+		}
+
+		var op LogOp
+		if err := decodeLogOp(l.Data, &op); err != nil {
+			logger.Debugf("cannot decode log entry %d: %s", i, err)
+			continue
+		}
+		entries = append(entries, logOpEntries(l, op)...)
+	}
+	return entries, nil
+}
+
+// decodeLogOp decodes a serialized LogOp in the same way that libp2p-raft
+// does.
+func decodeLogOp(data []byte, op *LogOp) error {
+	h := &codec.MsgpackHandle{}
+	h.ErrorIfNoField = true
+	return codec.NewDecoderBytes(data, h).Decode(op)
+}
+
+// logOpEntries converts the operation in a log entry to ConsensusLogEntries.
+// Batches are flattened, in reverse order.
+func logOpEntries(l hraft.Log, op LogOp) []api.ConsensusLogEntry {
+	entry := api.ConsensusLogEntry{
+		Index:     l.Index,
+		Type:      op.Type.String(),
+		Cid:       op.Cid.Cid,
+		Origin:    op.Origin,
+		Timestamp: l.AppendedAt,
+	}
+
+	switch op.Type {
+	case LogOpBatch:
+		entries := make([]api.ConsensusLogEntry, 0, len(op.Batch))
+		for i := len(op.Batch) - 1; i >= 0; i-- {
+			bOp := op.Batch[i]
+			e := entry
+			e.Type = bOp.Type.String()
+			e.Cid = bOp.Cid.Cid
+			if bOp.Origin != "" {
+				e.Origin = bOp.Origin
+			}
+			entries = append(entries, e)
+		}
+		return entries
+	case LogOpRollback:
+		entry.Cid = api.CidUndef
+		entry.Pins = len(op.Batch)
+	}
+	return []api.ConsensusLogEntry{entry}
+}
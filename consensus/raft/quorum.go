@@ -0,0 +1,91 @@
+package raft
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	hraft "github.com/hashicorp/raft"
+	network "github.com/libp2p/go-libp2p/core/network"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+
+	"go.opencensus.io/trace"
+)
+
+// Voters returns the peers that take part in Raft elections and commits.
+func (rw *raftWrapper) Voters(ctx context.Context) ([]peer.ID, error) {
+	_, span := trace.StartSpan(ctx, "consensus/raft/Voters")
+	defer span.End()
+
+	configFuture := rw.r().GetConfiguration()
+	if err := configFuture.Error(); err != nil {
+		return nil, err
+	}
+
+	var voters []peer.ID
+	for _, server := range configFuture.Configuration().Servers {
+		if server.Suffrage != hraft.Voter {
+			continue
+		}
+		pid, err := peer.Decode(string(server.ID))
+		if err != nil {
+			return nil, err
+		}
+		voters = append(voters, pid)
+	}
+	return voters, nil
+}
+
+// Quorum reports how many Raft voters this peer can reach and whether they
+// are enough to commit operations. Voters are considered reachable when
+// this peer is connected to them.
+func (cc *Consensus) Quorum(ctx context.Context) (api.QuorumStatus, error) {
+	ctx, span := trace.StartSpan(ctx, "consensus/Quorum")
+	defer span.End()
+
+	voters, err := cc.raft.Voters(ctx)
+	if err != nil {
+		return api.QuorumStatus{}, err
+	}
+
+	status := api.QuorumStatus{
+		Leader:        cc.raft.CurrentLeader(),
+		Voters:        voters,
+		Quorum:        len(voters)/2 + 1,
+		CommitLatency: time.Duration(atomic.LoadInt64(&cc.commitLatency)),
+	}
+	for _, v := range voters {
+		if v == cc.host.ID() || cc.host.Network().Connectedness(v) == network.Connected {
+			status.ReachableVoters = append(status.ReachableVoters, v)
+		}
+	}
+	status.HasQuorum = len(status.ReachableVoters) >= status.Quorum
+	return status, nil
+}
+
+// checkQuorum returns ErrNoQuorum when not enough voters are reachable
+// to commit operations.
+func (cc *Consensus) checkQuorum(ctx context.Context) error {
+	status, err := cc.Quorum(ctx)
+	if err != nil {
+		return classifyCommitError(err)
+	}
+	if !status.HasQuorum {
+		return fmt.Errorf(
+			"%w: %d of %d voters reachable, %d needed",
+			ErrNoQuorum,
+			len(status.ReachableVoters),
+			len(status.Voters),
+			status.Quorum,
+		)
+	}
+	return nil
+}
+
+// recordCommitLatency keeps the time taken by the last successful commit.
+func (cc *Consensus) recordCommitLatency(d time.Duration) {
+	atomic.StoreInt64(&cc.commitLatency, int64(d))
+}
@@ -7,8 +7,15 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/consensus/events"
+	"github.com/ipfs-cluster/ipfs-cluster/observations"
 	"github.com/ipfs-cluster/ipfs-cluster/state"
 
 	p2praft "github.com/libp2p/go-libp2p-raft"
@@ -17,6 +24,7 @@ import (
 
 	hraft "github.com/hashicorp/raft"
 	raftboltdb "github.com/hashicorp/raft-boltdb"
+	"go.opencensus.io/stats"
 	"go.opencensus.io/trace"
 )
 
@@ -26,12 +34,6 @@ var raftLogger = &p2praft.HcLogToLogger{}
 // the peer set, which won't happen
 var errWaitingForSelf = errors.New("waiting for ourselves to depart")
 
-// RaftMaxSnapshots indicates how many snapshots to keep in the consensus data
-// folder.
-// TODO: Maybe include this in Config. Not sure how useful it is to touch
-// this anyways.
-var RaftMaxSnapshots = 5
-
 // RaftLogCacheSize is the maximum number of logs to cache in-memory.
 // This is used to reduce disk I/O for the recently committed entries.
 var RaftLogCacheSize = 512
@@ -40,25 +42,50 @@ var RaftLogCacheSize = 512
 var waitForUpdatesShutdownTimeout = 5 * time.Second
 var waitForUpdatesInterval = 400 * time.Millisecond
 
+// How often we log progress while catching up with the log
+var catchUpLogInterval = 10 * time.Second
+
+// How often the sync lag metric is recorded
+var syncLagInterval = 10 * time.Second
+
 // How many times to retry snapshotting when shutting down
 var maxShutdownSnapshotRetries = 5
 
+// How often the Raft configuration is checked for added or removed peers
+var configurationCheckInterval = time.Second
+
 // raftWrapper wraps the hraft.Raft object and related things like the
 // different stores used or the hraft.Configuration.
 // Its methods provide functionality for working with Raft.
 type raftWrapper struct {
 	ctx           context.Context
 	cancel        context.CancelFunc
-	raft          *hraft.Raft
+	stopObservers context.CancelFunc
+	fsm           hraft.FSM
 	config        *Config
 	host          host.Host
 	serverConfig  hraft.Configuration
+	staging       bool
+
+	// The Raft instance, the transport and the stores are replaced when
+	// Raft is restarted. Use r() and logs() to access the Raft instance
+	// and the log store.
+	mux           sync.RWMutex
+	raft          *hraft.Raft
 	transport     *hraft.NetworkTransport
 	snapshotStore hraft.SnapshotStore
 	logStore      hraft.LogStore
 	stableStore   hraft.StableStore
 	boltdb        *raftboltdb.BoltStore
-	staging       bool
+
+	catchUpMux sync.RWMutex
+	catchUp    api.CatchUpProgress
+
+	leader        atomic.Value // peer.ID, updated by observeLeader
+	leaderSubsMux sync.Mutex
+	leaderSubs    map[chan peer.ID]struct{}
+
+	events *events.Bus
 }
 
 // newRaftWrapper creates a Raft instance and initializes
@@ -75,6 +102,9 @@ func newRaftWrapper(
 	raftW.config = cfg
 	raftW.host = host
 	raftW.staging = staging
+	raftW.leaderSubs = make(map[chan peer.ID]struct{})
+	raftW.leader.Store(peer.ID(""))
+	raftW.events = events.NewBus()
 	// Set correct LocalID
 	cfg.RaftConfig.LocalID = hraft.ServerID(host.ID().String())
 
@@ -86,34 +116,105 @@ func newRaftWrapper(
 
 	raftW.makeServerConfig()
 
-	err = raftW.makeTransport()
+	raftW.fsm = fsm
+	raftW.ctx, raftW.cancel = context.WithCancel(context.Background())
+	err = raftW.start()
 	if err != nil {
+		raftW.cancel()
 		return nil, err
 	}
+	return raftW, nil
+}
+
+// r returns the current Raft instance.
+func (rw *raftWrapper) r() *hraft.Raft {
+	rw.mux.RLock()
+	defer rw.mux.RUnlock()
+	return rw.raft
+}
+
+// logs returns the current log store.
+func (rw *raftWrapper) logs() hraft.LogStore {
+	rw.mux.RLock()
+	defer rw.mux.RUnlock()
+	return rw.logStore
+}
 
-	err = raftW.makeStores()
+// start creates the transport, the stores and the Raft instance, and
+// starts observing it.
+func (rw *raftWrapper) start() error {
+	rw.mux.Lock()
+	err := rw.create()
+	rw.mux.Unlock()
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	// The observers are bound to this Raft instance.
+	var ctx context.Context
+	ctx, rw.stopObservers = context.WithCancel(rw.ctx)
+	go rw.observePeers(ctx)
+	go rw.observeLeader(ctx)
+	go rw.observeConfiguration(ctx)
+	return nil
+}
+
+// create creates the transport, the stores and the Raft instance. The lock
+// must be held.
+func (rw *raftWrapper) create() error {
+	err := rw.makeTransport()
+	if err != nil {
+		return err
+	}
+
+	err = rw.makeStores()
+	if err != nil {
+		return err
 	}
 
 	logger.Debug("creating Raft")
-	raftW.raft, err = hraft.NewRaft(
-		cfg.RaftConfig,
-		fsm,
-		raftW.logStore,
-		raftW.stableStore,
-		raftW.snapshotStore,
-		raftW.transport,
+	rw.raft, err = hraft.NewRaft(
+		rw.config.RaftConfig,
+		rw.fsm,
+		rw.logStore,
+		rw.stableStore,
+		rw.snapshotStore,
+		rw.transport,
 	)
 	if err != nil {
 		logger.Error("initializing raft: ", err)
-		return nil, err
+		return err
 	}
+	return nil
+}
 
-	raftW.ctx, raftW.cancel = context.WithCancel(context.Background())
-	go raftW.observePeers()
+// restart shuts down the Raft instance and starts a new one with the same
+// stores and FSM, as a restart of the peer would. The new instance restores
+// the last snapshot and replays the log. It gives up when the running
+// instance does not shut down within the given timeout.
+func (rw *raftWrapper) restart(timeout time.Duration) error {
+	rw.stopObservers()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- rw.r().Shutdown().Error()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("could not shutdown raft: %w", err)
+		}
+	case <-time.After(timeout):
+		return errors.New("timed out shutting down raft")
+	}
 
-	return raftW, nil
+	rw.mux.RLock()
+	err := rw.boltdb.Close()
+	rw.mux.RUnlock()
+	if err != nil {
+		return fmt.Errorf("could not close boltdb: %w", err)
+	}
+	return rw.start()
 }
 
 // makeDataFolder creates the folder that is meant to store Raft data. Ensures
@@ -149,7 +250,7 @@ func (rw *raftWrapper) makeStores() error {
 	logger.Debug("creating raft snapshot store")
 	snapstore, err := hraft.NewFileSnapshotStoreWithLogger(
 		df,
-		RaftMaxSnapshots,
+		rw.config.MaxSnapshots,
 		raftLogger,
 	)
 	if err != nil {
@@ -183,7 +284,7 @@ func (rw *raftWrapper) Bootstrap() (bool, error) {
 
 		// Inform the user that we are working with a pre-existing peerset
 		logger.Info("existing Raft state found! raft.InitPeerset will be ignored")
-		cf := rw.raft.GetConfiguration()
+		cf := rw.r().GetConfiguration()
 		if err := cf.Error(); err != nil {
 			logger.Debug(err)
 			return false, err
@@ -198,6 +299,12 @@ func (rw *raftWrapper) Bootstrap() (bool, error) {
 		return false, nil
 	}
 
+	if rw.config.NonVoter && !rw.staging {
+		err := errors.New("non-voter peers cannot initialize a new cluster and should be started with --bootstrap")
+		logger.Error(err)
+		return false, err
+	}
+
 	if rw.staging {
 		logger.Debug("staging servers do not need initialization")
 		logger.Info("peer is ready to join a cluster")
@@ -211,7 +318,7 @@ func (rw *raftWrapper) Bootstrap() (bool, error) {
 
 	logger.Infof("initializing raft cluster with the following voters:\n%s\n", voters)
 
-	future := rw.raft.BootstrapCluster(rw.serverConfig)
+	future := rw.r().BootstrapCluster(rw.serverConfig)
 	if err := future.Error(); err != nil {
 		logger.Error("bootstrapping cluster: ", err)
 		return true, err
@@ -253,15 +360,27 @@ func (rw *raftWrapper) WaitForLeader(ctx context.Context) (string, error) {
 	ctx, span := trace.StartSpan(ctx, "consensus/raft/WaitForLeader")
 	defer span.End()
 
-	ticker := time.NewTicker(time.Second / 2)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// Subscribe first so that no change is missed.
+	sub := rw.SubscribeLeader(ctx)
+	if l := rw.CurrentLeader(); l != "" {
+		return l.String(), nil
+	}
+
 	for {
 		select {
-		case <-ticker.C:
-			if l := rw.raft.Leader(); l != "" {
-				logger.Debug("waitForleaderTimer")
+		case l, ok := <-sub:
+			if !ok {
+				// the context is done or we are shutting down.
+				if err := ctx.Err(); err != nil {
+					return "", err
+				}
+				return "", errors.New("raft is shutting down")
+			}
+			if l != "" {
 				logger.Infof("Current Raft Leader: %s", l)
-				ticker.Stop()
-				return string(l), nil
+				return l.String(), nil
 			}
 		case <-ctx.Done():
 			return "", ctx.Err()
@@ -282,7 +401,7 @@ func (rw *raftWrapper) WaitForVoter(ctx context.Context) error {
 			return ctx.Err()
 		default:
 			logger.Debugf("%s: get configuration", pid)
-			configFuture := rw.raft.GetConfiguration()
+			configFuture := rw.r().GetConfiguration()
 			if err := configFuture.Error(); err != nil {
 				return err
 			}
@@ -297,6 +416,39 @@ func (rw *raftWrapper) WaitForVoter(ctx context.Context) error {
 	}
 }
 
+// WaitForNonVoter holds until we have been added to the Raft configuration
+// as a non-voter.
+func (rw *raftWrapper) WaitForNonVoter(ctx context.Context) error {
+	ctx, span := trace.StartSpan(ctx, "consensus/raft/WaitForNonVoter")
+	defer span.End()
+
+	logger.Debug("waiting until we are added as a non-voter")
+
+	pid := hraft.ServerID(rw.host.ID().String())
+	for {
+		configFuture := rw.r().GetConfiguration()
+		if err := configFuture.Error(); err != nil {
+			return err
+		}
+		for _, server := range configFuture.Configuration().Servers {
+			if server.ID != pid {
+				continue
+			}
+			if server.Suffrage == hraft.Voter {
+				logger.Warn("this peer is configured as non-voter but it is a Raft voter. Remove it from the peerset and join again to demote it")
+			}
+			return nil
+		}
+		logger.Debugf("%s: not added yet", pid)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(waitForUpdatesInterval):
+		}
+	}
+}
+
 func isVoter(srvID hraft.ServerID, cfg hraft.Configuration) bool {
 	for _, server := range cfg.Servers {
 		if server.ID == srvID && server.Suffrage == hraft.Voter {
@@ -306,27 +458,154 @@ func isVoter(srvID hraft.ServerID, cfg hraft.Configuration) bool {
 	return false
 }
 
-// WaitForUpdates holds until Raft has synced to the last index in the log
+// WaitForUpdates holds until Raft has synced to the last index in the log.
+// It gives up after the configured CatchUpTimeout, if any.
 func (rw *raftWrapper) WaitForUpdates(ctx context.Context) error {
 	ctx, span := trace.StartSpan(ctx, "consensus/raft/WaitForUpdates")
 	defer span.End()
 
+	if rw.config.CatchUpTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, rw.config.CatchUpTimeout)
+		defer cancel()
+	}
+
 	logger.Debug("Raft state is catching up to the latest known version. Please wait...")
+	started := time.Now()
+	startIndex := rw.r().AppliedIndex()
+	startSnapshot := rw.lastSnapshotIndex()
+	lastLog := started
+	ticker := time.NewTicker(rw.config.CatchUpPollInterval)
+	defer ticker.Stop()
+
 	for {
+		lai := rw.r().AppliedIndex()
+		li := rw.r().LastIndex()
+		rw.setCatchUp(api.CatchUpProgress{
+			Syncing:      lai != li,
+			AppliedIndex: lai,
+			LastIndex:    li,
+			Started:      started,
+		})
+		rw.recordSyncLag(ctx)
+		logger.Debugf("current Raft index: %d/%d",
+			lai, li)
+		if lai == li {
+			if lai > startIndex {
+				rw.logCatchUp(startIndex, startSnapshot, lai, time.Since(started))
+			}
+			return nil
+		}
+
+		if time.Since(lastLog) >= catchUpLogInterval {
+			logger.Infof(
+				"Raft state is catching up: %d/%d entries applied (%s elapsed)",
+				lai, li, time.Since(started).Round(time.Second),
+			)
+			lastLog = time.Now()
+		}
+
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			lai := rw.raft.AppliedIndex()
-			li := rw.raft.LastIndex()
-			logger.Debugf("current Raft index: %d/%d",
-				lai, li)
-			if lai == li {
-				return nil
+			rw.setCatchUp(api.CatchUpProgress{})
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("state did not catch up after %s (%d/%d entries applied): %w", time.Since(started).Round(time.Second), lai, li, ctx.Err())
 			}
-			time.Sleep(waitForUpdatesInterval)
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (rw *raftWrapper) setCatchUp(p api.CatchUpProgress) {
+	rw.catchUpMux.Lock()
+	rw.catchUp = p
+	rw.catchUpMux.Unlock()
+}
+
+// CatchUpProgress returns the progress of the last WaitForUpdates call.
+func (rw *raftWrapper) CatchUpProgress() api.CatchUpProgress {
+	rw.catchUpMux.RLock()
+	defer rw.catchUpMux.RUnlock()
+	return rw.catchUp
+}
+
+// logCatchUp reports whether the state caught up by replaying the missed
+// log entries or by installing a full snapshot sent by the leader, which
+// happens when the leader has already compacted those entries.
+func (rw *raftWrapper) logCatchUp(from, fromSnapshot, to uint64, took time.Duration) {
+	took = took.Round(time.Millisecond)
+	if snap := rw.lastSnapshotIndex(); snap > fromSnapshot && snap > from {
+		logger.Infof(
+			"Raft state caught up by installing a snapshot at index %d (%d entries behind, %s). Raise trailing_logs to let peers replay longer outages from the log",
+			snap, to-from, took,
+		)
+		return
+	}
+	logger.Infof("Raft state caught up by replaying %d log entries (%s)", to-from, took)
+}
+
+func (rw *raftWrapper) lastSnapshotIndex() uint64 {
+	idx, _ := strconv.ParseUint(rw.r().Stats()["last_snapshot_index"], 10, 64)
+	return idx
+}
+
+// SyncLag returns the number of log entries known to this peer that have
+// not been applied to the state yet.
+func (rw *raftWrapper) SyncLag() uint64 {
+	lai := rw.r().AppliedIndex()
+	li := rw.r().LastIndex()
+	if li > lai {
+		return li - lai
+	}
+	return 0
+}
+
+func (rw *raftWrapper) recordSyncLag(ctx context.Context) {
+	stats.Record(ctx, observations.ConsensusSyncLag.M(int64(rw.SyncLag())))
+}
+
+// Stats returns the role, term and applied index of this peer along with
+// the snapshots it keeps and the disk usage of the Raft data folder.
+func (rw *raftWrapper) Stats() api.ConsensusStats {
+	var stats api.ConsensusStats
+	switch st := rw.r().State(); st {
+	case hraft.Leader:
+		stats.Role = api.ConsensusRoleLeader
+	case hraft.Follower:
+		stats.Role = api.ConsensusRoleFollower
+	case hraft.Candidate:
+		stats.Role = api.ConsensusRoleCandidate
+	default:
+		stats.Role = strings.ToLower(st.String())
+	}
+	stats.Term, _ = strconv.ParseUint(rw.r().Stats()["term"], 10, 64)
+	stats.AppliedIndex = rw.r().AppliedIndex()
+	stats.SyncLag = rw.SyncLag()
+
+	dataFolder := rw.config.GetDataFolder()
+	rw.mux.RLock()
+	snapshotStore := rw.snapshotStore
+	rw.mux.RUnlock()
+	snaps, err := snapshotStore.List()
+	if err != nil {
+		logger.Errorf("error listing Raft snapshots: %s", err)
+	}
+	stats.Snapshots = len(snaps)
+	if len(snaps) > 0 {
+		// the newest snapshot comes first
+		fi, err := os.Stat(filepath.Join(dataFolder, "snapshots", snaps[0].ID))
+		if err == nil {
+			stats.LastSnapshot = fi.ModTime()
 		}
 	}
+
+	usage, err := diskUsage(dataFolder)
+	if err != nil {
+		logger.Errorf("error measuring Raft disk usage: %s", err)
+	}
+	stats.StorageSize = usage
+	return stats
 }
 
 func (rw *raftWrapper) WaitForPeer(ctx context.Context, pid string, depart bool) error {
@@ -366,7 +645,7 @@ func (rw *raftWrapper) WaitForPeer(ctx context.Context, pid string, depart bool)
 
 // Snapshot tells Raft to take a snapshot.
 func (rw *raftWrapper) Snapshot() error {
-	future := rw.raft.Snapshot()
+	future := rw.r().Snapshot()
 	err := future.Error()
 	if err != nil && err.Error() != hraft.ErrNothingNewToSnapshot.Error() {
 		return err
@@ -413,24 +692,35 @@ func (rw *raftWrapper) Shutdown(ctx context.Context) error {
 
 	errMsgs := ""
 
+	err := rw.transferLeadership()
+	if err != nil {
+		// not fatal: the remaining peers will elect a new leader
+		// after the election timeout.
+		logger.Warnf("could not transfer leadership before shutdown: %s", err)
+	}
+
 	rw.cancel()
 
-	err := rw.snapshotOnShutdown()
+	err = rw.snapshotOnShutdown()
 	if err != nil {
 		errMsgs += err.Error() + ".\n"
 	}
 
-	future := rw.raft.Shutdown()
+	future := rw.r().Shutdown()
 	err = future.Error()
 	if err != nil {
 		errMsgs += "could not shutdown raft: " + err.Error() + ".\n"
 	}
 
+	rw.mux.RLock()
 	err = rw.boltdb.Close() // important!
+	rw.mux.RUnlock()
 	if err != nil {
 		errMsgs += "could not close boltdb: " + err.Error()
 	}
 
+	rw.events.Close()
+
 	if errMsgs != "" {
 		return errors.New(errMsgs)
 	}
@@ -438,8 +728,41 @@ func (rw *raftWrapper) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// transferLeadership hands leadership over to the most up to date voter
+// when this peer is the leader, so that the cluster does not stay without
+// leader until the election timeout triggers after this peer shuts down.
+func (rw *raftWrapper) transferLeadership() error {
+	if rw.r().State() != hraft.Leader {
+		return nil
+	}
+
+	configFuture := rw.r().GetConfiguration()
+	if err := configFuture.Error(); err != nil {
+		return err
+	}
+	selfID := hraft.ServerID(rw.host.ID().String())
+	hasVoters := false
+	for _, server := range configFuture.Configuration().Servers {
+		if server.ID != selfID && server.Suffrage == hraft.Voter {
+			hasVoters = true
+			break
+		}
+	}
+	if !hasVoters {
+		return nil
+	}
+
+	logger.Info("transferring Raft leadership before shutting down")
+	future := rw.r().LeadershipTransfer()
+	if err := future.Error(); err != nil {
+		return err
+	}
+	logger.Infof("Raft leadership transferred to %s", rw.r().Leader())
+	return nil
+}
+
 // AddPeer adds a peer to Raft
-func (rw *raftWrapper) AddPeer(ctx context.Context, peer string) error {
+func (rw *raftWrapper) AddPeer(ctx context.Context, peer string, nonVoter bool) error {
 	ctx, span := trace.StartSpan(ctx, "consensus/raft/AddPeer")
 	defer span.End()
 
@@ -454,12 +777,22 @@ func (rw *raftWrapper) AddPeer(ctx context.Context, peer string) error {
 		return nil
 	}
 
-	future := rw.raft.AddVoter(
-		hraft.ServerID(peer),
-		hraft.ServerAddress(peer),
-		0,
-		0,
-	) // TODO: Extra cfg value?
+	var future hraft.IndexFuture
+	if nonVoter {
+		future = rw.r().AddNonvoter(
+			hraft.ServerID(peer),
+			hraft.ServerAddress(peer),
+			0,
+			0,
+		)
+	} else {
+		future = rw.r().AddVoter(
+			hraft.ServerID(peer),
+			hraft.ServerAddress(peer),
+			0,
+			0,
+		) // TODO: Extra cfg value?
+	}
 	err = future.Error()
 	if err != nil {
 		logger.Error("raft cannot add peer: ", err)
@@ -487,7 +820,7 @@ func (rw *raftWrapper) RemovePeer(ctx context.Context, peer string) error {
 		return errors.New("cannot remove ourselves from a 1-peer cluster")
 	}
 
-	rmFuture := rw.raft.RemoveServer(
+	rmFuture := rw.r().RemoveServer(
 		hraft.ServerID(peer),
 		0,
 		0,
@@ -507,7 +840,7 @@ func (rw *raftWrapper) Leader(ctx context.Context) string {
 	_, span := trace.StartSpan(ctx, "consensus/raft/Leader")
 	defer span.End()
 
-	return string(rw.raft.Leader())
+	return string(rw.r().Leader())
 }
 
 func (rw *raftWrapper) Peers(ctx context.Context) ([]string, error) {
@@ -516,7 +849,7 @@ func (rw *raftWrapper) Peers(ctx context.Context) ([]string, error) {
 
 	ids := make([]string, 0)
 
-	configFuture := rw.raft.GetConfiguration()
+	configFuture := rw.r().GetConfiguration()
 	if err := configFuture.Error(); err != nil {
 		return nil, err
 	}
@@ -532,7 +865,7 @@ func (rw *raftWrapper) Peers(ctx context.Context) ([]string, error) {
 // provided basedir.  It returns the snapshot's metadata, and a reader
 // to the snapshot's bytes
 func latestSnapshot(raftDataFolder string) (*hraft.SnapshotMeta, io.ReadCloser, error) {
-	store, err := hraft.NewFileSnapshotStore(raftDataFolder, RaftMaxSnapshots, nil)
+	store, err := hraft.NewFileSnapshotStore(raftDataFolder, DefaultMaxSnapshots, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -605,7 +938,7 @@ func SnapshotSave(cfg *Config, newState state.State, pids []peer.ID) error {
 		srvCfg = makeServerConf(pids)
 	}
 
-	snapshotStore, err := hraft.NewFileSnapshotStoreWithLogger(dataFolder, RaftMaxSnapshots, nil)
+	snapshotStore, err := hraft.NewFileSnapshotStoreWithLogger(dataFolder, cfg.MaxSnapshots, nil)
 	if err != nil {
 		return err
 	}
@@ -667,7 +1000,7 @@ func find(s []string, elem string) bool {
 	return false
 }
 
-func (rw *raftWrapper) observePeers() {
+func (rw *raftWrapper) observePeers(ctx context.Context) {
 	obsCh := make(chan hraft.Observation, 1)
 	defer close(obsCh)
 
@@ -676,8 +1009,8 @@ func (rw *raftWrapper) observePeers() {
 		return ok && po.Removed
 	})
 
-	rw.raft.RegisterObserver(observer)
-	defer rw.raft.DeregisterObserver(observer)
+	rw.r().RegisterObserver(observer)
+	defer rw.r().DeregisterObserver(observer)
 
 	for {
 		select {
@@ -690,9 +1023,161 @@ func (rw *raftWrapper) observePeers() {
 				continue
 			}
 			rw.host.Peerstore().ClearAddrs(pID)
-		case <-rw.ctx.Done():
+		case <-ctx.Done():
 			logger.Debug("stopped observing raft peers")
 			return
 		}
 	}
 }
+
+// observeConfiguration publishes an event for every peer added to or
+// removed from the Raft configuration. Peer observations from raft are only
+// available on the leader, so the configuration is checked regularly
+// instead.
+func (rw *raftWrapper) observeConfiguration(ctx context.Context) {
+	ticker := time.NewTicker(configurationCheckInterval)
+	defer ticker.Stop()
+
+	var known map[hraft.ServerID]struct{}
+	for {
+		configFuture := rw.r().GetConfiguration()
+		if err := configFuture.Error(); err != nil {
+			logger.Debug(err)
+		} else {
+			current := make(map[hraft.ServerID]struct{})
+			for _, server := range configFuture.Configuration().Servers {
+				current[server.ID] = struct{}{}
+			}
+
+			// Peers present on start are not notified.
+			if known != nil {
+				for id := range current {
+					if _, ok := known[id]; !ok {
+						rw.publishPeerEvent(api.ConsensusEventPeerAdd, id)
+					}
+				}
+				for id := range known {
+					if _, ok := current[id]; !ok {
+						rw.publishPeerEvent(api.ConsensusEventPeerRm, id)
+					}
+				}
+			}
+			known = current
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (rw *raftWrapper) publishPeerEvent(t string, id hraft.ServerID) {
+	pid, err := peer.Decode(string(id))
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	rw.events.Publish(api.ConsensusEvent{
+		Type: t,
+		Peer: pid,
+	})
+}
+
+// observeLeader keeps track of the current leader and notifies
+// leadership changes to subscribers.
+func (rw *raftWrapper) observeLeader(ctx context.Context) {
+	// Observations are dropped when the channel is full. We always
+	// read the current leader from raft so that only the
+	// notification, and not the change, can be lost.
+	obsCh := make(chan hraft.Observation, 64)
+	defer close(obsCh)
+
+	observer := hraft.NewObserver(obsCh, false, func(o *hraft.Observation) bool {
+		_, ok := o.Data.(hraft.LeaderObservation)
+		return ok
+	})
+
+	rw.r().RegisterObserver(observer)
+	defer rw.r().DeregisterObserver(observer)
+
+	// A leader may have been elected before registering.
+	rw.updateLeader()
+
+	for {
+		select {
+		case <-obsCh:
+			rw.updateLeader()
+		case <-ctx.Done():
+			logger.Debug("stopped observing raft leader")
+			return
+		}
+	}
+}
+
+// updateLeader sets the current leader and notifies subscribers if it
+// changed.
+func (rw *raftWrapper) updateLeader() {
+	var leader peer.ID
+	if _, lID := rw.r().LeaderWithID(); lID != "" {
+		pid, err := peer.Decode(string(lID))
+		if err != nil {
+			logger.Error(err)
+			return
+		}
+		leader = pid
+	}
+
+	if rw.leader.Swap(leader).(peer.ID) == leader {
+		return
+	}
+	if leader != "" {
+		logger.Infof("Raft leader is now %s", leader)
+	} else {
+		logger.Warn("Raft has no leader")
+	}
+	rw.notifyLeader(leader)
+}
+
+// CurrentLeader returns the last known leader without blocking. It is empty
+// when there is no leader.
+func (rw *raftWrapper) CurrentLeader() peer.ID {
+	return rw.leader.Load().(peer.ID)
+}
+
+func (rw *raftWrapper) notifyLeader(leader peer.ID) {
+	rw.leaderSubsMux.Lock()
+	defer rw.leaderSubsMux.Unlock()
+	for ch := range rw.leaderSubs {
+		// Subscribers only care about the latest leader: replace
+		// any value that has not been read yet.
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- leader
+	}
+}
+
+// SubscribeLeader returns a channel which receives the new leader (or an
+// empty peer ID when the leader is lost) every time leadership changes. The
+// channel is closed when the context is cancelled or raft shuts down.
+func (rw *raftWrapper) SubscribeLeader(ctx context.Context) <-chan peer.ID {
+	ch := make(chan peer.ID, 1)
+	rw.leaderSubsMux.Lock()
+	rw.leaderSubs[ch] = struct{}{}
+	rw.leaderSubsMux.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-rw.ctx.Done():
+		}
+		rw.leaderSubsMux.Lock()
+		delete(rw.leaderSubs, ch)
+		close(ch)
+		rw.leaderSubsMux.Unlock()
+	}()
+	return ch
+}
@@ -0,0 +1,162 @@
+package raft
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"time"
+
+	libp2praft "github.com/libp2p/go-libp2p-raft"
+)
+
+// How often the stall watchdog checks that committed entries are applied.
+var stallCheckInterval = 10 * time.Second
+
+// How long to wait for a stalled Raft instance to shut down when restarting
+// it.
+var stallRestartTimeout = time.Minute
+
+// stallWatch follows the progress of the applied index.
+type stallWatch struct {
+	applied  uint64
+	since    time.Time
+	reported bool
+}
+
+// update records the current applied and commit indexes and returns true
+// when Raft has been stalled for longer than the given timeout: there is a
+// leader and entries are committed, but the applied index has not moved.
+// Every stall is reported once.
+func (w *stallWatch) update(applied, commit uint64, hasLeader bool, now time.Time, timeout time.Duration) bool {
+	if applied != w.applied || commit <= applied || !hasLeader || w.since.IsZero() {
+		*w = stallWatch{applied: applied, since: now}
+		return false
+	}
+	if w.reported || now.Sub(w.since) < timeout {
+		return false
+	}
+	w.reported = true
+	return true
+}
+
+// Launched in NewConsensus as a goroutine when StallTimeout is set. It
+// detects when Raft stops applying committed entries to the state, writes
+// diagnostics to the data folder and, with RestartOnStall, restarts Raft.
+func (cc *Consensus) watchStalls() {
+	ticker := time.NewTicker(stallCheckInterval)
+	defer ticker.Stop()
+
+	var w stallWatch
+	for {
+		select {
+		case <-cc.ctx.Done():
+			return
+		case <-ticker.C:
+			cc.checkStall(&w, time.Now())
+		}
+	}
+}
+
+func (cc *Consensus) checkStall(w *stallWatch, now time.Time) {
+	cc.shutdownLock.RLock()
+	if cc.shutdown {
+		cc.shutdownLock.RUnlock()
+		return
+	}
+	applied := cc.raft.r().AppliedIndex()
+	commit, _ := strconv.ParseUint(cc.raft.r().Stats()["commit_index"], 10, 64)
+	hasLeader := cc.raft.CurrentLeader() != ""
+	cc.shutdownLock.RUnlock()
+
+	if !w.update(applied, commit, hasLeader, now, cc.config.StallTimeout) {
+		return
+	}
+
+	logger.Errorf(
+		"Raft is stalled: no entries applied for %s (applied index: %d, commit index: %d)",
+		now.Sub(w.since).Round(time.Second), applied, commit,
+	)
+	path, err := cc.writeStallDiagnostics(now)
+	if err != nil {
+		logger.Errorf("error writing Raft stall diagnostics: %s", err)
+	} else {
+		logger.Errorf("Raft stall diagnostics written to %s", path)
+	}
+
+	if !cc.config.RestartOnStall {
+		return
+	}
+	logger.Warn("restarting Raft")
+	err = cc.restartRaft()
+	if err != nil {
+		logger.Errorf("error restarting Raft: %s. The peer needs to be restarted", err)
+		return
+	}
+	*w = stallWatch{}
+	logger.Info("Raft restarted")
+}
+
+// restartRaft restarts the Raft instance. Operations are not committed
+// while restarting.
+func (cc *Consensus) restartRaft() error {
+	// Commits may be blocked by the stall. Waiting on the lock would
+	// block everyone else too.
+	deadline := time.Now().Add(stallRestartTimeout)
+	for !cc.shutdownLock.TryLock() {
+		if time.Now().After(deadline) {
+			return errors.New("timed out waiting for ongoing commits")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	defer cc.shutdownLock.Unlock()
+	if cc.shutdown {
+		return nil
+	}
+
+	err := cc.raft.restart(stallRestartTimeout)
+	if err != nil {
+		return err
+	}
+	// The actor commits to the new Raft instance.
+	cc.actor = libp2praft.NewActor(cc.raft.r())
+	cc.consensus.SetActor(cc.actor)
+	return nil
+}
+
+// writeStallDiagnostics writes the Raft stats and the stacks of all
+// goroutines to a file in the Raft data folder.
+func (cc *Consensus) writeStallDiagnostics(t time.Time) (string, error) {
+	path := filepath.Join(
+		cc.config.GetDataFolder(),
+		fmt.Sprintf("stall-%s.txt", t.UTC().Format("20060102T150405Z")),
+	)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	stats := cc.raft.r().Stats()
+	keys := make([]string, 0, len(stats))
+	for k := range stats {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Fprintf(f, "Raft stats at %s:\n\n", t.UTC().Format(time.RFC3339))
+	for _, k := range keys {
+		fmt.Fprintf(f, "%s: %s\n", k, stats[k])
+	}
+	fmt.Fprintf(f, "\nGoroutines:\n\n")
+	err = pprof.Lookup("goroutine").WriteTo(f, 2)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
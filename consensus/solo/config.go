@@ -0,0 +1,112 @@
+package solo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ipfs-cluster/ipfs-cluster/config"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+var configKey = "solo"
+var envConfigKey = "cluster_solo"
+
+// Default configuration values
+var (
+	DefaultDatastoreNamespace = "/s" // from "/solo"
+)
+
+// Config is the configuration object for Consensus.
+type Config struct {
+	config.Saver
+
+	// All keys written to the datastore will be namespaced with this prefix
+	DatastoreNamespace string
+
+	// Tracing enables propagation of contexts across binary boundaries.
+	Tracing bool
+}
+
+type jsonConfig struct {
+	DatastoreNamespace string `json:"datastore_namespace,omitempty"`
+}
+
+// ConfigKey returns the section name for this type of configuration.
+func (cfg *Config) ConfigKey() string {
+	return configKey
+}
+
+// Validate returns an error if the configuration has invalid values.
+func (cfg *Config) Validate() error {
+	if cfg.DatastoreNamespace == "" {
+		return errors.New("solo.datastore_namespace cannot be empty")
+	}
+	return nil
+}
+
+// LoadJSON takes a raw JSON slice and sets all the configuration fields.
+func (cfg *Config) LoadJSON(raw []byte) error {
+	jcfg := &jsonConfig{}
+	err := json.Unmarshal(raw, jcfg)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling %s config", configKey)
+	}
+
+	cfg.Default()
+
+	return cfg.applyJSONConfig(jcfg)
+}
+
+func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
+	config.SetIfNotDefault(jcfg.DatastoreNamespace, &cfg.DatastoreNamespace)
+	return cfg.Validate()
+}
+
+// ToJSON returns the JSON representation of this configuration.
+func (cfg *Config) ToJSON() ([]byte, error) {
+	jcfg := cfg.toJSONConfig()
+
+	return config.DefaultJSONMarshal(jcfg)
+}
+
+// JSONKeys returns the keys of the JSON representation of this
+// configuration, including those omitted when empty.
+func (cfg *Config) JSONKeys() []string {
+	return config.JSONKeys(jsonConfig{})
+}
+
+func (cfg *Config) toJSONConfig() *jsonConfig {
+	jcfg := &jsonConfig{}
+
+	if cfg.DatastoreNamespace != DefaultDatastoreNamespace {
+		jcfg.DatastoreNamespace = cfg.DatastoreNamespace
+		// otherwise leave empty/hidden
+	}
+	return jcfg
+}
+
+// Default sets the configuration fields to their default values.
+func (cfg *Config) Default() error {
+	cfg.DatastoreNamespace = DefaultDatastoreNamespace
+	return nil
+}
+
+// ApplyEnvVars fills in any Config fields found
+// as environment variables.
+func (cfg *Config) ApplyEnvVars() error {
+	jcfg := cfg.toJSONConfig()
+
+	err := envconfig.Process(envConfigKey, jcfg)
+	if err != nil {
+		return err
+	}
+
+	return cfg.applyJSONConfig(jcfg)
+}
+
+// ToDisplayJSON returns JSON config as a string.
+func (cfg *Config) ToDisplayJSON() ([]byte, error) {
+	return config.DisplayJSON(cfg.toJSONConfig())
+}
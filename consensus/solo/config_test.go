@@ -0,0 +1,76 @@
+package solo
+
+import (
+	"os"
+	"testing"
+)
+
+var cfgJSON = []byte(`
+{
+    "datastore_namespace": "/solotest"
+}
+`)
+
+func TestLoadJSON(t *testing.T) {
+	cfg := &Config{}
+	err := cfg.LoadJSON(cfgJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DatastoreNamespace != "/solotest" {
+		t.Error("datastore_namespace was not parsed correctly")
+	}
+
+	cfg = &Config{}
+	err = cfg.LoadJSON([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DatastoreNamespace != DefaultDatastoreNamespace {
+		t.Error("datastore_namespace should be default when unset")
+	}
+}
+
+func TestToJSON(t *testing.T) {
+	cfg := &Config{}
+	cfg.LoadJSON(cfgJSON)
+	newjson, err := cfg.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg = &Config{}
+	err = cfg.LoadJSON(newjson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DatastoreNamespace != "/solotest" {
+		t.Error("datastore_namespace was not saved")
+	}
+}
+
+func TestDefault(t *testing.T) {
+	cfg := &Config{}
+	cfg.Default()
+	if cfg.Validate() != nil {
+		t.Fatal("error validating")
+	}
+
+	cfg.DatastoreNamespace = ""
+	if cfg.Validate() == nil {
+		t.Fatal("expected error validating")
+	}
+}
+
+func TestApplyEnvVars(t *testing.T) {
+	os.Setenv("CLUSTER_SOLO_DATASTORENAMESPACE", "/solo2")
+	defer os.Unsetenv("CLUSTER_SOLO_DATASTORENAMESPACE")
+
+	cfg := &Config{}
+	cfg.Default()
+	cfg.ApplyEnvVars()
+
+	if cfg.DatastoreNamespace != "/solo2" {
+		t.Error("failed to override datastore_namespace with env var")
+	}
+}
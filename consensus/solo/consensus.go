@@ -0,0 +1,324 @@
+// Package solo implements the IPFS Cluster consensus interface for
+// single-peer clusters. Pins are written directly to the state in the
+// datastore, without any replication, leader election or log.
+package solo
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/consensus/events"
+	"github.com/ipfs-cluster/ipfs-cluster/state"
+	"github.com/ipfs-cluster/ipfs-cluster/state/dsstate"
+
+	ds "github.com/ipfs/go-datastore"
+	query "github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log/v2"
+	rpc "github.com/libp2p/go-libp2p-gorpc"
+	host "github.com/libp2p/go-libp2p/core/host"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+
+	trace "go.opencensus.io/trace"
+)
+
+var logger = logging.Logger("solo")
+
+// ErrSinglePeer is returned when trying to modify the peerset.
+var ErrSinglePeer = errors.New("solo consensus component only supports a single peer")
+
+// Consensus implements ipfscluster.Consensus for a cluster made of a single
+// peer. This peer is always the leader and the only trusted peer.
+type Consensus struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	config *Config
+
+	host  host.Host
+	store ds.Datastore
+	state state.State
+
+	rpcClient *rpc.Client
+	rpcReady  chan struct{}
+	readyCh   chan struct{}
+
+	events *events.Bus
+
+	shutdownLock sync.RWMutex
+	shutdown     bool
+}
+
+// New creates a new solo Consensus component. The given thread-safe
+// datastore will be used to persist the state, with all keys prefixed
+// with cfg.DatastoreNamespace.
+func New(host host.Host, cfg *Config, store ds.Datastore) (*Consensus, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	st, err := dsstate.New(ctx, store, cfg.DatastoreNamespace, dsstate.DefaultHandle())
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	cc := &Consensus{
+		ctx:      ctx,
+		cancel:   cancel,
+		config:   cfg,
+		host:     host,
+		store:    store,
+		state:    st,
+		rpcReady: make(chan struct{}, 1),
+		readyCh:  make(chan struct{}, 1),
+		events:   events.NewBus(),
+	}
+
+	go cc.setup()
+	return cc, nil
+}
+
+func (cc *Consensus) setup() {
+	select {
+	case <-cc.ctx.Done():
+		return
+	case <-cc.rpcReady:
+	}
+	cc.readyCh <- struct{}{}
+}
+
+// Shutdown stops the component. The datastore is not closed.
+func (cc *Consensus) Shutdown(ctx context.Context) error {
+	cc.shutdownLock.Lock()
+	defer cc.shutdownLock.Unlock()
+
+	if cc.shutdown {
+		logger.Debug("already shutdown")
+		return nil
+	}
+	cc.shutdown = true
+
+	logger.Info("stopping Consensus component")
+	cc.cancel()
+	cc.events.Close()
+	return nil
+}
+
+// SetClient gives the component the ability to communicate and
+// leaves it ready to use.
+func (cc *Consensus) SetClient(c *rpc.Client) {
+	cc.rpcClient = c
+	cc.rpcReady <- struct{}{}
+}
+
+// Ready returns a channel which is signaled when the component
+// is ready to use.
+func (cc *Consensus) Ready(ctx context.Context) <-chan struct{} {
+	return cc.readyCh
+}
+
+// LogPin adds a pin to the state and tells the PinTracker to track it.
+func (cc *Consensus) LogPin(ctx context.Context, pin api.Pin) error {
+	ctx, span := trace.StartSpan(ctx, "consensus/LogPin")
+	defer span.End()
+
+	err := cc.state.Add(ctx, pin)
+	if err != nil {
+		return err
+	}
+	cc.events.Publish(api.ConsensusEvent{
+		Type: api.ConsensusEventPin,
+		Pin:  pin,
+	})
+	// Async, we let the PinTracker take care of any problems
+	cc.rpcClient.GoContext(
+		cc.ctx,
+		"",
+		"PinTracker",
+		"Track",
+		pin,
+		&struct{}{},
+		nil,
+	)
+	return nil
+}
+
+// LogUnpin removes a pin from the state and tells the PinTracker to
+// untrack it.
+func (cc *Consensus) LogUnpin(ctx context.Context, pin api.Pin) error {
+	ctx, span := trace.StartSpan(ctx, "consensus/LogUnpin")
+	defer span.End()
+
+	err := cc.state.Rm(ctx, pin.Cid)
+	if err != nil {
+		return err
+	}
+	cc.events.Publish(api.ConsensusEvent{
+		Type: api.ConsensusEventUnpin,
+		Pin:  pin,
+	})
+	// Async, we let the PinTracker take care of any problems
+	cc.rpcClient.GoContext(
+		cc.ctx,
+		"",
+		"PinTracker",
+		"Untrack",
+		pin,
+		&struct{}{},
+		nil,
+	)
+	return nil
+}
+
+// LogSchedule adds a pin schedule to the state, replacing any with the same
+// ID.
+func (cc *Consensus) LogSchedule(ctx context.Context, s api.PinSchedule) error {
+	ctx, span := trace.StartSpan(ctx, "consensus/LogSchedule")
+	defer span.End()
+
+	return cc.state.AddSchedule(ctx, s)
+}
+
+// LogUnschedule removes a pin schedule from the state.
+func (cc *Consensus) LogUnschedule(ctx context.Context, id string) error {
+	ctx, span := trace.StartSpan(ctx, "consensus/LogUnschedule")
+	defer span.End()
+
+	return cc.state.RmSchedule(ctx, id)
+}
+
+// AddPeer returns ErrSinglePeer unless the given peer is this one.
+func (cc *Consensus) AddPeer(ctx context.Context, pid peer.ID) error {
+	if pid == cc.host.ID() {
+		return nil
+	}
+	return ErrSinglePeer
+}
+
+// RmPeer returns ErrSinglePeer unless the given peer is this one.
+func (cc *Consensus) RmPeer(ctx context.Context, pid peer.ID) error {
+	if pid == cc.host.ID() {
+		return nil
+	}
+	return ErrSinglePeer
+}
+
+// State returns the cluster state.
+func (cc *Consensus) State(ctx context.Context) (state.ReadOnly, error) {
+	return cc.state, nil
+}
+
+// Leader returns this peer.
+func (cc *Consensus) Leader(ctx context.Context) (peer.ID, error) {
+	return cc.host.ID(), nil
+}
+
+// WaitForLeader returns this peer.
+func (cc *Consensus) WaitForLeader(ctx context.Context) (peer.ID, error) {
+	return cc.host.ID(), nil
+}
+
+// SubscribeLeader returns a channel that receives this peer, which never
+// loses the leadership. It is closed when the context is cancelled.
+func (cc *Consensus) SubscribeLeader(ctx context.Context) <-chan peer.ID {
+	ch := make(chan peer.ID, 1)
+	ch <- cc.host.ID()
+	go func() {
+		<-ctx.Done()
+		close(ch)
+	}()
+	return ch
+}
+
+// SubscribeEvents returns a channel which receives an event for every pin
+// added to or removed from the state. The channel is closed when the
+// context is cancelled or the component shuts down.
+func (cc *Consensus) SubscribeEvents(ctx context.Context) <-chan api.ConsensusEvent {
+	return cc.events.Subscribe(ctx)
+}
+
+// WaitForSync is a no-op as the state is always up to date.
+func (cc *Consensus) WaitForSync(ctx context.Context) error { return nil }
+
+// CatchUpProgress returns an empty progress, as there is nothing to catch
+// up with.
+func (cc *Consensus) CatchUpProgress(ctx context.Context) api.CatchUpProgress {
+	return api.CatchUpProgress{}
+}
+
+// Stats returns the leader role, since this peer is always the leader.
+func (cc *Consensus) Stats(ctx context.Context) api.ConsensusStats {
+	return api.ConsensusStats{
+		Role: api.ConsensusRoleLeader,
+	}
+}
+
+// Peers returns this peer.
+func (cc *Consensus) Peers(ctx context.Context) ([]peer.ID, error) {
+	return []peer.ID{cc.host.ID()}, nil
+}
+
+// IsTrustedPeer returns true only for this peer.
+func (cc *Consensus) IsTrustedPeer(ctx context.Context, pid peer.ID) bool {
+	return pid == cc.host.ID()
+}
+
+// IsNonVoter returns false.
+func (cc *Consensus) IsNonVoter(ctx context.Context) bool {
+	return false
+}
+
+// Trust is a no-op. Only this peer is trusted.
+func (cc *Consensus) Trust(ctx context.Context, pid peer.ID) error {
+	return nil
+}
+
+// Distrust is a no-op. Only this peer is trusted.
+func (cc *Consensus) Distrust(ctx context.Context, pid peer.ID) error {
+	return nil
+}
+
+// Clean deletes the state from the datastore.
+func (cc *Consensus) Clean(ctx context.Context) error {
+	return Clean(ctx, cc.config, cc.store)
+}
+
+// Clean deletes the solo-consensus state from the given datastore.
+func Clean(ctx context.Context, cfg *Config, store ds.Datastore) error {
+	logger.Info("cleaning all solo state from datastore")
+	q := query.Query{
+		Prefix:   cfg.DatastoreNamespace,
+		KeysOnly: true,
+	}
+
+	results, err := store.Query(ctx, q)
+	if err != nil {
+		return err
+	}
+	defer results.Close()
+
+	for r := range results.Next() {
+		if r.Error != nil {
+			return r.Error
+		}
+		k := ds.NewKey(r.Key)
+		err := store.Delete(ctx, k)
+		if err != nil {
+			// do not die, continue cleaning
+			logger.Error(err)
+		}
+	}
+	return nil
+}
+
+// OfflineState returns the state stored in the given datastore. As the
+// peer uses the same state when running, this allows to inspect and modify
+// it in offline mode.
+func OfflineState(cfg *Config, store ds.Datastore) (state.State, error) {
+	return dsstate.New(context.Background(), store, cfg.DatastoreNamespace, dsstate.DefaultHandle())
+}
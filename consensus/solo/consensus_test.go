@@ -0,0 +1,176 @@
+package solo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/datastore/inmem"
+	"github.com/ipfs-cluster/ipfs-cluster/test"
+
+	ds "github.com/ipfs/go-datastore"
+	libp2p "github.com/libp2p/go-libp2p"
+)
+
+func testingConsensus(t *testing.T, store ds.Datastore) *Consensus {
+	h, err := libp2p.New(libp2p.NoListenAddrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Close() })
+
+	cfg := &Config{}
+	cfg.Default()
+	cc, err := New(h, cfg, store)
+	if err != nil {
+		t.Fatal("cannot create Consensus:", err)
+	}
+	cc.SetClient(test.NewMockRPCClientWithHost(t, h))
+	<-cc.Ready(context.Background())
+	return cc
+}
+
+func testPin(c api.Cid) api.Pin {
+	p := api.PinCid(c)
+	p.ReplicationFactorMin = -1
+	p.ReplicationFactorMax = -1
+	return p
+}
+
+func listPins(t *testing.T, cc *Consensus) []api.Pin {
+	ctx := context.Background()
+	st, err := cc.State(ctx)
+	if err != nil {
+		t.Fatal("error getting state:", err)
+	}
+
+	out := make(chan api.Pin, 10)
+	err = st.List(ctx, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pins []api.Pin
+	for p := range out {
+		pins = append(pins, p)
+	}
+	return pins
+}
+
+func TestShutdownConsensus(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, inmem.New())
+	err := cc.Shutdown(ctx)
+	if err != nil {
+		t.Fatal("Consensus cannot shutdown:", err)
+	}
+	err = cc.Shutdown(ctx) // should be fine to shutdown twice
+	if err != nil {
+		t.Fatal("Consensus should be able to shutdown several times")
+	}
+}
+
+func TestConsensusPinUnpin(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, inmem.New())
+	defer cc.Shutdown(ctx)
+
+	events := cc.SubscribeEvents(ctx)
+
+	err := cc.LogPin(ctx, testPin(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pins := listPins(t, cc)
+	if len(pins) != 1 || !pins[0].Cid.Equals(test.Cid1) {
+		t.Error("the added pin should be in the state")
+	}
+	if ev := <-events; ev.Type != api.ConsensusEventPin || !ev.Pin.Cid.Equals(test.Cid1) {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+
+	err = cc.LogUnpin(ctx, api.PinCid(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pins := listPins(t, cc); len(pins) != 0 {
+		t.Error("the pin should have been removed")
+	}
+	if ev := <-events; ev.Type != api.ConsensusEventUnpin {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+}
+
+func TestConsensusPersistence(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.New()
+	cc := testingConsensus(t, store)
+	err := cc.LogPin(ctx, testPin(test.Cid1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc.Shutdown(ctx)
+
+	cc = testingConsensus(t, store)
+	defer cc.Shutdown(ctx)
+	if pins := listPins(t, cc); len(pins) != 1 {
+		t.Fatal("the state should have been kept in the datastore")
+	}
+
+	offline, err := OfflineState(cc.config, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := offline.Has(ctx, test.Cid1); !ok {
+		t.Error("the offline state should have the pin")
+	}
+
+	err = cc.Clean(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pins := listPins(t, cc); len(pins) != 0 {
+		t.Error("the state should have been cleaned")
+	}
+}
+
+func TestConsensusPeers(t *testing.T) {
+	ctx := context.Background()
+	cc := testingConsensus(t, inmem.New())
+	defer cc.Shutdown(ctx)
+
+	self := cc.host.ID()
+	peers, err := cc.Peers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 1 || peers[0] != self {
+		t.Error("the only peer should be this one")
+	}
+
+	leader, err := cc.Leader(ctx)
+	if err != nil || leader != self {
+		t.Error("this peer should be the leader")
+	}
+
+	if !cc.IsTrustedPeer(ctx, self) || cc.IsTrustedPeer(ctx, test.PeerID1) {
+		t.Error("only this peer should be trusted")
+	}
+
+	if err := cc.AddPeer(ctx, test.PeerID1); err != ErrSinglePeer {
+		t.Error("expected an error adding a peer:", err)
+	}
+	if err := cc.RmPeer(ctx, test.PeerID1); err != ErrSinglePeer {
+		t.Error("expected an error removing a peer:", err)
+	}
+
+	subCtx, cancel := context.WithCancel(ctx)
+	leaders := cc.SubscribeLeader(subCtx)
+	if l := <-leaders; l != self {
+		t.Error("expected this peer as leader")
+	}
+	cancel()
+	if _, ok := <-leaders; ok {
+		t.Error("channel should be closed")
+	}
+}
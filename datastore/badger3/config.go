@@ -327,6 +327,12 @@ func (cfg *Config) ToJSON() (raw []byte, err error) {
 	return
 }
 
+// JSONKeys returns the keys of the JSON representation of this
+// configuration, including those omitted when empty.
+func (cfg *Config) JSONKeys() []string {
+	return config.JSONKeys(jsonConfig{})
+}
+
 func (cfg *Config) toJSONConfig() *jsonConfig {
 	jCfg := &jsonConfig{}
 
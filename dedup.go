@@ -0,0 +1,234 @@
+package ipfscluster
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	rpc "github.com/libp2p/go-libp2p-gorpc"
+	trace "go.opencensus.io/trace"
+)
+
+const (
+	// maximum number of blocks listed for each sampled pin.
+	dedupMaxRefsPerPin = 10000
+	// number of blocks whose size is checked to estimate the average
+	// block size.
+	dedupBlockSizeSamples = 50
+)
+
+var errNoDedupStats = errors.New("deduplication statistics have not been computed. Check cluster.dedup_stats_interval")
+
+// dedupStats holds the last deduplication statistics computed by this
+// peer.
+type dedupStats struct {
+	mux   sync.RWMutex
+	stats api.DedupStats
+}
+
+func (ds *dedupStats) get() api.DedupStats {
+	ds.mux.RLock()
+	defer ds.mux.RUnlock()
+	return ds.stats
+}
+
+func (ds *dedupStats) set(stats api.DedupStats) {
+	ds.mux.Lock()
+	defer ds.mux.Unlock()
+	ds.stats = stats
+}
+
+// watchDedupStats periodically computes deduplication statistics.
+func (c *Cluster) watchDedupStats() {
+	if c.config.DedupStatsInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.config.DedupStatsInterval)
+	defer ticker.Stop()
+	for {
+		stats, err := c.computeDedupStats(c.ctx)
+		if err != nil {
+			logger.Errorf("error computing deduplication statistics: %s", err)
+			stats.Error = err.Error()
+		}
+		c.dedup.set(stats)
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// computeDedupStats lists the blocks of a random sample of the pins
+// allocated to this peer and counts how many of them are shared.
+func (c *Cluster) computeDedupStats(ctx context.Context) (api.DedupStats, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/computeDedupStats")
+	defer span.End()
+
+	stats := api.DedupStats{
+		Peer:      c.id,
+		Peername:  c.config.Peername,
+		Timestamp: time.Now(),
+	}
+
+	sample, total, err := c.sampleLocalPins(ctx, c.config.DedupStatsSampleSize)
+	if err != nil {
+		return stats, err
+	}
+	stats.Pins = total
+
+	blocks := make(map[api.Cid]int)
+	for _, pin := range sample {
+		refs, err := c.ipfs.Refs(ctx, pin.Cid, dedupMaxRefsPerPin)
+		if err != nil {
+			logger.Warnf("deduplication statistics: error listing blocks for %s: %s", pin.Cid, err)
+			continue
+		}
+		if len(refs) >= dedupMaxRefsPerPin {
+			stats.Truncated = true
+		}
+		stats.SampledPins++
+
+		// refs are unique per pin and do not include the root.
+		blocks[pin.Cid]++
+		for _, ref := range refs {
+			blocks[ref]++
+		}
+		stats.TotalBlocks += uint64(len(refs) + 1)
+	}
+
+	stats.UniqueBlocks = uint64(len(blocks))
+	sizeSamples := make([]api.Cid, 0, dedupBlockSizeSamples)
+	for b, count := range blocks {
+		if count > 1 {
+			stats.SharedBlocks++
+		}
+		// map iteration order is random enough for sampling.
+		if len(sizeSamples) < dedupBlockSizeSamples {
+			sizeSamples = append(sizeSamples, b)
+		}
+	}
+
+	var sampledBytes, sampledBlocks uint64
+	for _, b := range sizeSamples {
+		stat, err := c.ipfs.BlockStat(ctx, b)
+		if err != nil {
+			logger.Debugf("deduplication statistics: error getting block size for %s: %s", b, err)
+			continue
+		}
+		sampledBytes += uint64(stat.Size)
+		sampledBlocks++
+	}
+	if sampledBlocks > 0 {
+		avg := sampledBytes / sampledBlocks
+		stats.TotalBytes = avg * stats.TotalBlocks
+		stats.UniqueBytes = avg * stats.UniqueBlocks
+	}
+
+	logger.Debugf(
+		"deduplication statistics: %d unique blocks out of %d in %d sampled pins",
+		stats.UniqueBlocks, stats.TotalBlocks, stats.SampledPins,
+	)
+	return stats, nil
+}
+
+// sampleLocalPins returns a random sample of at most n non-meta pins
+// allocated to this peer, along with the total number of them.
+func (c *Cluster) sampleLocalPins(ctx context.Context, n int) ([]api.Pin, int, error) {
+	cState, err := c.consensus.State(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := make(chan api.Pin, 1024)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cState.List(ctx, out)
+	}()
+
+	// reservoir sampling
+	sample := make([]api.Pin, 0, n)
+	total := 0
+	for pin := range out {
+		if pin.Type != api.DataType && pin.Type != api.ShardType {
+			continue
+		}
+		if pin.IsRemotePin(c.id) {
+			continue
+		}
+		total++
+		if len(sample) < n {
+			sample = append(sample, pin)
+			continue
+		}
+		if i := rand.Intn(total); i < n {
+			sample[i] = pin
+		}
+	}
+	return sample, total, <-errCh
+}
+
+// DedupStatsLocal returns the last deduplication statistics computed by this
+// peer.
+func (c *Cluster) DedupStatsLocal(ctx context.Context) (api.DedupStats, error) {
+	_, span := trace.StartSpan(ctx, "cluster/DedupStatsLocal")
+	defer span.End()
+
+	stats := c.dedup.get()
+	if stats.Timestamp.IsZero() {
+		return api.DedupStats{}, errNoDedupStats
+	}
+	return stats, nil
+}
+
+// DedupStats returns the last deduplication statistics computed by every
+// cluster peer.
+func (c *Cluster) DedupStats(ctx context.Context) (api.GlobalDedupStats, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/DedupStats")
+	defer span.End()
+
+	members, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Error(err)
+		return api.GlobalDedupStats{}, err
+	}
+
+	global := api.GlobalDedupStats{PeerMap: make(map[string]api.DedupStats)}
+
+	for _, member := range members {
+		var stats api.DedupStats
+		err = c.rpcClient.CallContext(
+			ctx,
+			member,
+			"Cluster",
+			"DedupStatsLocal",
+			struct{}{},
+			&stats,
+		)
+		if err == nil {
+			global.PeerMap[member.String()] = stats
+			continue
+		}
+
+		if rpc.IsAuthorizationError(err) {
+			logger.Debug("rpc auth error:", err)
+			continue
+		}
+
+		pv := pingValueFromMetric(c.monitor.LatestForPeer(ctx, pingMetricName, member))
+		global.PeerMap[member.String()] = api.DedupStats{
+			Peer:     member,
+			Peername: pv.Peername,
+			Error:    err.Error(),
+		}
+	}
+
+	return global, nil
+}
@@ -0,0 +1,254 @@
+// Package dnsresolver builds DNS resolvers for multiaddresses and HTTP
+// clients which use custom DNS servers, including DNS-over-HTTPS endpoints,
+// instead of the system ones. Different resolvers can be used for specific
+// domains, which is useful in air-gapped networks with internal DNS.
+package dnsresolver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/miekg/dns"
+	madns "github.com/multiformats/go-multiaddr-dns"
+)
+
+var logger = logging.Logger("dnsresolver")
+
+// DefaultDomain is the domain key used to set the resolver used for all
+// domains without a more specific resolver.
+const DefaultDomain = "."
+
+// maximum size of a DNS message.
+const maxMessageSize = 65535
+
+// client used for DNS-over-HTTPS requests.
+var dohClient = &http.Client{Timeout: 10 * time.Second}
+
+// ParseResolvers parses a comma-separated list of "domain=resolver"
+// definitions. A definition without domain (just "resolver") sets the
+// default resolver.
+func ParseResolvers(s string) (map[string]string, error) {
+	resolvers := make(map[string]string)
+	for _, def := range strings.Split(s, ",") {
+		def = strings.TrimSpace(def)
+		if def == "" {
+			continue
+		}
+		domain, rslv, ok := strings.Cut(def, "=")
+		if !ok {
+			domain, rslv = DefaultDomain, def
+		}
+		domain = strings.TrimSpace(domain)
+		rslv = strings.TrimSpace(rslv)
+		if domain == "" || rslv == "" {
+			return nil, fmt.Errorf("bad resolver definition: %q", def)
+		}
+		resolvers[domain] = rslv
+	}
+	return resolvers, nil
+}
+
+// New returns a multiaddress resolver using the given resolvers, indexed by
+// domain. The DefaultDomain key sets the resolver for any domain not
+// explicitly listed. When not set, the system resolver is used for them.
+//
+// Resolvers are given as "https://..." URLs for DNS-over-HTTPS (RFC 8484)
+// endpoints or as "host[:port]" for regular DNS servers.
+func New(resolvers map[string]string) (*madns.Resolver, error) {
+	domains := make([]string, 0, len(resolvers))
+	for d := range resolvers {
+		domains = append(domains, d)
+	}
+	sort.Strings(domains)
+
+	var opts []madns.Option
+	for _, domain := range domains {
+		rslv, err := newBasicResolver(resolvers[domain])
+		if err != nil {
+			return nil, fmt.Errorf("resolver for %s: %w", domain, err)
+		}
+		if domain == DefaultDomain {
+			opts = append(opts, madns.WithDefaultResolver(rslv))
+			continue
+		}
+		opts = append(opts, madns.WithDomainResolver(dns.Fqdn(domain), rslv))
+	}
+	return madns.NewResolver(opts...)
+}
+
+// Setup creates a resolver with New and sets it as the default multiaddress
+// resolver (madns.DefaultResolver), which is used when resolving dns4, dns6
+// and dnsaddr multiaddresses.
+func Setup(resolvers map[string]string) (*madns.Resolver, error) {
+	rslv, err := New(resolvers)
+	if err != nil {
+		return nil, err
+	}
+	madns.DefaultResolver = rslv
+	for domain, r := range resolvers {
+		logger.Infof("using DNS resolver %s for domain %s", r, domain)
+	}
+	return rslv, nil
+}
+
+// HTTPClient returns an HTTP client that resolves hostnames with the given
+// resolver.
+func HTTPClient(rslv madns.BasicResolver) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = DialContext(rslv)
+	return &http.Client{Transport: transport}
+}
+
+// DialContext returns a dial function that resolves the host in the
+// address with the given resolver.
+func DialContext(rslv madns.BasicResolver) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	var dialer net.Dialer
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		ips, err := rslv.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var lastErr error = &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+func newBasicResolver(rslv string) (madns.BasicResolver, error) {
+	if strings.HasPrefix(rslv, "https://") {
+		return &dohResolver{
+			url:    rslv,
+			client: dohClient,
+		}, nil
+	}
+
+	server := rslv
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		return nil, fmt.Errorf("bad DNS server address %q: %w", rslv, err)
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}, nil
+}
+
+// dohResolver implements madns.BasicResolver using a DNS-over-HTTPS
+// endpoint.
+type dohResolver struct {
+	url    string
+	client *http.Client
+}
+
+func (r *dohResolver) exchange(ctx context.Context, name string, qtype uint16) ([]dns.RR, error) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.Id = 0 // recommended by RFC 8484 for cache friendliness
+	query, err := msg.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DNS-over-HTTPS request to %s failed: %s", r.url, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxMessageSize))
+	if err != nil {
+		return nil, err
+	}
+	reply := new(dns.Msg)
+	if err := reply.Unpack(body); err != nil {
+		return nil, err
+	}
+
+	switch reply.Rcode {
+	case dns.RcodeSuccess:
+		return reply.Answer, nil
+	case dns.RcodeNameError:
+		return nil, &net.DNSError{Err: "no such host", Name: name, Server: r.url, IsNotFound: true}
+	default:
+		return nil, &net.DNSError{Err: dns.RcodeToString[reply.Rcode], Name: name, Server: r.url}
+	}
+}
+
+// LookupIPAddr resolves the A and AAAA records for a domain.
+func (r *dohResolver) LookupIPAddr(ctx context.Context, domain string) ([]net.IPAddr, error) {
+	var addrs []net.IPAddr
+	var firstErr error
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		answer, err := r.exchange(ctx, domain, qtype)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, rr := range answer {
+			switch rec := rr.(type) {
+			case *dns.A:
+				addrs = append(addrs, net.IPAddr{IP: rec.A})
+			case *dns.AAAA:
+				addrs = append(addrs, net.IPAddr{IP: rec.AAAA})
+			}
+		}
+	}
+	if len(addrs) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return addrs, nil
+}
+
+// LookupTXT resolves the TXT records for a domain.
+func (r *dohResolver) LookupTXT(ctx context.Context, domain string) ([]string, error) {
+	answer, err := r.exchange(ctx, domain, dns.TypeTXT)
+	if err != nil {
+		return nil, err
+	}
+	var txts []string
+	for _, rr := range answer {
+		if rec, ok := rr.(*dns.TXT); ok {
+			txts = append(txts, strings.Join(rec.Txt, ""))
+		}
+	}
+	return txts, nil
+}
@@ -0,0 +1,135 @@
+package dnsresolver
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/miekg/dns"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+func TestParseResolvers(t *testing.T) {
+	res, err := ParseResolvers("https://doh.example/dns-query, corp.internal=10.0.0.53")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res[DefaultDomain] != "https://doh.example/dns-query" {
+		t.Error("default resolver not parsed")
+	}
+	if res["corp.internal"] != "10.0.0.53" {
+		t.Error("domain resolver not parsed")
+	}
+
+	_, err = ParseResolvers("corp.internal=")
+	if err == nil {
+		t.Error("expected an error with an empty resolver")
+	}
+}
+
+func testDoHServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/dns-message" {
+			http.Error(w, "bad content type", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		query := new(dns.Msg)
+		if err := query.Unpack(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		reply := new(dns.Msg)
+		reply.SetReply(query)
+		q := query.Question[0]
+		switch {
+		case q.Name == "peer.corp.internal." && q.Qtype == dns.TypeA:
+			rr, _ := dns.NewRR("peer.corp.internal. 60 IN A 10.1.2.3")
+			reply.Answer = append(reply.Answer, rr)
+		case q.Name == "_dnsaddr.corp.internal." && q.Qtype == dns.TypeTXT:
+			rr, _ := dns.NewRR(`_dnsaddr.corp.internal. 60 IN TXT "dnsaddr=/ip4/10.1.2.3/tcp/9096"`)
+			reply.Answer = append(reply.Answer, rr)
+		case q.Name != "peer.corp.internal.":
+			reply.Rcode = dns.RcodeNameError
+		}
+		out, _ := reply.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(out)
+	}))
+	dohClient = srv.Client()
+	return srv
+}
+
+func TestDoHResolver(t *testing.T) {
+	ctx := context.Background()
+	srv := testDoHServer(t)
+	defer srv.Close()
+
+	rslv, err := newBasicResolver(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doh := rslv.(*dohResolver)
+
+	addrs, err := doh.LookupIPAddr(ctx, "peer.corp.internal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || !addrs[0].IP.Equal(net.ParseIP("10.1.2.3")) {
+		t.Errorf("unexpected addresses: %v", addrs)
+	}
+
+	_, err = doh.LookupIPAddr(ctx, "missing.corp.internal")
+	if dnsErr, ok := err.(*net.DNSError); !ok || !dnsErr.IsNotFound {
+		t.Errorf("expected a not found error: %v", err)
+	}
+
+	txts, err := doh.LookupTXT(ctx, "_dnsaddr.corp.internal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txts) != 1 || txts[0] != "dnsaddr=/ip4/10.1.2.3/tcp/9096" {
+		t.Errorf("unexpected TXT records: %v", txts)
+	}
+}
+
+func TestNewDomainResolver(t *testing.T) {
+	ctx := context.Background()
+	srv := testDoHServer(t)
+	defer srv.Close()
+
+	mres, err := New(map[string]string{
+		"corp.internal": srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := ma.StringCast("/dns4/peer.corp.internal/tcp/9096")
+	resolved, err := mres.Resolve(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolved) != 1 || resolved[0].String() != "/ip4/10.1.2.3/tcp/9096" {
+		t.Errorf("unexpected resolution: %v", resolved)
+	}
+}
+
+func TestDialContext(t *testing.T) {
+	srv := testDoHServer(t)
+	defer srv.Close()
+
+	rslv, err := New(map[string]string{DefaultDomain: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dial := DialContext(rslv)
+	_, err = dial(context.Background(), "tcp", "missing.corp.internal:80")
+	if err == nil {
+		t.Error("expected an error dialing an unresolvable host")
+	}
+}
@@ -0,0 +1,326 @@
+package ipfscluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	trace "go.opencensus.io/trace"
+)
+
+// eventsNamespace is the datastore namespace where cluster events are
+// stored.
+const eventsNamespace = "/events"
+
+// eventLog is a capped history of cluster events. Events are stored under
+// their ID, which grows with every event, so that they can be read back in
+// order. The oldest events are removed when there are more than size
+// events or when they are older than the retention period.
+type eventLog struct {
+	store     ds.Datastore
+	peer      peer.ID
+	size      int
+	retention time.Duration
+
+	mu sync.Mutex
+	// IDs of the oldest and newest events in the store. The log is
+	// empty when first > last.
+	first uint64
+	last  uint64
+	// changed is closed and replaced every time an event is recorded,
+	// waking up those following the log.
+	changed chan struct{}
+}
+
+func newEventLog(ctx context.Context, store ds.Datastore, p peer.ID, size int, retention time.Duration) *eventLog {
+	el := &eventLog{
+		store:     namespace.Wrap(store, ds.NewKey(eventsNamespace)),
+		peer:      p,
+		size:      size,
+		retention: retention,
+		first:     1,
+		changed:   make(chan struct{}),
+	}
+	if err := el.load(ctx); err != nil {
+		logger.Errorf("error loading the cluster event history: %s", err)
+	}
+	return el
+}
+
+func (el *eventLog) key(id uint64) ds.Key {
+	// zero-padded so that keys sort like IDs.
+	return ds.NewKey(fmt.Sprintf("%020d", id))
+}
+
+// load finds the IDs of the oldest and newest stored events.
+func (el *eventLog) load(ctx context.Context) error {
+	results, err := el.store.Query(ctx, query.Query{KeysOnly: true})
+	if err != nil {
+		return err
+	}
+	defer results.Close()
+
+	var first, last uint64
+	for r := range results.Next() {
+		if r.Error != nil {
+			return r.Error
+		}
+		id, err := strconv.ParseUint(strings.TrimPrefix(r.Key, "/"), 10, 64)
+		if err != nil {
+			continue
+		}
+		if first == 0 || id < first {
+			first = id
+		}
+		if id > last {
+			last = id
+		}
+	}
+	if last > 0 {
+		el.first, el.last = first, last
+	}
+	return nil
+}
+
+// record stores an event, setting its ID, Peer and Timestamp, and removes
+// the events which no longer fit in the history.
+func (el *eventLog) record(ctx context.Context, ev api.ClusterEvent) error {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+
+	ev.ID = el.last + 1
+	ev.Peer = el.peer
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now()
+	}
+	v, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if err := el.store.Put(ctx, el.key(ev.ID), v); err != nil {
+		return err
+	}
+	el.last = ev.ID
+	close(el.changed)
+	el.changed = make(chan struct{})
+	return el.trim(ctx)
+}
+
+// trim removes the events over the size of the history and those older
+// than the retention period. It must be called with the lock held.
+func (el *eventLog) trim(ctx context.Context) error {
+	for el.last-el.first+1 > uint64(el.size) {
+		if err := el.store.Delete(ctx, el.key(el.first)); err != nil {
+			return err
+		}
+		el.first++
+	}
+
+	if el.retention <= 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-el.retention)
+	for el.first <= el.last {
+		ev, err := el.get(ctx, el.first)
+		if err != nil && err != ds.ErrNotFound {
+			return err
+		}
+		if err == nil && !ev.Timestamp.Before(cutoff) {
+			return nil
+		}
+		if err := el.store.Delete(ctx, el.key(el.first)); err != nil {
+			return err
+		}
+		el.first++
+	}
+	return nil
+}
+
+func (el *eventLog) get(ctx context.Context, id uint64) (api.ClusterEvent, error) {
+	var ev api.ClusterEvent
+	v, err := el.store.Get(ctx, el.key(id))
+	if err != nil {
+		return ev, err
+	}
+	err = json.Unmarshal(v, &ev)
+	return ev, err
+}
+
+// history sends the events selected by the query, oldest first, on the
+// given channel.
+func (el *eventLog) history(ctx context.Context, q api.ClusterEventQuery, out chan<- api.ClusterEvent) error {
+	el.mu.Lock()
+	first, last := el.first, el.last
+	el.mu.Unlock()
+
+	return el.send(ctx, q, first, last, out)
+}
+
+// follow sends the events selected by the query like history, and then the
+// new matching events as they are recorded, until the context is
+// cancelled. The query Limit is ignored.
+func (el *eventLog) follow(ctx context.Context, q api.ClusterEventQuery, out chan<- api.ClusterEvent) error {
+	q.Limit = 0
+	for {
+		el.mu.Lock()
+		first, last, changed := el.first, el.last, el.changed
+		el.mu.Unlock()
+
+		if err := el.send(ctx, q, first, last, out); err != nil {
+			return err
+		}
+		if last > q.After {
+			q.After = last
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// send sends the events between the first and last IDs which are selected
+// by the query.
+func (el *eventLog) send(ctx context.Context, q api.ClusterEventQuery, first, last uint64, out chan<- api.ClusterEvent) error {
+	if q.After >= first {
+		first = q.After + 1
+	}
+	var n int
+	for id := first; id <= last; id++ {
+		ev, err := el.get(ctx, id)
+		if err == ds.ErrNotFound { // trimmed in the meantime
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !q.Match(ev) {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case out <- ev:
+		}
+		n++
+		if q.Limit > 0 && n >= q.Limit {
+			return nil
+		}
+	}
+	return nil
+}
+
+// RecordEvent adds an event to the cluster event history of this peer.
+// The event ID, Peer and Timestamp are set automatically.
+func (c *Cluster) RecordEvent(ctx context.Context, ev api.ClusterEvent) {
+	if err := c.events.record(ctx, ev); err != nil {
+		logger.Errorf("error recording %s event: %s", ev.Type, err)
+	}
+}
+
+// EventHistory sends the events in the cluster event history of this peer
+// which match the given query on the out channel, oldest first. The
+// channel is closed when done.
+func (c *Cluster) EventHistory(ctx context.Context, q api.ClusterEventQuery, out chan<- api.ClusterEvent) error {
+	defer close(out)
+
+	ctx, span := trace.StartSpan(ctx, "cluster/EventHistory")
+	defer span.End()
+
+	return c.events.history(ctx, q, out)
+}
+
+// Events sends the events in the cluster event history of this peer which
+// match the given query on the out channel, oldest first, and then every
+// new matching event as it is recorded, until the context is cancelled.
+// The IDs of the events can be given as the After field of a later query
+// to resume the stream. The query Limit is ignored. The channel is closed
+// when it returns.
+func (c *Cluster) Events(ctx context.Context, q api.ClusterEventQuery, out chan<- api.ClusterEvent) error {
+	defer close(out)
+
+	ctx, span := trace.StartSpan(ctx, "cluster/Events")
+	defer span.End()
+
+	return c.events.follow(ctx, q, out)
+}
+
+// recordPinStatus records the outcome of a pin or unpin operation on this
+// peer, as reported by the pin tracker.
+func (c *Cluster) recordPinStatus(ctx context.Context, pi api.PinInfo) {
+	c.RecordEvent(ctx, api.ClusterEvent{
+		Type:    api.ClusterEventStatus,
+		Cid:     pi.Cid,
+		Name:    pi.Name,
+		Status:  pi.Status,
+		Message: pi.Error,
+	})
+}
+
+// watchEvents records the changes to the shared state and to the peerset,
+// and the leadership changes, in the cluster event history.
+func (c *Cluster) watchEvents(events <-chan api.ConsensusEvent, leaders <-chan peer.ID) {
+	for events != nil || leaders != nil {
+		select {
+		case <-c.ctx.Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			c.index.update(ev)
+			switch ev.Type {
+			case api.ConsensusEventUnpin:
+				c.watchUnpin(ev.Pin, true)
+			case api.ConsensusEventPin:
+				c.unpins.remove(ev.Pin.Cid)
+			}
+			c.recordConsensusEvent(ev)
+		case leader, ok := <-leaders:
+			if !ok {
+				leaders = nil
+				continue
+			}
+			c.RecordEvent(c.ctx, api.ClusterEvent{
+				Type:    api.ClusterEventLeaderChanged,
+				Subject: leader,
+			})
+		}
+	}
+}
+
+func (c *Cluster) recordConsensusEvent(ev api.ConsensusEvent) {
+	cev := api.ClusterEvent{Timestamp: ev.Timestamp}
+	switch ev.Type {
+	case api.ConsensusEventPin:
+		cev.Type = api.ClusterEventPin
+		cev.Cid = ev.Pin.Cid
+		cev.Name = ev.Pin.Name
+	case api.ConsensusEventUnpin:
+		cev.Type = api.ClusterEventUnpin
+		cev.Cid = ev.Pin.Cid
+		cev.Name = ev.Pin.Name
+	case api.ConsensusEventPeerAdd:
+		cev.Type = api.ClusterEventPeerJoined
+		cev.Subject = ev.Peer
+	case api.ConsensusEventPeerRm:
+		cev.Type = api.ClusterEventPeerLeft
+		cev.Subject = ev.Peer
+	default:
+		return
+	}
+	c.RecordEvent(c.ctx, cev)
+}
@@ -0,0 +1,92 @@
+package ipfscluster
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+)
+
+// pinExpiry keeps timers to unpin items right when they expire, instead of
+// waiting for the next StateSync. Only items expiring before the next
+// StateSync are scheduled, so that the number of timers stays bounded.
+type pinExpiry struct {
+	mux    sync.Mutex
+	timers map[api.Cid]*time.Timer
+	closed bool
+}
+
+// schedule calls f at the given time, replacing any function scheduled for
+// the same Cid.
+func (pe *pinExpiry) schedule(c api.Cid, at time.Time, f func()) {
+	pe.mux.Lock()
+	defer pe.mux.Unlock()
+
+	if pe.closed {
+		return
+	}
+	if pe.timers == nil {
+		pe.timers = make(map[api.Cid]*time.Timer)
+	}
+	if t, ok := pe.timers[c]; ok {
+		t.Stop()
+	}
+
+	var t *time.Timer
+	t = time.AfterFunc(time.Until(at), func() {
+		pe.mux.Lock()
+		if pe.timers[c] == t {
+			delete(pe.timers, c)
+		}
+		pe.mux.Unlock()
+		f()
+	})
+	pe.timers[c] = t
+}
+
+// stop cancels all the scheduled functions. Nothing can be scheduled
+// afterwards.
+func (pe *pinExpiry) stop() {
+	pe.mux.Lock()
+	defer pe.mux.Unlock()
+
+	pe.closed = true
+	for c, t := range pe.timers {
+		t.Stop()
+		delete(pe.timers, c)
+	}
+}
+
+// len returns the number of scheduled functions.
+func (pe *pinExpiry) len() int {
+	pe.mux.Lock()
+	defer pe.mux.Unlock()
+	return len(pe.timers)
+}
+
+// scheduleExpiry arranges for the given pin to be unpinned when it expires
+// if that happens before the next StateSync. The pin is only unpinned if it
+// is still in the state and expired by then, as it may have been updated
+// in the meantime.
+func (c *Cluster) scheduleExpiry(pin api.Pin) {
+	if !pin.ExpiredAt(time.Now().Add(c.config.StateSyncInterval)) {
+		return
+	}
+
+	c.expiry.schedule(pin.Cid, pin.ExpireAt, func() {
+		current, err := c.PinGet(c.ctx, pin.Cid)
+		if err != nil || !current.ExpiredAt(time.Now()) {
+			return
+		}
+		c.unpinExpired(c.ctx, current)
+	})
+}
+
+// unpinExpired unpins the given expired pin.
+func (c *Cluster) unpinExpired(ctx context.Context, pin api.Pin) {
+	logger.Infof("Unpinning %s: pin expired at %s", pin.Cid, pin.ExpireAt)
+	if _, err := c.unpin(ctx, pin.Cid, false); err != nil {
+		logger.Error(err)
+	}
+}
@@ -0,0 +1,359 @@
+package ipfscluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/api/rest/client"
+
+	"github.com/google/uuid"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	trace "go.opencensus.io/trace"
+)
+
+// how many pins of a handoff are handed off at the same time.
+var handoffConcurrency = 8
+
+// how often the status of a pin is checked in the target cluster.
+var handoffCheckFreq = 5 * time.Second
+
+// how long finished handoffs are remembered.
+var handoffRetention = 24 * time.Hour
+
+// newHandoffClient returns a client for the REST API of the target cluster
+// of a handoff.
+var newHandoffClient = func(req api.HandoffRequest) (client.Client, error) {
+	return client.NewDefaultClient(&client.Config{
+		APIAddr:  req.Target.Value(),
+		Username: req.Username,
+		Password: req.Password,
+	})
+}
+
+// handoffJobs keeps the handoffs started on this peer. They are only kept
+// in memory: a handoff interrupted by a restart is not resumed and its
+// status is lost. This is safe because every pin is only unpinned from this
+// cluster after the target confirms it, so running the same handoff again
+// hands off whatever was left behind.
+type handoffJobs struct {
+	mux     sync.Mutex
+	jobs    map[string]*api.HandoffJob
+	cancels map[string]context.CancelFunc
+}
+
+// add registers a new handoff and forgets those finished long ago.
+func (hj *handoffJobs) add(job *api.HandoffJob, cancel context.CancelFunc) {
+	hj.mux.Lock()
+	defer hj.mux.Unlock()
+	if hj.jobs == nil {
+		hj.jobs = make(map[string]*api.HandoffJob)
+		hj.cancels = make(map[string]context.CancelFunc)
+	}
+	for id, j := range hj.jobs {
+		if j.Status != api.HandoffRunning && time.Since(j.Finished) > handoffRetention {
+			delete(hj.jobs, id)
+			delete(hj.cancels, id)
+		}
+	}
+	hj.jobs[job.ID] = job
+	hj.cancels[job.ID] = cancel
+}
+
+// update runs f on the given handoff with the lock held.
+func (hj *handoffJobs) update(id string, f func(job *api.HandoffJob)) {
+	hj.mux.Lock()
+	defer hj.mux.Unlock()
+	if job, ok := hj.jobs[id]; ok {
+		f(job)
+	}
+}
+
+func (hj *handoffJobs) get(id string) (api.HandoffJob, bool) {
+	hj.mux.Lock()
+	defer hj.mux.Unlock()
+	job, ok := hj.jobs[id]
+	if !ok {
+		return api.HandoffJob{}, false
+	}
+	return copyHandoffJob(job), true
+}
+
+func (hj *handoffJobs) list() []api.HandoffJob {
+	hj.mux.Lock()
+	defer hj.mux.Unlock()
+	jobs := make([]api.HandoffJob, 0, len(hj.jobs))
+	for _, job := range hj.jobs {
+		jobs = append(jobs, copyHandoffJob(job))
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Started.Before(jobs[j].Started)
+	})
+	return jobs
+}
+
+func (hj *handoffJobs) cancel(id string) bool {
+	hj.mux.Lock()
+	defer hj.mux.Unlock()
+	cancel, ok := hj.cancels[id]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+func copyHandoffJob(job *api.HandoffJob) api.HandoffJob {
+	cp := *job
+	if job.Errors != nil {
+		cp.Errors = make(map[string]string, len(job.Errors))
+		for c, err := range job.Errors {
+			cp.Errors[c] = err
+		}
+	}
+	return cp
+}
+
+// Handoff starts transferring the selected pins to another cluster. Every
+// pin is pinned in the target cluster with the same options, and only
+// unpinned from this cluster once all the target peers allocated to it
+// report it as pinned. Pins that fail are left in this cluster. The
+// handoff runs in the background on this peer, and its progress can be
+// followed with HandoffStatus until the peer restarts. As the pins are
+// unpinned on the word of the target, only the targets in the
+// handoff_targets configuration are accepted.
+func (c *Cluster) Handoff(ctx context.Context, req api.HandoffRequest) (api.HandoffJob, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/Handoff")
+	defer span.End()
+
+	if c.config.FollowerMode {
+		return api.HandoffJob{}, errFollowerMode
+	}
+	if req.Target.Value() == nil {
+		return api.HandoffJob{}, errors.New("a target cluster API address must be given")
+	}
+	if !c.handoffTargetAllowed(req.Target) {
+		return api.HandoffJob{}, fmt.Errorf("%s is not one of the configured handoff targets", req.Target)
+	}
+	if req.Timeout < 0 {
+		return api.HandoffJob{}, errors.New("the handoff timeout cannot be negative")
+	}
+
+	target, err := newHandoffClient(req)
+	if err != nil {
+		return api.HandoffJob{}, err
+	}
+	err = c.checkHandoffTarget(ctx, target)
+	if err != nil {
+		return api.HandoffJob{}, err
+	}
+
+	// Only data pins are handed off: sharded pins are made of several
+	// pins that cannot be moved on their own.
+	opts := req.Pins
+	opts.Type = api.DataType
+	var pins []api.Pin
+	out := make(chan api.Pin, 1024)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.PinsWithOptions(ctx, opts, out)
+	}()
+	for pin := range out {
+		pins = append(pins, pin)
+	}
+	if err := <-errCh; err != nil {
+		return api.HandoffJob{}, err
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return api.HandoffJob{}, err
+	}
+	job := &api.HandoffJob{
+		ID:      id.String(),
+		Peer:    c.id,
+		Target:  req.Target.String(),
+		Status:  api.HandoffRunning,
+		Total:   len(pins),
+		Started: time.Now(),
+	}
+	jobCtx, cancel := context.WithCancel(c.ctx)
+	c.handoffs.add(job, cancel)
+	snapshot, _ := c.handoffs.get(job.ID)
+
+	logger.Infof("handoff %s: handing off %d pins to %s", job.ID, len(pins), job.Target)
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer cancel()
+		c.runHandoff(jobCtx, job.ID, target, pins, req.Timeout)
+	}()
+	return snapshot, nil
+}
+
+// handoffTargetAllowed returns whether the operator configured the given
+// target for handoffs.
+func (c *Cluster) handoffTargetAllowed(target api.Multiaddr) bool {
+	for _, addr := range c.config.HandoffTargets {
+		if addr.Equal(target.Value()) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHandoffTarget makes sure that the target cluster can be reached and
+// is not this cluster.
+func (c *Cluster) checkHandoffTarget(ctx context.Context, target client.Client) error {
+	targetID, err := target.ID(ctx)
+	if err != nil {
+		return fmt.Errorf("error contacting the target cluster: %w", err)
+	}
+	if targetID.Error != "" {
+		return fmt.Errorf("error contacting the target cluster: %s", targetID.Error)
+	}
+
+	members, err := c.consensus.Peers(ctx)
+	if err != nil {
+		return err
+	}
+	if len(peersIntersect(handoffPeers(targetID), members)) > 0 {
+		return errors.New("the handoff target is this cluster")
+	}
+	return nil
+}
+
+func (c *Cluster) runHandoff(ctx context.Context, id string, target client.Client, pins []api.Pin, timeout time.Duration) {
+	pinCh := make(chan api.Pin)
+	var wg sync.WaitGroup
+	for i := 0; i < handoffConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pin := range pinCh {
+				c.handoffPin(ctx, id, target, pin, timeout)
+			}
+		}()
+	}
+
+sendPins:
+	for _, pin := range pins {
+		select {
+		case <-ctx.Done():
+			break sendPins
+		case pinCh <- pin:
+		}
+	}
+	close(pinCh)
+	wg.Wait()
+
+	c.handoffs.update(id, func(job *api.HandoffJob) {
+		job.Finished = time.Now()
+		switch {
+		case ctx.Err() != nil:
+			job.Status = api.HandoffCanceled
+		case len(job.Errors) > 0:
+			job.Status = api.HandoffFailed
+			job.Error = fmt.Sprintf("%d pins could not be handed off", len(job.Errors))
+		default:
+			job.Status = api.HandoffDone
+		}
+		logger.Infof("handoff %s: %s (%d/%d pins unpinned)", id, job.Status, job.Unpinned, job.Total)
+	})
+}
+
+// handoffPin pins an item in the target cluster, waits until it is pinned
+// there and unpins it from this cluster.
+func (c *Cluster) handoffPin(ctx context.Context, id string, target client.Client, pin api.Pin, timeout time.Duration) {
+	fail := func(err error) {
+		logger.Errorf("handoff %s: %s: %s", id, pin.Cid, err)
+		c.handoffs.update(id, func(job *api.HandoffJob) {
+			if job.Errors == nil {
+				job.Errors = make(map[string]string)
+			}
+			job.Errors[pin.Cid.String()] = err.Error()
+		})
+	}
+
+	// Allocations are peers of this cluster and make no sense in the
+	// target.
+	opts := pin.PinOptions
+	opts.UserAllocations = nil
+	opts.PinUpdate = api.CidUndef
+	_, err := target.Pin(ctx, pin.Cid, opts)
+	if err != nil {
+		fail(fmt.Errorf("error pinning in the target cluster: %w", err))
+		return
+	}
+
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	_, err = client.WaitFor(waitCtx, target, client.StatusFilterParams{
+		Cid:       pin.Cid,
+		Target:    api.TrackerStatusPinned,
+		CheckFreq: handoffCheckFreq,
+	})
+	if err != nil {
+		fail(fmt.Errorf("the target cluster did not confirm the pin: %w", err))
+		return
+	}
+	c.handoffs.update(id, func(job *api.HandoffJob) {
+		job.Confirmed++
+	})
+
+	// The item is unpinned even if the handoff has just been canceled,
+	// as it is pinned in the target already.
+	_, err = c.Unpin(c.ctx, pin.Cid)
+	if err != nil {
+		fail(fmt.Errorf("error unpinning: %w", err))
+		return
+	}
+	c.handoffs.update(id, func(job *api.HandoffJob) {
+		job.Unpinned++
+	})
+}
+
+// HandoffStatus returns the progress of a handoff started on this peer.
+func (c *Cluster) HandoffStatus(ctx context.Context, id string) (api.HandoffJob, error) {
+	_, span := trace.StartSpan(ctx, "cluster/HandoffStatus")
+	defer span.End()
+
+	job, ok := c.handoffs.get(id)
+	if !ok {
+		return job, api.ErrHandoffNotFound
+	}
+	return job, nil
+}
+
+// Handoffs returns the handoffs started on this peer, oldest first.
+func (c *Cluster) Handoffs(ctx context.Context) ([]api.HandoffJob, error) {
+	_, span := trace.StartSpan(ctx, "cluster/Handoffs")
+	defer span.End()
+
+	return c.handoffs.list(), nil
+}
+
+// CancelHandoff stops a handoff started on this peer. Pins already
+// confirmed by the target cluster are still unpinned from this one.
+func (c *Cluster) CancelHandoff(ctx context.Context, id string) error {
+	_, span := trace.StartSpan(ctx, "cluster/CancelHandoff")
+	defer span.End()
+
+	if !c.handoffs.cancel(id) {
+		return api.ErrHandoffNotFound
+	}
+	return nil
+}
+
+// handoffPeers returns the peer IDs of an ID response: the peer itself and
+// the cluster peers it knows.
+func handoffPeers(id api.ID) []peer.ID {
+	return append([]peer.ID{id.ID}, id.ClusterPeers...)
+}
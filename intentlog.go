@@ -0,0 +1,232 @@
+package ipfscluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/state"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	trace "go.opencensus.io/trace"
+)
+
+// intentsNamespace is the datastore namespace where intents are stored.
+const intentsNamespace = "/intents"
+
+type intentOp string
+
+const (
+	intentPin   intentOp = "pin"
+	intentUnpin intentOp = "unpin"
+)
+
+// intent is an operation that was accepted by this peer and that is about to
+// be submitted to the consensus layer.
+type intent struct {
+	Op        intentOp  `json:"op"`
+	Pin       api.Pin   `json:"pin"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// intentLog is a write-ahead log of pin/unpin operations. Intents are
+// written before the operation is committed to the consensus layer and
+// removed once it is committed or once committing it failed. Intents left over
+// after a crash, or after a failure which may still let the operation
+// through (i.e. a timeout or an election), are replayed when the peer starts
+// again, so that no accepted operation is lost. Pin and unpin intents for
+// the same CID are kept separately and replayed in order.
+type intentLog struct {
+	store ds.Datastore
+}
+
+func newIntentLog(store ds.Datastore) *intentLog {
+	return &intentLog{
+		store: namespace.Wrap(store, ds.NewKey(intentsNamespace)),
+	}
+}
+
+func (il *intentLog) key(op intentOp, c api.Cid) ds.Key {
+	return ds.NewKey(string(op)).ChildString(c.String())
+}
+
+func (il *intentLog) record(ctx context.Context, op intentOp, pin api.Pin) error {
+	v, err := json.Marshal(intent{
+		Op:        op,
+		Pin:       pin,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	err = il.store.Put(ctx, il.key(op, pin.Cid), v)
+	if err != nil {
+		return err
+	}
+	return il.store.Sync(ctx, ds.NewKey(""))
+}
+
+func (il *intentLog) ack(ctx context.Context, op intentOp, c api.Cid) {
+	err := il.store.Delete(ctx, il.key(op, c))
+	if err != nil {
+		logger.Errorf("error removing %s intent for %s: %s", op, c, err)
+	}
+}
+
+// settle acknowledges an intent once the consensus layer has committed the
+// operation or has failed to commit it. Intents are only kept, to be
+// replayed, when the consensus layer reports that the operation may still
+// be committed (api.ErrUncertain). A committed operation supersedes any
+// older intent for the same CID.
+func (il *intentLog) settle(ctx context.Context, op intentOp, c api.Cid, err error) {
+	switch {
+	case err == nil:
+		il.ack(ctx, intentPin, c)
+		il.ack(ctx, intentUnpin, c)
+	case !errors.Is(err, api.ErrUncertain):
+		il.ack(ctx, op, c)
+	}
+}
+
+// pending returns the intents that were never acknowledged, oldest first.
+func (il *intentLog) pending(ctx context.Context) ([]intent, error) {
+	results, err := il.store.Query(ctx, query.Query{})
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+
+	var intents []intent
+	for r := range results.Next() {
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		var in intent
+		err := json.Unmarshal(r.Value, &in)
+		if err != nil {
+			logger.Errorf("discarding unreadable intent %s: %s", r.Key, err)
+			il.store.Delete(ctx, ds.NewKey(r.Key))
+			continue
+		}
+		intents = append(intents, in)
+	}
+	sort.SliceStable(intents, func(i, j int) bool {
+		return intents[i].Timestamp.Before(intents[j].Timestamp)
+	})
+	return intents, nil
+}
+
+// logPin records the intent and submits the pin to the consensus layer,
+// along with the requester carried by the context. Committed pins are added
+// to the audit trail.
+func (c *Cluster) logPin(ctx context.Context, pin api.Pin) error {
+	pin.Requester = c.requester(ctx)
+	if err := c.intents.record(ctx, intentPin, pin); err != nil {
+		return err
+	}
+	err := c.consensus.LogPin(ctx, pin)
+	c.intents.settle(ctx, intentPin, pin.Cid, err)
+	if err != nil {
+		return err
+	}
+	c.recordAudit(ctx, api.PinAuditPin, pin)
+	return nil
+}
+
+// logUnpin records the intent and submits the unpin to the consensus layer.
+// Committed unpins are added to the audit trail.
+func (c *Cluster) logUnpin(ctx context.Context, pin api.Pin) error {
+	pin.Requester = c.requester(ctx)
+	if err := c.intents.record(ctx, intentUnpin, pin); err != nil {
+		return err
+	}
+	err := c.consensus.LogUnpin(ctx, pin)
+	c.intents.settle(ctx, intentUnpin, pin.Cid, err)
+	if err != nil {
+		return err
+	}
+	c.recordAudit(ctx, api.PinAuditUnpin, pin)
+	return nil
+}
+
+// replayIntents submits to the consensus layer the given pending
+// operations, which were accepted but for which this peer did not hear back
+// from the consensus layer (i.e. because it crashed). Operations that have
+// been superseded by newer ones in the shared state are discarded.
+func (c *Cluster) replayIntents(ctx context.Context, intents []intent) {
+	ctx, span := trace.StartSpan(ctx, "cluster/replayIntents")
+	defer span.End()
+
+	if len(intents) == 0 {
+		return
+	}
+
+	logger.Infof("replaying %d unacknowledged operations from the intent log", len(intents))
+	for _, in := range intents {
+		existing, err := c.PinGet(ctx, in.Pin.Cid)
+		if err != nil && err != state.ErrNotFound {
+			logger.Errorf("error replaying %s %s: %s", in.Op, in.Pin.Cid, err)
+			continue
+		}
+
+		switch in.Op {
+		case intentPin:
+			if existing.Defined() && !existing.Timestamp.Before(in.Pin.Timestamp) {
+				logger.Debugf("intent to pin %s already committed", in.Pin.Cid)
+				c.intents.ack(ctx, in.Op, in.Pin.Cid)
+				continue
+			}
+			if !existing.Defined() {
+				// The pin may have been committed and unpinned
+				// since, i.e. by another peer.
+				unpinned, err := c.unpinnedSince(ctx, in.Pin.Cid, in.Timestamp)
+				if err != nil {
+					logger.Errorf("error replaying %s %s: %s", in.Op, in.Pin.Cid, err)
+					continue
+				}
+				if unpinned {
+					logger.Debugf("intent to pin %s superseded by an unpin", in.Pin.Cid)
+					c.intents.ack(ctx, in.Op, in.Pin.Cid)
+					continue
+				}
+			}
+			logger.Infof("replaying pin of %s", in.Pin.Cid)
+			err = c.logPin(withRequester(ctx, in.Pin.Requester), in.Pin)
+		case intentUnpin:
+			if !existing.Defined() || existing.Timestamp.After(in.Timestamp) {
+				logger.Debugf("intent to unpin %s already committed or superseded", in.Pin.Cid)
+				c.intents.ack(ctx, in.Op, in.Pin.Cid)
+				continue
+			}
+			logger.Infof("replaying unpin of %s", in.Pin.Cid)
+			err = c.logUnpin(withRequester(ctx, in.Pin.Requester), existing)
+		default:
+			logger.Errorf("discarding intent with unknown operation %q", in.Op)
+			c.intents.ack(ctx, in.Op, in.Pin.Cid)
+			continue
+		}
+		if err != nil {
+			logger.Errorf("error replaying %s %s: %s", in.Op, in.Pin.Cid, err)
+		}
+	}
+}
+
+// unpinnedSince returns whether the pin history of the cluster has an unpin
+// of the given CID after the given time.
+func (c *Cluster) unpinnedSince(ctx context.Context, h api.Cid, t time.Time) (bool, error) {
+	entries, err := c.PinHistory(ctx, h)
+	if err != nil {
+		return false, err
+	}
+	for _, entry := range entries {
+		if entry.Type == api.PinAuditUnpin && entry.Timestamp.After(t) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -41,15 +41,34 @@ type Consensus interface {
 	LogPin(context.Context, api.Pin) error
 	// Logs an unpin operation.
 	LogUnpin(context.Context, api.Pin) error
+	// Logs a pin schedule, replacing any with the same ID.
+	LogSchedule(context.Context, api.PinSchedule) error
+	// Logs the removal of a pin schedule.
+	LogUnschedule(context.Context, string) error
 	AddPeer(context.Context, peer.ID) error
 	RmPeer(context.Context, peer.ID) error
 	State(context.Context) (state.ReadOnly, error)
 	// Provide a node which is responsible to perform
 	// specific tasks which must only run in 1 cluster peer.
 	Leader(context.Context) (peer.ID, error)
+	// WaitForLeader returns the leader, waiting until there is one.
+	WaitForLeader(context.Context) (peer.ID, error)
+	// SubscribeLeader returns a channel that receives the new leader
+	// on every leadership change (empty when there is none). The
+	// channel is closed when the context is cancelled.
+	SubscribeLeader(context.Context) <-chan peer.ID
+	// SubscribeEvents returns a channel that receives an event for
+	// every operation applied to the shared state. The channel is
+	// closed when the context is cancelled.
+	SubscribeEvents(context.Context) <-chan api.ConsensusEvent
 	// Only returns when the consensus state has all log
 	// updates applied to it.
 	WaitForSync(context.Context) error
+	// CatchUpProgress reports how far WaitForSync is from completion.
+	CatchUpProgress(context.Context) api.CatchUpProgress
+	// Stats returns the role of the peer and the progress and disk
+	// usage of the consensus layer.
+	Stats(context.Context) api.ConsensusStats
 	// Clean removes all consensus data.
 	Clean(context.Context) error
 	// Peers returns the peerset participating in the Consensus.
@@ -59,6 +78,9 @@ type Consensus interface {
 	// non-trusted one. This should be fast as it will be
 	// called repeatedly for every remote RPC request.
 	IsTrustedPeer(context.Context, peer.ID) bool
+	// IsNonVoter returns true if this peer never takes part in
+	// consensus decisions (it only replicates the state).
+	IsNonVoter(context.Context) bool
 	// Trust marks a peer as "trusted".
 	Trust(context.Context, peer.ID) error
 	// Distrust removes a peer from the "trusted" set.
@@ -83,6 +105,9 @@ type IPFSConnector interface {
 	Pin(context.Context, api.Pin) error
 	Unpin(context.Context, api.Cid) error
 	PinLsCid(context.Context, api.Pin) (api.IPFSPinStatus, error)
+	// PinProgress returns the progress of an ongoing Pin for the given
+	// Cid, or an undefined PinProgress if it is not being pinned.
+	PinProgress(context.Context, api.Cid) (api.PinProgress, error)
 	// PinLs returns pins in the pinset of the given types (recursive, direct...)
 	PinLs(ctx context.Context, typeFilters []string, out chan<- api.IPFSPinInfo) error
 	// ConnectSwarms make sure this peer's IPFS daemon is connected to
@@ -98,12 +123,26 @@ type IPFSConnector interface {
 	RepoStat(context.Context) (api.IPFSRepoStat, error)
 	// RepoGC performs garbage collection sweep on the IPFS repo.
 	RepoGC(context.Context) (api.RepoGC, error)
+	// Reprovide announces again to the routing system the content
+	// provided by the IPFS daemon.
+	Reprovide(context.Context) error
 	// Resolve returns a cid given a path.
 	Resolve(context.Context, string) (api.Cid, error)
 	// BlockStream adds a stream of blocks to IPFS.
 	BlockStream(context.Context, <-chan api.NodeWithMeta) error
 	// BlockGet retrieves the raw data of an IPFS block.
 	BlockGet(context.Context, api.Cid) ([]byte, error)
+	// BlockStat returns information about an IPFS block.
+	BlockStat(context.Context, api.Cid) (api.IPFSBlockStat, error)
+	// DagStat returns the total size and number of blocks of the DAG
+	// of a pin, fetching it from the pin origins if needed.
+	DagStat(context.Context, api.Pin) (api.IPFSDagStat, error)
+	// Refs returns up to the given number of unique CIDs referenced
+	// recursively by a CID (0 for no limit).
+	Refs(context.Context, api.Cid, int) ([]api.Cid, error)
+	// VerifyBlocks checks that IPFS has locally a random sample of
+	// the blocks of a DAG and reports those that are missing.
+	VerifyBlocks(context.Context, api.IPFSBlockVerification) (api.IPFSBlockVerification, error)
 }
 
 // Peered represents a component which needs to be aware of the peers
@@ -132,10 +171,16 @@ type PinTracker interface {
 	Status(context.Context, api.Cid) api.PinInfo
 	// RecoverAll calls Recover() for all pins tracked.
 	RecoverAll(context.Context, chan<- api.PinInfo) error
+	// RecoverAllWithOptions calls Recover() for the pins selected by
+	// the options, at the given rate.
+	RecoverAllWithOptions(context.Context, api.RecoverAllOptions, chan<- api.PinInfo) error
 	// Recover retriggers a Pin/Unpin operation in a Cids with error status.
 	Recover(context.Context, api.Cid) (api.PinInfo, error)
 	// PinQueueSize returns the current size of the pinning queue.
 	PinQueueSize(context.Context) (int64, error)
+	// QueueInfo returns information about the queues of pin and unpin
+	// operations.
+	QueueInfo(context.Context) (api.PinQueueInfo, error)
 }
 
 // Informer provides Metric information from a peer. The metrics produced by
@@ -342,7 +342,7 @@ func createHosts(t *testing.T, clusterSecret []byte, nClusters int) ([]host.Host
 func createHost(t *testing.T, priv crypto.PrivKey, clusterSecret []byte, listen []ma.Multiaddr) (host.Host, *pubsub.PubSub, *dual.DHT) {
 	ctx := context.Background()
 
-	h, err := newHost(ctx, clusterSecret, priv, libp2p.ListenAddrs(listen...))
+	h, err := newHost(ctx, PrivateNetworkPnet, clusterSecret, priv, libp2p.ListenAddrs(listen...))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -663,6 +663,15 @@ func TestClustersPeers(t *testing.T) {
 		if id.IPFS.ID != id2.IPFS.ID {
 			t.Error("expected same ipfs daemon ID")
 		}
+		if id2.Version != id.Version || id2.IPFS.Version != test.IpfsAgentVersion {
+			t.Error("expected cluster and ipfs versions")
+		}
+		if k != clusters[j].id && id2.RTT <= 0 {
+			t.Errorf("expected an RTT for %s", k)
+		}
+		if k == clusters[j].id && id2.RTT != 0 {
+			t.Error("expected no RTT for the contacted peer")
+		}
 	}
 }
 
@@ -772,6 +781,73 @@ func TestClustersPin(t *testing.T) {
 	runF(t, clusters, funpinned)
 }
 
+func TestClustersSchedulePin(t *testing.T) {
+	ctx := context.Background()
+	syncInterval := pinScheduleSyncInterval
+	pinScheduleSyncInterval = time.Second
+	defer func() { pinScheduleSyncInterval = syncInterval }()
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+	waitForLeader(t, clusters)
+
+	// Schedules are part of the shared state: every peer knows about
+	// them.
+	recurring, err := clusters[nClusters-1].SchedulePin(ctx, api.PinSchedule{
+		Path: test.PathIPFS2,
+		Cron: "0 0 1 1 *",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	once, err := clusters[nClusters-1].SchedulePin(ctx, api.PinSchedule{
+		Path:      test.PathIPFS2,
+		Options:   api.PinOptions{Name: "later"},
+		NotBefore: time.Now().Add(time.Second),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * pinScheduleSyncInterval)
+	delay()
+
+	// The schedule which runs once has been run by one of the peers and
+	// removed.
+	for _, c := range clusters {
+		pin, err := c.PinGet(ctx, test.CidResolved)
+		if err != nil {
+			t.Fatalf("%s: the scheduled pin should have been committed: %s", c.id, err)
+		}
+		if pin.Name != "later" {
+			t.Errorf("unexpected pin name: %s", pin.Name)
+		}
+		schedules, err := c.PinSchedules(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(schedules) != 1 || schedules[0].ID != recurring.ID {
+			t.Errorf("%s: only the recurring schedule should be left: %+v", c.id, schedules)
+		}
+	}
+	if err := clusters[0].CancelPinSchedule(ctx, once.ID); err != state.ErrScheduleNotFound {
+		t.Errorf("expected ErrScheduleNotFound: %s", err)
+	}
+
+	err = clusters[0].CancelPinSchedule(ctx, recurring.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delay()
+	for _, c := range clusters {
+		schedules, err := c.PinSchedules(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(schedules) != 0 {
+			t.Errorf("%s: the schedule should have been canceled: %+v", c.id, schedules)
+		}
+	}
+}
+
 func TestClustersPinUpdate(t *testing.T) {
 	ctx := context.Background()
 	clusters, mock := createClusters(t)
@@ -962,6 +1038,83 @@ func TestClustersStatusAll(t *testing.T) {
 	runF(t, clusters, f)
 }
 
+func TestClustersStatusAllWithOptions(t *testing.T) {
+	ctx := context.Background()
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+	h := test.Cid1
+	clusters[0].Pin(ctx, h, api.PinOptions{Name: "test"})
+	pinDelay()
+
+	out := make(chan api.GlobalPinInfo, 10)
+	go func() {
+		opts := api.StatusAllOptions{
+			BatchSize:      2,
+			TimeoutPerPeer: 5 * time.Second,
+		}
+		err := clusters[0].StatusAllWithOptions(ctx, opts, out)
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	statuses := collectGlobalPinInfos(t, out, 5*time.Second)
+	if len(statuses) != (nClusters+1)/2 {
+		t.Fatalf("expected one item per batch, got %d", len(statuses))
+	}
+
+	seen := make(map[string]struct{})
+	for _, gpi := range statuses {
+		if !gpi.Cid.Equals(h) {
+			t.Error("bad cid in status")
+		}
+		if len(gpi.PeerMap) > 2 {
+			t.Error("too many peers in batch")
+		}
+		for pid, pi := range gpi.PeerMap {
+			if pi.Status != api.TrackerStatusPinned {
+				t.Error("the hash should have been pinned")
+			}
+			seen[pid] = struct{}{}
+		}
+	}
+	if len(seen) != nClusters {
+		t.Error("all peers should have been queried")
+	}
+}
+
+func TestClustersStatusAllFiltered(t *testing.T) {
+	ctx := context.Background()
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+	clusters[0].Pin(ctx, test.Cid1, api.PinOptions{})
+	clusters[0].Pin(ctx, test.Cid5, api.PinOptions{})
+	pinDelay()
+
+	out := make(chan api.GlobalPinInfo, 10)
+	go func() {
+		opts := api.StatusAllOptions{
+			CidPrefix: test.Cid1.String()[:10],
+			Peers:     []peer.ID{clusters[1].id, test.PeerID6},
+		}
+		err := clusters[0].StatusAllWithOptions(ctx, opts, out)
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	statuses := collectGlobalPinInfos(t, out, 5*time.Second)
+	if len(statuses) != 1 || !statuses[0].Cid.Equals(test.Cid1) {
+		t.Fatalf("expected only the status of %s: %+v", test.Cid1, statuses)
+	}
+	if len(statuses[0].PeerMap) != 1 {
+		t.Fatalf("expected only the status from the given peer: %+v", statuses[0].PeerMap)
+	}
+	if _, ok := statuses[0].PeerMap[clusters[1].id.String()]; !ok {
+		t.Error("expected the status from the given peer")
+	}
+}
+
 func TestClustersStatusAllWithErrors(t *testing.T) {
 	ctx := context.Background()
 	clusters, mock := createClusters(t)
@@ -1212,6 +1365,47 @@ func TestClustersRecoverAll(t *testing.T) {
 	}
 }
 
+func TestClustersRecoverAllWithOptions(t *testing.T) {
+	ctx := context.Background()
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+	hError := test.ErrorCid
+
+	ttlDelay()
+
+	clusters[0].Pin(ctx, hError, api.PinOptions{})
+
+	pinDelay()
+
+	recoverAll := func(opts api.RecoverAllOptions) []api.GlobalPinInfo {
+		out := make(chan api.GlobalPinInfo)
+		go func() {
+			err := clusters[mrand.Intn(nClusters)].RecoverAllWithOptions(ctx, opts, out)
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+		return collectGlobalPinInfos(t, out, 5*time.Second)
+	}
+
+	gInfos := recoverAll(api.RecoverAllOptions{CidPrefix: "nomatch"})
+	if len(gInfos) != 0 {
+		t.Error("expected no items")
+	}
+
+	gInfos = recoverAll(api.RecoverAllOptions{
+		Filter: api.TrackerStatusPinError,
+		Rate:   10,
+		Force:  true,
+	})
+	if len(gInfos) != 1 {
+		t.Fatal("expected one item")
+	}
+	if len(gInfos[0].PeerMap) != nClusters {
+		t.Error("number of peers do not match")
+	}
+}
+
 func TestClustersShutdown(t *testing.T) {
 	ctx := context.Background()
 	clusters, mock := createClusters(t)
@@ -1394,6 +1588,76 @@ func TestClustersReplicationFactorMax(t *testing.T) {
 	runF(t, clusters, f)
 }
 
+func TestClustersAllocationExplain(t *testing.T) {
+	ctx := context.Background()
+	if nClusters < 3 {
+		t.Skip("Need at least 3 peers")
+	}
+
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+	for _, c := range clusters {
+		c.config.ReplicationFactorMin = 1
+		c.config.ReplicationFactorMax = nClusters - 1
+	}
+
+	ttlDelay()
+
+	// Not pinned: explained with the default options.
+	expl, err := clusters[0].AllocationExplain(ctx, test.Cid2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expl.ReplicationFactorMax != nClusters-1 || len(expl.Allocations) != nClusters-1 {
+		t.Errorf("unexpected explanation for an unpinned cid: %+v", expl)
+	}
+
+	h := test.Cid1
+	_, err = clusters[0].Pin(ctx, h, api.PinOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pinDelay()
+
+	p, err := clusters[0].PinGet(ctx, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expl, err = clusters[0].AllocationExplain(ctx, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expl.Error != "" {
+		t.Error("unexpected error:", expl.Error)
+	}
+	if len(expl.Peers) != nClusters {
+		t.Fatalf("expected %d peers explained, got %d", nClusters, len(expl.Peers))
+	}
+
+	chosen := 0
+	for _, pe := range expl.Peers {
+		if len(pe.Metrics) != len(expl.AllocateBy) || pe.Reason == "" {
+			t.Errorf("incomplete peer explanation: %+v", pe)
+		}
+		if pe.Chosen != containsPeer(p.Allocations, pe.Peer) {
+			t.Errorf("peer %s chosen does not match the allocations", pe.Peer)
+		}
+		if pe.Chosen {
+			chosen++
+			if pe.Class != api.AllocationClassCurrent {
+				t.Errorf("allocated peer should be current: %+v", pe)
+			}
+		} else if pe.Class != api.AllocationClassCandidate || pe.Rank == 0 {
+			t.Errorf("non-allocated peer should be a ranked candidate: %+v", pe)
+		}
+	}
+	if chosen != nClusters-1 {
+		t.Errorf("expected %d chosen peers, got %d", nClusters-1, chosen)
+	}
+}
+
 // This tests checks that repinning something that is overpinned
 // removes some allocations
 func TestClustersReplicationFactorMaxLower(t *testing.T) {
@@ -2252,6 +2516,44 @@ func TestClusterPinsWithExpiration(t *testing.T) {
 	}
 }
 
+func TestClusterPinsExpireBeforeStateSync(t *testing.T) {
+	ctx := context.Background()
+
+	clusters, mock := createClusters(t)
+	defer shutdownClusters(t, clusters, mock)
+
+	ttlDelay()
+
+	cl := clusters[mrand.Intn(nClusters)]
+
+	expireIn := 1 * time.Second
+	opts := api.PinOptions{
+		ExpireAt: time.Now().Add(expireIn),
+	}
+	_, err := cl.Pin(ctx, test.Cid1, opts)
+	if err != nil {
+		t.Fatal("pin should have worked:", err)
+	}
+	if cl.expiry.len() != 1 {
+		t.Error("the expiration should have been scheduled")
+	}
+
+	// No StateSync: the scheduled unpin should remove the pin.
+	time.Sleep(expireIn)
+	pinDelay()
+
+	pins, err := cl.pinsSlice(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pins) != 0 {
+		t.Error("pin should not be part of the state")
+	}
+	if cl.expiry.len() != 0 {
+		t.Error("no expirations should be scheduled")
+	}
+}
+
 func TestClusterAlerts(t *testing.T) {
 	ctx := context.Background()
 	clusters, mock := createClusters(t)
@@ -26,6 +26,9 @@ const (
 	DefaultRepoGCTimeout           = 24 * time.Hour
 	DefaultInformerTriggerInterval = 0 // disabled
 	DefaultUnpinDisable            = false
+	DefaultBlockPutMaxPending      = 128
+	DefaultBlockPutLagThreshold    = 30 * time.Second
+	DefaultVerifiedCacheTTL        = 0 // disabled
 )
 
 // Config is used to initialize a Connector and allows to customize
@@ -60,6 +63,22 @@ type Config struct {
 	// Disables the unpin operation and returns an error.
 	UnpinDisable bool
 
+	// Maximum number of blocks streamed to IPFS during a block/put
+	// operation which have not been acknowledged yet. Once reached, no
+	// more blocks are sent (and no more data is read from the uploader)
+	// until IPFS catches up.
+	BlockPutMaxPending int
+
+	// Time after which a warning is logged when IPFS has not acknowledged
+	// any of the pending blocks.
+	BlockPutLagThreshold time.Duration
+
+	// How long the results of "pin ls <cid>" (for pinned items) and
+	// "block stat" are remembered, so that repeated status checks do
+	// not hit the IPFS daemon every time. Items unpinned directly in
+	// IPFS may appear as pinned until it expires. 0 to disable.
+	VerifiedCacheTTL time.Duration
+
 	// Tracing flag used to skip tracing specific paths when not enabled.
 	Tracing bool
 }
@@ -73,6 +92,9 @@ type jsonConfig struct {
 	RepoGCTimeout           string `json:"repogc_timeout"`
 	InformerTriggerInterval int    `json:"informer_trigger_interval"`
 	UnpinDisable            bool   `json:"unpin_disable,omitempty"`
+	BlockPutMaxPending      int    `json:"block_put_max_pending"`
+	BlockPutLagThreshold    string `json:"block_put_lag_threshold"`
+	VerifiedCacheTTL        string `json:"verified_cache_ttl"`
 }
 
 // ConfigKey provides a human-friendly identifier for this type of Config.
@@ -91,6 +113,9 @@ func (cfg *Config) Default() error {
 	cfg.RepoGCTimeout = DefaultRepoGCTimeout
 	cfg.InformerTriggerInterval = DefaultInformerTriggerInterval
 	cfg.UnpinDisable = DefaultUnpinDisable
+	cfg.BlockPutMaxPending = DefaultBlockPutMaxPending
+	cfg.BlockPutLagThreshold = DefaultBlockPutLagThreshold
+	cfg.VerifiedCacheTTL = DefaultVerifiedCacheTTL
 
 	return nil
 }
@@ -142,6 +167,18 @@ func (cfg *Config) Validate() error {
 		err = errors.New("ipfshttp.update_metrics_after")
 	}
 
+	if cfg.BlockPutMaxPending <= 0 {
+		err = errors.New("ipfshttp.block_put_max_pending must be greater than 0")
+	}
+
+	if cfg.BlockPutLagThreshold <= 0 {
+		err = errors.New("ipfshttp.block_put_lag_threshold invalid")
+	}
+
+	if cfg.VerifiedCacheTTL < 0 {
+		err = errors.New("ipfshttp.verified_cache_ttl invalid")
+	}
+
 	return err
 
 }
@@ -169,6 +206,7 @@ func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 	cfg.NodeAddr = nodeAddr
 	cfg.UnpinDisable = jcfg.UnpinDisable
 	cfg.InformerTriggerInterval = jcfg.InformerTriggerInterval
+	config.SetIfNotDefault(jcfg.BlockPutMaxPending, &cfg.BlockPutMaxPending)
 
 	err = config.ParseDurations(
 		"ipfshttp",
@@ -177,6 +215,8 @@ func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 		&config.DurationOpt{Duration: jcfg.PinTimeout, Dst: &cfg.PinTimeout, Name: "pin_timeout"},
 		&config.DurationOpt{Duration: jcfg.UnpinTimeout, Dst: &cfg.UnpinTimeout, Name: "unpin_timeout"},
 		&config.DurationOpt{Duration: jcfg.RepoGCTimeout, Dst: &cfg.RepoGCTimeout, Name: "repogc_timeout"},
+		&config.DurationOpt{Duration: jcfg.BlockPutLagThreshold, Dst: &cfg.BlockPutLagThreshold, Name: "block_put_lag_threshold"},
+		&config.DurationOpt{Duration: jcfg.VerifiedCacheTTL, Dst: &cfg.VerifiedCacheTTL, Name: "verified_cache_ttl"},
 	)
 	if err != nil {
 		return err
@@ -196,6 +236,12 @@ func (cfg *Config) ToJSON() (raw []byte, err error) {
 	return
 }
 
+// JSONKeys returns the keys of the JSON representation of this
+// configuration, including those omitted when empty.
+func (cfg *Config) JSONKeys() []string {
+	return config.JSONKeys(jsonConfig{})
+}
+
 func (cfg *Config) toJSONConfig() (jcfg *jsonConfig, err error) {
 	// Multiaddress String() may panic
 	defer func() {
@@ -215,6 +261,9 @@ func (cfg *Config) toJSONConfig() (jcfg *jsonConfig, err error) {
 	jcfg.RepoGCTimeout = cfg.RepoGCTimeout.String()
 	jcfg.InformerTriggerInterval = cfg.InformerTriggerInterval
 	jcfg.UnpinDisable = cfg.UnpinDisable
+	jcfg.BlockPutMaxPending = cfg.BlockPutMaxPending
+	jcfg.BlockPutLagThreshold = cfg.BlockPutLagThreshold.String()
+	jcfg.VerifiedCacheTTL = cfg.VerifiedCacheTTL.String()
 
 	return
 }
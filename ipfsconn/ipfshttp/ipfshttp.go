@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -17,6 +18,7 @@ import (
 	"time"
 
 	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/kvstore"
 	"github.com/ipfs-cluster/ipfs-cluster/observations"
 
 	files "github.com/ipfs/boxo/files"
@@ -66,6 +68,12 @@ type Connector struct {
 	failedRequests atomic.Uint64 // count failed requests.
 	reqRateLimitCh chan struct{}
 
+	verified *verifiedCache
+
+	// progress of the ongoing pin/add requests.
+	progressMu sync.Mutex
+	progress   map[api.Cid]*api.PinProgress
+
 	shutdownLock sync.Mutex
 	shutdown     bool
 	wg           sync.WaitGroup
@@ -101,8 +109,9 @@ func (unpinned ipfsUnpinnedError) Error() string {
 }
 
 type ipfsIDResp struct {
-	ID        string
-	Addresses []string
+	ID           string
+	Addresses    []string
+	AgentVersion string
 }
 
 type ipfsResolveResp struct {
@@ -132,6 +141,20 @@ type ipfsPeer struct {
 	Peer string
 }
 
+type ipfsRefsResp struct {
+	Ref string
+	Err string
+}
+
+type ipfsDagStatResp struct {
+	Size      uint64
+	NumBlocks uint64
+	DagStats  []struct {
+		Size      uint64
+		NumBlocks uint64
+	}
+}
+
 // NewConnector creates the component and leaves it ready to be started
 func NewConnector(cfg *Config) (*Connector, error) {
 	err := cfg.Validate()
@@ -179,11 +202,16 @@ func NewConnector(cfg *Config) (*Connector, error) {
 		rpcReady:       make(chan struct{}, 1),
 		reqRateLimitCh: make(chan struct{}),
 		client:         c,
+		verified:       newVerifiedCache(cfg.VerifiedCacheTTL),
+		progress:       make(map[api.Cid]*api.PinProgress),
 	}
 
 	initializeMetrics(ctx)
 
 	go ipfs.rateLimiter()
+	if ipfs.verified.enabled() {
+		go ipfs.purgeVerifiedCache()
+	}
 
 	go ipfs.run()
 	return ipfs, nil
@@ -243,10 +271,29 @@ func (ipfs *Connector) rateLimiter() {
 	}
 }
 
+// purgeVerifiedCache regularly removes expired entries from the
+// verified cache.
+func (ipfs *Connector) purgeVerifiedCache() {
+	ticker := time.NewTicker(ipfs.config.VerifiedCacheTTL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ipfs.ctx.Done():
+			return
+		case <-ticker.C:
+			ipfs.verified.purge(ipfs.ctx)
+		}
+	}
+}
+
 // connects all ipfs daemons when
 // we receive the rpcReady signal.
 func (ipfs *Connector) run() {
-	<-ipfs.rpcReady
+	select {
+	case <-ipfs.ctx.Done():
+		return
+	case <-ipfs.rpcReady:
+	}
 
 	// wait for IPFS to be available
 	i := 0
@@ -307,6 +354,12 @@ func (ipfs *Connector) SetClient(c *rpc.Client) {
 	ipfs.rpcReady <- struct{}{}
 }
 
+// SetScratchStore sets the store used to persist the verified cache so
+// that it survives restarts.
+func (ipfs *Connector) SetScratchStore(store *kvstore.Store) {
+	ipfs.verified.setStore(ipfs.ctx, store.Namespace("/ipfshttp/verified"))
+}
+
 // Shutdown stops any listeners and stops the component from taking
 // any requests.
 func (ipfs *Connector) Shutdown(ctx context.Context) error {
@@ -366,7 +419,8 @@ func (ipfs *Connector) ID(ctx context.Context) (api.IPFSID, error) {
 	}
 
 	id := api.IPFSID{
-		ID: pID,
+		ID:      pID,
+		Version: res.AgentVersion,
 	}
 
 	mAddrs := make([]api.Multiaddr, len(res.Addresses))
@@ -405,7 +459,8 @@ func (ipfs *Connector) Pin(ctx context.Context, pin api.Pin) error {
 	hash := pin.Cid
 	maxDepth := pin.MaxDepth
 
-	pinStatus, err := ipfs.PinLsCid(ctx, pin)
+	// Always ask IPFS, as the item may have been unpinned behind our back.
+	pinStatus, err := ipfs.pinLsCid(ctx, pin)
 	if err != nil {
 		return err
 	}
@@ -416,39 +471,19 @@ func (ipfs *Connector) Pin(ctx context.Context, pin api.Pin) error {
 	}
 
 	defer ipfs.updateInformerMetric(ctx)
+	defer ipfs.verified.invalidate(ipfs.ctx, verifiedPinPrefix(hash))
 
 	ctx, cancelRequest := context.WithCancel(ctx)
 	defer cancelRequest()
 
-	// If the pin has origins, tell ipfs to connect to a maximum of 10.
-	bound := len(pin.Origins)
-	if bound > 10 {
-		bound = 10
-	}
-	for _, orig := range pin.Origins[0:bound] {
-		// do it in the background, ignoring errors.
-		go func(o string) {
-			logger.Debugf("swarm-connect to origin before pinning: %s", o)
-			_, err := ipfs.postCtx(
-				ctx,
-				fmt.Sprintf("swarm/connect?arg=%s", o),
-				"",
-				nil,
-			)
-			if err != nil {
-				logger.Debug(err)
-				return
-			}
-			logger.Debugf("swarm-connect success to origin: %s", o)
-		}(url.QueryEscape(orig.String()))
-	}
+	ipfs.connectOrigins(ctx, pin.Origins)
 
 	// If we have a pin-update, and the old object
 	// is pinned recursively, then do pin/update.
 	// Otherwise do a normal pin.
 	if from := pin.PinUpdate; from.Defined() {
 		fromPin := api.PinWithOpts(from, pin.PinOptions)
-		pinStatus, _ := ipfs.PinLsCid(ctx, fromPin)
+		pinStatus, _ := ipfs.pinLsCid(ctx, fromPin)
 		if pinStatus.IsPinned(-1) { // pinned recursively.
 			// As a side note, if PinUpdate == pin.Cid, we are
 			// somehow pinning an already pinned thing and we'd
@@ -457,6 +492,21 @@ func (ipfs *Connector) Pin(ctx context.Context, pin api.Pin) error {
 		}
 	}
 
+	progress := &api.PinProgress{
+		Started:      time.Now(),
+		LastProgress: time.Now(),
+	}
+	ipfs.progressMu.Lock()
+	ipfs.progress[hash] = progress
+	ipfs.progressMu.Unlock()
+	defer func() {
+		ipfs.progressMu.Lock()
+		if ipfs.progress[hash] == progress {
+			delete(ipfs.progress, hash)
+		}
+		ipfs.progressMu.Unlock()
+	}()
+
 	// Pin request and timeout if there is no progress
 	outPins := make(chan int)
 	go func() {
@@ -481,6 +531,10 @@ func (ipfs *Connector) Pin(ctx context.Context, pin api.Pin) error {
 				if p > lastProgress {
 					lastProgress = p
 					lastProgressTime = time.Now()
+					ipfs.progressMu.Lock()
+					progress.Blocks = p
+					progress.LastProgress = lastProgressTime
+					ipfs.progressMu.Unlock()
 				}
 			case <-ctx.Done():
 				return
@@ -489,18 +543,35 @@ func (ipfs *Connector) Pin(ctx context.Context, pin api.Pin) error {
 	}()
 
 	stats.Record(ipfs.ctx, observations.PinsPinAdd.M(1))
+	start := time.Now()
 	err = ipfs.pinProgress(ctx, hash, maxDepth, outPins)
 	if err != nil {
 		stats.Record(ipfs.ctx, observations.PinsPinAddError.M(1))
 		return err
 	}
 	totalPins := atomic.AddInt64(&ipfs.ipfsPinCount, 1)
-	stats.Record(ipfs.ctx, observations.PinsIpfsPins.M(totalPins))
+	stats.Record(ipfs.ctx,
+		observations.PinsIpfsPins.M(totalPins),
+		observations.PinsPinLatency.M(float64(time.Since(start))/float64(time.Millisecond)),
+	)
 
 	logger.Info("IPFS Pin request succeeded: ", hash)
 	return nil
 }
 
+// PinProgress returns the progress of an ongoing Pin request for the given
+// Cid. The returned PinProgress is undefined when the Cid is not being
+// pinned.
+func (ipfs *Connector) PinProgress(ctx context.Context, c api.Cid) (api.PinProgress, error) {
+	ipfs.progressMu.Lock()
+	defer ipfs.progressMu.Unlock()
+	p, ok := ipfs.progress[c]
+	if !ok {
+		return api.PinProgress{}, nil
+	}
+	return *p, nil
+}
+
 // pinProgress pins an item and sends fetched node's progress on a
 // channel. Blocks until done or error. pinProgress will always close the out
 // channel.  pinProgress will not block on sending to the channel if it is full.
@@ -569,6 +640,7 @@ func (ipfs *Connector) Unpin(ctx context.Context, hash api.Cid) error {
 	}
 
 	defer ipfs.updateInformerMetric(ctx)
+	defer ipfs.verified.invalidate(ipfs.ctx, verifiedPinPrefix(hash))
 
 	path := fmt.Sprintf("pin/rm?arg=%s", hash)
 
@@ -662,11 +734,35 @@ nextFilter:
 
 // PinLsCid performs a "pin ls <hash>" request. It will use "type=recursive" or
 // "type=direct" (or other) depending on the given pin's MaxDepth setting.
-// It returns an api.IPFSPinStatus for that hash.
+// It returns an api.IPFSPinStatus for that hash. Items found pinned are
+// remembered in the verified cache (when enabled) and not checked again
+// until it expires.
 func (ipfs *Connector) PinLsCid(ctx context.Context, pin api.Pin) (api.IPFSPinStatus, error) {
 	ctx, span := trace.StartSpan(ctx, "ipfsconn/ipfshttp/PinLsCid")
 	defer span.End()
 
+	if !pin.Defined() {
+		return api.IPFSPinStatusBug, errors.New("calling PinLsCid without a defined CID")
+	}
+
+	key := verifiedPinPrefix(pin.Cid) + pin.MaxDepth.ToPinMode().String()
+	var status api.IPFSPinStatus
+	if ipfs.verified.get(ctx, key, &status) {
+		return status, nil
+	}
+
+	status, err := ipfs.pinLsCid(ctx, pin)
+	if err == nil && status.IsPinned(pin.MaxDepth) {
+		ipfs.verified.put(ctx, key, status)
+	}
+	return status, err
+}
+
+// pinLsCid is like PinLsCid but it always asks IPFS.
+func (ipfs *Connector) pinLsCid(ctx context.Context, pin api.Pin) (api.IPFSPinStatus, error) {
+	ctx, span := trace.StartSpan(ctx, "ipfsconn/ipfshttp/pinLsCid")
+	defer span.End()
+
 	ctx, cancel := context.WithTimeout(ctx, ipfs.config.IPFSRequestTimeout)
 	defer cancel()
 
@@ -814,6 +910,16 @@ func (ipfs *Connector) RepoStat(ctx context.Context) (api.IPFSRepoStat, error) {
 	return stats, nil
 }
 
+// Reprovide triggers a reprovide run on the IPFS daemon. Since it can
+// take long on large repositories, it is only bound by the given context.
+func (ipfs *Connector) Reprovide(ctx context.Context) error {
+	ctx, span := trace.StartSpan(ctx, "ipfsconn/ipfshttp/Reprovide")
+	defer span.End()
+
+	_, err := ipfs.postCtx(ctx, "routing/reprovide", "", nil)
+	return err
+}
+
 // RepoGC performs a garbage collection sweep on the cluster peer's IPFS repo.
 func (ipfs *Connector) RepoGC(ctx context.Context) (api.RepoGC, error) {
 	ctx, span := trace.StartSpan(ctx, "ipfsconn/ipfshttp/RepoGC")
@@ -950,6 +1056,73 @@ type chanIterator struct {
 
 	seenMu sync.Mutex
 	seen   map[string]int
+
+	// flow control: blocks sent to IPFS and not acknowledged yet.
+	maxPending   int
+	lagThreshold time.Duration
+	pendingMu    sync.Mutex
+	pending      []time.Time
+	acking       bool          // IPFS has acknowledged at least one block
+	acked        chan struct{} // signaled on every acknowledgement
+	finished     chan struct{} // closed when responses are no longer read
+}
+
+// ack records that IPFS has stored a block.
+func (ci *chanIterator) ack() {
+	ci.pendingMu.Lock()
+	ci.acking = true
+	if len(ci.pending) > 0 {
+		ci.pending = ci.pending[1:]
+	}
+	n := len(ci.pending)
+	ci.pendingMu.Unlock()
+	stats.Record(ci.ctx, observations.BlocksPending.M(int64(n)))
+
+	select {
+	case ci.acked <- struct{}{}:
+	default:
+	}
+}
+
+// waitPending blocks while the number of blocks pending acknowledgement is
+// at maxPending. Flow control is only applied once IPFS has acknowledged a
+// block, as daemons that do not stream responses would otherwise stall.
+func (ci *chanIterator) waitPending() {
+	for {
+		ci.pendingMu.Lock()
+		full := ci.acking && len(ci.pending) >= ci.maxPending
+		var oldest time.Time
+		if full {
+			oldest = ci.pending[0]
+		}
+		ci.pendingMu.Unlock()
+		if !full {
+			return
+		}
+
+		wait := ci.lagThreshold - time.Since(oldest)
+		if wait <= 0 {
+			logger.Warnf(
+				"IPFS is lagging: %d blocks pending acknowledgement for more than %s. Pausing upload",
+				ci.maxPending,
+				ci.lagThreshold,
+			)
+			wait = ci.lagThreshold
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ci.ctx.Done():
+			timer.Stop()
+			return
+		case <-ci.finished:
+			timer.Stop()
+			return
+		case <-ci.acked:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
 }
 
 func (ci *chanIterator) Name() string {
@@ -1019,6 +1192,9 @@ func (ci *chanIterator) Next() bool {
 		ci.seenMu.Lock()
 		ci.seen[string(b.Cid.Hash())]++
 		ci.seenMu.Unlock()
+		ci.pendingMu.Lock()
+		ci.pending = append(ci.pending, time.Now())
+		ci.pendingMu.Unlock()
 		stats.Record(ci.ctx, observations.BlocksAdded.M(1))
 		stats.Record(ci.ctx, observations.BlocksAddedSize.M(int64(len(b.Data))))
 
@@ -1030,6 +1206,8 @@ func (ci *chanIterator) Next() bool {
 		seeBlock(ci.current)
 		return true
 	}
+
+	ci.waitPending()
 	select {
 	case <-ci.ctx.Done():
 		ci.done = true
@@ -1086,10 +1264,15 @@ func (ipfs *Connector) BlockStream(ctx context.Context, blocks <-chan api.NodeWi
 	defer ipfs.updateInformerMetric(ctx)
 
 	it := &chanIterator{
-		ctx:    ctx,
-		blocks: blocks,
-		seen:   make(map[string]int),
-	}
+		ctx:          ctx,
+		blocks:       blocks,
+		seen:         make(map[string]int),
+		maxPending:   ipfs.config.BlockPutMaxPending,
+		lagThreshold: ipfs.config.BlockPutLagThreshold,
+		acked:        make(chan struct{}, 1),
+		finished:     make(chan struct{}),
+	}
+	defer close(it.finished)
 	dir := &chanDirectory{
 		iterator: it,
 	}
@@ -1133,6 +1316,7 @@ func (ipfs *Connector) BlockStream(ctx context.Context, blocks <-chan api.NodeWi
 			break
 		}
 		logger.Debugf("response block: %s", res.Key)
+		it.ack()
 		if !it.Seen(res.Key) {
 			logger.Warningf("blockPut response CID (%s) does not match the multihash of any blocks sent", res.Key)
 		}
@@ -1161,6 +1345,245 @@ func (ipfs *Connector) BlockGet(ctx context.Context, c api.Cid) ([]byte, error)
 	return ipfs.postCtx(ctx, url, "", nil)
 }
 
+// Refs returns up to maxRefs unique CIDs of the blocks referenced by the
+// given CID, recursively. The given CID is not included. A maxRefs value of
+// 0 or less means no limit.
+func (ipfs *Connector) Refs(ctx context.Context, c api.Cid, maxRefs int) ([]api.Cid, error) {
+	ctx, span := trace.StartSpan(ctx, "ipfsconn/ipfshttp/Refs")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, ipfs.config.IPFSRequestTimeout)
+	defer cancel()
+
+	q := url.Values{}
+	q.Set("arg", c.String())
+	q.Set("recursive", "true")
+	q.Set("unique", "true")
+	return ipfs.refs(ctx, q, maxRefs)
+}
+
+// refs performs a "refs" request with the given query and decodes up to
+// maxRefs CIDs from the response (all of them when maxRefs is 0 or less).
+func (ipfs *Connector) refs(ctx context.Context, q url.Values, maxRefs int) ([]api.Cid, error) {
+	body, err := ipfs.postCtxStreamResponse(ctx, "refs?"+q.Encode(), "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var refs []api.Cid
+	dec := json.NewDecoder(body)
+	for maxRefs <= 0 || len(refs) < maxRefs {
+		var ref ipfsRefsResp
+		err := dec.Decode(&ref)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error decoding refs response: %w", err)
+		}
+		if ref.Err != "" {
+			return nil, errors.New(ref.Err)
+		}
+		refCid, err := api.DecodeCid(ref.Ref)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, refCid)
+	}
+	return refs, nil
+}
+
+// connectOrigins tells ipfs to connect to a maximum of 10 of the given
+// origins. It is done in the background, ignoring errors, until the context
+// is cancelled.
+func (ipfs *Connector) connectOrigins(ctx context.Context, origins []api.Multiaddr) {
+	bound := len(origins)
+	if bound > 10 {
+		bound = 10
+	}
+	for _, orig := range origins[0:bound] {
+		go func(o string) {
+			logger.Debugf("swarm-connect to origin: %s", o)
+			_, err := ipfs.postCtx(
+				ctx,
+				fmt.Sprintf("swarm/connect?arg=%s", o),
+				"",
+				nil,
+			)
+			if err != nil {
+				logger.Debug(err)
+				return
+			}
+			logger.Debugf("swarm-connect success to origin: %s", o)
+		}(url.QueryEscape(orig.String()))
+	}
+}
+
+// DagStat returns the size and number of blocks of the DAG of a pin, as
+// reported by "dag stat". IPFS fetches the blocks that it does not have, so
+// it is first told to connect to the origins of the pin. The request lasts
+// as long as the given context allows.
+func (ipfs *Connector) DagStat(ctx context.Context, pin api.Pin) (api.IPFSDagStat, error) {
+	ctx, span := trace.StartSpan(ctx, "ipfsconn/ipfshttp/DagStat")
+	defer span.End()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ipfs.connectOrigins(ctx, pin.Origins)
+
+	q := url.Values{}
+	q.Set("arg", pin.Cid.String())
+	q.Set("progress", "false")
+	res, err := ipfs.postCtx(ctx, "dag/stat?"+q.Encode(), "", nil)
+	if err != nil {
+		return api.IPFSDagStat{}, err
+	}
+
+	var resp ipfsDagStatResp
+	err = json.Unmarshal(res, &resp)
+	if err != nil {
+		logger.Error(err)
+		return api.IPFSDagStat{}, err
+	}
+	// Newer IPFS versions report the stats of each of the requested
+	// DAGs (only one here) and their total size.
+	if len(resp.DagStats) > 0 {
+		return api.IPFSDagStat{
+			Size:      resp.DagStats[0].Size,
+			NumBlocks: resp.DagStats[0].NumBlocks,
+		}, nil
+	}
+	return api.IPFSDagStat{Size: resp.Size, NumBlocks: resp.NumBlocks}, nil
+}
+
+// BlockStat returns the size of a block as reported by "block stat".
+// Results are remembered in the verified cache (when enabled).
+func (ipfs *Connector) BlockStat(ctx context.Context, c api.Cid) (api.IPFSBlockStat, error) {
+	ctx, span := trace.StartSpan(ctx, "ipfsconn/ipfshttp/BlockStat")
+	defer span.End()
+
+	key := "block/" + c.String()
+	var stat api.IPFSBlockStat
+	if ipfs.verified.get(ctx, key, &stat) {
+		return stat, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ipfs.config.IPFSRequestTimeout)
+	defer cancel()
+	res, err := ipfs.postCtx(ctx, "block/stat?arg="+c.String(), "", nil)
+	if err != nil {
+		return api.IPFSBlockStat{}, err
+	}
+
+	err = json.Unmarshal(res, &stat)
+	if err != nil {
+		logger.Error(err)
+		return api.IPFSBlockStat{}, err
+	}
+	ipfs.verified.put(ctx, key, stat)
+	return stat, nil
+}
+
+// verifyMaxDepth bounds the length of the random walks done by
+// VerifyBlocks.
+const verifyMaxDepth = 64
+
+// VerifyBlocks checks, without fetching anything from the network, that
+// the IPFS daemon has the blocks of a random sample of up to in.Sample
+// blocks of the DAG under in.Cid. The sample is taken by walking the DAG
+// from the root down random links, so it always includes the root. Blocks
+// that are not available locally are reported in Missing. Errors are only
+// returned when the check itself could not be performed.
+func (ipfs *Connector) VerifyBlocks(ctx context.Context, in api.IPFSBlockVerification) (api.IPFSBlockVerification, error) {
+	ctx, span := trace.StartSpan(ctx, "ipfsconn/ipfshttp/VerifyBlocks")
+	defer span.End()
+
+	ctx, cancel := context.WithTimeout(ctx, ipfs.config.IPFSRequestTimeout)
+	defer cancel()
+
+	res := api.IPFSBlockVerification{
+		Cid:    in.Cid,
+		Sample: in.Sample,
+	}
+	if in.Sample <= 0 {
+		return res, nil
+	}
+
+	present := make(map[api.Cid]bool)
+	links := make(map[api.Cid][]api.Cid)
+	for walk := 0; walk < 2*in.Sample && res.Checked < in.Sample; walk++ {
+		c := in.Cid
+		for depth := 0; depth < verifyMaxDepth; depth++ {
+			has, checked := present[c]
+			if !checked {
+				var err error
+				has, err = ipfs.hasBlock(ctx, c)
+				if err != nil {
+					return res, err
+				}
+				present[c] = has
+				res.Checked++
+				if !has {
+					res.Missing = append(res.Missing, c)
+				}
+			}
+			if !has || res.Checked >= in.Sample {
+				break
+			}
+
+			children, ok := links[c]
+			if !ok {
+				q := url.Values{}
+				q.Set("arg", c.String())
+				q.Set("offline", "true")
+				refs, err := ipfs.refs(ctx, q, 0)
+				if err != nil {
+					return res, err
+				}
+				for _, ref := range refs {
+					if !ref.Equals(c) {
+						children = append(children, ref)
+					}
+				}
+				links[c] = children
+			}
+			if len(children) == 0 {
+				break
+			}
+			c = children[rand.Intn(len(children))]
+		}
+		// A DAG made of a single block has been fully checked.
+		if len(links[in.Cid]) == 0 {
+			break
+		}
+	}
+	return res, nil
+}
+
+// hasBlock returns whether the IPFS daemon has the given block locally.
+func (ipfs *Connector) hasBlock(ctx context.Context, c api.Cid) (bool, error) {
+	q := url.Values{}
+	q.Set("arg", c.String())
+	q.Set("offline", "true")
+	_, err := ipfs.postCtx(ctx, "block/stat?"+q.Encode(), "", nil)
+	if err == nil {
+		return true, nil
+	}
+	var ierr ipfsError
+	if errors.As(err, &ierr) && strings.Contains(ierr.Message, "not found") {
+		return false, nil
+	}
+	return false, err
+}
+
+// verifiedPinPrefix returns the prefix of the verified cache keys for the
+// pin statuses of the given cid.
+func verifiedPinPrefix(c api.Cid) string {
+	return "pin/" + c.String() + "/"
+}
+
 // // FetchRefs asks IPFS to download blocks recursively to the given depth.
 // // It discards the response, but waits until it completes.
 // func (ipfs *Connector) FetchRefs(ctx context.Context, c api.Cid, maxDepth int) error {
@@ -11,8 +11,12 @@ import (
 	ma "github.com/multiformats/go-multiaddr"
 
 	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/datastore/inmem"
+	"github.com/ipfs-cluster/ipfs-cluster/kvstore"
 	"github.com/ipfs-cluster/ipfs-cluster/test"
 	merkledag "github.com/ipfs/boxo/ipld/merkledag"
+	cid "github.com/ipfs/go-cid"
+	multihash "github.com/multiformats/go-multihash"
 )
 
 func init() {
@@ -60,6 +64,9 @@ func TestIPFSID(t *testing.T) {
 	if len(id.Addresses) != 2 {
 		t.Error("expected 2 address")
 	}
+	if id.Version != test.IpfsAgentVersion {
+		t.Error("expected the IPFS agent version")
+	}
 	if id.Error != "" {
 		t.Error("expected no error")
 	}
@@ -107,6 +114,40 @@ func TestPin(t *testing.T) {
 	}
 }
 
+func TestPinProgress(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+
+	c := test.SlowCid1
+	pinCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ipfs.Pin(pinCtx, api.PinCid(c))
+	}()
+	time.Sleep(time.Second)
+
+	p, err := ipfs.PinProgress(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Defined() {
+		t.Error("expected progress for an ongoing pin")
+	}
+
+	<-done
+	p, err = ipfs.PinProgress(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Defined() {
+		t.Error("expected no progress once the pin is over")
+	}
+}
+
 func TestPinUpdate(t *testing.T) {
 	ctx := context.Background()
 	ipfs, mock := testIPFSConnector(t)
@@ -240,6 +281,57 @@ func collectPins(t *testing.T, pch <-chan api.IPFSPinInfo) []api.IPFSPinInfo {
 	}
 }
 
+func TestIPFSPinLsCidVerifiedCache(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+	ipfs.config.VerifiedCacheTTL = time.Minute
+	ipfs.verified = newVerifiedCache(ipfs.config.VerifiedCacheTTL)
+	store := kvstore.New(inmem.New())
+	ipfs.SetScratchStore(store)
+
+	pin := api.PinCid(test.Cid1)
+	err := ipfs.Pin(ctx, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		ips, err := ipfs.PinLsCid(ctx, pin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ips.IsPinned(-1) {
+			t.Error("c should appear pinned")
+		}
+	}
+	// One from Pin, one from the first PinLsCid.
+	time.Sleep(100 * time.Millisecond)
+	if n := mock.GetCount("pin/ls"); n != 2 {
+		t.Errorf("pin/ls should have been called twice: %d", n)
+	}
+
+	// The cache is persisted.
+	vc := newVerifiedCache(time.Minute)
+	vc.setStore(ctx, store.Namespace("/ipfshttp/verified"))
+	var status api.IPFSPinStatus
+	if !vc.get(ctx, verifiedPinPrefix(test.Cid1)+"recursive", &status) || !status.IsPinned(-1) {
+		t.Error("the verified cache should have been loaded from the store")
+	}
+
+	err = ipfs.Unpin(ctx, test.Cid1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ips, err := ipfs.PinLsCid(ctx, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ips.IsPinned(-1) {
+		t.Error("unpinning should invalidate the verified cache")
+	}
+}
+
 func TestIPFSPinLs(t *testing.T) {
 	ctx := context.Background()
 	ipfs, mock := testIPFSConnector(t)
@@ -354,6 +446,76 @@ func TestBlockStream(t *testing.T) {
 	}
 }
 
+func TestBlockStreamFlowControl(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+	ipfs.config.BlockPutMaxPending = 2
+
+	blocks := make(chan api.NodeWithMeta)
+	var sent []api.Cid
+	go func() {
+		defer close(blocks)
+		for i := 0; i < 20; i++ {
+			data := []byte(fmt.Sprintf("block %d", i))
+			c, err := cid.V1Builder{Codec: cid.Raw, MhType: multihash.SHA2_256}.Sum(data)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			sent = append(sent, api.NewCid(c))
+			blocks <- api.NodeWithMeta{Cid: api.NewCid(c), Data: data}
+		}
+	}()
+
+	err := ipfs.BlockStream(ctx, blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range sent {
+		_, err := ipfs.BlockGet(ctx, c)
+		if err != nil {
+			t.Errorf("block %s was not put: %s", c, err)
+		}
+	}
+}
+
+func TestChanIteratorWaitPending(t *testing.T) {
+	it := &chanIterator{
+		ctx:          context.Background(),
+		maxPending:   1,
+		lagThreshold: time.Second,
+		pending:      []time.Time{time.Now()},
+		acked:        make(chan struct{}, 1),
+		finished:     make(chan struct{}),
+	}
+
+	// No acknowledgements received yet: no flow control.
+	it.waitPending()
+
+	it.acking = true
+	done := make(chan struct{})
+	go func() {
+		it.waitPending()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("waitPending should block while the window is full")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	it.ack()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("waitPending should return after an acknowledgement")
+	}
+}
+
 func TestBlockGet(t *testing.T) {
 	ctx := context.Background()
 	ipfs, mock := testIPFSConnector(t)
@@ -387,6 +549,115 @@ func TestBlockGet(t *testing.T) {
 	}
 }
 
+func TestBlockStat(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+
+	_, err := ipfs.BlockStat(ctx, test.ShardCid)
+	if err == nil {
+		t.Fatal("expected to fail getting stats for unput block")
+	}
+
+	blocks := make(chan api.NodeWithMeta, 1)
+	blocks <- api.NodeWithMeta{
+		Data: test.ShardData,
+		Cid:  test.ShardCid,
+	}
+	close(blocks)
+	err = ipfs.BlockStream(ctx, blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stat, err := ipfs.BlockStat(ctx, test.ShardCid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Size != len(test.ShardData) {
+		t.Errorf("unexpected block size: %d", stat.Size)
+	}
+}
+
+func TestDagStat(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+
+	blocks := make(chan api.NodeWithMeta, 1)
+	blocks <- api.NodeWithMeta{
+		Data: test.ShardData,
+		Cid:  test.ShardCid,
+	}
+	close(blocks)
+	err := ipfs.BlockStream(ctx, blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stat, err := ipfs.DagStat(ctx, api.PinCid(test.ShardCid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Size != uint64(len(test.ShardData)) || stat.NumBlocks != 1 {
+		t.Errorf("unexpected dag stats: %+v", stat)
+	}
+}
+
+func TestRefs(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+
+	refs, err := ipfs.Refs(ctx, test.Cid1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// See the ipfs mock implementation
+	if len(refs) != 1 || !refs[0].Equals(test.Cid1) {
+		t.Errorf("unexpected refs: %v", refs)
+	}
+}
+
+func TestVerifyBlocks(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+
+	in := api.IPFSBlockVerification{Cid: test.ShardCid, Sample: 10}
+	res, err := ipfs.VerifyBlocks(ctx, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// See the ipfs mock implementation: the block has no links.
+	if res.Checked != 1 || len(res.Missing) != 1 || !res.Missing[0].Equals(test.ShardCid) {
+		t.Errorf("expected the root block to be missing: %+v", res)
+	}
+
+	blocks := make(chan api.NodeWithMeta, 1)
+	blocks <- api.NodeWithMeta{
+		Data: test.ShardData,
+		Cid:  test.ShardCid,
+	}
+	close(blocks)
+	err = ipfs.BlockStream(ctx, blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = ipfs.VerifyBlocks(ctx, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Checked != 1 || len(res.Missing) != 0 {
+		t.Errorf("expected no missing blocks: %+v", res)
+	}
+}
+
 func TestRepoStat(t *testing.T) {
 	ctx := context.Background()
 	ipfs, mock := testIPFSConnector(t)
@@ -505,3 +776,18 @@ func TestRepoGC(t *testing.T) {
 		t.Errorf("expected different error, expected: %s, found: %s\n", merkledag.ErrLinkNotFound, res.Keys[4].Error)
 	}
 }
+
+func TestReprovide(t *testing.T) {
+	ctx := context.Background()
+	ipfs, mock := testIPFSConnector(t)
+	defer mock.Close()
+	defer ipfs.Shutdown(ctx)
+
+	err := ipfs.Reprovide(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mock.GetCount("routing/reprovide") != 1 {
+		t.Error("expected a reprovide request to the daemon")
+	}
+}
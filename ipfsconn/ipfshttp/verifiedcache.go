@@ -0,0 +1,164 @@
+package ipfshttp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/kvstore"
+)
+
+// verifiedCache remembers the results of recent checks made against the
+// IPFS daemon (pinned CIDs, block stats) for a limited time, so that
+// repeated status requests for the same items do not translate into
+// repeated requests to IPFS. When a scratch store is set, entries are
+// persisted in it and survive restarts.
+type verifiedCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]verifiedEntry
+	store   *kvstore.Store
+}
+
+type verifiedEntry struct {
+	Expire time.Time       `json:"expire"`
+	Value  json.RawMessage `json:"value"`
+}
+
+func (e verifiedEntry) expired() bool {
+	return time.Now().After(e.Expire)
+}
+
+func newVerifiedCache(ttl time.Duration) *verifiedCache {
+	return &verifiedCache{
+		ttl:     ttl,
+		entries: make(map[string]verifiedEntry),
+	}
+}
+
+// enabled returns false when the cache has been disabled by configuration.
+func (vc *verifiedCache) enabled() bool {
+	return vc.ttl > 0
+}
+
+// setStore loads the non-expired entries persisted in the given store and
+// persists new entries to it from now on.
+func (vc *verifiedCache) setStore(ctx context.Context, store *kvstore.Store) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+
+	vc.store = store
+	if !vc.enabled() {
+		// Do not keep anything around from times when it was.
+		if err := store.Clear(ctx); err != nil {
+			logger.Warnf("error clearing the verified cache: %s", err)
+		}
+		return
+	}
+
+	var expired []string
+	err := store.Iterate(ctx, func(key string, value []byte) error {
+		var e verifiedEntry
+		if err := json.Unmarshal(value, &e); err != nil || e.expired() {
+			expired = append(expired, key)
+			return nil
+		}
+		vc.entries[key] = e
+		return nil
+	})
+	if err != nil {
+		logger.Warnf("error loading the verified cache: %s", err)
+	}
+	for _, key := range expired {
+		if err := store.Delete(ctx, key); err != nil {
+			logger.Warnf("error removing expired verified cache entry %s: %s", key, err)
+		}
+	}
+}
+
+// get decodes the cached value for key into v. It returns false when there
+// is no such value or when it has expired.
+func (vc *verifiedCache) get(ctx context.Context, key string, v interface{}) bool {
+	if !vc.enabled() {
+		return false
+	}
+
+	vc.mu.Lock()
+	e, ok := vc.entries[key]
+	if ok && e.expired() {
+		vc.remove(ctx, key)
+		ok = false
+	}
+	vc.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(e.Value, v) == nil
+}
+
+// put caches v under key for the configured TTL.
+func (vc *verifiedCache) put(ctx context.Context, key string, v interface{}) {
+	if !vc.enabled() {
+		return
+	}
+
+	value, err := json.Marshal(v)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	e := verifiedEntry{
+		Expire: time.Now().Add(vc.ttl),
+		Value:  value,
+	}
+
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	vc.entries[key] = e
+	if vc.store != nil {
+		if err := vc.store.Put(ctx, key, e); err != nil {
+			logger.Warnf("error persisting verified cache entry %s: %s", key, err)
+		}
+	}
+}
+
+// invalidate removes all the entries whose key starts with the given
+// prefix.
+func (vc *verifiedCache) invalidate(ctx context.Context, prefix string) {
+	if !vc.enabled() {
+		return
+	}
+
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	for key := range vc.entries {
+		if strings.HasPrefix(key, prefix) {
+			vc.remove(ctx, key)
+		}
+	}
+}
+
+// purge removes the expired entries.
+func (vc *verifiedCache) purge(ctx context.Context) {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	for key, e := range vc.entries {
+		if e.expired() {
+			vc.remove(ctx, key)
+		}
+	}
+}
+
+// remove deletes an entry. The lock must be held.
+func (vc *verifiedCache) remove(ctx context.Context, key string) {
+	delete(vc.entries, key)
+	if vc.store != nil {
+		if err := vc.store.Delete(ctx, key); err != nil {
+			logger.Warnf("error removing verified cache entry %s: %s", key, err)
+		}
+	}
+}
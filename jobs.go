@@ -0,0 +1,146 @@
+package ipfscluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/rpcutil"
+
+	rpc "github.com/libp2p/go-libp2p-gorpc"
+	trace "go.opencensus.io/trace"
+)
+
+// Maintenance jobs that can be run on cluster peers with RunJob.
+const (
+	// JobReconnectIPFS connects the IPFS daemon to the IPFS daemons of
+	// the other cluster peers.
+	JobReconnectIPFS = "reconnect-ipfs"
+	// JobFlushMetrics publishes fresh informer metrics right away.
+	JobFlushMetrics = "flush-metrics"
+	// JobReprovide announces the content of the IPFS daemon to the
+	// routing system again.
+	JobReprovide = "reprovide"
+)
+
+// how long a peer may take to run a job.
+var jobTimeout = 10 * time.Minute
+
+var jobs = map[string]func(context.Context, *Cluster) error{
+	JobReconnectIPFS: func(ctx context.Context, c *Cluster) error {
+		return c.ipfs.ConnectSwarms(ctx)
+	},
+	JobFlushMetrics: func(ctx context.Context, c *Cluster) error {
+		return c.sendInformersMetrics(ctx)
+	},
+	JobReprovide: func(ctx context.Context, c *Cluster) error {
+		return c.ipfs.Reprovide(ctx)
+	},
+}
+
+// Jobs returns the names of the maintenance jobs that can be run with
+// RunJob.
+func Jobs() []string {
+	names := make([]string, 0, len(jobs))
+	for name := range jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RunJob runs a maintenance job on the requested peers (all cluster peers by
+// default) and returns the result from each of them.
+func (c *Cluster) RunJob(ctx context.Context, req api.JobRequest) (api.GlobalJobResult, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/RunJob")
+	defer span.End()
+
+	if _, ok := jobs[req.Job]; !ok {
+		return api.GlobalJobResult{}, fmt.Errorf("unknown job: %s", req.Job)
+	}
+
+	dests := req.Peers
+	if len(dests) == 0 {
+		var err error
+		dests, err = c.consensus.Peers(ctx)
+		if err != nil {
+			logger.Error(err)
+			return api.GlobalJobResult{}, err
+		}
+	}
+
+	logger.Infof("running job %s on %d peers", req.Job, len(dests))
+
+	ctxs, cancels := rpcutil.CtxsWithTimeout(ctx, len(dests), jobTimeout)
+	defer rpcutil.MultiCancel(cancels)
+
+	replies := make([]api.JobResult, len(dests))
+	ifaces := make([]interface{}, len(dests))
+	for i := range replies {
+		ifaces[i] = &replies[i]
+	}
+	errs := c.rpcClient.MultiCall(
+		ctxs,
+		dests,
+		"Cluster",
+		"RunJobLocal",
+		req.Job,
+		ifaces,
+	)
+
+	global := api.GlobalJobResult{
+		Job:     req.Job,
+		PeerMap: make(map[string]api.JobResult, len(dests)),
+	}
+	for i, p := range dests {
+		err := errs[i]
+		if err == nil {
+			global.PeerMap[p.String()] = replies[i]
+			continue
+		}
+
+		if rpc.IsAuthorizationError(err) {
+			logger.Debug("rpc auth error:", err)
+			continue
+		}
+
+		logger.Errorf("%s: error in broadcast response from %s: %s ", c.id, p, err)
+		pv := pingValueFromMetric(c.monitor.LatestForPeer(ctx, pingMetricName, p))
+		global.PeerMap[p.String()] = api.JobResult{
+			Peer:     p,
+			Peername: c.peername(pv, p),
+			Job:      req.Job,
+			Error:    err.Error(),
+		}
+	}
+	return global, nil
+}
+
+// RunJobLocal runs a maintenance job on this peer. Errors running the job
+// are part of the result.
+func (c *Cluster) RunJobLocal(ctx context.Context, job string) (api.JobResult, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/RunJobLocal")
+	defer span.End()
+
+	run, ok := jobs[job]
+	if !ok {
+		return api.JobResult{}, fmt.Errorf("unknown job: %s", job)
+	}
+
+	logger.Infof("running job %s", job)
+	start := time.Now()
+	err := run(ctx, c)
+	res := api.JobResult{
+		Peer:     c.id,
+		Peername: c.config.Peername,
+		Job:      job,
+		Duration: time.Since(start),
+	}
+	if err != nil {
+		logger.Errorf("job %s failed: %s", job, err)
+		res.Error = err.Error()
+	}
+	return res, nil
+}
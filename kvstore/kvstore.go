@@ -0,0 +1,112 @@
+// Package kvstore provides a small key-value store on top of the cluster
+// peer datastore, which components can use to persist scratch data (retry
+// counters, caches, timestamps...) that is local to the peer and not part of
+// the shared state.
+package kvstore
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+)
+
+// RootNamespace is the datastore namespace under which all the scratch
+// data is stored.
+const RootNamespace = "/scratch"
+
+// ErrNotFound is returned by Get when the key does not exist.
+var ErrNotFound = ds.ErrNotFound
+
+// Store is a namespaced key-value store. Values are JSON-encoded.
+type Store struct {
+	store ds.Datastore
+}
+
+// New returns a Store which writes to the given datastore under
+// RootNamespace.
+func New(store ds.Datastore) *Store {
+	return &Store{
+		store: namespace.Wrap(store, ds.NewKey(RootNamespace)),
+	}
+}
+
+// Namespace returns a Store whose keys are prefixed with the given name.
+// Components should use their own namespace so that their keys do not
+// clash.
+func (s *Store) Namespace(name string) *Store {
+	return &Store{
+		store: namespace.Wrap(s.store, ds.NewKey(name)),
+	}
+}
+
+// Put stores the JSON representation of v under the given key.
+func (s *Store) Put(ctx context.Context, key string, v interface{}) error {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.store.Put(ctx, ds.NewKey(key), bs)
+}
+
+// Get decodes the value stored under the given key into v. It returns
+// ErrNotFound when the key does not exist.
+func (s *Store) Get(ctx context.Context, key string, v interface{}) error {
+	bs, err := s.store.Get(ctx, ds.NewKey(key))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bs, v)
+}
+
+// Has returns whether the given key exists.
+func (s *Store) Has(ctx context.Context, key string) (bool, error) {
+	return s.store.Has(ctx, ds.NewKey(key))
+}
+
+// Delete removes the given key. Deleting a key that does not exist is not
+// an error.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	return s.store.Delete(ctx, ds.NewKey(key))
+}
+
+// Iterate calls f with every key in the store (without the leading "/")
+// and its raw JSON value, stopping at the first error.
+func (s *Store) Iterate(ctx context.Context, f func(key string, value []byte) error) error {
+	results, err := s.store.Query(ctx, query.Query{})
+	if err != nil {
+		return err
+	}
+	defer results.Close()
+
+	for r := range results.Next() {
+		if r.Error != nil {
+			return r.Error
+		}
+		if err := f(strings.TrimPrefix(r.Key, "/"), r.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Clear removes all the keys in the store.
+func (s *Store) Clear(ctx context.Context) error {
+	var keys []string
+	err := s.Iterate(ctx, func(key string, _ []byte) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := s.Delete(ctx, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package kvstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs-cluster/ipfs-cluster/datastore/inmem"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	store := New(inmem.New())
+	a := store.Namespace("/a")
+	b := store.Namespace("/b")
+
+	err := a.Put(ctx, "key", map[string]int{"n": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.Put(ctx, "key", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m map[string]int
+	err = a.Get(ctx, "key", &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["n"] != 1 {
+		t.Errorf("unexpected value: %v", m)
+	}
+
+	var str string
+	err = b.Get(ctx, "key", &str)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if str != "value" {
+		t.Errorf("unexpected value: %s", str)
+	}
+
+	err = a.Get(ctx, "missing", &str)
+	if err != ErrNotFound {
+		t.Errorf("expected ErrNotFound: %s", err)
+	}
+
+	var keys []string
+	err = store.Iterate(ctx, func(key string, _ []byte) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("expected 2 keys: %v", keys)
+	}
+
+	err = a.Clear(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	has, _ := a.Has(ctx, "key")
+	if has {
+		t.Error("key should have been cleared")
+	}
+	has, _ = b.Has(ctx, "key")
+	if !has {
+		t.Error("clearing a namespace should not affect others")
+	}
+
+	err = b.Delete(ctx, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	has, _ = b.Has(ctx, "key")
+	if has {
+		t.Error("key should have been deleted")
+	}
+}
@@ -16,6 +16,7 @@ var LoggingFacilities = map[string]string{
 	"pinsvcapilog": "INFO",
 	"ipfsproxy":    "INFO",
 	"ipfsproxylog": "INFO",
+	"grpcapi":      "INFO",
 	"ipfshttp":     "INFO",
 	"monitor":      "INFO",
 	"dsstate":      "INFO",
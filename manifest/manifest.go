@@ -0,0 +1,259 @@
+// Package manifest implements signed peerset manifests. A manifest is
+// published by a trusted cluster peer (i.e. on IPNS) and contains the
+// information that follower peers need to bootstrap: the trusted peers, the
+// addresses of cluster peers and the hash of the configuration template.
+// Followers verify that the manifest was signed by one of the peers they
+// already trust before using it, and that it is not older than the last
+// manifest they accepted, so that old manifests cannot be replayed.
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	crypto "github.com/libp2p/go-libp2p/core/crypto"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+// Version of the manifest format.
+const Version = 1
+
+// maximum size of a manifest that we are willing to read.
+const maxManifestSize = 1 << 20
+
+// ErrUntrustedSigner is returned when a manifest is not signed by a trusted
+// peer.
+var ErrUntrustedSigner = errors.New("manifest signer is not trusted")
+
+// ErrBadSignature is returned when the manifest signature does not verify.
+var ErrBadSignature = errors.New("manifest signature is not valid")
+
+// ErrStale is returned when a manifest is older than the one in use.
+var ErrStale = errors.New("manifest is older than the current one")
+
+// Manifest describes the peerset of a cluster.
+type Manifest struct {
+	Version       int       `json:"version"`
+	TrustedPeers  []peer.ID `json:"trusted_peers"`
+	PeerAddresses []string  `json:"peer_addresses"`
+	// ConfigHash is the hex-encoded sha256 of the configuration template
+	// that followers use.
+	ConfigHash string    `json:"config_hash,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// Signed wraps a JSON-serialized Manifest along with the signature of its
+// issuer.
+type Signed struct {
+	Payload   []byte  `json:"payload"`
+	Signer    peer.ID `json:"signer"`
+	Signature []byte  `json:"signature"`
+}
+
+// HashConfig returns the hash of a configuration, as used in
+// Manifest.ConfigHash.
+func HashConfig(cfg []byte) string {
+	sum := sha256.Sum256(cfg)
+	return hex.EncodeToString(sum[:])
+}
+
+// Sign serializes and signs a manifest with the given private key.
+func Sign(m Manifest, priv crypto.PrivKey) (*Signed, error) {
+	if m.Version == 0 {
+		m.Version = Version
+	}
+	if m.Timestamp.IsZero() {
+		m.Timestamp = time.Now().UTC()
+	}
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := peer.IDFromPrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := priv.Sign(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &Signed{
+		Payload:   payload,
+		Signer:    signer,
+		Signature: sig,
+	}, nil
+}
+
+// Open verifies the signature of the manifest and returns it. The signer
+// must be one of the given trusted peers. There is no way to accept any
+// signer: a manifest that anyone can sign would let anyone choose the
+// peers that a follower trusts.
+func (s *Signed) Open(trusted []peer.ID) (Manifest, error) {
+	if !containsPeer(trusted, s.Signer) {
+		return Manifest{}, fmt.Errorf("%w: %s", ErrUntrustedSigner, s.Signer)
+	}
+
+	pub, err := s.Signer.ExtractPublicKey()
+	if err != nil {
+		return Manifest{}, fmt.Errorf("cannot obtain the public key of %s: %w", s.Signer, err)
+	}
+	ok, err := pub.Verify(s.Payload, s.Signature)
+	if err != nil || !ok {
+		return Manifest{}, ErrBadSignature
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(s.Payload, &m); err != nil {
+		return Manifest{}, fmt.Errorf("error decoding manifest: %w", err)
+	}
+	if m.Version != Version {
+		return Manifest{}, fmt.Errorf("unsupported manifest version %d", m.Version)
+	}
+	return m, nil
+}
+
+// CheckNewer returns ErrStale when the manifest is older than the given
+// one. Manifests with the same timestamp are accepted.
+func (m Manifest) CheckNewer(current Manifest) error {
+	if m.Timestamp.Before(current.Timestamp) {
+		return fmt.Errorf("%w: %s is before %s", ErrStale,
+			m.Timestamp.Format(time.RFC3339), current.Timestamp.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Load reads a manifest written by Save. It returns an empty manifest when
+// the file does not exist.
+func Load(path string) (Manifest, error) {
+	var m Manifest
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(data, &m)
+	return m, err
+}
+
+// Save writes a manifest that has been accepted to the given path, so that
+// older ones are rejected after restarting.
+func Save(path string, m Manifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Fetch retrieves a signed manifest from the given URL.
+func Fetch(ctx context.Context, client *http.Client, manifestURL string) (*Signed, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching manifest: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching manifest from %s: %s", manifestURL, resp.Status)
+	}
+
+	var s Signed
+	dec := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize))
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("error decoding signed manifest: %w", err)
+	}
+	return &s, nil
+}
+
+// Publish adds the signed manifest to IPFS using the HTTP API at apiURL
+// (i.e. http://127.0.0.1:5001) and publishes it on IPNS with the given key
+// (the node's default key when empty). It returns the IPNS name.
+func Publish(ctx context.Context, client *http.Client, apiURL string, s *Signed, key string) (string, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	body := new(bytes.Buffer)
+	mpw := multipart.NewWriter(body)
+	part, err := mpw.CreateFormFile("file", "manifest.json")
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", err
+	}
+	if err := mpw.Close(); err != nil {
+		return "", err
+	}
+
+	var added struct {
+		Hash string
+	}
+	err = ipfsPost(ctx, client, apiURL+"/api/v0/add?pin=true", mpw.FormDataContentType(), body, &added)
+	if err != nil {
+		return "", fmt.Errorf("error adding manifest to IPFS: %w", err)
+	}
+
+	q := url.Values{}
+	q.Set("arg", "/ipfs/"+added.Hash)
+	if key != "" {
+		q.Set("key", key)
+	}
+	var published struct {
+		Name string
+	}
+	err = ipfsPost(ctx, client, apiURL+"/api/v0/name/publish?"+q.Encode(), "", nil, &published)
+	if err != nil {
+		return "", fmt.Errorf("error publishing manifest on IPNS: %w", err)
+	}
+	return published.Name, nil
+}
+
+func ipfsPost(ctx context.Context, client *http.Client, u, contentType string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, respBody)
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+func containsPeer(peers []peer.ID, p peer.ID) bool {
+	for _, pid := range peers {
+		if pid == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package manifest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	crypto "github.com/libp2p/go-libp2p/core/crypto"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+func testKey(t *testing.T) (crypto.PrivKey, peer.ID) {
+	t.Helper()
+	priv, _, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := peer.IDFromPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv, pid
+}
+
+func TestSignOpen(t *testing.T) {
+	priv, pid := testKey(t)
+	_, other := testKey(t)
+
+	m := Manifest{
+		TrustedPeers:  []peer.ID{pid, other},
+		PeerAddresses: []string{"/ip4/1.2.3.4/tcp/9096/p2p/" + pid.String()},
+		ConfigHash:    HashConfig([]byte("{}")),
+	}
+	signed, err := Sign(m, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opened, err := signed.Open([]peer.ID{pid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opened.TrustedPeers) != 2 || opened.ConfigHash != m.ConfigHash {
+		t.Errorf("unexpected manifest: %+v", opened)
+	}
+
+	_, err = signed.Open([]peer.ID{other})
+	if !errors.Is(err, ErrUntrustedSigner) {
+		t.Error("expected an untrusted signer error:", err)
+	}
+
+	_, err = signed.Open(nil)
+	if !errors.Is(err, ErrUntrustedSigner) {
+		t.Error("no signer should be accepted without trusted peers:", err)
+	}
+
+	signed.Payload = []byte(`{"version":1,"trusted_peers":[]}`)
+	_, err = signed.Open([]peer.ID{pid})
+	if !errors.Is(err, ErrBadSignature) {
+		t.Error("expected a bad signature error:", err)
+	}
+}
+
+func TestCheckNewerSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+
+	current, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !current.Timestamp.IsZero() {
+		t.Errorf("expected an empty manifest: %+v", current)
+	}
+
+	m := Manifest{Version: Version, Timestamp: time.Now().UTC()}
+	if err := m.CheckNewer(current); err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(path, m); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !current.Timestamp.Equal(m.Timestamp) {
+		t.Errorf("unexpected saved manifest: %+v", current)
+	}
+	if err := m.CheckNewer(current); err != nil {
+		t.Error("the same manifest should be accepted again:", err)
+	}
+
+	old := Manifest{Version: Version, Timestamp: m.Timestamp.Add(-time.Minute)}
+	if err := old.CheckNewer(current); !errors.Is(err, ErrStale) {
+		t.Error("expected a stale manifest error:", err)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	priv, pid := testKey(t)
+	signed, err := Sign(Manifest{TrustedPeers: []peer.ID{pid}}, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(signed)
+	}))
+	defer srv.Close()
+
+	fetched, err := Fetch(context.Background(), srv.Client(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fetched.Open([]peer.ID{pid})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	priv, pid := testKey(t)
+	signed, err := Sign(Manifest{TrustedPeers: []peer.ID{pid}}, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v0/add":
+			w.Write([]byte(`{"Hash":"QmManifest"}`))
+		case "/api/v0/name/publish":
+			if r.URL.Query().Get("arg") != "/ipfs/QmManifest" {
+				http.Error(w, "bad arg", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"Name":"k51name"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	name, err := Publish(context.Background(), srv.Client(), srv.URL, signed, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "k51name" {
+		t.Errorf("unexpected IPNS name: %s", name)
+	}
+}
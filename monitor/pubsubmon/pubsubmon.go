@@ -11,6 +11,7 @@ import (
 
 	"github.com/ipfs-cluster/ipfs-cluster/api"
 	"github.com/ipfs-cluster/ipfs-cluster/monitor/metrics"
+	"github.com/ipfs-cluster/ipfs-cluster/recovery"
 
 	logging "github.com/ipfs/go-log/v2"
 	peer "github.com/libp2p/go-libp2p/core/peer"
@@ -107,8 +108,10 @@ func New(
 func (mon *Monitor) run() {
 	select {
 	case <-mon.rpcReady:
-		go mon.logFromPubsub()
-		go mon.checker.Watch(mon.ctx, mon.peers, mon.config.CheckInterval)
+		recovery.Go(mon.ctx, "monitor/pubsub", mon.logFromPubsub)
+		recovery.Go(mon.ctx, "monitor/checker", func() {
+			mon.checker.Watch(mon.ctx, mon.peers, mon.config.CheckInterval)
+		})
 	case <-mon.ctx.Done():
 	}
 }
@@ -0,0 +1,110 @@
+package ipfscluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+)
+
+// setupNamespace keeps the namespace of the existing pin when the new one
+// does not set any, and checks the quota of the namespace when the pin is
+// new. Pins cannot be moved to a different namespace by re-pinning them.
+func (c *Cluster) setupNamespace(ctx context.Context, pin, existing api.Pin) (api.Pin, error) {
+	if !existing.Defined() {
+		return pin, c.checkNamespaceQuota(ctx, pin)
+	}
+
+	if pin.Namespace == "" {
+		pin.Namespace = existing.Namespace
+	}
+	if pin.Namespace != existing.Namespace {
+		msg := "cannot repin a CID which is pinned in a different "
+		msg += "namespace. Unpin it first."
+		return pin, errors.New(msg)
+	}
+	return pin, nil
+}
+
+// checkNamespaceQuota returns an error when the namespace of a new pin
+// already holds as many pins as its quota allows. Only the pins added by
+// users (not the shards or the cluster DAGs of sharded pins) count.
+func (c *Cluster) checkNamespaceQuota(ctx context.Context, pin api.Pin) error {
+	quota, ok := c.config.NamespaceQuotas[pin.Namespace]
+	if !ok || !countsForQuota(pin) {
+		return nil
+	}
+
+	cState, err := c.consensus.State(ctx)
+	if err != nil {
+		return err
+	}
+
+	pins := make(chan api.Pin, 1024)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cState.List(ctx, pins)
+	}()
+
+	var n int
+	for p := range pins {
+		if p.Namespace == pin.Namespace && countsForQuota(p) {
+			n++
+		}
+	}
+	if err := <-errCh; err != nil {
+		return err
+	}
+
+	if n >= quota {
+		return fmt.Errorf("namespace %s has reached its quota of %d pins", pin.Namespace, quota)
+	}
+	return nil
+}
+
+// checkPinName returns an error when UniquePinNames is enabled and the name
+// of the pin is used by a different CID in the same namespace. A pin update
+// can take the name of the pin that it updates.
+func (c *Cluster) checkPinName(ctx context.Context, pin, existing api.Pin) error {
+	if !c.config.UniquePinNames || pin.Name == "" || !countsForQuota(pin) {
+		return nil
+	}
+	if existing.Defined() && existing.Name == pin.Name {
+		return nil
+	}
+
+	cState, err := c.consensus.State(ctx)
+	if err != nil {
+		return err
+	}
+
+	pins := make(chan api.Pin, 1024)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cState.List(ctx, pins)
+	}()
+
+	var conflict api.Cid
+	for p := range pins {
+		if conflict.Defined() || p.Name != pin.Name || p.Namespace != pin.Namespace {
+			continue
+		}
+		if p.Cid.Equals(pin.Cid) || p.Cid.Equals(pin.PinUpdate) || !countsForQuota(p) {
+			continue
+		}
+		conflict = p.Cid
+	}
+	if err := <-errCh; err != nil {
+		return err
+	}
+
+	if conflict.Defined() {
+		return fmt.Errorf("%w: %s already uses the name %q", api.ErrPinNameConflict, conflict, pin.Name)
+	}
+	return nil
+}
+
+func countsForQuota(pin api.Pin) bool {
+	return pin.Type == api.DataType || pin.Type == api.MetaType
+}
@@ -23,6 +23,9 @@ const (
 	DefaultEnableStats        = false
 	DefaultPrometheusEndpoint = "/ip4/127.0.0.1/tcp/8888"
 	DefaultReportingInterval  = 2 * time.Second
+	DefaultExportType         = ""
+	DefaultExportTable        = "ipfscluster_metrics"
+	DefaultExportInterval     = 10 * time.Second
 
 	DefaultEnableTracing       = false
 	DefaultJaegerAgentEndpoint = "/ip4/0.0.0.0/udp/6831"
@@ -30,6 +33,12 @@ const (
 	DefaultServiceName         = "cluster-daemon"
 )
 
+// Metrics exporters that can be used as ExportType.
+const (
+	ExportInfluxDB  = "influxdb"
+	ExportTimescale = "timescale"
+)
+
 // MetricsConfig configures metrics collection.
 type MetricsConfig struct {
 	config.Saver
@@ -37,12 +46,41 @@ type MetricsConfig struct {
 	EnableStats        bool
 	PrometheusEndpoint ma.Multiaddr
 	ReportingInterval  time.Duration
+
+	// ExportType enables writing metrics directly to a time series
+	// database: "influxdb" (line protocol over HTTP) or "timescale"
+	// (PostgreSQL). It works independently from EnableStats.
+	ExportType string
+	// ExportEndpoint is the InfluxDB write URL (i.e.
+	// http://localhost:8086/write?db=cluster) or the PostgreSQL
+	// connection string.
+	ExportEndpoint string
+	// ExportToken is sent as an InfluxDB v2 API token, if set.
+	ExportToken string
+	// ExportTable is the PostgreSQL table where metrics are written.
+	ExportTable string
+	// ExportMetrics selects the metrics to export by name (i.e.
+	// "pins/pin_latency"). All of them are exported when empty.
+	ExportMetrics []string
+	// ExportInterval sets how often metrics are written.
+	ExportInterval time.Duration
+
+	// Exported metrics are tagged with these.
+	ClusterID       string
+	ClusterPeername string
 }
 
 type jsonMetricsConfig struct {
 	EnableStats        bool   `json:"enable_stats"`
 	PrometheusEndpoint string `json:"prometheus_endpoint"`
 	ReportingInterval  string `json:"reporting_interval"`
+
+	ExportType     string   `json:"export_type,omitempty"`
+	ExportEndpoint string   `json:"export_endpoint,omitempty" hidden:"true"`
+	ExportToken    string   `json:"export_token,omitempty" hidden:"true"`
+	ExportTable    string   `json:"export_table,omitempty"`
+	ExportMetrics  []string `json:"export_metrics,omitempty"`
+	ExportInterval string   `json:"export_interval,omitempty"`
 }
 
 // ConfigKey provides a human-friendly identifier for this type of Config.
@@ -56,6 +94,12 @@ func (cfg *MetricsConfig) Default() error {
 	endpointAddr, _ := ma.NewMultiaddr(DefaultPrometheusEndpoint)
 	cfg.PrometheusEndpoint = endpointAddr
 	cfg.ReportingInterval = DefaultReportingInterval
+	cfg.ExportType = DefaultExportType
+	cfg.ExportEndpoint = ""
+	cfg.ExportToken = ""
+	cfg.ExportTable = DefaultExportTable
+	cfg.ExportMetrics = nil
+	cfg.ExportInterval = DefaultExportInterval
 
 	return nil
 }
@@ -84,6 +128,28 @@ func (cfg *MetricsConfig) Validate() error {
 			return errors.New("metrics.reporting_interval is invalid")
 		}
 	}
+
+	switch cfg.ExportType {
+	case "":
+		return nil
+	case ExportInfluxDB, ExportTimescale:
+	default:
+		return fmt.Errorf("metrics.export_type is invalid: %s", cfg.ExportType)
+	}
+	if cfg.ExportEndpoint == "" {
+		return errors.New("metrics.export_endpoint is undefined")
+	}
+	if cfg.ExportType == ExportTimescale && cfg.ExportTable == "" {
+		return errors.New("metrics.export_table is undefined")
+	}
+	if cfg.ExportInterval <= 0 {
+		return errors.New("metrics.export_interval is invalid")
+	}
+	for _, m := range cfg.ExportMetrics {
+		if !knownMetric(m) {
+			return fmt.Errorf("metrics.export_metrics: unknown metric: %s", m)
+		}
+	}
 	return nil
 }
 
@@ -119,6 +185,12 @@ func (cfg *MetricsConfig) loadMetricsOptions(jcfg *jsonMetricsConfig) error {
 	}
 	cfg.PrometheusEndpoint = endpointAddr
 
+	config.SetIfNotDefault(jcfg.ExportType, &cfg.ExportType)
+	config.SetIfNotDefault(jcfg.ExportEndpoint, &cfg.ExportEndpoint)
+	config.SetIfNotDefault(jcfg.ExportToken, &cfg.ExportToken)
+	config.SetIfNotDefault(jcfg.ExportTable, &cfg.ExportTable)
+	cfg.ExportMetrics = jcfg.ExportMetrics
+
 	return config.ParseDurations(
 		metricsConfigKey,
 		&config.DurationOpt{
@@ -126,6 +198,11 @@ func (cfg *MetricsConfig) loadMetricsOptions(jcfg *jsonMetricsConfig) error {
 			Dst:      &cfg.ReportingInterval,
 			Name:     "metrics.reporting_interval",
 		},
+		&config.DurationOpt{
+			Duration: jcfg.ExportInterval,
+			Dst:      &cfg.ExportInterval,
+			Name:     "metrics.export_interval",
+		},
 	)
 }
 
@@ -136,12 +213,27 @@ func (cfg *MetricsConfig) ToJSON() ([]byte, error) {
 	return config.DefaultJSONMarshal(jcfg)
 }
 
+// JSONKeys returns the keys of the JSON representation of this
+// configuration, including those omitted when empty.
+func (cfg *MetricsConfig) JSONKeys() []string {
+	return config.JSONKeys(jsonMetricsConfig{})
+}
+
 func (cfg *MetricsConfig) toJSONConfig() *jsonMetricsConfig {
-	return &jsonMetricsConfig{
+	jcfg := &jsonMetricsConfig{
 		EnableStats:        cfg.EnableStats,
 		PrometheusEndpoint: cfg.PrometheusEndpoint.String(),
 		ReportingInterval:  cfg.ReportingInterval.String(),
 	}
+	if cfg.ExportType != "" {
+		jcfg.ExportType = cfg.ExportType
+		jcfg.ExportEndpoint = cfg.ExportEndpoint
+		jcfg.ExportToken = cfg.ExportToken
+		jcfg.ExportTable = cfg.ExportTable
+		jcfg.ExportMetrics = cfg.ExportMetrics
+		jcfg.ExportInterval = cfg.ExportInterval.String()
+	}
+	return jcfg
 }
 
 // ToDisplayJSON returns JSON config as a string.
@@ -254,6 +346,12 @@ func (cfg *TracingConfig) ToJSON() ([]byte, error) {
 	return config.DefaultJSONMarshal(jcfg)
 }
 
+// JSONKeys returns the keys of the JSON representation of this
+// configuration, including those omitted when empty.
+func (cfg *TracingConfig) JSONKeys() []string {
+	return config.JSONKeys(jsonTracingConfig{})
+}
+
 func (cfg *TracingConfig) toJSONConfig() *jsonTracingConfig {
 	return &jsonTracingConfig{
 		EnableTracing:       cfg.EnableTracing,
@@ -3,6 +3,7 @@ package observations
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestApplyEnvVars(t *testing.T) {
@@ -24,3 +25,43 @@ func TestApplyEnvVars(t *testing.T) {
 		t.Fatal("failed to override enable_tracing with env var")
 	}
 }
+
+func TestMetricsExportConfig(t *testing.T) {
+	cfg := &MetricsConfig{}
+	cfg.Default()
+	err := cfg.LoadJSON([]byte(`{
+  "enable_stats": false,
+  "prometheus_endpoint": "/ip4/127.0.0.1/tcp/8888",
+  "reporting_interval": "2s",
+  "export_type": "influxdb",
+  "export_endpoint": "http://localhost:8086/write?db=cluster",
+  "export_metrics": ["pins/pin_latency"],
+  "export_interval": "1m"
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ExportType != ExportInfluxDB || cfg.ExportInterval != time.Minute {
+		t.Error("export options not loaded")
+	}
+	if cfg.ExportTable != DefaultExportTable {
+		t.Error("export_table should keep its default")
+	}
+
+	cfg.ExportMetrics = []string{"pins/nope"}
+	if cfg.Validate() == nil {
+		t.Error("expected error with unknown metric")
+	}
+
+	cfg.ExportMetrics = nil
+	cfg.ExportType = "graphite"
+	if cfg.Validate() == nil {
+		t.Error("expected error with unknown export type")
+	}
+
+	cfg.ExportType = ExportTimescale
+	cfg.ExportEndpoint = ""
+	if cfg.Validate() == nil {
+		t.Error("expected error without endpoint")
+	}
+}
@@ -0,0 +1,414 @@
+package observations
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opencensus.io/stats/view"
+)
+
+// point is a single exported sample of a metric.
+type point struct {
+	name   string
+	tags   map[string]string
+	fields map[string]float64
+	time   time.Time
+}
+
+// key identifies the series the point belongs to.
+func (p point) key() string {
+	var b strings.Builder
+	b.WriteString(p.name)
+	for _, k := range sortedKeys(p.tags) {
+		b.WriteString(",")
+		b.WriteString(k)
+		b.WriteString("=")
+		b.WriteString(p.tags[k])
+	}
+	return b.String()
+}
+
+// pointWriter writes points to a time series database.
+type pointWriter interface {
+	write(ctx context.Context, pts []point) error
+}
+
+// metricsExporter is an OpenCensus view exporter which keeps the last
+// value of every exported series and writes them to a pointWriter on
+// regular intervals. Since all metrics are cumulative or gauges, writing
+// only the last value does not lose information.
+type metricsExporter struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	writer   pointWriter
+	interval time.Duration
+	filter   map[string]struct{}
+	tags     map[string]string
+
+	mu      sync.Mutex
+	pending map[string]point
+}
+
+func newMetricsExporter(cfg *MetricsConfig, w pointWriter) *metricsExporter {
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &metricsExporter{
+		ctx:      ctx,
+		cancel:   cancel,
+		writer:   w,
+		interval: cfg.ExportInterval,
+		tags:     make(map[string]string),
+		pending:  make(map[string]point),
+	}
+	if len(cfg.ExportMetrics) > 0 {
+		e.filter = make(map[string]struct{})
+		for _, m := range cfg.ExportMetrics {
+			e.filter[m] = struct{}{}
+		}
+	}
+	if cfg.ClusterID != "" {
+		e.tags["peer"] = cfg.ClusterID
+	}
+	if cfg.ClusterPeername != "" {
+		e.tags["peername"] = cfg.ClusterPeername
+	}
+
+	e.wg.Add(1)
+	go e.run()
+	return e
+}
+
+// ExportView implements view.Exporter.
+func (e *metricsExporter) ExportView(vd *view.Data) {
+	name := viewName(vd.View)
+	if e.filter != nil {
+		if _, ok := e.filter[name]; !ok {
+			return
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, row := range vd.Rows {
+		p := point{
+			name:   name,
+			tags:   make(map[string]string, len(row.Tags)+len(e.tags)),
+			fields: aggregationFields(row.Data),
+			time:   vd.End,
+		}
+		if p.fields == nil {
+			continue
+		}
+		for _, t := range row.Tags {
+			p.tags[t.Key.Name()] = t.Value
+		}
+		for k, v := range e.tags {
+			p.tags[k] = v
+		}
+		e.pending[p.key()] = p
+	}
+}
+
+func (e *metricsExporter) run() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.ctx.Done():
+			e.flush()
+			return
+		case <-ticker.C:
+			e.flush()
+		}
+	}
+}
+
+func (e *metricsExporter) flush() {
+	e.mu.Lock()
+	pending := e.pending
+	e.pending = make(map[string]point)
+	e.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	pts := make([]point, 0, len(pending))
+	for _, k := range sortedKeys(pending) {
+		pts = append(pts, pending[k])
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.interval)
+	defer cancel()
+	if err := e.writer.write(ctx, pts); err != nil {
+		logger.Errorf("error exporting metrics: %s", err)
+	}
+}
+
+// shutdown writes any pending points and stops the exporter.
+func (e *metricsExporter) shutdown() {
+	e.cancel()
+	e.wg.Wait()
+}
+
+func setupMetricsExport(cfg *MetricsConfig) error {
+	var w pointWriter
+	switch cfg.ExportType {
+	case ExportInfluxDB:
+		w = newInfluxWriter(cfg.ExportEndpoint, cfg.ExportToken)
+	case ExportTimescale:
+		tw, err := newTimescaleWriter(cfg.ExportEndpoint, cfg.ExportTable)
+		if err != nil {
+			return err
+		}
+		w = tw
+	default:
+		return fmt.Errorf("unknown metrics export type: %s", cfg.ExportType)
+	}
+
+	if err := view.Register(DefaultViews...); err != nil {
+		return err
+	}
+	if !cfg.EnableStats {
+		view.SetReportingPeriod(cfg.ExportInterval)
+	}
+	view.RegisterExporter(newMetricsExporter(cfg, w))
+	return nil
+}
+
+// influxWriter writes points using the InfluxDB line protocol.
+type influxWriter struct {
+	client   *http.Client
+	endpoint string
+	token    string
+}
+
+func newInfluxWriter(endpoint, token string) *influxWriter {
+	return &influxWriter{
+		client:   &http.Client{},
+		endpoint: endpoint,
+		token:    token,
+	}
+}
+
+func (iw *influxWriter) write(ctx context.Context, pts []point) error {
+	var body bytes.Buffer
+	for _, p := range pts {
+		body.WriteString(lineProtocol(p))
+		body.WriteString("\n")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, iw.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if iw.token != "" {
+		req.Header.Set("Authorization", "Token "+iw.token)
+	}
+
+	resp, err := iw.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("influxdb write failed: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	keyEscaper         = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+)
+
+// lineProtocol encodes a point in the InfluxDB line protocol. Metric names
+// are prefixed with "ipfscluster_" and slashes are replaced by underscores,
+// as done for the Prometheus metrics.
+func lineProtocol(p point) string {
+	var b strings.Builder
+	b.WriteString(measurementEscaper.Replace(measurementName(p.name)))
+	for _, k := range sortedKeys(p.tags) {
+		if p.tags[k] == "" {
+			continue
+		}
+		b.WriteString(",")
+		b.WriteString(keyEscaper.Replace(k))
+		b.WriteString("=")
+		b.WriteString(keyEscaper.Replace(p.tags[k]))
+	}
+	for i, k := range sortedKeys(p.fields) {
+		if i == 0 {
+			b.WriteString(" ")
+		} else {
+			b.WriteString(",")
+		}
+		b.WriteString(keyEscaper.Replace(k))
+		b.WriteString("=")
+		b.WriteString(strconv.FormatFloat(p.fields[k], 'g', -1, 64))
+	}
+	b.WriteString(" ")
+	b.WriteString(strconv.FormatInt(p.time.UnixNano(), 10))
+	return b.String()
+}
+
+// timescaleWriter inserts points in a PostgreSQL table, which is turned
+// into a TimescaleDB hypertable when the extension is available.
+type timescaleWriter struct {
+	db    *sql.DB
+	table string
+
+	mu    sync.Mutex
+	ready bool
+}
+
+func newTimescaleWriter(dsn, table string) (*timescaleWriter, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &timescaleWriter{
+		db:    db,
+		table: table,
+	}, nil
+}
+
+// prepare creates the metrics table. It is done on the first write so that
+// peers can start while the database is unavailable.
+func (tw *timescaleWriter) prepare(ctx context.Context) error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.ready {
+		return nil
+	}
+
+	_, err := tw.db.ExecContext(ctx, fmt.Sprintf(
+		`CREATE TABLE IF NOT EXISTS %s (
+			time TIMESTAMPTZ NOT NULL,
+			metric TEXT NOT NULL,
+			field TEXT NOT NULL,
+			value DOUBLE PRECISION,
+			tags JSONB
+		)`,
+		pq.QuoteIdentifier(tw.table),
+	))
+	if err != nil {
+		return err
+	}
+
+	_, err = tw.db.ExecContext(ctx,
+		`SELECT create_hypertable($1, 'time', if_not_exists => TRUE)`,
+		tw.table,
+	)
+	if err != nil {
+		logger.Warnf("metrics table %s is not a TimescaleDB hypertable: %s", tw.table, err)
+	}
+	tw.ready = true
+	return nil
+}
+
+func (tw *timescaleWriter) write(ctx context.Context, pts []point) error {
+	if err := tw.prepare(ctx); err != nil {
+		return err
+	}
+
+	tx, err := tw.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // nolint:errcheck
+
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(
+		`INSERT INTO %s (time, metric, field, value, tags) VALUES ($1, $2, $3, $4, $5)`,
+		pq.QuoteIdentifier(tw.table),
+	))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, p := range pts {
+		tags, err := json.Marshal(p.tags)
+		if err != nil {
+			return err
+		}
+		for _, f := range sortedKeys(p.fields) {
+			_, err := stmt.ExecContext(ctx, p.time, p.name, f, p.fields[f], string(tags))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// aggregationFields returns the values of a view row. Distributions are
+// reported by their count, sum, mean, min and max.
+func aggregationFields(data view.AggregationData) map[string]float64 {
+	switch d := data.(type) {
+	case *view.CountData:
+		return map[string]float64{"value": float64(d.Value)}
+	case *view.SumData:
+		return map[string]float64{"value": d.Value}
+	case *view.LastValueData:
+		return map[string]float64{"value": d.Value}
+	case *view.DistributionData:
+		fields := map[string]float64{
+			"count": float64(d.Count),
+			"sum":   d.Sum(),
+			"mean":  d.Mean,
+		}
+		if d.Count > 0 {
+			fields["min"] = d.Min
+			fields["max"] = d.Max
+		}
+		return fields
+	default:
+		return nil
+	}
+}
+
+func viewName(v *view.View) string {
+	if v.Name != "" {
+		return v.Name
+	}
+	return v.Measure.Name()
+}
+
+func measurementName(name string) string {
+	return "ipfscluster_" + strings.ReplaceAll(name, "/", "_")
+}
+
+// knownMetric returns true when name is one of the DefaultViews.
+func knownMetric(name string) bool {
+	for _, v := range DefaultViews {
+		if viewName(v) == name {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,89 @@
+package observations
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+)
+
+func TestLineProtocol(t *testing.T) {
+	p := point{
+		name: "pins/pin_latency",
+		tags: map[string]string{
+			"peername": "my peer",
+			"peer":     "QmPeer",
+			"a,b":      "c=d",
+		},
+		fields: map[string]float64{
+			"count": 2,
+			"mean":  1.5,
+		},
+		time: time.Unix(1, 5),
+	}
+	expected := `ipfscluster_pins_pin_latency,a\,b=c\=d,peer=QmPeer,peername=my\ peer count=2,mean=1.5 1000000005`
+	if l := lineProtocol(p); l != expected {
+		t.Errorf("unexpected line:\n%s\n%s", l, expected)
+	}
+}
+
+func TestMetricsExporterInflux(t *testing.T) {
+	lines := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Token secret" {
+			t.Error("token not sent")
+		}
+		body, _ := io.ReadAll(r.Body)
+		lines <- string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cfg := &MetricsConfig{}
+	cfg.Default()
+	cfg.ExportType = ExportInfluxDB
+	cfg.ExportEndpoint = srv.URL
+	cfg.ExportToken = "secret"
+	cfg.ExportMetrics = []string{"pins/pin_latency"}
+	cfg.ExportInterval = 100 * time.Millisecond
+	cfg.ClusterID = "QmPeer"
+
+	e := newMetricsExporter(cfg, newInfluxWriter(cfg.ExportEndpoint, cfg.ExportToken))
+	defer e.shutdown()
+
+	hostKey, _ := tag.NewKey("host")
+	e.ExportView(&view.Data{
+		View: PinsPinAddView,
+		Rows: []*view.Row{{Data: &view.SumData{Value: 3}}},
+		End:  time.Unix(10, 0),
+	})
+	e.ExportView(&view.Data{
+		View: PinsPinLatencyView,
+		Rows: []*view.Row{{
+			Tags: []tag.Tag{{Key: hostKey, Value: "h"}},
+			Data: &view.DistributionData{Count: 2, Min: 10, Max: 30, Mean: 20},
+		}},
+		End: time.Unix(10, 0),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	select {
+	case <-ctx.Done():
+		t.Fatal("no metrics received")
+	case body := <-lines:
+		expected := "ipfscluster_pins_pin_latency,host=h,peer=QmPeer count=2,max=30,mean=20,min=10,sum=40 10000000000\n"
+		if body != expected {
+			t.Errorf("unexpected body:\n%s\n%s", body, expected)
+		}
+		if strings.Contains(body, "pin_add") {
+			t.Error("filtered metric was exported")
+		}
+	}
+}
@@ -27,6 +27,9 @@ var (
 var (
 	HostKey       = makeKey("host")
 	RemotePeerKey = makeKey("remote_peer")
+	ProtocolKey   = makeKey("rpc_protocol")
+	ClassKey      = makeKey("storage_class")
+	ComponentKey  = makeKey("component")
 )
 
 // metrics
@@ -34,6 +37,11 @@ var (
 	// This metric is managed in state/dsstate.
 	Pins = stats.Int64("pins", "Total number of cluster pins", stats.UnitDimensionless)
 
+	// These metrics are managed by the cluster when pin SLOs are
+	// configured. They are tagged with the SLO class.
+	PinsTimeToPinned  = stats.Float64("pins/time_to_pinned", "Time from pin submission until pinned on this peer", stats.UnitMilliseconds)
+	PinsSLOCompliance = stats.Float64("pins/slo_compliance", "Fraction of the pins pinned within the target time over the SLO window", stats.UnitDimensionless)
+
 	// These metrics are managed by the pintracker/optracker module.
 	PinsQueued   = stats.Int64("pins/pin_queued", "Current number of pins queued for pinning", stats.UnitDimensionless)
 	PinsPinning  = stats.Int64("pins/pinning", "Current number of pins currently pinning", stats.UnitDimensionless)
@@ -43,13 +51,46 @@ var (
 	PinsIpfsPins    = stats.Int64("pins/ipfs_pins", "Current number of items pinned on IPFS", stats.UnitDimensionless)
 	PinsPinAdd      = stats.Int64("pins/pin_add", "Total number of IPFS pin requests", stats.UnitDimensionless)
 	PinsPinAddError = stats.Int64("pins/pin_add_errors", "Total number of failed pin requests", stats.UnitDimensionless)
+	PinsPinLatency  = stats.Float64("pins/pin_latency", "Time taken by successful IPFS pin requests", stats.UnitMilliseconds)
 	BlocksPut       = stats.Int64("blocks/put", "Total number of blocks/put requests", stats.UnitDimensionless)
 	BlocksAddedSize = stats.Int64("blocks/added_size", "Total size of blocks added in bytes", stats.UnitBytes)
 
 	BlocksAdded      = stats.Int64("blocks/added", "Total number of blocks added", stats.UnitDimensionless)
 	BlocksAddedError = stats.Int64("blocks/put_errors", "Total number of block/put errors", stats.UnitDimensionless)
+	BlocksPending    = stats.Int64("blocks/put_pending", "Current number of blocks sent to IPFS and not yet acknowledged", stats.UnitDimensionless)
+
+	// This metric is managed by the raft consensus component.
+	RaftDiskUsage = stats.Int64("consensus/raft_disk_usage", "Size of the Raft data folder in bytes", stats.UnitBytes)
+
+	// These metrics are managed by the raft consensus component when
+	// batching is enabled.
+	ConsensusQueueDepth    = stats.Int64("consensus/queue_depth", "Current number of operations waiting to be batched", stats.UnitDimensionless)
+	ConsensusQueueWait     = stats.Float64("consensus/queue_wait", "Time operations wait before being batched", stats.UnitMilliseconds)
+	ConsensusQueueRejected = stats.Int64("consensus/queue_rejected", "Total number of operations rejected or shed because the batching queue was full", stats.UnitDimensionless)
+
+	// This metric is managed by the raft consensus component. It holds
+	// the first bytes of the pinset checksum.
+	RaftStateChecksum = stats.Int64("consensus/state_checksum", "Prefix of the checksum of the shared state", stats.UnitDimensionless)
+
+	// This metric is managed by the consensus components. It counts the
+	// log entries (Raft) or DAG nodes (CRDT) received but not yet
+	// applied to the shared state.
+	ConsensusSyncLag = stats.Int64("consensus/sync_lag", "Current number of updates known but not yet applied to the shared state", stats.UnitDimensionless)
+
+	// This metric is managed by the cluster RPC server.
+	RPCLegacyRequests = stats.Int64("rpc/legacy_requests", "Total number of RPC requests received from peers using an older protocol version", stats.UnitDimensionless)
 
 	InformerDisk = stats.Int64("informer/disk", "The metric value weight issued by disk informer", stats.UnitDimensionless)
+
+	// This metric is managed by the cluster peer applications.
+	ConfigSaveErrors = stats.Int64("config/save_errors", "Total number of failed configuration saves", stats.UnitDimensionless)
+
+	// This metric is managed by the stateless pintracker. It is 1 while
+	// operations are paused because the IPFS daemon keeps failing.
+	IPFSDegraded = stats.Int64("ipfs/degraded", "Whether operations are paused because the IPFS daemon is failing", stats.UnitDimensionless)
+
+	// This metric is managed in recovery.
+	ComponentPanics = stats.Int64("components/panics", "Total number of panics recovered in component goroutines", stats.UnitDimensionless)
 )
 
 // views, which is just the aggregation of the metrics
@@ -96,6 +137,11 @@ var (
 		Aggregation: view.Sum(),
 	}
 
+	PinsPinLatencyView = &view.View{
+		Measure:     PinsPinLatency,
+		Aggregation: view.Distribution(100, 500, 1000, 5000, 10000, 30000, 60000, 300000, 900000, 3600000),
+	}
+
 	BlocksPutView = &view.View{
 		Measure:     BlocksPut,
 		Aggregation: view.Sum(),
@@ -116,11 +162,80 @@ var (
 		Aggregation: view.Sum(),
 	}
 
+	BlocksPendingView = &view.View{
+		Measure:     BlocksPending,
+		Aggregation: view.LastValue(),
+	}
+
+	PinsTimeToPinnedView = &view.View{
+		Measure:     PinsTimeToPinned,
+		TagKeys:     []tag.Key{ClassKey},
+		Aggregation: view.Distribution(1000, 10000, 60000, 300000, 900000, 3600000, 21600000, 86400000),
+	}
+
+	PinsSLOComplianceView = &view.View{
+		Measure:     PinsSLOCompliance,
+		TagKeys:     []tag.Key{ClassKey},
+		Aggregation: view.LastValue(),
+	}
+
+	RaftDiskUsageView = &view.View{
+		Measure:     RaftDiskUsage,
+		Aggregation: view.LastValue(),
+	}
+
+	ConsensusQueueDepthView = &view.View{
+		Measure:     ConsensusQueueDepth,
+		Aggregation: view.LastValue(),
+	}
+
+	ConsensusQueueWaitView = &view.View{
+		Measure:     ConsensusQueueWait,
+		Aggregation: view.Distribution(1, 10, 50, 100, 500, 1000, 5000, 10000, 30000, 60000),
+	}
+
+	ConsensusQueueRejectedView = &view.View{
+		Measure:     ConsensusQueueRejected,
+		Aggregation: view.Sum(),
+	}
+
+	RaftStateChecksumView = &view.View{
+		Measure:     RaftStateChecksum,
+		Aggregation: view.LastValue(),
+	}
+
+	ConsensusSyncLagView = &view.View{
+		Measure:     ConsensusSyncLag,
+		Aggregation: view.LastValue(),
+	}
+
+	RPCLegacyRequestsView = &view.View{
+		Measure:     RPCLegacyRequests,
+		TagKeys:     []tag.Key{ProtocolKey},
+		Aggregation: view.Sum(),
+	}
+
 	InformerDiskView = &view.View{
 		Measure:     InformerDisk,
 		Aggregation: view.LastValue(),
 	}
 
+	ConfigSaveErrorsView = &view.View{
+		Measure:     ConfigSaveErrors,
+		Aggregation: view.Sum(),
+	}
+
+	IPFSDegradedView = &view.View{
+		Measure:     IPFSDegraded,
+		Aggregation: view.LastValue(),
+	}
+
+	ComponentPanicsView = &view.View{
+		Measure:     ComponentPanics,
+		TagKeys:     []tag.Key{ComponentKey},
+		Aggregation: view.Sum(),
+	}
+
 	DefaultViews = []*view.View{
 		PinsView,
 		PinsQueuedView,
@@ -129,11 +244,25 @@ var (
 		PinsIpfsPinsView,
 		PinsPinAddView,
 		PinsPinAddErrorView,
+		PinsPinLatencyView,
 		BlocksPutView,
 		BlocksAddedSizeView,
 		BlocksAddedView,
 		BlocksAddedErrorView,
+		BlocksPendingView,
+		PinsTimeToPinnedView,
+		PinsSLOComplianceView,
+		RaftDiskUsageView,
+		ConsensusQueueDepthView,
+		ConsensusQueueWaitView,
+		ConsensusQueueRejectedView,
+		RaftStateChecksumView,
+		ConsensusSyncLagView,
+		RPCLegacyRequestsView,
 		InformerDiskView,
+		ConfigSaveErrorsView,
+		IPFSDegradedView,
+		ComponentPanicsView,
 	}
 )
 
@@ -25,7 +25,13 @@ import (
 func SetupMetrics(cfg *MetricsConfig) error {
 	if cfg.EnableStats {
 		logger.Infof("stats collection enabled on %s", cfg.PrometheusEndpoint)
-		return setupMetrics(cfg)
+		if err := setupMetrics(cfg); err != nil {
+			return err
+		}
+	}
+	if cfg.ExportType != "" {
+		logger.Infof("metrics export to %s enabled", cfg.ExportType)
+		return setupMetricsExport(cfg)
 	}
 	return nil
 }
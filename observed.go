@@ -0,0 +1,102 @@
+package ipfscluster
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	trace "go.opencensus.io/trace"
+)
+
+// observedNamespace is the datastore namespace where observed external pins
+// are stored.
+const observedNamespace = "/observed"
+
+// observedPins keeps track of the pins made directly on the IPFS daemon of
+// this peer, as reported by the IPFS proxy in audit mode. They are local to
+// this peer and never committed to the shared state.
+type observedPins struct {
+	store ds.Datastore
+}
+
+func newObservedPins(store ds.Datastore) *observedPins {
+	return &observedPins{
+		store: namespace.Wrap(store, ds.NewKey(observedNamespace)),
+	}
+}
+
+func (op *observedPins) key(c api.Cid) ds.Key {
+	return ds.NewKey(c.String())
+}
+
+func (op *observedPins) add(ctx context.Context, pin api.ObservedPin) error {
+	v, err := json.Marshal(pin)
+	if err != nil {
+		return err
+	}
+	return op.store.Put(ctx, op.key(pin.Cid), v)
+}
+
+func (op *observedPins) remove(ctx context.Context, c api.Cid) error {
+	return op.store.Delete(ctx, op.key(c))
+}
+
+func (op *observedPins) list(ctx context.Context) ([]api.ObservedPin, error) {
+	results, err := op.store.Query(ctx, query.Query{})
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+
+	pins := []api.ObservedPin{}
+	for r := range results.Next() {
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		var pin api.ObservedPin
+		err := json.Unmarshal(r.Value, &pin)
+		if err != nil {
+			logger.Errorf("discarding unreadable observed pin %s: %s", r.Key, err)
+			continue
+		}
+		pins = append(pins, pin)
+	}
+	return pins, nil
+}
+
+// ObservePin records a pin made directly on the IPFS daemon of this peer.
+func (c *Cluster) ObservePin(ctx context.Context, pin api.ObservedPin) error {
+	ctx, span := trace.StartSpan(ctx, "cluster/ObservePin")
+	defer span.End()
+
+	pin.Peer = c.id
+	if pin.Timestamp.IsZero() {
+		pin.Timestamp = time.Now()
+	}
+	logger.Infof("observed external pin: %s", pin.Cid)
+	return c.observed.add(ctx, pin)
+}
+
+// ObserveUnpin forgets an observed pin after it has been unpinned directly
+// on the IPFS daemon of this peer.
+func (c *Cluster) ObserveUnpin(ctx context.Context, ci api.Cid) error {
+	ctx, span := trace.StartSpan(ctx, "cluster/ObserveUnpin")
+	defer span.End()
+
+	logger.Infof("observed external unpin: %s", ci)
+	return c.observed.remove(ctx, ci)
+}
+
+// ObservedPins returns the pins that were made directly on the IPFS daemon
+// of this peer, as observed by the IPFS proxy in audit mode.
+func (c *Cluster) ObservedPins(ctx context.Context) ([]api.ObservedPin, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/ObservedPins")
+	defer span.End()
+
+	return c.observed.list(ctx)
+}
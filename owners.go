@@ -0,0 +1,91 @@
+package ipfscluster
+
+import (
+	"context"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	"go.opencensus.io/trace"
+)
+
+// setupOwners keeps the owners of the existing pin, so that pinning a CID
+// which is already pinned adds references to it instead of replacing them.
+func setupOwners(pin, existing api.Pin) api.Pin {
+	owners := make([]string, 0, len(existing.Owners)+len(pin.Owners))
+	owners = append(owners, existing.Owners...)
+	owners = append(owners, pin.Owners...)
+	pin.Owners = api.NormalizeTags(owners)
+	return pin
+}
+
+// unref removes the references that the given owners hold on the pin for h.
+// The pin is unpinned when no owners are left, or when no owners are given.
+// Otherwise it is kept with the remaining owners, and that is the pin
+// returned.
+//
+// This is the method called by the Cluster.Unpin RPC endpoint.
+func (c *Cluster) unref(ctx context.Context, h api.Cid, owners []string) (api.Pin, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/unref")
+	defer span.End()
+
+	if len(owners) == 0 {
+		return c.Unpin(ctx, h)
+	}
+
+	if c.config.FollowerMode {
+		return api.Pin{}, errFollowerMode
+	}
+
+	pin, err := c.PinGet(ctx, h)
+	if err != nil {
+		return api.Pin{}, err
+	}
+
+	// Shards and cluster DAGs are not referenced directly: Unpin
+	// returns the right error for them.
+	if pin.Type != api.DataType && pin.Type != api.MetaType {
+		return c.Unpin(ctx, h)
+	}
+
+	remaining := removeOwners(pin.Owners, owners)
+	if len(remaining) == 0 {
+		return c.Unpin(ctx, h)
+	}
+	if len(remaining) == len(pin.Owners) {
+		// None of the owners held a reference.
+		return pin, nil
+	}
+
+	logger.Infof("IPFS cluster removing references of %s to %s. Still referenced by %s", owners, h, remaining)
+	pin.Owners = remaining
+	return pin, c.logPin(ctx, pin)
+}
+
+// unrefPath is like unref for the CID that the given IPFS path resolves to.
+//
+// This is the method called by the Cluster.UnpinPath RPC endpoint.
+func (c *Cluster) unrefPath(ctx context.Context, path string, owners []string) (api.Pin, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/unrefPath")
+	defer span.End()
+
+	ci, err := c.ipfs.Resolve(ctx, path)
+	if err != nil {
+		return api.Pin{}, err
+	}
+
+	return c.unref(ctx, ci, owners)
+}
+
+func removeOwners(owners, removed []string) []string {
+	drop := make(map[string]struct{}, len(removed))
+	for _, o := range removed {
+		drop[o] = struct{}{}
+	}
+	var res []string
+	for _, o := range owners {
+		if _, ok := drop[o]; !ok {
+			res = append(res, o)
+		}
+	}
+	return res
+}
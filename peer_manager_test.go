@@ -272,6 +272,7 @@ func TestClustersPeerRemove(t *testing.T) {
 		return
 	case "raft":
 		p := clusters[1].ID(ctx).ID
+		name := clusters[1].config.Peername
 		err := clusters[0].PeerRemove(ctx, p)
 		if err != nil {
 			t.Error(err)
@@ -290,6 +291,10 @@ func TestClustersPeerRemove(t *testing.T) {
 				if len(ids) != nClusters-1 {
 					t.Error("should have removed 1 peer")
 				}
+				archive := c.PeersArchive(ctx)
+				if len(archive) != 1 || archive[0].ID != p || archive[0].Peername != name || archive[0].Removed.IsZero() {
+					t.Errorf("removed peer should be archived: %+v", archive)
+				}
 			}
 		}
 
@@ -0,0 +1,161 @@
+package ipfscluster
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/rpcutil"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	trace "go.opencensus.io/trace"
+)
+
+// peersArchiveNamespace is the datastore namespace where removed peers are
+// recorded.
+const peersArchiveNamespace = "/peers-archive"
+
+// peersArchive keeps the name and removal time of the peers that have left
+// the cluster. Entries are never deleted. It is kept in memory too, as it
+// is used to resolve peer names when reporting status.
+type peersArchive struct {
+	store ds.Datastore
+
+	mux   sync.RWMutex
+	peers map[peer.ID]api.ArchivedPeer
+}
+
+func newPeersArchive(ctx context.Context, store ds.Datastore) *peersArchive {
+	pa := &peersArchive{
+		store: namespace.Wrap(store, ds.NewKey(peersArchiveNamespace)),
+		peers: make(map[peer.ID]api.ArchivedPeer),
+	}
+
+	results, err := pa.store.Query(ctx, query.Query{})
+	if err != nil {
+		logger.Errorf("error loading the peers archive: %s", err)
+		return pa
+	}
+	defer results.Close()
+	for r := range results.Next() {
+		if r.Error != nil {
+			logger.Errorf("error loading the peers archive: %s", r.Error)
+			break
+		}
+		var ap api.ArchivedPeer
+		err := json.Unmarshal(r.Value, &ap)
+		if err != nil {
+			logger.Errorf("discarding unreadable archived peer %s: %s", r.Key, err)
+			continue
+		}
+		pa.peers[ap.ID] = ap
+	}
+	return pa
+}
+
+func (pa *peersArchive) add(ctx context.Context, ap api.ArchivedPeer) error {
+	v, err := json.Marshal(ap)
+	if err != nil {
+		return err
+	}
+	err = pa.store.Put(ctx, ds.NewKey(ap.ID.String()), v)
+	if err != nil {
+		return err
+	}
+
+	pa.mux.Lock()
+	pa.peers[ap.ID] = ap
+	pa.mux.Unlock()
+	return nil
+}
+
+func (pa *peersArchive) get(p peer.ID) (api.ArchivedPeer, bool) {
+	pa.mux.RLock()
+	defer pa.mux.RUnlock()
+	ap, ok := pa.peers[p]
+	return ap, ok
+}
+
+// list returns the archived peers, most recently removed first.
+func (pa *peersArchive) list() []api.ArchivedPeer {
+	pa.mux.RLock()
+	peers := make([]api.ArchivedPeer, 0, len(pa.peers))
+	for _, ap := range pa.peers {
+		peers = append(peers, ap)
+	}
+	pa.mux.RUnlock()
+
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].Removed.After(peers[j].Removed)
+	})
+	return peers
+}
+
+// ArchivePeer records a peer that has been removed from the cluster in the
+// peers archive of this peer.
+func (c *Cluster) ArchivePeer(ctx context.Context, ap api.ArchivedPeer) error {
+	ctx, span := trace.StartSpan(ctx, "cluster/ArchivePeer")
+	defer span.End()
+
+	logger.Debugf("archiving removed peer %s (%s)", ap.ID, ap.Peername)
+	return c.archive.add(ctx, ap)
+}
+
+// PeersArchive returns the peers that have been removed from the cluster,
+// most recently removed first.
+func (c *Cluster) PeersArchive(ctx context.Context) []api.ArchivedPeer {
+	_, span := trace.StartSpan(ctx, "cluster/PeersArchive")
+	defer span.End()
+
+	return c.archive.list()
+}
+
+// archiveRemovedPeer records a removed peer in the archive of all the
+// remaining peers. Errors are logged.
+func (c *Cluster) archiveRemovedPeer(ctx context.Context, pid peer.ID, name string) {
+	ap := api.ArchivedPeer{
+		ID:       pid,
+		Peername: name,
+		Removed:  time.Now(),
+	}
+
+	peers, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Errorf("cannot archive removed peer %s in other peers: %s", pid, err)
+		peers = []peer.ID{c.id}
+	}
+
+	ctxs, cancels := rpcutil.CtxsWithTimeout(ctx, len(peers), 10*time.Second)
+	defer rpcutil.MultiCancel(cancels)
+	errs := c.rpcClient.MultiCall(
+		ctxs,
+		peers,
+		"Cluster",
+		"ArchivePeer",
+		ap,
+		rpcutil.RPCDiscardReplies(len(peers)),
+	)
+	for i, err := range errs {
+		if err != nil {
+			logger.Errorf("error archiving removed peer %s in %s: %s", pid, peers[i], err)
+		}
+	}
+}
+
+// peername returns the name of a peer from its ping metric value or, for
+// peers that have been removed, from the peers archive.
+func (c *Cluster) peername(pv pingValue, p peer.ID) string {
+	if pv.Peername != "" {
+		return pv.Peername
+	}
+	if ap, ok := c.archive.get(p); ok {
+		return ap.Peername
+	}
+	return ""
+}
@@ -0,0 +1,182 @@
+package ipfscluster
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	humanize "github.com/dustin/go-humanize"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	trace "go.opencensus.io/trace"
+)
+
+// pinGCKey selects the trusted peer that runs the pin garbage collector:
+// the closest one to it.
+const pinGCKey = "pin-gc"
+
+// watchPinGC periodically evaluates the pin garbage collection policy when
+// this peer is the one in charge of it.
+func (c *Cluster) watchPinGC() {
+	if c.config.PinGCInterval <= 0 || c.config.FollowerMode {
+		return
+	}
+
+	ticker := time.NewTicker(c.config.PinGCInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		distance, err := c.distances(c.ctx, "")
+		if err != nil || !distance.isClosestToKey(pinGCKey) {
+			continue
+		}
+
+		report, err := c.PinGC(c.ctx, c.config.PinGCAutoUnpin)
+		if err != nil {
+			logger.Errorf("error evaluating the pin garbage collection policy: %s", err)
+			continue
+		}
+		for _, cand := range report.Candidates {
+			switch {
+			case cand.Error != "":
+				logger.Errorf("pin gc: error unpinning %s: %s", cand.Cid, cand.Error)
+			case report.Executed:
+				logger.Infof("pin gc: unpinned %s (%s, %s)", cand.Cid, cand.Reason, humanize.Bytes(cand.Size))
+			default:
+				logger.Infof("pin gc: proposing to unpin %s (%s, %s)", cand.Cid, cand.Reason, humanize.Bytes(cand.Size))
+			}
+		}
+	}
+}
+
+// PinGC evaluates the pin garbage collection policy and returns the pins
+// that it selects: those older than the maximum pin age and, when the
+// storage used by the cluster is over the high water mark, the least
+// recently pinned ones until the estimated usage falls under the low water
+// mark. The selected pins are unpinned when execute is true.
+func (c *Cluster) PinGC(ctx context.Context, execute bool) (api.PinGCReport, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/PinGC")
+	defer span.End()
+
+	if execute && c.config.FollowerMode {
+		return api.PinGCReport{}, errFollowerMode
+	}
+
+	capacity, err := c.Capacity(ctx)
+	if err != nil {
+		return api.PinGCReport{}, err
+	}
+
+	now := time.Now()
+	report := api.PinGCReport{
+		Timestamp: now,
+		Used:      capacity.Total.Used,
+		Limit:     capacity.Total.StorageMax,
+	}
+	if maxSize := c.config.PinGCMaxTotalSize; maxSize > 0 && (report.Limit == 0 || maxSize < report.Limit) {
+		report.Limit = maxSize
+	}
+	highWater := uint64(float64(report.Limit) * c.config.PinGCHighWater)
+	lowWater := uint64(float64(report.Limit) * c.config.PinGCLowWater)
+	if report.Limit > 0 && report.Used > highWater {
+		report.ToFree = report.Used - lowWater
+	}
+
+	if report.ToFree == 0 && c.config.PinGCMaxPinAge <= 0 {
+		return report, nil
+	}
+
+	var storagePeers []peer.ID
+	for _, pc := range capacity.PeerMap {
+		if pc.Error == "" {
+			storagePeers = append(storagePeers, pc.Peer)
+		}
+	}
+
+	pins, err := c.pinGCCandidates(ctx, now)
+	if err != nil {
+		return report, err
+	}
+
+	var freed uint64
+	for _, pin := range pins {
+		reason := api.PinGCReasonUsage
+		if maxAge := c.config.PinGCMaxPinAge; maxAge > 0 && now.Sub(pin.Timestamp) > maxAge {
+			reason = api.PinGCReasonAge
+		} else if freed >= report.ToFree {
+			continue
+		}
+
+		cand := api.PinGCCandidate{
+			Cid:       pin.Cid,
+			Name:      pin.Name,
+			Timestamp: pin.Timestamp,
+			Reason:    reason,
+		}
+
+		// The pin frees its size in every peer holding it.
+		holders := pin.Allocations
+		if len(holders) == 0 {
+			holders = storagePeers
+		}
+		stat, err := c.dagStat(ctx, pin, holders)
+		if err != nil {
+			logger.Warn(err)
+		}
+		cand.Size = stat.Size * uint64(len(holders))
+		freed += cand.Size
+
+		report.Candidates = append(report.Candidates, cand)
+	}
+
+	if !execute {
+		return report, nil
+	}
+
+	for i, cand := range report.Candidates {
+		if _, err := c.unpin(ctx, cand.Cid, false); err != nil {
+			report.Candidates[i].Error = err.Error()
+		}
+	}
+	report.Executed = true
+	return report, nil
+}
+
+// pinGCCandidates returns the pins that can be collected, least recently
+// pinned first. Only the pins added by users (not the shards or the cluster
+// DAGs of sharded pins) can be collected, and only when they are older than
+// the minimum pin age.
+func (c *Cluster) pinGCCandidates(ctx context.Context, now time.Time) ([]api.Pin, error) {
+	cState, err := c.consensus.State(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pinsCh := make(chan api.Pin, 1024)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cState.List(ctx, pinsCh)
+	}()
+
+	var pins []api.Pin
+	for p := range pinsCh {
+		if p.Type != api.DataType || now.Sub(p.Timestamp) < c.config.PinGCMinPinAge {
+			continue
+		}
+		pins = append(pins, p)
+	}
+	if err := <-errCh; err != nil {
+		return nil, err
+	}
+
+	sort.Slice(pins, func(i, j int) bool {
+		return pins[i].Timestamp.Before(pins[j].Timestamp)
+	})
+	return pins, nil
+}
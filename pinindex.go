@@ -0,0 +1,360 @@
+package ipfscluster
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/state"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+// Pin fields that can be indexed (cluster.pin_indexes).
+const (
+	pinIndexName    = "name"
+	pinIndexTags    = "tags"
+	pinIndexOrigins = "origins"
+	pinIndexCreated = "created"
+)
+
+func isPinIndexField(field string) bool {
+	switch field {
+	case pinIndexName, pinIndexTags, pinIndexOrigins, pinIndexCreated:
+		return true
+	default:
+		return false
+	}
+}
+
+// The created index groups pins by the day they were created.
+const pinIndexCreatedBucket = 24 * time.Hour
+
+type cidSet map[api.Cid]struct{}
+
+func (s cidSet) add(c api.Cid) {
+	s[c] = struct{}{}
+}
+
+// pinIndexEntry keeps the indexed values of a pin so that it can be
+// removed from the indexes when it changes.
+type pinIndexEntry struct {
+	name    string
+	tags    []string
+	origins []peer.ID
+	created int64
+}
+
+// pinIndexData holds the indexes themselves.
+type pinIndexData struct {
+	pins    map[api.Cid]pinIndexEntry
+	names   map[string]cidSet
+	tags    map[string]cidSet
+	origins map[string]cidSet
+	created map[int64]cidSet
+}
+
+func newPinIndexData() *pinIndexData {
+	return &pinIndexData{
+		pins:    make(map[api.Cid]pinIndexEntry),
+		names:   make(map[string]cidSet),
+		tags:    make(map[string]cidSet),
+		origins: make(map[string]cidSet),
+		created: make(map[int64]cidSet),
+	}
+}
+
+// pinIndex indexes the pins in the shared state by some of their fields, so
+// that filtered pin listings only read the pins that may match. It is built
+// from the state when the peer starts, kept up to date with the consensus
+// events and rebuilt regularly, since events can be missed (i.e. when a
+// snapshot is installed). All methods can be called on a nil index, which
+// is used when no fields are indexed.
+type pinIndex struct {
+	fields map[string]bool
+
+	mux   sync.RWMutex
+	data  *pinIndexData
+	ready bool
+
+	// Events received while rebuilding, which are applied to the new
+	// index before it replaces the old one.
+	rebuilding bool
+	pending    []api.ConsensusEvent
+}
+
+func newPinIndex(fields []string) *pinIndex {
+	if len(fields) == 0 {
+		return nil
+	}
+	pi := &pinIndex{
+		fields: make(map[string]bool),
+		data:   newPinIndexData(),
+	}
+	for _, f := range fields {
+		pi.fields[f] = true
+	}
+	return pi
+}
+
+func (pi *pinIndex) entry(pin api.Pin) pinIndexEntry {
+	var e pinIndexEntry
+	if pi.fields[pinIndexName] {
+		e.name = pin.Name
+	}
+	if pi.fields[pinIndexTags] {
+		e.tags = pin.Tags
+	}
+	if pi.fields[pinIndexOrigins] {
+		e.origins = pin.OriginPeers()
+	}
+	if pi.fields[pinIndexCreated] {
+		e.created = createdBucket(pin.Timestamp)
+	}
+	return e
+}
+
+func createdBucket(t time.Time) int64 {
+	return t.Unix() / int64(pinIndexCreatedBucket/time.Second)
+}
+
+func (d *pinIndexData) add(c api.Cid, e pinIndexEntry, fields map[string]bool) {
+	d.remove(c, fields)
+	d.pins[c] = e
+	if fields[pinIndexName] {
+		addToIndex(d.names, e.name, c)
+	}
+	if fields[pinIndexTags] {
+		for _, t := range e.tags {
+			addToIndex(d.tags, t, c)
+		}
+	}
+	if fields[pinIndexOrigins] {
+		for _, o := range e.origins {
+			addToIndex(d.origins, o.String(), c)
+		}
+	}
+	if fields[pinIndexCreated] {
+		s, ok := d.created[e.created]
+		if !ok {
+			s = make(cidSet)
+			d.created[e.created] = s
+		}
+		s.add(c)
+	}
+}
+
+func (d *pinIndexData) remove(c api.Cid, fields map[string]bool) {
+	e, ok := d.pins[c]
+	if !ok {
+		return
+	}
+	delete(d.pins, c)
+	if fields[pinIndexName] {
+		removeFromIndex(d.names, e.name, c)
+	}
+	if fields[pinIndexTags] {
+		for _, t := range e.tags {
+			removeFromIndex(d.tags, t, c)
+		}
+	}
+	if fields[pinIndexOrigins] {
+		for _, o := range e.origins {
+			removeFromIndex(d.origins, o.String(), c)
+		}
+	}
+	if fields[pinIndexCreated] {
+		delete(d.created[e.created], c)
+		if len(d.created[e.created]) == 0 {
+			delete(d.created, e.created)
+		}
+	}
+}
+
+func addToIndex(idx map[string]cidSet, k string, c api.Cid) {
+	s, ok := idx[k]
+	if !ok {
+		s = make(cidSet)
+		idx[k] = s
+	}
+	s.add(c)
+}
+
+func removeFromIndex(idx map[string]cidSet, k string, c api.Cid) {
+	s, ok := idx[k]
+	if !ok {
+		return
+	}
+	delete(s, c)
+	if len(s) == 0 {
+		delete(idx, k)
+	}
+}
+
+func (pi *pinIndex) apply(d *pinIndexData, ev api.ConsensusEvent) {
+	switch ev.Type {
+	case api.ConsensusEventPin:
+		d.add(ev.Pin.Cid, pi.entry(ev.Pin), pi.fields)
+	case api.ConsensusEventUnpin:
+		d.remove(ev.Pin.Cid, pi.fields)
+	}
+}
+
+// update applies a consensus event to the index.
+func (pi *pinIndex) update(ev api.ConsensusEvent) {
+	if pi == nil {
+		return
+	}
+	pi.mux.Lock()
+	defer pi.mux.Unlock()
+	if pi.rebuilding {
+		pi.pending = append(pi.pending, ev)
+	}
+	pi.apply(pi.data, ev)
+}
+
+// rebuild indexes all the pins in the given state and replaces the current
+// index with the result.
+func (pi *pinIndex) rebuild(ctx context.Context, st state.ReadOnly) error {
+	if pi == nil {
+		return nil
+	}
+	pi.mux.Lock()
+	pi.rebuilding = true
+	pi.pending = nil
+	pi.mux.Unlock()
+
+	d := newPinIndexData()
+	pins := make(chan api.Pin, 1024)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- st.List(ctx, pins)
+	}()
+	for pin := range pins {
+		d.add(pin.Cid, pi.entry(pin), pi.fields)
+	}
+	err := <-errCh
+
+	pi.mux.Lock()
+	defer pi.mux.Unlock()
+	pi.rebuilding = false
+	pending := pi.pending
+	pi.pending = nil
+	if err != nil {
+		return err
+	}
+	for _, ev := range pending {
+		pi.apply(d, ev)
+	}
+	pi.data = d
+	pi.ready = true
+	return nil
+}
+
+// candidates returns the CIDs of the pins that may match the given options
+// according to the indexes. It returns false when the index is not ready
+// or no indexed field is used by the options, in which case the whole
+// pinset needs to be read. The candidates still have to be matched against
+// the options.
+func (pi *pinIndex) candidates(opts api.PinListOptions) ([]api.Cid, bool) {
+	if pi == nil {
+		return nil, false
+	}
+	pi.mux.RLock()
+	defer pi.mux.RUnlock()
+	if !pi.ready {
+		return nil, false
+	}
+	d := pi.data
+
+	var sets []cidSet
+	if pi.fields[pinIndexName] && (opts.NamePrefix != "" || opts.PinFilter.Name != "") {
+		lname := strings.ToLower(opts.PinFilter.Name)
+		s := make(cidSet)
+		for name, cids := range d.names {
+			if !strings.HasPrefix(name, opts.NamePrefix) ||
+				!strings.Contains(strings.ToLower(name), lname) {
+				continue
+			}
+			for c := range cids {
+				s.add(c)
+			}
+		}
+		sets = append(sets, s)
+	}
+	if pi.fields[pinIndexTags] {
+		for _, t := range opts.PinFilter.Tags {
+			sets = append(sets, d.tags[t])
+		}
+	}
+	if pi.fields[pinIndexOrigins] && opts.Origin != "" {
+		sets = append(sets, d.origins[opts.Origin.String()])
+	}
+	if pi.fields[pinIndexCreated] && (!opts.CreatedAfter.IsZero() || !opts.CreatedBefore.IsZero()) {
+		s := make(cidSet)
+		for bucket, cids := range d.created {
+			if !opts.CreatedAfter.IsZero() && bucket < createdBucket(opts.CreatedAfter) {
+				continue
+			}
+			if !opts.CreatedBefore.IsZero() && bucket > createdBucket(opts.CreatedBefore) {
+				continue
+			}
+			for c := range cids {
+				s.add(c)
+			}
+		}
+		sets = append(sets, s)
+	}
+	if len(sets) == 0 {
+		return nil, false
+	}
+
+	smallest := sets[0]
+	for _, s := range sets[1:] {
+		if len(s) < len(smallest) {
+			smallest = s
+		}
+	}
+	cids := make([]api.Cid, 0, len(smallest))
+	for c := range smallest {
+		inAll := true
+		for _, s := range sets {
+			if _, ok := s[c]; !ok {
+				inAll = false
+				break
+			}
+		}
+		if inAll {
+			cids = append(cids, c)
+		}
+	}
+	return cids, true
+}
+
+// watchPinIndex builds the pin index and rebuilds it every
+// StateSyncInterval.
+func (c *Cluster) watchPinIndex() {
+	if c.index == nil {
+		return
+	}
+	for {
+		started := time.Now()
+		cState, err := c.consensus.State(c.ctx)
+		if err == nil {
+			err = c.index.rebuild(c.ctx, cState)
+		}
+		if err != nil {
+			logger.Errorf("error building the pin index: %s", err)
+		} else {
+			logger.Debugf("pin index built in %s", time.Since(started))
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(c.config.StateSyncInterval):
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package ipfscluster
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/state"
+
+	trace "go.opencensus.io/trace"
+)
+
+// PinsWithOptions sends the pins in the shared state which match the given
+// options on the out channel, which is closed when done.
+//
+// When opts.Limit is set, only the pins with the lowest CIDs (after
+// opts.After) are sent, sorted by CID, so that large pinsets can be paged
+// through by using the last CID of a page as the cursor for the next. The
+// full pinset is read for every page, but at most one page is kept in
+// memory.
+//
+// When the options filter by fields indexed in memory (see
+// Config.PinIndexes) and not by status, only the pins selected by the
+// indexes are read from the state.
+func (c *Cluster) PinsWithOptions(ctx context.Context, opts api.PinListOptions, out chan<- api.Pin) error {
+	ctx, span := trace.StartSpan(ctx, "cluster/PinsWithOptions")
+	defer span.End()
+	defer close(out)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pins := make(chan api.Pin, 1024)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.listPins(ctx, opts, pins)
+	}()
+	// let the listing finish when we stop reading early.
+	defer func() {
+		cancel()
+		for range pins {
+		}
+	}()
+
+	if opts.Limit <= 0 {
+		for p := range pins {
+			if !opts.Match(p) {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case out <- p:
+			}
+		}
+		return <-errCh
+	}
+
+	page := &pinPage{}
+	for p := range pins {
+		if !opts.Match(p) {
+			continue
+		}
+		heap.Push(page, pinPageItem{key: p.Cid.String(), pin: p})
+		if page.Len() > opts.Limit {
+			heap.Pop(page)
+		}
+	}
+	if err := <-errCh; err != nil {
+		return err
+	}
+
+	sort.Sort(sort.Reverse(page))
+	for _, item := range *page {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case out <- item.pin:
+		}
+	}
+	return nil
+}
+
+// listPins sends all the pins in the state, only those with the given
+// status in this peer, or only those selected by the pin index, on the
+// given channel, which is closed when done. The pins still need to be
+// matched against the options.
+func (c *Cluster) listPins(ctx context.Context, opts api.PinListOptions, out chan<- api.Pin) error {
+	status := opts.Status
+	if status == api.TrackerStatusUndefined {
+		cState, err := c.consensus.State(ctx)
+		if err != nil {
+			close(out)
+			return err
+		}
+		if cids, ok := c.index.candidates(opts); ok {
+			return listIndexedPins(ctx, cState, cids, out)
+		}
+		return cState.List(ctx, out)
+	}
+
+	defer close(out)
+	pinfos := make(chan api.PinInfo, 1024)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.tracker.StatusAll(ctx, status, pinfos)
+	}()
+	for pi := range pinfos {
+		pin, err := c.PinGet(ctx, pi.Cid)
+		if err != nil { // unpinned in the meantime
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case out <- pin:
+		}
+	}
+	return <-errCh
+}
+
+// listIndexedPins sends the pins with the given CIDs which are in the state.
+func listIndexedPins(ctx context.Context, st state.ReadOnly, cids []api.Cid, out chan<- api.Pin) error {
+	defer close(out)
+	for _, ci := range cids {
+		pin, err := st.Get(ctx, ci)
+		if err == state.ErrNotFound { // unpinned in the meantime
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case out <- pin:
+		}
+	}
+	return nil
+}
+
+type pinPageItem struct {
+	key string
+	pin api.Pin
+}
+
+// pinPage is a max-heap of pins by CID which holds the lowest CIDs seen.
+type pinPage []pinPageItem
+
+func (pp pinPage) Len() int           { return len(pp) }
+func (pp pinPage) Less(i, j int) bool { return pp[i].key > pp[j].key }
+func (pp pinPage) Swap(i, j int)      { pp[i], pp[j] = pp[j], pp[i] }
+
+func (pp *pinPage) Push(x interface{}) {
+	*pp = append(*pp, x.(pinPageItem))
+}
+
+func (pp *pinPage) Pop() interface{} {
+	old := *pp
+	item := old[len(old)-1]
+	*pp = old[:len(old)-1]
+	return item
+}
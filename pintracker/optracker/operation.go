@@ -65,6 +65,11 @@ type Operation struct {
 	attemptCount int
 	priority     bool
 	error        string
+	errorReason  string
+	nextRetry    time.Time
+	failed       bool
+	queueSeq     uint64
+	progress     api.PinProgress
 	ts           time.Time
 }
 
@@ -167,6 +172,47 @@ func (op *Operation) IncAttempt() {
 	op.mu.Unlock()
 }
 
+// SetAttemptCount sets the AttemptCount, i.e. when restoring it from a
+// previous run.
+func (op *Operation) SetAttemptCount(n int) {
+	op.mu.Lock()
+	op.attemptCount = n
+	op.mu.Unlock()
+}
+
+// QueueSeq returns the sequence number given to the operation when it was
+// queued.
+func (op *Operation) QueueSeq() uint64 {
+	var n uint64
+	op.mu.RLock()
+	n = op.queueSeq
+	op.mu.RUnlock()
+	return n
+}
+
+// SetQueueSeq sets the sequence number of the operation in its queue.
+func (op *Operation) SetQueueSeq(n uint64) {
+	op.mu.Lock()
+	op.queueSeq = n
+	op.mu.Unlock()
+}
+
+// Progress returns the last progress reported for an ongoing operation.
+func (op *Operation) Progress() api.PinProgress {
+	var p api.PinProgress
+	op.mu.RLock()
+	p = op.progress
+	op.mu.RUnlock()
+	return p
+}
+
+// SetProgress sets the progress of an ongoing operation.
+func (op *Operation) SetProgress(p api.PinProgress) {
+	op.mu.Lock()
+	op.progress = p
+	op.mu.Unlock()
+}
+
 // PriorityPin returns true if the pin has been marked as priority pin.
 func (op *Operation) PriorityPin() bool {
 	var p bool
@@ -206,6 +252,56 @@ func (op *Operation) SetError(err error) {
 	op.mu.Unlock()
 }
 
+// ErrorReason returns the classification of the error attached to the
+// operation.
+func (op *Operation) ErrorReason() string {
+	var r string
+	op.mu.RLock()
+	r = op.errorReason
+	op.mu.RUnlock()
+	return r
+}
+
+// NextRetry returns the time after which an operation in error can be
+// retried automatically.
+func (op *Operation) NextRetry() time.Time {
+	var t time.Time
+	op.mu.RLock()
+	t = op.nextRetry
+	op.mu.RUnlock()
+	return t
+}
+
+// Failed returns true when the operation has been marked as failed with
+// SetFailed.
+func (op *Operation) Failed() bool {
+	var f bool
+	op.mu.RLock()
+	f = op.failed
+	op.mu.RUnlock()
+	return f
+}
+
+// SetRetry sets the reason of the error of the operation and the time after
+// which it can be retried.
+func (op *Operation) SetRetry(reason string, next time.Time) {
+	op.mu.Lock()
+	op.errorReason = reason
+	op.nextRetry = next
+	op.failed = false
+	op.mu.Unlock()
+}
+
+// SetFailed sets the reason of the error of the operation and marks it as
+// failed: it will not be retried automatically.
+func (op *Operation) SetFailed(reason string) {
+	op.mu.Lock()
+	op.errorReason = reason
+	op.nextRetry = time.Time{}
+	op.failed = true
+	op.mu.Unlock()
+}
+
 // Type returns the operation Type.
 func (op *Operation) Type() OperationType {
 	return op.opType
@@ -247,6 +343,9 @@ func (op *Operation) ToTrackerStatus() api.TrackerStatus {
 	case OperationPin:
 		switch ph {
 		case PhaseError:
+			if op.Failed() {
+				return api.TrackerStatusPinFailed
+			}
 			return api.TrackerStatusPinError
 		case PhaseQueued:
 			return api.TrackerStatusPinQueued
@@ -284,7 +383,7 @@ func (op *Operation) ToTrackerStatus() api.TrackerStatus {
 // converts it to an OpType and Phase.
 func TrackerStatusToOperationPhase(status api.TrackerStatus) (OperationType, Phase) {
 	switch status {
-	case api.TrackerStatusPinError:
+	case api.TrackerStatusPinError, api.TrackerStatusPinFailed:
 		return OperationPin, PhaseError
 	case api.TrackerStatusPinQueued:
 		return OperationPin, PhaseQueued
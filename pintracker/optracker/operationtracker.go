@@ -38,6 +38,8 @@ type OperationTracker struct {
 
 	mu         sync.RWMutex
 	operations map[api.Cid]*Operation
+
+	queuePosition func(*Operation) int
 }
 
 func (opt *OperationTracker) String() string {
@@ -70,6 +72,13 @@ func NewOperationTracker(ctx context.Context, pid peer.ID, peerName string) *Ope
 	}
 }
 
+// SetQueuePositionFunc sets the function used to obtain the position of
+// queued operations in their queue, which is then reported in their
+// PinInfo. It must be called before tracking any operation.
+func (opt *OperationTracker) SetQueuePositionFunc(f func(*Operation) int) {
+	opt.queuePosition = f
+}
+
 // TrackNewOperation will create, track and return a new operation unless
 // one already exists to do the same thing, in which case nil is returned.
 //
@@ -110,6 +119,39 @@ func (opt *OperationTracker) TrackNewOperation(ctx context.Context, pin api.Pin,
 	return op2
 }
 
+// Abort cancels any operation for the given pin, even an ongoing pin, and
+// replaces it with a pin operation that failed with the given error and
+// reason, which is not retried automatically. Nothing is done if the pin
+// was already aborted for the same reason.
+func (opt *OperationTracker) Abort(ctx context.Context, pin api.Pin, err error, reason string) {
+	_, span := trace.StartSpan(ctx, "optracker/Abort")
+	defer span.End()
+
+	opt.mu.Lock()
+	defer opt.mu.Unlock()
+
+	op, ok := opt.operations[pin.Cid]
+	if ok {
+		if op.Type() == OperationPin && op.Phase() == PhaseError && op.Failed() && op.ErrorReason() == reason {
+			op.pin = pin
+			return
+		}
+		op.tracker.recordMetricUnsafe(op, -1)
+		op.Cancel()
+	}
+
+	op2 := newOperation(opt.ctx, pin, OperationPin, PhaseError, opt)
+	op2.Cancel()
+	op2.error = err.Error()
+	op2.SetFailed(reason)
+	if ok && op.Type() == OperationPin {
+		op2.attemptCount = op.AttemptCount()
+	}
+	logger.Debugf("'%s' on cid '%s' has been aborted: %s", OperationPin, pin.Cid, err)
+	opt.operations[pin.Cid] = op2
+	opt.recordMetricUnsafe(op2, 1)
+}
+
 // Clean deletes an operation from the tracker if it is the one we are tracking
 // (compares pointers).
 func (opt *OperationTracker) Clean(ctx context.Context, op *Operation) {
@@ -121,6 +163,17 @@ func (opt *OperationTracker) Clean(ctx context.Context, op *Operation) {
 	}
 }
 
+// ResetAttemptCount sets the AttemptCount of the operation for the given Cid,
+// if any, to 0.
+func (opt *OperationTracker) ResetAttemptCount(ctx context.Context, c api.Cid) {
+	opt.mu.RLock()
+	op, ok := opt.operations[c]
+	opt.mu.RUnlock()
+	if ok {
+		op.SetAttemptCount(0)
+	}
+}
+
 // Status returns the TrackerStatus associated to the last operation known
 // with the given Cid. It returns false if we are not tracking any operation
 // for the given Cid.
@@ -178,6 +231,14 @@ func (opt *OperationTracker) unsafePinInfo(ctx context.Context, op *Operation, i
 			},
 		}
 	}
+	var queuePosition int
+	if opt.queuePosition != nil && op.Phase() == PhaseQueued {
+		queuePosition = opt.queuePosition(op)
+	}
+	var progress *api.PinProgress
+	if p := op.Progress(); p.Defined() && op.Phase() == PhaseInProgress {
+		progress = &p
+	}
 	return api.PinInfo{
 		Cid:         op.Cid(),
 		Name:        op.Pin().Name,
@@ -186,6 +247,8 @@ func (opt *OperationTracker) unsafePinInfo(ctx context.Context, op *Operation, i
 		Origins:     op.Pin().Origins,
 		Created:     op.Pin().Timestamp,
 		Metadata:    op.Pin().Metadata,
+		Tags:        op.Pin().Tags,
+		Namespace:   op.Pin().Namespace,
 		PinInfoShort: api.PinInfoShort{
 			PeerName:      opt.peerName,
 			IPFS:          ipfs.ID,
@@ -195,6 +258,10 @@ func (opt *OperationTracker) unsafePinInfo(ctx context.Context, op *Operation, i
 			AttemptCount:  op.AttemptCount(),
 			PriorityPin:   op.PriorityPin(),
 			Error:         op.Error(),
+			ErrorReason:   op.ErrorReason(),
+			NextRetry:     op.NextRetry(),
+			QueuePosition: queuePosition,
+			Progress:      progress,
 		},
 	}
 }
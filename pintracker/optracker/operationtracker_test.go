@@ -105,6 +105,31 @@ func TestOperationTracker_Clean(t *testing.T) {
 	})
 }
 
+func TestOperationTracker_Abort(t *testing.T) {
+	ctx := context.Background()
+	opt := testOperationTracker(t)
+	op := opt.TrackNewOperation(ctx, api.PinCid(test.Cid1), OperationPin, PhaseInProgress)
+	op.SetAttemptCount(2)
+
+	opt.Abort(ctx, api.PinCid(test.Cid1), errors.New("canceled"), api.ErrorReasonCanceled)
+	if !op.Canceled() {
+		t.Error("the ongoing operation should have been canceled")
+	}
+	pinfo := opt.Get(ctx, test.Cid1, api.IPFSID{})
+	if pinfo.Status != api.TrackerStatusPinFailed || pinfo.Error != "canceled" || pinfo.ErrorReason != api.ErrorReasonCanceled {
+		t.Errorf("unexpected status: %+v", pinfo)
+	}
+	if pinfo.AttemptCount != 2 {
+		t.Errorf("the attempt count should have been carried over: %d", pinfo.AttemptCount)
+	}
+
+	// A new pin operation replaces the aborted one.
+	op = opt.TrackNewOperation(ctx, api.PinCid(test.Cid1), OperationPin, PhaseQueued)
+	if op == nil {
+		t.Fatal("should have created a new operation")
+	}
+}
+
 func TestOperationTracker_Status(t *testing.T) {
 	ctx := context.Background()
 	opt := testOperationTracker(t)
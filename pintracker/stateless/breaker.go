@@ -0,0 +1,148 @@
+package stateless
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/observations"
+	"github.com/ipfs-cluster/ipfs-cluster/pintracker/optracker"
+	"github.com/ipfs-cluster/ipfs-cluster/recovery"
+
+	"go.opencensus.io/stats"
+)
+
+// breaker is a circuit breaker for the IPFS daemon. It opens after a number
+// of consecutive operations fail because IPFS is unavailable, and stays open
+// until it is closed, which happens once IPFS responds again. Operation
+// workers wait while it is open instead of failing every queued operation.
+type breaker struct {
+	threshold int
+
+	mu       sync.Mutex
+	failures int
+	// resume is not nil while the breaker is open, and it is closed
+	// when the breaker closes.
+	resume chan struct{}
+}
+
+func newBreaker(threshold int) *breaker {
+	return &breaker{threshold: threshold}
+}
+
+// record registers the outcome of an operation. It returns true when the
+// failure of the operation opens the breaker.
+func (b *breaker) record(unavailable bool) bool {
+	if b.threshold <= 0 {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !unavailable {
+		b.failures = 0
+		return false
+	}
+	b.failures++
+	if b.resume != nil || b.failures < b.threshold {
+		return false
+	}
+	b.resume = make(chan struct{})
+	return true
+}
+
+// isOpen returns whether operations are paused.
+func (b *breaker) isOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.resume != nil
+}
+
+// close resumes operations.
+func (b *breaker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.resume != nil {
+		close(b.resume)
+		b.resume = nil
+	}
+	b.failures = 0
+}
+
+// wait blocks while the breaker is open. It returns false if the context is
+// done before it closes.
+func (b *breaker) wait(ctx context.Context) bool {
+	b.mu.Lock()
+	resume := b.resume
+	b.mu.Unlock()
+	if resume == nil {
+		return true
+	}
+	select {
+	case <-resume:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// recordOutcome updates the breaker with the result of a finished
+// operation, pausing all operations when it opens.
+func (spt *Tracker) recordOutcome(op *optracker.Operation) {
+	var opened bool
+	switch op.Phase() {
+	case optracker.PhaseDone:
+		opened = spt.breaker.record(false)
+	case optracker.PhaseError:
+		opened = spt.breaker.record(errorReason(op.Error()) == api.ErrorReasonIPFSUnavailable)
+	}
+	if !opened {
+		return
+	}
+
+	logger.Errorf(
+		"%d consecutive operations failed because IPFS is unavailable. Pausing all operations until it recovers",
+		spt.config.IPFSBreakerThreshold,
+	)
+	stats.Record(spt.ctx, observations.IPFSDegraded.M(1))
+	recovery.Go(spt.ctx, "pintracker/breaker", spt.probeIPFS)
+}
+
+// probeIPFS checks whether IPFS is available with an exponential backoff and
+// closes the breaker as soon as it is. Launched when the breaker opens.
+func (spt *Tracker) probeIPFS() {
+	backoff := spt.config.IPFSBreakerBackoff
+	for {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-spt.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		var stat api.IPFSRepoStat
+		err := spt.rpcClient.CallContext(
+			spt.ctx,
+			"",
+			"IPFSConnector",
+			"RepoStat",
+			struct{}{},
+			&stat,
+		)
+		if err == nil {
+			break
+		}
+
+		backoff *= 2
+		if backoff > spt.config.IPFSBreakerMaxBackoff {
+			backoff = spt.config.IPFSBreakerMaxBackoff
+		}
+		logger.Warnf("IPFS is still unavailable (%s). Checking again in %s", err, backoff)
+	}
+
+	logger.Info("IPFS is available again. Resuming operations")
+	stats.Record(spt.ctx, observations.IPFSDegraded.M(0))
+	spt.breaker.close()
+}
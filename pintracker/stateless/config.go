@@ -17,8 +17,21 @@ const envConfigKey = "cluster_stateless"
 const (
 	DefaultMaxPinQueueSize       = 1000000
 	DefaultConcurrentPins        = 10
+	DefaultConcurrentUnpins      = 1
 	DefaultPriorityPinMaxAge     = 24 * time.Hour
 	DefaultPriorityPinMaxRetries = 5
+	DefaultWarmCacheTTL          = 10 * time.Minute
+	DefaultMaxPinAttempts        = 0
+	DefaultPinRetryDelay         = time.Minute
+	DefaultPinRetryMaxDelay      = time.Hour
+	DefaultPinRetryJitter        = 0.2
+	DefaultReconcileInterval     = 0
+	DefaultReconcileBatchSize    = 1000
+	DefaultReconcileJitter       = 0.2
+	DefaultVerifySampleSize      = 0
+	DefaultIPFSBreakerThreshold  = 5
+	DefaultIPFSBreakerBackoff    = 5 * time.Second
+	DefaultIPFSBreakerMaxBackoff = 5 * time.Minute
 )
 
 // Config allows to initialize a Monitor and customize some parameters.
@@ -29,8 +42,11 @@ type Config struct {
 	MaxPinQueueSize int
 	// ConcurrentPins specifies how many pin requests can be sent to the ipfs
 	// daemon in parallel. If the pinning method is "refs", it might increase
-	// speed. Unpin requests are always processed one by one.
+	// speed.
 	ConcurrentPins int
+	// ConcurrentUnpins specifies how many unpin requests can be sent to
+	// the ipfs daemon in parallel.
+	ConcurrentUnpins int
 
 	// PriorityPinMaxAge specifies the maximum age that a pin needs to
 	// can have since it was submitted to the cluster to be pinned
@@ -40,13 +56,83 @@ type Config struct {
 	// PriorityPinMaxRetries specifies the maximum amount of retries that
 	// a pin can have before it is moved to a non-prioritary queue.
 	PriorityPinMaxRetries int
+
+	// WarmCacheTTL specifies for how long the IPFS pinset, listed once
+	// on start, is used to tell which items are already pinned instead
+	// of checking them one by one. 0 disables it.
+	WarmCacheTTL time.Duration
+
+	// MaxPinAttempts specifies how many times a pin is attempted before
+	// it is marked as failed (pin_failed status) and no longer retried
+	// automatically. 0 means that pins are retried forever.
+	MaxPinAttempts int
+
+	// PinRetryDelay specifies how long to wait after a pin has failed
+	// before it is retried automatically. The delay doubles with every
+	// failed attempt, up to PinRetryMaxDelay. 0 means that pins in error
+	// are retried every time that they are recovered.
+	PinRetryDelay time.Duration
+
+	// PinRetryMaxDelay specifies the maximum delay between automatic
+	// retries of a pin.
+	PinRetryMaxDelay time.Duration
+
+	// PinRetryJitter is the fraction (between 0 and 1) of the retry
+	// delays which is randomized so that many failed pins are not retried
+	// at once.
+	PinRetryJitter float64
+
+	// ReconcileInterval specifies how often a slice of the items
+	// allocated to this peer is checked against IPFS, re-pinning those
+	// which are not pinned. The whole pinset is walked in successive
+	// rounds. 0 disables it.
+	ReconcileInterval time.Duration
+
+	// ReconcileBatchSize specifies how many items are checked against
+	// IPFS on every reconciliation round.
+	ReconcileBatchSize int
+
+	// ReconcileJitter is the fraction (between 0 and 1) of the
+	// ReconcileInterval which is randomized so that peers do not check
+	// their pins all at once.
+	ReconcileJitter float64
+
+	// VerifySampleSize specifies how many blocks of every pinned item
+	// are checked to be present in the IPFS repository during
+	// reconciliation rounds. Items with missing blocks are marked as
+	// corrupt and recovered. 0 disables it.
+	VerifySampleSize int
+
+	// IPFSBreakerThreshold specifies after how many consecutive
+	// operations failing because IPFS is unavailable all operations are
+	// paused. 0 disables it.
+	IPFSBreakerThreshold int
+
+	// IPFSBreakerBackoff specifies how long to wait before checking
+	// whether IPFS is available again once operations are paused. The
+	// wait doubles after every failed check, up to IPFSBreakerMaxBackoff.
+	IPFSBreakerBackoff    time.Duration
+	IPFSBreakerMaxBackoff time.Duration
 }
 
 type jsonConfig struct {
-	MaxPinQueueSize       int    `json:"max_pin_queue_size,omitempty"`
-	ConcurrentPins        int    `json:"concurrent_pins"`
-	PriorityPinMaxAge     string `json:"priority_pin_max_age"`
-	PriorityPinMaxRetries int    `json:"priority_pin_max_retries"`
+	MaxPinQueueSize       int     `json:"max_pin_queue_size,omitempty"`
+	ConcurrentPins        int     `json:"concurrent_pins"`
+	ConcurrentUnpins      int     `json:"concurrent_unpins"`
+	PriorityPinMaxAge     string  `json:"priority_pin_max_age"`
+	PriorityPinMaxRetries int     `json:"priority_pin_max_retries"`
+	WarmCacheTTL          string  `json:"warm_cache_ttl"`
+	MaxPinAttempts        int     `json:"max_pin_attempts"`
+	PinRetryDelay         string  `json:"pin_retry_delay"`
+	PinRetryMaxDelay      string  `json:"pin_retry_max_delay"`
+	PinRetryJitter        float64 `json:"pin_retry_jitter"`
+	ReconcileInterval     string  `json:"reconcile_interval"`
+	ReconcileBatchSize    int     `json:"reconcile_batch_size"`
+	ReconcileJitter       float64 `json:"reconcile_jitter"`
+	VerifySampleSize      int     `json:"verify_sample_size"`
+	IPFSBreakerThreshold  int     `json:"ipfs_breaker_threshold"`
+	IPFSBreakerBackoff    string  `json:"ipfs_breaker_backoff"`
+	IPFSBreakerMaxBackoff string  `json:"ipfs_breaker_max_backoff"`
 }
 
 // ConfigKey provides a human-friendly identifier for this type of Config.
@@ -58,8 +144,21 @@ func (cfg *Config) ConfigKey() string {
 func (cfg *Config) Default() error {
 	cfg.MaxPinQueueSize = DefaultMaxPinQueueSize
 	cfg.ConcurrentPins = DefaultConcurrentPins
+	cfg.ConcurrentUnpins = DefaultConcurrentUnpins
 	cfg.PriorityPinMaxAge = DefaultPriorityPinMaxAge
 	cfg.PriorityPinMaxRetries = DefaultPriorityPinMaxRetries
+	cfg.WarmCacheTTL = DefaultWarmCacheTTL
+	cfg.MaxPinAttempts = DefaultMaxPinAttempts
+	cfg.PinRetryDelay = DefaultPinRetryDelay
+	cfg.PinRetryMaxDelay = DefaultPinRetryMaxDelay
+	cfg.PinRetryJitter = DefaultPinRetryJitter
+	cfg.ReconcileInterval = DefaultReconcileInterval
+	cfg.ReconcileBatchSize = DefaultReconcileBatchSize
+	cfg.ReconcileJitter = DefaultReconcileJitter
+	cfg.VerifySampleSize = DefaultVerifySampleSize
+	cfg.IPFSBreakerThreshold = DefaultIPFSBreakerThreshold
+	cfg.IPFSBreakerBackoff = DefaultIPFSBreakerBackoff
+	cfg.IPFSBreakerMaxBackoff = DefaultIPFSBreakerMaxBackoff
 	return nil
 }
 
@@ -87,6 +186,10 @@ func (cfg *Config) Validate() error {
 		return errors.New("statelesstracker.concurrent_pins is too low")
 	}
 
+	if cfg.ConcurrentUnpins <= 0 {
+		return errors.New("statelesstracker.concurrent_unpins is too low")
+	}
+
 	if cfg.PriorityPinMaxAge <= 0 {
 		return errors.New("statelesstracker.priority_pin_max_age is too low")
 	}
@@ -95,6 +198,54 @@ func (cfg *Config) Validate() error {
 		return errors.New("statelesstracker.priority_pin_max_retries is too low")
 	}
 
+	if cfg.WarmCacheTTL < 0 {
+		return errors.New("statelesstracker.warm_cache_ttl is invalid")
+	}
+
+	if cfg.MaxPinAttempts < 0 {
+		return errors.New("statelesstracker.max_pin_attempts is invalid")
+	}
+
+	if cfg.PinRetryDelay < 0 {
+		return errors.New("statelesstracker.pin_retry_delay is invalid")
+	}
+
+	if cfg.PinRetryMaxDelay < cfg.PinRetryDelay {
+		return errors.New("statelesstracker.pin_retry_max_delay cannot be lower than pin_retry_delay")
+	}
+
+	if cfg.PinRetryJitter < 0 || cfg.PinRetryJitter > 1 {
+		return errors.New("statelesstracker.pin_retry_jitter must be between 0 and 1")
+	}
+
+	if cfg.ReconcileInterval < 0 {
+		return errors.New("statelesstracker.reconcile_interval is invalid")
+	}
+
+	if cfg.ReconcileBatchSize <= 0 {
+		return errors.New("statelesstracker.reconcile_batch_size is too low")
+	}
+
+	if cfg.ReconcileJitter < 0 || cfg.ReconcileJitter > 1 {
+		return errors.New("statelesstracker.reconcile_jitter must be between 0 and 1")
+	}
+
+	if cfg.VerifySampleSize < 0 {
+		return errors.New("statelesstracker.verify_sample_size is invalid")
+	}
+
+	if cfg.IPFSBreakerThreshold < 0 {
+		return errors.New("statelesstracker.ipfs_breaker_threshold is invalid")
+	}
+
+	if cfg.IPFSBreakerThreshold > 0 && cfg.IPFSBreakerBackoff <= 0 {
+		return errors.New("statelesstracker.ipfs_breaker_backoff is too low")
+	}
+
+	if cfg.IPFSBreakerMaxBackoff < cfg.IPFSBreakerBackoff {
+		return errors.New("statelesstracker.ipfs_breaker_max_backoff cannot be lower than ipfs_breaker_backoff")
+	}
+
 	return nil
 }
 
@@ -116,18 +267,55 @@ func (cfg *Config) LoadJSON(raw []byte) error {
 func (cfg *Config) applyJSONConfig(jcfg *jsonConfig) error {
 	config.SetIfNotDefault(jcfg.MaxPinQueueSize, &cfg.MaxPinQueueSize)
 	config.SetIfNotDefault(jcfg.ConcurrentPins, &cfg.ConcurrentPins)
+	config.SetIfNotDefault(jcfg.ConcurrentUnpins, &cfg.ConcurrentUnpins)
 	err := config.ParseDurations(cfg.ConfigKey(),
 		&config.DurationOpt{
 			Duration: jcfg.PriorityPinMaxAge,
 			Dst:      &cfg.PriorityPinMaxAge,
 			Name:     "priority_pin_max_age",
 		},
+		&config.DurationOpt{
+			Duration: jcfg.WarmCacheTTL,
+			Dst:      &cfg.WarmCacheTTL,
+			Name:     "warm_cache_ttl",
+		},
+		&config.DurationOpt{
+			Duration: jcfg.PinRetryDelay,
+			Dst:      &cfg.PinRetryDelay,
+			Name:     "pin_retry_delay",
+		},
+		&config.DurationOpt{
+			Duration: jcfg.PinRetryMaxDelay,
+			Dst:      &cfg.PinRetryMaxDelay,
+			Name:     "pin_retry_max_delay",
+		},
+		&config.DurationOpt{
+			Duration: jcfg.ReconcileInterval,
+			Dst:      &cfg.ReconcileInterval,
+			Name:     "reconcile_interval",
+		},
+		&config.DurationOpt{
+			Duration: jcfg.IPFSBreakerBackoff,
+			Dst:      &cfg.IPFSBreakerBackoff,
+			Name:     "ipfs_breaker_backoff",
+		},
+		&config.DurationOpt{
+			Duration: jcfg.IPFSBreakerMaxBackoff,
+			Dst:      &cfg.IPFSBreakerMaxBackoff,
+			Name:     "ipfs_breaker_max_backoff",
+		},
 	)
 	if err != nil {
 		return err
 	}
 
 	config.SetIfNotDefault(jcfg.PriorityPinMaxRetries, &cfg.PriorityPinMaxRetries)
+	config.SetIfNotDefault(jcfg.MaxPinAttempts, &cfg.MaxPinAttempts)
+	config.SetIfNotDefault(jcfg.PinRetryJitter, &cfg.PinRetryJitter)
+	config.SetIfNotDefault(jcfg.ReconcileBatchSize, &cfg.ReconcileBatchSize)
+	config.SetIfNotDefault(jcfg.ReconcileJitter, &cfg.ReconcileJitter)
+	config.SetIfNotDefault(jcfg.VerifySampleSize, &cfg.VerifySampleSize)
+	cfg.IPFSBreakerThreshold = jcfg.IPFSBreakerThreshold
 
 	return cfg.Validate()
 }
@@ -139,11 +327,30 @@ func (cfg *Config) ToJSON() ([]byte, error) {
 	return config.DefaultJSONMarshal(jcfg)
 }
 
+// JSONKeys returns the keys of the JSON representation of this
+// configuration, including those omitted when empty.
+func (cfg *Config) JSONKeys() []string {
+	return config.JSONKeys(jsonConfig{})
+}
+
 func (cfg *Config) toJSONConfig() *jsonConfig {
 	jCfg := &jsonConfig{
 		ConcurrentPins:        cfg.ConcurrentPins,
+		ConcurrentUnpins:      cfg.ConcurrentUnpins,
 		PriorityPinMaxAge:     cfg.PriorityPinMaxAge.String(),
 		PriorityPinMaxRetries: cfg.PriorityPinMaxRetries,
+		WarmCacheTTL:          cfg.WarmCacheTTL.String(),
+		MaxPinAttempts:        cfg.MaxPinAttempts,
+		PinRetryDelay:         cfg.PinRetryDelay.String(),
+		PinRetryMaxDelay:      cfg.PinRetryMaxDelay.String(),
+		PinRetryJitter:        cfg.PinRetryJitter,
+		ReconcileInterval:     cfg.ReconcileInterval.String(),
+		ReconcileBatchSize:    cfg.ReconcileBatchSize,
+		ReconcileJitter:       cfg.ReconcileJitter,
+		VerifySampleSize:      cfg.VerifySampleSize,
+		IPFSBreakerThreshold:  cfg.IPFSBreakerThreshold,
+		IPFSBreakerBackoff:    cfg.IPFSBreakerBackoff.String(),
+		IPFSBreakerMaxBackoff: cfg.IPFSBreakerMaxBackoff.String(),
 	}
 	if cfg.MaxPinQueueSize != DefaultMaxPinQueueSize {
 		jCfg.MaxPinQueueSize = cfg.MaxPinQueueSize
@@ -12,7 +12,8 @@ var cfgJSON = []byte(`
 	"max_pin_queue_size": 4092,
 	"concurrent_pins": 2,
 	"priority_pin_max_age": "240h",
-	"priority_pin_max_retries": 4
+	"priority_pin_max_retries": 4,
+	"warm_cache_ttl": "5m"
 }
 `)
 
@@ -43,6 +44,92 @@ func TestLoadJSON(t *testing.T) {
 	if cfg.PriorityPinMaxRetries != 2 {
 		t.Error("expected 2 max retries")
 	}
+	if cfg.WarmCacheTTL != 5*time.Minute {
+		t.Error("expected 5m warm cache ttl")
+	}
+
+	j.WarmCacheTTL = "-1s"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected an error with a negative warm_cache_ttl")
+	}
+
+	j.WarmCacheTTL = "5m"
+	j.MaxPinAttempts = 3
+	j.PinRetryDelay = "10m"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxPinAttempts != 3 || cfg.PinRetryDelay != 10*time.Minute {
+		t.Error("expected 3 max attempts and a 10m retry delay")
+	}
+
+	j.PinRetryJitter = 1.5
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected an error with pin_retry_jitter out of range")
+	}
+
+	j.PinRetryJitter = 0.2
+	j.ReconcileInterval = "30m"
+	j.ReconcileBatchSize = 200
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ReconcileInterval != 30*time.Minute || cfg.ReconcileBatchSize != 200 {
+		t.Error("expected a 30m reconcile interval and batches of 200")
+	}
+
+	j.ReconcileJitter = -1
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected an error with reconcile_jitter out of range")
+	}
+
+	j.ReconcileJitter = 0.2
+	j.VerifySampleSize = 16
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.VerifySampleSize != 16 {
+		t.Error("expected a verify sample size of 16")
+	}
+
+	j.VerifySampleSize = -1
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected an error with a negative verify_sample_size")
+	}
+
+	j.VerifySampleSize = 0
+	j.IPFSBreakerThreshold = 3
+	j.IPFSBreakerBackoff = "1s"
+	j.IPFSBreakerMaxBackoff = "1m"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.IPFSBreakerThreshold != 3 || cfg.IPFSBreakerBackoff != time.Second || cfg.IPFSBreakerMaxBackoff != time.Minute {
+		t.Error("unexpected ipfs breaker options")
+	}
+
+	j.IPFSBreakerMaxBackoff = "100ms"
+	tst, _ = json.Marshal(j)
+	err = cfg.LoadJSON(tst)
+	if err == nil {
+		t.Error("expected an error with ipfs_breaker_max_backoff lower than the backoff")
+	}
 }
 
 func TestToJSON(t *testing.T) {
@@ -0,0 +1,135 @@
+package stateless
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/pintracker/optracker"
+
+	"go.opencensus.io/trace"
+)
+
+// opQueue is a queue of operations. It keeps count of the operations pushed
+// to and popped from it so that the position of every queued operation can
+// be known.
+type opQueue struct {
+	ch chan *optracker.Operation
+
+	pushMu sync.Mutex
+	pushed uint64
+	popped uint64 // atomic
+}
+
+func newOpQueue(size int) *opQueue {
+	return &opQueue{
+		ch: make(chan *optracker.Operation, size),
+	}
+}
+
+// push adds the operation to the queue. It returns false when the queue is
+// full.
+func (q *opQueue) push(op *optracker.Operation) bool {
+	q.pushMu.Lock()
+	defer q.pushMu.Unlock()
+
+	op.SetQueueSeq(q.pushed + 1)
+	select {
+	case q.ch <- op:
+		q.pushed++
+		return true
+	default:
+		return false
+	}
+}
+
+// channel returns the channel to receive operations from. Received
+// operations must be marked with done. It is nil for nil queues.
+func (q *opQueue) channel() chan *optracker.Operation {
+	if q == nil {
+		return nil
+	}
+	return q.ch
+}
+
+// done records that an operation has been taken from the queue.
+func (q *opQueue) done() {
+	atomic.AddUint64(&q.popped, 1)
+}
+
+// position returns the position, starting at 1, of the operation in this
+// queue.
+func (q *opQueue) position(op *optracker.Operation) int {
+	pos := int(op.QueueSeq()) - int(atomic.LoadUint64(&q.popped))
+	if pos < 1 {
+		return 1
+	}
+	return pos
+}
+
+// len returns the number of queued operations. It includes operations
+// that have been canceled while in the queue.
+func (q *opQueue) len() int {
+	return len(q.ch)
+}
+
+// queuePosition returns the position of a queued operation among all the
+// operations of its type that are waiting to be processed. Pins in the
+// high-priority queue go before those in the priority queue, which go before
+// the rest.
+func (spt *Tracker) queuePosition(op *optracker.Operation) int {
+	if op.Type() == optracker.OperationUnpin {
+		return spt.unpinQueue.position(op)
+	}
+
+	switch {
+	case op.Pin().Priority > api.PinPriorityNormal:
+		return spt.highPinQueue.position(op)
+	case op.PriorityPin():
+		return spt.highPinQueue.len() + spt.priorityPinQueue.position(op)
+	default:
+		return spt.highPinQueue.len() + spt.priorityPinQueue.len() + spt.pinQueue.position(op)
+	}
+}
+
+// QueueInfo returns information about the queues of pin and unpin
+// operations.
+func (spt *Tracker) QueueInfo(ctx context.Context) (api.PinQueueInfo, error) {
+	ctx, span := trace.StartSpan(ctx, "tracker/stateless/QueueInfo")
+	defer span.End()
+
+	info := api.PinQueueInfo{
+		Peer:             spt.peerID,
+		Peername:         spt.peerName,
+		PinDepth:         spt.highPinQueue.len() + spt.priorityPinQueue.len() + spt.pinQueue.len(),
+		UnpinDepth:       spt.unpinQueue.len(),
+		ConcurrentPins:   spt.config.ConcurrentPins,
+		ConcurrentUnpins: spt.config.ConcurrentUnpins,
+		Degraded:         spt.breaker.isOpen(),
+	}
+
+	oldest := func(t *time.Time, ts time.Time) {
+		if t.IsZero() || ts.Before(*t) {
+			*t = ts
+		}
+	}
+	for _, pi := range spt.optracker.Filter(ctx, api.IPFSID{}, optracker.PhaseQueued) {
+		switch pi.Status {
+		case api.TrackerStatusPinQueued:
+			oldest(&info.OldestPin, pi.TS)
+		case api.TrackerStatusUnpinQueued:
+			oldest(&info.OldestUnpin, pi.TS)
+		}
+	}
+	for _, pi := range spt.optracker.Filter(ctx, api.IPFSID{}, optracker.PhaseInProgress) {
+		switch pi.Status {
+		case api.TrackerStatusPinning:
+			info.Pinning++
+		case api.TrackerStatusUnpinning:
+			info.Unpinning++
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,200 @@
+package stateless
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/pintracker/optracker"
+
+	"go.opencensus.io/trace"
+)
+
+// reconcileLoop checks a slice of the pinset against IPFS every
+// ReconcileInterval, so that drift between the shared state and what is
+// actually pinned is corrected even when no operation touches the items.
+// Launched in SetClient when ReconcileInterval is set.
+func (spt *Tracker) reconcileLoop() {
+	var cursor string
+	for {
+		timer := time.NewTimer(jitter(spt.config.ReconcileInterval, spt.config.ReconcileJitter))
+		select {
+		case <-spt.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		cursor = spt.reconcile(spt.ctx, cursor)
+	}
+}
+
+type reconcileItem struct {
+	key string
+	pin api.Pin
+}
+
+// reconcile checks up to ReconcileBatchSize items allocated to this peer,
+// the first ones following the cursor in Cid order, and re-queues those
+// that are not pinned in IPFS. When VerifySampleSize is set, a sample of the
+// blocks of pinned items is verified too, and those with missing blocks are
+// marked as corrupt and re-queued. Items with ongoing or errored operations
+// are left alone. It returns the cursor for the next round, which is empty once
+// the whole pinset has been walked. The pinset is not listed in any
+// particular order, so every round goes through all of it.
+func (spt *Tracker) reconcile(ctx context.Context, cursor string) string {
+	ctx, span := trace.StartSpan(ctx, "tracker/stateless/reconcile")
+	defer span.End()
+
+	if spt.breaker.isOpen() {
+		logger.Debug("skipping reconciliation while IPFS is unavailable")
+		return cursor
+	}
+
+	st, err := spt.getState(ctx)
+	if err != nil {
+		logger.Error(err)
+		return cursor
+	}
+
+	statePins := make(chan api.Pin, pinsChannelSize)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- st.List(ctx, statePins)
+	}()
+
+	size := spt.config.ReconcileBatchSize
+	var batch []reconcileItem
+	truncate := func() {
+		sort.Slice(batch, func(i, j int) bool {
+			return batch[i].key < batch[j].key
+		})
+		if len(batch) > size {
+			batch = batch[:size+1]
+		}
+	}
+	for pin := range statePins {
+		if pin.Type == api.MetaType || pin.IsRemotePin(spt.peerID) {
+			continue
+		}
+		key := pin.Cid.String()
+		if key <= cursor {
+			continue
+		}
+		batch = append(batch, reconcileItem{key: key, pin: pin})
+		if len(batch) >= 2*size {
+			truncate()
+		}
+	}
+	if err := <-errCh; err != nil {
+		logger.Errorf("aborting reconciliation: %s", err)
+		return cursor
+	}
+
+	// We keep one more item than needed to know whether there are more
+	// items after this batch.
+	truncate()
+	next := ""
+	if len(batch) > size {
+		batch = batch[:size]
+		next = batch[size-1].key
+	}
+
+	repinned := 0
+	corrupt := 0
+	for _, item := range batch {
+		if _, ok := spt.optracker.GetExists(ctx, item.pin.Cid, api.IPFSID{}); ok {
+			continue
+		}
+
+		var ips api.IPFSPinStatus
+		err := spt.rpcClient.CallContext(
+			ctx,
+			"",
+			"IPFSConnector",
+			"PinLsCid",
+			item.pin,
+			&ips,
+		)
+		if err != nil {
+			logger.Errorf("aborting reconciliation: %s", err)
+			return cursor
+		}
+
+		switch ips.ToTrackerStatus() {
+		case api.TrackerStatusUnpinned:
+			logger.Warnf("%s should be pinned but it is not. Re-queueing it", item.pin.Cid)
+		case api.TrackerStatusPinned:
+			if spt.config.VerifySampleSize <= 0 {
+				continue
+			}
+			var ver api.IPFSBlockVerification
+			err := spt.rpcClient.CallContext(
+				ctx,
+				"",
+				"IPFSConnector",
+				"VerifyBlocks",
+				api.IPFSBlockVerification{
+					Cid:    item.pin.Cid,
+					Sample: spt.config.VerifySampleSize,
+				},
+				&ver,
+			)
+			if err != nil {
+				logger.Errorf("aborting reconciliation: %s", err)
+				return cursor
+			}
+			if len(ver.Missing) == 0 {
+				continue
+			}
+			logger.Warnf(
+				"%s is pinned but %d of %d verified blocks are missing (%s...). Marking it as corrupt and re-queueing it",
+				item.pin.Cid,
+				len(ver.Missing),
+				ver.Checked,
+				ver.Missing[0],
+			)
+			spt.corrupt.Store(item.pin.Cid, struct{}{})
+			corrupt++
+		default:
+			continue
+		}
+
+		spt.warm.forget(item.pin.Cid)
+		err = spt.enqueue(ctx, item.pin, optracker.OperationPin)
+		if err != nil {
+			logger.Error(err)
+			continue
+		}
+		repinned++
+	}
+	logger.Debugf("reconciled %d items: %d re-queued (%d corrupt)", len(batch), repinned, corrupt)
+	return next
+}
+
+// repair makes IPFS fetch again the missing blocks of a pin that was found
+// corrupt, as pinning something that is already pinned does not check that
+// its blocks are present. "dag stat" traverses the whole DAG, retrieving the
+// blocks that are missing from the pin origins or the network.
+func (spt *Tracker) repair(ctx context.Context, op *optracker.Operation) error {
+	ctx, span := trace.StartSpan(ctx, "tracker/stateless/repair")
+	defer span.End()
+
+	logger.Infof("fetching the missing blocks of corrupt pin %s", op.Cid())
+	var stat api.IPFSDagStat
+	err := spt.rpcClient.CallContext(
+		ctx,
+		"",
+		"IPFSConnector",
+		"DagStat",
+		op.Pin(),
+		&stat,
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errCorrupt, err)
+	}
+	spt.corrupt.Delete(op.Cid())
+	logger.Infof("%s repaired: %d blocks present", op.Cid(), stat.NumBlocks)
+	return nil
+}
@@ -0,0 +1,92 @@
+package stateless
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/pintracker/optracker"
+	"github.com/ipfs-cluster/ipfs-cluster/recovery"
+)
+
+// errorReason classifies an operation error into one of the
+// api.ErrorReason* values. Errors from the IPFS connector reach us through
+// RPC, so only their messages can be inspected.
+func errorReason(err string) string {
+	switch {
+	case strings.Contains(err, recovery.ErrPanic.Error()):
+		return api.ErrorReasonPanic
+	case strings.Contains(err, errCorrupt.Error()):
+		return api.ErrorReasonCorrupt
+	case strings.Contains(err, ErrFullQueue.Error()):
+		return api.ErrorReasonQueueFull
+	case strings.Contains(err, context.DeadlineExceeded.Error()),
+		strings.Contains(err, "timeout"),
+		strings.Contains(err, "timed out"):
+		return api.ErrorReasonTimeout
+	case strings.Contains(err, "connection refused"),
+		strings.Contains(err, "connection reset"),
+		strings.Contains(err, "no such host"):
+		return api.ErrorReasonIPFSUnavailable
+	default:
+		return api.ErrorReasonIPFSError
+	}
+}
+
+// retryDelay returns how long to wait before retrying a pin after the given
+// number of failed attempts: PinRetryDelay doubled with every attempt, up to
+// PinRetryMaxDelay, and randomized by PinRetryJitter.
+func (spt *Tracker) retryDelay(attempts int) time.Duration {
+	d := spt.config.PinRetryDelay
+	max := spt.config.PinRetryMaxDelay
+	for i := 1; i < attempts && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return jitter(d, spt.config.PinRetryJitter)
+}
+
+// jitter randomizes the given fraction of a duration around it.
+func jitter(d time.Duration, fraction float64) time.Duration {
+	j := float64(d) * fraction
+	return time.Duration(float64(d) - j/2 + rand.Float64()*j)
+}
+
+// applyRetryPolicy classifies the error of an operation and decides when a
+// pin can be retried, or marks it as failed when it has used all its
+// attempts.
+func (spt *Tracker) applyRetryPolicy(op *optracker.Operation) {
+	reason := errorReason(op.Error())
+	if op.Type() != optracker.OperationPin {
+		op.SetRetry(reason, time.Time{})
+		return
+	}
+
+	attempts := op.AttemptCount()
+	if max := spt.config.MaxPinAttempts; max > 0 && attempts >= max {
+		logger.Warnf("%s failed to pin after %d attempts (%s). It will not be retried automatically", op.Cid(), attempts, reason)
+		op.SetFailed(reason)
+		return
+	}
+
+	var next time.Time
+	if spt.config.PinRetryDelay > 0 {
+		next = time.Now().Add(spt.retryDelay(attempts))
+	}
+	op.SetRetry(reason, next)
+}
+
+// resetAttempts forgets the attempts made to pin a cid.
+func (spt *Tracker) resetAttempts(ctx context.Context, c api.Cid) {
+	spt.optracker.ResetAttemptCount(ctx, c)
+	if spt.attempts == nil {
+		return
+	}
+	if err := spt.attempts.Delete(ctx, c.String()); err != nil {
+		logger.Warnf("error resetting pin attempts for %s: %s", c, err)
+	}
+}
@@ -11,7 +11,9 @@ import (
 	"time"
 
 	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/kvstore"
 	"github.com/ipfs-cluster/ipfs-cluster/pintracker/optracker"
+	"github.com/ipfs-cluster/ipfs-cluster/recovery"
 	"github.com/ipfs-cluster/ipfs-cluster/state"
 
 	logging "github.com/ipfs/go-log/v2"
@@ -25,12 +27,27 @@ var logger = logging.Logger("pintracker")
 
 const pinsChannelSize = 1024
 
+// recoverProgressEvery sets how often the progress of RecoverAll is logged,
+// in number of re-queued items.
+const recoverProgressEvery = 1000
+
+// ProgressInterval specifies how often the progress of ongoing pins is
+// requested to the IPFS connector.
+var ProgressInterval = 5 * time.Second
+
 var (
 	// ErrFullQueue is the error used when pin or unpin operation channel is full.
 	ErrFullQueue = errors.New("pin/unpin operation queue is full. Try increasing max_pin_queue_size")
 
 	// items with this error should be recovered
 	errUnexpectedlyUnpinned = errors.New("the item should be pinned but it is not")
+
+	// errCorrupt is returned when the missing blocks of a corrupt
+	// pin could not be fetched again.
+	errCorrupt = errors.New("blocks of the item are missing and could not be recovered")
+
+	// errPinCanceled is the error of canceled pins which are not pinned.
+	errPinCanceled = errors.New("the pin was canceled")
 )
 
 // Tracker uses the optracker.OperationTracker to manage
@@ -51,9 +68,26 @@ type Tracker struct {
 	rpcClient *rpc.Client
 	rpcReady  chan struct{}
 
-	priorityPinCh chan *optracker.Operation
-	pinCh         chan *optracker.Operation
-	unpinCh       chan *optracker.Operation
+	// attempts persists the number of attempts of failed pins so that
+	// they survive restarts. May be nil.
+	attempts *kvstore.Store
+
+	// warm caches the IPFS pinset on start. Nil when disabled.
+	warm *warmCache
+
+	// corrupt holds the Cids of the items with blocks missing in IPFS,
+	// as found by reconcile, which need repairing.
+	corrupt sync.Map // api.Cid -> struct{}
+
+	// breaker pauses the operation workers while IPFS is unavailable.
+	breaker *breaker
+
+	// highPinQueue takes the pins with a high priority
+	// (PinPriorityHigh), which are processed before any other.
+	highPinQueue     *opQueue
+	priorityPinQueue *opQueue
+	pinQueue         *opQueue
+	unpinQueue       *opQueue
 
 	shutdownMu sync.Mutex
 	shutdown   bool
@@ -65,23 +99,36 @@ func New(cfg *Config, pid peer.ID, peerName string, getState func(ctx context.Co
 	ctx, cancel := context.WithCancel(context.Background())
 
 	spt := &Tracker{
-		config:        cfg,
-		peerID:        pid,
-		peerName:      peerName,
-		ctx:           ctx,
-		cancel:        cancel,
-		getState:      getState,
-		optracker:     optracker.NewOperationTracker(ctx, pid, peerName),
-		rpcReady:      make(chan struct{}, 1),
-		priorityPinCh: make(chan *optracker.Operation, cfg.MaxPinQueueSize),
-		pinCh:         make(chan *optracker.Operation, cfg.MaxPinQueueSize),
-		unpinCh:       make(chan *optracker.Operation, cfg.MaxPinQueueSize),
+		config:           cfg,
+		peerID:           pid,
+		peerName:         peerName,
+		ctx:              ctx,
+		cancel:           cancel,
+		getState:         getState,
+		optracker:        optracker.NewOperationTracker(ctx, pid, peerName),
+		rpcReady:         make(chan struct{}, 1),
+		highPinQueue:     newOpQueue(cfg.MaxPinQueueSize),
+		priorityPinQueue: newOpQueue(cfg.MaxPinQueueSize),
+		pinQueue:         newOpQueue(cfg.MaxPinQueueSize),
+		unpinQueue:       newOpQueue(cfg.MaxPinQueueSize),
+		breaker:          newBreaker(cfg.IPFSBreakerThreshold),
+	}
+	spt.optracker.SetQueuePositionFunc(spt.queuePosition)
+
+	if cfg.WarmCacheTTL > 0 {
+		spt.warm = newWarmCache()
 	}
 
 	for i := 0; i < spt.config.ConcurrentPins; i++ {
-		go spt.opWorker(spt.pin, spt.priorityPinCh, spt.pinCh)
+		recovery.Go(ctx, "pintracker/pin", func() {
+			spt.opWorker(spt.pin, spt.highPinQueue, spt.priorityPinQueue, spt.pinQueue)
+		})
+	}
+	for i := 0; i < spt.config.ConcurrentUnpins; i++ {
+		recovery.Go(ctx, "pintracker/unpin", func() {
+			spt.opWorker(spt.unpin, nil, spt.unpinQueue, nil)
+		})
 	}
-	go spt.opWorker(spt.unpin, spt.unpinCh, nil)
 
 	return spt
 }
@@ -107,41 +154,107 @@ func (spt *Tracker) getIPFSID(ctx context.Context) api.IPFSID {
 	return ipfsid
 }
 
-// receives a pin Function (pin or unpin) and channels.  Used for both pinning
+// receives a pin Function (pin or unpin) and queues.  Used for both pinning
 // and unpinning.
-func (spt *Tracker) opWorker(pinF func(*optracker.Operation) error, prioCh, normalCh chan *optracker.Operation) {
+func (spt *Tracker) opWorker(pinF func(*optracker.Operation) error, high, prio, normal *opQueue) {
+	highCh := high.channel()
+	prioCh := prio.channel()
+	normalCh := normal.channel()
 
 	var op *optracker.Operation
+	var q *opQueue
 
 	for {
-		// Process the priority channel first.
+		// Do not take operations while IPFS is unavailable.
+		if !spt.breaker.wait(spt.ctx) {
+			return
+		}
+
+		// Process the high priority queue first.
 		select {
+		case op = <-highCh:
+			q = high
+			goto APPLY_OP
+		case <-spt.ctx.Done():
+			return
+		default:
+		}
+
+		// Then the priority queue.
+		select {
+		case op = <-highCh:
+			q = high
+			goto APPLY_OP
 		case op = <-prioCh:
+			q = prio
 			goto APPLY_OP
 		case <-spt.ctx.Done():
 			return
 		default:
 		}
 
-		// Then process things on the other channels.
+		// Then process things on the other queues.
 		// Block if there are no things to process.
 		select {
+		case op = <-highCh:
+			q = high
+			goto APPLY_OP
 		case op = <-prioCh:
+			q = prio
 			goto APPLY_OP
 		case op = <-normalCh:
+			q = normal
 			goto APPLY_OP
 		case <-spt.ctx.Done():
 			return
 		}
 
-		// apply operations that came from some channel
+		// apply operations that came from some queue
 	APPLY_OP:
-		if clean := applyPinF(pinF, op); clean {
+		q.done()
+		clean := applyPinF(pinF, op)
+		spt.recordOutcome(op)
+		if op.Phase() == optracker.PhaseError {
+			spt.applyRetryPolicy(op)
+		}
+		spt.reportStatus(op)
+		spt.saveAttempts(op, clean)
+		if clean {
 			spt.optracker.Clean(op.Context(), op)
 		}
 	}
 }
 
+// reportStatus records the status reached by a finished operation in the
+// cluster event history. Canceled operations are not reported.
+func (spt *Tracker) reportStatus(op *optracker.Operation) {
+	switch op.Phase() {
+	case optracker.PhaseDone, optracker.PhaseError:
+	default:
+		return
+	}
+
+	pi := api.PinInfo{
+		Cid:  op.Cid(),
+		Name: op.Pin().Name,
+		PinInfoShort: api.PinInfoShort{
+			Status: op.ToTrackerStatus(),
+			Error:  op.Error(),
+		},
+	}
+	err := spt.rpcClient.CallContext(
+		spt.ctx,
+		"",
+		"Cluster",
+		"RecordPinStatus",
+		pi,
+		&struct{}{},
+	)
+	if err != nil {
+		logger.Debugf("error recording the status of %s: %s", op.Cid(), err)
+	}
+}
+
 // applyPinF returns true if the operation can be considered "DONE".
 func applyPinF(pinF func(*optracker.Operation) error, op *optracker.Operation) bool {
 	if op.Canceled() {
@@ -151,7 +264,8 @@ func applyPinF(pinF func(*optracker.Operation) error, op *optracker.Operation) b
 	}
 	op.SetPhase(optracker.PhaseInProgress)
 	op.IncAttempt()
-	err := pinF(op) // call pin/unpin
+	// call pin/unpin. A panic fails the operation instead of the worker.
+	err := recovery.Do("pintracker/op", func() error { return pinF(op) })
 	if err != nil {
 		if op.Canceled() {
 			// there was an error because
@@ -171,7 +285,19 @@ func (spt *Tracker) pin(op *optracker.Operation) error {
 	ctx, span := trace.StartSpan(op.Context(), "tracker/stateless/pin")
 	defer span.End()
 
+	if _, ok := spt.corrupt.Load(op.Cid()); ok {
+		if err := spt.repair(ctx, op); err != nil {
+			return err
+		}
+	}
+
+	if spt.warm.isPinned(ctx, op.Pin()) {
+		logger.Debugf("%s is already pinned according to the warm cache", op.Cid())
+		return nil
+	}
+
 	logger.Debugf("issuing pin call for %s", op.Cid())
+	stopProgress := spt.watchProgress(ctx, op)
 	err := spt.rpcClient.CallContext(
 		ctx,
 		"",
@@ -180,16 +306,71 @@ func (spt *Tracker) pin(op *optracker.Operation) error {
 		op.Pin(),
 		&struct{}{},
 	)
+	stopProgress()
 	if err != nil {
 		return err
 	}
+	spt.warm.pinned(op.Pin())
+
+	// Report the pin time for the pin SLOs.
+	err = spt.rpcClient.CallContext(
+		ctx,
+		"",
+		"Cluster",
+		"RecordPinTime",
+		op.Pin(),
+		&struct{}{},
+	)
+	if err != nil {
+		logger.Debugf("error recording the pin time of %s: %s", op.Cid(), err)
+	}
 	return nil
 }
 
+// watchProgress regularly asks the IPFS connector about the progress of
+// the pin performed by op and records it in the operation. The returned
+// function stops watching.
+func (spt *Tracker) watchProgress(ctx context.Context, op *optracker.Operation) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(ProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				var p api.PinProgress
+				err := spt.rpcClient.CallContext(
+					ctx,
+					"",
+					"IPFSConnector",
+					"PinProgress",
+					op.Cid(),
+					&p,
+				)
+				if err != nil {
+					logger.Debugf("error obtaining the pin progress of %s: %s", op.Cid(), err)
+					continue
+				}
+				op.SetProgress(p)
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
 func (spt *Tracker) unpin(op *optracker.Operation) error {
 	ctx, span := trace.StartSpan(op.Context(), "tracker/stateless/unpin")
 	defer span.End()
 
+	spt.warm.forget(op.Cid())
+	spt.corrupt.Delete(op.Cid())
 	logger.Debugf("issuing unpin call for %s", op.Cid())
 	err := spt.rpcClient.CallContext(
 		ctx,
@@ -211,33 +392,53 @@ func (spt *Tracker) enqueue(ctx context.Context, c api.Pin, typ optracker.Operat
 	defer span.End()
 
 	logger.Debugf("entering enqueue: pin: %+v", c)
+	// Canceled pins abort any ongoing pin operation, including the
+	// request to IPFS, and are not pinned until they are pinned again.
+	if typ == optracker.OperationPin && c.IsCanceled() {
+		spt.optracker.Abort(ctx, c, errPinCanceled, api.ErrorReasonCanceled)
+		return nil
+	}
+
 	op := spt.optracker.TrackNewOperation(ctx, c, typ, optracker.PhaseQueued)
 	if op == nil {
 		return nil // the operation exists and must be queued already.
 	}
 
-	var ch chan *optracker.Operation
+	var q *opQueue
 
 	switch typ {
 	case optracker.OperationPin:
-		isPriorityPin := time.Now().Before(c.Timestamp.Add(spt.config.PriorityPinMaxAge)) &&
-			op.AttemptCount() <= spt.config.PriorityPinMaxRetries
-		op.SetPriorityPin(isPriorityPin)
-
-		if isPriorityPin {
-			ch = spt.priorityPinCh
-		} else {
-			ch = spt.pinCh
+		if op.AttemptCount() == 0 {
+			op.SetAttemptCount(spt.loadAttempts(ctx, c.Cid))
 		}
+		// Pins with a high priority always go first and those with
+		// a low priority last. Otherwise, recent pins which have not
+		// been retried too many times are prioritized.
+		var isPriorityPin bool
+		switch {
+		case c.Priority > api.PinPriorityNormal:
+			isPriorityPin = true
+			q = spt.highPinQueue
+		case c.Priority < api.PinPriorityNormal:
+			q = spt.pinQueue
+		default:
+			isPriorityPin = time.Now().Before(c.Timestamp.Add(spt.config.PriorityPinMaxAge)) &&
+				op.AttemptCount() <= spt.config.PriorityPinMaxRetries
+			if isPriorityPin {
+				q = spt.priorityPinQueue
+			} else {
+				q = spt.pinQueue
+			}
+		}
+		op.SetPriorityPin(isPriorityPin)
 	case optracker.OperationUnpin:
-		ch = spt.unpinCh
+		q = spt.unpinQueue
 	}
 
-	select {
-	case ch <- op:
-	default:
+	if !q.push(op) {
 		err := ErrFullQueue
 		op.SetError(err)
+		op.SetRetry(api.ErrorReasonQueueFull, time.Time{})
 		op.Cancel()
 		logger.Error(err.Error())
 		return err
@@ -250,6 +451,55 @@ func (spt *Tracker) enqueue(ctx context.Context, c api.Pin, typ optracker.Operat
 func (spt *Tracker) SetClient(c *rpc.Client) {
 	spt.rpcClient = c
 	close(spt.rpcReady)
+
+	if spt.warm != nil {
+		spt.wg.Add(1)
+		go spt.loadWarmCache()
+	}
+
+	if spt.config.ReconcileInterval > 0 {
+		recovery.Go(spt.ctx, "pintracker/reconcile", spt.reconcileLoop)
+	}
+}
+
+// SetScratchStore sets the store used to persist the number of attempts
+// of pins which have failed, so that they are not reset on restarts.
+func (spt *Tracker) SetScratchStore(store *kvstore.Store) {
+	spt.attempts = store.Namespace("/stateless-tracker/attempts")
+}
+
+// loadAttempts returns the persisted number of attempts for a cid, or 0.
+func (spt *Tracker) loadAttempts(ctx context.Context, c api.Cid) int {
+	if spt.attempts == nil {
+		return 0
+	}
+	var n int
+	err := spt.attempts.Get(ctx, c.String(), &n)
+	if err != nil && err != kvstore.ErrNotFound {
+		logger.Warnf("error loading pin attempts for %s: %s", c, err)
+	}
+	return n
+}
+
+// saveAttempts persists the number of attempts of failed pins and forgets
+// them once the item has been pinned or unpinned.
+func (spt *Tracker) saveAttempts(op *optracker.Operation, clean bool) {
+	if spt.attempts == nil {
+		return
+	}
+
+	var err error
+	switch {
+	case clean:
+		err = spt.attempts.Delete(spt.ctx, op.Cid().String())
+	case op.Type() == optracker.OperationPin && op.Phase() == optracker.PhaseError:
+		err = spt.attempts.Put(spt.ctx, op.Cid().String(), op.AttemptCount())
+	default:
+		return
+	}
+	if err != nil {
+		logger.Warnf("error saving pin attempts for %s: %s", op.Cid(), err)
+	}
 }
 
 // Shutdown finishes the services provided by the StatelessPinTracker
@@ -349,6 +599,10 @@ func (spt *Tracker) StatusAll(ctx context.Context, filter api.TrackerStatus, out
 	var ipfsRecursivePins map[api.Cid]api.IPFSPinStatus
 	// Only query IPFS if we want to status for pinned items
 	if filter.Match(api.TrackerStatusPinned | api.TrackerStatusUnexpectedlyUnpinned) {
+		// Use the pinset listed on start while it is fresh.
+		ipfsRecursivePins = spt.warm.pinset(ctx)
+	}
+	if ipfsRecursivePins == nil && filter.Match(api.TrackerStatusPinned|api.TrackerStatusUnexpectedlyUnpinned) {
 		ipfsRecursivePins = make(map[api.Cid]api.IPFSPinStatus)
 		// At some point we need a full map of what we have and what
 		// we don't. The IPFS pinset is the smallest thing we can keep
@@ -413,6 +667,8 @@ func (spt *Tracker) StatusAll(ctx context.Context, filter api.TrackerStatus, out
 			Origins:     p.Origins,
 			Created:     p.Timestamp,
 			Metadata:    p.Metadata,
+			Tags:        p.Tags,
+			Namespace:   p.Namespace,
 
 			PinInfoShort: api.PinInfoShort{
 				PeerName:      spt.peerName,
@@ -438,6 +694,8 @@ func (spt *Tracker) StatusAll(ctx context.Context, filter api.TrackerStatus, out
 			// unless the filter is Pinned |
 			// UnexpectedlyUnpinned. We filter at the end.
 			info.Status = ipfsStatus.ToTrackerStatus()
+		case p.IsCanceled():
+			setCanceled(&info.PinInfoShort)
 		default:
 			// Not on an operation
 			// Not a meta pin
@@ -545,6 +803,10 @@ func (spt *Tracker) Status(ctx context.Context, c api.Cid) api.PinInfo {
 	ipfsStatus := ips.ToTrackerStatus()
 	switch ipfsStatus {
 	case api.TrackerStatusUnpinned:
+		if gpin.IsCanceled() {
+			setCanceled(&pinInfo.PinInfoShort)
+			break
+		}
 		// The item is in the state but not in IPFS:
 		// PinError. Should be pinned.
 		pinInfo.Status = api.TrackerStatusUnexpectedlyUnpinned
@@ -555,23 +817,56 @@ func (spt *Tracker) Status(ctx context.Context, c api.Cid) api.PinInfo {
 	return pinInfo
 }
 
+// setCanceled sets the status of a canceled pin which is not pinned.
+func setCanceled(pis *api.PinInfoShort) {
+	pis.Status = api.TrackerStatusPinFailed
+	pis.Error = errPinCanceled.Error()
+	pis.ErrorReason = api.ErrorReasonCanceled
+}
+
 // RecoverAll attempts to recover all items tracked by this peer. It returns
 // any errors or when it is done re-tracking.
 func (spt *Tracker) RecoverAll(ctx context.Context, out chan<- api.PinInfo) error {
+	return spt.RecoverAllWithOptions(ctx, api.RecoverAllOptions{}, out)
+}
+
+// RecoverAllWithOptions attempts to recover the items tracked by this peer
+// which are selected by the options. Items are re-queued at opts.Rate per
+// second at most. Progress is logged regularly.
+func (spt *Tracker) RecoverAllWithOptions(ctx context.Context, opts api.RecoverAllOptions, out chan<- api.PinInfo) error {
 	defer close(out)
 
 	ctx, span := trace.StartSpan(ctx, "tracker/stateless/RecoverAll")
 	defer span.End()
 
+	var limiter <-chan time.Time
+	if opts.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.Rate))
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+
 	statusesCh := make(chan api.PinInfo, 1024)
 	go func() {
-		err := spt.StatusAll(ctx, api.TrackerStatusUndefined, statusesCh)
+		err := spt.StatusAll(ctx, opts.Filter, statusesCh)
 		if err != nil {
 			logger.Error(err)
 		}
 	}()
 
+	requeued := 0
+	defer func() {
+		if requeued > 0 {
+			logger.Infof("recover: %d items re-queued", requeued)
+		}
+	}()
+
 	for st := range statusesCh {
+		if !opts.Match(st) {
+			continue
+		}
+		requeue := needsRecover(st, opts.Force)
+
 		// Break out if we shutdown. We might be going through
 		// a very long list of statuses.
 		select {
@@ -580,26 +875,57 @@ func (spt *Tracker) RecoverAll(ctx context.Context, out chan<- api.PinInfo) erro
 			logger.Error(err)
 			return err
 		default:
-			p, err := spt.recoverWithPinInfo(ctx, st)
-			if err != nil {
-				err = fmt.Errorf("RecoverAll error: %w", err)
+		}
+
+		if requeue && limiter != nil {
+			select {
+			case <-ctx.Done():
+				err := fmt.Errorf("RecoverAll aborted: %w", ctx.Err())
 				logger.Error(err)
 				return err
+			case <-limiter:
 			}
-			if p.Defined() {
-				select {
-				case <-ctx.Done():
-					err = fmt.Errorf("RecoverAll aborted: %w", ctx.Err())
-					logger.Error(err)
-					return err
-				case out <- p:
-				}
+		}
+
+		p, err := spt.recoverWithPinInfo(ctx, st, opts.Force)
+		if err != nil {
+			err = fmt.Errorf("RecoverAll error: %w", err)
+			logger.Error(err)
+			return err
+		}
+		if requeue {
+			requeued++
+			if requeued%recoverProgressEvery == 0 {
+				logger.Infof("recover: %d items re-queued so far", requeued)
+			}
+		}
+		if p.Defined() {
+			select {
+			case <-ctx.Done():
+				err = fmt.Errorf("RecoverAll aborted: %w", ctx.Err())
+				logger.Error(err)
+				return err
+			case out <- p:
 			}
 		}
 	}
 	return nil
 }
 
+// needsRecover returns whether recoverWithPinInfo re-queues the given item.
+func needsRecover(pi api.PinInfo, force bool) bool {
+	switch pi.Status {
+	case api.TrackerStatusPinFailed:
+		return force
+	case api.TrackerStatusPinError, api.TrackerStatusUnexpectedlyUnpinned:
+		return force || !time.Now().Before(pi.NextRetry)
+	case api.TrackerStatusUnpinError:
+		return true
+	default:
+		return false
+	}
+}
+
 // Recover will trigger pinning or unpinning for items in
 // PinError or UnpinError states.
 func (spt *Tracker) Recover(ctx context.Context, c api.Cid) (api.PinInfo, error) {
@@ -608,7 +934,7 @@ func (spt *Tracker) Recover(ctx context.Context, c api.Cid) (api.PinInfo, error)
 
 	pi := spt.Status(ctx, c)
 
-	recPi, err := spt.recoverWithPinInfo(ctx, pi)
+	recPi, err := spt.recoverWithPinInfo(ctx, pi, true)
 	// if it was not enqueued, no updated pin-info is returned.
 	// Use the one we had.
 	if !recPi.Defined() {
@@ -617,7 +943,11 @@ func (spt *Tracker) Recover(ctx context.Context, c api.Cid) (api.PinInfo, error)
 	return recPi, err
 }
 
-func (spt *Tracker) recoverWithPinInfo(ctx context.Context, pi api.PinInfo) (api.PinInfo, error) {
+// recoverWithPinInfo re-queues items in error. Unless forced, the retry
+// policy is respected: pins waiting for their next retry are returned
+// untouched and failed pins are ignored. Forcing the recovery of a failed pin
+// resets its attempt count.
+func (spt *Tracker) recoverWithPinInfo(ctx context.Context, pi api.PinInfo, force bool) (api.PinInfo, error) {
 	st, err := spt.getState(ctx)
 	if err != nil {
 		logger.Error(err)
@@ -627,7 +957,17 @@ func (spt *Tracker) recoverWithPinInfo(ctx context.Context, pi api.PinInfo) (api
 	var pin api.Pin
 
 	switch pi.Status {
+	case api.TrackerStatusPinFailed:
+		if !force {
+			return api.PinInfo{}, nil
+		}
+		spt.resetAttempts(ctx, pi.Cid)
+		fallthrough
 	case api.TrackerStatusPinError, api.TrackerStatusUnexpectedlyUnpinned:
+		if !force && time.Now().Before(pi.NextRetry) {
+			logger.Debugf("%s will be retried after %s", pi.Cid, pi.NextRetry)
+			return pi, nil
+		}
 		pin, err = st.Get(ctx, pi.Cid)
 		if err != nil { // ignore error - in case pin was removed while recovering
 			logger.Warn(err)
@@ -3,11 +3,14 @@ package stateless
 import (
 	"context"
 	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/ipfs-cluster/ipfs-cluster/api"
 	"github.com/ipfs-cluster/ipfs-cluster/datastore/inmem"
+	"github.com/ipfs-cluster/ipfs-cluster/kvstore"
 	"github.com/ipfs-cluster/ipfs-cluster/state"
 	"github.com/ipfs-cluster/ipfs-cluster/state/dsstate"
 	"github.com/ipfs-cluster/ipfs-cluster/test"
@@ -20,6 +23,7 @@ var (
 	pinCancelCid      = test.Cid3
 	unpinCancelCid    = test.Cid2
 	pinErrCid         = test.ErrorCid
+	unavailableCid    = test.NotFoundCid
 	errPinCancelCid   = errors.New("should not have received rpc.IPFSPin operation")
 	errUnpinCancelCid = errors.New("should not have received rpc.IPFSUnpin operation")
 	pinOpts           = api.PinOptions{
@@ -34,11 +38,24 @@ var (
 // 	os.Exit(m.Run())
 // }
 
+// counts the calls to the IPFS Pin method.
+var ipfsPinCalls int64
+
+// records the order of the calls to the IPFS Pin method.
+var (
+	ipfsPinnedMux sync.Mutex
+	ipfsPinned    []api.Cid
+)
+
 // Overwrite Pin and Unpin methods on the normal mock in order to return
 // special errors when unwanted operations have been triggered.
 type mockIPFS struct{}
 
 func (mock *mockIPFS) Pin(ctx context.Context, in api.Pin, out *struct{}) error {
+	atomic.AddInt64(&ipfsPinCalls, 1)
+	ipfsPinnedMux.Lock()
+	ipfsPinned = append(ipfsPinned, in.Cid)
+	ipfsPinnedMux.Unlock()
 	switch in.Cid {
 	case pinCancelCid:
 		return errPinCancelCid
@@ -46,6 +63,8 @@ func (mock *mockIPFS) Pin(ctx context.Context, in api.Pin, out *struct{}) error
 		time.Sleep(time.Second)
 	case pinErrCid:
 		return errors.New("error pinning")
+	case unavailableCid:
+		return errors.New("dial tcp: connection refused")
 	}
 	return nil
 }
@@ -63,6 +82,17 @@ func (mock *mockIPFS) Unpin(ctx context.Context, in api.Pin, out *struct{}) erro
 	return nil
 }
 
+func (mock *mockIPFS) PinProgress(ctx context.Context, in api.Cid, out *api.PinProgress) error {
+	if in == test.SlowCid1 {
+		*out = api.PinProgress{
+			Blocks:       5,
+			Started:      time.Now(),
+			LastProgress: time.Now(),
+		}
+	}
+	return nil
+}
+
 func (mock *mockIPFS) PinLs(ctx context.Context, in <-chan []string, out chan<- api.IPFSPinInfo) error {
 	out <- api.IPFSPinInfo{
 		Cid:  api.Cid(test.Cid1),
@@ -88,6 +118,34 @@ func (mock *mockIPFS) PinLsCid(ctx context.Context, in api.Pin, out *api.IPFSPin
 	return nil
 }
 
+// counts the calls to the IPFS DagStat method.
+var ipfsDagStatCalls int64
+
+func (mock *mockIPFS) DagStat(ctx context.Context, in api.Pin, out *api.IPFSDagStat) error {
+	atomic.AddInt64(&ipfsDagStatCalls, 1)
+	*out = api.IPFSDagStat{Size: 1000, NumBlocks: 1}
+	return nil
+}
+
+// VerifyBlocks reports the root block of Cid2 as missing.
+func (mock *mockIPFS) VerifyBlocks(ctx context.Context, in api.IPFSBlockVerification, out *api.IPFSBlockVerification) error {
+	*out = api.IPFSBlockVerification{Cid: in.Cid, Sample: in.Sample, Checked: 1}
+	if in.Cid == test.Cid2 {
+		out.Missing = []api.Cid{in.Cid}
+	}
+	return nil
+}
+
+// makes the IPFS RepoStat method fail when set to 1.
+var ipfsUnavailable int32
+
+func (mock *mockIPFS) RepoStat(ctx context.Context, in struct{}, out *api.IPFSRepoStat) error {
+	if atomic.LoadInt32(&ipfsUnavailable) == 1 {
+		return errors.New("dial tcp: connection refused")
+	}
+	return nil
+}
+
 type mockCluster struct{}
 
 func (mock *mockCluster) IPFSID(ctx context.Context, in peer.ID, out *api.IPFSID) error {
@@ -99,6 +157,18 @@ func (mock *mockCluster) IPFSID(ctx context.Context, in peer.ID, out *api.IPFSID
 	return nil
 }
 
+// the last status reported for each CID with RecordPinStatus.
+var reportedStatus sync.Map
+
+func (mock *mockCluster) RecordPinStatus(ctx context.Context, in api.PinInfo, out *struct{}) error {
+	reportedStatus.Store(in.Cid, in)
+	return nil
+}
+
+func (mock *mockCluster) RecordPinTime(ctx context.Context, in api.Pin, out *struct{}) error {
+	return nil
+}
+
 func mockRPCClient(t testing.TB) *rpc.Client {
 	t.Helper()
 
@@ -234,6 +304,38 @@ func TestTrackUntrackWithCancel(t *testing.T) {
 	}
 }
 
+func TestTrackCanceled(t *testing.T) {
+	ctx := context.Background()
+	spt := testStatelessPinTracker(t)
+	defer spt.Shutdown(ctx)
+
+	slowPin := api.PinWithOpts(test.SlowCid1, pinOpts)
+	err := spt.Track(ctx, slowPin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(100 * time.Millisecond) // let pinning start
+
+	opCtx := spt.optracker.OpContext(ctx, slowPin.Cid)
+	slowPin.CanceledAt = time.Now()
+	err = spt.Track(ctx, slowPin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-opCtx.Done():
+	case <-time.After(100 * time.Millisecond):
+		t.Error("the pin operation should have been canceled")
+	}
+
+	pInfo := spt.optracker.Get(ctx, slowPin.Cid, api.IPFSID{})
+	if pInfo.Status != api.TrackerStatusPinFailed || pInfo.ErrorReason != api.ErrorReasonCanceled {
+		t.Errorf("unexpected status of the canceled pin: %s (%s)", pInfo.Status, pInfo.ErrorReason)
+	}
+}
+
 // This tracks a slow CID and then tracks a fast/normal one.
 // Because we are pinning the slow CID, the fast one will stay
 // queued. We proceed to untrack it then. Since it was never
@@ -573,3 +675,555 @@ func TestAttemptCountAndPriority(t *testing.T) {
 		t.Errorf("errPin should have 2 attempt counts to unpin: %+v", st)
 	}
 }
+
+func TestPinPriority(t *testing.T) {
+	ctx := context.Background()
+
+	spt := testStatelessPinTracker(t)
+	defer spt.Shutdown(ctx)
+
+	// keep the only pin worker busy while the rest are queued.
+	err := spt.Track(ctx, api.PinWithOpts(test.SlowCid1, pinOpts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	lowOpts := pinOpts
+	lowOpts.Priority = api.PinPriorityLow
+	highOpts := pinOpts
+	highOpts.Priority = api.PinPriorityHigh
+	pins := []api.Pin{
+		api.PinWithOpts(test.CidResolved, lowOpts),
+		api.PinWithOpts(test.Cid4, pinOpts),
+		api.PinWithOpts(test.Cid5, highOpts),
+	}
+	ipfsPinnedMux.Lock()
+	ipfsPinned = nil
+	ipfsPinnedMux.Unlock()
+	for _, pin := range pins {
+		err := spt.Track(ctx, pin)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if st := spt.Status(ctx, test.CidResolved); st.PriorityPin {
+		t.Error("a low priority pin should not be prioritized")
+	}
+	if st := spt.Status(ctx, test.Cid5); !st.PriorityPin {
+		t.Error("a high priority pin should be prioritized")
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+	ipfsPinnedMux.Lock()
+	defer ipfsPinnedMux.Unlock()
+	expected := []api.Cid{test.Cid5, test.Cid4, test.CidResolved}
+	if len(ipfsPinned) != len(expected) {
+		t.Fatalf("unexpected pins: %v", ipfsPinned)
+	}
+	for i, c := range expected {
+		if !ipfsPinned[i].Equals(c) {
+			t.Errorf("expected %s to be pinned in position %d: %v", c, i, ipfsPinned)
+		}
+	}
+}
+
+func TestQueueInfo(t *testing.T) {
+	ctx := context.Background()
+
+	spt := testStatelessPinTracker(t)
+	defer spt.Shutdown(ctx)
+
+	// keep the only pin worker busy while the rest are queued.
+	err := spt.Track(ctx, api.PinWithOpts(test.SlowCid1, pinOpts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	lowOpts := pinOpts
+	lowOpts.Priority = api.PinPriorityLow
+	highOpts := pinOpts
+	highOpts.Priority = api.PinPriorityHigh
+	pins := []api.Pin{
+		api.PinWithOpts(test.CidResolved, lowOpts),
+		api.PinWithOpts(test.Cid4, pinOpts),
+		api.PinWithOpts(test.Cid5, highOpts),
+	}
+	for _, pin := range pins {
+		err := spt.Track(ctx, pin)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[api.Cid]int{
+		test.Cid5:        1,
+		test.Cid4:        2,
+		test.CidResolved: 3,
+	}
+	for c, pos := range expected {
+		if st := spt.Status(ctx, c); st.QueuePosition != pos {
+			t.Errorf("%s should be in position %d: %+v", c, pos, st)
+		}
+	}
+	if st := spt.Status(ctx, test.SlowCid1); st.QueuePosition != 0 {
+		t.Error("items being pinned have no queue position")
+	}
+
+	info, err := spt.QueueInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.PinDepth != 3 || info.Pinning != 1 || info.UnpinDepth != 0 {
+		t.Errorf("unexpected queue info: %+v", info)
+	}
+	if info.OldestPin.IsZero() || !info.OldestUnpin.IsZero() {
+		t.Errorf("unexpected oldest items: %+v", info)
+	}
+	if info.ConcurrentPins != 1 || info.ConcurrentUnpins != 1 {
+		t.Errorf("unexpected concurrency: %+v", info)
+	}
+}
+
+func TestPinProgress(t *testing.T) {
+	ctx := context.Background()
+	ProgressInterval = 100 * time.Millisecond
+	defer func() { ProgressInterval = 5 * time.Second }()
+
+	spt := testStatelessPinTracker(t)
+	defer spt.Shutdown(ctx)
+
+	err := spt.Track(ctx, api.PinWithOpts(test.SlowCid1, pinOpts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(500 * time.Millisecond)
+
+	st := spt.Status(ctx, test.SlowCid1)
+	if st.Status != api.TrackerStatusPinning {
+		t.Fatalf("expected pinning status: %s", st.Status)
+	}
+	if st.Progress == nil || st.Progress.Blocks != 5 {
+		t.Errorf("expected progress to be reported: %+v", st.Progress)
+	}
+
+	time.Sleep(time.Second)
+	st = spt.Status(ctx, test.SlowCid1)
+	if st.Progress != nil {
+		t.Errorf("finished items should not report progress: %+v", st)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+
+	pins := []api.Pin{
+		api.PinWithOpts(test.Cid1, pinOpts),
+		api.PinWithOpts(test.Cid4, pinOpts),
+		api.PinWithOpts(test.Cid5, pinOpts),
+	}
+	spt := testStatelessPinTracker(t, pins...)
+	defer spt.Shutdown(ctx)
+	spt.config.ReconcileBatchSize = 1
+
+	ipfsPinnedMux.Lock()
+	ipfsPinned = nil
+	ipfsPinnedMux.Unlock()
+
+	// Every round checks one item until the whole pinset is walked.
+	cursor := ""
+	for i := 0; i < len(pins); i++ {
+		cursor = spt.reconcile(ctx, cursor)
+		if i < len(pins)-1 && cursor == "" {
+			t.Fatalf("round %d: the pinset should not be walked yet", i)
+		}
+	}
+	if cursor != "" {
+		t.Errorf("expected to start over after the last round: %s", cursor)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	ipfsPinnedMux.Lock()
+	defer ipfsPinnedMux.Unlock()
+	if len(ipfsPinned) != 2 {
+		t.Fatalf("expected the 2 items missing in IPFS to be pinned: %v", ipfsPinned)
+	}
+	for _, c := range ipfsPinned {
+		if c.Equals(test.Cid1) {
+			t.Error("items pinned in IPFS should not be re-pinned")
+		}
+	}
+}
+
+func TestReconcileVerify(t *testing.T) {
+	ctx := context.Background()
+
+	pins := []api.Pin{
+		api.PinWithOpts(test.Cid1, pinOpts),
+		api.PinWithOpts(test.Cid2, pinOpts),
+	}
+	spt := testStatelessPinTracker(t, pins...)
+	defer spt.Shutdown(ctx)
+	spt.config.VerifySampleSize = 4
+
+	ipfsPinnedMux.Lock()
+	ipfsPinned = nil
+	ipfsPinnedMux.Unlock()
+	atomic.StoreInt64(&ipfsDagStatCalls, 0)
+
+	spt.reconcile(ctx, "")
+	time.Sleep(200 * time.Millisecond)
+
+	ipfsPinnedMux.Lock()
+	defer ipfsPinnedMux.Unlock()
+	if len(ipfsPinned) != 1 || !ipfsPinned[0].Equals(test.Cid2) {
+		t.Fatalf("expected only the corrupt item to be re-pinned: %v", ipfsPinned)
+	}
+	if n := atomic.LoadInt64(&ipfsDagStatCalls); n != 1 {
+		t.Errorf("expected the corrupt item to be repaired once: %d", n)
+	}
+	if _, ok := spt.corrupt.Load(test.Cid2); ok {
+		t.Error("the item should not be corrupt after repairing it")
+	}
+	if st := spt.Status(ctx, test.Cid2); st.Status != api.TrackerStatusPinned {
+		t.Errorf("expected the item to be pinned: %s", st.Status)
+	}
+}
+
+func TestIPFSBreaker(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := &Config{}
+	cfg.Default()
+	cfg.ConcurrentPins = 1
+	cfg.IPFSBreakerThreshold = 1
+	cfg.IPFSBreakerBackoff = 50 * time.Millisecond
+	cfg.IPFSBreakerMaxBackoff = 100 * time.Millisecond
+	spt := New(cfg, test.PeerID1, test.PeerName1, getStateFunc(t))
+	spt.SetClient(mockRPCClient(t))
+	defer spt.Shutdown(ctx)
+
+	ipfsPinnedMux.Lock()
+	ipfsPinned = nil
+	ipfsPinnedMux.Unlock()
+	atomic.StoreInt32(&ipfsUnavailable, 1)
+	defer atomic.StoreInt32(&ipfsUnavailable, 0)
+
+	err := spt.Track(ctx, api.PinWithOpts(unavailableCid, pinOpts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	info, _ := spt.QueueInfo(ctx)
+	if !info.Degraded {
+		t.Fatal("operations should be paused after IPFS failed")
+	}
+
+	// New operations wait until IPFS is available.
+	err = spt.Track(ctx, api.PinWithOpts(test.Cid4, pinOpts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if st := spt.Status(ctx, test.Cid4); st.Status != api.TrackerStatusPinQueued {
+		t.Errorf("expected the item to stay queued: %s", st.Status)
+	}
+
+	atomic.StoreInt32(&ipfsUnavailable, 0)
+	time.Sleep(300 * time.Millisecond)
+
+	info, _ = spt.QueueInfo(ctx)
+	if info.Degraded {
+		t.Error("operations should resume once IPFS is available")
+	}
+	ipfsPinnedMux.Lock()
+	defer ipfsPinnedMux.Unlock()
+	if n := len(ipfsPinned); n == 0 || !ipfsPinned[n-1].Equals(test.Cid4) {
+		t.Errorf("expected the item to be pinned after resuming: %v", ipfsPinned)
+	}
+}
+
+func TestAttemptCountPersisted(t *testing.T) {
+	ctx := context.Background()
+
+	errPin := api.PinWithOpts(pinErrCid, pinOpts)
+	store := kvstore.New(inmem.New())
+
+	spt := testStatelessPinTracker(t, errPin)
+	spt.SetScratchStore(store)
+	err := spt.Track(ctx, errPin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond) // let the pin be applied
+	spt.Shutdown(ctx)
+
+	// A new tracker using the same store continues counting.
+	spt = testStatelessPinTracker(t, errPin)
+	defer spt.Shutdown(ctx)
+	spt.SetScratchStore(store)
+	err = spt.Track(ctx, errPin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond) // let the pin be applied
+	st := spt.Status(ctx, pinErrCid)
+	if st.AttemptCount != 2 {
+		t.Errorf("errPin should have 2 attempt counts after restart: %+v", st)
+	}
+
+	// Successful pins do not leave anything behind.
+	normalPin := api.PinWithOpts(test.Cid1, pinOpts)
+	err = spt.Track(ctx, normalPin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond) // let the pin be applied
+	has, err := spt.attempts.Has(ctx, test.Cid1.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if has {
+		t.Error("attempts should not be kept for pinned items")
+	}
+}
+
+func TestRecoverAllWithOptions(t *testing.T) {
+	ctx := context.Background()
+
+	errPin := api.PinWithOpts(pinErrCid, pinOpts)
+	spt := testStatelessPinTracker(t, errPin)
+	defer spt.Shutdown(ctx)
+	spt.config.PinRetryDelay = time.Hour
+	spt.config.PinRetryMaxDelay = 2 * time.Hour
+
+	err := spt.Track(ctx, errPin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond) // let the pin be applied
+
+	recoverAll := func(opts api.RecoverAllOptions) []api.PinInfo {
+		out := make(chan api.PinInfo, 10)
+		err := spt.RecoverAllWithOptions(ctx, opts, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var infos []api.PinInfo
+		for pi := range out {
+			infos = append(infos, pi)
+		}
+		time.Sleep(200 * time.Millisecond) // let the pin be applied
+		return infos
+	}
+
+	recoverAll(api.RecoverAllOptions{CidPrefix: "nomatch", Force: true})
+	if st := spt.Status(ctx, pinErrCid); st.AttemptCount != 1 {
+		t.Errorf("items not matching the options should not be retried: %+v", st)
+	}
+
+	start := time.Now()
+	infos := recoverAll(api.RecoverAllOptions{
+		Filter: api.TrackerStatusPinError,
+		Rate:   5,
+		Force:  true,
+	})
+	if len(infos) != 1 || !infos[0].Cid.Equals(pinErrCid) {
+		t.Errorf("expected errPin to be recovered: %+v", infos)
+	}
+	if time.Since(start) < 400*time.Millisecond {
+		t.Error("the recover should have been rate limited")
+	}
+	if st := spt.Status(ctx, pinErrCid); st.AttemptCount != 2 {
+		t.Errorf("errPin should have been retried despite its next retry time: %+v", st)
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	errPin := api.PinWithOpts(pinErrCid, pinOpts)
+	spt := testStatelessPinTracker(t, errPin)
+	defer spt.Shutdown(ctx)
+	spt.config.MaxPinAttempts = 2
+	spt.config.PinRetryDelay = time.Hour
+	spt.config.PinRetryMaxDelay = 2 * time.Hour
+
+	recoverAll := func() {
+		out := make(chan api.PinInfo, 10)
+		err := spt.RecoverAll(ctx, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(200 * time.Millisecond) // let the pin be applied
+	}
+
+	err := spt.Track(ctx, errPin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond) // let the pin be applied
+	st := spt.Status(ctx, pinErrCid)
+	if st.Status != api.TrackerStatusPinError || st.ErrorReason != api.ErrorReasonIPFSError {
+		t.Errorf("errPin should be in pin_error: %+v", st)
+	}
+	if d := time.Until(st.NextRetry); d < 50*time.Minute || d > 70*time.Minute {
+		t.Errorf("next retry should be in about an hour: %s", st.NextRetry)
+	}
+
+	// Automatic recovery waits for the next retry.
+	recoverAll()
+	if st = spt.Status(ctx, pinErrCid); st.AttemptCount != 1 {
+		t.Errorf("errPin should not have been retried: %+v", st)
+	}
+
+	// Manual recovery does not.
+	_, err = spt.Recover(ctx, pinErrCid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond) // let the pin be applied
+	st = spt.Status(ctx, pinErrCid)
+	if st.Status != api.TrackerStatusPinFailed || st.AttemptCount != 2 {
+		t.Errorf("errPin should have failed after 2 attempts: %+v", st)
+	}
+	if !st.NextRetry.IsZero() {
+		t.Error("failed pins should not have a next retry")
+	}
+
+	// Failed pins are left alone by automatic recoveries...
+	spt.config.PinRetryDelay = 0
+	recoverAll()
+	if st = spt.Status(ctx, pinErrCid); st.Status != api.TrackerStatusPinFailed || st.AttemptCount != 2 {
+		t.Errorf("failed pin should not have been retried: %+v", st)
+	}
+
+	// ...and get new attempts when recovered manually.
+	_, err = spt.Recover(ctx, pinErrCid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond) // let the pin be applied
+	st = spt.Status(ctx, pinErrCid)
+	if st.Status != api.TrackerStatusPinError || st.AttemptCount != 1 {
+		t.Errorf("errPin should have a new set of attempts: %+v", st)
+	}
+}
+
+func TestErrorReason(t *testing.T) {
+	cases := map[string]string{
+		"error pinning":                                    api.ErrorReasonIPFSError,
+		"dial tcp: connection refused":                     api.ErrorReasonIPFSUnavailable,
+		"context deadline exceeded":                        api.ErrorReasonTimeout,
+		ErrFullQueue.Error():                               api.ErrorReasonQueueFull,
+		"recovered from panic: nil map":                    api.ErrorReasonPanic,
+		errCorrupt.Error() + ": context deadline exceeded": api.ErrorReasonCorrupt,
+	}
+	for err, reason := range cases {
+		if r := errorReason(err); r != reason {
+			t.Errorf("%s: expected %s, got %s", err, reason, r)
+		}
+	}
+}
+
+func TestReportStatus(t *testing.T) {
+	ctx := context.Background()
+	spt := testStatelessPinTracker(t)
+	defer spt.Shutdown(ctx)
+
+	pin := api.PinWithOpts(test.Cid4, pinOpts)
+	errPin := api.PinWithOpts(pinErrCid, pinOpts)
+	for _, p := range []api.Pin{pin, errPin} {
+		if err := spt.Track(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(200 * time.Millisecond) // let the pins be applied
+
+	reported := func(c api.Cid) api.PinInfo {
+		v, ok := reportedStatus.Load(c)
+		if !ok {
+			t.Fatalf("no status reported for %s", c)
+		}
+		return v.(api.PinInfo)
+	}
+	if pi := reported(test.Cid4); pi.Status != api.TrackerStatusPinned || pi.Error != "" {
+		t.Errorf("unexpected status reported: %+v", pi)
+	}
+	if pi := reported(pinErrCid); pi.Status != api.TrackerStatusPinError || pi.Error != "error pinning" {
+		t.Errorf("unexpected status reported: %+v", pi)
+	}
+
+	if err := spt.Untrack(ctx, test.Cid4); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if pi := reported(test.Cid4); pi.Status != api.TrackerStatusUnpinned {
+		t.Errorf("unexpected status reported: %+v", pi)
+	}
+}
+
+func TestWarmCache(t *testing.T) {
+	ctx := context.Background()
+
+	pinnedPin := api.PinWithOpts(test.Cid1, pinOpts)
+	pinnedPin.MaxDepth = -1
+	newPin := api.PinWithOpts(test.Cid4, pinOpts)
+	newPin.MaxDepth = -1
+
+	spt := testStatelessPinTracker(t, pinnedPin, newPin)
+	defer spt.Shutdown(ctx)
+	atomic.StoreInt64(&ipfsPinCalls, 0)
+
+	// Cid1 is listed by the IPFS mock.
+	err := spt.Track(ctx, pinnedPin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if n := atomic.LoadInt64(&ipfsPinCalls); n != 0 {
+		t.Error("an item in the warm cache should not be pinned again:", n)
+	}
+
+	err = spt.Track(ctx, newPin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if n := atomic.LoadInt64(&ipfsPinCalls); n != 1 {
+		t.Error("an item not in the warm cache should be pinned:", n)
+	}
+
+	// The IPFS mock does not list Cid4, but the cache knows it is
+	// pinned now.
+	stAll := make(chan api.PinInfo, 10)
+	err = spt.StatusAll(ctx, api.TrackerStatusUndefined, stAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for pi := range stAll {
+		if pi.Status != api.TrackerStatusPinned {
+			t.Errorf("%s should be pinned: %s", pi.Cid, pi.Status)
+		}
+	}
+
+	// Without the warm cache every item is sent to IPFS.
+	cfg := &Config{}
+	cfg.Default()
+	cfg.WarmCacheTTL = 0
+	spt2 := New(cfg, test.PeerID1, test.PeerName1, getStateFunc(t, pinnedPin))
+	spt2.SetClient(mockRPCClient(t))
+	defer spt2.Shutdown(ctx)
+	atomic.StoreInt64(&ipfsPinCalls, 0)
+
+	err = spt2.Track(ctx, pinnedPin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if n := atomic.LoadInt64(&ipfsPinCalls); n != 1 {
+		t.Error("the pin should have been sent to IPFS:", n)
+	}
+}
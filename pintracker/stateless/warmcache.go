@@ -0,0 +1,139 @@
+package stateless
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+)
+
+// warmCache holds the IPFS pinset as listed once when the tracker starts.
+// Pin operations triggered while the shared state is loaded, and the
+// first recover of the whole pinset, are answered from it instead of
+// asking IPFS about every item. It expires after WarmCacheTTL, after which
+// items are checked one by one again as they are tracked.
+type warmCache struct {
+	ready chan struct{}
+
+	mu      sync.Mutex
+	pins    map[api.Cid]api.IPFSPinStatus
+	expires time.Time
+}
+
+func newWarmCache() *warmCache {
+	return &warmCache{
+		ready: make(chan struct{}),
+	}
+}
+
+// Launched in SetClient as a goroutine when the warm cache is enabled.
+func (spt *Tracker) loadWarmCache() {
+	defer spt.wg.Done()
+	defer close(spt.warm.ready)
+
+	started := time.Now()
+	pins := make(map[api.Cid]api.IPFSPinStatus)
+	ipfsPinsCh, errCh := spt.ipfsPins(spt.ctx)
+	for ipfsPinInfo := range ipfsPinsCh {
+		pins[ipfsPinInfo.Cid] = ipfsPinInfo.Type
+	}
+	if err := <-errCh; err != nil {
+		logger.Warnf("could not load the IPFS pinset, items will be checked one by one: %s", err)
+		return
+	}
+
+	n := len(pins)
+	spt.warm.mu.Lock()
+	spt.warm.pins = pins
+	spt.warm.expires = time.Now().Add(spt.config.WarmCacheTTL)
+	spt.warm.mu.Unlock()
+	logger.Infof("loaded %d IPFS pins in the warm cache (%s)", n, time.Since(started).Round(time.Millisecond))
+}
+
+// wait blocks until the cache has been loaded. It returns false if the
+// context is canceled first.
+func (w *warmCache) wait(ctx context.Context) bool {
+	select {
+	case <-w.ready:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// valid returns whether the cache is loaded and not expired. It must be
+// called with the lock held, and drops the pinset once expired.
+func (w *warmCache) valid() bool {
+	if w.pins == nil {
+		return false
+	}
+	if time.Now().After(w.expires) {
+		logger.Debug("warm cache expired")
+		w.pins = nil
+		return false
+	}
+	return true
+}
+
+// isPinned returns whether the cache knows the given pin to be pinned in
+// IPFS with the right depth.
+func (w *warmCache) isPinned(ctx context.Context, pin api.Pin) bool {
+	if w == nil || !w.wait(ctx) {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.valid() {
+		return false
+	}
+	st, ok := w.pins[pin.Cid]
+	return ok && st.IsPinned(pin.MaxDepth)
+}
+
+// pinned records a pin that has just been pinned in IPFS.
+func (w *warmCache) pinned(pin api.Pin) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.valid() {
+		return
+	}
+	if pin.MaxDepth == 0 {
+		w.pins[pin.Cid] = api.IPFSPinStatusDirect
+	} else {
+		w.pins[pin.Cid] = api.IPFSPinStatusRecursive
+	}
+}
+
+// forget removes an item that is being unpinned.
+func (w *warmCache) forget(c api.Cid) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.valid() {
+		delete(w.pins, c)
+	}
+}
+
+// pinset returns a copy of the cached pinset, or nil if the cache is not
+// usable.
+func (w *warmCache) pinset(ctx context.Context) map[api.Cid]api.IPFSPinStatus {
+	if w == nil || !w.wait(ctx) {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.valid() {
+		return nil
+	}
+	pins := make(map[api.Cid]api.IPFSPinStatus, len(w.pins))
+	for c, st := range w.pins {
+		pins[c] = st
+	}
+	return pins
+}
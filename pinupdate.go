@@ -0,0 +1,74 @@
+package ipfscluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+)
+
+// how often the status of an updated pin is checked before unpinning the
+// pin it replaces.
+var pinUpdateCheckFreq = 10 * time.Second
+
+// how long an updated pin may take to be pinned before giving up on
+// unpinning the pin it replaces.
+var pinUpdateReplaceTimeout = 24 * time.Hour
+
+// replaceUpdated unpins the pin that the given pin updates (ReplaceUpdated)
+// once the given pin is pinned by all the peers it is allocated to. Until
+// then, both pins are kept, so that the IPFS daemons can update the old
+// pin to the new one. The old pin is left alone if the new one does not get
+// pinned in time or is unpinned meanwhile. Only the references of the given
+// owners are removed from the old pin.
+func (c *Cluster) replaceUpdated(pin api.Pin, owners []string) {
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+
+		ctx, cancel := context.WithTimeout(c.ctx, pinUpdateReplaceTimeout)
+		defer cancel()
+
+		ticker := time.NewTicker(pinUpdateCheckFreq)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				logger.Warnf("%s was not pinned in time: not unpinning %s, which it updates", pin.Cid, pin.PinUpdate)
+				return
+			case <-ticker.C:
+			}
+
+			current, err := c.PinGet(ctx, pin.Cid)
+			if err != nil || !current.PinUpdate.Equals(pin.PinUpdate) {
+				logger.Infof("%s changed while pinning: not unpinning %s, which it updates", pin.Cid, pin.PinUpdate)
+				return
+			}
+			gpin, err := c.Status(ctx, pin.Cid)
+			if err == nil && isPinnedEverywhere(gpin) {
+				break
+			}
+		}
+
+		logger.Infof("unpinning %s: replaced by %s", pin.PinUpdate, pin.Cid)
+		if _, err := c.unref(ctx, pin.PinUpdate, owners); err != nil {
+			logger.Error(err)
+		}
+	}()
+}
+
+// isPinnedEverywhere returns whether all the peers that should pin an item
+// have pinned it.
+func isPinnedEverywhere(gpin api.GlobalPinInfo) bool {
+	pinned := false
+	for _, pinfo := range gpin.PeerMap {
+		switch pinfo.Status {
+		case api.TrackerStatusPinned:
+			pinned = true
+		case api.TrackerStatusRemote:
+		default:
+			return false
+		}
+	}
+	return pinned
+}
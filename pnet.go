@@ -0,0 +1,244 @@
+package ipfscluster
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	libp2p "github.com/libp2p/go-libp2p"
+	network "github.com/libp2p/go-libp2p/core/network"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	corepnet "github.com/libp2p/go-libp2p/core/pnet"
+	transport "github.com/libp2p/go-libp2p/core/transport"
+	tcp "github.com/libp2p/go-libp2p/p2p/transport/tcp"
+	websocket "github.com/libp2p/go-libp2p/p2p/transport/websocket"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Private network modes, which define how the cluster secret is used to
+// keep peers from other clusters out.
+const (
+	// PrivateNetworkPnet uses the libp2p private network protector,
+	// which encrypts all connections with the cluster secret.
+	PrivateNetworkPnet = "pnet"
+	// PrivateNetworkTLSPSK wraps the TCP connections of the cluster
+	// host in TLS 1.3, with both ends authenticating with a key derived
+	// from the cluster secret. It only uses the Go standard TLS stack.
+	// Relays are disabled, as relayed connections are not wrapped.
+	PrivateNetworkTLSPSK = "tls-psk"
+)
+
+// privateNetworkOpts returns the libp2p options for the transports of the
+// cluster host, which are restricted to the peers with the same secret
+// according to the given private network mode.
+func privateNetworkOpts(mode string, psk corepnet.PSK) ([]libp2p.Option, error) {
+	switch mode {
+	case PrivateNetworkPnet, "":
+		return []libp2p.Option{
+			libp2p.PrivateNetwork(psk),
+			// TODO: quic does not support private networks
+			// libp2p.DefaultTransports,
+			libp2p.NoTransports,
+			libp2p.Transport(tcp.NewTCPTransport),
+			libp2p.Transport(websocket.New),
+		}, nil
+	case PrivateNetworkTLSPSK:
+		if len(psk) == 0 {
+			return nil, errors.New("the tls-psk private network mode needs a cluster secret")
+		}
+		tlsCfg, err := newTLSPSKConfig(psk)
+		if err != nil {
+			return nil, err
+		}
+		// Only TCP connections are wrapped, so it is the only
+		// transport enabled. Relayed connections would skip it.
+		return []libp2p.Option{
+			libp2p.NoTransports,
+			libp2p.DisableRelay(),
+			libp2p.Transport(func(upgrader transport.Upgrader, rcmgr network.ResourceManager) *tlsPSKTransport {
+				return &tlsPSKTransport{
+					upgrader: upgrader,
+					rcmgr:    rcmgr,
+					tlsCfg:   tlsCfg,
+				}
+			}),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown private network mode %q", mode)
+	}
+}
+
+// newTLSPSKConfig returns a TLS configuration for the given secret. An
+// Ed25519 key and a self-signed certificate are derived deterministically
+// from the secret, so every peer with the same secret has the same
+// certificate. Both ends of a connection must present it and sign the
+// handshake with its key, which proves that they know the secret. This is
+// not the pre-shared key mode of TLS, which the Go TLS stack lacks, and it
+// only tells that the remote peer belongs to the cluster: peers are told
+// apart by the libp2p security handshake that follows.
+func newTLSPSKConfig(psk corepnet.PSK) (*tls.Config, error) {
+	seed := make([]byte, ed25519.SeedSize)
+	kdf := hkdf.New(sha256.New, psk, nil, []byte("ipfs-cluster tls-psk"))
+	if _, err := io.ReadFull(kdf, seed); err != nil {
+		return nil, err
+	}
+	priv := ed25519.NewKeyFromSeed(seed)
+	pub := priv.Public().(ed25519.PublicKey)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(nil, tmpl, tmpl, pub, priv)
+	if err != nil {
+		return nil, err
+	}
+
+	verify := func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) != 1 {
+			return errors.New("tls-psk: expected one certificate")
+		}
+		cert, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return err
+		}
+		peerPub, ok := cert.PublicKey.(ed25519.PublicKey)
+		if !ok || !pub.Equal(peerPub) {
+			return errors.New("tls-psk: the remote peer has a different cluster secret")
+		}
+		return nil
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS13,
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{der},
+			PrivateKey:  priv,
+		}},
+		ClientAuth: tls.RequireAnyClientCert,
+		// The certificate is checked by VerifyPeerCertificate
+		// instead of against a CA.
+		InsecureSkipVerify:    true, // nolint:gosec
+		VerifyPeerCertificate: verify,
+	}, nil
+}
+
+// tlsPSKTransport is a TCP transport which wraps connections in TLS before
+// upgrading them to libp2p connections.
+type tlsPSKTransport struct {
+	upgrader transport.Upgrader
+	rcmgr    network.ResourceManager
+	tlsCfg   *tls.Config
+}
+
+var _ transport.Transport = (*tlsPSKTransport)(nil)
+
+// Dial dials the peer at the remote address and performs the TLS
+// handshake.
+func (t *tlsPSKTransport) Dial(ctx context.Context, raddr ma.Multiaddr, p peer.ID) (transport.CapableConn, error) {
+	connScope, err := t.rcmgr.OpenConnection(network.DirOutbound, true, raddr)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := t.dialWithScope(ctx, raddr, p, connScope)
+	if err != nil {
+		connScope.Done()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (t *tlsPSKTransport) dialWithScope(ctx context.Context, raddr ma.Multiaddr, p peer.ID, connScope network.ConnManagementScope) (transport.CapableConn, error) {
+	if err := connScope.SetPeer(p); err != nil {
+		return nil, err
+	}
+	var d manet.Dialer
+	conn, err := d.DialContext(ctx, raddr)
+	if err != nil {
+		return nil, err
+	}
+	tc := tls.Client(conn, t.tlsCfg)
+	if err := tc.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	direction := network.DirOutbound
+	if ok, isClient, _ := network.GetSimultaneousConnect(ctx); ok && !isClient {
+		direction = network.DirInbound
+	}
+	return t.upgrader.Upgrade(ctx, t, &tlsPSKConn{Conn: tc, maconn: conn}, direction, p, connScope)
+}
+
+// CanDial returns true for TCP addresses.
+func (t *tlsPSKTransport) CanDial(addr ma.Multiaddr) bool {
+	protos := addr.Protocols()
+	return len(protos) == 2 &&
+		(protos[0].Code == ma.P_IP4 || protos[0].Code == ma.P_IP6) &&
+		protos[1].Code == ma.P_TCP
+}
+
+// Listen listens on the given TCP multiaddress. The TLS handshake of the
+// accepted connections happens when they are upgraded.
+func (t *tlsPSKTransport) Listen(laddr ma.Multiaddr) (transport.Listener, error) {
+	l, err := manet.Listen(laddr)
+	if err != nil {
+		return nil, err
+	}
+	return t.upgrader.UpgradeListener(t, &tlsPSKListener{Listener: l, tlsCfg: t.tlsCfg}), nil
+}
+
+// Protocols returns the TCP protocol.
+func (t *tlsPSKTransport) Protocols() []int {
+	return []int{ma.P_TCP}
+}
+
+// Proxy returns false.
+func (t *tlsPSKTransport) Proxy() bool {
+	return false
+}
+
+func (t *tlsPSKTransport) String() string {
+	return "TLS-PSK"
+}
+
+type tlsPSKListener struct {
+	manet.Listener
+	tlsCfg *tls.Config
+}
+
+func (l *tlsPSKListener) Accept() (manet.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &tlsPSKConn{Conn: tls.Server(conn, l.tlsCfg), maconn: conn}, nil
+}
+
+// tlsPSKConn is a TLS connection with the multiaddresses of the underlying
+// TCP connection.
+type tlsPSKConn struct {
+	*tls.Conn
+	maconn manet.Conn
+}
+
+func (c *tlsPSKConn) LocalMultiaddr() ma.Multiaddr {
+	return c.maconn.LocalMultiaddr()
+}
+
+func (c *tlsPSKConn) RemoteMultiaddr() ma.Multiaddr {
+	return c.maconn.RemoteMultiaddr()
+}
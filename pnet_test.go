@@ -3,6 +3,14 @@ package ipfscluster
 import (
 	"context"
 	"testing"
+
+	libp2p "github.com/libp2p/go-libp2p"
+	crypto "github.com/libp2p/go-libp2p/core/crypto"
+	host "github.com/libp2p/go-libp2p/core/host"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	corepnet "github.com/libp2p/go-libp2p/core/pnet"
+	transport "github.com/libp2p/go-libp2p/core/transport"
+	ma "github.com/multiformats/go-multiaddr"
 )
 
 func TestClusterSecretFormat(t *testing.T) {
@@ -61,6 +69,62 @@ func TestSimplePNet(t *testing.T) {
 	}
 }
 
+func TestTLSPSKPrivateNetwork(t *testing.T) {
+	ctx := context.Background()
+	otherSecret := make([]byte, 32)
+	copy(otherSecret, testingClusterSecret)
+	otherSecret[0]++
+
+	newTestHost := func(mode string, secret corepnet.PSK) host.Host {
+		priv, _, err := crypto.GenerateKeyPair(crypto.Ed25519, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := newHost(ctx, mode, secret, priv, libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { h.Close() })
+		return h
+	}
+	connect := func(h1, h2 host.Host) error {
+		return h1.Connect(ctx, peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()})
+	}
+
+	h1 := newTestHost(PrivateNetworkTLSPSK, testingClusterSecret)
+	h2 := newTestHost(PrivateNetworkTLSPSK, testingClusterSecret)
+	if err := connect(h1, h2); err != nil {
+		t.Fatal(err)
+	}
+
+	// relayed connections would not be wrapped in TLS.
+	type dialer interface {
+		TransportForDialing(ma.Multiaddr) transport.Transport
+	}
+	relayAddr := ma.StringCast("/ip4/127.0.0.1/tcp/1/p2p/" + h2.ID().String() + "/p2p-circuit")
+	if h1.Network().(dialer).TransportForDialing(relayAddr) != nil {
+		t.Error("tls-psk hosts should not dial through relays")
+	}
+
+	h3 := newTestHost(PrivateNetworkTLSPSK, otherSecret)
+	if err := connect(h1, h3); err == nil {
+		t.Error("connected to a peer with a different secret")
+	}
+	if err := connect(h3, h2); err == nil {
+		t.Error("connected from a peer with a different secret")
+	}
+
+	h4 := newTestHost(PrivateNetworkPnet, testingClusterSecret)
+	if err := connect(h4, h1); err == nil {
+		t.Error("connected to a tls-psk peer with pnet")
+	}
+
+	_, err := newHost(ctx, PrivateNetworkTLSPSK, nil, nil)
+	if err == nil {
+		t.Error("expected an error without a secret")
+	}
+}
+
 // // Adds one minute to tests. Disabled for the moment.
 // func TestClusterSecretRequired(t *testing.T) {
 // 	cl1Secret, err := pnet.GenerateV1Bytes()
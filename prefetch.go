@@ -0,0 +1,130 @@
+package ipfscluster
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	trace "go.opencensus.io/trace"
+)
+
+const (
+	// allocations within allocBurstWindow that make a burst.
+	allocBurstSize   = 10
+	allocBurstWindow = 5 * time.Second
+	// how long to wait for fresh metrics from a peer.
+	metricsPrefetchTimeout = 2 * time.Second
+)
+
+// allocBurst detects bursts of allocations (i.e. when pins are imported in
+// batches) so that fresh metrics can be requested from peers instead of
+// relying on possibly stale ones.
+type allocBurst struct {
+	mux          sync.Mutex
+	windowStart  time.Time
+	count        int
+	lastPrefetch time.Time
+}
+
+// observe records an allocation and returns true when metrics should be
+// prefetched. This happens at most once per window while a burst lasts.
+func (ab *allocBurst) observe(now time.Time) bool {
+	ab.mux.Lock()
+	defer ab.mux.Unlock()
+
+	if now.Sub(ab.windowStart) > allocBurstWindow {
+		ab.windowStart = now
+		ab.count = 0
+	}
+	ab.count++
+
+	if ab.count < allocBurstSize || now.Sub(ab.lastPrefetch) < allocBurstWindow {
+		return false
+	}
+	ab.lastPrefetch = now
+	return true
+}
+
+// InformerMetricsLocal returns freshly obtained metrics from all the
+// informers of this peer, without publishing them.
+func (c *Cluster) InformerMetricsLocal(ctx context.Context) []api.Metric {
+	ctx, span := trace.StartSpan(ctx, "cluster/InformerMetricsLocal")
+	defer span.End()
+
+	// Arbiters must not be allocated anything.
+	if c.config.ArbiterMode {
+		return nil
+	}
+
+	var metrics []api.Metric
+	for _, informer := range c.informers {
+		for _, m := range informer.GetMetrics(ctx) {
+			if m.Discard() {
+				continue
+			}
+			m.Peer = c.id
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics
+}
+
+// prefetchMetrics asks the cluster peers for fresh informer metrics and
+// logs them in the monitor. At most cluster.metrics_prefetch_fanout peers
+// are contacted at the same time. Errors are logged and otherwise ignored:
+// the allocation proceeds with whatever metrics the monitor has.
+func (c *Cluster) prefetchMetrics(ctx context.Context) {
+	ctx, span := trace.StartSpan(ctx, "cluster/prefetchMetrics")
+	defer span.End()
+
+	peers, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Warnf("metrics prefetch: cannot obtain peers: %s", err)
+		return
+	}
+
+	sem := make(chan struct{}, c.config.MetricsPrefetchFanout)
+	var wg sync.WaitGroup
+	for _, p := range peers {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(p peer.ID) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			c.prefetchPeerMetrics(ctx, p)
+		}(p)
+	}
+	wg.Wait()
+	logger.Debugf("metrics prefetch: refreshed metrics from %d peers", len(peers))
+}
+
+func (c *Cluster) prefetchPeerMetrics(ctx context.Context, p peer.ID) {
+	ctx, cancel := context.WithTimeout(ctx, metricsPrefetchTimeout)
+	defer cancel()
+
+	var metrics []api.Metric
+	err := c.rpcClient.CallContext(
+		ctx,
+		p,
+		"Cluster",
+		"InformerMetricsLocal",
+		struct{}{},
+		&metrics,
+	)
+	if err != nil {
+		logger.Debugf("metrics prefetch: error requesting metrics from %s: %s", p, err)
+		return
+	}
+
+	for _, m := range metrics {
+		if m.Peer != p { // peers only report their own metrics
+			continue
+		}
+		c.monitor.LogMetric(ctx, m)
+	}
+}
@@ -0,0 +1,100 @@
+package ipfscluster
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	humanize "github.com/dustin/go-humanize"
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	"go.opencensus.io/trace"
+)
+
+// pinSizeLimit returns the maximum size of the DAG of a pin, or 0 when it
+// is not limited. Pins made by API users take the lowest of the limits of
+// their owners.
+func (c *Cluster) pinSizeLimit(pin api.Pin) uint64 {
+	if len(pin.Owners) == 0 {
+		return c.config.PinSizeLimit
+	}
+
+	var limit uint64
+	for _, owner := range pin.Owners {
+		l, ok := c.config.UserPinSizeLimits[owner]
+		if !ok {
+			l, ok = c.config.UserPinSizeLimits["*"]
+		}
+		if !ok {
+			l = c.config.PinSizeLimit
+		}
+		if l > 0 && (limit == 0 || l < limit) {
+			limit = l
+		}
+	}
+	return limit
+}
+
+// preflightPinSize estimates the size of the DAG of a new pin and returns
+// an error when it exceeds the size limit that applies to it. Only the pins
+// of data added by users are checked. The estimation is asked to the peers
+// allocated by the user, which may already host the content, and then to
+// this peer. IPFS fetches the DAG from the pin origins when needed.
+func (c *Cluster) preflightPinSize(ctx context.Context, pin, existing api.Pin) error {
+	ctx, span := trace.StartSpan(ctx, "cluster/preflightPinSize")
+	defer span.End()
+
+	if existing.Defined() || pin.Type != api.DataType {
+		return nil
+	}
+	limit := c.pinSizeLimit(pin)
+	if limit == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.config.PinPreflightTimeout)
+	defer cancel()
+
+	candidates := append([]peer.ID{}, pin.UserAllocations...)
+	if !c.config.ArbiterMode {
+		candidates = append(candidates, c.id)
+	}
+
+	stat, err := c.dagStat(ctx, pin, candidates)
+	if err != nil {
+		return err
+	}
+
+	if stat.Size > limit {
+		return fmt.Errorf(
+			"%w: %s is %s, which exceeds the limit of %s",
+			api.ErrPinTooLarge,
+			pin.Cid,
+			humanize.Bytes(stat.Size),
+			humanize.Bytes(limit),
+		)
+	}
+	return nil
+}
+
+// dagStat asks the given peers, in order, for the size of the DAG of a pin
+// and returns the first answer.
+func (c *Cluster) dagStat(ctx context.Context, pin api.Pin, peers []peer.ID) (api.IPFSDagStat, error) {
+	var stat api.IPFSDagStat
+	err := fmt.Errorf("no peers can estimate the size of %s", pin.Cid)
+	for _, p := range peers {
+		err = c.rpcClient.CallContext(
+			ctx,
+			p,
+			"IPFSConnector",
+			"DagStat",
+			pin,
+			&stat,
+		)
+		if err == nil {
+			return stat, nil
+		}
+		logger.Debugf("error estimating the size of %s on %s: %s", pin.Cid, p, err)
+	}
+	return stat, fmt.Errorf("error estimating the size of %s: %w", pin.Cid, err)
+}
@@ -0,0 +1,119 @@
+// Package recovery provides helpers to isolate the goroutines of the cluster
+// components from panics. A recovered panic is logged with a crash report
+// and counted in the observations.ComponentPanics metric, and the affected
+// worker is restarted or the failed operation turned into an error, so that
+// a single bad item cannot take the whole peer down.
+package recovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/observations"
+
+	logging "github.com/ipfs/go-log/v2"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+var logger = logging.Logger("recovery")
+
+// ErrPanic is wrapped by the errors returned by Do when the function
+// panicked.
+var ErrPanic = errors.New("recovered from panic")
+
+// RestartDelay is the time that Go waits before restarting a function which
+// panicked.
+var RestartDelay = time.Second
+
+// Report describes a recovered panic.
+type Report struct {
+	Component string
+	Panic     string
+	Stack     string
+	Time      time.Time
+}
+
+// report logs a crash report and records the panic in the metrics.
+func report(component string, r interface{}) Report {
+	rep := Report{
+		Component: component,
+		Panic:     fmt.Sprint(r),
+		Stack:     string(debug.Stack()),
+		Time:      time.Now(),
+	}
+	logger.Errorw(
+		"recovered from panic",
+		"component", rep.Component,
+		"panic", rep.Panic,
+		"time", rep.Time,
+		"stack", rep.Stack,
+	)
+
+	ctx, err := tag.New(context.Background(), tag.Upsert(observations.ComponentKey, component))
+	if err != nil {
+		ctx = context.Background()
+	}
+	stats.Record(ctx, observations.ComponentPanics.M(1))
+	return rep
+}
+
+// Do calls f and returns its error. When f panics, the panic is reported and
+// returned as an error wrapping ErrPanic.
+func Do(component string, f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			report(component, r)
+			err = fmt.Errorf("%w: %v", ErrPanic, r)
+		}
+	}()
+	return f()
+}
+
+// Go runs f in a new goroutine. When f panics, the panic is reported and f
+// is started again after RestartDelay, unless the context is done. f is not
+// restarted when it returns normally.
+func Go(ctx context.Context, component string, f func()) {
+	go func() {
+		for {
+			err := Do(component, func() error {
+				f()
+				return nil
+			})
+			if err == nil {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(RestartDelay):
+				logger.Warnf("restarting %s after a panic", component)
+			}
+		}
+	}()
+}
+
+// Handler wraps an http.Handler so that panics while serving a request are
+// reported and answered with a 500 error instead of aborting the
+// connection.
+func Handler(component string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler { // used on purpose by handlers
+				panic(rec)
+			}
+			report(component, rec)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}()
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,84 @@
+package recovery
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	errTest := errors.New("test error")
+	err := Do("test", func() error { return errTest })
+	if err != errTest {
+		t.Error("expected the function error")
+	}
+
+	err = Do("test", func() error {
+		var m map[string]int
+		m["a"] = 1 // nil map
+		return nil
+	})
+	if !errors.Is(err, ErrPanic) {
+		t.Error("expected a panic error:", err)
+	}
+}
+
+func TestGo(t *testing.T) {
+	RestartDelay = 10 * time.Millisecond
+	defer func() { RestartDelay = time.Second }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var runs int32
+	done := make(chan struct{})
+	Go(ctx, "test", func() {
+		if atomic.AddInt32(&runs, 1) < 3 {
+			panic("boom")
+		}
+		close(done)
+	})
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("function was not restarted")
+	}
+	if n := atomic.LoadInt32(&runs); n != 3 {
+		t.Errorf("expected 3 runs, got %d", n)
+	}
+}
+
+func TestGoCanceled(t *testing.T) {
+	RestartDelay = 50 * time.Millisecond
+	defer func() { RestartDelay = time.Second }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var runs int32
+	Go(ctx, "test", func() {
+		atomic.AddInt32(&runs, 1)
+		cancel()
+		panic("boom")
+	})
+
+	time.Sleep(200 * time.Millisecond)
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Errorf("function should not be restarted after cancel: %d runs", n)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h := Handler("test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500, got %d", rec.Code)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/ipfs-cluster/ipfs-cluster/version"
 
 	peer "github.com/libp2p/go-libp2p/core/peer"
+	protocol "github.com/libp2p/go-libp2p/core/protocol"
 	rpc "github.com/libp2p/go-libp2p-gorpc"
 
 	ocgorpc "github.com/lanzafame/go-libp2p-ocgorpc"
@@ -39,8 +40,10 @@ const rpcStreamBufferSize = 1024
 // This does not cover globalPinInfo*(...) broadcasts nor redirects to leader
 // in Raft.
 
-// newRPCServer returns a new RPC Server for Cluster.
-func newRPCServer(c *Cluster) (*rpc.Server, error) {
+// newRPCServer returns a new RPC Server for Cluster speaking the given
+// protocol. The services returned by the shim, if any, replace the default
+// ones.
+func newRPCServer(c *Cluster, p protocol.ID, shim rpcShim) (*rpc.Server, error) {
 	var s *rpc.Server
 
 	authF := func(pid peer.ID, svc, method string) bool {
@@ -58,43 +61,42 @@ func newRPCServer(c *Cluster) (*rpc.Server, error) {
 			return false
 		}
 	}
+	if p != version.RPCProtocol {
+		authF = legacyAuthorizeFunc(c, p, authF)
+	}
 
 	if c.config.Tracing {
 		s = rpc.NewServer(
 			c.host,
-			version.RPCProtocol,
+			p,
 			rpc.WithServerStatsHandler(&ocgorpc.ServerHandler{}),
 			rpc.WithAuthorizeFunc(authF),
 			rpc.WithStreamBufferSize(rpcStreamBufferSize),
 		)
 	} else {
-		s = rpc.NewServer(c.host, version.RPCProtocol, rpc.WithAuthorizeFunc(authF))
+		s = rpc.NewServer(c.host, p, rpc.WithAuthorizeFunc(authF))
 	}
 
-	cl := &ClusterRPCAPI{c}
-	err := s.RegisterName(RPCServiceID(cl), cl)
-	if err != nil {
-		return nil, err
+	services := []interface{}{
+		&ClusterRPCAPI{c},
+		&PinTrackerRPCAPI{c.tracker},
+		&IPFSConnectorRPCAPI{c.ipfs},
+		&ConsensusRPCAPI{c.consensus},
+		&PeerMonitorRPCAPI{mon: c.monitor, pid: c.id},
 	}
-	pt := &PinTrackerRPCAPI{c.tracker}
-	err = s.RegisterName(RPCServiceID(pt), pt)
-	if err != nil {
-		return nil, err
-	}
-	ic := &IPFSConnectorRPCAPI{c.ipfs}
-	err = s.RegisterName(RPCServiceID(ic), ic)
-	if err != nil {
-		return nil, err
+	var shimmed map[string]interface{}
+	if shim != nil {
+		shimmed = shim(c)
 	}
-	cons := &ConsensusRPCAPI{c.consensus}
-	err = s.RegisterName(RPCServiceID(cons), cons)
-	if err != nil {
-		return nil, err
-	}
-	pm := &PeerMonitorRPCAPI{mon: c.monitor, pid: c.id}
-	err = s.RegisterName(RPCServiceID(pm), pm)
-	if err != nil {
-		return nil, err
+	for _, svc := range services {
+		name := RPCServiceID(svc)
+		if replacement, ok := shimmed[name]; ok {
+			svc = replacement
+		}
+		err := s.RegisterName(name, svc)
+		if err != nil {
+			return nil, err
+		}
 	}
 	return s, nil
 }
@@ -176,6 +178,7 @@ func (rpcapi *ClusterRPCAPI) Pin(ctx context.Context, in api.Pin, out *api.Pin)
 	// we do not call the Pin method directly since that method does not
 	// allow to pin other than regular DataType pins. The adder will
 	// however send Meta, Shard and ClusterDAG pins.
+	ctx = withRPCRequester(ctx, in.Requester)
 	pin, _, err := rpcapi.c.pin(ctx, in, []peer.ID{})
 	if err != nil {
 		return err
@@ -184,9 +187,32 @@ func (rpcapi *ClusterRPCAPI) Pin(ctx context.Context, in api.Pin, out *api.Pin)
 	return nil
 }
 
-// Unpin runs Cluster.Unpin().
+// Unpin removes the references of in.Owners to the pin, and runs
+// Cluster.Unpin() when none are left.
 func (rpcapi *ClusterRPCAPI) Unpin(ctx context.Context, in api.Pin, out *api.Pin) error {
-	pin, err := rpcapi.c.Unpin(ctx, in.Cid)
+	ctx = withRPCRequester(ctx, in.Requester)
+	pin, err := rpcapi.c.unref(ctx, in.Cid, in.Owners)
+	if err != nil {
+		return err
+	}
+	*out = pin
+	return nil
+}
+
+// Restore runs Cluster.Restore().
+func (rpcapi *ClusterRPCAPI) Restore(ctx context.Context, in api.Cid, out *api.Pin) error {
+	pin, err := rpcapi.c.Restore(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = pin
+	return nil
+}
+
+// CancelPin runs Cluster.CancelPin().
+func (rpcapi *ClusterRPCAPI) CancelPin(ctx context.Context, in api.CancelPinRequest, out *api.Pin) error {
+	ctx = withRPCRequester(ctx, in.Requester)
+	pin, err := rpcapi.c.CancelPin(ctx, in.Cid, in.Unpin)
 	if err != nil {
 		return err
 	}
@@ -196,6 +222,7 @@ func (rpcapi *ClusterRPCAPI) Unpin(ctx context.Context, in api.Pin, out *api.Pin
 
 // PinPath resolves path into a cid and runs Cluster.Pin().
 func (rpcapi *ClusterRPCAPI) PinPath(ctx context.Context, in api.PinPath, out *api.Pin) error {
+	ctx = withRPCRequester(ctx, in.Requester)
 	pin, err := rpcapi.c.PinPath(ctx, in.Path, in.PinOptions)
 	if err != nil {
 		return err
@@ -204,9 +231,10 @@ func (rpcapi *ClusterRPCAPI) PinPath(ctx context.Context, in api.PinPath, out *a
 	return nil
 }
 
-// UnpinPath resolves path into a cid and runs Cluster.Unpin().
+// UnpinPath resolves path into a cid and handles it like Unpin.
 func (rpcapi *ClusterRPCAPI) UnpinPath(ctx context.Context, in api.PinPath, out *api.Pin) error {
-	pin, err := rpcapi.c.UnpinPath(ctx, in.Path)
+	ctx = withRPCRequester(ctx, in.Requester)
+	pin, err := rpcapi.c.unrefPath(ctx, in.Path, in.Owners)
 	if err != nil {
 		return err
 	}
@@ -214,11 +242,37 @@ func (rpcapi *ClusterRPCAPI) UnpinPath(ctx context.Context, in api.PinPath, out
 	return nil
 }
 
+// PinHistory runs Cluster.PinHistory().
+func (rpcapi *ClusterRPCAPI) PinHistory(ctx context.Context, in api.Cid, out *[]api.PinAuditEntry) error {
+	entries, err := rpcapi.c.PinHistory(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = entries
+	return nil
+}
+
+// PinHistoryLocal runs Cluster.PinHistoryLocal().
+func (rpcapi *ClusterRPCAPI) PinHistoryLocal(ctx context.Context, in api.Cid, out *[]api.PinAuditEntry) error {
+	entries, err := rpcapi.c.PinHistoryLocal(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = entries
+	return nil
+}
+
 // Pins runs Cluster.Pins().
 func (rpcapi *ClusterRPCAPI) Pins(ctx context.Context, in <-chan struct{}, out chan<- api.Pin) error {
 	return rpcapi.c.Pins(ctx, out)
 }
 
+// PinsWithOptions runs Cluster.PinsWithOptions().
+func (rpcapi *ClusterRPCAPI) PinsWithOptions(ctx context.Context, in <-chan api.PinListOptions, out chan<- api.Pin) error {
+	opts := <-in
+	return rpcapi.c.PinsWithOptions(ctx, opts, out)
+}
+
 // PinGet runs Cluster.PinGet().
 func (rpcapi *ClusterRPCAPI) PinGet(ctx context.Context, in api.Cid, out *api.Pin) error {
 	pin, err := rpcapi.c.PinGet(ctx, in)
@@ -229,6 +283,16 @@ func (rpcapi *ClusterRPCAPI) PinGet(ctx context.Context, in api.Cid, out *api.Pi
 	return nil
 }
 
+// AllocationExplain runs Cluster.AllocationExplain().
+func (rpcapi *ClusterRPCAPI) AllocationExplain(ctx context.Context, in api.Cid, out *api.AllocationExplanation) error {
+	expl, err := rpcapi.c.AllocationExplain(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = expl
+	return nil
+}
+
 // Version runs Cluster.Version().
 func (rpcapi *ClusterRPCAPI) Version(ctx context.Context, in struct{}, out *api.Version) error {
 	*out = api.Version{
@@ -286,6 +350,12 @@ func (rpcapi *ClusterRPCAPI) StatusAll(ctx context.Context, in <-chan api.Tracke
 	return rpcapi.c.StatusAll(ctx, filter, out)
 }
 
+// StatusAllWithOptions runs Cluster.StatusAllWithOptions().
+func (rpcapi *ClusterRPCAPI) StatusAllWithOptions(ctx context.Context, in <-chan api.StatusAllOptions, out chan<- api.GlobalPinInfo) error {
+	opts := <-in
+	return rpcapi.c.StatusAllWithOptions(ctx, opts, out)
+}
+
 // StatusAllLocal runs Cluster.StatusAllLocal().
 func (rpcapi *ClusterRPCAPI) StatusAllLocal(ctx context.Context, in <-chan api.TrackerStatus, out chan<- api.PinInfo) error {
 	filter := <-in
@@ -319,6 +389,18 @@ func (rpcapi *ClusterRPCAPI) RecoverAllLocal(ctx context.Context, in <-chan stru
 	return rpcapi.c.RecoverAllLocal(ctx, out)
 }
 
+// RecoverAllWithOptions runs Cluster.RecoverAllWithOptions().
+func (rpcapi *ClusterRPCAPI) RecoverAllWithOptions(ctx context.Context, in <-chan api.RecoverAllOptions, out chan<- api.GlobalPinInfo) error {
+	opts := <-in
+	return rpcapi.c.RecoverAllWithOptions(ctx, opts, out)
+}
+
+// RecoverAllLocalWithOptions runs Cluster.RecoverAllLocalWithOptions().
+func (rpcapi *ClusterRPCAPI) RecoverAllLocalWithOptions(ctx context.Context, in <-chan api.RecoverAllOptions, out chan<- api.PinInfo) error {
+	opts := <-in
+	return rpcapi.c.RecoverAllLocalWithOptions(ctx, opts, out)
+}
+
 // Recover runs Cluster.Recover().
 func (rpcapi *ClusterRPCAPI) Recover(ctx context.Context, in api.Cid, out *api.GlobalPinInfo) error {
 	pinfo, err := rpcapi.c.Recover(ctx, in)
@@ -390,6 +472,107 @@ func (rpcapi *ClusterRPCAPI) BlockAllocate(ctx context.Context, in api.Pin, out
 	return nil
 }
 
+// ConsensusLog runs Cluster.ConsensusLog().
+func (rpcapi *ClusterRPCAPI) ConsensusLog(ctx context.Context, in api.ConsensusLogQuery, out *[]api.ConsensusLogEntry) error {
+	entries, err := rpcapi.c.ConsensusLog(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = entries
+	return nil
+}
+
+// DeadLetters runs Cluster.DeadLetters().
+func (rpcapi *ClusterRPCAPI) DeadLetters(ctx context.Context, in struct{}, out *[]api.DeadLetter) error {
+	letters, err := rpcapi.c.DeadLetters(ctx)
+	if err != nil {
+		return err
+	}
+	*out = letters
+	return nil
+}
+
+// RetryDeadLetter runs Cluster.RetryDeadLetter().
+func (rpcapi *ClusterRPCAPI) RetryDeadLetter(ctx context.Context, in string, out *struct{}) error {
+	return rpcapi.c.RetryDeadLetter(ctx, in)
+}
+
+// DiscardDeadLetter runs Cluster.DiscardDeadLetter().
+func (rpcapi *ClusterRPCAPI) DiscardDeadLetter(ctx context.Context, in string, out *struct{}) error {
+	return rpcapi.c.DiscardDeadLetter(ctx, in)
+}
+
+// Handoff runs Cluster.Handoff().
+func (rpcapi *ClusterRPCAPI) Handoff(ctx context.Context, in api.HandoffRequest, out *api.HandoffJob) error {
+	job, err := rpcapi.c.Handoff(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = job
+	return nil
+}
+
+// HandoffStatus runs Cluster.HandoffStatus().
+func (rpcapi *ClusterRPCAPI) HandoffStatus(ctx context.Context, in string, out *api.HandoffJob) error {
+	job, err := rpcapi.c.HandoffStatus(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = job
+	return nil
+}
+
+// Handoffs runs Cluster.Handoffs().
+func (rpcapi *ClusterRPCAPI) Handoffs(ctx context.Context, in struct{}, out *[]api.HandoffJob) error {
+	jobs, err := rpcapi.c.Handoffs(ctx)
+	if err != nil {
+		return err
+	}
+	*out = jobs
+	return nil
+}
+
+// CancelHandoff runs Cluster.CancelHandoff().
+func (rpcapi *ClusterRPCAPI) CancelHandoff(ctx context.Context, in string, out *struct{}) error {
+	return rpcapi.c.CancelHandoff(ctx, in)
+}
+
+// SchedulePin runs Cluster.SchedulePin().
+func (rpcapi *ClusterRPCAPI) SchedulePin(ctx context.Context, in api.PinSchedule, out *api.PinSchedule) error {
+	ctx = withRPCRequester(ctx, in.Options.Requester)
+	s, err := rpcapi.c.SchedulePin(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = s
+	return nil
+}
+
+// PinSchedules runs Cluster.PinSchedules().
+func (rpcapi *ClusterRPCAPI) PinSchedules(ctx context.Context, in struct{}, out *[]api.PinSchedule) error {
+	schedules, err := rpcapi.c.PinSchedules(ctx)
+	if err != nil {
+		return err
+	}
+	*out = schedules
+	return nil
+}
+
+// CancelPinSchedule runs Cluster.CancelPinSchedule().
+func (rpcapi *ClusterRPCAPI) CancelPinSchedule(ctx context.Context, in string, out *struct{}) error {
+	return rpcapi.c.CancelPinSchedule(ctx, in)
+}
+
+// Rollback runs Cluster.Rollback().
+func (rpcapi *ClusterRPCAPI) Rollback(ctx context.Context, in []api.Pin, out *api.RollbackInfo) error {
+	info, err := rpcapi.c.Rollback(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = info
+	return nil
+}
+
 // RepoGC performs garbage collection sweep on all peers' repos.
 func (rpcapi *ClusterRPCAPI) RepoGC(ctx context.Context, in struct{}, out *api.GlobalRepoGC) error {
 	res, err := rpcapi.c.RepoGC(ctx)
@@ -410,6 +593,225 @@ func (rpcapi *ClusterRPCAPI) RepoGCLocal(ctx context.Context, in struct{}, out *
 	return nil
 }
 
+// PinQueues runs Cluster.PinQueues().
+func (rpcapi *ClusterRPCAPI) PinQueues(ctx context.Context, in struct{}, out *api.GlobalPinQueueInfo) error {
+	res, err := rpcapi.c.PinQueues(ctx)
+	if err != nil {
+		return err
+	}
+	*out = res
+	return nil
+}
+
+// PinQueuesLocal runs Cluster.PinQueuesLocal().
+func (rpcapi *ClusterRPCAPI) PinQueuesLocal(ctx context.Context, in struct{}, out *api.PinQueueInfo) error {
+	res, err := rpcapi.c.PinQueuesLocal(ctx)
+	if err != nil {
+		return err
+	}
+	*out = res
+	return nil
+}
+
+// DedupStats returns the deduplication statistics from all peers.
+func (rpcapi *ClusterRPCAPI) DedupStats(ctx context.Context, in struct{}, out *api.GlobalDedupStats) error {
+	res, err := rpcapi.c.DedupStats(ctx)
+	if err != nil {
+		return err
+	}
+	*out = res
+	return nil
+}
+
+// Capacity runs Cluster.Capacity().
+func (rpcapi *ClusterRPCAPI) Capacity(ctx context.Context, in struct{}, out *api.GlobalCapacity) error {
+	res, err := rpcapi.c.Capacity(ctx)
+	if err != nil {
+		return err
+	}
+	*out = res
+	return nil
+}
+
+// CapacityLocal runs Cluster.CapacityLocal().
+func (rpcapi *ClusterRPCAPI) CapacityLocal(ctx context.Context, in struct{}, out *api.PeerCapacity) error {
+	res, err := rpcapi.c.CapacityLocal(ctx)
+	if err != nil {
+		return err
+	}
+	*out = res
+	return nil
+}
+
+// RecordPinTime records the time an item took to be pinned on this peer
+// for the pin SLOs.
+func (rpcapi *ClusterRPCAPI) RecordPinTime(ctx context.Context, in api.Pin, out *struct{}) error {
+	rpcapi.c.recordPinTime(ctx, in)
+	return nil
+}
+
+// RecordPinStatus records the outcome of a pin or unpin operation on this
+// peer in the cluster event history.
+func (rpcapi *ClusterRPCAPI) RecordPinStatus(ctx context.Context, in api.PinInfo, out *struct{}) error {
+	rpcapi.c.recordPinStatus(ctx, in)
+	return nil
+}
+
+// SLOReport runs Cluster.SLOReport().
+func (rpcapi *ClusterRPCAPI) SLOReport(ctx context.Context, in struct{}, out *api.SLOReport) error {
+	res, err := rpcapi.c.SLOReport(ctx)
+	if err != nil {
+		return err
+	}
+	*out = res
+	return nil
+}
+
+// SLOReportLocal runs Cluster.SLOReportLocal().
+func (rpcapi *ClusterRPCAPI) SLOReportLocal(ctx context.Context, in struct{}, out *api.SLOReport) error {
+	res, err := rpcapi.c.SLOReportLocal(ctx)
+	if err != nil {
+		return err
+	}
+	*out = res
+	return nil
+}
+
+// DedupStatsLocal returns the deduplication statistics of this peer.
+func (rpcapi *ClusterRPCAPI) DedupStatsLocal(ctx context.Context, in struct{}, out *api.DedupStats) error {
+	res, err := rpcapi.c.DedupStatsLocal(ctx)
+	if err != nil {
+		return err
+	}
+	*out = res
+	return nil
+}
+
+// ObservePin runs Cluster.ObservePin().
+func (rpcapi *ClusterRPCAPI) ObservePin(ctx context.Context, in api.ObservedPin, out *struct{}) error {
+	return rpcapi.c.ObservePin(ctx, in)
+}
+
+// ObserveUnpin runs Cluster.ObserveUnpin().
+func (rpcapi *ClusterRPCAPI) ObserveUnpin(ctx context.Context, in api.Cid, out *struct{}) error {
+	return rpcapi.c.ObserveUnpin(ctx, in)
+}
+
+// ObservedPins runs Cluster.ObservedPins().
+func (rpcapi *ClusterRPCAPI) ObservedPins(ctx context.Context, in struct{}, out *[]api.ObservedPin) error {
+	pins, err := rpcapi.c.ObservedPins(ctx)
+	if err != nil {
+		return err
+	}
+	*out = pins
+	return nil
+}
+
+// PinGC runs Cluster.PinGC().
+func (rpcapi *ClusterRPCAPI) PinGC(ctx context.Context, in bool, out *api.PinGCReport) error {
+	res, err := rpcapi.c.PinGC(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = res
+	return nil
+}
+
+// ConsensusEvents runs Cluster.ConsensusEvents().
+func (rpcapi *ClusterRPCAPI) ConsensusEvents(ctx context.Context, in <-chan struct{}, out chan<- api.ConsensusEvent) error {
+	return rpcapi.c.ConsensusEvents(ctx, out)
+}
+
+// Events runs Cluster.Events().
+func (rpcapi *ClusterRPCAPI) Events(ctx context.Context, in <-chan api.ClusterEventQuery, out chan<- api.ClusterEvent) error {
+	q := <-in
+	return rpcapi.c.Events(ctx, q, out)
+}
+
+// EventHistory runs Cluster.EventHistory().
+func (rpcapi *ClusterRPCAPI) EventHistory(ctx context.Context, in <-chan api.ClusterEventQuery, out chan<- api.ClusterEvent) error {
+	q := <-in
+	return rpcapi.c.EventHistory(ctx, q, out)
+}
+
+// Quorum runs Cluster.Quorum().
+func (rpcapi *ClusterRPCAPI) Quorum(ctx context.Context, in struct{}, out *api.QuorumStatus) error {
+	status, err := rpcapi.c.Quorum(ctx)
+	if err != nil {
+		return err
+	}
+	*out = status
+	return nil
+}
+
+// StateChecksum runs Cluster.StateChecksum().
+func (rpcapi *ClusterRPCAPI) StateChecksum(ctx context.Context, in struct{}, out *api.GlobalStateChecksum) error {
+	res, err := rpcapi.c.StateChecksum(ctx)
+	if err != nil {
+		return err
+	}
+	*out = res
+	return nil
+}
+
+// StateChecksumLocal runs Cluster.StateChecksumLocal().
+func (rpcapi *ClusterRPCAPI) StateChecksumLocal(ctx context.Context, in struct{}, out *api.StateChecksum) error {
+	res, err := rpcapi.c.StateChecksumLocal(ctx)
+	if err != nil {
+		return err
+	}
+	*out = res
+	return nil
+}
+
+// RunJob runs Cluster.RunJob().
+func (rpcapi *ClusterRPCAPI) RunJob(ctx context.Context, in api.JobRequest, out *api.GlobalJobResult) error {
+	res, err := rpcapi.c.RunJob(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = res
+	return nil
+}
+
+// RunJobLocal runs Cluster.RunJobLocal().
+func (rpcapi *ClusterRPCAPI) RunJobLocal(ctx context.Context, in string, out *api.JobResult) error {
+	res, err := rpcapi.c.RunJobLocal(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = res
+	return nil
+}
+
+// ArchivePeer runs Cluster.ArchivePeer().
+func (rpcapi *ClusterRPCAPI) ArchivePeer(ctx context.Context, in api.ArchivedPeer, out *struct{}) error {
+	return rpcapi.c.ArchivePeer(ctx, in)
+}
+
+// PeersArchive runs Cluster.PeersArchive().
+func (rpcapi *ClusterRPCAPI) PeersArchive(ctx context.Context, in struct{}, out *[]api.ArchivedPeer) error {
+	*out = rpcapi.c.PeersArchive(ctx)
+	return nil
+}
+
+// APIEndpoints runs Cluster.APIEndpoints().
+func (rpcapi *ClusterRPCAPI) APIEndpoints(ctx context.Context, in struct{}, out *[]api.APIEndpoint) error {
+	endpoints, err := rpcapi.c.APIEndpoints(ctx)
+	if err != nil {
+		return err
+	}
+	*out = endpoints
+	return nil
+}
+
+// InformerMetricsLocal returns fresh metrics from all the informers of this
+// peer.
+func (rpcapi *ClusterRPCAPI) InformerMetricsLocal(ctx context.Context, in struct{}, out *[]api.Metric) error {
+	*out = rpcapi.c.InformerMetricsLocal(ctx)
+	return nil
+}
+
 // SendInformerMetrics runs Cluster.sendInformerMetric().
 func (rpcapi *ClusterRPCAPI) SendInformerMetrics(ctx context.Context, in struct{}, out *struct{}) error {
 	return rpcapi.c.sendInformersMetrics(ctx)
@@ -531,6 +933,16 @@ func (rpcapi *IPFSConnectorRPCAPI) PinLsCid(ctx context.Context, in api.Pin, out
 	return nil
 }
 
+// PinProgress runs IPFSConnector.PinProgress().
+func (rpcapi *IPFSConnectorRPCAPI) PinProgress(ctx context.Context, in api.Cid, out *api.PinProgress) error {
+	p, err := rpcapi.ipfs.PinProgress(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = p
+	return nil
+}
+
 // PinLs runs IPFSConnector.PinLs().
 func (rpcapi *IPFSConnectorRPCAPI) PinLs(ctx context.Context, in <-chan []string, out chan<- api.IPFSPinInfo) error {
 	select {
@@ -592,6 +1004,26 @@ func (rpcapi *IPFSConnectorRPCAPI) BlockGet(ctx context.Context, in api.Cid, out
 	return nil
 }
 
+// DagStat runs IPFSConnector.DagStat().
+func (rpcapi *IPFSConnectorRPCAPI) DagStat(ctx context.Context, in api.Pin, out *api.IPFSDagStat) error {
+	res, err := rpcapi.ipfs.DagStat(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = res
+	return nil
+}
+
+// VerifyBlocks runs IPFSConnector.VerifyBlocks().
+func (rpcapi *IPFSConnectorRPCAPI) VerifyBlocks(ctx context.Context, in api.IPFSBlockVerification, out *api.IPFSBlockVerification) error {
+	res, err := rpcapi.ipfs.VerifyBlocks(ctx, in)
+	if err != nil {
+		return err
+	}
+	*out = res
+	return nil
+}
+
 // Resolve runs IPFSConnector.Resolve().
 func (rpcapi *IPFSConnectorRPCAPI) Resolve(ctx context.Context, in string, out *api.Cid) error {
 	c, err := rpcapi.ipfs.Resolve(ctx, in)
@@ -620,6 +1052,20 @@ func (rpcapi *ConsensusRPCAPI) LogUnpin(ctx context.Context, in api.Pin, out *st
 	return rpcapi.cons.LogUnpin(ctx, in)
 }
 
+// LogSchedule runs Consensus.LogSchedule().
+func (rpcapi *ConsensusRPCAPI) LogSchedule(ctx context.Context, in api.PinSchedule, out *struct{}) error {
+	ctx, span := trace.StartSpan(ctx, "rpc/consensus/LogSchedule")
+	defer span.End()
+	return rpcapi.cons.LogSchedule(ctx, in)
+}
+
+// LogUnschedule runs Consensus.LogUnschedule().
+func (rpcapi *ConsensusRPCAPI) LogUnschedule(ctx context.Context, in string, out *struct{}) error {
+	ctx, span := trace.StartSpan(ctx, "rpc/consensus/LogUnschedule")
+	defer span.End()
+	return rpcapi.cons.LogUnschedule(ctx, in)
+}
+
 // AddPeer runs Consensus.AddPeer().
 func (rpcapi *ConsensusRPCAPI) AddPeer(ctx context.Context, in peer.ID, out *struct{}) error {
 	ctx, span := trace.StartSpan(ctx, "rpc/consensus/AddPeer")
@@ -627,6 +1073,12 @@ func (rpcapi *ConsensusRPCAPI) AddPeer(ctx context.Context, in peer.ID, out *str
 	return rpcapi.cons.AddPeer(ctx, in)
 }
 
+// IsNonVoter runs Consensus.IsNonVoter().
+func (rpcapi *ConsensusRPCAPI) IsNonVoter(ctx context.Context, in struct{}, out *bool) error {
+	*out = rpcapi.cons.IsNonVoter(ctx)
+	return nil
+}
+
 // RmPeer runs Consensus.RmPeer().
 func (rpcapi *ConsensusRPCAPI) RmPeer(ctx context.Context, in peer.ID, out *struct{}) error {
 	ctx, span := trace.StartSpan(ctx, "rpc/consensus/RmPeer")
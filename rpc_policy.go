@@ -7,36 +7,83 @@ package ipfscluster
 // without missing any endpoint.
 var DefaultRPCPolicy = map[string]RPCEndpointType{
 	// Cluster methods
-	"Cluster.Alerts":               RPCClosed,
-	"Cluster.BlockAllocate":        RPCClosed,
-	"Cluster.ConnectGraph":         RPCClosed,
-	"Cluster.ID":                   RPCOpen,
-	"Cluster.IDStream":             RPCOpen,
-	"Cluster.IPFSID":               RPCClosed,
-	"Cluster.Join":                 RPCClosed,
-	"Cluster.PeerAdd":              RPCOpen, // Used by Join()
-	"Cluster.PeerRemove":           RPCTrusted,
-	"Cluster.Peers":                RPCTrusted, // Used by ConnectGraph()
-	"Cluster.PeersWithFilter":      RPCClosed,
-	"Cluster.Pin":                  RPCClosed,
-	"Cluster.PinGet":               RPCClosed,
-	"Cluster.PinPath":              RPCClosed,
-	"Cluster.Pins":                 RPCClosed, // Used in stateless tracker, ipfsproxy, restapi
-	"Cluster.Recover":              RPCClosed,
-	"Cluster.RecoverAll":           RPCClosed,
-	"Cluster.RecoverAllLocal":      RPCTrusted,
-	"Cluster.RecoverLocal":         RPCTrusted,
-	"Cluster.RepoGC":               RPCClosed,
-	"Cluster.RepoGCLocal":          RPCTrusted,
-	"Cluster.SendInformerMetrics":  RPCClosed,
-	"Cluster.SendInformersMetrics": RPCClosed,
-	"Cluster.Status":               RPCClosed,
-	"Cluster.StatusAll":            RPCClosed,
-	"Cluster.StatusAllLocal":       RPCClosed,
-	"Cluster.StatusLocal":          RPCClosed,
-	"Cluster.Unpin":                RPCClosed,
-	"Cluster.UnpinPath":            RPCClosed,
-	"Cluster.Version":              RPCOpen,
+	"Cluster.APIEndpoints":               RPCClosed,
+	"Cluster.Alerts":                     RPCClosed,
+	"Cluster.AllocationExplain":          RPCClosed,
+	"Cluster.ArchivePeer":                RPCTrusted, // Called when removing peers
+	"Cluster.BlockAllocate":              RPCClosed,
+	"Cluster.Capacity":                   RPCClosed,
+	"Cluster.CapacityLocal":              RPCTrusted,
+	"Cluster.CancelHandoff":              RPCClosed,
+	"Cluster.CancelPin":                  RPCClosed,
+	"Cluster.CancelPinSchedule":          RPCClosed,
+	"Cluster.ConnectGraph":               RPCClosed,
+	"Cluster.ConsensusEvents":            RPCClosed,
+	"Cluster.ConsensusLog":               RPCClosed,
+	"Cluster.DeadLetters":                RPCClosed,
+	"Cluster.DedupStats":                 RPCClosed,
+	"Cluster.DedupStatsLocal":            RPCTrusted,
+	"Cluster.DiscardDeadLetter":          RPCClosed,
+	"Cluster.EventHistory":               RPCClosed,
+	"Cluster.Events":                     RPCClosed,
+	"Cluster.Handoff":                    RPCClosed,
+	"Cluster.HandoffStatus":              RPCClosed,
+	"Cluster.Handoffs":                   RPCClosed,
+	"Cluster.ID":                         RPCOpen,
+	"Cluster.IDStream":                   RPCOpen,
+	"Cluster.IPFSID":                     RPCClosed,
+	"Cluster.InformerMetricsLocal":       RPCTrusted, // Called when prefetching metrics for allocations
+	"Cluster.Join":                       RPCClosed,
+	"Cluster.ObservePin":                 RPCClosed,
+	"Cluster.ObserveUnpin":               RPCClosed,
+	"Cluster.ObservedPins":               RPCClosed,
+	"Cluster.PeerAdd":                    RPCOpen, // Used by Join()
+	"Cluster.PeerRemove":                 RPCTrusted,
+	"Cluster.Peers":                      RPCTrusted, // Used by ConnectGraph()
+	"Cluster.PeersArchive":               RPCClosed,
+	"Cluster.PeersWithFilter":            RPCClosed,
+	"Cluster.Pin":                        RPCClosed,
+	"Cluster.PinGC":                      RPCClosed,
+	"Cluster.PinGet":                     RPCClosed,
+	"Cluster.PinHistory":                 RPCClosed,
+	"Cluster.PinHistoryLocal":            RPCTrusted,
+	"Cluster.PinPath":                    RPCClosed,
+	"Cluster.PinQueues":                  RPCClosed,
+	"Cluster.PinQueuesLocal":             RPCTrusted,
+	"Cluster.PinSchedules":               RPCClosed,
+	"Cluster.Pins":                       RPCClosed, // Used in stateless tracker, ipfsproxy, restapi
+	"Cluster.PinsWithOptions":            RPCClosed,
+	"Cluster.Quorum":                     RPCClosed,
+	"Cluster.Recover":                    RPCClosed,
+	"Cluster.RecoverAll":                 RPCClosed,
+	"Cluster.RecoverAllLocal":            RPCTrusted,
+	"Cluster.RecoverAllLocalWithOptions": RPCTrusted,
+	"Cluster.RecoverAllWithOptions":      RPCClosed,
+	"Cluster.RecoverLocal":               RPCTrusted,
+	"Cluster.RecordPinStatus":            RPCClosed, // Called by the pin tracker
+	"Cluster.RecordPinTime":              RPCClosed, // Called by the pin tracker
+	"Cluster.RepoGC":                     RPCClosed,
+	"Cluster.RepoGCLocal":                RPCTrusted,
+	"Cluster.Restore":                    RPCClosed,
+	"Cluster.RetryDeadLetter":            RPCClosed,
+	"Cluster.Rollback":                   RPCClosed,
+	"Cluster.RunJob":                     RPCClosed,
+	"Cluster.RunJobLocal":                RPCTrusted,
+	"Cluster.SLOReport":                  RPCClosed,
+	"Cluster.SLOReportLocal":             RPCTrusted,
+	"Cluster.SchedulePin":                RPCClosed,
+	"Cluster.SendInformerMetrics":        RPCClosed,
+	"Cluster.SendInformersMetrics":       RPCClosed,
+	"Cluster.StateChecksum":              RPCClosed,
+	"Cluster.StateChecksumLocal":         RPCTrusted,
+	"Cluster.Status":                     RPCClosed,
+	"Cluster.StatusAll":                  RPCClosed,
+	"Cluster.StatusAllLocal":             RPCClosed,
+	"Cluster.StatusAllWithOptions":       RPCClosed,
+	"Cluster.StatusLocal":                RPCClosed,
+	"Cluster.Unpin":                      RPCClosed,
+	"Cluster.UnpinPath":                  RPCClosed,
+	"Cluster.Version":                    RPCOpen,
 
 	// PinTracker methods
 	"PinTracker.PinQueueSize": RPCClosed,
@@ -48,23 +95,29 @@ var DefaultRPCPolicy = map[string]RPCEndpointType{
 	"PinTracker.Untrack":      RPCClosed,
 
 	// IPFSConnector methods
-	"IPFSConnector.BlockGet":    RPCClosed,
-	"IPFSConnector.BlockStream": RPCTrusted, // Called by adders
-	"IPFSConnector.ConfigKey":   RPCClosed,
-	"IPFSConnector.Pin":         RPCClosed,
-	"IPFSConnector.PinLs":       RPCClosed,
-	"IPFSConnector.PinLsCid":    RPCClosed,
-	"IPFSConnector.RepoStat":    RPCTrusted, // Called in broadcast from proxy/repo/stat
-	"IPFSConnector.Resolve":     RPCClosed,
-	"IPFSConnector.SwarmPeers":  RPCTrusted, // Called in ConnectGraph
-	"IPFSConnector.Unpin":       RPCClosed,
+	"IPFSConnector.BlockGet":     RPCClosed,
+	"IPFSConnector.BlockStream":  RPCTrusted, // Called by adders
+	"IPFSConnector.ConfigKey":    RPCClosed,
+	"IPFSConnector.DagStat":      RPCTrusted, // Called by the pin size preflight
+	"IPFSConnector.Pin":          RPCClosed,
+	"IPFSConnector.PinLs":        RPCClosed,
+	"IPFSConnector.PinLsCid":     RPCClosed,
+	"IPFSConnector.PinProgress":  RPCClosed,  // Called by the pin tracker
+	"IPFSConnector.RepoStat":     RPCTrusted, // Called in broadcast from proxy/repo/stat
+	"IPFSConnector.Resolve":      RPCClosed,
+	"IPFSConnector.SwarmPeers":   RPCTrusted, // Called in ConnectGraph
+	"IPFSConnector.Unpin":        RPCClosed,
+	"IPFSConnector.VerifyBlocks": RPCClosed, // Called by the pin tracker
 
 	// Consensus methods
-	"Consensus.AddPeer":  RPCTrusted, // Called by Raft/redirect to leader
-	"Consensus.LogPin":   RPCTrusted, // Called by Raft/redirect to leader
-	"Consensus.LogUnpin": RPCTrusted, // Called by Raft/redirect to leader
-	"Consensus.Peers":    RPCClosed,
-	"Consensus.RmPeer":   RPCTrusted, // Called by Raft/redirect to leader
+	"Consensus.AddPeer":       RPCTrusted, // Called by Raft/redirect to leader
+	"Consensus.IsNonVoter":    RPCTrusted, // Called by the Raft leader when adding peers
+	"Consensus.LogPin":        RPCTrusted, // Called by Raft/redirect to leader
+	"Consensus.LogSchedule":   RPCTrusted, // Called by Raft/redirect to leader
+	"Consensus.LogUnpin":      RPCTrusted, // Called by Raft/redirect to leader
+	"Consensus.LogUnschedule": RPCTrusted, // Called by Raft/redirect to leader
+	"Consensus.Peers":         RPCClosed,
+	"Consensus.RmPeer":        RPCTrusted, // Called by Raft/redirect to leader
 
 	// PeerMonitor methods
 	"PeerMonitor.LatestMetrics": RPCClosed,
@@ -0,0 +1,62 @@
+package ipfscluster
+
+import (
+	"sync"
+
+	"github.com/ipfs-cluster/ipfs-cluster/observations"
+	"github.com/ipfs-cluster/ipfs-cluster/version"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+	protocol "github.com/libp2p/go-libp2p/core/protocol"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+)
+
+// rpcShim returns, by service name, the RPC services which answer the
+// requests received on a legacy protocol version instead of the default
+// ones. Shims usually embed the default service and override the methods
+// whose arguments or responses have changed since that version, converting
+// them from and to the old types. Peers on the legacy version then get
+// responses they can decode instead of errors.
+type rpcShim func(c *Cluster) map[string]interface{}
+
+// rpcShims contains the shims for the protocols in
+// version.LegacyRPCProtocols. Legacy protocols without shims are answered
+// by the default services.
+var rpcShims = map[protocol.ID]rpcShim{}
+
+// setupLegacyRPC starts answering RPC requests on the legacy protocol
+// versions.
+func (c *Cluster) setupLegacyRPC() error {
+	for _, p := range version.LegacyRPCProtocols {
+		if p == version.RPCProtocol {
+			continue
+		}
+		_, err := newRPCServer(c, p, rpcShims[p])
+		if err != nil {
+			return err
+		}
+		logger.Infof("answering RPC requests from peers using %s", p)
+	}
+	return nil
+}
+
+// legacyAuthorizeFunc wraps the authorization function of the RPC server
+// for a legacy protocol to record the requests received on it, so that
+// operators know which peers still need to be upgraded.
+func legacyAuthorizeFunc(c *Cluster, p protocol.ID, authF func(peer.ID, string, string) bool) func(peer.ID, string, string) bool {
+	var warned sync.Map
+	return func(pid peer.ID, svc, method string) bool {
+		if _, loaded := warned.LoadOrStore(pid, struct{}{}); !loaded {
+			logger.Warnf("peer %s is using an older RPC protocol (%s). It should be upgraded", pid, p)
+		}
+		logger.Debugf("legacy RPC request from %s: %s.%s (%s)", pid, svc, method, p)
+		stats.RecordWithTags(
+			c.ctx,
+			[]tag.Mutator{tag.Upsert(observations.ProtocolKey, string(p))},
+			observations.RPCLegacyRequests.M(1),
+		)
+		return authF(pid, svc, method)
+	}
+}
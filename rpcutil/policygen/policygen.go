@@ -24,20 +24,29 @@ func rpcTypeStr(t cluster.RPCEndpointType) string {
 }
 
 var comments = map[string]string{
-	"Cluster.PeerAdd":           "Used by Join()",
-	"Cluster.Peers":             "Used by ConnectGraph()",
-	"Cluster.Pins":              "Used in stateless tracker, ipfsproxy, restapi",
-	"PinTracker.Recover":        "Called in broadcast from Recover()",
-	"PinTracker.RecoverAll":     "Broadcast in RecoverAll unimplemented",
-	"Pintracker.Status":         "Called in broadcast from Status()",
-	"Pintracker.StatusAll":      "Called in broadcast from StatusAll()",
-	"IPFSConnector.BlockStream": "Called by adders",
-	"IPFSConnector.RepoStat":    "Called in broadcast from proxy/repo/stat",
-	"IPFSConnector.SwarmPeers":  "Called in ConnectGraph",
-	"Consensus.AddPeer":         "Called by Raft/redirect to leader",
-	"Consensus.LogPin":          "Called by Raft/redirect to leader",
-	"Consensus.LogUnpin":        "Called by Raft/redirect to leader",
-	"Consensus.RmPeer":          "Called by Raft/redirect to leader",
+	"Cluster.PeerAdd":              "Used by Join()",
+	"Cluster.Peers":                "Used by ConnectGraph()",
+	"Cluster.Pins":                 "Used in stateless tracker, ipfsproxy, restapi",
+	"Cluster.InformerMetricsLocal": "Called when prefetching metrics for allocations",
+	"Cluster.ArchivePeer":          "Called when removing peers",
+	"Cluster.RecordPinStatus":      "Called by the pin tracker",
+	"Cluster.RecordPinTime":        "Called by the pin tracker",
+	"Cluster.RunJobLocal":          "Called in broadcast from RunJob()",
+	"PinTracker.Recover":           "Called in broadcast from Recover()",
+	"PinTracker.RecoverAll":        "Broadcast in RecoverAll unimplemented",
+	"Pintracker.Status":            "Called in broadcast from Status()",
+	"Pintracker.StatusAll":         "Called in broadcast from StatusAll()",
+	"IPFSConnector.BlockStream":    "Called by adders",
+	"IPFSConnector.DagStat":        "Called by the pin size preflight",
+	"IPFSConnector.RepoStat":       "Called in broadcast from proxy/repo/stat",
+	"IPFSConnector.SwarmPeers":     "Called in ConnectGraph",
+	"Consensus.AddPeer":            "Called by Raft/redirect to leader",
+	"Consensus.IsNonVoter":         "Called by the Raft leader when adding peers",
+	"Consensus.LogPin":             "Called by Raft/redirect to leader",
+	"Consensus.LogSchedule":        "Called by Raft/redirect to leader",
+	"Consensus.LogUnpin":           "Called by Raft/redirect to leader",
+	"Consensus.LogUnschedule":      "Called by Raft/redirect to leader",
+	"Consensus.RmPeer":             "Called by Raft/redirect to leader",
 }
 
 func main() {
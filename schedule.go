@@ -0,0 +1,414 @@
+package ipfscluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/state"
+
+	"github.com/google/uuid"
+	gopath "github.com/ipfs/boxo/path"
+	trace "go.opencensus.io/trace"
+)
+
+// pinScheduleSyncInterval is how often the pin schedules in the shared
+// state are checked. Those running before the next check are armed.
+var pinScheduleSyncInterval = time.Minute
+
+// pinScheduler keeps the timers to run the pin schedules. The schedules
+// themselves are part of the shared state, so every peer knows about them,
+// but only one of them runs each.
+type pinScheduler struct {
+	mux    sync.Mutex
+	timers map[string]*time.Timer
+	closed bool
+}
+
+func newPinScheduler() *pinScheduler {
+	return &pinScheduler{
+		timers: make(map[string]*time.Timer),
+	}
+}
+
+// arm calls f at the given time, replacing anything armed for the same
+// schedule.
+func (ps *pinScheduler) arm(id string, at time.Time, f func()) {
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+
+	if ps.closed {
+		return
+	}
+	if t, ok := ps.timers[id]; ok {
+		t.Stop()
+	}
+
+	var t *time.Timer
+	t = time.AfterFunc(time.Until(at), func() {
+		ps.mux.Lock()
+		if ps.timers[id] != t {
+			// disarmed or re-armed in the meantime.
+			ps.mux.Unlock()
+			return
+		}
+		delete(ps.timers, id)
+		ps.mux.Unlock()
+		f()
+	})
+	ps.timers[id] = t
+}
+
+func (ps *pinScheduler) disarm(id string) {
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+	if t, ok := ps.timers[id]; ok {
+		t.Stop()
+		delete(ps.timers, id)
+	}
+}
+
+// stop cancels all the timers. Nothing can be armed afterwards.
+func (ps *pinScheduler) stop() {
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+
+	ps.closed = true
+	for id, t := range ps.timers {
+		t.Stop()
+		delete(ps.timers, id)
+	}
+}
+
+// SchedulePin registers a pin to be committed at a later time (NotBefore),
+// or repeatedly following a cron expression. The schedule is committed to
+// the shared state and run by the leader or, when the consensus has no
+// leader, by the trusted peer closest to it.
+func (c *Cluster) SchedulePin(ctx context.Context, s api.PinSchedule) (api.PinSchedule, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/SchedulePin")
+	defer span.End()
+
+	if c.config.FollowerMode {
+		return api.PinSchedule{}, errFollowerMode
+	}
+	if _, err := gopath.ParsePath(s.Path); err != nil {
+		return api.PinSchedule{}, err
+	}
+	if s.NotBefore.IsZero() && s.Cron == "" {
+		return api.PinSchedule{}, errors.New("a not-before time or a cron expression must be given")
+	}
+
+	next := s.NotBefore
+	if now := time.Now(); next.Before(now) {
+		next = now
+	}
+	if s.Cron != "" {
+		cron, err := parseCron(s.Cron)
+		if err != nil {
+			return api.PinSchedule{}, err
+		}
+		// the minute of NotBefore counts as an occurrence.
+		next = cron.next(next.Add(-time.Minute))
+		if next.IsZero() {
+			return api.PinSchedule{}, fmt.Errorf("the cron expression %q never matches", s.Cron)
+		}
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return api.PinSchedule{}, err
+	}
+	s.ID = id.String()
+	s.Peer = c.id
+	s.Created = time.Now()
+	s.Next = next
+	s.Options.Requester = c.requester(ctx)
+	s.LastRun = time.Time{}
+	s.LastCid = api.CidUndef
+	s.LastError = ""
+
+	err = c.consensus.LogSchedule(ctx, s)
+	if err != nil {
+		return api.PinSchedule{}, err
+	}
+	c.armPinSchedule(s)
+	logger.Infof("scheduled pin of %s (%s): next run at %s", s.Path, s.ID, s.Next)
+	return s, nil
+}
+
+// PinSchedules returns the pin schedules in the shared state, sorted by the
+// time when they run next.
+func (c *Cluster) PinSchedules(ctx context.Context) ([]api.PinSchedule, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/PinSchedules")
+	defer span.End()
+
+	cState, err := c.consensus.State(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cState.ListSchedules(ctx)
+}
+
+// CancelPinSchedule removes a pin schedule from the shared state. Pins
+// already committed by it are not affected.
+func (c *Cluster) CancelPinSchedule(ctx context.Context, id string) error {
+	ctx, span := trace.StartSpan(ctx, "cluster/CancelPinSchedule")
+	defer span.End()
+
+	if c.config.FollowerMode {
+		return errFollowerMode
+	}
+	// Schedule IDs are UUIDs. Anything else would not be a valid key.
+	if _, err := uuid.Parse(id); err != nil {
+		return state.ErrScheduleNotFound
+	}
+
+	cState, err := c.consensus.State(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := cState.GetSchedule(ctx, id); err != nil {
+		return err
+	}
+	err = c.consensus.LogUnschedule(ctx, id)
+	if err != nil {
+		return err
+	}
+	c.schedules.disarm(id)
+	logger.Infof("canceled pin schedule %s", id)
+	return nil
+}
+
+// watchPinSchedules arms the pin schedules in the shared state which run
+// before the next check, so that schedules created or updated by other
+// peers are run too.
+func (c *Cluster) watchPinSchedules() {
+	if c.config.FollowerMode {
+		return
+	}
+
+	ticker := time.NewTicker(pinScheduleSyncInterval)
+	defer ticker.Stop()
+	for {
+		c.armPinSchedules(c.ctx)
+
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Cluster) armPinSchedules(ctx context.Context) {
+	schedules, err := c.PinSchedules(ctx)
+	if err != nil {
+		logger.Errorf("error loading pin schedules: %s", err)
+		return
+	}
+	limit := time.Now().Add(pinScheduleSyncInterval)
+	for _, s := range schedules {
+		if s.Next.Before(limit) {
+			c.armPinSchedule(s)
+		}
+	}
+}
+
+func (c *Cluster) armPinSchedule(s api.PinSchedule) {
+	c.schedules.arm(s.ID, s.Next, func() {
+		c.runPinSchedule(c.ctx, s.ID)
+	})
+}
+
+// runsPinSchedule returns whether this peer commits the pins of the given
+// schedule: the leader does and, when there is no leader (i.e. in CRDT
+// clusters), the trusted peer closest to the schedule.
+func (c *Cluster) runsPinSchedule(ctx context.Context, s api.PinSchedule) bool {
+	leader, err := c.consensus.Leader(ctx)
+	if err == nil {
+		return leader == c.id
+	}
+	distance, err := c.distances(ctx, "")
+	if err != nil {
+		return false
+	}
+	return distance.isClosestToKey(s.ID)
+}
+
+// runPinSchedule commits the pin of a due schedule if this peer runs it, and
+// updates the schedule in the shared state with the results and the next
+// run. Schedules which run once are removed afterwards.
+func (c *Cluster) runPinSchedule(ctx context.Context, id string) {
+	ctx, span := trace.StartSpan(ctx, "cluster/runPinSchedule")
+	defer span.End()
+
+	cState, err := c.consensus.State(ctx)
+	if err != nil {
+		logger.Errorf("error reading pin schedule %s: %s", id, err)
+		return
+	}
+	s, err := cState.GetSchedule(ctx, id)
+	if err != nil {
+		if err != state.ErrScheduleNotFound {
+			logger.Errorf("error reading pin schedule %s: %s", id, err)
+		}
+		return
+	}
+	if s.Next.After(time.Now()) {
+		// updated in the meantime.
+		c.armPinSchedule(s)
+		return
+	}
+	if !c.runsPinSchedule(ctx, s) {
+		return
+	}
+
+	logger.Infof("pin schedule %s: pinning %s", s.ID, s.Path)
+	pin, err := c.PinPath(withRequester(ctx, s.Options.Requester), s.Path, s.Options)
+	s.LastRun = time.Now()
+	s.LastCid = pin.Cid
+	s.LastError = ""
+	if err != nil {
+		logger.Errorf("pin schedule %s: error pinning %s: %s", s.ID, s.Path, err)
+		s.LastError = err.Error()
+	}
+
+	if s.Cron == "" {
+		err = c.consensus.LogUnschedule(ctx, s.ID)
+		if err != nil {
+			logger.Errorf("error removing pin schedule %s: %s", s.ID, err)
+		}
+		return
+	}
+
+	cron, err := parseCron(s.Cron)
+	if err != nil {
+		logger.Errorf("pin schedule %s: %s", s.ID, err)
+		return
+	}
+	s.Next = cron.next(time.Now())
+	err = c.consensus.LogSchedule(ctx, s)
+	if err != nil {
+		logger.Errorf("error updating pin schedule %s: %s", s.ID, err)
+		return
+	}
+	c.armPinSchedule(s)
+}
+
+// cronSchedule is a parsed cron expression with the five standard fields:
+// minute, hour, day of month, month and day of week (0 is Sunday). Every
+// field is "*", a number, a range ("1-5") or a step ("*/15", "0-30/10"), or
+// a comma-separated list of them.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// when both days of month and week are restricted, either of them
+	// matching is enough, as in cron.
+	domAny, dowAny bool
+}
+
+var cronFieldRanges = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+
+func parseCron(expr string) (cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return cronSchedule{}, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+
+	var bits [5]uint64
+	for i, f := range fields {
+		b, err := parseCronField(f, cronFieldRanges[i][0], cronFieldRanges[i][1])
+		if err != nil {
+			return cronSchedule{}, fmt.Errorf("cron expression %q: %w", expr, err)
+		}
+		bits[i] = b
+	}
+	return cronSchedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rng, step = part[:i], s
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			var err error
+			lo, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %q", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				hi, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return 0, fmt.Errorf("invalid value in %q", part)
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (cs cronSchedule) matchesDay(t time.Time) bool {
+	dom := cs.dom&(1<<uint(t.Day())) != 0
+	dow := cs.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case cs.domAny:
+		return dow
+	case cs.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// next returns the first time matching the schedule after t, or the zero
+// time if there is none in the next five years.
+func (cs cronSchedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case cs.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !cs.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case cs.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case cs.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -31,6 +31,14 @@ test_expect_success IPFS,CLUSTER,JQ "state export saves the correct state to exp
     jq -r ".cid" export.json | grep -q "$cid"
 '
 
+test_expect_success IPFS,CLUSTER,JQ "state export and import in car format (raft)" '
+    ipfs-cluster-service --debug --config "test-config" state export --format car -f export.car &&
+    [ -s export.car ] &&
+    ipfs-cluster-service --debug --config "test-config" state import -f --format car export.car &&
+    ipfs-cluster-service --debug --config "test-config" state export -f export2.json &&
+    diff export.json export2.json
+'
+
 test_clean_ipfs
 test_clean_cluster
 
@@ -0,0 +1,72 @@
+package ipfscluster
+
+import (
+	"context"
+	"time"
+)
+
+// names of the components which can be given a shutdown timeout with
+// Config.ComponentShutdownTimeouts, in the order they are stopped.
+var shutdownComponents = []string{
+	"api",
+	"consensus",
+	"monitor",
+	"ipfs_connector",
+	"pin_tracker",
+	"informer",
+	"tracer",
+}
+
+func isShutdownComponent(name string) bool {
+	for _, n := range shutdownComponents {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// shutdownComponent runs the given shutdown function with a context bounded
+// by the component's shutdown timeout and by the overall shutdown deadline
+// in ctx. When the component does not finish in time, it is logged and
+// abandoned, unless mustFinish is set, in which case we keep waiting for it
+// regardless (Consensus must persist its state before the host and the
+// datastore are closed).
+func (c *Cluster) shutdownComponent(ctx context.Context, name string, mustFinish bool, shutdown func(context.Context) error) error {
+	compCtx := ctx
+	timeout := c.config.ComponentShutdownTimeouts[name]
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		compCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- shutdown(compCtx)
+	}()
+
+	select {
+	case err := <-done:
+		logger.Debugf("%s stopped in %s", name, time.Since(start))
+		return err
+	case <-compCtx.Done():
+	}
+
+	if ctx.Err() != nil {
+		logger.Errorf("%s: shutdown_timeout (%s) exceeded while stopping this component", name, c.config.ShutdownTimeout)
+	} else {
+		logger.Errorf("%s: component shutdown timeout (%s) exceeded", name, timeout)
+	}
+
+	if !mustFinish {
+		logger.Errorf("%s: not waiting any longer for this component to stop", name)
+		return nil
+	}
+
+	logger.Warnf("%s: waiting for this component to persist its state before continuing", name)
+	err := <-done
+	logger.Warnf("%s stopped after %s", name, time.Since(start))
+	return err
+}
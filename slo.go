@@ -0,0 +1,213 @@
+package ipfscluster
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+	"github.com/ipfs-cluster/ipfs-cluster/observations"
+
+	rpc "github.com/libp2p/go-libp2p-gorpc"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	trace "go.opencensus.io/trace"
+)
+
+// sloDefaultClass is the pin_slos entry used for pins whose storage class
+// has no entry of its own.
+const sloDefaultClass = "*"
+
+// The SLO window is split in this many buckets, which are dropped as they
+// leave the window.
+const sloBuckets = 60
+
+// sloBucket counts the pins pinned, and pinned on time, during a slice of
+// the SLO window.
+type sloBucket struct {
+	start  time.Time
+	pinned int
+	onTime int
+}
+
+// sloStats keeps the pin times measured by this peer for each SLO class.
+type sloStats struct {
+	mux     sync.Mutex
+	buckets map[string][]sloBucket
+}
+
+// add counts a pin in the bucket for now and drops the buckets that have
+// left the window.
+func (ss *sloStats) add(class string, onTime bool, now time.Time, window time.Duration) {
+	ss.mux.Lock()
+	defer ss.mux.Unlock()
+	if ss.buckets == nil {
+		ss.buckets = make(map[string][]sloBucket)
+	}
+
+	start := now.Truncate(window / sloBuckets)
+	bs := ss.buckets[class]
+	if len(bs) == 0 || bs[len(bs)-1].start.Before(start) {
+		bs = append(bs, sloBucket{start: start})
+	}
+	last := &bs[len(bs)-1]
+	last.pinned++
+	if onTime {
+		last.onTime++
+	}
+	ss.buckets[class] = pruneSLOBuckets(bs, now, window)
+}
+
+// count returns the pins pinned, and pinned on time, during the window.
+func (ss *sloStats) count(class string, now time.Time, window time.Duration) (pinned, onTime int) {
+	ss.mux.Lock()
+	defer ss.mux.Unlock()
+	bs := pruneSLOBuckets(ss.buckets[class], now, window)
+	ss.buckets[class] = bs
+	for _, b := range bs {
+		pinned += b.pinned
+		onTime += b.onTime
+	}
+	return pinned, onTime
+}
+
+func pruneSLOBuckets(bs []sloBucket, now time.Time, window time.Duration) []sloBucket {
+	i := 0
+	for i < len(bs) && !bs[i].start.After(now.Add(-window)) {
+		i++
+	}
+	return bs[i:]
+}
+
+// sloClass returns the pin_slos entry that applies to a storage class.
+func (c *Cluster) sloClass(storageClass string) (string, api.SLOClassReport, bool) {
+	class := storageClass
+	slo, ok := c.config.PinSLOs[class]
+	if !ok {
+		class = sloDefaultClass
+		slo, ok = c.config.PinSLOs[class]
+	}
+	return class, api.SLOClassReport{
+		Class:     class,
+		Target:    slo.Target,
+		Objective: slo.Objective,
+	}, ok
+}
+
+// recordPinTime is called by the pin tracker when an item has been pinned
+// on this peer. The time since the pin was submitted counts towards the
+// SLO of its storage class. Pins submitted before the start of the window
+// (re-pinned or recovered items) are not counted.
+func (c *Cluster) recordPinTime(ctx context.Context, pin api.Pin) {
+	class, rep, ok := c.sloClass(pin.StorageClass)
+	if !ok || pin.Timestamp.IsZero() {
+		return
+	}
+	now := time.Now()
+	took := now.Sub(pin.Timestamp)
+	if took > c.config.PinSLOWindow {
+		return
+	}
+
+	c.slo.add(class, took <= rep.Target, now, c.config.PinSLOWindow)
+	pinned, onTime := c.slo.count(class, now, c.config.PinSLOWindow)
+	stats.RecordWithTags(
+		ctx,
+		[]tag.Mutator{tag.Upsert(observations.ClassKey, class)},
+		observations.PinsTimeToPinned.M(float64(took.Milliseconds())),
+		observations.PinsSLOCompliance.M(float64(onTime)/float64(pinned)),
+	)
+}
+
+// SLOReportLocal returns the compliance with the pin SLOs of the pins
+// pinned by this peer during the SLO window.
+func (c *Cluster) SLOReportLocal(ctx context.Context) (api.SLOReport, error) {
+	_, span := trace.StartSpan(ctx, "cluster/SLOReportLocal")
+	defer span.End()
+
+	now := time.Now()
+	report := api.SLOReport{
+		Timestamp: now,
+		Window:    c.config.PinSLOWindow,
+		Classes:   []api.SLOClassReport{},
+	}
+	for class := range c.config.PinSLOs {
+		_, rep, _ := c.sloClass(class)
+		rep.Pinned, rep.OnTime = c.slo.count(class, now, c.config.PinSLOWindow)
+		setSLOCompliance(&rep)
+		report.Classes = append(report.Classes, rep)
+	}
+	sort.Slice(report.Classes, func(i, j int) bool {
+		return report.Classes[i].Class < report.Classes[j].Class
+	})
+	return report, nil
+}
+
+// SLOReport aggregates the SLO reports of all the cluster peers.
+func (c *Cluster) SLOReport(ctx context.Context) (api.SLOReport, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/SLOReport")
+	defer span.End()
+
+	members, err := c.consensus.Peers(ctx)
+	if err != nil {
+		logger.Error(err)
+		return api.SLOReport{}, err
+	}
+
+	report, err := c.SLOReportLocal(ctx)
+	if err != nil {
+		return report, err
+	}
+	for i := range report.Classes {
+		report.Classes[i].Pinned = 0
+		report.Classes[i].OnTime = 0
+	}
+
+	for _, member := range members {
+		var local api.SLOReport
+		err = c.rpcClient.CallContext(
+			ctx,
+			member,
+			"Cluster",
+			"SLOReportLocal",
+			struct{}{},
+			&local,
+		)
+		if err != nil {
+			if rpc.IsAuthorizationError(err) {
+				logger.Debug("rpc auth error:", err)
+				continue
+			}
+			if report.PeerErrors == nil {
+				report.PeerErrors = make(map[string]string)
+			}
+			report.PeerErrors[member.String()] = err.Error()
+			continue
+		}
+		for _, lrep := range local.Classes {
+			for i := range report.Classes {
+				if report.Classes[i].Class == lrep.Class {
+					report.Classes[i].Pinned += lrep.Pinned
+					report.Classes[i].OnTime += lrep.OnTime
+				}
+			}
+		}
+	}
+
+	for i := range report.Classes {
+		setSLOCompliance(&report.Classes[i])
+	}
+	return report, nil
+}
+
+// setSLOCompliance sets the compliance and whether the objective is met.
+// A class without pins in the window meets it.
+func setSLOCompliance(rep *api.SLOClassReport) {
+	if rep.Pinned == 0 {
+		rep.Compliance = 1
+	} else {
+		rep.Compliance = float64(rep.OnTime) / float64(rep.Pinned)
+	}
+	rep.Met = rep.Compliance >= rep.Objective
+}
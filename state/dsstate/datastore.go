@@ -4,8 +4,11 @@ package dsstate
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
 	"sync/atomic"
 
 	"github.com/ipfs-cluster/ipfs-cluster/api"
@@ -27,6 +30,10 @@ var _ state.BatchingState = (*BatchingState)(nil)
 
 var logger = logging.Logger("dsstate")
 
+// SchedulesNamespace is the namespace, relative to the one of the state,
+// under which pin schedules are stored.
+const SchedulesNamespace = "/schedules"
+
 // State implements the IPFS Cluster "state" interface by wrapping
 // a go-datastore and choosing how api.Pin objects are stored
 // in it. It also provides serialization methods for the whole
@@ -179,6 +186,9 @@ func (st *State) List(ctx context.Context, out chan<- api.Pin) error {
 			return err
 		}
 		k := ds.NewKey(r.Key)
+		if st.schedulesNamespace().IsAncestorOf(k) {
+			continue
+		}
 		ci, err := st.unkey(k)
 		if err != nil {
 			logger.Warn("bad key (ignoring). key: ", k, "error: ", err)
@@ -205,6 +215,82 @@ func (st *State) List(ctx context.Context, out chan<- api.Pin) error {
 	return nil
 }
 
+// AddSchedule adds a new pin schedule or replaces an existing one.
+func (st *State) AddSchedule(ctx context.Context, s api.PinSchedule) error {
+	_, span := trace.StartSpan(ctx, "state/dsstate/AddSchedule")
+	defer span.End()
+
+	v, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return st.dsWrite.Put(ctx, st.scheduleKey(s.ID), v)
+}
+
+// RmSchedule removes a pin schedule. It does not error if it does not
+// exist.
+func (st *State) RmSchedule(ctx context.Context, id string) error {
+	_, span := trace.StartSpan(ctx, "state/dsstate/RmSchedule")
+	defer span.End()
+
+	err := st.dsWrite.Delete(ctx, st.scheduleKey(id))
+	if err == ds.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// GetSchedule returns the pin schedule with the given ID, or
+// state.ErrScheduleNotFound.
+func (st *State) GetSchedule(ctx context.Context, id string) (api.PinSchedule, error) {
+	_, span := trace.StartSpan(ctx, "state/dsstate/GetSchedule")
+	defer span.End()
+
+	v, err := st.dsRead.Get(ctx, st.scheduleKey(id))
+	if err == ds.ErrNotFound {
+		return api.PinSchedule{}, state.ErrScheduleNotFound
+	}
+	if err != nil {
+		return api.PinSchedule{}, err
+	}
+	var s api.PinSchedule
+	err = json.Unmarshal(v, &s)
+	return s, err
+}
+
+// ListSchedules returns all the pin schedules, sorted by the time when they
+// run next.
+func (st *State) ListSchedules(ctx context.Context) ([]api.PinSchedule, error) {
+	_, span := trace.StartSpan(ctx, "state/dsstate/ListSchedules")
+	defer span.End()
+
+	results, err := st.dsRead.Query(ctx, query.Query{
+		Prefix: st.schedulesNamespace().String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer results.Close()
+
+	var schedules []api.PinSchedule
+	for r := range results.Next() {
+		if r.Error != nil {
+			return nil, fmt.Errorf("error in query result: %w", r.Error)
+		}
+		var s api.PinSchedule
+		err := json.Unmarshal(r.Value, &s)
+		if err != nil {
+			logger.Errorf("error deserializing pin schedule (%s): %s", r.Key, err)
+			continue
+		}
+		schedules = append(schedules, s)
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].Next.Before(schedules[j].Next)
+	})
+	return schedules, nil
+}
+
 // Migrate migrates an older state version to the current one.
 // This is a no-op for now.
 func (st *State) Migrate(ctx context.Context, r io.Reader) error {
@@ -217,8 +303,8 @@ type serialEntry struct {
 }
 
 // Marshal dumps the state to a writer. It does this by encoding every
-// key/value in the store. The keys are stored without the namespace part to
-// reduce the size of the snapshot.
+// key/value in the store, pin schedules included. The keys are stored
+// without the namespace part to reduce the size of the snapshot.
 func (st *State) Marshal(w io.Writer) error {
 	q := query.Query{
 		Prefix: st.namespace.String(),
@@ -238,10 +324,11 @@ func (st *State) Marshal(w io.Writer) error {
 			return r.Error
 		}
 
-		k := ds.NewKey(r.Key)
-		// reduce snapshot size by not storing the prefix
+		// reduce snapshot size by not storing the prefix. Pins are
+		// stored right under it, so this is their base name.
+		k := strings.TrimPrefix(r.Key, st.namespace.String())
 		err := enc.Encode(serialEntry{
-			Key:   k.BaseNamespace(),
+			Key:   strings.TrimPrefix(k, "/"),
 			Value: r.Value,
 		})
 		if err != nil {
@@ -302,6 +389,15 @@ func (st *State) unkey(k ds.Key) (api.Cid, error) {
 	return dsKeyToCid(ds.NewKey(k.BaseNamespace()))
 }
 
+func (st *State) schedulesNamespace() ds.Key {
+	return st.namespace.Child(ds.NewKey(SchedulesNamespace))
+}
+
+// convert a schedule ID to /namespace/schedules/id
+func (st *State) scheduleKey(id string) ds.Key {
+	return st.schedulesNamespace().Child(ds.NewKey(id))
+}
+
 // this decides how a Pin object is serialized to be stored in the
 // datastore. Changing this may require a migration!
 func (st *State) serializePin(c api.Pin) ([]byte, error) {
@@ -8,6 +8,7 @@ import (
 
 	"github.com/ipfs-cluster/ipfs-cluster/api"
 	"github.com/ipfs-cluster/ipfs-cluster/datastore/inmem"
+	"github.com/ipfs-cluster/ipfs-cluster/state"
 
 	peer "github.com/libp2p/go-libp2p/core/peer"
 )
@@ -151,3 +152,77 @@ func TestMarshalUnmarshal(t *testing.T) {
 		t.Error("expected different cid")
 	}
 }
+
+func TestSchedules(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.New()
+	st, err := New(ctx, store, "/ns", DefaultHandle())
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Add(ctx, c)
+
+	now := time.Now()
+	s1 := api.PinSchedule{ID: "s1", Path: "/ipfs/" + testCid1.String(), Next: now.Add(time.Hour)}
+	s2 := api.PinSchedule{ID: "s2", Path: "/ipfs/" + testCid1.String(), Next: now, Cron: "* * * * *"}
+	for _, s := range []api.PinSchedule{s1, s2} {
+		if err := st.AddSchedule(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	schedules, err := st.ListSchedules(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schedules) != 2 || schedules[0].ID != "s2" || schedules[1].ID != "s1" {
+		t.Fatalf("unexpected schedules: %+v", schedules)
+	}
+
+	// Schedules are not pins.
+	out := make(chan api.Pin, 10)
+	if err := st.List(ctx, out); err != nil {
+		t.Fatal(err)
+	}
+	var pins []api.Pin
+	for p := range out {
+		pins = append(pins, p)
+	}
+	if len(pins) != 1 || !pins[0].Equals(c) {
+		t.Errorf("unexpected pins: %+v", pins)
+	}
+
+	// They are part of the snapshots.
+	buf := new(bytes.Buffer)
+	if err := st.Marshal(buf); err != nil {
+		t.Fatal(err)
+	}
+	st2, err := New(ctx, inmem.New(), "/other", DefaultHandle())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st2.Unmarshal(buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st2.Get(ctx, c.Cid); err != nil {
+		t.Error(err)
+	}
+	get, err := st2.GetSchedule(ctx, "s2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if get.Cron != s2.Cron || !get.Next.Equal(s2.Next) {
+		t.Errorf("unexpected schedule: %+v", get)
+	}
+
+	if err := st.RmSchedule(ctx, "s2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.RmSchedule(ctx, "s2"); err != nil {
+		t.Error("removing a missing schedule should not fail:", err)
+	}
+	_, err = st.GetSchedule(ctx, "s2")
+	if err != state.ErrScheduleNotFound {
+		t.Errorf("expected ErrScheduleNotFound: %s", err)
+	}
+}
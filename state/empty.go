@@ -21,6 +21,14 @@ func (e *empty) Get(ctx context.Context, c api.Cid) (api.Pin, error) {
 	return api.Pin{}, ErrNotFound
 }
 
+func (e *empty) ListSchedules(ctx context.Context) ([]api.PinSchedule, error) {
+	return nil, nil
+}
+
+func (e *empty) GetSchedule(ctx context.Context, id string) (api.PinSchedule, error) {
+	return api.PinSchedule{}, ErrScheduleNotFound
+}
+
 // Empty returns an empty read-only state.
 func Empty() ReadOnly {
 	return &empty{}
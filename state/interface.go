@@ -14,6 +14,10 @@ import (
 // ErrNotFound should be returned when a pin is not part of the state.
 var ErrNotFound = errors.New("pin is not part of the pinset")
 
+// ErrScheduleNotFound should be returned when a pin schedule is not part of
+// the state.
+var ErrScheduleNotFound = errors.New("pin schedule not found")
+
 // State is a wrapper to the Cluster shared state so that Pin objects can
 // be easily read, written and queried. The state can be marshaled and
 // unmarshaled. Implementation should be thread-safe.
@@ -38,6 +42,11 @@ type ReadOnly interface {
 	// Get returns the information attacthed to this pin, if any. If the
 	// pin is not part of the state, it should return ErrNotFound.
 	Get(context.Context, api.Cid) (api.Pin, error)
+	// ListSchedules returns all the pin schedules in the state.
+	ListSchedules(context.Context) ([]api.PinSchedule, error)
+	// GetSchedule returns the pin schedule with the given ID. If it is
+	// not part of the state, it should return ErrScheduleNotFound.
+	GetSchedule(context.Context, string) (api.PinSchedule, error)
 }
 
 // WriteOnly represents the write side of a State.
@@ -46,6 +55,11 @@ type WriteOnly interface {
 	Add(context.Context, api.Pin) error
 	// Rm removes a pin from the State.
 	Rm(context.Context, api.Cid) error
+	// AddSchedule adds a pin schedule to the State, replacing any with
+	// the same ID.
+	AddSchedule(context.Context, api.PinSchedule) error
+	// RmSchedule removes a pin schedule from the State.
+	RmSchedule(context.Context, string) error
 }
 
 // BatchingState represents a state which batches write operations.
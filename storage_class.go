@@ -0,0 +1,78 @@
+package ipfscluster
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+// metric produced by the tags informer which identifies the peer group.
+const peerGroupMetric = "tag:group"
+
+// setupStorageClass sets the replication factors of the pin's storage
+// class, unless the pin sets them explicitly.
+func (c *Cluster) setupStorageClass(pin api.Pin) (api.Pin, error) {
+	if pin.StorageClass == "" {
+		return pin, nil
+	}
+
+	sc, ok := c.config.StorageClasses[pin.StorageClass]
+	if !ok {
+		return pin, fmt.Errorf("unknown storage class: %s", pin.StorageClass)
+	}
+
+	if pin.ReplicationFactorMin == 0 {
+		pin.ReplicationFactorMin = sc.ReplicationFactorMin
+	}
+	if pin.ReplicationFactorMax == 0 {
+		pin.ReplicationFactorMax = sc.ReplicationFactorMax
+	}
+	return pin, nil
+}
+
+// storageClassGroup returns the peers that belong to the peer group of the
+// given storage class, according to the last "tag:group" metrics received
+// from them. It returns false when the class does not have a peer group.
+func (c *Cluster) storageClassGroup(ctx context.Context, class string) ([]peer.ID, bool, error) {
+	if class == "" {
+		return nil, false, nil
+	}
+	sc, ok := c.config.StorageClasses[class]
+	if !ok {
+		return nil, false, fmt.Errorf("unknown storage class: %s", class)
+	}
+	if sc.PeerGroup == "" {
+		return nil, false, nil
+	}
+
+	var group []peer.ID
+	for _, m := range c.monitor.LatestMetrics(ctx, peerGroupMetric) {
+		if m.Value == sc.PeerGroup {
+			group = append(group, m.Peer)
+		}
+	}
+	return group, true, nil
+}
+
+// storageClassExcludedPeers returns the cluster peers that do not belong to
+// the peer group of the given storage class. Peers without a valid
+// "tag:group" metric are excluded.
+func (c *Cluster) storageClassExcludedPeers(ctx context.Context, class string) ([]peer.ID, error) {
+	group, ok, err := c.storageClassGroup(ctx, class)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return c.peersOutside(ctx, group)
+}
+
+// peersOutside returns the cluster peers which are not in the given list.
+func (c *Cluster) peersOutside(ctx context.Context, group []peer.ID) ([]peer.ID, error) {
+	members, err := c.consensus.Peers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return peersSubtract(members, group), nil
+}
@@ -22,6 +22,9 @@ var (
 	// ErrorCid is meant to be used as a Cid which causes errors. i.e. the
 	// ipfs mock fails when pinning this CID.
 	ErrorCid, _ = api.DecodeCid("QmP63DkAFEnDYNjDYBpyNDfttu1fvUw99x1brscPzpqmmc")
+	// LargeCid is meant to be used as a CID whose DAG exceeds the pin
+	// size limits. i.e. the cluster mock refuses to pin it.
+	LargeCid, _ = api.DecodeCid("QmP63DkAFEnDYNjDYBpyNDfttu1fvUw99x1brscPzpqmme")
 	// NotFoundCid is meant to be used as a CID that doesn't exist in the
 	// pinset.
 	NotFoundCid, _ = api.DecodeCid("bafyreiay3jpjk74dkckv2r74eyvf3lfnxujefay2rtuluintasq2zlapv4")
@@ -42,6 +45,10 @@ var (
 	PeerName5 = "TestPeer5"
 	PeerName6 = "TestPeer6"
 
+	// ConflictingPinName is a pin name which the mock considers in use
+	// by another pin.
+	ConflictingPinName = "conflicting-name"
+
 	PathIPFS1 = "/ipfs/QmaNJ5acV31sx8jq626qTpAWW4DXKw34aGhx53dECLvXbY"
 	PathIPFS2 = "/ipfs/QmbUNM297ZwxB8CfFAznK7H9YMesDoY6Tt5bPgt5MSCB2u/im.gif"
 	PathIPFS3 = "/ipfs/QmbUNM297ZwxB8CfFAznK7H9YMesDoY6Tt5bPgt5MSCB2u/im.gif/"
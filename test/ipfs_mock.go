@@ -31,6 +31,7 @@ const (
 	IpfsCustomHeaderValue = "42"
 	IpfsACAOrigin         = "myorigin"
 	IpfsErrFromNotPinned  = "'from' cid was not recursively pinned already"
+	IpfsAgentVersion      = "kubo/0.18.1/"
 )
 
 // IpfsMock is an ipfs daemon mock which should sustain the functionality used by ipfscluster.
@@ -68,8 +69,9 @@ type ipfsErr struct {
 }
 
 type mockIDResp struct {
-	ID        string
-	Addresses []string
+	ID           string
+	Addresses    []string
+	AgentVersion string
 }
 
 type mockRepoStatResp struct {
@@ -101,6 +103,16 @@ type mockBlockPutResp struct {
 	Key string
 }
 
+type mockBlockStatResp struct {
+	Key  string
+	Size int
+}
+
+type mockDagStatResp struct {
+	Size      int
+	NumBlocks int
+}
+
 type mockDagPutResp struct {
 	Cid cid.Cid
 }
@@ -176,6 +188,13 @@ func (m *IpfsMock) handler(w http.ResponseWriter, r *http.Request) {
 
 	m.reqCounter <- endp
 
+	// Gateway requests return the requested path.
+	if strings.HasPrefix(p, "/ipfs/") {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(p))
+		return
+	}
+
 	switch endp {
 	case "id":
 		resp := mockIDResp{
@@ -184,6 +203,7 @@ func (m *IpfsMock) handler(w http.ResponseWriter, r *http.Request) {
 				"/ip4/0.0.0.0/tcp/1234",
 				"/ip6/::/tcp/1234",
 			},
+			AgentVersion: IpfsAgentVersion,
 		}
 		j, _ := json.Marshal(resp)
 		w.Write(j)
@@ -444,6 +464,29 @@ func (m *IpfsMock) handler(w http.ResponseWriter, r *http.Request) {
 			goto ERROR
 		}
 		w.Write(data)
+	case "block/stat":
+		arg := r.URL.Query().Get("arg")
+		data, ok := m.BlockStore[arg]
+		if !ok && r.URL.Query().Get("offline") == "true" {
+			w.WriteHeader(http.StatusInternalServerError)
+			resp := ipfsErr{0, "block was not found locally (offline): ipld: could not find " + arg}
+			j, _ := json.Marshal(resp)
+			w.Write(j)
+			return
+		}
+		if !ok {
+			goto ERROR
+		}
+		j, _ := json.Marshal(mockBlockStatResp{Key: arg, Size: len(data)})
+		w.Write(j)
+	case "dag/stat":
+		arg := r.URL.Query().Get("arg")
+		size := 1000
+		if data, ok := m.BlockStore[arg]; ok {
+			size = len(data)
+		}
+		j, _ := json.Marshal(mockDagStatResp{Size: size, NumBlocks: 1})
+		w.Write(j)
 	case "dag/put":
 		// DAG-put is a fake implementation as we are not going to
 		// parse the input and we are just going to hash it and return
@@ -503,6 +546,8 @@ func (m *IpfsMock) handler(w http.ResponseWriter, r *http.Request) {
 			j, _ := json.Marshal(resp)
 			w.Write(j)
 		}
+	case "routing/reprovide":
+		w.Write([]byte(`{}`))
 	case "repo/gc":
 		// It assumes `/repo/gc` with parameter `stream-errors=true`
 		enc := json.NewEncoder(w)
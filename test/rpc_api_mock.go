@@ -3,6 +3,8 @@ package test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -69,6 +71,12 @@ func (mock *mockCluster) Pin(ctx context.Context, in api.Pin, out *api.Pin) erro
 	if in.Cid.Equals(ErrorCid) {
 		return ErrBadCid
 	}
+	if in.Cid.Equals(LargeCid) {
+		return fmt.Errorf("%w: %s is 10 GB, which exceeds the limit of 1 GB", api.ErrPinTooLarge, in.Cid)
+	}
+	if in.Name == ConflictingPinName {
+		return fmt.Errorf("%w: %s already uses the name %q", api.ErrPinNameConflict, Cid1, in.Name)
+	}
 
 	// a pin is never returned the replications set to 0.
 	if in.ReplicationFactorMin == 0 {
@@ -92,6 +100,32 @@ func (mock *mockCluster) Unpin(ctx context.Context, in api.Pin, out *api.Pin) er
 	return nil
 }
 
+func (mock *mockCluster) Restore(ctx context.Context, in api.Cid, out *api.Pin) error {
+	if in.Equals(ErrorCid) {
+		return ErrBadCid
+	}
+	if in.Equals(NotFoundCid) {
+		return state.ErrNotFound
+	}
+	*out = api.PinCid(in)
+	return nil
+}
+
+func (mock *mockCluster) CancelPin(ctx context.Context, in api.CancelPinRequest, out *api.Pin) error {
+	if in.Cid.Equals(ErrorCid) {
+		return ErrBadCid
+	}
+	if in.Cid.Equals(NotFoundCid) {
+		return state.ErrNotFound
+	}
+	pin := api.PinCid(in.Cid)
+	if !in.Unpin {
+		pin.CanceledAt = time.Now()
+	}
+	*out = pin
+	return nil
+}
+
 func (mock *mockCluster) PinPath(ctx context.Context, in api.PinPath, out *api.Pin) error {
 	p, err := gopath.ParsePath(in.Path)
 	if err != nil {
@@ -134,11 +168,68 @@ func (mock *mockCluster) Pins(ctx context.Context, in <-chan struct{}, out chan<
 
 	out <- api.PinWithOpts(Cid1, opts)
 	out <- api.PinCid(Cid2)
+	opts.Tags = []string{"test"}
+	opts.Namespace = "test"
 	out <- api.PinWithOpts(Cid3, opts)
 	close(out)
 	return nil
 }
 
+func (mock *mockCluster) PinsWithOptions(ctx context.Context, in <-chan api.PinListOptions, out chan<- api.Pin) error {
+	opts := <-in
+	all := make(chan api.Pin, 3)
+	mock.Pins(ctx, nil, all)
+
+	var pins []api.Pin
+	for p := range all {
+		if opts.Match(p) {
+			pins = append(pins, p)
+		}
+	}
+	if opts.Limit > 0 {
+		sort.Slice(pins, func(i, j int) bool {
+			return pins[i].Cid.String() < pins[j].Cid.String()
+		})
+		if len(pins) > opts.Limit {
+			pins = pins[:opts.Limit]
+		}
+	}
+	for _, p := range pins {
+		out <- p
+	}
+	close(out)
+	return nil
+}
+
+func (mock *mockCluster) AllocationExplain(ctx context.Context, in api.Cid, out *api.AllocationExplanation) error {
+	if in.Equals(ErrorCid) {
+		return ErrBadCid
+	}
+	*out = api.AllocationExplanation{
+		Cid:                  in,
+		ReplicationFactorMin: 1,
+		ReplicationFactorMax: 1,
+		AllocateBy:           []string{"freespace"},
+		Allocations:          []peer.ID{PeerID1},
+		Peers: []api.PeerAllocationExplanation{
+			{
+				Peer:   PeerID1,
+				Class:  api.AllocationClassCandidate,
+				Rank:   1,
+				Chosen: true,
+				Reason: "ranked 1 by the allocator",
+			},
+			{
+				Peer:   PeerID2,
+				Class:  api.AllocationClassCandidate,
+				Rank:   2,
+				Reason: "ranked 2 by the allocator, after replication_factor_max was reached",
+			},
+		},
+	}
+	return nil
+}
+
 func (mock *mockCluster) PinGet(ctx context.Context, in api.Cid, out *api.Pin) error {
 	switch in.String() {
 	case ErrorCid.String():
@@ -147,6 +238,9 @@ func (mock *mockCluster) PinGet(ctx context.Context, in api.Cid, out *api.Pin) e
 		p := api.PinCid(in)
 		p.ReplicationFactorMin = -1
 		p.ReplicationFactorMax = -1
+		if in.Equals(Cid3) {
+			p.Namespace = "test"
+		}
 		*out = p
 		return nil
 	case Cid2.String(): // This is a remote pin
@@ -276,6 +370,7 @@ func (mock *mockCluster) StatusAll(ctx context.Context, in <-chan api.TrackerSta
 			Metadata: map[string]string{
 				"ccc": "3c",
 			},
+			Namespace: "test",
 			PeerMap: map[string]api.PinInfoShort{
 				pid: {
 					Status: api.TrackerStatusPinError,
@@ -303,6 +398,25 @@ func (mock *mockCluster) StatusAll(ctx context.Context, in <-chan api.TrackerSta
 	return nil
 }
 
+func (mock *mockCluster) StatusAllWithOptions(ctx context.Context, in <-chan api.StatusAllOptions, out chan<- api.GlobalPinInfo) error {
+	defer close(out)
+	opts := <-in
+	f := make(chan api.TrackerStatus, 1)
+	f <- opts.Filter
+	close(f)
+	gpis := make(chan api.GlobalPinInfo, 10)
+	err := mock.StatusAll(ctx, f, gpis)
+	if err != nil {
+		return err
+	}
+	for gpi := range gpis {
+		if strings.HasPrefix(gpi.Cid.String(), opts.CidPrefix) {
+			out <- gpi
+		}
+	}
+	return nil
+}
+
 func (mock *mockCluster) StatusAllLocal(ctx context.Context, in <-chan api.TrackerStatus, out chan<- api.PinInfo) error {
 	return (&mockPinTracker{}).StatusAll(ctx, in, out)
 }
@@ -350,6 +464,19 @@ func (mock *mockCluster) RecoverAllLocal(ctx context.Context, in <-chan struct{}
 	return (&mockPinTracker{}).RecoverAll(ctx, in, out)
 }
 
+func (mock *mockCluster) RecoverAllWithOptions(ctx context.Context, in <-chan api.RecoverAllOptions, out chan<- api.GlobalPinInfo) error {
+	opts := <-in
+	f := make(chan api.TrackerStatus, 1)
+	f <- opts.Filter
+	close(f)
+	return mock.StatusAll(ctx, f, out)
+}
+
+func (mock *mockCluster) RecoverAllLocalWithOptions(ctx context.Context, in <-chan api.RecoverAllOptions, out chan<- api.PinInfo) error {
+	close(out)
+	return nil
+}
+
 func (mock *mockCluster) Recover(ctx context.Context, in api.Cid, out *api.GlobalPinInfo) error {
 	return mock.Status(ctx, in, out)
 }
@@ -366,6 +493,217 @@ func (mock *mockCluster) BlockAllocate(ctx context.Context, in api.Pin, out *[]p
 	return nil
 }
 
+func (mock *mockCluster) ConsensusEvents(ctx context.Context, in <-chan struct{}, out chan<- api.ConsensusEvent) error {
+	defer close(out)
+	out <- api.ConsensusEvent{
+		Type:      api.ConsensusEventPin,
+		Pin:       api.PinCid(Cid1),
+		Timestamp: time.Now(),
+	}
+	out <- api.ConsensusEvent{
+		Type:      api.ConsensusEventPeerAdd,
+		Peer:      PeerID2,
+		Timestamp: time.Now(),
+	}
+	return nil
+}
+
+// Events sends the event history and then waits for the context to be
+// cancelled, as if no new events happened.
+func (mock *mockCluster) Events(ctx context.Context, in <-chan api.ClusterEventQuery, out chan<- api.ClusterEvent) error {
+	hist := make(chan api.ClusterEvent, 10)
+	err := mock.EventHistory(ctx, in, hist)
+	defer close(out)
+	if err != nil {
+		return err
+	}
+	for ev := range hist {
+		out <- ev
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (mock *mockCluster) EventHistory(ctx context.Context, in <-chan api.ClusterEventQuery, out chan<- api.ClusterEvent) error {
+	defer close(out)
+	q := <-in
+	events := []api.ClusterEvent{
+		{
+			ID:      1,
+			Type:    api.ClusterEventPeerJoined,
+			Peer:    PeerID1,
+			Subject: PeerID2,
+		},
+		{
+			ID:   2,
+			Type: api.ClusterEventPin,
+			Peer: PeerID1,
+			Cid:  Cid1,
+		},
+		{
+			ID:   3,
+			Type: api.ClusterEventUnpin,
+			Peer: PeerID1,
+			Cid:  Cid1,
+		},
+	}
+	var n int
+	for _, ev := range events {
+		if !q.Match(ev) {
+			continue
+		}
+		out <- ev
+		n++
+		if q.Limit > 0 && n >= q.Limit {
+			break
+		}
+	}
+	return nil
+}
+
+func (mock *mockCluster) Quorum(ctx context.Context, in struct{}, out *api.QuorumStatus) error {
+	*out = api.QuorumStatus{
+		Leader:          PeerID1,
+		Voters:          []peer.ID{PeerID1, PeerID2, PeerID3},
+		ReachableVoters: []peer.ID{PeerID1, PeerID2},
+		Quorum:          2,
+		HasQuorum:       true,
+		CommitLatency:   10 * time.Millisecond,
+	}
+	return nil
+}
+
+func (mock *mockCluster) ConsensusLog(ctx context.Context, in api.ConsensusLogQuery, out *[]api.ConsensusLogEntry) error {
+	entries := []api.ConsensusLogEntry{
+		{Index: 3, Type: "unpin", Cid: Cid1, Origin: PeerID1},
+		{Index: 2, Type: "pin", Cid: Cid2, Origin: PeerID2},
+		{Index: 1, Type: "pin", Cid: Cid1, Origin: PeerID1},
+	}
+	var res []api.ConsensusLogEntry
+	for _, e := range entries {
+		if in.Before > 0 && e.Index >= in.Before {
+			continue
+		}
+		if in.Limit > 0 && len(res) >= in.Limit {
+			break
+		}
+		res = append(res, e)
+	}
+	*out = res
+	return nil
+}
+
+func (mock *mockCluster) DeadLetters(ctx context.Context, in struct{}, out *[]api.DeadLetter) error {
+	*out = []api.DeadLetter{
+		{
+			ID:     "pin-" + Cid1.String(),
+			Type:   "pin",
+			Pin:    api.PinCid(Cid1),
+			Origin: PeerID2,
+			Error:  "error applying operation",
+		},
+	}
+	return nil
+}
+
+func (mock *mockCluster) RetryDeadLetter(ctx context.Context, in string, out *struct{}) error {
+	if in != "pin-"+Cid1.String() {
+		return errors.New("dead letter not found")
+	}
+	return nil
+}
+
+func (mock *mockCluster) DiscardDeadLetter(ctx context.Context, in string, out *struct{}) error {
+	return mock.RetryDeadLetter(ctx, in, out)
+}
+
+func (mock *mockCluster) Handoff(ctx context.Context, in api.HandoffRequest, out *api.HandoffJob) error {
+	if in.Target.Value() == nil {
+		return errors.New("a target cluster API address must be given")
+	}
+	*out = api.HandoffJob{
+		ID:      "handoff-1",
+		Peer:    PeerID1,
+		Target:  in.Target.String(),
+		Status:  api.HandoffRunning,
+		Total:   1,
+		Started: time.Now(),
+	}
+	return nil
+}
+
+func (mock *mockCluster) HandoffStatus(ctx context.Context, in string, out *api.HandoffJob) error {
+	if in != "handoff-1" {
+		return api.ErrHandoffNotFound
+	}
+	*out = api.HandoffJob{
+		ID:        in,
+		Peer:      PeerID1,
+		Status:    api.HandoffDone,
+		Total:     1,
+		Confirmed: 1,
+		Unpinned:  1,
+	}
+	return nil
+}
+
+func (mock *mockCluster) Handoffs(ctx context.Context, in struct{}, out *[]api.HandoffJob) error {
+	var job api.HandoffJob
+	err := mock.HandoffStatus(ctx, "handoff-1", &job)
+	*out = []api.HandoffJob{job}
+	return err
+}
+
+func (mock *mockCluster) CancelHandoff(ctx context.Context, in string, out *struct{}) error {
+	var job api.HandoffJob
+	return mock.HandoffStatus(ctx, in, &job)
+}
+
+func (mock *mockCluster) SchedulePin(ctx context.Context, in api.PinSchedule, out *api.PinSchedule) error {
+	if in.NotBefore.IsZero() && in.Cron == "" {
+		return errors.New("a not-before time or a cron expression must be given")
+	}
+	in.ID = "schedule-1"
+	in.Peer = PeerID1
+	in.Created = time.Now()
+	in.Next = in.NotBefore
+	*out = in
+	return nil
+}
+
+func (mock *mockCluster) PinSchedules(ctx context.Context, in struct{}, out *[]api.PinSchedule) error {
+	*out = []api.PinSchedule{
+		{
+			ID:      "schedule-1",
+			Path:    "/ipns/" + PeerID1.String(),
+			Cron:    "0 * * * *",
+			Peer:    PeerID1,
+			Created: time.Now().Add(-time.Hour),
+			Next:    time.Now().Add(time.Hour).Truncate(time.Hour),
+			LastRun: time.Now().Truncate(time.Hour),
+			LastCid: Cid1,
+		},
+	}
+	return nil
+}
+
+func (mock *mockCluster) CancelPinSchedule(ctx context.Context, in string, out *struct{}) error {
+	if in != "schedule-1" {
+		return errors.New("pin schedule not found")
+	}
+	return nil
+}
+
+func (mock *mockCluster) Rollback(ctx context.Context, in []api.Pin, out *api.RollbackInfo) error {
+	*out = api.RollbackInfo{
+		Peer:          PeerID1,
+		AuditSnapshot: "rollback-audit.json",
+		Pins:          len(in),
+		Timestamp:     time.Now(),
+	}
+	return nil
+}
+
 func (mock *mockCluster) RepoGC(ctx context.Context, in struct{}, out *api.GlobalRepoGC) error {
 	localrepoGC := api.RepoGC{}
 	_ = mock.RepoGCLocal(ctx, struct{}{}, &localrepoGC)
@@ -377,6 +715,81 @@ func (mock *mockCluster) RepoGC(ctx context.Context, in struct{}, out *api.Globa
 	return nil
 }
 
+func (mock *mockCluster) PinQueues(ctx context.Context, in struct{}, out *api.GlobalPinQueueInfo) error {
+	local := api.PinQueueInfo{}
+	_ = mock.PinQueuesLocal(ctx, struct{}{}, &local)
+	*out = api.GlobalPinQueueInfo{
+		PeerMap: map[string]api.PinQueueInfo{
+			PeerID1.String(): local,
+		},
+	}
+	return nil
+}
+
+func (mock *mockCluster) PinHistory(ctx context.Context, in api.Cid, out *[]api.PinAuditEntry) error {
+	return mock.PinHistoryLocal(ctx, in, out)
+}
+
+func (mock *mockCluster) PinHistoryLocal(ctx context.Context, in api.Cid, out *[]api.PinAuditEntry) error {
+	*out = []api.PinAuditEntry{
+		{
+			Type: api.PinAuditPin,
+			Cid:  in,
+			Requester: api.PinRequester{
+				User:    "alice",
+				Address: "127.0.0.1",
+				Peer:    PeerID1,
+			},
+			Timestamp: time.Now().Add(-time.Hour),
+		},
+		{
+			Type:      api.PinAuditUnpin,
+			Cid:       in,
+			Requester: api.PinRequester{Peer: PeerID1},
+			Timestamp: time.Now(),
+		},
+	}
+	return nil
+}
+
+func (mock *mockCluster) PinQueuesLocal(ctx context.Context, in struct{}, out *api.PinQueueInfo) error {
+	*out = api.PinQueueInfo{
+		Peer:             PeerID1,
+		Peername:         PeerName1,
+		PinDepth:         10,
+		OldestPin:        time.Now().Add(-time.Minute),
+		Pinning:          2,
+		ConcurrentPins:   2,
+		ConcurrentUnpins: 1,
+	}
+	return nil
+}
+
+func (mock *mockCluster) RunJob(ctx context.Context, in api.JobRequest, out *api.GlobalJobResult) error {
+	if in.Job != "reconnect-ipfs" {
+		return errors.New("unknown job: " + in.Job)
+	}
+	localRes := api.JobResult{}
+	_ = mock.RunJobLocal(ctx, in.Job, &localRes)
+	*out = api.GlobalJobResult{
+		Job: in.Job,
+		PeerMap: map[string]api.JobResult{
+			PeerID1.String(): localRes,
+		},
+	}
+	return nil
+}
+
+func (mock *mockCluster) RunJobLocal(ctx context.Context, in string, out *api.JobResult) error {
+	*out = api.JobResult{
+		Peer:     PeerID1,
+		Peername: PeerName1,
+		Job:      in,
+		Duration: time.Second,
+	}
+	return nil
+}
+
 func (mock *mockCluster) RepoGCLocal(ctx context.Context, in struct{}, out *api.RepoGC) error {
 	*out = api.RepoGC{
 		Peer: PeerID1,
@@ -402,6 +815,193 @@ func (mock *mockCluster) RepoGCLocal(ctx context.Context, in struct{}, out *api.
 	return nil
 }
 
+func (mock *mockCluster) Capacity(ctx context.Context, in struct{}, out *api.GlobalCapacity) error {
+	local := api.PeerCapacity{}
+	_ = mock.CapacityLocal(ctx, struct{}{}, &local)
+	*out = api.GlobalCapacity{
+		Total: local.Capacity,
+		PeerMap: map[string]api.PeerCapacity{
+			PeerID1.String(): local,
+		},
+	}
+	return nil
+}
+
+func (mock *mockCluster) StateChecksum(ctx context.Context, in struct{}, out *api.GlobalStateChecksum) error {
+	local := api.StateChecksum{}
+	_ = mock.StateChecksumLocal(ctx, struct{}{}, &local)
+	*out = api.GlobalStateChecksum{
+		PeerMap: map[string]api.StateChecksum{
+			PeerID1.String(): local,
+		},
+	}
+	return nil
+}
+
+func (mock *mockCluster) StateChecksumLocal(ctx context.Context, in struct{}, out *api.StateChecksum) error {
+	*out = api.StateChecksum{
+		Peer:         PeerID1,
+		Checksum:     "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		Pins:         2,
+		AppliedIndex: 10,
+		Leader:       true,
+	}
+	return nil
+}
+
+func (mock *mockCluster) CapacityLocal(ctx context.Context, in struct{}, out *api.PeerCapacity) error {
+	*out = api.PeerCapacity{
+		Capacity: api.Capacity{
+			StorageMax: 100 * 1024,
+			Used:       40 * 1024,
+			Free:       60 * 1024,
+			IngestRate: 1024,
+			DaysToFull: 60,
+		},
+		Peer:      PeerID1,
+		Timestamp: time.Now(),
+	}
+	return nil
+}
+
+func (mock *mockCluster) PinGC(ctx context.Context, in bool, out *api.PinGCReport) error {
+	*out = api.PinGCReport{
+		Timestamp: time.Now(),
+		Used:      95 * 1024,
+		Limit:     100 * 1024,
+		ToFree:    15 * 1024,
+		Candidates: []api.PinGCCandidate{
+			{
+				Cid:       Cid1,
+				Timestamp: time.Now().Add(-48 * time.Hour),
+				Size:      20 * 1024,
+				Reason:    api.PinGCReasonUsage,
+			},
+		},
+		Executed: in,
+	}
+	return nil
+}
+
+func (mock *mockCluster) RecordPinStatus(ctx context.Context, in api.PinInfo, out *struct{}) error {
+	return nil
+}
+
+func (mock *mockCluster) RecordPinTime(ctx context.Context, in api.Pin, out *struct{}) error {
+	return nil
+}
+
+func (mock *mockCluster) SLOReport(ctx context.Context, in struct{}, out *api.SLOReport) error {
+	return mock.SLOReportLocal(ctx, in, out)
+}
+
+func (mock *mockCluster) SLOReportLocal(ctx context.Context, in struct{}, out *api.SLOReport) error {
+	*out = api.SLOReport{
+		Timestamp: time.Now(),
+		Window:    24 * time.Hour,
+		Classes: []api.SLOClassReport{
+			{
+				Class:      "gold",
+				Target:     time.Minute,
+				Objective:  0.99,
+				Pinned:     100,
+				OnTime:     98,
+				Compliance: 0.98,
+				Met:        false,
+			},
+		},
+	}
+	return nil
+}
+
+func (mock *mockCluster) DedupStats(ctx context.Context, in struct{}, out *api.GlobalDedupStats) error {
+	local := api.DedupStats{}
+	_ = mock.DedupStatsLocal(ctx, struct{}{}, &local)
+	*out = api.GlobalDedupStats{
+		PeerMap: map[string]api.DedupStats{
+			PeerID1.String(): local,
+		},
+	}
+	return nil
+}
+
+func (mock *mockCluster) DedupStatsLocal(ctx context.Context, in struct{}, out *api.DedupStats) error {
+	*out = api.DedupStats{
+		Peer:         PeerID1,
+		Pins:         10,
+		SampledPins:  2,
+		TotalBlocks:  6,
+		UniqueBlocks: 4,
+		SharedBlocks: 2,
+		TotalBytes:   6 * 1024,
+		UniqueBytes:  4 * 1024,
+		Timestamp:    time.Now(),
+	}
+	return nil
+}
+
+func (mock *mockCluster) ObservePin(ctx context.Context, in api.ObservedPin, out *struct{}) error {
+	return nil
+}
+
+func (mock *mockCluster) ObserveUnpin(ctx context.Context, in api.Cid, out *struct{}) error {
+	return nil
+}
+
+func (mock *mockCluster) ArchivePeer(ctx context.Context, in api.ArchivedPeer, out *struct{}) error {
+	return nil
+}
+
+func (mock *mockCluster) PeersArchive(ctx context.Context, in struct{}, out *[]api.ArchivedPeer) error {
+	*out = []api.ArchivedPeer{
+		{
+			ID:       PeerID3,
+			Peername: PeerName3,
+			Removed:  time.Now(),
+		},
+	}
+	return nil
+}
+
+func (mock *mockCluster) APIEndpoints(ctx context.Context, in struct{}, out *[]api.APIEndpoint) error {
+	addr, _ := api.NewMultiaddr("/ip4/1.2.3.4/tcp/9094")
+	*out = []api.APIEndpoint{
+		{
+			Peer:      PeerID1,
+			Peername:  PeerName1,
+			Addresses: []api.Multiaddr{addr},
+			Public:    true,
+		},
+	}
+	return nil
+}
+
+func (mock *mockCluster) ObservedPins(ctx context.Context, in struct{}, out *[]api.ObservedPin) error {
+	*out = []api.ObservedPin{
+		{
+			Cid:       Cid1,
+			Path:      "/ipfs/" + Cid1.String(),
+			Mode:      api.PinModeRecursive,
+			Peer:      PeerID1,
+			Timestamp: time.Now(),
+		},
+	}
+	return nil
+}
+
+func (mock *mockCluster) InformerMetricsLocal(ctx context.Context, in struct{}, out *[]api.Metric) error {
+	*out = []api.Metric{
+		{
+			Name:   "numpin",
+			Peer:   PeerID1,
+			Value:  "1",
+			Expire: time.Now().Add(time.Minute).UnixNano(),
+			Valid:  true,
+		},
+	}
+	return nil
+}
+
 func (mock *mockCluster) SendInformerMetrics(ctx context.Context, in struct{}, out *struct{}) error {
 	return nil
 }
@@ -455,6 +1055,7 @@ func (mock *mockPinTracker) StatusAll(ctx context.Context, in <-chan api.Tracker
 		{
 			Cid:  Cid3,
 			Peer: PeerID1,
+			Tags: []string{"test"},
 			PinInfoShort: api.PinInfoShort{
 				Status: api.TrackerStatusPinError,
 				TS:     time.Now(),
@@ -557,6 +1158,17 @@ func (mock *mockIPFSConnector) PinLsCid(ctx context.Context, in api.Pin, out *ap
 	return nil
 }
 
+func (mock *mockIPFSConnector) PinProgress(ctx context.Context, in api.Cid, out *api.PinProgress) error {
+	if in.Equals(SlowCid1) {
+		*out = api.PinProgress{
+			Blocks:       5,
+			Started:      time.Now().Add(-time.Minute),
+			LastProgress: time.Now(),
+		}
+	}
+	return nil
+}
+
 func (mock *mockIPFSConnector) PinLs(ctx context.Context, in <-chan []string, out chan<- api.IPFSPinInfo) error {
 	out <- api.IPFSPinInfo{Cid: api.Cid(Cid1), Type: api.IPFSPinStatusRecursive}
 	out <- api.IPFSPinInfo{Cid: api.Cid(Cid3), Type: api.IPFSPinStatusRecursive}
@@ -594,6 +1206,16 @@ func (mock *mockIPFSConnector) BlockStream(ctx context.Context, in <-chan api.No
 	return nil
 }
 
+func (mock *mockIPFSConnector) DagStat(ctx context.Context, in api.Pin, out *api.IPFSDagStat) error {
+	*out = api.IPFSDagStat{Size: 1000, NumBlocks: 1}
+	return nil
+}
+
+func (mock *mockIPFSConnector) VerifyBlocks(ctx context.Context, in api.IPFSBlockVerification, out *api.IPFSBlockVerification) error {
+	*out = api.IPFSBlockVerification{Cid: in.Cid, Sample: in.Sample, Checked: 1}
+	return nil
+}
+
 func (mock *mockIPFSConnector) Resolve(ctx context.Context, in string, out *api.Cid) error {
 	switch in {
 	case ErrorCid.String(), "/ipfs/" + ErrorCid.String():
@@ -608,6 +1230,11 @@ func (mock *mockConsensus) AddPeer(ctx context.Context, in peer.ID, out *struct{
 	return errors.New("mock rpc cannot redirect")
 }
 
+func (mock *mockConsensus) IsNonVoter(ctx context.Context, in struct{}, out *bool) error {
+	*out = false
+	return nil
+}
+
 func (mock *mockConsensus) RmPeer(ctx context.Context, in peer.ID, out *struct{}) error {
 	return errors.New("mock rpc cannot redirect")
 }
@@ -0,0 +1,86 @@
+package ipfscluster
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	trace "go.opencensus.io/trace"
+)
+
+var errNotTrashed = errors.New("the pin is not in the trash")
+
+// trash moves a pin to the trash: it is marked as trashed and stays pinned
+// until the trash retention period is over.
+func (c *Cluster) trash(ctx context.Context, pin api.Pin) (api.Pin, error) {
+	pin.TrashedAt = time.Now()
+	logger.Infof("moving %s to the trash until %s", pin.Cid, c.trashPurgeAt(pin))
+	if err := c.logPin(ctx, pin); err != nil {
+		return pin, err
+	}
+	c.scheduleTrashPurge(pin)
+	return pin, nil
+}
+
+// trashPurgeAt returns when a trashed pin should be unpinned. When the trash
+// is disabled, trashed pins are unpinned right away.
+func (c *Cluster) trashPurgeAt(pin api.Pin) time.Time {
+	return pin.TrashedAt.Add(c.config.TrashRetention)
+}
+
+// scheduleTrashPurge arranges for a trashed pin to be unpinned when the
+// retention period is over, if that happens before the next StateSync. As
+// with expired pins, the pin is only unpinned if it is still in the trash
+// by then.
+func (c *Cluster) scheduleTrashPurge(pin api.Pin) {
+	purgeAt := c.trashPurgeAt(pin)
+	if purgeAt.After(time.Now().Add(c.config.StateSyncInterval)) {
+		return
+	}
+
+	c.expiry.schedule(pin.Cid, purgeAt, func() {
+		current, err := c.PinGet(c.ctx, pin.Cid)
+		if err != nil || !current.IsTrashed() || c.trashPurgeAt(current).After(time.Now()) {
+			return
+		}
+		c.purgeTrashed(c.ctx, current)
+	})
+}
+
+// purgeTrashed unpins the given trashed pin.
+func (c *Cluster) purgeTrashed(ctx context.Context, pin api.Pin) {
+	logger.Infof("Unpinning %s: in the trash since %s", pin.Cid, pin.TrashedAt)
+	if _, err := c.unpin(ctx, pin.Cid, false); err != nil {
+		logger.Error(err)
+	}
+}
+
+// Restore takes a pin out of the trash, so that it is not unpinned when the
+// trash retention period is over. It returns the restored pin, or an error
+// if the pin is not in the trash.
+func (c *Cluster) Restore(ctx context.Context, h api.Cid) (api.Pin, error) {
+	ctx, span := trace.StartSpan(ctx, "cluster/Restore")
+	defer span.End()
+
+	if c.config.FollowerMode {
+		return api.Pin{}, errFollowerMode
+	}
+
+	pin, err := c.PinGet(ctx, h)
+	if err != nil {
+		return api.Pin{}, err
+	}
+	if !pin.IsTrashed() {
+		return pin, errNotTrashed
+	}
+
+	logger.Info("restoring from the trash:", h)
+	pin.TrashedAt = time.Time{}
+	if err := c.logPin(ctx, pin); err != nil {
+		return pin, err
+	}
+	c.scheduleExpiry(pin)
+	return pin, nil
+}
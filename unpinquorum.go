@@ -0,0 +1,140 @@
+package ipfscluster
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ipfs-cluster/ipfs-cluster/api"
+
+	peer "github.com/libp2p/go-libp2p/core/peer"
+)
+
+// pendingUnpin is an item removed from the shared state whose unpinning
+// has not been confirmed by enough of the peers it was allocated to.
+type pendingUnpin struct {
+	pin   api.Pin
+	since time.Time
+}
+
+// pendingUnpins tracks the unpins waiting for confirmation (UnpinQuorum).
+type pendingUnpins struct {
+	mux  sync.Mutex
+	pins map[api.Cid]pendingUnpin
+}
+
+// add starts waiting for the confirmations of an unpin. When onlyNew is
+// set, unpins already waited for are left alone.
+func (pu *pendingUnpins) add(pin api.Pin, onlyNew bool, timeout time.Duration) {
+	pu.mux.Lock()
+	defer pu.mux.Unlock()
+	if pu.pins == nil {
+		pu.pins = make(map[api.Cid]pendingUnpin)
+	}
+	if _, ok := pu.pins[pin.Cid]; ok && onlyNew {
+		return
+	}
+
+	now := time.Now()
+	for c, p := range pu.pins {
+		if now.Sub(p.since) > timeout {
+			logger.Warnf("unpin of %s was not confirmed by enough peers after %s", c, timeout)
+			delete(pu.pins, c)
+		}
+	}
+	pu.pins[pin.Cid] = pendingUnpin{pin: pin, since: now}
+}
+
+// get returns the pending unpin for a CID, unless it timed out.
+func (pu *pendingUnpins) get(c api.Cid, timeout time.Duration) (pendingUnpin, bool) {
+	pu.mux.Lock()
+	defer pu.mux.Unlock()
+	p, ok := pu.pins[c]
+	if !ok {
+		return p, false
+	}
+	if time.Since(p.since) > timeout {
+		logger.Warnf("unpin of %s was not confirmed by enough peers after %s", c, timeout)
+		delete(pu.pins, c)
+		return p, false
+	}
+	return p, true
+}
+
+func (pu *pendingUnpins) remove(c api.Cid) {
+	pu.mux.Lock()
+	defer pu.mux.Unlock()
+	delete(pu.pins, c)
+}
+
+// watchUnpin starts waiting for the confirmations of an unpin when
+// UnpinQuorum is set. Unpins seen in consensus events are only tracked
+// when the event carries the allocations of the pin.
+func (c *Cluster) watchUnpin(pin api.Pin, fromEvent bool) {
+	if c.config.UnpinQuorum <= 0 {
+		return
+	}
+	if fromEvent && len(pin.Allocations) == 0 && !pin.IsPinEverywhere() {
+		return
+	}
+	c.unpins.add(pin, fromEvent, c.config.UnpinQuorumTimeout)
+}
+
+// unpinQuorum returns how many of the given allocations need to confirm an
+// unpin.
+func (c *Cluster) unpinQuorum(allocations int) int {
+	n := int(math.Ceil(c.config.UnpinQuorum * float64(allocations)))
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// confirmUnpin checks the status reported by the peers that were allocated
+// to an unpinned item. When enough of them report it as unpinned, the unpin
+// stops being pending and the peers that have not finished are logged.
+// It returns whether the unpin is confirmed.
+func (c *Cluster) confirmUnpin(h api.Cid, allocated []peer.ID, gpin api.GlobalPinInfo) bool {
+	var confirmed int
+	var stragglers []string
+	for _, p := range allocated {
+		pis, ok := gpin.PeerMap[p.String()]
+		if ok && pis.Status == api.TrackerStatusUnpinned {
+			confirmed++
+			continue
+		}
+		stragglers = append(stragglers, p.String())
+	}
+	if confirmed < c.unpinQuorum(len(allocated)) {
+		return false
+	}
+	c.unpins.remove(h)
+	if len(stragglers) > 0 {
+		logger.Infof("unpin of %s confirmed by %d/%d peers. Still unpinning: %v", h, confirmed, len(allocated), stragglers)
+	}
+	return true
+}
+
+// unpinQuorumStatus returns the status of a pending unpin: the peers it
+// was allocated to are asked for their status and the rest are reported
+// as unpinned. Once confirmed, all peers are reported as unpinned.
+func (c *Cluster) unpinQuorumStatus(ctx context.Context, comp, method string, pu pendingUnpin, members []peer.ID) (api.GlobalPinInfo, error) {
+	allocated := members
+	if !pu.pin.IsPinEverywhere() {
+		allocated = peersIntersect(pu.pin.Allocations, members)
+	}
+
+	gpin := api.GlobalPinInfo{Cid: pu.pin.Cid, Name: pu.pin.Name}
+	unpinned := api.PinCid(pu.pin.Cid)
+	timeNow := time.Now()
+	c.setTrackerStatus(&gpin, pu.pin.Cid, peersSubtract(members, allocated), api.TrackerStatusUnpinned, unpinned, timeNow)
+	c.multiCallPinInfo(ctx, comp, method, pu.pin.Cid, unpinned, allocated, &gpin)
+
+	if !c.confirmUnpin(pu.pin.Cid, allocated, gpin) {
+		return gpin, nil
+	}
+	gpin = api.GlobalPinInfo{}
+	c.setTrackerStatus(&gpin, pu.pin.Cid, members, api.TrackerStatusUnpinned, unpinned, timeNow)
+	return gpin, nil
+}
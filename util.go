@@ -100,13 +100,19 @@ type distanceChecker struct {
 }
 
 func (dc distanceChecker) isClosest(ci api.Cid) bool {
-	ciHash := convertKey(ci.KeyString())
+	return dc.isClosestToKey(ci.KeyString())
+}
+
+// isClosestToKey is like isClosest for an arbitrary key. It allows to pick
+// a single peer to perform a cluster-wide task.
+func (dc distanceChecker) isClosestToKey(key string) bool {
+	keyHash := convertKey(key)
 	localPeerHash := dc.convertPeerID(dc.local)
-	myDistance := xor(ciHash, localPeerHash)
+	myDistance := xor(keyHash, localPeerHash)
 
 	for _, p := range dc.otherPeers {
 		peerHash := dc.convertPeerID(p)
-		distance := xor(peerHash, ciHash)
+		distance := xor(peerHash, keyHash)
 
 		// if myDistance is larger than for other peers...
 		if bytes.Compare(myDistance[:], distance[:]) > 0 {
@@ -161,11 +167,18 @@ func peersSubtract(a []peer.ID, b []peer.ID) []peer.ID {
 	return result
 }
 
+// returns the peers in a which are also in b.
+func peersIntersect(a []peer.ID, b []peer.ID) []peer.ID {
+	return peersSubtract(a, peersSubtract(a, b))
+}
+
 // pingValue describes the value carried by ping metrics
 type pingValue struct {
 	Peername      string          `json:"peer_name,omitempty"`
 	IPFSID        peer.ID         `json:"ipfs_id,omitempty"`
 	IPFSAddresses []api.Multiaddr `json:"ipfs_addresses,omitempty"`
+	APIAddresses  []api.Multiaddr `json:"api_addresses,omitempty"`
+	APIPublic     bool            `json:"api_public,omitempty"`
 }
 
 // Valid returns true if the PingValue has IPFSID set.
@@ -18,3 +18,10 @@ var Version = semver.MustParse("1.0.6")
 // are introduced, though at this point we aim to minimize those as much as
 // possible.
 var RPCProtocol = protocol.ID("/ipfscluster/1.0/rpc")
+
+// LegacyRPCProtocols are older RPC protocol versions which this peer still
+// answers, so that peers which have not been upgraded yet keep working
+// during rolling upgrades. When the RPCProtocol is bumped, the previous one
+// should be added here, along with the shims needed to answer the methods
+// whose arguments or responses changed.
+var LegacyRPCProtocols = []protocol.ID{}